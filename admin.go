@@ -0,0 +1,244 @@
+package discord2pushover
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// adminUnixPrefix marks an admin listen address as a unix domain socket path.
+const adminUnixPrefix = "unix:"
+
+// adminHealth reports whether the bot is healthy, served at /healthz. Nil until the bot is connected.
+var adminHealth func(now time.Time) error
+
+// startTime is when the process started, for uptime in diagnostics.
+var startTime = time.Now()
+
+// debugState is the runtime snapshot served at /debug/state.
+type debugState struct {
+	Version            string           `json:"version"`
+	UptimeSeconds      int64            `json:"uptimeSeconds"`
+	Goroutines         int              `json:"goroutines"`
+	HeapAllocBytes     uint64           `json:"heapAllocBytes"`
+	TrackedReceipts    int              `json:"trackedReceipts"`
+	OpenIncidents      int              `json:"openIncidents"`
+	ActiveFloods       int              `json:"activeFloods"`
+	ReplyCodes         int              `json:"replyCodes"`
+	CheckpointChannels int              `json:"checkpointChannels"`
+	PanicsRecovered    int64            `json:"panicsRecovered"`
+	RuleMatches        map[string]int64 `json:"ruleMatches"`
+	GatewayDisconnects int64            `json:"gatewayDisconnects"`
+	GatewayReconnects  int64            `json:"gatewayReconnects"`
+	LastOutageMillis   int64            `json:"lastGatewayOutageMs"`
+}
+
+// snapshotDebugState collects the sizes of the bot's in-memory state, which should stay bounded.
+func snapshotDebugState() debugState {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	state := debugState{
+		Version:         Version,
+		UptimeSeconds:   int64(time.Since(startTime).Seconds()),
+		Goroutines:      runtime.NumGoroutine(),
+		HeapAllocBytes:  mem.HeapAlloc,
+		PanicsRecovered: panicsRecovered.Value(),
+		RuleMatches:     make(map[string]int64),
+
+		GatewayDisconnects: gatewayDisconnects.Value(),
+		GatewayReconnects:  gatewayReconnects.Value(),
+		LastOutageMillis:   gatewayLastOutageMillis.Value(),
+	}
+	if config := globalConfig; config != nil {
+		config.trackedEmergencies().Range(func(key, value interface{}) bool {
+			state.TrackedReceipts++
+			return true
+		})
+	}
+	incidents.mu.Lock()
+	state.OpenIncidents = len(incidents.open)
+	incidents.mu.Unlock()
+	floods.mu.Lock()
+	state.ActiveFloods = len(floods.states)
+	floods.mu.Unlock()
+	replyTargets.mu.Lock()
+	state.ReplyCodes = len(replyTargets.targets)
+	replyTargets.mu.Unlock()
+	checkpoints.mu.Lock()
+	state.CheckpointChannels = len(checkpoints.channels)
+	checkpoints.mu.Unlock()
+	for _, stats := range snapshotRuleStats(nil) {
+		state.RuleMatches[stats.Rule] = stats.Matched
+	}
+	return state
+}
+
+func serveDebugState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(snapshotDebugState())
+}
+
+// serveHealth answers 200 while the bot is connected and healthy, and 503 otherwise.
+func serveHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	health := adminHealth
+	if health == nil {
+		http.Error(w, "starting", http.StatusServiceUnavailable)
+		return
+	}
+	if err := health(time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// adminListenerAuth returns the auth of the admin listener, with the eventsToken as a token of the read
+// role. It is nil without auth.
+func adminListenerAuth(admin *AdminListener) *AdminAuth {
+	if admin.Auth == nil || admin.EventsToken == "" {
+		return admin.Auth
+	}
+	auth := *admin.Auth
+	auth.Tokens = append(append([]AdminToken{}, auth.Tokens...), AdminToken{Name: "eventsToken", Token: admin.EventsToken, Role: adminRoleRead})
+	return &auth
+}
+
+// newAdminMux returns the handler of the admin listener. With auth, every endpoint but /healthz
+// requires the read role, and pprof the operator role.
+func newAdminMux(admin *AdminListener) *http.ServeMux {
+	auth := adminListenerAuth(admin)
+	read := func(handler http.HandlerFunc) http.HandlerFunc {
+		return requireAdminRole(auth, adminRoleRead, handler)
+	}
+	operate := func(handler http.HandlerFunc) http.HandlerFunc {
+		return requireAdminRole(auth, adminRoleOperator, handler)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", serveHealth)
+	mux.HandleFunc("/status", read(serveStatus))
+	mux.HandleFunc("/debug/state", read(serveDebugState))
+	mux.HandleFunc("/debug/vars", read(expvar.Handler().ServeHTTP))
+	if auth != nil {
+		mux.HandleFunc("/events", read(streamEvents))
+	} else if admin.EventsToken != "" {
+		mux.HandleFunc("/events", serveEventStream(admin.EventsToken))
+	}
+	if admin.Pprof {
+		mux.HandleFunc("/debug/pprof/", operate(pprof.Index))
+		mux.HandleFunc("/debug/pprof/cmdline", operate(pprof.Cmdline))
+		mux.HandleFunc("/debug/pprof/profile", operate(pprof.Profile))
+		mux.HandleFunc("/debug/pprof/symbol", operate(pprof.Symbol))
+		mux.HandleFunc("/debug/pprof/trace", operate(pprof.Trace))
+	}
+	return mux
+}
+
+// adminListen listens on a TCP address or, with the "unix:" prefix, a unix domain socket.
+func adminListen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, adminUnixPrefix); ok {
+		os.Remove(path) // Left over if the bot wasn't shut down cleanly
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", address)
+}
+
+// startAdminListener serves the admin endpoints in the background, with TLS if auth has a certificate.
+func startAdminListener(admin *AdminListener) *http.Server {
+	server := &http.Server{Addr: admin.Listen, Handler: newAdminMux(admin), ReadHeaderTimeout: 10 * time.Second}
+	var tlsConfig *tls.Config
+	if admin.Auth != nil {
+		var err error
+		if err = admin.Auth.validate(); err == nil {
+			tlsConfig, err = admin.Auth.tlsConfig()
+		}
+		if err != nil {
+			log.Errorf("Admin listener on %s not started: %v", admin.Listen, err)
+			return server
+		}
+	}
+	listener, err := adminListen(admin.Listen)
+	if err != nil {
+		log.Errorf("Admin listener on %s failed: %v", admin.Listen, err)
+		return server
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	go func() {
+		defer recoverPanic("startAdminListener")
+		log.Infof("Admin listener on %s (pprof: %v).", admin.Listen, admin.Pprof)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Admin listener on %s failed: %v", admin.Listen, err)
+		}
+	}()
+	return server
+}
+
+// adminClient returns a client for the admin listener of a running bot and the base URL to use. With
+// auth, it authenticates with a configured token and trusts the listener's certificate.
+func adminClient(admin *AdminListener) (*http.Client, string, error) {
+	if admin == nil || admin.Listen == "" {
+		return nil, "", fmt.Errorf("admin.listen is not configured, so the running bot cannot be queried")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	scheme, host := "http", admin.Listen
+	if path, ok := strings.CutPrefix(admin.Listen, adminUnixPrefix); ok {
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		}
+		host = "localhost"
+	} else if strings.HasPrefix(host, ":") {
+		host = "127.0.0.1" + host
+	}
+	var roundTripper http.RoundTripper = transport
+	if auth := admin.Auth; auth != nil {
+		if auth.CertFile != "" {
+			roots, err := x509.SystemCertPool()
+			if err != nil {
+				roots = x509.NewCertPool()
+			}
+			if pem, err := os.ReadFile(auth.CertFile); err == nil {
+				roots.AppendCertsFromPEM(pem)
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+			scheme = "https"
+		}
+		if token := adminListenerAuth(admin).adminClientToken(); token != "" {
+			roundTripper = &bearerTransport{token: token, base: transport}
+		}
+	}
+	return &http.Client{Transport: roundTripper, Timeout: notifierHTTPTimeout}, scheme + "://" + host, nil
+}
+
+// checkHealth queries the health endpoint of a running bot, failing unless it reports healthy.
+func checkHealth(admin *AdminListener) error {
+	client, base, err := adminClient(admin)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(base + "/healthz")
+	if err != nil {
+		return fmt.Errorf("failed to query the admin listener: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unhealthy (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
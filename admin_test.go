@@ -0,0 +1,67 @@
+package discord2pushover
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminDebugState(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	globalConfig = &Config{}
+	globalConfig.trackedEmergencies().Store("admin-test-receipt", TrackedEmergencyMessage{})
+	countersFor("AdminTestRule").matched.Add(2)
+
+	recorder := httptest.NewRecorder()
+	newAdminMux(&AdminListener{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", recorder.Code)
+	}
+	var state debugState
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if state.Goroutines == 0 || state.TrackedReceipts < 1 || state.RuleMatches["AdminTestRule"] < 2 {
+		t.Errorf("Unexpected state %+v", state)
+	}
+}
+
+func TestAdminPprofGuarded(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		recorder := httptest.NewRecorder()
+		newAdminMux(&AdminListener{Pprof: enabled}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+		if (recorder.Code == http.StatusOK) != enabled {
+			t.Errorf("pprof enabled=%v: got HTTP %d", enabled, recorder.Code)
+		}
+	}
+}
+
+func TestAdminHealthcheck(t *testing.T) {
+	oldHealth := adminHealth
+	defer func() { adminHealth = oldHealth }()
+	admin := &AdminListener{Listen: "unix:" + filepath.Join(t.TempDir(), "admin.sock")}
+	server := startAdminListener(admin)
+	defer server.Close()
+
+	adminHealth = nil
+	if err := checkHealth(admin); err == nil || !strings.Contains(err.Error(), "starting") {
+		t.Errorf("Expected unhealthy while starting, got %v", err)
+	}
+	adminHealth = func(now time.Time) error { return nil }
+	if err := checkHealth(admin); err != nil {
+		t.Errorf("Expected healthy, got %v", err)
+	}
+	adminHealth = func(now time.Time) error { return errors.New("gateway wedged") }
+	if err := checkHealth(admin); err == nil || !strings.Contains(err.Error(), "HTTP 503): gateway wedged") {
+		t.Errorf("Expected unhealthy, got %v", err)
+	}
+	if err := checkHealth(nil); err == nil {
+		t.Error("Expected an error without admin.listen")
+	}
+}
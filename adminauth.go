@@ -0,0 +1,248 @@
+package discord2pushover
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Roles of admin clients. Operators may do everything readers may.
+const (
+	adminRoleRead     = "read"     // Diagnostics, statistics, rules and events
+	adminRoleOperator = "operator" // Also profiling, importing rules and delivering test messages
+)
+
+// adminPrincipal is an authenticated admin client.
+type adminPrincipal struct {
+	Name string
+	Role string
+}
+
+// allows reports whether the principal's role grants the required role.
+func (p adminPrincipal) allows(role string) bool {
+	return p.Role == adminRoleOperator || p.Role == role
+}
+
+// validAdminRole reports whether a role is one of the admin roles.
+func validAdminRole(role string) bool {
+	return role == adminRoleRead || role == adminRoleOperator
+}
+
+// authenticate identifies a client by its bearer token or, failing that, its verified client
+// certificate. ok is false for unknown clients.
+func (a *AdminAuth) authenticate(token string, state *tls.ConnectionState) (principal adminPrincipal, ok bool) {
+	if token != "" {
+		for _, t := range a.Tokens {
+			if t.Token != "" && validAdminRole(t.Role) && subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+				return adminPrincipal{Name: t.Name, Role: t.Role}, true
+			}
+		}
+	}
+	if state != nil && len(state.VerifiedChains) > 0 {
+		name := state.VerifiedChains[0][0].Subject.CommonName
+		if role := a.ClientRoles[name]; validAdminRole(role) {
+			return adminPrincipal{Name: "cert:" + name, Role: role}, true
+		}
+	}
+	return adminPrincipal{}, false
+}
+
+// validate checks that every client has a known role.
+func (a *AdminAuth) validate() error {
+	for i, t := range a.Tokens {
+		if !validAdminRole(t.Role) {
+			return fmt.Errorf("token %d (%s) has role %q, expected %q or %q", i+1, t.Name, t.Role, adminRoleRead, adminRoleOperator)
+		}
+	}
+	for name, role := range a.ClientRoles {
+		if !validAdminRole(role) {
+			return fmt.Errorf("client certificate %s has role %q, expected %q or %q", name, role, adminRoleRead, adminRoleOperator)
+		}
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of a listener, or nil to serve without TLS. With clientCaFile,
+// client certificates signed by the CA are verified; they are required unless tokens are configured too.
+func (a *AdminAuth) tlsConfig() (*tls.Config, error) {
+	if a.CertFile == "" {
+		if a.ClientCAFile != "" {
+			return nil, errors.New("clientCaFile requires certFile and keyFile")
+		}
+		return nil, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certFile and keyFile: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	if a.ClientCAFile != "" {
+		pem, err := os.ReadFile(a.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read clientCaFile: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in clientCaFile %s", a.ClientCAFile)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if len(a.Tokens) > 0 {
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return config, nil
+}
+
+// adminAuditRecord is a line of the audit file.
+type adminAuditRecord struct {
+	Time      time.Time `json:"time"`
+	Listener  string    `json:"listener"`
+	Principal string    `json:"principal,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	Action    string    `json:"action"`
+	Remote    string    `json:"remote,omitempty"`
+	Allowed   bool      `json:"allowed"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// auditFileMu serializes appends to audit files.
+var auditFileMu sync.Mutex
+
+// audit logs an admin action, allowed or denied, and appends it to the auditFile if one is configured.
+func (a *AdminAuth) audit(record adminAuditRecord) {
+	record.Time = time.Now()
+	if record.Allowed {
+		log.Infof("Audit: %s %s (%s) %s from %s.", record.Listener, record.Principal, record.Role, record.Action, record.Remote)
+	} else {
+		log.Warnf("Audit: %s denied %s from %s: %s.", record.Listener, record.Action, record.Remote, record.Reason)
+	}
+	if a.AuditFile == "" {
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	auditFileMu.Lock()
+	defer auditFileMu.Unlock()
+	file, err := os.OpenFile(a.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Errorf("Error writing audit file %s: %v", a.AuditFile, err)
+		return
+	}
+	defer file.Close()
+	file.Write(append(line, '\n'))
+}
+
+// authorize authenticates a client and checks that it has the role an action requires. Denied
+// requests and actions requiring the operator role are audited; reads are only logged at debug level.
+func (a *AdminAuth) authorize(listener string, token string, state *tls.ConnectionState, remote string, action string, role string) (adminPrincipal, error) {
+	principal, ok := a.authenticate(token, state)
+	record := adminAuditRecord{Listener: listener, Principal: principal.Name, Role: principal.Role, Action: action, Remote: remote}
+	switch {
+	case !ok:
+		record.Reason = "not authenticated"
+		a.audit(record)
+		return principal, errAdminUnauthenticated
+	case !principal.allows(role):
+		record.Reason = fmt.Sprintf("%s has role %s, %s is required", principal.Name, principal.Role, role)
+		a.audit(record)
+		return principal, errAdminForbidden
+	case role == adminRoleOperator:
+		record.Allowed = true
+		a.audit(record)
+	default:
+		log.Debugf("%s: %s (%s) %s from %s.", listener, principal.Name, principal.Role, action, remote)
+	}
+	return principal, nil
+}
+
+var (
+	errAdminUnauthenticated = errors.New("missing or invalid credentials")
+	errAdminForbidden       = errors.New("permission denied")
+)
+
+// bearerToken returns the bearer token of a request. Browsers' EventSource cannot set headers, so the
+// token may also be given as the token query parameter.
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+// requireAdminRole wraps a handler of the admin listener so only clients with role may use it. Without
+// auth the handler is returned as it is.
+func requireAdminRole(auth *AdminAuth, role string, handler http.HandlerFunc) http.HandlerFunc {
+	if auth == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, err := auth.authorize("admin", bearerToken(r), r.TLS, r.RemoteAddr, r.Method+" "+r.URL.Path, role)
+		switch err {
+		case errAdminUnauthenticated:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errAdminForbidden:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			handler(w, r)
+		}
+	}
+}
+
+// peerTLSState returns the remote address of a gRPC call and the TLS state of its connection, which
+// is nil without TLS.
+func peerTLSState(ctx context.Context) (string, *tls.ConnectionState) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	remote := ""
+	if p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		return remote, &info.State
+	}
+	return remote, nil
+}
+
+// adminClientToken returns the token the CLI authenticates to the admin listener with: the first
+// one configured, preferring the read role.
+func (a *AdminAuth) adminClientToken() string {
+	token := ""
+	for _, t := range a.Tokens {
+		if t.Role == adminRoleRead {
+			return t.Token
+		}
+		if token == "" && validAdminRole(t.Role) {
+			token = t.Token
+		}
+	}
+	return token
+}
+
+// bearerTransport adds a bearer token to every request.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(r)
+}
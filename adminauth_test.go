@@ -0,0 +1,81 @@
+package discord2pushover
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/user/discord2pushover/controlpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminAuth(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	admin := &AdminListener{Pprof: true, EventsToken: "events", Auth: &AdminAuth{
+		Tokens:    []AdminToken{{Name: "grafana", Token: "reader", Role: adminRoleRead}, {Name: "oncall", Token: "operator", Role: adminRoleOperator}},
+		AuditFile: auditFile,
+	}}
+	mux := newAdminMux(admin)
+	get := func(path string, token string) int {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	for _, c := range []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{"/healthz", "", http.StatusServiceUnavailable}, // Not authenticated, not yet healthy
+		{"/status", "", http.StatusUnauthorized},
+		{"/status", "wrong", http.StatusUnauthorized},
+		{"/status", "reader", http.StatusOK},
+		{"/debug/vars", "events", http.StatusOK},
+		{"/debug/pprof/", "reader", http.StatusForbidden},
+		{"/debug/pprof/", "operator", http.StatusOK},
+	} {
+		if code := get(c.path, c.token); code != c.want {
+			t.Errorf("GET %s with token %q: expected %d, got %d", c.path, c.token, c.want, code)
+		}
+	}
+	audit, _ := os.ReadFile(auditFile)
+	if lines := strings.Split(strings.TrimSpace(string(audit)), "\n"); len(lines) != 4 ||
+		!strings.Contains(lines[2], `"reason":"grafana has role read, operator is required"`) || !strings.Contains(lines[3], `"principal":"oncall","role":"operator","action":"GET /debug/pprof/"`) {
+		t.Errorf("Expected the denied requests and the operator's action audited, got:\n%s", audit)
+	}
+
+	admin.Auth.ClientRoles = map[string]string{"ci": adminRoleOperator}
+	state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "ci"}}}}}
+	if principal, ok := admin.Auth.authenticate("", state); !ok || principal.Name != "cert:ci" || principal.Role != adminRoleOperator {
+		t.Errorf("Expected the client certificate's role, got %+v", principal)
+	}
+	if err := (&AdminAuth{Tokens: []AdminToken{{Token: "x", Role: "admin"}}}).validate(); err == nil {
+		t.Error("Expected an unknown role to be refused")
+	}
+
+	auth := controlPlaneAuth(&ControlPlane{Token: "s3cret", Auth: &AdminAuth{Tokens: []AdminToken{{Name: "dashboard", Token: "reader", Role: adminRoleRead}}}})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer reader"))
+	if err := authorizeControlPlane(ctx, auth, controlpb.ControlPlane_TestMessage_FullMethodName, &controlpb.TestMessageRequest{}); err != nil {
+		t.Errorf("Expected a reader to test messages, got %v", err)
+	}
+	if err := authorizeControlPlane(ctx, auth, controlpb.ControlPlane_TestMessage_FullMethodName, &controlpb.TestMessageRequest{Deliver: true}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected a reader not to deliver test messages, got %v", err)
+	}
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer s3cret"))
+	if err := authorizeControlPlane(ctx, auth, controlpb.ControlPlane_ImportRules_FullMethodName, nil); err != nil {
+		t.Errorf("Expected the control plane token to import rules, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package discord2pushover
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	defaultBackfillMessages = 20
+	maxBackfillMessages     = 100 // Discord's page size for channel messages
+	defaultBackfillMaxAge   = time.Hour
+)
+
+// backfillChannelIDs returns the channels of message rules, in a stable order. Rules without a
+// channelId can't be backfilled, since any channel may match them.
+func backfillChannelIDs(config *Config) []string {
+	seen := make(map[string]bool)
+	var channelIDs []string
+	for _, rule := range config.Rules {
+		channelID := rule.Conditions.ChannelID
+		if channelID == "" || ruleEvent(&rule) != ruleEventMessage || seen[channelID] {
+			continue
+		}
+		seen[channelID] = true
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+	return channelIDs
+}
+
+// fetchSinceCheckpoint returns the messages of a channel newer than the checkpoint, newest first. If
+// more than limit messages were posted since, only the newest limit are returned.
+func fetchSinceCheckpoint(session DiscordSessionInterface, channelID string, checkpoint string, limit int) ([]*discordgo.Message, error) {
+	var messages []*discordgo.Message
+	beforeID := ""
+	for len(messages) < limit {
+		pageSize := min(limit-len(messages), maxBackfillMessages)
+		page, err := session.ChannelMessages(channelID, pageSize, beforeID, "", "")
+		if err != nil {
+			return messages, err
+		}
+		for _, message := range page {
+			if !snowflakeAfter(message.ID, checkpoint) {
+				return messages, nil
+			}
+			messages = append(messages, message)
+		}
+		if len(page) < pageSize {
+			return messages, nil
+		}
+		beforeID = page[len(page)-1].ID
+	}
+	log.Warnf("Backfill: at least %d messages were posted in channel %s since the last checkpoint; any older ones are skipped.", limit, channelID)
+	return messages, nil
+}
+
+// backfillMessages runs the messages of the rules' channels that were missed while offline through the
+// rules, oldest first and marked as late. With a checkpoint, these are the messages since the last one
+// processed (up to catchUpLimit); otherwise the recent ones not older than maxAgeMinutes. Messages the
+// bot already reacted to are deduplicated like edits. Returns the number of messages processed.
+func backfillMessages(session DiscordSessionInterface, config *Config, now time.Time) int {
+	backfill := config.Backfill
+	limit := backfill.Messages
+	if limit <= 0 {
+		limit = defaultBackfillMessages
+	}
+	limit = min(limit, maxBackfillMessages)
+	catchUpLimit := backfill.CatchUpLimit
+	if catchUpLimit <= 0 {
+		catchUpLimit = defaultCatchUpLimit
+	}
+	maxAge := defaultBackfillMaxAge
+	if backfill.MaxAgeMinutes > 0 {
+		maxAge = time.Duration(backfill.MaxAgeMinutes) * time.Minute
+	}
+	botID := ""
+	if state := session.State(); state != nil && state.User != nil {
+		botID = state.User.ID
+	}
+
+	processed := 0
+	channelIDs := backfillChannelIDs(config)
+	for _, channelID := range channelIDs {
+		var messages []*discordgo.Message
+		var err error
+		checkpoint := checkpoints.last(channelID)
+		if checkpoint != "" {
+			messages, err = fetchSinceCheckpoint(session, channelID, checkpoint, catchUpLimit)
+		} else {
+			messages, err = session.ChannelMessages(channelID, limit, "", "", "")
+		}
+		if err != nil {
+			log.Errorf("Backfill: error fetching recent messages of channel %s: %v", channelID, err)
+			if len(messages) == 0 {
+				continue
+			}
+		}
+		guildID := ""
+		if state := session.State(); state != nil {
+			if channel, err := state.Channel(channelID); err == nil {
+				guildID = channel.GuildID
+			}
+		}
+		// Discord returns the newest message first
+		for i := len(messages) - 1; i >= 0; i-- {
+			message := messages[i]
+			if message.Author != nil && message.Author.ID == botID {
+				continue
+			}
+			if checkpoint == "" && now.Sub(messageTime(message)) > maxAge {
+				continue
+			}
+			if message.GuildID == "" {
+				message.GuildID = guildID // Not included in REST responses, needed for links
+			}
+			ctx, cancel := eventContext(config)
+			processRulesForEvent(ctx, ruleEventMessage, message, &EventDetails{Late: true}, config, session, notifiedPriorityFromReactions(config, message))
+			cancel()
+			checkpointMessage(config, channelID, message.ID)
+			processed++
+		}
+	}
+	log.Infof("Backfill: processed %d missed messages from %d channels.", processed, len(channelIDs))
+	return processed
+}
+
+// runBackfill is started once the Discord session is open.
+func runBackfill(session DiscordSessionInterface, config *Config) {
+	defer recoverPanic("runBackfill")
+	backfillMessages(session, config, time.Now())
+}
@@ -0,0 +1,64 @@
+package discord2pushover
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestBackfillMessages(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() {
+		testHookDisablePushoverSend = false
+		testHookPushoverSendCalled = false
+	}()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	config := &Config{
+		Backfill: &Backfill{Messages: 10, MaxAgeMinutes: 30},
+		Rules: []Rule{
+			{Name: "Alerts", Conditions: RuleConditions{ChannelID: "alerts", ContentIncludes: []string{"FIRING"}},
+				Actions: RuleActions{PushoverDestination: "user", ReactionEmoji: EmojiList{"📟"}}},
+			{Name: "Anywhere", Conditions: RuleConditions{ContentIncludes: []string{"help"}}},
+			{Name: "Pins", Event: ruleEventPin, Conditions: RuleConditions{ChannelID: "pins"}},
+		},
+	}
+	if got := strings.Join(backfillChannelIDs(config), ","); got != "alerts" {
+		t.Fatalf("Expected only the message rule's channel to be backfilled, got %q", got)
+	}
+
+	var limit int
+	session := mockSessionForRulesTest("bot").(*MockDiscordSession)
+	session.CustomChannelMessagesFunc = func(channelID string, l int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error) {
+		limit = l
+		author := &discordgo.User{ID: "grafana", Username: "grafana"}
+		return []*discordgo.Message{ // Newest first
+			{ID: "new", ChannelID: "alerts", Content: "FIRING new", Author: author, Timestamp: now.Add(-5 * time.Minute)},
+			{ID: "notified", ChannelID: "alerts", Content: "FIRING notified", Author: author, Timestamp: now.Add(-10 * time.Minute),
+				Reactions: []*discordgo.MessageReactions{{Me: true, Emoji: &discordgo.Emoji{Name: "📟"}}}},
+			{ID: "own", ChannelID: "alerts", Content: "FIRING own", Author: &discordgo.User{ID: "bot"}, Timestamp: now.Add(-10 * time.Minute)},
+			{ID: "old", ChannelID: "alerts", Content: "FIRING old", Author: author, Timestamp: now.Add(-2 * time.Hour)},
+		}, nil
+	}
+
+	if processed := backfillMessages(session, config, now); processed != 2 {
+		t.Errorf("Expected 2 messages to be processed, got %d", processed)
+	}
+	if limit != 10 {
+		t.Errorf("Expected 10 messages to be fetched, got %d", limit)
+	}
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, "Pushover notification sent for rule 'Alerts' (message ID new)") {
+		t.Errorf("Expected the missed alert to be notified. Logs:\n%s", logs)
+	}
+	if !strings.Contains(logs, "Suppressing Pushover notification for rule 'Alerts' (Priority: 0) on message ID notified") {
+		t.Errorf("Expected the already notified alert to be suppressed. Logs:\n%s", logs)
+	}
+	if strings.Contains(logs, "message ID old") || strings.Contains(logs, "message ID own") {
+		t.Errorf("Expected old and own messages to be skipped. Logs:\n%s", logs)
+	}
+}
@@ -0,0 +1,194 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultReplyPollInterval is how often the reply bridge fetches new messages when pollIntervalSeconds is unset.
+const defaultReplyPollInterval = 30 * time.Second
+
+// replyCodeTTL is how long a notification can be replied to.
+const replyCodeTTL = 24 * time.Hour
+
+// replyTarget is the Discord message a reply code refers to.
+type replyTarget struct {
+	ChannelID string
+	MessageID string
+	Expires   time.Time
+}
+
+// replyRegistry hands out short reply codes for sent notifications.
+type replyRegistry struct {
+	mu      sync.Mutex
+	next    uint64
+	targets map[string]replyTarget
+}
+
+var replyTargets = &replyRegistry{targets: make(map[string]replyTarget)}
+
+// register returns a new reply code for a notification about the given message, forgetting expired codes.
+func (r *replyRegistry) register(channelID string, messageID string, now time.Time) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for code, target := range r.targets {
+		if now.After(target.Expires) {
+			delete(r.targets, code)
+		}
+	}
+	r.next++
+	code := strconv.FormatUint(r.next, 36)
+	r.targets[code] = replyTarget{ChannelID: channelID, MessageID: messageID, Expires: now.Add(replyCodeTTL)}
+	return code
+}
+
+// lookup returns the target of a reply code.
+func (r *replyRegistry) lookup(code string, now time.Time) (replyTarget, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	target, ok := r.targets[strings.ToLower(code)]
+	if !ok || now.After(target.Expires) {
+		return replyTarget{}, false
+	}
+	return target, true
+}
+
+// withReplyCode appends the reply instructions to a notification body.
+func withReplyCode(body string, code string) string {
+	if code == "" {
+		return body
+	}
+	return fmt.Sprintf("%s\n\nReply code: %s", body, code)
+}
+
+// parseReply splits a reply into its code and text: "#3f on it" or "3f: on it".
+func parseReply(text string) (code string, reply string) {
+	word, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+	code = strings.TrimSuffix(strings.TrimPrefix(word, "#"), ":")
+	return strings.ToLower(code), strings.TrimSpace(rest)
+}
+
+// openClientMessage is a message delivered to an Open Client device.
+type openClientMessage struct {
+	ID      int64  `json:"id"`
+	Message string `json:"message"`
+	Title   string `json:"title"`
+	App     string `json:"app"`
+}
+
+// fetchOpenClientMessages downloads the messages pending for the bridge's device from the Open Client
+// API, which is served along with the Pushover API.
+func fetchOpenClientMessages(ctx context.Context, config *Config) ([]openClientMessage, error) {
+	bridge := config.ReplyBridge
+	query := url.Values{"secret": {bridge.Secret}, "device_id": {bridge.DeviceID}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, config.pushoverBase()+"/messages.json?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := newHTTPClient(config).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Open Client messages: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Status   int                 `json:"status"`
+		Messages []openClientMessage `json:"messages"`
+		Errors   []string            `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode Open Client messages: %w", err)
+	}
+	if result.Status != 1 {
+		return nil, fmt.Errorf("open Client API error: status %d, errors: %v", result.Status, result.Errors)
+	}
+	return result.Messages, nil
+}
+
+// deleteOpenClientMessages removes messages up to and including highestID from the device.
+func deleteOpenClientMessages(ctx context.Context, config *Config, highestID int64) error {
+	bridge := config.ReplyBridge
+	form := url.Values{"secret": {bridge.Secret}, "message": {strconv.FormatInt(highestID, 10)}}
+	endpoint := config.pushoverBase() + "/devices/" + url.PathEscape(bridge.DeviceID) + "/update_highest_message.json"
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := newHTTPClient(config).Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to delete Open Client messages: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete Open Client messages: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// bridgeReplies fetches pending replies once, posts those with a known reply code into Discord and
+// deletes them from the device. Returns the number of replies posted.
+func bridgeReplies(ctx context.Context, session DiscordSessionInterface, config *Config) (int, error) {
+	messages, err := fetchOpenClientMessages(ctx, config)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+	posted := 0
+	var highestID int64
+	for _, m := range messages {
+		if m.ID > highestID {
+			highestID = m.ID
+		}
+		code, text := parseReply(m.Message)
+		target, ok := replyTargets.lookup(code, time.Now())
+		if !ok || text == "" {
+			log.Warnf("Ignoring Pushover reply %d without a valid reply code: %.50s", m.ID, m.Message)
+			continue
+		}
+		content := truncateRunes(fmt.Sprintf("📨 Reply via Pushover: %s", text), 2000) // Discord message limit
+		reference := &discordgo.MessageReference{ChannelID: target.ChannelID, MessageID: target.MessageID}
+		if _, err := session.ChannelMessageSendReply(target.ChannelID, content, reference); err != nil {
+			log.Errorf("Error posting Pushover reply %d to channel %s: %v", m.ID, target.ChannelID, err)
+			continue
+		}
+		log.Infof("Posted Pushover reply %d to Discord message %s (channel %s).", m.ID, target.MessageID, target.ChannelID)
+		posted++
+	}
+	return posted, deleteOpenClientMessages(ctx, config, highestID)
+}
+
+// pollReplyBridge periodically bridges replies from the Open Client device into Discord until ctx is done.
+func pollReplyBridge(ctx context.Context, session DiscordSessionInterface, config *Config) {
+	defer recoverPanic("pollReplyBridge")
+	bridge := config.ReplyBridge
+	if bridge.Secret == "" || bridge.DeviceID == "" {
+		log.Error("replyBridge requires secret and deviceId; reply bridge disabled.")
+		return
+	}
+	interval := defaultReplyPollInterval
+	if bridge.PollIntervalSeconds > 0 {
+		interval = time.Duration(bridge.PollIntervalSeconds) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Infof("Starting Pushover reply bridge (interval: %s)...", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := bridgeReplies(ctx, session, config); err != nil && ctx.Err() == nil {
+			log.Errorf("Reply bridge: %v", err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestBridgeReplies(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	code := replyTargets.register("ops", "alertMsg", time.Now())
+	if body := withReplyCode("disk full", code); body != "disk full\n\nReply code: "+code {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	var deletedUpTo string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/messages.json":
+			if r.URL.Query().Get("secret") != "s3cret" || r.URL.Query().Get("device_id") != "dev1" {
+				w.Write([]byte(`{"status":0,"errors":["invalid secret"]}`))
+				return
+			}
+			fmt.Fprintf(w, `{"status":1,"messages":[{"id":7,"message":"#%s on it, rebooting"},{"id":9,"message":"zz unknown code"}]}`, strings.ToUpper(code))
+		case "/devices/dev1/update_highest_message.json":
+			r.ParseForm()
+			deletedUpTo = r.PostForm.Get("message")
+			w.Write([]byte(`{"status":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+	config := &Config{PushoverAPIBase: server.URL, ReplyBridge: &ReplyBridge{Secret: "s3cret", DeviceID: "dev1"}}
+	posted, err := bridgeReplies(context.Background(), session, config)
+	if err != nil || posted != 1 {
+		t.Fatalf("Expected 1 reply posted, got %d, %v", posted, err)
+	}
+	if deletedUpTo != "9" {
+		t.Errorf("Expected messages up to 9 to be deleted, got %q", deletedUpTo)
+	}
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, "ChannelMessageSendReply called with: chID=ops, replyTo=alertMsg, content=📨 Reply via Pushover: on it, rebooting") {
+		t.Errorf("Expected the reply to be posted to the alert. Logs:\n%s", logs)
+	}
+
+	config.ReplyBridge = &ReplyBridge{Secret: "wrong", DeviceID: "dev1"}
+	if _, err := bridgeReplies(context.Background(), session, config); err == nil {
+		t.Error("Expected an error for a rejected secret")
+	}
+}
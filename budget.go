@@ -0,0 +1,137 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// budgetGlobalKey identifies the global budget in budgetTracker.
+const budgetGlobalKey = "\x00global"
+
+// budgetGuildKeyPrefix is followed by the guild ID in budgetTracker's keys of guild budgets.
+const budgetGuildKeyPrefix = "\x00guild:"
+
+// budgetWindow tracks the sends counted against one budget and whether its exhaustion was reported.
+type budgetWindow struct {
+	sends    []time.Time // Oldest first, pruned to the last day
+	exceeded bool
+}
+
+// count returns the number of sends since the given time.
+func (w *budgetWindow) count(since time.Time) int {
+	n := 0
+	for i := len(w.sends) - 1; i >= 0 && w.sends[i].After(since); i-- {
+		n++
+	}
+	return n
+}
+
+// available reports whether another send fits the budget's hourly and daily caps.
+func (w *budgetWindow) available(budget *Budget, now time.Time) bool {
+	if budget.MaxPerHour > 0 && w.count(now.Add(-time.Hour)) >= budget.MaxPerHour {
+		return false
+	}
+	if budget.MaxPerDay > 0 && w.count(now.Add(-24*time.Hour)) >= budget.MaxPerDay {
+		return false
+	}
+	return true
+}
+
+// budgetTracker counts notifications against the global, per-guild and per-rule budgets.
+type budgetTracker struct {
+	mu      sync.Mutex
+	windows map[string]*budgetWindow
+}
+
+var budgets = &budgetTracker{windows: make(map[string]*budgetWindow)}
+
+func (t *budgetTracker) window(key string, now time.Time) *budgetWindow {
+	w, ok := t.windows[key]
+	if !ok {
+		w = &budgetWindow{}
+		t.windows[key] = w
+	}
+	cutoff := now.Add(-24 * time.Hour)
+	i := 0
+	for i < len(w.sends) && !w.sends[i].After(cutoff) {
+		i++
+	}
+	w.sends = w.sends[i:]
+	return w
+}
+
+// allow counts a notification for the rule if the global budget, the budget of the message's guild
+// (see Config.Tenants) and the rule's budget have room. When a budget is exhausted it returns false,
+// plus a description of the exhausted budget the first time so the caller can report it once per
+// exhaustion; the report is re-armed when the budget has room again.
+func (t *budgetTracker) allow(config *Config, rule *Rule, ruleNameLog string, guildID string, now time.Time) (ok bool, exceeded []string) {
+	var tenantBudget *Budget
+	if tenant := tenantFor(config, guildID); tenant != nil {
+		tenantBudget = tenant.Budget
+	}
+	if config.Budget == nil && tenantBudget == nil && rule.Budget == nil {
+		return true, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	type check struct {
+		budget *Budget
+		window *budgetWindow
+		name   string
+	}
+	var checks []check
+	if config.Budget != nil {
+		checks = append(checks, check{config.Budget, t.window(budgetGlobalKey, now), "Global notification budget"})
+	}
+	if tenantBudget != nil {
+		checks = append(checks, check{tenantBudget, t.window(budgetGuildKeyPrefix+guildID, now), fmt.Sprintf("Notification budget of guild %s", guildID)})
+	}
+	if rule.Budget != nil {
+		checks = append(checks, check{rule.Budget, t.window(ruleNameLog, now), fmt.Sprintf("Notification budget of rule '%s'", ruleNameLog)})
+	}
+
+	ok = true
+	for _, c := range checks {
+		if c.window.available(c.budget, now) {
+			c.window.exceeded = false
+			continue
+		}
+		ok = false
+		if !c.window.exceeded {
+			c.window.exceeded = true
+			exceeded = append(exceeded, fmt.Sprintf("%s exceeded (max %s); further notifications are suppressed until it has room again.",
+				c.name, describeBudget(c.budget)))
+		}
+	}
+	if ok {
+		for _, c := range checks {
+			c.window.sends = append(c.window.sends, now)
+		}
+	}
+	return ok, exceeded
+}
+
+func describeBudget(budget *Budget) string {
+	switch {
+	case budget.MaxPerHour > 0 && budget.MaxPerDay > 0:
+		return fmt.Sprintf("%d per hour, %d per day", budget.MaxPerHour, budget.MaxPerDay)
+	case budget.MaxPerHour > 0:
+		return fmt.Sprintf("%d per hour", budget.MaxPerHour)
+	default:
+		return fmt.Sprintf("%d per day", budget.MaxPerDay)
+	}
+}
+
+// guildSendsSince returns how many notifications were counted against a guild's budget since the
+// given time.
+func (t *budgetTracker) guildSendsSince(guildID string, since time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.windows[budgetGuildKeyPrefix+guildID]
+	if !ok {
+		return 0
+	}
+	return w.count(since)
+}
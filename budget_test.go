@@ -0,0 +1,84 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestBudgetTrackerAllow(t *testing.T) {
+	tracker := &budgetTracker{windows: make(map[string]*budgetWindow)}
+	config := &Config{Budget: &Budget{MaxPerDay: 3}}
+	noisy := &Rule{Budget: &Budget{MaxPerHour: 2}}
+	quiet := &Rule{}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if ok, exceeded := tracker.allow(config, noisy, "noisy", "", start); !ok || exceeded != nil {
+			t.Fatalf("Send %d should fit the budgets", i+1)
+		}
+	}
+	ok, exceeded := tracker.allow(config, noisy, "noisy", "", start.Add(time.Minute))
+	if ok || len(exceeded) != 1 || !strings.Contains(exceeded[0], "rule 'noisy'") {
+		t.Fatalf("Expected the rule budget to be exceeded once, got %v %v", ok, exceeded)
+	}
+	if ok, exceeded := tracker.allow(config, noisy, "noisy", "", start.Add(2*time.Minute)); ok || exceeded != nil {
+		t.Errorf("Expected a silent suppression after the first report, got %v %v", ok, exceeded)
+	}
+
+	// Other rules still have room in the global budget until it is used up too
+	if ok, _ := tracker.allow(config, quiet, "quiet", "", start.Add(3*time.Minute)); !ok {
+		t.Error("Expected another rule to be allowed")
+	}
+	ok, exceeded = tracker.allow(config, quiet, "quiet", "", start.Add(4*time.Minute))
+	if ok || len(exceeded) != 1 || !strings.HasPrefix(exceeded[0], "Global notification budget exceeded (max 3 per day)") {
+		t.Errorf("Expected the global budget to be exceeded, got %v %v", ok, exceeded)
+	}
+
+	// A day later both budgets have room again and exhaustion is reported anew
+	nextDay := start.Add(25 * time.Hour)
+	if ok, _ := tracker.allow(config, noisy, "noisy", "", nextDay); !ok {
+		t.Error("Expected the budgets to have room after a day")
+	}
+	tracker.allow(config, noisy, "noisy", "", nextDay)
+	if _, exceeded := tracker.allow(config, noisy, "noisy", "", nextDay); len(exceeded) != 1 {
+		t.Errorf("Expected the exhausted rule budget to be reported again, got %v", exceeded)
+	}
+}
+
+func TestProcessRules_BudgetExceeded(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() {
+		testHookDisablePushoverSend = false
+		testHookPushoverSendCalled = false
+		budgets = &budgetTracker{windows: make(map[string]*budgetWindow)}
+	}()
+
+	config := &Config{
+		ErrorNotification: &ErrorNotification{DiscordChannelID: "meta"},
+		Rules: []Rule{{
+			Name:       "Runaway",
+			Conditions: RuleConditions{ChannelID: "spam"},
+			Actions:    RuleActions{PushoverDestination: "user"},
+			Budget:     &Budget{MaxPerHour: 1},
+		}},
+	}
+	session := mockSessionForRulesTest("bot")
+	for _, id := range []string{"m1", "m2", "m3"} {
+		testHookPushoverSendCalled = false
+		ProcessRules(context.Background(), &discordgo.Message{ID: id, ChannelID: "spam", Content: "spam"}, config, session, math.MaxInt32)
+		if sent := testHookPushoverSendCalled; sent != (id == "m1") {
+			t.Errorf("Message %s: Pushover sent = %v", id, sent)
+		}
+	}
+	logs := testLogBufferForTest.String()
+	if n := strings.Count(logs, "ChannelMessageSend called with: chID=meta"); n != 1 {
+		t.Errorf("Expected exactly one budget meta-alert, got %d. Logs:\n%s", n, logs)
+	}
+}
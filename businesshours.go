@@ -0,0 +1,161 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Values of RuleConditions.Schedule.
+const (
+	scheduleBusinessHours = "businessHours" // Within businessHours on a working day that is not a holiday
+	scheduleOffHours      = "offHours"      // Any other time, including holidays
+)
+
+// defaultHolidayPollInterval is how often the holiday calendar is fetched when pollIntervalMinutes is not set.
+const defaultHolidayPollInterval = 6 * time.Hour
+
+// maxHolidaySpan bounds the days a single calendar event can mark as holidays.
+const maxHolidaySpan = 366
+
+var defaultBusinessDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+// holidayCalendar holds the dates ("2006-01-02") of the last fetched holiday calendar.
+var holidayCalendar = struct {
+	sync.Mutex
+	dates map[string]bool
+}{}
+
+// holidayDates returns the dates covered by calendar events, taking all-day events' dates as given.
+func holidayDates(events []calendarEvent) map[string]bool {
+	dates := make(map[string]bool)
+	for _, event := range events {
+		day := time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, time.UTC)
+		for i := 0; i < maxHolidaySpan && (i == 0 || day.Before(event.End)); i++ {
+			dates[day.Format(time.DateOnly)] = true
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+	return dates
+}
+
+// isHoliday reports whether date ("2006-01-02") is one of the static holidays or in the holiday calendar.
+func isHoliday(hours *BusinessHours, date string) bool {
+	for _, holiday := range hours.Holidays {
+		if holiday == date {
+			return true
+		}
+	}
+	if hours.HolidayCalendarURL == "" {
+		return false
+	}
+	holidayCalendar.Lock()
+	defer holidayCalendar.Unlock()
+	return holidayCalendar.dates[date]
+}
+
+// parseClock parses a time of day such as "09:00" into its offset from midnight.
+func parseClock(clock string, fallback time.Duration) (time.Duration, error) {
+	if clock == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: expected a time like 09:00", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// inBusinessHours reports whether now falls within business hours, and if not, why.
+func inBusinessHours(hours *BusinessHours, now time.Time) (bool, string, error) {
+	loc := time.UTC
+	if hours.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(hours.Timezone); err != nil {
+			return false, "", fmt.Errorf("invalid timezone %q: %w", hours.Timezone, err)
+		}
+	}
+	start, err := parseClock(hours.Start, 9*time.Hour)
+	if err != nil {
+		return false, "", err
+	}
+	end, err := parseClock(hours.End, 17*time.Hour)
+	if err != nil {
+		return false, "", err
+	}
+	local := now.In(loc)
+	date := local.Format(time.DateOnly)
+	if isHoliday(hours, date) {
+		return false, date + " is a holiday", nil
+	}
+	days := hours.Days
+	if len(days) == 0 {
+		days = defaultBusinessDays
+	}
+	weekday := strings.ToLower(local.Weekday().String()[:3])
+	if !containsFold(days, weekday) {
+		return false, weekday + " is not a business day", nil
+	}
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if clock < start || clock >= end {
+		return false, local.Format("15:04") + " is outside business hours", nil
+	}
+	return true, local.Format("15:04") + " on a business day", nil
+}
+
+// checkScheduleCondition evaluates the schedule condition against the config's business hours at now.
+// Like event conditions, it is only checked at the top level of a rule's conditions and in score signals.
+func checkScheduleCondition(config *Config, conditions *RuleConditions, now time.Time, ruleNameLog string) (bool, string) {
+	if conditions.Schedule == "" {
+		return true, ""
+	}
+	logPrefix := fmt.Sprintf("Rule '%s': ", ruleNameLog)
+	if config.BusinessHours == nil {
+		log.Errorf("Rule '%s' has a schedule condition but the config has no businessHours.", ruleNameLog)
+		return conditionFailed(logPrefix, "Schedule", "no businessHours configured")
+	}
+	inHours, why, err := inBusinessHours(config.BusinessHours, now)
+	if err != nil {
+		log.Errorf("Rule '%s': businessHours: %v", ruleNameLog, err)
+		return conditionFailed(logPrefix, "Schedule", "%v", err)
+	}
+	if inHours != (conditions.Schedule == scheduleBusinessHours) {
+		return conditionFailed(logPrefix, "Schedule", "not %s: %s", conditions.Schedule, why)
+	}
+	log.Debugf(logPrefix+"Condition passed (Schedule): %s, %s", conditions.Schedule, why)
+	return true, ""
+}
+
+// pollHolidayCalendar keeps the holiday calendar current until ctx is done. A failed fetch keeps the
+// previous calendar.
+func pollHolidayCalendar(ctx context.Context, config *Config) {
+	defer recoverPanic("pollHolidayCalendar")
+	interval := defaultHolidayPollInterval
+	if config.BusinessHours.PollIntervalMinutes > 0 {
+		interval = time.Duration(config.BusinessHours.PollIntervalMinutes) * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Infof("Polling holiday calendar every %s...", interval)
+	for {
+		if events, err := fetchCalendar(ctx, config, config.BusinessHours.HolidayCalendarURL); err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("Holiday calendar: %v", err)
+			}
+		} else {
+			dates := holidayDates(events)
+			holidayCalendar.Lock()
+			holidayCalendar.dates = dates
+			holidayCalendar.Unlock()
+			log.Debugf("Holiday calendar: %d holidays loaded.", len(dates))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestInBusinessHours(t *testing.T) {
+	const calendar = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20240501\r\n" +
+		"DTEND;VALUE=DATE:20240502\r\n" +
+		"SUMMARY:Labour Day\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20241224\r\n" +
+		"DTEND;VALUE=DATE:20241227\r\n" +
+		"SUMMARY:Christmas\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	events, err := parseCalendar(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("parseCalendar: %v", err)
+	}
+	holidayCalendar.Lock()
+	holidayCalendar.dates = holidayDates(events)
+	holidayCalendar.Unlock()
+	defer func() {
+		holidayCalendar.Lock()
+		holidayCalendar.dates = nil
+		holidayCalendar.Unlock()
+	}()
+
+	hours := &BusinessHours{
+		Timezone:           "Europe/Berlin",
+		Start:              "08:30",
+		End:                "18:00",
+		Holidays:           []string{"2024-10-03"},
+		HolidayCalendarURL: "https://example.com/holidays.ics",
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"Working day", time.Date(2024, 4, 30, 10, 0, 0, 0, time.UTC), true},
+		{"Start in the timezone", time.Date(2024, 4, 30, 6, 30, 0, 0, time.UTC), true},
+		{"Before start", time.Date(2024, 4, 30, 6, 29, 0, 0, time.UTC), false},
+		{"At end", time.Date(2024, 4, 30, 16, 0, 0, 0, time.UTC), false},
+		{"Weekend", time.Date(2024, 5, 4, 10, 0, 0, 0, time.UTC), false},
+		{"Calendar holiday", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"Multi-day calendar holiday", time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC), false},
+		{"Day after a calendar holiday", time.Date(2024, 12, 27, 10, 0, 0, 0, time.UTC), true},
+		{"Static holiday", time.Date(2024, 10, 3, 10, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, why, err := inBusinessHours(hours, tt.now)
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %v, got %v (%s, err %v)", tt.want, got, why, err)
+			}
+		})
+	}
+
+	if _, _, err := inBusinessHours(&BusinessHours{Start: "9am"}, time.Now()); err == nil {
+		t.Error("Expected an error for an invalid start")
+	}
+}
+
+func TestProcessRules_ScheduleCondition(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		// Today is a holiday, so it is off-hours all day
+		BusinessHours: &BusinessHours{Holidays: []string{time.Now().UTC().Format(time.DateOnly)}},
+		Rules: []Rule{
+			{
+				Name:       "Working hours",
+				Conditions: RuleConditions{ChannelID: "alerts", Schedule: scheduleBusinessHours},
+				Actions:    RuleActions{PushoverDestination: "uTeam"},
+			},
+			{
+				Name:       "Off hours",
+				Conditions: RuleConditions{ChannelID: "alerts", Schedule: scheduleOffHours},
+				Actions:    RuleActions{PushoverDestination: "uOnCall", Priority: 1},
+			},
+		},
+	}
+	config.SetPushoverClient(fake)
+
+	message := &discordgo.Message{ID: "m1", ChannelID: "alerts", Content: "disk full", Author: &discordgo.User{ID: "dave"}}
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	if len(fake.recipients) != 1 || fake.recipients[0] != "uOnCall" || fake.sent[0].Priority != 1 {
+		t.Errorf("Expected the off-hours rule to notify on a holiday, got %v", fake.recipients)
+	}
+}
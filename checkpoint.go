@@ -0,0 +1,181 @@
+package discord2pushover
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// checkpointFlushInterval is how often changed checkpoints are written to the state file.
+const checkpointFlushInterval = 10 * time.Second
+
+// defaultCatchUpLimit bounds how many messages per channel are caught up from a checkpoint.
+const defaultCatchUpLimit = 500
+
+// stateFileContent is the JSON document stored in the state file.
+type stateFileContent struct {
+	Channels    map[string]string       `json:"channels"`              // Channel ID to the last processed message ID
+	Subscribers map[string]string       `json:"subscribers,omitempty"` // Discord user ID to the Pushover user key they registered
+	Reminders   []pendingReminder       `json:"reminders,omitempty"`   // Reminders of rules with remindAfterMinutes not due yet
+	GuildRules  map[string]guildRuleSet `json:"guildRules,omitempty"`  // Guild ID to the rules its admins manage
+}
+
+// checkpointStore remembers the last processed message per monitored channel, the Pushover user
+// keys registered for subscriptions, pending reminders and the rules guild admins manage.
+type checkpointStore struct {
+	mu          sync.Mutex
+	path        string
+	channels    map[string]string
+	subscribers map[string]string
+	reminders   map[string]pendingReminder
+	guildRules  map[string]guildRuleSet
+	dirty       bool
+}
+
+var checkpoints = &checkpointStore{channels: make(map[string]string), subscribers: make(map[string]string)}
+
+// snowflakeAfter reports whether snowflake a is newer than b. Any valid ID is newer than an empty one.
+func snowflakeAfter(a string, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA != nil {
+		return false
+	}
+	return errB != nil || x > y
+}
+
+// load reads the checkpoints from the state file. A missing file starts with no checkpoints.
+func (c *checkpointStore) load(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.path = path
+	c.channels = make(map[string]string)
+	c.subscribers = make(map[string]string)
+	c.reminders = make(map[string]pendingReminder)
+	c.guildRules = make(map[string]guildRuleSet)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	var content stateFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	for channelID, messageID := range content.Channels {
+		c.channels[channelID] = messageID
+	}
+	for userID, userKey := range content.Subscribers {
+		c.subscribers[userID] = userKey
+	}
+	for _, reminder := range content.Reminders {
+		c.reminders[reminderKey(reminder.RuleName, reminder.MessageID)] = reminder
+	}
+	for guildID, set := range content.GuildRules {
+		c.guildRules[guildID] = set
+	}
+	return nil
+}
+
+// last returns the last processed message of a channel, or "" if there is no checkpoint.
+func (c *checkpointStore) last(channelID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.channels[channelID]
+}
+
+// record moves a channel's checkpoint forward to messageID. Older messages (e.g. edits) are ignored.
+func (c *checkpointStore) record(channelID string, messageID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" || !snowflakeAfter(messageID, c.channels[channelID]) {
+		return
+	}
+	c.channels[channelID] = messageID
+	c.dirty = true
+}
+
+// flush writes changed checkpoints to the state file, replacing it atomically.
+func (c *checkpointStore) flush() error {
+	c.mu.Lock()
+	if !c.dirty || c.path == "" {
+		c.mu.Unlock()
+		return nil
+	}
+	content := stateFileContent{Channels: make(map[string]string, len(c.channels)), Subscribers: make(map[string]string, len(c.subscribers))}
+	for channelID, messageID := range c.channels {
+		content.Channels[channelID] = messageID
+	}
+	for userID, userKey := range c.subscribers {
+		content.Subscribers[userID] = userKey
+	}
+	for _, reminder := range c.reminders {
+		content.Reminders = append(content.Reminders, reminder)
+	}
+	if len(c.guildRules) > 0 {
+		content.GuildRules = make(map[string]guildRuleSet, len(c.guildRules))
+		for guildID, set := range c.guildRules {
+			content.GuildRules[guildID] = set
+		}
+	}
+	path := c.path
+	c.dirty = false
+	c.mu.Unlock()
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		c.mu.Lock()
+		c.dirty = true // Try again on the next flush
+		c.mu.Unlock()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// checkpointMessage records a processed message if its channel is backfilled.
+func checkpointMessage(config *Config, channelID string, messageID string) {
+	if config.StateFile == "" || config.Backfill == nil {
+		return
+	}
+	for _, backfilled := range backfillChannelIDs(config) {
+		if backfilled == channelID {
+			checkpoints.record(channelID, messageID)
+			return
+		}
+	}
+}
+
+// flushCheckpoints periodically writes changed checkpoints to the state file.
+func flushCheckpoints() {
+	defer recoverPanic("flushCheckpoints")
+	ticker := time.NewTicker(checkpointFlushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := checkpoints.flush(); err != nil {
+			log.Errorf("Checkpoints: %v", err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package discord2pushover
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := &checkpointStore{}
+	if err := store.load(path); err != nil {
+		t.Fatalf("load of a missing state file failed: %v", err)
+	}
+	store.record("c1", "200")
+	store.record("c1", "150") // An edit of an older message doesn't move the checkpoint back
+	store.record("c2", "90")
+	if err := store.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	reloaded := &checkpointStore{}
+	if err := reloaded.load(path); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if reloaded.last("c1") != "200" || reloaded.last("c2") != "90" || reloaded.last("c3") != "" {
+		t.Errorf("Unexpected checkpoints after reload: %v", reloaded.channels)
+	}
+
+	os.WriteFile(path, []byte("{not json"), 0o600)
+	if err := reloaded.load(path); err == nil {
+		t.Error("Expected an error for a corrupt state file")
+	}
+}
+
+func TestBackfillFromCheckpoint(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	oldCheckpoints := checkpoints
+	defer func() {
+		testHookDisablePushoverSend = false
+		testHookPushoverSendCalled = false
+		checkpoints = oldCheckpoints
+	}()
+	checkpoints = &checkpointStore{}
+	checkpoints.load(filepath.Join(t.TempDir(), "state.json"))
+	checkpoints.record("alerts", "1000")
+
+	// 250 messages (IDs 1001-1250) were posted since the checkpoint; two days ago, beyond maxAgeMinutes
+	posted := time.Now().Add(-48 * time.Hour)
+	var requests []string
+	session := mockSessionForRulesTest("bot").(*MockDiscordSession)
+	session.CustomChannelMessagesFunc = func(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error) {
+		requests = append(requests, strconv.Itoa(limit)+"/"+beforeID)
+		newest := 1250
+		if beforeID != "" {
+			before, _ := strconv.Atoi(beforeID)
+			newest = before - 1
+		}
+		var page []*discordgo.Message
+		for id := newest; id > newest-limit && id > 900; id-- {
+			page = append(page, &discordgo.Message{ID: strconv.Itoa(id), ChannelID: channelID, Content: "FIRING", Timestamp: posted,
+				Author: &discordgo.User{ID: "grafana"}})
+		}
+		return page, nil
+	}
+	config := &Config{
+		StateFile: "state.json",
+		Backfill:  &Backfill{CatchUpLimit: 150},
+		Rules: []Rule{{Name: "Alerts", Conditions: RuleConditions{ChannelID: "alerts", ContentIncludes: []string{"FIRING"}},
+			Actions: RuleActions{PushoverDestination: "user"}}},
+	}
+
+	if processed := backfillMessages(session, config, time.Now()); processed != 150 {
+		t.Errorf("Expected the newest 150 messages to be caught up, got %d", processed)
+	}
+	if got := strings.Join(requests, ","); got != "100/,50/1151" {
+		t.Errorf("Unexpected page requests %s", got)
+	}
+	if last := checkpoints.last("alerts"); last != "1250" {
+		t.Errorf("Expected the checkpoint to advance to 1250, got %s", last)
+	}
+	if !strings.Contains(testLogBufferForTest.String(), "at least 150 messages were posted in channel alerts") {
+		t.Error("Expected a warning about skipped messages")
+	}
+
+	// Without new messages nothing is processed again
+	requests = nil
+	session.CustomChannelMessagesFunc = func(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error) {
+		return []*discordgo.Message{{ID: "1250", ChannelID: channelID, Content: "FIRING", Author: &discordgo.User{ID: "grafana"}}}, nil
+	}
+	if processed := backfillMessages(session, config, time.Now()); processed != 0 {
+		t.Errorf("Expected nothing to catch up, got %d", processed)
+	}
+}
@@ -0,0 +1,128 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultClassifyTimeout bounds a classification request when the condition sets no timeoutSeconds.
+const defaultClassifyTimeout = 5 * time.Second
+
+// maxClassifyCacheEntries bounds the classification cache; it is cleared when full.
+const maxClassifyCacheEntries = 1000
+
+// classifyRequest is the JSON body POSTed to a classification endpoint.
+type classifyRequest struct {
+	MessageID  string `json:"messageId"`
+	ChannelID  string `json:"channelId"`
+	GuildID    string `json:"guildId"`
+	AuthorID   string `json:"authorId"`
+	AuthorName string `json:"authorName"`
+	Content    string `json:"content"`
+	Text       string `json:"text"` // Content plus embed text
+}
+
+// classifyResponse is the JSON an endpoint must answer with.
+type classifyResponse struct {
+	Labels []string `json:"labels"`
+}
+
+// classifyCache holds labels per endpoint and message content, so re-evaluations triggered by
+// reactions and edits that don't change the content don't call the endpoint again.
+var classifyCache = struct {
+	sync.Mutex
+	labels map[string][]string
+}{labels: make(map[string][]string)}
+
+// classifyMessage returns the labels the endpoint assigns to message, asked through the config's transport.
+func classifyMessage(ctx context.Context, config *Config, condition *ClassifyCondition, message *discordgo.Message) ([]string, error) {
+	cacheKey := condition.URL + "\x00" + message.ID + "\x00" + message.Content
+	classifyCache.Lock()
+	labels, ok := classifyCache.labels[cacheKey]
+	classifyCache.Unlock()
+	if ok {
+		return labels, nil
+	}
+
+	request := classifyRequest{
+		MessageID: message.ID,
+		ChannelID: message.ChannelID,
+		GuildID:   message.GuildID,
+		Content:   message.Content,
+		Text:      messageSearchText(message),
+	}
+	if message.Author != nil {
+		request.AuthorID = message.Author.ID
+		request.AuthorName = message.Author.Username
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, condition.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid classify url %q: %w", condition.URL, err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	for name, value := range condition.Headers {
+		httpRequest.Header.Set(name, value)
+	}
+
+	timeout := defaultClassifyTimeout
+	if condition.TimeoutSeconds > 0 {
+		timeout = time.Duration(condition.TimeoutSeconds) * time.Second
+	}
+	resp, err := newHTTPClientTimeout(config, timeout).Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("classify request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("classify endpoint returned %s", resp.Status)
+	}
+	var result classifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid classify response: %w", err)
+	}
+
+	classifyCache.Lock()
+	if len(classifyCache.labels) >= maxClassifyCacheEntries {
+		classifyCache.labels = make(map[string][]string)
+	}
+	classifyCache.labels[cacheKey] = result.Labels
+	classifyCache.Unlock()
+	return result.Labels, nil
+}
+
+// checkClassifyCondition classifies the message and checks that any of the returned labels is wanted.
+// If the endpoint fails, the condition fails unless matchOnError is set.
+func checkClassifyCondition(ctx context.Context, config *Config, message *discordgo.Message, condition *ClassifyCondition, logPrefix string) bool {
+	if condition.URL == "" {
+		log.Errorf(logPrefix + "classify has no url. Condition will fail.")
+		return false
+	}
+	labels, err := classifyMessage(ctx, config, condition, message)
+	if err != nil {
+		if condition.MatchOnError {
+			log.Errorf(logPrefix+"Classification failed: %v. Condition treated as met (matchOnError).", err)
+			return true
+		}
+		log.Errorf(logPrefix+"Classification failed: %v. Condition will fail.", err)
+		return false
+	}
+	for _, label := range labels {
+		if len(condition.Labels) == 0 || containsFold(condition.Labels, label) {
+			log.Debugf(logPrefix+"Condition passed (Classify): label '%s'", label)
+			return true
+		}
+	}
+	log.Debugf(logPrefix+"Condition failed (Classify): labels %v contain none of %v", labels, condition.Labels)
+	return false
+}
@@ -0,0 +1,74 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCheckClassifyCondition(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var request classifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		labels := []string{"chatter"}
+		if strings.Contains(request.Text, "down") {
+			labels = []string{"outage", "urgent"}
+		}
+		json.NewEncoder(w).Encode(classifyResponse{Labels: labels})
+	}))
+	defer server.Close()
+
+	condition := &ClassifyCondition{URL: server.URL, Labels: []string{"Urgent"}, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	outage := &discordgo.Message{ID: "m1", Embeds: []*discordgo.MessageEmbed{{Title: "api is down"}}}
+	chatter := &discordgo.Message{ID: "m2", Content: "lunch?"}
+
+	if !checkClassifyCondition(context.Background(), nil, outage, condition, "") {
+		t.Error("Expected outage to match")
+	}
+	if checkClassifyCondition(context.Background(), nil, chatter, condition, "") {
+		t.Error("Expected chatter not to match")
+	}
+	checkClassifyCondition(context.Background(), nil, outage, condition, "")
+	if calls != 2 {
+		t.Errorf("Expected cached classification to be reused, endpoint called %d times", calls)
+	}
+
+	anyLabel := &ClassifyCondition{URL: server.URL, Headers: condition.Headers}
+	if !checkClassifyCondition(context.Background(), nil, chatter, anyLabel, "") {
+		t.Error("Expected any label to match when labels is empty")
+	}
+
+	unauthorized := &ClassifyCondition{URL: server.URL, Labels: []string{"urgent"}}
+	uncached := &discordgo.Message{ID: "m3", Content: "db down"}
+	if checkClassifyCondition(context.Background(), nil, uncached, unauthorized, "") {
+		t.Error("Expected endpoint error to fail the condition")
+	}
+	unauthorized.MatchOnError = true
+	if !checkClassifyCondition(context.Background(), nil, uncached, unauthorized, "") {
+		t.Error("Expected endpoint error to match with matchOnError")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	if checkClassifyCondition(cancelled, nil, &discordgo.Message{ID: "m4", Content: "api down"}, condition, "") || calls != 0 {
+		t.Errorf("Expected a cancelled message not to be classified, endpoint called %d times", calls)
+	}
+}
@@ -0,0 +1,12 @@
+// Command discord2pushover forwards Discord messages matching the configured rules to Pushover.
+package main
+
+import (
+	"os"
+
+	"github.com/user/discord2pushover"
+)
+
+func main() {
+	os.Exit(discord2pushover.Main())
+}
@@ -0,0 +1,196 @@
+package discord2pushover
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// subcommands maps CLI subcommand names to their one-line descriptions, used for dispatch and usage output.
+var subcommands = map[string]string{
+	"channels":        "List guilds with their categories and channels, including IDs",
+	"diagnose":        "Check the Discord token, intents, channel permissions and Pushover destinations",
+	"export-rules":    "Print the rules as a portable bundle, with destinations replaced by aliases and secrets stripped",
+	"guilds":          "List guilds the bot is a member of, including IDs",
+	"import-rules":    "Merge a rule bundle into the configuration file, keeping a .bak copy; rules with the same name are replaced",
+	"healthcheck":     "Exit 0 if the running bot reports healthy via its admin listener, 1 otherwise (e.g. for Docker HEALTHCHECK)",
+	"migrate-config":  "Upgrade the configuration file to the current schema, keeping a .bak copy",
+	"install":         "Install and start the bot as a service (Windows service, launchd daemon or systemd unit)",
+	"uninstall":       "Stop and remove the installed service",
+	"run-as-service":  "Run the bot under the service manager; used by the installed service",
+	"schema":          "Print the JSON Schema of the configuration file, for editor completion and validation",
+	"status":          "Print per-rule statistics of the running bot, queried via its admin listener",
+	"validate":        "Check the configuration file; with --run-tests, also run its rule tests",
+	"verify-delivery": "Show the deliveryLog records of a Discord message ID, Pushover request ID or receipt, with the delivery status of emergencies",
+	"whoami":          "Print the bot account the configured token belongs to",
+}
+
+func isKnownSubcommand(name string) bool {
+	_, ok := subcommands[name]
+	return ok
+}
+
+// printUsage prints the command-line usage, including subcommands.
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: discord2pushover [command] [flags]\n\n")
+	fmt.Fprintf(out, "Without a command, the bot runs in the foreground.\n\nCommands:\n")
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-16s %s\n", name, subcommands[name])
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// subcommandArg returns the first argument after the subcommand, e.g. the ID of verify-delivery.
+func subcommandArg(name string) string {
+	if arg := flag.Arg(0); arg != name {
+		return arg
+	}
+	return flag.Arg(1)
+}
+
+// runSubcommand executes a CLI subcommand and returns the process exit code.
+func runSubcommand(name string, config *Config) int {
+	dg, err := discordgo.New("Bot " + config.currentDiscordToken())
+	if err != nil {
+		log.Errorf("Error creating Discord session: %v", err)
+		return 1
+	}
+
+	switch name {
+	case "guilds":
+		err = listGuilds(dg, os.Stdout)
+	case "channels":
+		err = listChannels(dg, os.Stdout)
+	case "whoami":
+		var botUser *discordgo.User
+		if botUser, err = dg.User("@me"); err == nil {
+			fmt.Printf("%s (ID: %s, bot: %t)\n", botUser.String(), botUser.ID, botUser.Bot)
+		}
+	case "healthcheck":
+		if err = checkHealth(config.Admin); err == nil {
+			fmt.Println("healthy")
+		}
+	case "status":
+		err = printStatus(config.Admin, os.Stdout)
+	case "verify-delivery":
+		ctx, cancel := backgroundContext()
+		defer cancel()
+		err = verifyDelivery(ctx, config, subcommandArg(name), os.Stdout)
+	case "diagnose":
+		if !runDiagnose(dg, config, os.Stdout) {
+			return 1
+		}
+	default:
+		err = fmt.Errorf("unknown command %q", name)
+	}
+	if err != nil {
+		log.Errorf("Command '%s' failed: %v", name, err)
+		return 1
+	}
+	return 0
+}
+
+// discordDirectory is the subset of discordgo.Session REST calls used to enumerate guilds and channels.
+type discordDirectory interface {
+	UserGuilds(limit int, beforeID, afterID string, withCounts bool, options ...discordgo.RequestOption) ([]*discordgo.UserGuild, error)
+	GuildChannels(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Channel, error)
+}
+
+var _ discordDirectory = &discordgo.Session{}
+
+// fetchAllGuilds pages through the bot's guild list.
+func fetchAllGuilds(dir discordDirectory) ([]*discordgo.UserGuild, error) {
+	var all []*discordgo.UserGuild
+	afterID := ""
+	for {
+		page, err := dir.UserGuilds(200, "", afterID, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list guilds: %w", err)
+		}
+		all = append(all, page...)
+		if len(page) < 200 {
+			return all, nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// listGuilds prints each guild the bot belongs to with its ID.
+func listGuilds(dir discordDirectory, out io.Writer) error {
+	guilds, err := fetchAllGuilds(dir)
+	if err != nil {
+		return err
+	}
+	if len(guilds) == 0 {
+		fmt.Fprintln(out, "The bot is not a member of any guild.")
+		return nil
+	}
+	for _, g := range guilds {
+		fmt.Fprintf(out, "%s\t%s\n", g.ID, g.Name)
+	}
+	return nil
+}
+
+// listChannels prints every guild with its channels grouped under their categories.
+func listChannels(dir discordDirectory, out io.Writer) error {
+	guilds, err := fetchAllGuilds(dir)
+	if err != nil {
+		return err
+	}
+	for _, g := range guilds {
+		fmt.Fprintf(out, "Guild: %s (%s)\n", g.Name, g.ID)
+		channels, err := dir.GuildChannels(g.ID)
+		if err != nil {
+			fmt.Fprintf(out, "  (failed to list channels: %v)\n", err)
+			continue
+		}
+		sort.SliceStable(channels, func(i, j int) bool { return channels[i].Position < channels[j].Position })
+
+		// Channels without a category are printed first, then each category with its children.
+		for _, ch := range channels {
+			if ch.Type != discordgo.ChannelTypeGuildCategory && ch.ParentID == "" {
+				fmt.Fprintf(out, "  %s\n", formatChannelLine(ch))
+			}
+		}
+		for _, cat := range channels {
+			if cat.Type != discordgo.ChannelTypeGuildCategory {
+				continue
+			}
+			fmt.Fprintf(out, "  Category: %s (%s)\n", cat.Name, cat.ID)
+			for _, ch := range channels {
+				if ch.ParentID == cat.ID {
+					fmt.Fprintf(out, "    %s\n", formatChannelLine(ch))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func formatChannelLine(ch *discordgo.Channel) string {
+	kind := "other"
+	switch ch.Type {
+	case discordgo.ChannelTypeGuildText:
+		kind = "text"
+	case discordgo.ChannelTypeGuildVoice:
+		kind = "voice"
+	case discordgo.ChannelTypeGuildNews:
+		kind = "announcement"
+	case discordgo.ChannelTypeGuildForum:
+		kind = "forum"
+	case discordgo.ChannelTypeGuildStageVoice:
+		kind = "stage"
+	}
+	return fmt.Sprintf("#%s (%s) [%s]", ch.Name, ch.ID, kind)
+}
@@ -0,0 +1,63 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type fakeDiscordDirectory struct {
+	guilds   []*discordgo.UserGuild
+	channels map[string][]*discordgo.Channel
+	emojis   map[string][]*discordgo.Emoji
+}
+
+func (f *fakeDiscordDirectory) UserGuilds(limit int, beforeID, afterID string, withCounts bool, options ...discordgo.RequestOption) ([]*discordgo.UserGuild, error) {
+	return f.guilds, nil
+}
+
+func (f *fakeDiscordDirectory) GuildChannels(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Channel, error) {
+	return f.channels[guildID], nil
+}
+
+func (f *fakeDiscordDirectory) GuildEmojis(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Emoji, error) {
+	return f.emojis[guildID], nil
+}
+
+func TestListChannels(t *testing.T) {
+	dir := &fakeDiscordDirectory{
+		guilds: []*discordgo.UserGuild{{ID: "g1", Name: "Ops"}},
+		channels: map[string][]*discordgo.Channel{
+			"g1": {
+				{ID: "c2", Name: "alerts", Type: discordgo.ChannelTypeGuildText, ParentID: "cat1", Position: 2},
+				{ID: "cat1", Name: "Monitoring", Type: discordgo.ChannelTypeGuildCategory, Position: 1},
+				{ID: "c1", Name: "general", Type: discordgo.ChannelTypeGuildText, Position: 0},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := listChannels(dir, &out); err != nil {
+		t.Fatalf("listChannels returned error: %v", err)
+	}
+	expected := "Guild: Ops (g1)\n" +
+		"  #general (c1) [text]\n" +
+		"  Category: Monitoring (cat1)\n" +
+		"    #alerts (c2) [text]\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestListGuilds(t *testing.T) {
+	dir := &fakeDiscordDirectory{guilds: []*discordgo.UserGuild{{ID: "g1", Name: "Ops"}, {ID: "g2", Name: "Dev"}}}
+	var out bytes.Buffer
+	if err := listGuilds(dir, &out); err != nil {
+		t.Fatalf("listGuilds returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "g1\tOps") || !strings.Contains(out.String(), "g2\tDev") {
+		t.Errorf("Expected both guilds in output, got: %s", out.String())
+	}
+}
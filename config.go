@@ -1,65 +1,600 @@
-package main
+package discord2pushover
 
 import (
 	"fmt" // Keep fmt for error wrapping
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config is the top-level configuration structure.
 type Config struct {
-	DiscordToken   string `yaml:"discordToken"`
-	PushoverAppKey string `yaml:"pushoverAppKey"`
-	LogLevel       string `yaml:"logLevel,omitempty"` // Added LogLevel
-	Rules          []Rule `yaml:"rules"`
+	DiscordToken    string `yaml:"discordToken"`
+	PushoverAppKey  string `yaml:"pushoverAppKey"`
+	LogLevel        string `yaml:"logLevel,omitempty"`      // Added LogLevel
+	LogFile         string `yaml:"logFile,omitempty"`       // Write logs to this file instead of stderr
+	LogMaxSizeMB    int    `yaml:"logMaxSizeMB,omitempty"`  // logFile is rotated at this size. Default 100.
+	LogMaxBackups   int    `yaml:"logMaxBackups,omitempty"` // Rotated log files kept. Default 5.
+	LogSink         string `yaml:"logSink,omitempty"`       // "syslog" or "journald": log there instead of stderr, with severities
+	SyslogAddress   string `yaml:"syslogAddress,omitempty"` // logSink syslog: "udp://host:514" or "tcp://host:514". Default: local syslog.
+	SentryDSN       string `yaml:"sentryDsn,omitempty"`
+	LinkTitle       string `yaml:"linkTitle,omitempty"`       // Label for the Discord link shown by Pushover
+	LinkInBody      bool   `yaml:"linkInBody,omitempty"`      // Compatibility: append the link to the body instead
+	AutoJoinThreads bool   `yaml:"autoJoinThreads,omitempty"` // Join new threads in channels referenced by rules
+	Rules           []Rule `yaml:"rules"`
+
+	LifecycleNotifications  *LifecycleNotifications   `yaml:"lifecycleNotifications,omitempty"`
+	EventWebhooks           []EventWebhook            `yaml:"eventWebhooks,omitempty"` // Endpoints receiving the bot's own events as JSON
+	ErrorNotification       *ErrorNotification        `yaml:"errorNotification,omitempty"`
+	TokenCheck              *TokenCheck               `yaml:"tokenCheck,omitempty"`  // Periodic check of the Discord token and Pushover app key
+	DeliveryLog             string                    `yaml:"deliveryLog,omitempty"` // Append every notification sent to Pushover, with its response, to this file
+	ReplyBridge             *ReplyBridge              `yaml:"replyBridge,omitempty"`
+	Notifiers               map[string]NotifierConfig `yaml:"notifiers,omitempty"`               // Named non-Pushover destinations for rules' notify
+	Budget                  *Budget                   `yaml:"budget,omitempty"`                  // Caps notifications across all rules
+	Backfill                *Backfill                 `yaml:"backfill,omitempty"`                // Catch up on messages missed while offline
+	StateFile               string                    `yaml:"stateFile,omitempty"`               // JSON file persisting state across restarts
+	Ignore                  *IgnoreList               `yaml:"ignore,omitempty"`                  // Sources dropped before any rule is evaluated
+	Admin                   *AdminListener            `yaml:"admin,omitempty"`                   // HTTP listener for diagnostics
+	ControlPlane            *ControlPlane             `yaml:"controlPlane,omitempty"`            // gRPC API for rule management, test messages and event streams
+	StatsLogIntervalMinutes int                       `yaml:"statsLogIntervalMinutes,omitempty"` // Rule statistics are logged this often. Default 60, negative disables.
+	TraceDecisions          bool                      `yaml:"traceDecisions,omitempty"`          // Log one JSON object per message explaining every rule's result
+	RuleEvaluation          string                    `yaml:"ruleEvaluation,omitempty"`          // "firstMatch" (default) or "allMatches"
+	Tests                   []RuleTest                `yaml:"tests,omitempty"`                   // Rule tests run by `validate --run-tests`
+	EventTimeoutSeconds     int                       `yaml:"eventTimeoutSeconds,omitempty"`     // Processing of one Discord event, including its notifications, is cancelled after this. Default 60.
+	HTTPTimeoutSeconds      int                       `yaml:"httpTimeoutSeconds,omitempty"`      // Requests to the Pushover API time out after this. Default 10.
+	PushoverAPIBase         string                    `yaml:"pushoverApiBase,omitempty"`         // Pushover API endpoint, e.g. a fake or Pushover-compatible server. Default "https://api.pushover.net/1".
+	HTTPProxy               string                    `yaml:"httpProxy,omitempty"`               // Proxy for outbound connections, e.g. "http://proxy:3128". Default: HTTPS_PROXY/HTTP_PROXY.
+	CAFile                  string                    `yaml:"caFile,omitempty"`                  // PEM file of CA certificates trusted in addition to the system's, e.g. of a TLS-intercepting proxy
+	Network                 *Network                  `yaml:"network,omitempty"`                 // Address family and DNS settings for outbound connections
+	Translation             *Translation              `yaml:"translation,omitempty"`             // Provider for rules' translateTo
+	UserDestinations        map[string]string         `yaml:"userDestinations,omitempty"`        // Discord user ID to Pushover user key, for notifySubscribersOfEmoji and notifyMentionedUsers
+	RoleDestinations        map[string]string         `yaml:"roleDestinations,omitempty"`        // Discord role ID to Pushover group key, for notifyMentionedRoles
+	Subscriptions           *Subscriptions            `yaml:"subscriptions,omitempty"`           // Self-registration of Pushover user keys
+	GuildRules              *GuildRules               `yaml:"guildRules,omitempty"`              // Keyword rules guild admins manage with a slash command
+	Tenants                 *Tenants                  `yaml:"tenants,omitempty"`                 // Per-guild quotas and destination allowlists
+	OnCall                  *OnCall                   `yaml:"onCall,omitempty"`                  // Schedule resolving the {{oncall}} destination token
+	BusinessHours           *BusinessHours            `yaml:"businessHours,omitempty"`           // Working hours and holidays for schedule conditions
+	IncidentSync            *IncidentSync             `yaml:"incidentSync,omitempty"`            // Keeps emergencies and PagerDuty/Opsgenie incidents acknowledged together
+	Ingest                  *Ingest                   `yaml:"ingest,omitempty"`                  // HTTP listener receiving alerts from Alertmanager for onAlertmanager rules
+
+	ruleIndex   *ruleIndex      // Built by LoadConfig
+	pushover    PushoverClient  // Created by LoadConfig, shared by all sends
+	transport   *http.Transport // Built by LoadConfig from httpProxy, caFile and network
+	emergencies *sync.Map       // Emergencies pending acknowledgement, see trackedEmergencies
+	credentials *sync.RWMutex   // Guards DiscordToken and PushoverAppKey, see lockCredentials
+	filePath    string          // File and environment LoadConfigEnv read, reloaded by monitorTokens
+	env         string
+}
+
+// Network tunes how the hosts of outbound connections are resolved and dialed, for networks with
+// broken IPv6 or unreliable DNS. Without it, Go's defaults apply.
+type Network struct {
+	PreferIPv4        bool     `yaml:"preferIPv4,omitempty"`        // Try IPv4 addresses first, IPv6 only once all have failed
+	DNSTimeoutSeconds int      `yaml:"dnsTimeoutSeconds,omitempty"` // Resolving a host fails after this. Default: only the dial timeout.
+	Resolvers         []string `yaml:"resolvers,omitempty"`         // DNS servers used instead of the system's, e.g. "1.1.1.1" or "10.0.0.2:5353"
+}
+
+// BusinessHours defines the working hours that schedule conditions check. Holidays are off-hours all day.
+type BusinessHours struct {
+	Timezone            string   `yaml:"timezone,omitempty"`            // IANA zone the hours and holidays are in, e.g. "Europe/Berlin". Default UTC.
+	Days                []string `yaml:"days,omitempty"`                // Working days, e.g. ["mon", "tue"]. Default Monday to Friday.
+	Start               string   `yaml:"start,omitempty"`               // Default "09:00"
+	End                 string   `yaml:"end,omitempty"`                 // Default "17:00"
+	Holidays            []string `yaml:"holidays,omitempty"`            // Dates like "2024-12-25"
+	HolidayCalendarURL  string   `yaml:"holidayCalendarUrl,omitempty"`  // iCal feed whose events' dates are holidays
+	PollIntervalMinutes int      `yaml:"pollIntervalMinutes,omitempty"` // How often holidayCalendarUrl is fetched. Default 360.
+}
+
+// OnCall is the schedule deciding whom the {{oncall}} token in a pushoverDestination notifies. A current
+// calendar shift takes precedence over the rota; without either, the fallback is notified.
+type OnCall struct {
+	Rota                *OnCallRota       `yaml:"rota,omitempty"`
+	CalendarURL         string            `yaml:"calendarUrl,omitempty"`         // iCal feed of the shifts, e.g. a PagerDuty or Opsgenie schedule export. An event is a shift of the person of people its summary names.
+	PollIntervalMinutes int               `yaml:"pollIntervalMinutes,omitempty"` // How often calendarUrl is fetched. Default 15.
+	People              map[string]string `yaml:"people,omitempty"`              // Name to Pushover user key. Rota entries not listed are used as keys themselves.
+	Fallback            string            `yaml:"fallback,omitempty"`            // Pushover key notified while nobody is on call
+}
+
+// OnCallRota hands the on-call shift from person to person in a fixed rotation.
+type OnCallRota struct {
+	Start      string   `yaml:"start"`      // When the first person's first shift begins, e.g. "2024-01-01T09:00:00Z"
+	ShiftHours int      `yaml:"shiftHours"` // Default 168, one week
+	People     []string `yaml:"people"`     // Names in the order they are on call
+}
+
+// Subscriptions lets people link their own Pushover user key, in addition to userDestinations, so rules
+// with notifySubscribersOfEmoji can notify them once they subscribed by reacting to a pinned message.
+type Subscriptions struct {
+	SelfRegistration bool   `yaml:"selfRegistration"` // Users may link their own key by DMing the bot the command
+	Command          string `yaml:"command"`          // Default "!pushover"
+}
+
+// GuildRules lets guild admins manage simple keyword rules for their own guild, notifying a Pushover
+// destination of their choice, with a slash command. The rules are kept in the state file and evaluated
+// after, and independently of, the rules of the config.
+type GuildRules struct {
+	Command          string   `yaml:"command,omitempty"`          // Slash command name. Default "pushover".
+	GuildIDs         []string `yaml:"guildIds,omitempty"`         // Guilds the command is registered in. Default: a global command.
+	AdminRoleIDs     []string `yaml:"adminRoleIds,omitempty"`     // Roles that may manage rules, besides members with the Manage Server permission
+	MaxRulesPerGuild int      `yaml:"maxRulesPerGuild,omitempty"` // Default 25
+}
+
+// Tenants isolates the guilds of a multi-guild deployment from each other: a guild's notifications
+// count against its own budget and may only go to its allowed destinations.
+type Tenants struct {
+	Default *Tenant           `yaml:"default,omitempty"` // Applies to guilds without an entry of their own
+	Guilds  map[string]Tenant `yaml:"guilds,omitempty"`  // Guild ID to its settings
+}
+
+// Tenant is the quota and allowlist of one guild.
+type Tenant struct {
+	Budget              *Budget  `yaml:"budget,omitempty"`              // Caps the notifications for the guild's messages, across all rules
+	AllowedDestinations []string `yaml:"allowedDestinations,omitempty"` // Pushover keys and notifiers the guild's notifications may go to. Default any.
+}
+
+// Translation is the machine translation service used by rules with translateTo.
+type Translation struct {
+	Provider       string `yaml:"provider"`       // "libretranslate" or "deepl"
+	URL            string `yaml:"url"`            // Translate endpoint; required for libretranslate, DeepL's API by default
+	APIKey         string `yaml:"apiKey"`         // API key, e.g. "${DEEPL_API_KEY}"
+	TimeoutSeconds int    `yaml:"timeoutSeconds"` // Default 5
+}
+
+// AdminListener is an HTTP listener serving runtime diagnostics. Without auth, only the event stream
+// is authenticated, so it should only listen on localhost or a private network.
+type AdminListener struct {
+	Listen      string     `yaml:"listen"`                // Address, e.g. "127.0.0.1:8081"
+	Pprof       bool       `yaml:"pprof"`                 // Serve net/http/pprof under /debug/pprof/
+	EventsToken string     `yaml:"eventsToken,omitempty"` // Serve the event stream at /events to clients with this bearer token
+	Auth        *AdminAuth `yaml:"auth,omitempty"`        // Authenticate every endpoint but /healthz
+}
+
+// AdminAuth authenticates the clients of the admin listener or control plane by bearer token or client
+// certificate, grants them the role "read" or "operator", and audits their actions.
+type AdminAuth struct {
+	Tokens       []AdminToken      `yaml:"tokens"`
+	CertFile     string            `yaml:"certFile"`     // Serve TLS with this certificate
+	KeyFile      string            `yaml:"keyFile"`      // Private key of certFile
+	ClientCAFile string            `yaml:"clientCaFile"` // Verify client certificates signed by this CA (mTLS)
+	ClientRoles  map[string]string `yaml:"clientRoles"`  // Role of client certificates by subject common name
+	AuditFile    string            `yaml:"auditFile"`    // Also append audited actions to this file as JSON lines
+}
+
+// AdminToken is a bearer token of an admin client.
+type AdminToken struct {
+	Name  string `yaml:"name"`  // Names the client in the audit log
+	Token string `yaml:"token"` // e.g. "${ADMIN_OPERATOR_TOKEN}"
+	Role  string `yaml:"role"`  // "read" or "operator"
+}
+
+// IgnoreList drops messages from known-noisy sources before any rule is evaluated. A message is
+// ignored if any entry matches.
+type IgnoreList struct {
+	UserIDs         []string `yaml:"userIds"`
+	ChannelIDs      []string `yaml:"channelIds"`
+	GuildIDs        []string `yaml:"guildIds"`
+	ContentPatterns []string `yaml:"contentPatterns"` // Regular expressions matched against content and embed text
+}
+
+// Backfill runs recent messages of the rules' channels through the rules at startup, so alerts
+// posted while the bot was down aren't missed.
+type Backfill struct {
+	Messages      int `yaml:"messages"`      // Recent messages fetched per channel. Default 20, at most 100.
+	MaxAgeMinutes int `yaml:"maxAgeMinutes"` // Older messages are skipped. Default 60.
+	CatchUpLimit  int `yaml:"catchUpLimit"`  // With a checkpoint in stateFile: messages caught up per channel at most. Default 500.
+}
+
+// Budget caps how many notifications are sent. Notifications over the cap are suppressed and a single
+// meta-alert is sent via errorNotification. Zero means no cap.
+type Budget struct {
+	MaxPerHour int `yaml:"maxPerHour"`
+	MaxPerDay  int `yaml:"maxPerDay"`
+}
+
+// NotifierConfig configures a named notification backend besides Pushover. Exactly one backend is set.
+type NotifierConfig struct {
+	Matrix        *MatrixNotifier        `yaml:"matrix,omitempty"`
+	Twilio        *TwilioNotifier        `yaml:"twilio,omitempty"`
+	MQTT          *MQTTNotifier          `yaml:"mqtt,omitempty"`
+	HomeAssistant *HomeAssistantNotifier `yaml:"homeAssistant,omitempty"`
+	Desktop       *DesktopNotifier       `yaml:"desktop,omitempty"`
+	PagerDuty     *PagerDutyNotifier     `yaml:"pagerDuty,omitempty"`
+	Opsgenie      *OpsgenieNotifier      `yaml:"opsgenie,omitempty"`
+	Discord       *DiscordNotifier       `yaml:"discord,omitempty"`
+}
+
+// MatrixNotifier posts notifications into a Matrix room.
+type MatrixNotifier struct {
+	Homeserver     string   `yaml:"homeserver"`     // e.g. "https://matrix.org"
+	RoomID         string   `yaml:"roomId"`         // e.g. "!abcdef:matrix.org"
+	AccessToken    string   `yaml:"accessToken"`    // Access token of the posting account
+	MentionUserIDs []string `yaml:"mentionUserIds"` // Mentioned for priority 1 and above, e.g. "@alice:matrix.org"
+}
+
+// TwilioNotifier sends notifications as SMS or voice calls through Twilio.
+type TwilioNotifier struct {
+	AccountSID string   `yaml:"accountSid"`
+	AuthToken  string   `yaml:"authToken"`
+	From       string   `yaml:"from"` // Twilio phone number in E.164 format, e.g. "+15005550006"
+	To         []string `yaml:"to"`   // Numbers to text or call
+	Mode       string   `yaml:"mode"` // "sms" (default) or "call"; a call reads the notification aloud
+}
+
+// MQTTNotifier publishes notifications as JSON to an MQTT broker, e.g. for home automation.
+type MQTTNotifier struct {
+	Broker   string `yaml:"broker"`   // e.g. "tcp://homeassistant.local:1883" or "ssl://broker:8883"
+	Topic    string `yaml:"topic"`    // Template, e.g. "discord2pushover/{{.RuleName}}"
+	QoS      byte   `yaml:"qos"`      // 0 (default), 1 or 2
+	Retain   bool   `yaml:"retain"`   // Publish as a retained message
+	ClientID string `yaml:"clientId"` // Default "discord2pushover"
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// HomeAssistantNotifier calls a Home Assistant service or fires an event, e.g. to announce an alert
+// via TTS or sound a siren. Exactly one of service and event is set.
+type HomeAssistantNotifier struct {
+	URL     string            `yaml:"url"`     // e.g. "http://homeassistant.local:8123"
+	Token   string            `yaml:"token"`   // Long-lived access token
+	Service string            `yaml:"service"` // Service to call, e.g. "notify.mobile_app_phone" or "script.siren"
+	Event   string            `yaml:"event"`   // Event type to fire, e.g. "discord_alert"
+	Data    map[string]string `yaml:"data"`    // Service/event data; values are templates. Defaults depend on the target.
+}
+
+// DesktopNotifier shows notifications on the desktop of the machine running the bot, using
+// notify-send on Linux, osascript on macOS and PowerShell toasts on Windows.
+type DesktopNotifier struct {
+	AppName string `yaml:"appName"` // Shown as the sender where supported. Default "discord2pushover".
+	Icon    string `yaml:"icon"`    // Icon name or path (Linux only)
+}
+
+// PagerDutyNotifier opens PagerDuty incidents through the Events API v2. Messages of one correlated
+// alert update the same incident.
+type PagerDutyNotifier struct {
+	RoutingKey string `yaml:"routingKey"` // Integration key of an Events API v2 integration
+	Region     string `yaml:"region"`     // "us" (default) or "eu"
+	Source     string `yaml:"source"`     // Affected system shown in the incident. Default "discord2pushover".
+}
+
+// OpsgenieNotifier creates Opsgenie alerts through the Alert API. Messages of one correlated alert are
+// folded into the same alert.
+type OpsgenieNotifier struct {
+	APIKey string   `yaml:"apiKey"` // Key of an API integration
+	Region string   `yaml:"region"` // "us" (default) or "eu"
+	Teams  []string `yaml:"teams"`  // Names of the teams the alert is routed to
+	Tags   []string `yaml:"tags"`
+}
+
+// DiscordNotifier posts notifications into a Discord channel with the bot's account, so rules for other
+// inputs, e.g. Alertmanager, and escalations or reminders can write into Discord.
+type DiscordNotifier struct {
+	ChannelID string        `yaml:"channelId"`       // Channel to post to
+	Template  string        `yaml:"template"`        // Template of the message content. Default: title, body and link; empty with embed.
+	Embed     *DiscordEmbed `yaml:"embed,omitempty"` // Post an embed built from these templates as well
+}
+
+// DiscordEmbed is the embed a discord notifier posts. Every field is a template with the same fields as
+// a rule's template.
+type DiscordEmbed struct {
+	Title       string              `yaml:"title"`       // Default: the notification's title
+	Description string              `yaml:"description"` // Default: the notification's body
+	URL         string              `yaml:"url"`         // Default: the notification's link
+	Color       string              `yaml:"color"`       // "#rrggbb". Default: by priority, from grey for -2 to red for 2.
+	Footer      string              `yaml:"footer"`
+	Fields      []DiscordEmbedField `yaml:"fields"`
+}
+
+// DiscordEmbedField is a field of a DiscordEmbed.
+type DiscordEmbedField struct {
+	Name   string `yaml:"name"`
+	Value  string `yaml:"value"`
+	Inline bool   `yaml:"inline"`
+}
+
+// IncidentSync keeps emergencies in step with the incidents their rule opened through pagerDuty and
+// opsgenie notifiers: acknowledging in Pushover acknowledges the incident, and acknowledging or resolving
+// the incident, reported by the tools' webhooks, ends the emergency.
+type IncidentSync struct {
+	OnAcknowledge   string           `yaml:"onAcknowledge"`      // What a Pushover acknowledgement does to the incident: "acknowledge" (default) or "resolve"
+	Listen          string           `yaml:"listen"`             // Address of the webhook listener, e.g. ":8091". Without it, only Pushover acknowledgements are synced.
+	PagerDutySecret string           `yaml:"pagerDutySecret"`    // Signing secret of the PagerDuty webhook subscription; enables /webhooks/pagerduty
+	OpsgenieToken   string           `yaml:"opsgenieToken"`      // Expected in the token parameter of the Opsgenie webhook URL; enables /webhooks/opsgenie
+	Security        *InboundSecurity `yaml:"security,omitempty"` // Source allowlist and signature check of the webhook listener
+}
+
+// Ingest is the HTTP listener receiving alerts from other systems, e.g. Alertmanager at /alertmanager,
+// which are evaluated by the rules of their event.
+type Ingest struct {
+	Listen    string           `yaml:"listen"`             // Address of the ingest listener, e.g. ":8092"
+	ChannelID string           `yaml:"channelId"`          // Discord channel the alert summaries are posted to, and rules see them in
+	Security  *InboundSecurity `yaml:"security,omitempty"` // Source allowlist and signature check of the listener
+}
+
+// InboundSecurity guards an inbound webhook listener against spoofed requests, by the address they come
+// from and an HMAC signature of their body.
+type InboundSecurity struct {
+	AllowedSources  []string `yaml:"allowedSources"`  // IPs and CIDRs requests may come from, e.g. "10.0.0.0/8". Default any.
+	SignatureSecret string   `yaml:"signatureSecret"` // Require an HMAC-SHA256 signature of the body with this secret
+	SignatureHeader string   `yaml:"signatureHeader"` // Header holding the hex signature. Default "X-Signature".
+	TimestampHeader string   `yaml:"timestampHeader"` // Header holding the Unix time signed along with the body, against replays
+	MaxSkewSeconds  int      `yaml:"maxSkewSeconds"`  // How far the timestamp may be off. Default 300.
+}
+
+// ReplyBridge posts messages sent to a Pushover Open Client device back into Discord, so people away
+// from Discord can answer a notification. Replies start with the reply code shown in the notification.
+type ReplyBridge struct {
+	Secret              string `yaml:"secret"`              // Open Client session secret
+	DeviceID            string `yaml:"deviceId"`            // Open Client device ID
+	PollIntervalSeconds int    `yaml:"pollIntervalSeconds"` // Default 30
+}
+
+// LifecycleNotifications defines where the bot announces its own startup and graceful shutdown.
+// Either or both destinations may be set.
+type LifecycleNotifications struct {
+	DiscordChannelID    string `yaml:"discordChannelId"`
+	PushoverDestination string `yaml:"pushoverDestination"`
+	Priority            int    `yaml:"priority"`
+}
+
+// EventWebhook receives the bot's own events, such as startup, rule matches and acknowledgements, as
+// JSON POSTs, for automation built on the bot.
+type EventWebhook struct {
+	URL    string   `yaml:"url"`
+	Events []string `yaml:"events,omitempty"` // Events to post, e.g. ["ruleMatched"]. Default all.
+	Secret string   `yaml:"secret,omitempty"` // Signs each body with HMAC-SHA256 in the X-Discord2pushover-Signature header
+}
+
+// ControlPlane is the gRPC API defined in proto/controlplane.proto. Unlike the admin listener, it
+// can change the config file and send notifications, so every call needs the token.
+type ControlPlane struct {
+	Listen string     `yaml:"listen"`         // Address, e.g. "127.0.0.1:9090", or "unix:/run/discord2pushover/control.sock"
+	Token  string     `yaml:"token"`          // Bearer token of clients with the operator role, e.g. "${CONTROL_PLANE_TOKEN}"
+	Auth   *AdminAuth `yaml:"auth,omitempty"` // Further clients and their roles
 }
 
 // Rule defines a single rule for processing messages.
 type Rule struct {
-	Name       string         `yaml:"name"`
+	Name       string            `yaml:"name"`
+	Event      string            `yaml:"event,omitempty"`  // "message" (default), "onPin", "onThreadCreate", "onAutomod", "onAuditLog", "onCommand" or "onAlertmanager"
+	Labels     map[string]string `yaml:"labels,omitempty"` // Metadata such as team, service or runbook URL, for templates, payloads and stats
+	Conditions RuleConditions    `yaml:"conditions"`
+	Actions    RuleActions       `yaml:"actions"`
+	ResolveOn  *ResolveOn        `yaml:"resolveOn,omitempty"`
+
+	RetractOnReactionRemove bool `yaml:"retractOnReactionRemove,omitempty"` // Cancel a pending emergency when the messageHasEmoji reactions are removed
+
+	CorrelationKey           string `yaml:"correlationKey,omitempty"`           // Template; messages with the same key update one incident
+	CorrelationWindowSeconds int    `yaml:"correlationWindowSeconds,omitempty"` // Idle time after which an incident is closed. Default 3600.
+
+	Budget *Budget       `yaml:"budget,omitempty"` // Caps this rule's notifications
+	Flood  *FloodControl `yaml:"flood,omitempty"`  // Collapses bursts of matches into summaries
+
+	PerAuthorCooldownSeconds int `yaml:"perAuthorCooldownSeconds,omitempty"` // After a notification, further matches by the same author in the channel are not notified for this long
+
+	Scoring *Scoring `yaml:"scoring,omitempty"` // Weighted conditions whose score decides whether and how urgently to notify
+
+	SamplePercent float64 `yaml:"samplePercent,omitempty"` // Notify only this share of matches, e.g. 5; other actions still run. Default: all.
+}
+
+// Scoring lets weighted conditions add up to a score instead of all having to match. After the rule's
+// own conditions matched, the message must reach a threshold; the highest one reached sets the priority.
+type Scoring struct {
+	Signals    []ScoreSignal    `yaml:"signals"`
+	Thresholds []ScoreThreshold `yaml:"thresholds"`
+}
+
+// ScoreSignal adds its points to the score of messages meeting its conditions.
+type ScoreSignal struct {
+	Name       string         `yaml:"name"`   // Shown in the logged score breakdown
+	Points     int            `yaml:"points"` // May be negative, e.g. for messages that look harmless
 	Conditions RuleConditions `yaml:"conditions"`
-	Actions    RuleActions    `yaml:"actions"`
+}
+
+// ScoreThreshold maps a minimum score to a priority.
+type ScoreThreshold struct {
+	Score    int `yaml:"score"`
+	Priority int `yaml:"priority"`
+}
+
+// FloodControl collapses bursts: once threshold matches of a rule arrive in one channel within
+// windowSeconds, further matches are not notified individually but summarized after each window.
+type FloodControl struct {
+	Threshold     int `yaml:"threshold"`
+	WindowSeconds int `yaml:"windowSeconds"` // Default 60
+}
+
+// ResolveOn describes the follow-up message that resolves a rule's pending emergency notifications.
+// Alerts and resolutions are paired by channel and by the rendered fingerprint template.
+type ResolveOn struct {
+	ContentPattern string `yaml:"contentPattern"` // Regular expression matched against content and embed text
+	Fingerprint    string `yaml:"fingerprint"`    // Template identifying the alert; defaults to the rule's correlationKey
+	ResolvedEmoji  string `yaml:"resolvedEmoji"`  // Reaction added to the original alert message once resolved
 }
 
 // RuleConditions defines the conditions for a rule to match.
 type RuleConditions struct {
-	ChannelID        string   `yaml:"channelId"`
-	MessageHasEmoji  []string `yaml:"messageHasEmoji"`
-	ReactToAtMention bool     `yaml:"reactToAtMention"`
-	SpecificMentions []string `yaml:"specificMentions"`
-	ContentIncludes  []string `yaml:"contentIncludes"`
+	ChannelID           string             `yaml:"channelId"`
+	MessageHasEmoji     []string           `yaml:"messageHasEmoji"`
+	ReactToAtMention    bool               `yaml:"reactToAtMention"`
+	SpecificMentions    []string           `yaml:"specificMentions"`
+	ContentIncludes     []string           `yaml:"contentIncludes"`
+	ContentMatch        string             `yaml:"contentMatch"`   // "allOf" (default) or "anyOf" for contentIncludes
+	MatchCase           bool               `yaml:"matchCase"`      // contentIncludes is case-sensitive
+	WholeWord           bool               `yaml:"wholeWord"`      // contentIncludes keywords must match whole words
+	FoldDiacritics      bool               `yaml:"foldDiacritics"` // contentIncludes ignores accents, e.g. "cafe" matches "café"
+	IsReplyTo           *ReplyCondition    `yaml:"isReplyTo,omitempty"`
+	Classify            *ClassifyCondition `yaml:"classify,omitempty"`
+	When                string             `yaml:"when,omitempty"`      // Boolean expression over message fields, see README
+	ParentChannelID     string             `yaml:"parentChannelId"`     // Message or thread must be in a thread under this channel
+	ThreadNamePattern   string             `yaml:"threadNamePattern"`   // Regular expression matched against the thread name
+	AutomodRuleNames    []string           `yaml:"automodRuleNames"`    // onAutomod: name of the AutoMod rule that fired (any of)
+	ActionTypes         []string           `yaml:"actionTypes"`         // onAutomod/onAuditLog: action taken (any of)
+	EmbedColorIn        []string           `yaml:"embedColorIn"`        // An embed's color must match any of these hex colors or named ranges
+	WebhookIDs          []string           `yaml:"webhookIds"`          // Message must be posted by one of these webhooks
+	WebhookNameIncludes []string           `yaml:"webhookNameIncludes"` // Webhook display name must contain any of these (case-insensitive)
+	Command             *CommandTrigger    `yaml:"command,omitempty"`   // onCommand: the command and who may invoke it
+	AllOf               []RuleConditions   `yaml:"allOf,omitempty"`     // Every group of conditions must match
+	AnyOf               []RuleConditions   `yaml:"anyOf,omitempty"`     // At least one group of conditions must match
+	Not                 *RuleConditions    `yaml:"not,omitempty"`       // The group of conditions must not match
+	Schedule            string             `yaml:"schedule,omitempty"`  // "businessHours" or "offHours", see Config.BusinessHours; top level and score signals only
+}
+
+// CommandTrigger describes a bot command such as "!page @oncall disk full". Everything after the
+// command word becomes the arguments available to templates.
+type CommandTrigger struct {
+	Name      string   `yaml:"name"`      // Command word including its prefix, e.g. "!page"
+	AuthorIDs []string `yaml:"authorIds"` // Users allowed to invoke the command
+	RoleIDs   []string `yaml:"roleIds"`   // Members with any of these roles may invoke it; anyone if both lists are empty
+}
+
+// ReplyCondition matches messages that reply to a parent message with the given author and/or content.
+type ReplyCondition struct {
+	AuthorIDs      []string `yaml:"authorIds"`
+	ContentPattern string   `yaml:"contentPattern"` // Regular expression matched against the parent's content
+}
+
+// ClassifyCondition sends the message to an external HTTP classifier and matches on the labels it returns.
+type ClassifyCondition struct {
+	URL            string            `yaml:"url"`            // Endpoint receiving a JSON POST, answering {"labels": [...]}
+	Labels         []string          `yaml:"labels"`         // Any of these labels must be returned; empty accepts any label
+	Headers        map[string]string `yaml:"headers"`        // Extra request headers, e.g. Authorization
+	TimeoutSeconds int               `yaml:"timeoutSeconds"` // Default 5
+	MatchOnError   bool              `yaml:"matchOnError"`   // Treat the condition as met if the endpoint fails
 }
 
 // RuleActions defines the actions to take when a rule matches.
 type RuleActions struct {
-	PushoverDestination string           `yaml:"pushoverDestination"`
-	Priority            int              `yaml:"priority"`
-	ReactionEmoji       string           `yaml:"reactionEmoji"`
-	Emergency           *EmergencyParams `yaml:"emergency,omitempty"`
+	PushoverDestination      string            `yaml:"pushoverDestination"`
+	Notify                   []string          `yaml:"notify,omitempty"` // Names of notifiers to send to as well
+	Priority                 int               `yaml:"priority"`
+	ReactionEmoji            EmojiList         `yaml:"reactionEmoji"`                      // One emoji or a list, added in order
+	LinkStyle                string            `yaml:"linkStyle,omitempty"`                // web (default), app or both
+	IncludeContext           int               `yaml:"includeContext,omitempty"`           // Number of preceding messages to include
+	Template                 string            `yaml:"template,omitempty"`                 // Go text/template for the notification body
+	TitleTemplate            string            `yaml:"titleTemplate,omitempty"`            // Go text/template for the notification title
+	Timezone                 string            `yaml:"timezone,omitempty"`                 // IANA zone for rendered times, e.g. "Europe/Berlin"
+	TimestampFormat          string            `yaml:"timestampFormat,omitempty"`          // Go time layout for {{.Timestamp}}
+	TemplateDefinitions      map[string]string `yaml:"templateDefinitions,omitempty"`      // Named templates usable as {{template "name" .}}
+	PayloadFormat            string            `yaml:"payloadFormat,omitempty"`            // Parse the sender's webhook format into {{.Payload}}, e.g. "grafana"
+	PayloadPriority          bool              `yaml:"payloadPriority,omitempty"`          // Derive the priority from the payload's status: down 1, up -2
+	SeverityMap              []SeverityMapping `yaml:"severityMap,omitempty"`              // First matching entry overrides priority
+	PriorityTags             *PriorityTags     `yaml:"priorityTags,omitempty"`             // Inline tags like "!p2" override priority
+	TranslateTo              string            `yaml:"translateTo,omitempty"`              // Translate the content into this language, e.g. "en", see Translation
+	NotifySubscribersOfEmoji string            `yaml:"notifySubscribersOfEmoji,omitempty"` // Also notify the users who reacted with this emoji on a pinned message of the channel
+	NotifyMentionedUsers     bool              `yaml:"notifyMentionedUsers,omitempty"`     // Also notify the users mentioned in the message, see Config.UserDestinations
+	NotifyMentionedRoles     bool              `yaml:"notifyMentionedRoles,omitempty"`     // Also notify the groups of the roles mentioned in the message, see Config.RoleDestinations
+	Script                   string            `yaml:"script,omitempty"`                   // Path of a Lua script run when the rule matches
+	ScriptTimeoutSeconds     int               `yaml:"scriptTimeoutSeconds,omitempty"`     // Default 10
+	Devices                  []DeviceVariant   `yaml:"devices,omitempty"`                  // Separate notification per device, see DeviceVariant
+	DelaySeconds             int               `yaml:"delaySeconds,omitempty"`             // Hold the notification this long, cancelling it if the alert is resolved, retracted or deleted meanwhile
+	RemindAfterMinutes       int               `yaml:"remindAfterMinutes,omitempty"`       // Notify again, one priority higher, if nobody reacts to or replies to the message within this time
+	Emergency                *EmergencyParams  `yaml:"emergency,omitempty"`
+}
+
+// DeviceVariant is a notification variant for some of the destination's Pushover devices, e.g. a short
+// text with its own sound for a watch. Unset fields fall back to the rule's settings.
+type DeviceVariant struct {
+	Device        string `yaml:"device"`        // Pushover device name; several may be comma-separated
+	Template      string `yaml:"template"`      // Body template for this device
+	TitleTemplate string `yaml:"titleTemplate"` // Title template for this device
+	Sound         string `yaml:"sound"`         // Pushover sound name, e.g. "siren"
+	MaxLength     int    `yaml:"maxLength"`     // Truncate the body to this many characters
+}
+
+// PriorityTags lets allowed people in the channel override a rule's priority with an inline tag in the
+// message, e.g. "!p2" to force an emergency page.
+type PriorityTags struct {
+	Tags      map[string]int `yaml:"tags"`      // Tag to priority, e.g. {"!p2": 2, "#page": 2}
+	AuthorIDs []string       `yaml:"authorIds"` // Users allowed to use the tags
+	RoleIDs   []string       `yaml:"roleIds"`   // Members with any of these roles may use the tags
+}
+
+// EmojiList is a list of emoji that may also be written as a single YAML string.
+type EmojiList []string
+
+// UnmarshalYAML accepts either `reactionEmoji: "📟"` or `reactionEmoji: ["📟", "🔴"]`.
+func (l *EmojiList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var emoji string
+		if err := node.Decode(&emoji); err != nil {
+			return err
+		}
+		*l = nil
+		if emoji != "" {
+			*l = EmojiList{emoji}
+		}
+		return nil
+	}
+	var emojis []string
+	if err := node.Decode(&emojis); err != nil {
+		return err
+	}
+	*l = emojis
+	return nil
+}
+
+// SeverityMapping maps a severity found in an alert message to a Pushover priority.
+// If both pattern and embedColor are set, both must match.
+type SeverityMapping struct {
+	Pattern    string `yaml:"pattern"`    // Regular expression matched against content and embed text
+	EmbedColor string `yaml:"embedColor"` // Embed color, e.g. "#e01e5a" or "red"
+	Priority   int    `yaml:"priority"`
+}
+
+// ErrorNotification defines where meta-alerts about the bridge's own failures are sent.
+// When a backend is failing, the alert is routed through the other backend if it is configured.
+type ErrorNotification struct {
+	DiscordChannelID           string `yaml:"discordChannelId"`
+	PushoverDestination        string `yaml:"pushoverDestination"`
+	Priority                   int    `yaml:"priority"`
+	PushoverFailureThreshold   int    `yaml:"pushoverFailureThreshold"`   // Consecutive failed sends before alerting. Default 3.
+	DisconnectThresholdSeconds int    `yaml:"disconnectThresholdSeconds"` // Seconds disconnected from Discord before alerting. Default 300.
+}
+
+// TokenCheck configures the periodic check that the Discord token and Pushover app key are still valid.
+type TokenCheck struct {
+	IntervalMinutes     int `yaml:"intervalMinutes"`     // Default 60; -1 disables the check
+	QuotaWarningPercent int `yaml:"quotaWarningPercent"` // Alert when less of the monthly Pushover quota remains. Default 10.
 }
 
 // EmergencyParams defines parameters for Pushover emergency priority messages.
 type EmergencyParams struct {
-	AckEmoji string `yaml:"ackEmoji"`
-	Expire   int    `yaml:"expire"`
-	Retry    int    `yaml:"retry"`
+	AckEmoji            string `yaml:"ackEmoji"`
+	PendingEmoji        string `yaml:"pendingEmoji"`        // Shown while unacknowledged; replaced by ackEmoji on acknowledgement
+	RemoveReactionOnAck bool   `yaml:"removeReactionOnAck"` // Remove the rule's reactionEmoji once acknowledged
+	Expire              int    `yaml:"expire"`
+	Retry               int    `yaml:"retry"`
+	StatusReply         bool   `yaml:"statusReply"`    // Reply with the paging progress, edited every retry cycle
+	CountdownEmoji      bool   `yaml:"countdownEmoji"` // React with 🔟 to 1️⃣ as the expiry approaches
+
+	EscalateTo           []string `yaml:"escalateTo"`           // Notifiers to send to if still unacknowledged after escalateAfterSeconds
+	EscalateAfterSeconds int      `yaml:"escalateAfterSeconds"` // Default 300
 }
 
 // LoadConfig reads a YAML file from filePath, parses it into a Config struct,
 // and replaces environment variable placeholders.
 func LoadConfig(filePath string) (*Config, error) {
-	// Read the YAML file
+	return LoadConfigEnv(filePath, "")
+}
+
+// LoadConfigEnv is LoadConfig with the overlay of an environment merged over the file, e.g. staging.yaml
+// next to it for env "staging". Without env it is LoadConfig.
+func LoadConfigEnv(filePath string, env string) (*Config, error) {
 	log.Infof("Reading configuration file: %s", filePath)
-	data, err := os.ReadFile(filePath)
+	data, err := readConfigFile(filePath, env)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+		return nil, err
 	}
 
-	// Substitute environment variables
-	substitutedData := substituteEnvVars(data)
+	// Substitute environment variables and secrets
+	substitutedData, err := substituteEnvVars(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", filePath, err)
+	}
 
 	// Parse the YAML
 	var cfg Config
@@ -69,12 +604,24 @@ func LoadConfig(filePath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
 	}
 	log.Info("YAML configuration parsed successfully.")
+	cfg.ruleIndex = newRuleIndex(cfg.Rules)
+	cfg.filePath, cfg.env = filePath, env
+	cfg.credentials = new(sync.RWMutex)
+	if cfg.transport, err = newTransport(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", filePath, err)
+	}
+	if err := validatePushoverAPIBase(cfg.PushoverAPIBase); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", filePath, err)
+	}
+	cfg.pushover = &pushoverAPI{token: cfg.PushoverAppKey, base: cfg.pushoverBase(), httpClient: newHTTPClient(&cfg)}
+	precompilePatterns(&cfg)
 	return &cfg, nil
 }
 
 // substituteEnvVars replaces placeholders like $VAR_NAME or ${VAR_NAME} in the
-// input byte slice with corresponding environment variable values.
-func substituteEnvVars(data []byte) []byte {
+// input byte slice with corresponding environment variable values, and then secret
+// references like ${vault:secret/myapp#token} with the secrets (see resolveSecrets).
+func substituteEnvVars(data []byte) ([]byte, error) {
 	s := string(data)
 	// Regex to find $VAR_NAME or ${VAR_NAME}
 	// It captures VAR_NAME in both cases
@@ -99,5 +646,5 @@ func substituteEnvVars(data []byte) []byte {
 		}
 		return val
 	})
-	return []byte(replacedString)
+	return resolveSecrets([]byte(replacedString))
 }
@@ -0,0 +1,63 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxIncludeContext is Discord's page size limit for fetching channel messages.
+	maxIncludeContext = 100
+	// contextLineMaxRunes bounds each transcript line so the whole push stays within Pushover's limits.
+	contextLineMaxRunes = 120
+)
+
+// fetchMessageContext returns a condensed transcript of up to n messages preceding the given message,
+// oldest first. Errors are logged and produce an empty transcript so the notification is still sent.
+func fetchMessageContext(session DiscordSessionInterface, message *discordgo.Message, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n > maxIncludeContext {
+		log.Warnf("includeContext %d exceeds the maximum of %d, capping.", n, maxIncludeContext)
+		n = maxIncludeContext
+	}
+	previous, err := session.ChannelMessages(message.ChannelID, n, message.ID, "", "")
+	if err != nil {
+		log.Errorf("Error fetching %d context messages before message %s (channel %s): %v", n, message.ID, message.ChannelID, err)
+		return ""
+	}
+	return buildContextTranscript(previous)
+}
+
+// buildContextTranscript renders messages (as returned by Discord, newest first) as "author: content" lines, oldest first.
+func buildContextTranscript(newestFirst []*discordgo.Message) string {
+	lines := make([]string, 0, len(newestFirst))
+	for i := len(newestFirst) - 1; i >= 0; i-- {
+		m := newestFirst[i]
+		author := "unknown"
+		if m.Author != nil {
+			author = m.Author.Username
+		}
+		content := strings.Join(strings.Fields(m.Content), " ") // Collapse newlines and runs of whitespace
+		if content == "" {
+			content = fmt.Sprintf("(%d attachment(s)/embed(s))", len(m.Attachments)+len(m.Embeds))
+		}
+		lines = append(lines, truncateRunes(fmt.Sprintf("%s: %s", author, content), contextLineMaxRunes))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateRunes shortens s to at most max runes, marking the cut with an ellipsis.
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	if max <= 1 {
+		return string(runes[:max])
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,52 @@
+package discord2pushover
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestFetchMessageContext(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	var gotLimit int
+	var gotBefore string
+	mockSess := &MockDiscordSession{
+		CustomChannelMessagesFunc: func(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error) {
+			gotLimit, gotBefore = limit, beforeID
+			// Discord returns newest first
+			return []*discordgo.Message{
+				{Author: &discordgo.User{Username: "bob"}, Content: "checking\nnow"},
+				{Author: &discordgo.User{Username: "alice"}, Content: "api latency is " + strings.Repeat("very ", 40) + "high"},
+			}, nil
+		},
+	}
+	trigger := &discordgo.Message{ID: "m3", ChannelID: "c1", Content: "it's down"}
+
+	transcript := fetchMessageContext(mockSess, trigger, 2)
+	if gotLimit != 2 || gotBefore != "m3" {
+		t.Errorf("Expected ChannelMessages(limit=2, before=m3), got limit=%d before=%s", gotLimit, gotBefore)
+	}
+	lines := strings.Split(transcript, "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 transcript lines, got %d: %q", len(lines), transcript)
+	}
+	if !strings.HasPrefix(lines[0], "alice: api latency is very") || !strings.HasSuffix(lines[0], "…") {
+		t.Errorf("Expected oldest message first and truncated, got %q", lines[0])
+	}
+	if len([]rune(lines[0])) != contextLineMaxRunes {
+		t.Errorf("Expected truncated line of %d runes, got %d", contextLineMaxRunes, len([]rune(lines[0])))
+	}
+	if lines[1] != "bob: checking now" {
+		t.Errorf("Expected collapsed whitespace in 'bob: checking now', got %q", lines[1])
+	}
+}
+
+func TestFetchMessageContext_Disabled(t *testing.T) {
+	mockSess := &MockDiscordSession{}
+	if transcript := fetchMessageContext(mockSess, &discordgo.Message{}, 0); transcript != "" {
+		t.Errorf("Expected empty transcript when includeContext is 0, got %q", transcript)
+	}
+}
@@ -0,0 +1,249 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/user/discord2pushover/controlpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// controlPlaneServer implements the gRPC service of proto/controlplane.proto.
+type controlPlaneServer struct {
+	controlpb.UnimplementedControlPlaneServer
+	config     *Config
+	configPath string
+}
+
+// controlPlaneAuth returns the auth of the control plane: its token as a token of the operator role
+// and the clients of its auth.
+func controlPlaneAuth(controlPlane *ControlPlane) *AdminAuth {
+	auth := &AdminAuth{}
+	if controlPlane.Auth != nil {
+		*auth = *controlPlane.Auth
+		auth.Tokens = append([]AdminToken{}, auth.Tokens...)
+	}
+	if controlPlane.Token != "" {
+		auth.Tokens = append(auth.Tokens, AdminToken{Name: "token", Token: controlPlane.Token, Role: adminRoleOperator})
+	}
+	return auth
+}
+
+// controlPlaneRole returns the role a call requires: operator for changes and deliveries, else read.
+func controlPlaneRole(method string, req any) string {
+	switch method {
+	case controlpb.ControlPlane_ImportRules_FullMethodName:
+		return adminRoleOperator
+	case controlpb.ControlPlane_TestMessage_FullMethodName:
+		if test, ok := req.(*controlpb.TestMessageRequest); ok && test.GetDeliver() {
+			return adminRoleOperator
+		}
+	}
+	return adminRoleRead
+}
+
+// authorizeControlPlane checks that the client of a call has the role the call requires.
+func authorizeControlPlane(ctx context.Context, auth *AdminAuth, method string, req any) error {
+	token := ""
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if given, ok := strings.CutPrefix(value, "Bearer "); ok {
+			token = given
+		}
+	}
+	remote, state := peerTLSState(ctx)
+	switch _, err := auth.authorize("control plane", token, state, remote, path.Base(method), controlPlaneRole(method, req)); err {
+	case errAdminUnauthenticated:
+		return status.Error(codes.Unauthenticated, "missing or invalid bearer token or client certificate")
+	case errAdminForbidden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// controlPlaneServerOptions returns the interceptors authorizing every call, and the TLS credentials if
+// auth has a certificate.
+func controlPlaneServerOptions(auth *AdminAuth) ([]grpc.ServerOption, error) {
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := authorizeControlPlane(ctx, auth, info.FullMethod, req); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorizeControlPlane(stream.Context(), auth, info.FullMethod, nil); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+	if err := auth.validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := auth.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return options, nil
+}
+
+func (s *controlPlaneServer) ListRules(ctx context.Context, req *controlpb.ListRulesRequest) (*controlpb.ListRulesResponse, error) {
+	stats := make(map[string]RuleStats)
+	for _, snapshot := range snapshotRuleStats(s.config) {
+		stats[snapshot.Rule] = snapshot
+	}
+	resp := &controlpb.ListRulesResponse{}
+	for i := range s.config.Rules {
+		rule := &s.config.Rules[i]
+		name := ruleNameForLog(rule, i)
+		resp.Rules = append(resp.Rules, &controlpb.Rule{
+			Name:       name,
+			Event:      ruleEvent(rule),
+			Labels:     rule.Labels,
+			Matched:    stats[name].Matched,
+			Notified:   stats[name].Notified,
+			Suppressed: stats[name].Suppressed,
+		})
+	}
+	return resp, nil
+}
+
+func (s *controlPlaneServer) ExportRules(ctx context.Context, req *controlpb.ExportRulesRequest) (*controlpb.ExportRulesResponse, error) {
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to read config: %v", err)
+	}
+	bundle, notes, err := exportRules(data)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(bundle); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to write bundle: %v", err)
+	}
+	encoder.Close()
+	return &controlpb.ExportRulesResponse{Bundle: out.String(), Notes: notes}, nil
+}
+
+func (s *controlPlaneServer) ImportRules(ctx context.Context, req *controlpb.ImportRulesRequest) (*controlpb.ImportRulesResponse, error) {
+	changes, notes, err := importRulesIntoFile(s.configPath, []byte(req.GetBundle()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	log.Infof("Control plane: imported %d rules into %s; they take effect on restart.", len(changes), s.configPath)
+	return &controlpb.ImportRulesResponse{Changes: changes, Notes: notes}, nil
+}
+
+func (s *controlPlaneServer) TestMessage(ctx context.Context, req *controlpb.TestMessageRequest) (*controlpb.TestMessageResponse, error) {
+	test := TestMessage{
+		ID:           req.GetId(),
+		Content:      req.GetContent(),
+		ChannelID:    req.GetChannelId(),
+		GuildID:      req.GetGuildId(),
+		AuthorID:     req.GetAuthorId(),
+		AuthorName:   req.GetAuthorName(),
+		AuthorBot:    req.GetAuthorBot(),
+		WebhookID:    req.GetWebhookId(),
+		Mentions:     req.GetMentions(),
+		RoleMentions: req.GetRoleMentions(),
+		Reactions:    req.GetReactions(),
+	}
+	event := req.GetEvent()
+	if event == "" {
+		event = ruleEventMessage
+	}
+	message, details, err := test.discordMessage()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	session := newRuleTestSession()
+	resp := &controlpb.TestMessageResponse{MatchedRules: matchingRules(ctx, s.config, event, message, details, session)}
+	if req.GetDeliver() && len(resp.MatchedRules) > 0 {
+		log.Infof("Control plane: delivering test message %s to rules %v.", message.ID, resp.MatchedRules)
+		processRulesForEvent(ctx, event, message, details, s.config, session, math.MaxInt32)
+	}
+	return resp, nil
+}
+
+func (s *controlPlaneServer) StreamEvents(req *controlpb.StreamEventsRequest, stream controlpb.ControlPlane_StreamEventsServer) error {
+	events, unsubscribe := subscribeBotEvents()
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if len(req.GetEvents()) > 0 && !containsFold(req.GetEvents(), event.Event) {
+				continue
+			}
+			if err := stream.Send(controlPlaneEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// controlPlaneEvent converts an event to its protobuf message.
+func controlPlaneEvent(event botEvent) *controlpb.Event {
+	return &controlpb.Event{
+		Event:          event.Event,
+		Time:           event.Time.Format(time.RFC3339Nano),
+		Rule:           event.Rule,
+		Labels:         event.Labels,
+		GuildId:        event.GuildID,
+		ChannelId:      event.ChannelID,
+		MessageId:      event.MessageID,
+		Receipt:        event.Receipt,
+		AcknowledgedBy: event.AcknowledgedBy,
+		Error:          event.Error,
+		Text:           event.Text,
+		Summary:        event.Summary,
+	}
+}
+
+// startControlPlane serves the gRPC control plane in the background. It refuses to start without a
+// token or client certificates and returns nil if it did not start.
+func startControlPlane(config *Config, configPath string) *grpc.Server {
+	controlPlane := config.ControlPlane
+	auth := controlPlaneAuth(controlPlane)
+	if len(auth.Tokens) == 0 && len(auth.ClientRoles) == 0 {
+		log.Errorf("Control plane on %s not started: controlPlane.token or auth is required.", controlPlane.Listen)
+		return nil
+	}
+	options, err := controlPlaneServerOptions(auth)
+	if err != nil {
+		log.Errorf("Control plane on %s not started: %v", controlPlane.Listen, err)
+		return nil
+	}
+	listener, err := adminListen(controlPlane.Listen)
+	if err != nil {
+		log.Errorf("Control plane on %s failed: %v", controlPlane.Listen, err)
+		return nil
+	}
+	server := grpc.NewServer(options...)
+	controlpb.RegisterControlPlaneServer(server, &controlPlaneServer{config: config, configPath: configPath})
+	go func() {
+		defer recoverPanic("startControlPlane")
+		log.Infof("Control plane on %s.", controlPlane.Listen)
+		if err := server.Serve(listener); err != nil {
+			log.Errorf("Control plane on %s failed: %v", controlPlane.Listen, err)
+		}
+	}()
+	return server
+}
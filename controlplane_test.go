@@ -0,0 +1,115 @@
+package discord2pushover
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/user/discord2pushover/controlpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestControlPlane(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := "pushoverAppKey: app\nrules:\n  - name: Alerts\n    conditions:\n      channelId: alerts\n    actions:\n      pushoverDestination: uKey\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		Rules: []Rule{{
+			Name:       "Alerts",
+			Labels:     map[string]string{"team": "infra"},
+			Conditions: RuleConditions{ChannelID: "alerts"},
+			Actions:    RuleActions{PushoverDestination: "uKey"},
+		}},
+	}
+	config.SetPushoverClient(fake)
+
+	listener := bufconn.Listen(1 << 20)
+	options, err := controlPlaneServerOptions(controlPlaneAuth(&ControlPlane{Token: "s3cret"}))
+	if err != nil {
+		t.Fatalf("controlPlaneServerOptions: %v", err)
+	}
+	server := grpc.NewServer(options...)
+	controlpb.RegisterControlPlaneServer(server, &controlPlaneServer{config: config, configPath: configPath})
+	go server.Serve(listener)
+	defer server.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+	client := controlpb.NewControlPlaneClient(conn)
+
+	if _, err := client.ListRules(context.Background(), &controlpb.ListRulesRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected a call without the token to be rejected, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret"), 5*time.Second)
+	defer cancel()
+
+	rules, err := client.ListRules(ctx, &controlpb.ListRulesRequest{})
+	if err != nil || len(rules.Rules) != 1 || rules.Rules[0].Name != "Alerts" || rules.Rules[0].Labels["team"] != "infra" {
+		t.Errorf("Expected the rule Alerts, got %v (err %v)", rules, err)
+	}
+
+	stream, err := client.StreamEvents(ctx, &controlpb.StreamEventsRequest{Events: []string{botEventRuleMatched}})
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	// The subscription is registered once the server handles the call
+	for i := 0; i < 100; i++ {
+		botEventSubscribers.Lock()
+		subscribed := len(botEventSubscribers.channels) > 0
+		botEventSubscribers.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	dryRun, err := client.TestMessage(ctx, &controlpb.TestMessageRequest{ChannelId: "alerts", Content: "disk full", AuthorId: "dave"})
+	if err != nil || len(dryRun.MatchedRules) != 1 || len(fake.sent) != 0 {
+		t.Errorf("Expected a dry run to match Alerts without notifying, got %v, %d sent (err %v)", dryRun, len(fake.sent), err)
+	}
+	if _, err := client.TestMessage(ctx, &controlpb.TestMessageRequest{Id: "m1", ChannelId: "alerts", Content: "disk full", AuthorId: "dave", Deliver: true}); err != nil {
+		t.Fatalf("TestMessage: %v", err)
+	}
+	if len(fake.recipients) != 1 || fake.recipients[0] != "uKey" {
+		t.Errorf("Expected the delivered test message to notify uKey, got %v", fake.recipients)
+	}
+	event, err := stream.Recv()
+	if err != nil || event.Event != botEventRuleMatched || event.Rule != "Alerts" || event.MessageId != "m1" {
+		t.Errorf("Expected the match streamed, got %v (err %v)", event, err)
+	}
+
+	exported, err := client.ExportRules(ctx, &controlpb.ExportRulesRequest{})
+	if err != nil || !strings.Contains(exported.Bundle, "@alerts") {
+		t.Fatalf("Expected a bundle with the destination aliased, got %v (err %v)", exported, err)
+	}
+	bundle := strings.Replace(exported.Bundle, "name: Alerts", "name: Pages", 1)
+	imported, err := client.ImportRules(ctx, &controlpb.ImportRulesRequest{Bundle: bundle})
+	if err != nil || len(imported.Changes) != 1 || imported.Changes[0] != "Added rule 'Pages'" {
+		t.Errorf("Expected the rule Pages added, got %v (err %v)", imported, err)
+	}
+	if data, _ := os.ReadFile(configPath); !strings.Contains(string(data), "name: Pages") {
+		t.Errorf("Expected the config file to have the imported rule, got:\n%s", data)
+	}
+	if _, err := client.ImportRules(ctx, &controlpb.ImportRulesRequest{Bundle: "kind: other"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected an invalid bundle to be rejected, got %v", err)
+	}
+}
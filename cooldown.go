@@ -0,0 +1,46 @@
+package discord2pushover
+
+import (
+	"sync"
+	"time"
+)
+
+// maxAuthorCooldownEntries is the size at which expired cooldowns are pruned.
+const maxAuthorCooldownEntries = 1000
+
+// authorCooldown is the last notification an author triggered for a rule in a channel.
+type authorCooldown struct {
+	Notified time.Time
+	Priority int
+	Window   time.Duration
+}
+
+// authorCooldownTracker holds the cooldowns of rules with perAuthorCooldownSeconds, keyed by rule
+// name, channel and author.
+type authorCooldownTracker struct {
+	mu        sync.Mutex
+	cooldowns map[string]authorCooldown
+}
+
+var authorCooldowns = &authorCooldownTracker{cooldowns: make(map[string]authorCooldown)}
+
+// allow reports whether a match of the rule by the author should be notified: true unless a
+// notification was allowed for the author in the channel within window, except when the priority
+// escalates it. An allowed match starts a new cooldown.
+func (t *authorCooldownTracker) allow(ruleName string, channelID string, authorID string, priority int, window time.Duration, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := ruleName + "\x00" + channelID + "\x00" + authorID
+	if cooldown, ok := t.cooldowns[id]; ok && now.Sub(cooldown.Notified) < window && priority <= cooldown.Priority {
+		return false
+	}
+	if len(t.cooldowns) >= maxAuthorCooldownEntries {
+		for key, cooldown := range t.cooldowns {
+			if now.Sub(cooldown.Notified) >= cooldown.Window {
+				delete(t.cooldowns, key)
+			}
+		}
+	}
+	t.cooldowns[id] = authorCooldown{Notified: now, Priority: priority, Window: window}
+	return true
+}
@@ -0,0 +1,74 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestAuthorCooldownTracker(t *testing.T) {
+	tracker := &authorCooldownTracker{cooldowns: make(map[string]authorCooldown)}
+	now := time.Now()
+
+	if !tracker.allow("r", "c1", "u1", 0, time.Minute, now) {
+		t.Fatal("Expected the first match to be notified")
+	}
+	if tracker.allow("r", "c1", "u1", 0, time.Minute, now.Add(10*time.Second)) {
+		t.Error("Expected a match within the cooldown to be suppressed")
+	}
+	if !tracker.allow("r", "c1", "u2", 0, time.Minute, now.Add(10*time.Second)) {
+		t.Error("Expected another author to be notified")
+	}
+	if !tracker.allow("r", "c2", "u1", 0, time.Minute, now.Add(10*time.Second)) {
+		t.Error("Expected the author in another channel to be notified")
+	}
+	if !tracker.allow("r", "c1", "u1", 1, time.Minute, now.Add(20*time.Second)) {
+		t.Error("Expected an escalation to be notified")
+	}
+	if tracker.allow("r", "c1", "u1", 1, time.Minute, now.Add(70*time.Second)) {
+		t.Error("Expected the escalation to start a new cooldown")
+	}
+	if !tracker.allow("r", "c1", "u1", 0, time.Minute, now.Add(90*time.Second)) {
+		t.Error("Expected a match after the cooldown to be notified")
+	}
+}
+
+func TestProcessRules_PerAuthorCooldown(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+	originalCooldowns := authorCooldowns
+	authorCooldowns = &authorCooldownTracker{cooldowns: make(map[string]authorCooldown)}
+	defer func() { authorCooldowns = originalCooldowns }()
+
+	config := &Config{Rules: []Rule{{
+		Name:                     "Support",
+		Conditions:               RuleConditions{ChannelID: "support"},
+		Actions:                  RuleActions{PushoverDestination: "uKey"},
+		PerAuthorCooldownSeconds: 120,
+	}}}
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+
+	steps := []struct {
+		id       string
+		author   string
+		notified bool
+	}{
+		{"m1", "u1", true},
+		{"m2", "u1", false},
+		{"m3", "u2", true},
+		{"m4", "u1", false},
+	}
+	for _, step := range steps {
+		testHookPushoverSendCalled = false
+		message := &discordgo.Message{ID: step.id, ChannelID: "support", Content: "hello", Author: &discordgo.User{ID: step.author}}
+		ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+		if testHookPushoverSendCalled != step.notified {
+			t.Errorf("Message %s by %s: expected notified=%v, got %v", step.id, step.author, step.notified, testHookPushoverSendCalled)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package discord2pushover
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultCorrelationWindow is how long an incident stays open without updates when a rule sets no correlationWindowSeconds.
+const defaultCorrelationWindow = time.Hour
+
+// incident is the state of one correlated alert: all messages of a rule rendering the same correlation key.
+type incident struct {
+	RuleName  string
+	Key       string
+	ChannelID string // Channel and message of the notification that opened the incident
+	MessageID string
+	Priority  int // Highest priority notified so far
+	Updates   int // Messages correlated into the incident after it was opened
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// incidentTracker holds open incidents keyed by rule name and correlation key.
+type incidentTracker struct {
+	mu   sync.Mutex
+	open map[string]*incident
+}
+
+var incidents = &incidentTracker{open: make(map[string]*incident)}
+
+func incidentID(ruleName string, key string) string {
+	return ruleName + "\x00" + key
+}
+
+// correlationWindow returns how long the rule's incidents stay open without updates.
+func correlationWindow(rule *Rule) time.Duration {
+	if rule.CorrelationWindowSeconds > 0 {
+		return time.Duration(rule.CorrelationWindowSeconds) * time.Second
+	}
+	return defaultCorrelationWindow
+}
+
+// correlationKey renders the rule's correlationKey template for a message. It returns "" if the rule
+// has no correlationKey or it fails to render, in which case the message is handled on its own.
+func correlationKey(rule *Rule, ruleNameLog string, event string, message *discordgo.Message) string {
+	if rule.CorrelationKey == "" {
+		return ""
+	}
+	data := newNotificationData(rule, ruleNameLog, event, message, message.Content, discordMessageLink(message))
+	key, err := renderTemplate(rule.CorrelationKey, rule.Actions.TemplateDefinitions, data)
+	if err != nil {
+		log.Errorf("Rule '%s': correlationKey %v", ruleNameLog, err)
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
+
+// observe records a message correlated to key and reports whether it should be notified: true when
+// no incident is open (or the open one went stale) and when the priority escalates the open incident.
+func (t *incidentTracker) observe(ruleName string, key string, priority int, window time.Duration, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := incidentID(ruleName, key)
+	inc, ok := t.open[id]
+	if ok && now.Sub(inc.LastSeen) > window {
+		delete(t.open, id)
+		ok = false
+	}
+	if !ok {
+		return true
+	}
+	inc.Updates++
+	inc.LastSeen = now
+	return priority > inc.Priority
+}
+
+// record opens the incident for key after a notification was sent, or raises its priority on escalation.
+func (t *incidentTracker) record(ruleName string, key string, message *discordgo.Message, priority int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := incidentID(ruleName, key)
+	if inc, ok := t.open[id]; ok {
+		if priority > inc.Priority {
+			inc.Priority = priority
+		}
+		inc.LastSeen = now
+		return
+	}
+	t.open[id] = &incident{
+		RuleName:  ruleName,
+		Key:       key,
+		ChannelID: message.ChannelID,
+		MessageID: message.ID,
+		Priority:  priority,
+		FirstSeen: now,
+		LastSeen:  now,
+	}
+}
+
+// close removes and returns the open incident for key in channelID, or nil if there is none.
+func (t *incidentTracker) close(ruleName string, key string, channelID string) *incident {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := incidentID(ruleName, key)
+	inc, ok := t.open[id]
+	if !ok || inc.ChannelID != channelID {
+		return nil
+	}
+	delete(t.open, id)
+	return inc
+}
@@ -0,0 +1,96 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestIncidentTracker(t *testing.T) {
+	tracker := &incidentTracker{open: make(map[string]*incident)}
+	now := time.Now()
+	message := &discordgo.Message{ID: "m1", ChannelID: "c1"}
+
+	if !tracker.observe("r", "disk", 0, time.Hour, now) {
+		t.Fatal("Expected a new incident to be notified")
+	}
+	tracker.record("r", "disk", message, 0, now)
+
+	if tracker.observe("r", "disk", 0, time.Hour, now.Add(time.Minute)) {
+		t.Error("Expected an update with the same priority to be suppressed")
+	}
+	if !tracker.observe("r", "disk", 1, time.Hour, now.Add(2*time.Minute)) {
+		t.Error("Expected an escalation to be notified")
+	}
+	tracker.record("r", "disk", &discordgo.Message{ID: "m3", ChannelID: "c1"}, 1, now.Add(2*time.Minute))
+	if !tracker.observe("r", "cpu", 0, time.Hour, now) {
+		t.Error("Expected a different key to be a separate incident")
+	}
+	if !tracker.observe("r", "disk", 1, time.Hour, now.Add(4*time.Hour)) {
+		t.Error("Expected a stale incident to be reopened")
+	}
+
+	tracker.record("r", "disk", message, 1, now)
+	if inc := tracker.close("r", "disk", "other"); inc != nil {
+		t.Error("Expected close in another channel to do nothing")
+	}
+	inc := tracker.close("r", "disk", "c1")
+	if inc == nil || inc.MessageID != "m1" {
+		t.Fatalf("Expected incident opened by m1, got %+v", inc)
+	}
+	if tracker.close("r", "disk", "c1") != nil {
+		t.Error("Expected incident to be closed only once")
+	}
+}
+
+func TestProcessRules_CorrelationKey(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+	originalIncidents := incidents
+	incidents = &incidentTracker{open: make(map[string]*incident)}
+	defer func() { incidents = originalIncidents }()
+
+	config := &Config{Rules: []Rule{{
+		Name:           "Alerts",
+		Conditions:     RuleConditions{ChannelID: "alerts"},
+		CorrelationKey: `{{capture "host=(\\S+)" .Content}}`,
+		Actions: RuleActions{
+			PushoverDestination: "uKey",
+			Priority:            0,
+			SeverityMap:         []SeverityMapping{{Pattern: "(?i)critical", Priority: 1}},
+		},
+		ResolveOn: &ResolveOn{ContentPattern: "(?i)recovered", ResolvedEmoji: "✅"},
+	}}}
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+
+	steps := []struct {
+		id       string
+		content  string
+		notified bool
+	}{
+		{"m1", "warning host=db1", true},
+		{"m2", "warning host=db1", false},
+		{"m3", "warning host=db2", true},
+		{"m4", "critical host=db1", true},
+		{"m5", "critical host=db1", false},
+		{"m6", "recovered host=db1", true}, // Closes the incident, then opens a new one as it matches the rule
+		{"m7", "warning host=db1", false},
+	}
+	for _, step := range steps {
+		testHookPushoverSendCalled = false
+		message := &discordgo.Message{ID: step.id, ChannelID: "alerts", Content: step.content, Author: &discordgo.User{ID: "u1"}}
+		ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+		if testHookPushoverSendCalled != step.notified {
+			t.Errorf("Message %s (%q): expected notified=%v, got %v", step.id, step.content, step.notified, testHookPushoverSendCalled)
+		}
+	}
+	if !strings.Contains(testLogBufferForTest.String(), "msgID=m1, emoji=✅") {
+		t.Error("Expected the message that opened the incident to be marked resolved")
+	}
+}
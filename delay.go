@@ -0,0 +1,174 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// heldNotification is a rule's notification for a message, held back for the rule's delaySeconds.
+type heldNotification struct {
+	RuleName    string
+	ChannelID   string
+	MessageID   string
+	Fingerprint string // The rule's resolveOn fingerprint for the message, to pair it with resolutions
+	timer       *time.Timer
+}
+
+// heldTracker holds delayed notifications, keyed by rule name and message ID.
+type heldTracker struct {
+	mu   sync.Mutex
+	held map[string]*heldNotification
+}
+
+var heldNotifications = &heldTracker{held: make(map[string]*heldNotification)}
+
+// heldDelayUnit is the unit of delaySeconds; tests shorten it.
+var heldDelayUnit = time.Second
+
+func heldKey(ruleName string, messageID string) string {
+	return ruleName + "\x00" + messageID
+}
+
+// hold calls deliver after delay unless the notification is cancelled first. It returns false if the
+// rule's notification for the message is already held, e.g. when an edit re-evaluates the message.
+func (t *heldTracker) hold(n *heldNotification, delay time.Duration, deliver func()) bool {
+	key := heldKey(n.RuleName, n.MessageID)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.held[key]; ok {
+		return false
+	}
+	t.held[key] = n
+	n.timer = time.AfterFunc(delay, func() {
+		t.mu.Lock()
+		due := t.held[key] == n
+		if due {
+			delete(t.held, key)
+		}
+		t.mu.Unlock()
+		if due {
+			deliver()
+		}
+	})
+	return true
+}
+
+// cancel drops the held notifications match returns true for and returns them.
+func (t *heldTracker) cancel(match func(n *heldNotification) bool) []*heldNotification {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var cancelled []*heldNotification
+	for key, n := range t.held {
+		if match(n) {
+			n.timer.Stop()
+			delete(t.held, key)
+			cancelled = append(cancelled, n)
+		}
+	}
+	return cancelled
+}
+
+// ruleNames returns the rules with a notification held for messageID.
+func (t *heldTracker) ruleNames(messageID string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var names []string
+	for _, n := range t.held {
+		if n.MessageID == messageID {
+			names = append(names, n.RuleName)
+		}
+	}
+	return names
+}
+
+// holdNotification holds a rule's notification for delay and then delivers it, unless it is cancelled
+// meanwhile by a resolution, a retraction or the message's deletion.
+func holdNotification(config *Config, n *heldNotification, delay time.Duration, deliver func(ctx context.Context)) {
+	held := heldNotifications.hold(n, delay, func() {
+		defer recoverPanic("holdNotification")
+		log.Infof("Rule '%s': delay for message ID %s passed without cancellation; notifying.", n.RuleName, n.MessageID)
+		ctx, cancel := eventContext(config)
+		defer cancel()
+		deliver(ctx)
+	})
+	if !held {
+		log.Infof("Rule '%s': notification for message ID %s is already held; not holding it again.", n.RuleName, n.MessageID)
+		return
+	}
+	log.Infof("Rule '%s': holding notification for message ID %s for %s (delaySeconds).", n.RuleName, n.MessageID, delay)
+}
+
+// cancelHeld cancels the held notifications match returns true for, counting them as suppressed, and
+// returns how many it cancelled.
+func cancelHeld(match func(n *heldNotification) bool, reason string) int {
+	cancelled := heldNotifications.cancel(match)
+	for _, n := range cancelled {
+		log.Infof("Rule '%s': held notification for message ID %s cancelled: %s.", n.RuleName, n.MessageID, reason)
+		countersFor(n.RuleName).suppressed.Add(1)
+	}
+	return len(cancelled)
+}
+
+// resolveHeld cancels the rule's notifications held for alerts in the channel that message resolves.
+func resolveHeld(ruleNameLog string, fingerprint string, message *discordgo.Message) int {
+	return cancelHeld(func(n *heldNotification) bool {
+		return n.RuleName == ruleNameLog && n.ChannelID == message.ChannelID && n.Fingerprint == fingerprint && n.MessageID != message.ID
+	}, fmt.Sprintf("resolved by message ID %s", message.ID))
+}
+
+// heldRetractions returns the rules with a notification held for messageID that retract on removal of
+// the given emoji, keyed by rule name.
+func heldRetractions(config *Config, messageID string, removed *discordgo.Emoji) map[string]*Rule {
+	retractable := make(map[string]*Rule)
+	for _, name := range heldNotifications.ruleNames(messageID) {
+		rule := ruleByLogName(config, name)
+		if rule == nil || !rule.RetractOnReactionRemove {
+			continue
+		}
+		for _, spec := range rule.Conditions.MessageHasEmoji {
+			if emojiMatches(spec, removed) {
+				retractable[name] = rule
+				break
+			}
+		}
+	}
+	return retractable
+}
+
+// retractHeld cancels the notifications held for message whose triggering emoji reactions were all
+// removed. Returns the number cancelled.
+func retractHeld(message *discordgo.Message, rules map[string]*Rule) int {
+	retracted := 0
+	for name, rule := range rules {
+		if hasUserReaction(message, rule.Conditions.MessageHasEmoji) {
+			continue
+		}
+		retracted += cancelHeld(func(n *heldNotification) bool {
+			return n.RuleName == name && n.MessageID == message.ID
+		}, "its reaction was removed")
+	}
+	return retracted
+}
+
+// dgMessageDelete is the raw handler for discordgo's MessageDelete events.
+func dgMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	defer recoverPanic("dgMessageDelete")
+	messageDeleteLogic(m.ID)
+}
+
+// dgMessageDeleteBulk is the raw handler for discordgo's MessageDeleteBulk events.
+func dgMessageDeleteBulk(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+	defer recoverPanic("dgMessageDeleteBulk")
+	for _, id := range m.Messages {
+		messageDeleteLogic(id)
+	}
+}
+
+// messageDeleteLogic cancels the notifications held for a deleted message.
+func messageDeleteLogic(messageID string) int {
+	return cancelHeld(func(n *heldNotification) bool { return n.MessageID == messageID }, "the message was deleted")
+}
@@ -0,0 +1,139 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestHeldTracker(t *testing.T) {
+	tracker := &heldTracker{held: make(map[string]*heldNotification)}
+	delivered := make(chan string, 2)
+	if !tracker.hold(&heldNotification{RuleName: "Alerts", MessageID: "m1"}, 10*time.Millisecond, func() { delivered <- "m1" }) {
+		t.Fatal("Expected the notification to be held")
+	}
+	if tracker.hold(&heldNotification{RuleName: "Alerts", MessageID: "m1"}, 10*time.Millisecond, func() { delivered <- "again" }) {
+		t.Error("Expected a notification already held not to be held again")
+	}
+	tracker.hold(&heldNotification{RuleName: "Alerts", MessageID: "m2"}, 10*time.Millisecond, func() { delivered <- "m2" })
+	if cancelled := tracker.cancel(func(n *heldNotification) bool { return n.MessageID == "m2" }); len(cancelled) != 1 {
+		t.Errorf("Expected one cancelled notification, got %d", len(cancelled))
+	}
+
+	select {
+	case id := <-delivered:
+		if id != "m1" {
+			t.Errorf("Expected m1 to be delivered, got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the held notification to be delivered after the delay")
+	}
+	select {
+	case id := <-delivered:
+		t.Errorf("Expected only m1 to be delivered, got %s as well", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(tracker.held) != 0 {
+		t.Errorf("Expected nothing held after delivery, got %d", len(tracker.held))
+	}
+}
+
+// shortenHeldDelays makes each delaySeconds last d for the test.
+func shortenHeldDelays(t *testing.T, d time.Duration) {
+	saved := heldDelayUnit
+	heldDelayUnit = d
+	t.Cleanup(func() { heldDelayUnit = saved })
+}
+
+// waitDelivered waits for the fake to send a message and returns its recipient.
+func waitDelivered(t *testing.T, fake *fakePushoverClient) string {
+	t.Helper()
+	select {
+	case recipient := <-fake.delivered:
+		return recipient
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the held notification to be delivered")
+		return ""
+	}
+}
+
+func TestProcessRules_DelaySeconds(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	shortenHeldDelays(t, 50*time.Millisecond)
+
+	fake := &fakePushoverClient{delivered: make(chan string, 4)}
+	config := &Config{
+		PushoverAppKey: "app",
+		Rules: []Rule{{
+			Name:       "Alerts",
+			Conditions: RuleConditions{ChannelID: "alerts", ContentIncludes: []string{"FIRING"}},
+			Actions:    RuleActions{PushoverDestination: "uKey", DelaySeconds: 1},
+			ResolveOn:  &ResolveOn{ContentPattern: `(?i)\[resolved\]`, Fingerprint: `{{capture "alertname=(\\S+)" .Content}}`},
+		}},
+	}
+	config.SetPushoverClient(fake)
+	session := mockSessionForRulesTest("bot")
+	author := &discordgo.User{ID: "grafana"}
+	alert := func(id string) *discordgo.Message {
+		return &discordgo.Message{ID: id, ChannelID: "alerts", Content: "[FIRING] alertname=DiskFull", Author: author}
+	}
+
+	ProcessRules(context.Background(), alert("flapping"), config, session, math.MaxInt32)
+	resolution := &discordgo.Message{ID: "resolved", ChannelID: "alerts", Content: "[RESOLVED] alertname=DiskFull", Author: author}
+	ProcessRules(context.Background(), resolution, config, session, math.MaxInt32)
+
+	ProcessRules(context.Background(), alert("deleted"), config, session, math.MaxInt32)
+	if n := messageDeleteLogic("deleted"); n != 1 {
+		t.Errorf("Expected the deleted message's notification to be cancelled, cancelled %d", n)
+	}
+
+	ProcessRules(context.Background(), alert("real"), config, session, math.MaxInt32)
+	if recipient := waitDelivered(t, fake); recipient != "uKey" {
+		t.Errorf("Expected the alert that was not cancelled to notify uKey, got %s", recipient)
+	}
+	select {
+	case recipient := <-fake.delivered:
+		t.Errorf("Expected only the alert that was not cancelled to notify, %s notified as well", recipient)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestProcessRules_DelaySecondsChargedOnDelivery(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	shortenHeldDelays(t, 50*time.Millisecond)
+	savedBudgets, savedCooldowns := budgets, authorCooldowns
+	budgets = &budgetTracker{windows: make(map[string]*budgetWindow)}
+	authorCooldowns = &authorCooldownTracker{cooldowns: make(map[string]authorCooldown)}
+	defer func() { budgets, authorCooldowns = savedBudgets, savedCooldowns }()
+
+	fake := &fakePushoverClient{delivered: make(chan string, 4)}
+	config := &Config{
+		PushoverAppKey: "app",
+		Rules: []Rule{{
+			Name:                     "Alerts",
+			Conditions:               RuleConditions{ChannelID: "alerts", ContentIncludes: []string{"FIRING"}},
+			Actions:                  RuleActions{PushoverDestination: "uKey", DelaySeconds: 1},
+			Budget:                   &Budget{MaxPerHour: 1},
+			PerAuthorCooldownSeconds: 3600,
+		}},
+	}
+	config.SetPushoverClient(fake)
+	session := mockSessionForRulesTest("bot")
+	author := &discordgo.User{ID: "grafana"}
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		ProcessRules(context.Background(), &discordgo.Message{ID: id, ChannelID: "alerts", Content: "[FIRING]", Author: author}, config, session, math.MaxInt32)
+		if n := messageDeleteLogic(id); n != 1 {
+			t.Fatalf("Expected the notification for %s to be cancelled, cancelled %d", id, n)
+		}
+	}
+	ProcessRules(context.Background(), &discordgo.Message{ID: "real", ChannelID: "alerts", Content: "[FIRING]", Author: author}, config, session, math.MaxInt32)
+	if recipient := waitDelivered(t, fake); recipient != "uKey" {
+		t.Errorf("Expected the alert after the cancelled ones to notify uKey, got %s", recipient)
+	}
+}
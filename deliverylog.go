@@ -0,0 +1,177 @@
+package discord2pushover
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gregdel/pushover"
+)
+
+// deliveryOrigin identifies what a notification was sent for, recorded in the delivery log.
+type deliveryOrigin struct {
+	Rule      string
+	GuildID   string
+	ChannelID string
+	MessageID string
+}
+
+type deliveryOriginKey struct{}
+
+// withDeliveryOrigin returns a context whose notifications are recorded as sent for origin.
+func withDeliveryOrigin(ctx context.Context, origin deliveryOrigin) context.Context {
+	return context.WithValue(ctx, deliveryOriginKey{}, origin)
+}
+
+// deliveryRecord is a line of the delivery log: one notification handed to Pushover, with its response.
+type deliveryRecord struct {
+	Time        time.Time `json:"time"`
+	Rule        string    `json:"rule,omitempty"`
+	GuildID     string    `json:"guildId,omitempty"`
+	ChannelID   string    `json:"channelId,omitempty"`
+	MessageID   string    `json:"messageId,omitempty"` // The Discord message
+	Destination string    `json:"destination"`
+	Device      string    `json:"device,omitempty"`
+	Priority    int       `json:"priority"`
+	Title       string    `json:"title"`
+	Request     string    `json:"request,omitempty"` // Pushover's request ID, to quote to Pushover support
+	Receipt     string    `json:"receipt,omitempty"` // Receipt of an emergency
+	Status      int       `json:"status"`            // Pushover's status, 1 if accepted
+	Errors      []string  `json:"errors,omitempty"`  // Pushover's reasons for rejecting the message
+	Error       string    `json:"error,omitempty"`   // The request failed, e.g. timed out
+}
+
+// deliveryLogMu serializes appends to the delivery log.
+var deliveryLogMu sync.Mutex
+
+// recordDelivery appends a sent notification and Pushover's response to the deliveryLog, if configured.
+func recordDelivery(ctx context.Context, config *Config, message *pushover.Message, destination string, resp *pushover.Response, sendErr error) {
+	if config.DeliveryLog == "" {
+		return
+	}
+	origin, _ := ctx.Value(deliveryOriginKey{}).(deliveryOrigin)
+	record := deliveryRecord{
+		Time:        time.Now(),
+		Rule:        origin.Rule,
+		GuildID:     origin.GuildID,
+		ChannelID:   origin.ChannelID,
+		MessageID:   origin.MessageID,
+		Destination: destination,
+		Device:      message.DeviceName,
+		Priority:    message.Priority,
+		Title:       message.Title,
+	}
+	if resp != nil {
+		record.Request, record.Receipt, record.Status, record.Errors = resp.ID, resp.Receipt, resp.Status, resp.Errors
+	}
+	if sendErr != nil {
+		record.Error = sendErr.Error()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	deliveryLogMu.Lock()
+	defer deliveryLogMu.Unlock()
+	file, err := os.OpenFile(config.DeliveryLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Errorf("Error writing delivery log %s: %v", config.DeliveryLog, err)
+		return
+	}
+	defer file.Close()
+	file.Write(append(line, '\n'))
+}
+
+// findDeliveries returns the records of the delivery log for a Discord message ID, Pushover request ID
+// or receipt.
+func findDeliveries(path string, id string) ([]deliveryRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delivery log: %w", err)
+	}
+	defer file.Close()
+	var records []deliveryRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var record deliveryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // A line cut short by a crash
+		}
+		if record.MessageID == id || record.Request == id || (record.Receipt != "" && record.Receipt == id) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// formatOptionalTime formats a time reported by Pushover, which is nil if it did not happen.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+// verifyDelivery prints what the delivery log recorded for a Discord message ID, Pushover request ID or
+// receipt, and for emergencies what Pushover reports about their delivery and acknowledgement. Pushover
+// reports nothing about the delivery of other messages beyond accepting them.
+func verifyDelivery(ctx context.Context, config *Config, id string, w io.Writer) error {
+	if id == "" {
+		return errors.New("usage: discord2pushover verify-delivery <Discord message ID, Pushover request ID or receipt>")
+	}
+	if config.DeliveryLog == "" {
+		return errors.New("deliveryLog is not configured, so no deliveries were recorded")
+	}
+	records, err := findDeliveries(config.DeliveryLog, id)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no notification for %s in the delivery log", id)
+	}
+	for _, record := range records {
+		fmt.Fprintf(w, "%s  %q to %s", record.Time.Local().Format(time.RFC3339), record.Title, record.Destination)
+		if record.Device != "" {
+			fmt.Fprintf(w, " (device %s)", record.Device)
+		}
+		fmt.Fprintf(w, ", priority %d", record.Priority)
+		if record.Rule != "" {
+			fmt.Fprintf(w, ", rule '%s'", record.Rule)
+		}
+		fmt.Fprintln(w)
+		switch {
+		case record.Error != "":
+			fmt.Fprintf(w, "  Not sent: %s\n", record.Error)
+			continue
+		case record.Status != 1:
+			fmt.Fprintf(w, "  Rejected by Pushover (request %s): %v\n", record.Request, record.Errors)
+			continue
+		}
+		fmt.Fprintf(w, "  Accepted by Pushover (request %s).\n", record.Request)
+		if record.Receipt == "" {
+			fmt.Fprintln(w, "  Pushover reports no delivery status for non-emergency messages; quote the request ID to Pushover support.")
+			continue
+		}
+		details, err := config.pushoverClient().GetReceiptDetails(ctx, record.Receipt)
+		if err != nil {
+			fmt.Fprintf(w, "  Receipt %s: could not be queried: %v\n", record.Receipt, err)
+			continue
+		}
+		fmt.Fprintf(w, "  Receipt %s: last delivered %s, acknowledged %s", record.Receipt, formatOptionalTime(details.LastDeliveredAt), formatOptionalTime(details.AcknowledgedAt))
+		if details.Acknowledged && details.AcknowledgedBy != "" {
+			fmt.Fprintf(w, " by %s", details.AcknowledgedBy)
+		}
+		if details.Expired {
+			fmt.Fprint(w, ", expired")
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDeliveryLog(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		DeliveryLog:    filepath.Join(t.TempDir(), "deliveries.jsonl"),
+		Rules: []Rule{
+			{Name: "Outage", Conditions: RuleConditions{ContentIncludes: []string{"down"}}, Actions: RuleActions{PushoverDestination: "uOncall", Priority: 2, Emergency: &EmergencyParams{Retry: 60, Expire: 600}}},
+			{Name: "Deploys", Conditions: RuleConditions{ContentIncludes: []string{"deploy"}}, Actions: RuleActions{PushoverDestination: "uTeam"}},
+		},
+	}
+	config.SetPushoverClient(fake)
+	session := mockSessionForRulesTest("bot")
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1", Content: "site down", Author: &discordgo.User{ID: "dave"}}, config, session, math.MaxInt32)
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m2", GuildID: "g1", ChannelID: "c1", Content: "deploy done", Author: &discordgo.User{ID: "dave"}}, config, session, math.MaxInt32)
+	SendPushoverText(context.Background(), config, "uAdmin", "discord2pushover", "started", 0)
+
+	records, err := findDeliveries(config.DeliveryLog, "m1")
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected the emergency recorded, got %+v (err %v)", records, err)
+	}
+	if r := records[0]; r.Rule != "Outage" || r.GuildID != "g1" || r.Destination != "uOncall" || r.Request != "req-1" || r.Receipt != "receipt-1" || r.Status != 1 {
+		t.Errorf("Unexpected record %+v", r)
+	}
+	if records, _ := findDeliveries(config.DeliveryLog, "req-3"); len(records) != 1 || records[0].Destination != "uAdmin" || records[0].Rule != "" {
+		t.Errorf("Expected the plain notification recorded without rule, got %+v", records)
+	}
+
+	var out bytes.Buffer
+	if err := verifyDelivery(context.Background(), config, "receipt-1", &out); err != nil || !strings.Contains(out.String(), "Receipt receipt-1: last delivered never, acknowledged never") {
+		t.Errorf("Expected the receipt's status, got %q (err %v)", out.String(), err)
+	}
+	out.Reset()
+	if err := verifyDelivery(context.Background(), config, "m2", &out); err != nil || !strings.Contains(out.String(), "Accepted by Pushover (request req-2)") || !strings.Contains(out.String(), "no delivery status") {
+		t.Errorf("Expected the accepted request, got %q (err %v)", out.String(), err)
+	}
+	if err := verifyDelivery(context.Background(), config, "m9", &out); err == nil {
+		t.Error("Expected an error for an unknown ID")
+	}
+}
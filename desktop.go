@@ -0,0 +1,105 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// defaultDesktopAppName is the sender shown for desktop notifications when appName is unset.
+const defaultDesktopAppName = "discord2pushover"
+
+// desktopMaxBodyLength keeps toasts readable; the full text is in Discord.
+const desktopMaxBodyLength = 500
+
+// desktopGOOS selects the notification command; tests override it.
+var desktopGOOS = runtime.GOOS
+
+// runDesktopCommand runs a notification command; tests replace it to capture the command.
+var runDesktopCommand = func(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// windowsToastScript shows a toast with the title and body passed in environment variables, which
+// avoids quoting them into the script. Toasts must come from a registered app, so they are sent as
+// PowerShell.
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:D2P_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:D2P_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe').Show([Windows.UI.Notifications.ToastNotification]::new($template))`
+
+// macOSNotifyScript shows a notification with the title, body and app name (as subtitle) passed as arguments.
+var macOSNotifyScript = []string{
+	"-e", "on run argv",
+	"-e", "display notification (item 2 of argv) with title (item 1 of argv) subtitle (item 3 of argv)",
+	"-e", "end run",
+}
+
+type desktopNotifier struct {
+	config *DesktopNotifier
+}
+
+// notifySendUrgency maps a Pushover priority to a notify-send urgency level.
+func notifySendUrgency(priority int) string {
+	switch {
+	case priority < 0:
+		return "low"
+	case priority == 0:
+		return "normal"
+	default:
+		return "critical"
+	}
+}
+
+// desktopCommand builds the command showing a notification on the given OS.
+func desktopCommand(goos string, cfg *DesktopNotifier, n *Notification) (*exec.Cmd, error) {
+	appName := cfg.AppName
+	if appName == "" {
+		appName = defaultDesktopAppName
+	}
+	title := n.Title
+	if title == "" {
+		title = appName
+	}
+	body := truncateRunes(n.Body, desktopMaxBodyLength)
+
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		args := []string{"--app-name=" + appName, "--urgency=" + notifySendUrgency(n.Priority)}
+		if cfg.Icon != "" {
+			args = append(args, "--icon="+cfg.Icon)
+		}
+		return exec.Command("notify-send", append(args, "--", title, body)...), nil
+	case "darwin":
+		return exec.Command("osascript", append(append([]string{}, macOSNotifyScript...), title, body, appName)...), nil
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		cmd.Env = append(os.Environ(), "D2P_TITLE="+title, "D2P_BODY="+body)
+		return cmd, nil
+	default:
+		return nil, fmt.Errorf("desktop notifications are not supported on %s", goos)
+	}
+}
+
+// Notify shows the notification on the local desktop.
+func (d *desktopNotifier) Notify(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cmd, err := desktopCommand(desktopGOOS, d.config, n)
+	if err != nil {
+		return err
+	}
+	return runDesktopCommand(cmd)
+}
@@ -0,0 +1,59 @@
+package discord2pushover
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestDesktopCommand(t *testing.T) {
+	n := &Notification{Title: "Deploy", Body: "build #42 finished", Priority: -1}
+
+	cmd, err := desktopCommand("linux", &DesktopNotifier{Icon: "dialog-information"}, n)
+	if err != nil {
+		t.Fatalf("linux: %v", err)
+	}
+	if got := strings.Join(cmd.Args, " "); got != "notify-send --app-name=discord2pushover --urgency=low --icon=dialog-information -- Deploy build #42 finished" {
+		t.Errorf("Unexpected notify-send command %q", got)
+	}
+
+	cmd, err = desktopCommand("darwin", &DesktopNotifier{AppName: "Alerts"}, n)
+	if err != nil {
+		t.Fatalf("darwin: %v", err)
+	}
+	if args := cmd.Args; args[0] != "osascript" || strings.Join(args[len(args)-3:], "|") != "Deploy|build #42 finished|Alerts" {
+		t.Errorf("Unexpected osascript command %q", args)
+	}
+
+	cmd, err = desktopCommand("windows", &DesktopNotifier{}, n)
+	if err != nil {
+		t.Fatalf("windows: %v", err)
+	}
+	if env := strings.Join(cmd.Env, "\n"); !strings.Contains(env, "D2P_TITLE=Deploy\n") || !strings.Contains(env, "D2P_BODY=build #42 finished") {
+		t.Errorf("Expected title and body in the PowerShell environment, got args %q", cmd.Args)
+	}
+
+	if _, err := desktopCommand("plan9", &DesktopNotifier{}, n); err == nil {
+		t.Error("Expected an error for an unsupported OS")
+	}
+}
+
+func TestDesktopNotifier(t *testing.T) {
+	oldGOOS, oldRun := desktopGOOS, runDesktopCommand
+	defer func() { desktopGOOS, runDesktopCommand = oldGOOS, oldRun }()
+	var ran []string
+	desktopGOOS = "linux"
+	runDesktopCommand = func(cmd *exec.Cmd) error {
+		ran = cmd.Args
+		return nil
+	}
+
+	config := &Config{Notifiers: map[string]NotifierConfig{"desktop": {Desktop: &DesktopNotifier{}}}}
+	if err := notifyBackends(context.Background(), config, []string{"desktop"}, &Notification{RuleName: "Chatter", Body: "hi", Priority: 2}); err != nil {
+		t.Fatalf("notifyBackends failed: %v", err)
+	}
+	if strings.Join(ran, " ") != "notify-send --app-name=discord2pushover --urgency=critical -- discord2pushover hi" {
+		t.Errorf("Unexpected command %q", ran)
+	}
+}
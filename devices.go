@@ -0,0 +1,80 @@
+package discord2pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// sendRuleNotification renders and sends a matched rule's notification: once to the destination, or
+// once per device variant if the rule has any. It returns the receipt IDs of the emergency
+// notifications that were sent, even if other variants failed.
+func sendRuleNotification(ctx context.Context, config *Config, rule *Rule, actions *RuleActions, data *NotificationData, ruleNameLog string, link string) ([]string, error) {
+	ctx = withDeliveryOrigin(ctx, deliveryOrigin{Rule: ruleNameLog, GuildID: data.GuildID, ChannelID: data.ChannelID, MessageID: data.MessageID})
+	if len(actions.Devices) == 0 {
+		title, body := renderNotification(rule, data, ruleNameLog)
+		body = withReplyCode(body, data.ReplyCode)
+		receiptID, err := SendPushoverNotification(ctx, config, actions, nil, title, body, link)
+		if receiptID == "" {
+			return nil, err
+		}
+		return []string{receiptID}, err
+	}
+
+	var receiptIDs []string
+	var errs []error
+	for i := range actions.Devices {
+		device := &actions.Devices[i]
+		title, body := renderDeviceVariant(rule, device, data, ruleNameLog)
+		body = withReplyCode(body, data.ReplyCode)
+		receiptID, err := SendPushoverNotification(ctx, config, actions, device, title, body, link)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("device %s: %w", device.Device, err))
+			continue
+		}
+		if receiptID != "" {
+			receiptIDs = append(receiptIDs, receiptID)
+		}
+	}
+	return receiptIDs, errors.Join(errs...)
+}
+
+// renderDeviceVariant renders a device variant's title and body, using the rule's templates for unset fields.
+func renderDeviceVariant(rule *Rule, device *DeviceVariant, data *NotificationData, ruleNameLog string) (title string, body string) {
+	variant := *rule
+	if device.Template != "" {
+		variant.Actions.Template = device.Template
+	}
+	if device.TitleTemplate != "" {
+		variant.Actions.TitleTemplate = device.TitleTemplate
+	}
+	title, body = renderNotification(&variant, data, ruleNameLog)
+	if device.MaxLength > 0 {
+		body = truncateRunes(body, device.MaxLength)
+	}
+	return title, body
+}
+
+// cancelSiblingReceipts cancels the other device variants' emergency notifications for the same alert
+// once one of them has been acknowledged, so the remaining devices stop alerting.
+func cancelSiblingReceipts(ctx context.Context, config *Config, acknowledged TrackedEmergencyMessage) int {
+	cancelled := 0
+	config.trackedEmergencies().Range(func(key, value interface{}) bool {
+		trackedMsg, ok := value.(TrackedEmergencyMessage)
+		if !ok || trackedMsg.DiscordMessageID != acknowledged.DiscordMessageID || trackedMsg.RuleName != acknowledged.RuleName {
+			return true
+		}
+		receiptID := key.(string)
+		if _, loaded := config.trackedEmergencies().LoadAndDelete(receiptID); !loaded {
+			return true
+		}
+		if err := CancelPushoverEmergency(ctx, config, receiptID); err != nil {
+			log.Errorf("Error cancelling emergency (Receipt: %s) after acknowledgement on another device: %v", receiptID, err)
+		} else {
+			log.Infof("Cancelled emergency (Receipt: %s, DiscordMsg: %s): acknowledged on another device.", receiptID, trackedMsg.DiscordMessageID)
+		}
+		cancelled++
+		return true
+	})
+	return cancelled
+}
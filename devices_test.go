@@ -0,0 +1,55 @@
+package discord2pushover
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRenderDeviceVariant(t *testing.T) {
+	rule := &Rule{Actions: RuleActions{TitleTemplate: "{{.RuleName}}"}}
+	data := &NotificationData{RuleName: "Disk", Body: "disk on db1 is at 97% and rising quickly"}
+
+	title, body := renderDeviceVariant(rule, &DeviceVariant{Device: "phone"}, data, "Disk")
+	if title != "Disk" || body != data.Body {
+		t.Errorf("Expected the rule's rendering for a plain variant, got %q / %q", title, body)
+	}
+	title, body = renderDeviceVariant(rule, &DeviceVariant{Device: "watch", TitleTemplate: "!", Template: "{{.Body}}", MaxLength: 12}, data, "Disk")
+	if title != "!" || body != "disk on db1…" {
+		t.Errorf("Expected the watch variant's title and truncated body, got %q / %q", title, body)
+	}
+	if rule.Actions.TitleTemplate != "{{.RuleName}}" {
+		t.Error("Expected the rule's own templates to be left alone")
+	}
+}
+
+func TestSendRuleNotification_DevicesAndSiblings(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+
+	rule := &Rule{Actions: RuleActions{
+		PushoverDestination: "uKey",
+		Priority:            2,
+		Emergency:           &EmergencyParams{Expire: 600, Retry: 60},
+		Devices:             []DeviceVariant{{Device: "phone"}, {Device: "watch", Sound: "siren"}},
+	}}
+	receiptIDs, err := sendRuleNotification(context.Background(), &Config{}, rule, &rule.Actions, &NotificationData{Body: "down"}, "Page", "")
+	if err != nil || len(receiptIDs) != 2 {
+		t.Fatalf("Expected one receipt per device, got %v, %v", receiptIDs, err)
+	}
+
+	acknowledged := TrackedEmergencyMessage{DiscordMessageID: "m1", RuleName: "Page", PushoverReceiptID: "r-phone"}
+	config := &Config{}
+	config.trackedEmergencies().Store("r-watch", TrackedEmergencyMessage{DiscordMessageID: "m1", RuleName: "Page", PushoverReceiptID: "r-watch"})
+	config.trackedEmergencies().Store("r-other", TrackedEmergencyMessage{DiscordMessageID: "m2", RuleName: "Page", PushoverReceiptID: "r-other"})
+	if n := cancelSiblingReceipts(context.Background(), config, acknowledged); n != 1 {
+		t.Errorf("Expected 1 sibling receipt cancelled, got %d", n)
+	}
+	if _, ok := config.trackedEmergencies().Load("r-watch"); ok {
+		t.Error("Expected the watch receipt to be untracked")
+	}
+	if _, ok := config.trackedEmergencies().Load("r-other"); !ok {
+		t.Error("Expected other alerts to stay tracked")
+	}
+}
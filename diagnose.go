@@ -0,0 +1,157 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Application flags reporting privileged gateway intents enabled in the Discord developer portal.
+const (
+	applicationFlagGatewayPresence              = 1 << 12
+	applicationFlagGatewayPresenceLimited       = 1 << 13
+	applicationFlagGatewayGuildMembers          = 1 << 14
+	applicationFlagGatewayGuildMembersLimited   = 1 << 15
+	applicationFlagGatewayMessageContent        = 1 << 18
+	applicationFlagGatewayMessageContentLimited = 1 << 19
+)
+
+// diagnosticResult is one row of the diagnose pass/fail matrix.
+type diagnosticResult struct {
+	Check  string
+	Target string
+	OK     bool
+	Detail string
+}
+
+// diagnoseSession is the subset of discordgo.Session used by the diagnose command.
+type diagnoseSession interface {
+	channelPermissionResolver
+	User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error)
+	Application(appID string) (*discordgo.Application, error)
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+}
+
+var _ diagnoseSession = &discordgo.Session{}
+
+// runDiagnose checks the Discord token, privileged intents, per-channel permissions and Pushover
+// destinations, prints a pass/fail matrix and reports whether every check passed.
+func runDiagnose(session diagnoseSession, config *Config, out io.Writer) bool {
+	results := diagnoseDiscord(session, config)
+	results = append(results, diagnosePushover(config)...)
+	return printDiagnostics(out, results)
+}
+
+// diagnoseDiscord validates the bot token, reports privileged intents and checks permissions in every configured channel.
+func diagnoseDiscord(session diagnoseSession, config *Config) []diagnosticResult {
+	var results []diagnosticResult
+
+	botUser, err := session.User("@me")
+	if err != nil {
+		return append(results, diagnosticResult{Check: "discord token", Target: "-", OK: false, Detail: err.Error()})
+	}
+	tokenResult := diagnosticResult{Check: "discord token", Target: botUser.String(), OK: true, Detail: "bot ID " + botUser.ID}
+	if !botUser.Bot {
+		tokenResult.OK = false
+		tokenResult.Detail = "token does not belong to a bot account"
+	}
+	results = append(results, tokenResult)
+
+	app, err := session.Application("@me")
+	if err != nil {
+		results = append(results, diagnosticResult{Check: "intents", Target: "-", OK: false, Detail: "failed to fetch application: " + err.Error()})
+	} else {
+		hasContent := app.Flags&(applicationFlagGatewayMessageContent|applicationFlagGatewayMessageContentLimited) != 0
+		detail := "enabled"
+		if !hasContent {
+			detail = "not enabled in the developer portal; message text is only visible when the bot is mentioned"
+		}
+		results = append(results, diagnosticResult{Check: "intent: message content", Target: "privileged", OK: hasContent, Detail: detail})
+
+		// Informational only: the bot does not need these, so they never fail the diagnosis.
+		optional := []string{}
+		if app.Flags&(applicationFlagGatewayGuildMembers|applicationFlagGatewayGuildMembersLimited) != 0 {
+			optional = append(optional, "server members")
+		}
+		if app.Flags&(applicationFlagGatewayPresence|applicationFlagGatewayPresenceLimited) != 0 {
+			optional = append(optional, "presence")
+		}
+		if len(optional) > 0 {
+			results = append(results, diagnosticResult{Check: "intents: other privileged", Target: "privileged", OK: true, Detail: strings.Join(optional, ", ")})
+		}
+	}
+
+	channels := configuredChannels(config)
+	channelIDs := make([]string, 0, len(channels))
+	for channelID := range channels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs) // Stable report order across runs
+	for _, channelID := range channelIDs {
+		required := channels[channelID]
+		target := channelID
+		if ch, err := session.Channel(channelID); err == nil && ch.Name != "" {
+			target = fmt.Sprintf("#%s (%s)", ch.Name, channelID)
+		}
+		missing, err := missingChannelPermissions(session, botUser.ID, channelID, required)
+		switch {
+		case err != nil:
+			results = append(results, diagnosticResult{Check: "channel permissions", Target: target, OK: false, Detail: err.Error()})
+		case len(missing) > 0:
+			results = append(results, diagnosticResult{Check: "channel permissions", Target: target, OK: false, Detail: "missing: " + strings.Join(missing, ", ")})
+		default:
+			names := make([]string, 0, len(required))
+			for _, p := range required {
+				names = append(names, p.Name)
+			}
+			results = append(results, diagnosticResult{Check: "channel permissions", Target: target, OK: true, Detail: strings.Join(names, ", ")})
+		}
+	}
+	return results
+}
+
+// diagnosePushover validates every configured Pushover destination against the application key.
+func diagnosePushover(config *Config) []diagnosticResult {
+	var results []diagnosticResult
+	destinations := configuredPushoverDestinations(config)
+	if len(destinations) == 0 {
+		return append(results, diagnosticResult{Check: "pushover destination", Target: "-", OK: true, Detail: "none configured"})
+	}
+	for _, dest := range destinations {
+		ctx, cancel := backgroundContext()
+		err := ValidatePushoverDestination(ctx, config, dest)
+		cancel()
+		if err != nil {
+			results = append(results, diagnosticResult{Check: "pushover destination", Target: dest, OK: false, Detail: err.Error()})
+		} else {
+			results = append(results, diagnosticResult{Check: "pushover destination", Target: dest, OK: true, Detail: "valid"})
+		}
+	}
+	return results
+}
+
+// printDiagnostics writes the results as an aligned table and reports whether all checks passed.
+func printDiagnostics(out io.Writer, results []diagnosticResult) bool {
+	allOK := true
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tTARGET\tRESULT\tDETAIL")
+	for _, r := range results {
+		status := "PASS"
+		if !r.OK {
+			status = "FAIL"
+			allOK = false
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Check, r.Target, status, r.Detail)
+	}
+	w.Flush()
+	if allOK {
+		fmt.Fprintln(out, "\nAll checks passed.")
+	} else {
+		fmt.Fprintln(out, "\nSome checks failed.")
+	}
+	return allOK
+}
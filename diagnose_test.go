@@ -0,0 +1,83 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type fakeDiagnoseSession struct {
+	user        *discordgo.User
+	appFlags    int
+	permissions map[string]int64
+}
+
+func (f *fakeDiagnoseSession) User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error) {
+	if f.user == nil {
+		return nil, fmt.Errorf("HTTP 401 Unauthorized")
+	}
+	return f.user, nil
+}
+
+func (f *fakeDiagnoseSession) Application(appID string) (*discordgo.Application, error) {
+	return &discordgo.Application{Flags: f.appFlags}, nil
+}
+
+func (f *fakeDiagnoseSession) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: channelID, Name: "name-" + channelID}, nil
+}
+
+func (f *fakeDiagnoseSession) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	perms, ok := f.permissions[channelID]
+	if !ok {
+		return 0, fmt.Errorf("HTTP 403 Forbidden")
+	}
+	return perms, nil
+}
+
+func TestDiagnoseDiscord(t *testing.T) {
+	session := &fakeDiagnoseSession{
+		user:     &discordgo.User{ID: "bot1", Username: "pager", Bot: true},
+		appFlags: applicationFlagGatewayMessageContent,
+		permissions: map[string]int64{
+			"chOK":      discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory | discordgo.PermissionAddReactions,
+			"chNoReact": discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory,
+		},
+	}
+	cfg := &Config{Rules: []Rule{
+		{Conditions: RuleConditions{ChannelID: "chOK"}},
+		{Conditions: RuleConditions{ChannelID: "chNoReact"}},
+		{Conditions: RuleConditions{ChannelID: "chForbidden"}},
+	}}
+
+	results := diagnoseDiscord(session, cfg)
+	var out bytes.Buffer
+	if printDiagnostics(&out, results) {
+		t.Fatalf("Expected diagnosis to fail. Output:\n%s", out.String())
+	}
+	output := out.String()
+	for _, expected := range []string{
+		"discord token",
+		"#name-chOK (chOK)",
+		"missing: Add Reactions",
+		"HTTP 403 Forbidden",
+		"Some checks failed.",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected '%s' in output:\n%s", expected, output)
+		}
+	}
+	if forbidden, noReact, ok := strings.Index(output, "chForbidden"), strings.Index(output, "chNoReact"), strings.Index(output, "(chOK)"); !(forbidden < noReact && noReact < ok) {
+		t.Errorf("Expected the channels sorted by ID. Output:\n%s", output)
+	}
+}
+
+func TestDiagnoseDiscord_InvalidToken(t *testing.T) {
+	results := diagnoseDiscord(&fakeDiagnoseSession{}, &Config{})
+	if len(results) != 1 || results[0].OK {
+		t.Fatalf("Expected a single failed token result, got %+v", results)
+	}
+}
@@ -0,0 +1,121 @@
+package discord2pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// discordMessageSender posts messages with embeds into channels.
+type discordMessageSender interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+var _ discordMessageSender = &discordgo.Session{}
+
+// discordPoster is the session discord notifiers post with, set by runBot; nil when the bot is not
+// connected, e.g. in replays or subcommands.
+var discordPoster discordMessageSender
+
+// Embed colors of the priorities, used without an embed color configured.
+var discordPriorityColors = map[int]int{-2: 0x95a5a6, -1: 0x95a5a6, 0: 0x3498db, 1: 0xe67e22, 2: 0xe74c3c}
+
+type discordNotifier struct {
+	config *DiscordNotifier
+}
+
+// renderField renders a template field of the notifier, or returns fallback if it is empty.
+func renderField(source string, fallback string, data *NotificationData) (string, error) {
+	if source == "" {
+		return fallback, nil
+	}
+	return renderTemplate(source, nil, data)
+}
+
+// discordMessage renders the message the notifier posts for a notification.
+func discordMessage(cfg *DiscordNotifier, n *Notification) (*discordgo.MessageSend, error) {
+	data := templateData(n)
+	fallback := strings.TrimSpace(strings.Join([]string{n.Title, n.Body, n.Link}, "\n"))
+	if cfg.Embed != nil {
+		fallback = ""
+	}
+	content, err := renderField(cfg.Template, fallback, data)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	message := &discordgo.MessageSend{
+		Content: truncateRunes(content, 2000), // Discord message limit
+		// Mentions in alert texts are not meant to ping anybody
+		AllowedMentions: &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}},
+	}
+	if cfg.Embed == nil {
+		return message, nil
+	}
+	embed := &discordgo.MessageEmbed{Color: discordPriorityColors[n.Priority]}
+	for _, field := range []struct {
+		name     string
+		source   string
+		fallback string
+		target   *string
+		limit    int
+	}{
+		{"title", cfg.Embed.Title, n.Title, &embed.Title, 256},
+		{"description", cfg.Embed.Description, n.Body, &embed.Description, 4096},
+		{"url", cfg.Embed.URL, n.Link, &embed.URL, 2048},
+	} {
+		value, err := renderField(field.source, field.fallback, data)
+		if err != nil {
+			return nil, fmt.Errorf("embed %s: %w", field.name, err)
+		}
+		*field.target = truncateRunes(value, field.limit)
+	}
+	if cfg.Embed.Color != "" {
+		if embed.Color, err = parseEmbedColor(cfg.Embed.Color); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Embed.Footer != "" {
+		footer, err := renderTemplate(cfg.Embed.Footer, nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("embed footer: %w", err)
+		}
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: truncateRunes(footer, 2048)}
+	}
+	for i, f := range cfg.Embed.Fields {
+		name, err := renderTemplate(f.Name, nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("embed field %d name: %w", i+1, err)
+		}
+		value, err := renderTemplate(f.Value, nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("embed field %d value: %w", i+1, err)
+		}
+		if name == "" || value == "" {
+			continue // Discord rejects empty fields, e.g. of a label the message does not have
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: truncateRunes(name, 256), Value: truncateRunes(value, 1024), Inline: f.Inline})
+	}
+	message.Embeds = []*discordgo.MessageEmbed{embed}
+	return message, nil
+}
+
+// Notify posts the notification into the channel.
+func (d *discordNotifier) Notify(ctx context.Context, n *Notification) error {
+	if d.config.ChannelID == "" {
+		return errors.New("discord notifier requires channelId")
+	}
+	if discordPoster == nil {
+		return errors.New("not connected to Discord")
+	}
+	message, err := discordMessage(d.config, n)
+	if err != nil {
+		return err
+	}
+	if _, err := discordPoster.ChannelMessageSendComplex(d.config.ChannelID, message, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to post to channel %s: %w", d.config.ChannelID, err)
+	}
+	return nil
+}
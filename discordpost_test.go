@@ -0,0 +1,65 @@
+package discord2pushover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeDiscordPoster records the messages posted through it.
+type fakeDiscordPoster struct {
+	channels []string
+	messages []*discordgo.MessageSend
+}
+
+func (f *fakeDiscordPoster) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.channels = append(f.channels, channelID)
+	f.messages = append(f.messages, data)
+	return &discordgo.Message{ID: "posted", ChannelID: channelID}, nil
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	defer func(poster discordMessageSender) { discordPoster = poster }(discordPoster)
+	n := &Notification{
+		RuleName: "Prometheus", Title: "HighCPU", Body: "CPU above 90% on web-1", Link: "https://discord.com/channels/g/c/m", Priority: 1,
+		Data: &NotificationData{RuleName: "Prometheus", Body: "CPU above 90% on web-1", Labels: map[string]string{"team": "infra"}},
+	}
+	plain := &discordNotifier{config: &DiscordNotifier{ChannelID: "ops"}}
+	if err := plain.Notify(context.Background(), n); err == nil {
+		t.Error("Expected an error while not connected to Discord")
+	}
+
+	poster := &fakeDiscordPoster{}
+	discordPoster = poster
+	if err := plain.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if poster.channels[0] != "ops" || poster.messages[0].Content != "HighCPU\nCPU above 90% on web-1\nhttps://discord.com/channels/g/c/m" || len(poster.messages[0].Embeds) != 0 {
+		t.Errorf("Unexpected message %+v", poster.messages[0])
+	}
+
+	embedded := &discordNotifier{config: &DiscordNotifier{ChannelID: "ops", Template: "Alert for {{.Labels.team}}", Embed: &DiscordEmbed{
+		Footer: "rule {{.RuleName}}",
+		Fields: []DiscordEmbedField{{Name: "Team", Value: "{{.Labels.team}}", Inline: true}, {Name: "Runbook", Value: "{{.Labels.runbook}}"}},
+	}}}
+	if err := embedded.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	message := poster.messages[1]
+	if message.Content != "Alert for infra" || len(message.Embeds) != 1 {
+		t.Fatalf("Unexpected message %+v", message)
+	}
+	embed := message.Embeds[0]
+	if embed.Title != "HighCPU" || embed.Description != "CPU above 90% on web-1" || embed.URL != n.Link || embed.Color != 0xe67e22 || embed.Footer.Text != "rule Prometheus" {
+		t.Errorf("Unexpected embed %+v", embed)
+	}
+	if len(embed.Fields) != 1 || embed.Fields[0].Value != "infra" || !embed.Fields[0].Inline {
+		t.Errorf("Expected only the field with a value, got %+v", embed.Fields)
+	}
+
+	invalid := &discordNotifier{config: &DiscordNotifier{ChannelID: "ops", Embed: &DiscordEmbed{Color: "red"}}}
+	if err := invalid.Notify(context.Background(), n); err == nil {
+		t.Error("Expected an error for an invalid embed color")
+	}
+}
@@ -0,0 +1,37 @@
+// Package discord2pushover routes Discord messages to Pushover and other notification backends
+// according to YAML rules.
+//
+// The discord2pushover command in cmd/discord2pushover is a thin wrapper around Main, which runs
+// the bot and returns its exit code. Other Go programs can embed the routing engine instead: load
+// a config with LoadConfig, then hand each new message to ProcessMessage together with a
+// DiscordSessionInterface, which is implemented for a discordgo session by
+// DiscordGoSessionWrapper. Cancelling the context aborts the message's processing and its
+// in-flight notifications. Rules are described by the Rule, RuleConditions and RuleActions types,
+// and backends of the embedding program are made available to rules' notifiers with
+// RegisterNotifier. Logging goes to the embedding program's logger once passed to SetLogger.
+//
+// Emergencies pending acknowledgement are tracked per Config. Cooldowns, budgets, rule statistics
+// and the like are shared by all configs of the process, and the package's expvars are only
+// published by Main.
+//
+// The package is not split into config, rules, notify and discord packages: the rule engine, the
+// notifiers and the Discord handlers share the Config's runtime state (rule index, transport,
+// Pushover client, tracked emergencies) and the trackers above, which a split would have to export.
+// The functions above are the API instead; the Discord handlers and the bot's polling loops, which
+// rely on the state Main sets up, are unexported.
+//
+//	config, err := discord2pushover.LoadConfig("discord2pushover.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	discord2pushover.RegisterNotifier("audit", auditNotifier)
+//	session := &discord2pushover.DiscordGoSessionWrapper{RealSession: dg}
+//	discord2pushover.ProcessMessage(ctx, message, config, session)
+package discord2pushover
+
+import "github.com/sirupsen/logrus"
+
+// SetLogger replaces the logger of the package, e.g. with the embedding program's logger.
+func SetLogger(logger *logrus.Logger) {
+	log = logger
+}
@@ -0,0 +1,109 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Named embed color ranges, matched by hue so that the slightly different reds, oranges and greens
+// used by alerting integrations all fall in the same range.
+var embedColorNames = []string{"red", "orange", "yellow", "green", "blue", "purple", "grey", "black", "white"}
+
+// parseEmbedColor parses a color given as "#rrggbb", "0xrrggbb" or "rrggbb".
+func parseEmbedColor(s string) (int, error) {
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "#"), "0x")
+	color, err := strconv.ParseInt(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return 0, fmt.Errorf("invalid embed color %q", s)
+	}
+	return int(color), nil
+}
+
+// embedColorName returns the named range a color falls in.
+func embedColorName(color int) string {
+	r := float64((color>>16)&0xff) / 255
+	g := float64((color>>8)&0xff) / 255
+	b := float64(color&0xff) / 255
+	maxC := max(r, g, b)
+	minC := min(r, g, b)
+	delta := maxC - minC
+
+	switch {
+	case maxC < 0.15:
+		return "black"
+	case delta/maxC < 0.2:
+		if maxC > 0.85 {
+			return "white"
+		}
+		return "grey"
+	}
+
+	var hue float64
+	switch maxC {
+	case r:
+		hue = 60 * (g - b) / delta
+	case g:
+		hue = 60 * ((b-r)/delta + 2)
+	default:
+		hue = 60 * ((r-g)/delta + 4)
+	}
+	if hue < 0 {
+		hue += 360
+	}
+
+	switch {
+	case hue < 15 || hue >= 330:
+		return "red"
+	case hue < 45:
+		return "orange"
+	case hue < 70:
+		return "yellow"
+	case hue < 170:
+		return "green"
+	case hue < 260:
+		return "blue"
+	default:
+		return "purple"
+	}
+}
+
+// embedColorMatches reports whether color matches spec, which is either a hex color or a named range.
+func embedColorMatches(spec string, color int) (bool, error) {
+	name := strings.ToLower(strings.TrimSpace(spec))
+	if name == "gray" {
+		name = "grey"
+	}
+	for _, known := range embedColorNames {
+		if name == known {
+			return embedColorName(color) == name, nil
+		}
+	}
+	want, err := parseEmbedColor(spec)
+	if err != nil {
+		return false, fmt.Errorf("invalid embed color %q: use #rrggbb or one of %v", spec, embedColorNames)
+	}
+	return color == want, nil
+}
+
+// hasEmbedColor reports whether any of the message's embeds has a color matching any of specs.
+// Embeds without a color never match.
+func hasEmbedColor(message *discordgo.Message, specs []string) (bool, error) {
+	for _, embed := range message.Embeds {
+		if embed == nil || embed.Color == 0 {
+			continue
+		}
+		for _, spec := range specs {
+			matched, err := embedColorMatches(spec, embed.Color)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
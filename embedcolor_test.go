@@ -0,0 +1,68 @@
+package discord2pushover
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseEmbedColor(t *testing.T) {
+	for input, expected := range map[string]int{"#E01E5A": 0xe01e5a, "0x00ff00": 0x00ff00, "0000ff": 0x0000ff} {
+		if color, err := parseEmbedColor(input); err != nil || color != expected {
+			t.Errorf("parseEmbedColor(%q) = %x, %v; expected %x", input, color, err, expected)
+		}
+	}
+	for _, input := range []string{"red", "#fff", ""} {
+		if _, err := parseEmbedColor(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestEmbedColorName(t *testing.T) {
+	colors := map[int]string{
+		0xe01e5a: "red",    // Grafana firing
+		0xd63232: "red",    // Alertmanager critical
+		0xff9900: "orange", // warning
+		0xfade2a: "yellow",
+		0x1b855e: "green", // Grafana resolved
+		0x2eb886: "green",
+		0x3498db: "blue",
+		0x9b59b6: "purple",
+		0x95a5a6: "grey",
+		0x0a0a0a: "black",
+		0xf5f5f5: "white",
+	}
+	for color, expected := range colors {
+		if name := embedColorName(color); name != expected {
+			t.Errorf("embedColorName(%06x) = %s, expected %s", color, name, expected)
+		}
+	}
+}
+
+func TestHasEmbedColor(t *testing.T) {
+	message := &discordgo.Message{Embeds: []*discordgo.MessageEmbed{{Title: "no color"}, {Title: "firing", Color: 0xe01e5a}}}
+
+	tests := []struct {
+		specs    []string
+		expected bool
+	}{
+		{[]string{"red"}, true},
+		{[]string{"green", "RED"}, true},
+		{[]string{"#E01E5A"}, true},
+		{[]string{"green"}, false},
+		{[]string{"#e01e5b"}, false},
+	}
+	for _, tc := range tests {
+		matched, err := hasEmbedColor(message, tc.specs)
+		if err != nil || matched != tc.expected {
+			t.Errorf("hasEmbedColor(%v) = %v, %v; expected %v", tc.specs, matched, err, tc.expected)
+		}
+	}
+	if _, err := hasEmbedColor(message, []string{"crimson"}); err == nil {
+		t.Error("Expected error for unknown color name")
+	}
+	if matched, _ := hasEmbedColor(&discordgo.Message{Content: "plain"}, []string{"black"}); matched {
+		t.Error("Expected a message without embeds not to match")
+	}
+}
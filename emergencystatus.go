@@ -0,0 +1,170 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// minEmergencyRetry is the shortest retry interval Pushover accepts for emergencies.
+const minEmergencyRetry = 30 * time.Second
+
+// emergencyStatus is the status reply of a tracked emergency, which shows the channel how paging
+// progresses. Like the escalation it is shared by all receipts of the alert, so it is edited once
+// per retry cycle.
+type emergencyStatus struct {
+	Sent   time.Time
+	Retry  time.Duration
+	Expiry time.Time
+
+	mu       sync.Mutex
+	replyID  string // The bot's reply, once posted
+	attempt  int    // Attempt the reply shows
+	finished bool
+}
+
+// newEmergencyStatus returns the status reply configured for an emergency, or nil if there is none.
+func newEmergencyStatus(params *EmergencyParams, sent time.Time, expiry time.Time) *emergencyStatus {
+	if params == nil || !params.StatusReply {
+		return nil
+	}
+	retry := time.Duration(params.Retry) * time.Second
+	if retry < minEmergencyRetry {
+		retry = minEmergencyRetry
+	}
+	return &emergencyStatus{Sent: sent, Retry: retry, Expiry: expiry}
+}
+
+// formatEmergencyStatus formats the status reply while paging, e.g. "Paging... attempt 3, 4m until expiry".
+func formatEmergencyStatus(attempt int, remaining time.Duration) string {
+	until := "<1m"
+	if remaining = remaining.Round(time.Minute); remaining >= time.Minute {
+		until = strings.TrimSuffix(remaining.String(), "0s")
+	}
+	return fmt.Sprintf("📟 Paging... attempt %d, %s until expiry", attempt, until)
+}
+
+// updateEmergencyStatus posts the status reply of an unacknowledged emergency, and edits it once
+// Pushover is due to have retried. Failed posts and edits are retried on the next poll.
+func updateEmergencyStatus(session DiscordSessionInterface, trackedMsg TrackedEmergencyMessage, now time.Time) {
+	status := trackedMsg.Status
+	if status == nil {
+		return
+	}
+	status.mu.Lock()
+	defer status.mu.Unlock()
+	attempt := int(now.Sub(status.Sent)/status.Retry) + 1
+	if status.finished || attempt == status.attempt {
+		return
+	}
+	text := formatEmergencyStatus(attempt, status.Expiry.Sub(now))
+	if status.replyID == "" {
+		reply, err := session.ChannelMessageSendReply(trackedMsg.DiscordChannelID, text,
+			&discordgo.MessageReference{MessageID: trackedMsg.DiscordMessageID, ChannelID: trackedMsg.DiscordChannelID})
+		if err != nil {
+			log.Errorf("Error posting emergency status reply to Discord message %s (channel %s): %v", trackedMsg.DiscordMessageID, trackedMsg.DiscordChannelID, err)
+			return
+		}
+		status.replyID = reply.ID
+	} else if _, err := session.ChannelMessageEdit(trackedMsg.DiscordChannelID, status.replyID, text); err != nil {
+		log.Errorf("Error editing emergency status reply %s (channel %s): %v", status.replyID, trackedMsg.DiscordChannelID, err)
+		return
+	}
+	status.attempt = attempt
+}
+
+// finishEmergencyStatus edits the status reply of an emergency that is no longer paging one last
+// time, e.g. to "Acknowledged". Later updates leave it alone.
+func finishEmergencyStatus(session DiscordSessionInterface, trackedMsg TrackedEmergencyMessage, text string) {
+	status := trackedMsg.Status
+	if status == nil {
+		return
+	}
+	status.mu.Lock()
+	defer status.mu.Unlock()
+	if status.finished {
+		return
+	}
+	status.finished = true
+	if status.replyID == "" {
+		return // Ended before the reply was posted
+	}
+	if status.attempt > 1 {
+		text += fmt.Sprintf(" after %d attempts", status.attempt)
+	}
+	if _, err := session.ChannelMessageEdit(trackedMsg.DiscordChannelID, status.replyID, text); err != nil {
+		log.Errorf("Error editing emergency status reply %s (channel %s): %v", status.replyID, trackedMsg.DiscordChannelID, err)
+	}
+}
+
+// countdownEmojis are the countdown reactions from the start of an emergency to its expiry.
+var countdownEmojis = []string{"🔟", "9️⃣", "8️⃣", "7️⃣", "6️⃣", "5️⃣", "4️⃣", "3️⃣", "2️⃣", "1️⃣"}
+
+// emergencyCountdown is the countdown reaction of a tracked emergency, swapped as it approaches
+// its expiry. It is shared by all receipts of the alert.
+type emergencyCountdown struct {
+	Sent   time.Time
+	Expiry time.Time
+
+	mu       sync.Mutex
+	shown    string // Emoji currently on the message
+	finished bool
+}
+
+// newEmergencyCountdown returns the countdown configured for an emergency, or nil if there is none.
+func newEmergencyCountdown(params *EmergencyParams, sent time.Time, expiry time.Time) *emergencyCountdown {
+	if params == nil || !params.CountdownEmoji {
+		return nil
+	}
+	return &emergencyCountdown{Sent: sent, Expiry: expiry}
+}
+
+// countdownEmoji returns the countdown emoji for a time: 🔟 in the first tenth of the expiry
+// period, down to 1️⃣ in its last tenth.
+func (c *emergencyCountdown) countdownEmoji(now time.Time) string {
+	step := 0
+	if total := c.Expiry.Sub(c.Sent); total > 0 {
+		step = int(int64(now.Sub(c.Sent)) * int64(len(countdownEmojis)) / int64(total))
+	}
+	return countdownEmojis[max(0, min(step, len(countdownEmojis)-1))]
+}
+
+// updateEmergencyCountdown swaps the countdown reaction of an unacknowledged emergency once it is
+// due. Failed swaps are retried on the next poll.
+func updateEmergencyCountdown(session DiscordSessionInterface, trackedMsg TrackedEmergencyMessage, now time.Time) {
+	countdown := trackedMsg.Countdown
+	if countdown == nil {
+		return
+	}
+	countdown.mu.Lock()
+	defer countdown.mu.Unlock()
+	emoji := countdown.countdownEmoji(now)
+	if countdown.finished || emoji == countdown.shown {
+		return
+	}
+	if err := session.MessageReactionAdd(trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, reactionEmojiAPIName(emoji)); err != nil {
+		log.Errorf("Error adding countdown emoji '%s' to Discord message %s (channel %s): %v", emoji, trackedMsg.DiscordMessageID, trackedMsg.DiscordChannelID, err)
+		return
+	}
+	removeBotReaction(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, countdown.shown)
+	countdown.shown = emoji
+}
+
+// clearEmergencyCountdown removes the countdown reaction of an emergency that is no longer paging.
+func clearEmergencyCountdown(session DiscordSessionInterface, trackedMsg TrackedEmergencyMessage) {
+	countdown := trackedMsg.Countdown
+	if countdown == nil {
+		return
+	}
+	countdown.mu.Lock()
+	defer countdown.mu.Unlock()
+	if countdown.finished {
+		return
+	}
+	countdown.finished = true
+	removeBotReaction(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, countdown.shown)
+	countdown.shown = ""
+}
@@ -0,0 +1,96 @@
+package discord2pushover
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// statusReplySession records the status replies posted and edited through it.
+type statusReplySession struct {
+	MockDiscordSession
+	replies []string
+	edits   []string
+}
+
+func (s *statusReplySession) ChannelMessageSendReply(channelID, content string, reference *discordgo.MessageReference, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.replies = append(s.replies, reference.MessageID+": "+content)
+	return &discordgo.Message{ID: "status", ChannelID: channelID, Content: content}, nil
+}
+
+func (s *statusReplySession) ChannelMessageEdit(channelID, messageID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.edits = append(s.edits, messageID+": "+content)
+	return &discordgo.Message{ID: messageID, ChannelID: channelID, Content: content}, nil
+}
+
+func TestFormatEmergencyStatus(t *testing.T) {
+	for remaining, want := range map[time.Duration]string{
+		4*time.Minute + 10*time.Second: "📟 Paging... attempt 3, 4m until expiry",
+		time.Hour + 5*time.Minute:      "📟 Paging... attempt 3, 1h5m until expiry",
+		20 * time.Second:               "📟 Paging... attempt 3, <1m until expiry",
+	} {
+		if got := formatEmergencyStatus(3, remaining); got != want {
+			t.Errorf("Expected %q for %s, got %q", want, remaining, got)
+		}
+	}
+}
+
+func TestEmergencyStatusReply(t *testing.T) {
+	if newEmergencyStatus(&EmergencyParams{Retry: 60}, time.Now(), time.Now()) != nil {
+		t.Error("Expected no status reply without statusReply")
+	}
+	sent := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	status := newEmergencyStatus(&EmergencyParams{Retry: 60, Expire: 600, StatusReply: true}, sent, sent.Add(10*time.Minute))
+	tracked := TrackedEmergencyMessage{DiscordMessageID: "alert", DiscordChannelID: "ops", Status: status}
+	session := &statusReplySession{}
+
+	updateEmergencyStatus(session, tracked, sent.Add(5*time.Second))
+	updateEmergencyStatus(session, tracked, sent.Add(30*time.Second)) // Same retry cycle
+	tracked.PushoverReceiptID = "sibling"
+	updateEmergencyStatus(session, tracked, sent.Add(2*time.Minute+5*time.Second))
+	if len(session.replies) != 1 || session.replies[0] != "alert: 📟 Paging... attempt 1, 10m until expiry" {
+		t.Errorf("Expected one status reply to the alert, got %v", session.replies)
+	}
+	if len(session.edits) != 1 || session.edits[0] != "status: 📟 Paging... attempt 3, 8m until expiry" {
+		t.Errorf("Expected the reply edited for the third attempt, got %v", session.edits)
+	}
+
+	finishEmergencyStatus(session, tracked, "✅ Acknowledged")
+	finishEmergencyStatus(session, tracked, "⌛ Expired unacknowledged")
+	updateEmergencyStatus(session, tracked, sent.Add(5*time.Minute))
+	if len(session.edits) != 2 || !strings.HasSuffix(session.edits[1], "✅ Acknowledged after 3 attempts") {
+		t.Errorf("Expected the reply edited once more on acknowledgement, got %v", session.edits)
+	}
+}
+
+func TestEmergencyCountdown(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	if newEmergencyCountdown(&EmergencyParams{Expire: 600}, time.Now(), time.Now()) != nil {
+		t.Error("Expected no countdown without countdownEmoji")
+	}
+	sent := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	countdown := newEmergencyCountdown(&EmergencyParams{Expire: 600, CountdownEmoji: true}, sent, sent.Add(10*time.Minute))
+	for elapsed, want := range map[time.Duration]string{0: "🔟", 59 * time.Second: "🔟", 5 * time.Minute: "5️⃣", 9*time.Minute + 30*time.Second: "1️⃣", 11 * time.Minute: "1️⃣"} {
+		if got := countdown.countdownEmoji(sent.Add(elapsed)); got != want {
+			t.Errorf("Expected %s after %s, got %s", want, elapsed, got)
+		}
+	}
+
+	tracked := TrackedEmergencyMessage{DiscordMessageID: "alert", DiscordChannelID: "ops", Countdown: countdown}
+	session := mockSessionForRulesTest("bot")
+	updateEmergencyCountdown(session, tracked, sent.Add(5*time.Second))
+	updateEmergencyCountdown(session, tracked, sent.Add(30*time.Second)) // Still 🔟
+	updateEmergencyCountdown(session, tracked, sent.Add(61*time.Second))
+	clearEmergencyCountdown(session, tracked)
+	updateEmergencyCountdown(session, tracked, sent.Add(5*time.Minute))
+	logs := testLogBufferForTest.String()
+	if strings.Count(logs, "MessageReactionAdd called") != 2 || !strings.Contains(logs, "emoji=🔟") || !strings.Contains(logs, "emoji=9️⃣") {
+		t.Errorf("Expected 🔟 and then 9️⃣ added. Logs:\n%s", logs)
+	}
+	if strings.Count(logs, "MessageReactionRemove called") != 2 || strings.Contains(logs, "emoji=5️⃣") {
+		t.Errorf("Expected 🔟 swapped for 9️⃣ and 9️⃣ removed once cleared. Logs:\n%s", logs)
+	}
+}
@@ -0,0 +1,207 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Emoji in the config may be written as a Unicode emoji ("🔥"), a custom emoji name ("partyparrot"
+// or ":partyparrot:"), "name:id", or the raw Discord form "<:name:id>" / "<a:name:id>".
+var (
+	customEmojiMention = regexp.MustCompile(`^<a?:([A-Za-z0-9_~]+):(\d+)>$`)
+	customEmojiWithID  = regexp.MustCompile(`^:?([A-Za-z0-9_~]+):(\d+)$`)
+	customEmojiName    = regexp.MustCompile(`^:?([A-Za-z0-9_~]+):?$`)
+)
+
+// emojiSpec is a parsed emoji from the config.
+type emojiSpec struct {
+	Name   string // Unicode emoji (normalized) or custom emoji name
+	ID     string // Custom emoji ID, if given
+	Custom bool
+}
+
+// parseEmojiSpec parses an emoji as written in the config.
+func parseEmojiSpec(s string) emojiSpec {
+	s = strings.TrimSpace(s)
+	if m := customEmojiMention.FindStringSubmatch(s); m != nil {
+		return emojiSpec{Name: m[1], ID: m[2], Custom: true}
+	}
+	if m := customEmojiWithID.FindStringSubmatch(s); m != nil {
+		return emojiSpec{Name: m[1], ID: m[2], Custom: true}
+	}
+	if m := customEmojiName.FindStringSubmatch(s); m != nil {
+		return emojiSpec{Name: m[1], Custom: true}
+	}
+	return emojiSpec{Name: normalizeEmoji(s)}
+}
+
+// normalizeEmoji strips variation selectors so "❤" and "❤️" compare equal.
+func normalizeEmoji(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\uFE0F' || r == '\uFE0E' { // Emoji and text presentation selectors
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// emojiMatches reports whether a reaction's emoji is the emoji written as spec in the config.
+func emojiMatches(spec string, emoji *discordgo.Emoji) bool {
+	if emoji == nil {
+		return false
+	}
+	parsed := parseEmojiSpec(spec)
+	if parsed.ID != "" {
+		return emoji.ID == parsed.ID
+	}
+	if parsed.Custom {
+		return emoji.Name == parsed.Name
+	}
+	return emoji.ID == "" && normalizeEmoji(emoji.Name) == parsed.Name
+}
+
+// ruleReactsWith reports whether emoji is one the rule's actions add to a matched message, i.e. whether
+// a bot reaction with it shows the rule already handled the message.
+func ruleReactsWith(rule *Rule, emoji *discordgo.Emoji) bool {
+	for _, spec := range rule.Actions.ReactionEmoji {
+		if emojiMatches(spec, emoji) {
+			return true
+		}
+	}
+	emergency := rule.Actions.Emergency
+	if emergency == nil {
+		return false
+	}
+	// With removeReactionOnAck the ack emoji is the only marker left on acknowledged messages
+	return (emergency.PendingEmoji != "" && emojiMatches(emergency.PendingEmoji, emoji)) ||
+		(emergency.RemoveReactionOnAck && emergency.AckEmoji != "" && emojiMatches(emergency.AckEmoji, emoji))
+}
+
+// removeBotReaction removes the bot's own reaction with emoji from a message. emoji may be empty.
+func removeBotReaction(session DiscordSessionInterface, channelID string, messageID string, emoji string) {
+	if emoji == "" {
+		return
+	}
+	if errReact := session.MessageReactionRemove(channelID, messageID, reactionEmojiAPIName(emoji), "@me"); errReact != nil {
+		log.Errorf("Error removing reaction '%s' from Discord message %s: %v", emoji, messageID, errReact)
+	}
+}
+
+// customEmojis maps custom emoji names to their "name:id" API form, loaded from the bot's guilds at startup.
+var customEmojis = struct {
+	sync.RWMutex
+	byName map[string]string
+}{byName: make(map[string]string)}
+
+// reactionEmojiAPIName converts an emoji from the config into the form MessageReactionAdd expects:
+// the Unicode emoji itself or "name:id" for custom emoji. Custom emoji given by name only are looked up
+// among the guild emoji loaded at startup.
+func reactionEmojiAPIName(spec string) string {
+	parsed := parseEmojiSpec(spec)
+	switch {
+	case parsed.ID != "":
+		return parsed.Name + ":" + parsed.ID
+	case parsed.Custom:
+		customEmojis.RLock()
+		defer customEmojis.RUnlock()
+		if apiName, ok := customEmojis.byName[parsed.Name]; ok {
+			return apiName
+		}
+		return parsed.Name
+	default:
+		return strings.TrimSpace(spec)
+	}
+}
+
+// guildEmojiDirectory is the subset of discordgo.Session REST calls used to load custom emoji.
+type guildEmojiDirectory interface {
+	discordDirectory
+	GuildEmojis(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Emoji, error)
+}
+
+var _ guildEmojiDirectory = &discordgo.Session{}
+
+// loadCustomEmojis fetches the custom emoji of every guild the bot is in into customEmojis.
+func loadCustomEmojis(dir guildEmojiDirectory) error {
+	guilds, err := fetchAllGuilds(dir)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string)
+	for _, guild := range guilds {
+		emojis, err := dir.GuildEmojis(guild.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list emoji of guild %s: %w", guild.ID, err)
+		}
+		for _, emoji := range emojis {
+			byName[emoji.Name] = emoji.Name + ":" + emoji.ID
+		}
+	}
+	customEmojis.Lock()
+	customEmojis.byName = byName
+	customEmojis.Unlock()
+	log.Debugf("Loaded %d custom emoji from %d guild(s).", len(byName), len(guilds))
+	return nil
+}
+
+// configuredEmojis returns every emoji referenced by the rules, keyed by a description of where it is used.
+func configuredEmojis(config *Config) map[string]string {
+	emojis := make(map[string]string)
+	for i, rule := range config.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("unnamed_rule_%d", i+1)
+		}
+		for _, conditions := range conditionGroups(&rule.Conditions) {
+			for _, emoji := range conditions.MessageHasEmoji {
+				emojis[fmt.Sprintf("rule '%s' messageHasEmoji '%s'", name, emoji)] = emoji
+			}
+		}
+		for _, emoji := range rule.Actions.ReactionEmoji {
+			emojis[fmt.Sprintf("rule '%s' reactionEmoji '%s'", name, emoji)] = emoji
+		}
+		if rule.Actions.Emergency != nil && rule.Actions.Emergency.AckEmoji != "" {
+			emojis[fmt.Sprintf("rule '%s' ackEmoji", name)] = rule.Actions.Emergency.AckEmoji
+		}
+		if rule.Actions.Emergency != nil && rule.Actions.Emergency.PendingEmoji != "" {
+			emojis[fmt.Sprintf("rule '%s' pendingEmoji", name)] = rule.Actions.Emergency.PendingEmoji
+		}
+		if rule.Actions.NotifySubscribersOfEmoji != "" {
+			emojis[fmt.Sprintf("rule '%s' notifySubscribersOfEmoji", name)] = rule.Actions.NotifySubscribersOfEmoji
+		}
+		if rule.ResolveOn != nil && rule.ResolveOn.ResolvedEmoji != "" {
+			emojis[fmt.Sprintf("rule '%s' resolvedEmoji", name)] = rule.ResolveOn.ResolvedEmoji
+		}
+	}
+	return emojis
+}
+
+// validateConfiguredEmojis reports custom emoji in the config that none of the bot's guilds has.
+// It returns the number of unknown emoji.
+func validateConfiguredEmojis(config *Config) int {
+	customEmojis.RLock()
+	knownIDs := make(map[string]bool, len(customEmojis.byName))
+	for _, apiName := range customEmojis.byName {
+		knownIDs[apiName[strings.LastIndex(apiName, ":")+1:]] = true
+	}
+	knownNames := customEmojis.byName
+	customEmojis.RUnlock()
+
+	unknown := 0
+	for where, emoji := range configuredEmojis(config) {
+		parsed := parseEmojiSpec(emoji)
+		switch {
+		case parsed.ID != "" && !knownIDs[parsed.ID]:
+			log.Errorf("Unknown custom emoji '%s' in %s: no guild of the bot has an emoji with ID %s.", emoji, where, parsed.ID)
+			unknown++
+		case parsed.ID == "" && parsed.Custom && knownNames[parsed.Name] == "":
+			log.Errorf("Unknown custom emoji '%s' in %s: no guild of the bot has an emoji named '%s'. Use a Unicode emoji or the name of a custom emoji.", emoji, where, parsed.Name)
+			unknown++
+		}
+	}
+	return unknown
+}
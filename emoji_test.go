@@ -0,0 +1,126 @@
+package discord2pushover
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseEmojiSpec(t *testing.T) {
+	tests := map[string]emojiSpec{
+		"🔥":                      {Name: "🔥"},
+		"❤️":                     {Name: "❤"},
+		"partyparrot":            {Name: "partyparrot", Custom: true},
+		":partyparrot:":          {Name: "partyparrot", Custom: true},
+		"partyparrot:123456":     {Name: "partyparrot", ID: "123456", Custom: true},
+		"<:partyparrot:123456>":  {Name: "partyparrot", ID: "123456", Custom: true},
+		"<a:partyparrot:123456>": {Name: "partyparrot", ID: "123456", Custom: true},
+		" :partyparrot:123456 ":  {Name: "partyparrot", ID: "123456", Custom: true},
+	}
+	for input, expected := range tests {
+		if result := parseEmojiSpec(input); result != expected {
+			t.Errorf("parseEmojiSpec(%q) = %+v, expected %+v", input, result, expected)
+		}
+	}
+}
+
+func TestEmojiMatches(t *testing.T) {
+	heart := &discordgo.Emoji{Name: "❤"}
+	parrot := &discordgo.Emoji{Name: "partyparrot", ID: "123456"}
+	tests := []struct {
+		spec     string
+		emoji    *discordgo.Emoji
+		expected bool
+	}{
+		{"❤️", heart, true},
+		{"❤", &discordgo.Emoji{Name: "❤️"}, true},
+		{"🔥", heart, false},
+		{":partyparrot:", parrot, true},
+		{"partyparrot", parrot, true},
+		{"partyparrot:123456", parrot, true},
+		{"<a:otherparrot:123456>", parrot, true},
+		{"partyparrot:999", parrot, false},
+		{"sadparrot", parrot, false},
+		{"❤", nil, false},
+	}
+	for _, tc := range tests {
+		if result := emojiMatches(tc.spec, tc.emoji); result != tc.expected {
+			t.Errorf("emojiMatches(%q, %+v) = %v, expected %v", tc.spec, tc.emoji, result, tc.expected)
+		}
+	}
+}
+
+func TestLoadAndValidateCustomEmojis(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	customEmojis.RLock()
+	saved := customEmojis.byName
+	customEmojis.RUnlock()
+	defer func() {
+		customEmojis.Lock()
+		customEmojis.byName = saved
+		customEmojis.Unlock()
+	}()
+
+	dir := &fakeDiscordDirectory{
+		guilds: []*discordgo.UserGuild{{ID: "g1"}, {ID: "g2"}},
+		emojis: map[string][]*discordgo.Emoji{
+			"g1": {{Name: "partyparrot", ID: "111"}},
+			"g2": {{Name: "ack", ID: "222"}},
+		},
+	}
+	if err := loadCustomEmojis(dir); err != nil {
+		t.Fatalf("loadCustomEmojis returned error: %v", err)
+	}
+
+	apiNames := map[string]string{
+		":partyparrot:": "partyparrot:111",
+		"ack":           "ack:222",
+		"other:333":     "other:333",
+		"<:other:333>":  "other:333",
+		"❤️":            "❤️",
+		"unknown":       "unknown",
+	}
+	for spec, expected := range apiNames {
+		if result := reactionEmojiAPIName(spec); result != expected {
+			t.Errorf("reactionEmojiAPIName(%q) = %q, expected %q", spec, result, expected)
+		}
+	}
+
+	config := &Config{Rules: []Rule{
+		{
+			Name:       "Known",
+			Conditions: RuleConditions{MessageHasEmoji: []string{"✅", ":partyparrot:"}},
+			Actions:    RuleActions{ReactionEmoji: EmojiList{"ack:222"}, Emergency: &EmergencyParams{AckEmoji: "👍"}},
+		},
+		{
+			Name:       "Unknown",
+			Conditions: RuleConditions{MessageHasEmoji: []string{":sadparrot:"}},
+			Actions:    RuleActions{ReactionEmoji: EmojiList{"ack:999"}},
+		},
+	}}
+	if unknown := validateConfiguredEmojis(config); unknown != 2 {
+		t.Errorf("Expected 2 unknown emoji, got %d. Logs:\n%s", unknown, testLogBufferForTest.String())
+	}
+}
+
+func TestEmojiListYAML(t *testing.T) {
+	tests := map[string]EmojiList{
+		`reactionEmoji: "📟"`:           {"📟"},
+		`reactionEmoji: ["📟", "🔴"]`:    {"📟", "🔴"},
+		"reactionEmoji:\n  - 📟\n  - 🔴": {"📟", "🔴"},
+		`reactionEmoji: ""`:            nil,
+	}
+	for input, expected := range tests {
+		var actions RuleActions
+		if err := yaml.Unmarshal([]byte(input), &actions); err != nil {
+			t.Errorf("Failed to parse %q: %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(actions.ReactionEmoji, expected) {
+			t.Errorf("Parsing %q gave %#v, expected %#v", input, actions.ReactionEmoji, expected)
+		}
+	}
+}
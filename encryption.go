@@ -0,0 +1,155 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables holding the age identity that decrypts the config, as an alternative to --age-identity.
+const (
+	ageKeyEnv     = "DISCORD2PUSHOVER_AGE_KEY"      // The identity itself, "AGE-SECRET-KEY-1..."
+	ageKeyFileEnv = "DISCORD2PUSHOVER_AGE_KEY_FILE" // Path of an identity file
+)
+
+// ageIdentityFile is the identity file selected with --age-identity, passed on to the installed service.
+var ageIdentityFile string
+
+// sopsCommand runs SOPS to decrypt SOPS-encrypted configs; a variable so tests can replace it.
+var sopsCommand = "sops"
+
+// ageIdentityPath returns the identity file given with --age-identity or in the environment, or "".
+func ageIdentityPath() string {
+	if ageIdentityFile != "" {
+		return ageIdentityFile
+	}
+	return os.Getenv(ageKeyFileEnv)
+}
+
+// ageIdentities returns the identities decrypting age-encrypted config values.
+func ageIdentities() ([]age.Identity, error) {
+	if key := os.Getenv(ageKeyEnv); key != "" {
+		identities, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ageKeyEnv, err)
+		}
+		return identities, nil
+	}
+	path := ageIdentityPath()
+	if path == "" {
+		return nil, fmt.Errorf("no age identity: use --age-identity or set %s or %s", ageKeyFileEnv, ageKeyEnv)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read age identity: %w", err)
+	}
+	defer file.Close()
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// isSOPSDocument reports whether a config was encrypted with SOPS, which adds its metadata under "sops".
+func isSOPSDocument(data []byte) bool {
+	doc, err := configDocument(data)
+	if err != nil {
+		return false
+	}
+	return mappingValue(mappingValue(doc, "sops"), "mac") != nil
+}
+
+// decryptSOPS decrypts a SOPS-encrypted config with the sops command. The age identity of the bot is
+// handed to SOPS unless its own environment already names one.
+func decryptSOPS(path string) ([]byte, error) {
+	cmd := exec.Command(sopsCommand, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", path)
+	cmd.Env = os.Environ()
+	if os.Getenv("SOPS_AGE_KEY") == "" && os.Getenv("SOPS_AGE_KEY_FILE") == "" {
+		if key := os.Getenv(ageKeyEnv); key != "" {
+			cmd.Env = append(cmd.Env, "SOPS_AGE_KEY="+key)
+		} else if identity := ageIdentityPath(); identity != "" {
+			cmd.Env = append(cmd.Env, "SOPS_AGE_KEY_FILE="+identity)
+		}
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("%s is encrypted with SOPS but the sops command is not installed", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %v: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// decryptAgeValues replaces the age-encrypted values of a config, ASCII-armored as written by
+// `age --armor`, with their plaintext. Configs without encrypted values are returned as they are.
+func decryptAgeValues(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(armor.Header)) {
+		return data, nil
+	}
+	identities, err := ageIdentities()
+	if err != nil {
+		return nil, fmt.Errorf("config has encrypted values: %w", err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	decrypted := 0
+	var walk func(node *yaml.Node) error
+	walk = func(node *yaml.Node) error {
+		if node.Kind == yaml.ScalarNode && strings.HasPrefix(strings.TrimSpace(node.Value), armor.Header) {
+			r, err := age.Decrypt(armor.NewReader(strings.NewReader(strings.TrimSpace(node.Value))), identities...)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt the value at line %d: %w", node.Line, err)
+			}
+			plaintext, err := io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt the value at line %d: %w", node.Line, err)
+			}
+			// Untagged and plain, so the plaintext is typed like any other value, e.g. a priority as integer
+			node.Value, node.Tag, node.Style = strings.TrimSuffix(string(plaintext), "\n"), "", 0
+			decrypted++
+			return nil
+		}
+		for _, child := range node.Content {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(&root); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, fmt.Errorf("failed to write config: %w", err)
+	}
+	encoder.Close()
+	log.Infof("Decrypted %d encrypted config values.", decrypted)
+	return unescapeAstral(out.Bytes()), nil
+}
+
+// decryptConfig returns the plaintext of a config file: decrypted by SOPS if it was encrypted with
+// SOPS, else with its age-encrypted values decrypted.
+func decryptConfig(path string, data []byte) ([]byte, error) {
+	if isSOPSDocument(data) {
+		log.Infof("Decrypting %s with sops...", path)
+		return decryptSOPS(path)
+	}
+	return decryptAgeValues(data)
+}
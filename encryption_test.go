@@ -0,0 +1,96 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// encryptAgeValue encrypts a value like `age --armor -r recipient`, indented as a YAML block scalar.
+func encryptAgeValue(t *testing.T, recipient age.Recipient, value string, indent string) string {
+	t.Helper()
+	var out bytes.Buffer
+	armored := armor.NewWriter(&out)
+	w, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	w.Write([]byte(value))
+	w.Close()
+	armored.Close()
+	return "|\n" + indent + strings.ReplaceAll(strings.TrimSpace(out.String()), "\n", "\n"+indent)
+}
+
+func TestEncryptedConfigValues(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+	content := "discordToken: " + encryptAgeValue(t, identity.Recipient(), "secret-token", "  ") + `
+pushoverAppKey: app
+rules:
+  - name: Alerts
+    conditions:
+      contentIncludes: ["down"]
+    actions:
+      pushoverDestination: u1
+      priority: ` + encryptAgeValue(t, identity.Recipient(), "1", "        ") + "\n"
+	path := filepath.Join(t.TempDir(), "discord2pushover.yaml")
+	os.WriteFile(path, []byte(content), 0o600)
+
+	t.Setenv(ageKeyEnv, "")
+	t.Setenv(ageKeyFileEnv, "")
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "no age identity") {
+		t.Errorf("Expected an error without identity, got %v", err)
+	}
+
+	t.Setenv(ageKeyEnv, identity.String())
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.DiscordToken != "secret-token" || config.Rules[0].Actions.Priority != 1 {
+		t.Errorf("Expected the values decrypted, got token %q and priority %d", config.DiscordToken, config.Rules[0].Actions.Priority)
+	}
+
+	other, _ := age.GenerateX25519Identity()
+	t.Setenv(ageKeyEnv, "")
+	identityPath := filepath.Join(t.TempDir(), "key.txt")
+	os.WriteFile(identityPath, []byte(other.String()+"\n"), 0o600)
+	t.Setenv(ageKeyFileEnv, identityPath)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected the wrong identity to fail with the value's line, got %v", err)
+	}
+}
+
+func TestSOPSConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "discord2pushover.yaml")
+	os.WriteFile(path, []byte("discordToken: ENC[AES256_GCM,data:abc,type:str]\nsops:\n  mac: ENC[AES256_GCM,data:def,type:str]\n  version: 3.9.0\n"), 0o600)
+	fakeSOPS := filepath.Join(dir, "sops")
+	os.WriteFile(fakeSOPS, []byte("#!/bin/sh\necho \"discordToken: $SOPS_AGE_KEY\"\necho 'pushoverAppKey: app'\n"), 0o700)
+	saved := sopsCommand
+	sopsCommand = fakeSOPS
+	defer func() { sopsCommand = saved }()
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv(ageKeyEnv, "AGE-SECRET-KEY-1FAKE")
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.DiscordToken != "AGE-SECRET-KEY-1FAKE" {
+		t.Errorf("Expected the config decrypted by sops with the bot's identity, got token %q", config.DiscordToken)
+	}
+
+	sopsCommand = "discord2pushover-missing-sops"
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("Expected an error without sops, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package discord2pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	errorBackendDiscord  = "discord"
+	errorBackendPushover = "pushover"
+
+	defaultPushoverFailureThreshold   = 3
+	defaultDisconnectThresholdSeconds = 300
+)
+
+// errorReporter tracks failure state used to decide when to emit meta-alerts.
+type errorReporter struct {
+	mu                       sync.Mutex
+	consecutivePushoverFails int
+	pushoverAlertSent        bool
+	disconnectTimer          *time.Timer
+	disconnectAlertSent      bool
+}
+
+var errReporter errorReporter
+
+// sendErrorNotification delivers a meta-alert, avoiding the failing backend where another one is configured.
+func sendErrorNotification(session DiscordSessionInterface, config *Config, failingBackend string, text string) {
+	if config == nil || config.ErrorNotification == nil {
+		log.Warnf("Error notification not configured, meta-alert only logged: %s", text)
+		return
+	}
+	en := config.ErrorNotification
+
+	useDiscord := en.DiscordChannelID != "" && session != nil
+	usePushover := en.PushoverDestination != ""
+	if failingBackend == errorBackendPushover && useDiscord {
+		usePushover = false
+	}
+	if failingBackend == errorBackendDiscord && usePushover {
+		useDiscord = false
+	}
+
+	if useDiscord {
+		if _, err := session.ChannelMessageSend(en.DiscordChannelID, "⚠️ "+text); err != nil {
+			log.Errorf("Error posting meta-alert to Discord channel %s: %v", en.DiscordChannelID, err)
+		} else {
+			log.Infof("Posted meta-alert to Discord channel %s.", en.DiscordChannelID)
+		}
+	}
+	if usePushover {
+		// The alert often reports a failure of the event's own sends, so it gets its own deadline
+		ctx, cancel := backgroundContext()
+		defer cancel()
+		if err := SendPushoverText(ctx, config, en.PushoverDestination, "discord2pushover error", text, en.Priority); err != nil {
+			log.Errorf("Error sending meta-alert to Pushover destination %s: %v", en.PushoverDestination, err)
+		}
+	}
+}
+
+// reportPushoverResult records the outcome of a Pushover send and emits a meta-alert once
+// consecutive failures reach the configured threshold. A success re-arms the alert. Sends aborted by
+// shutdown are not failures of Pushover and are not counted.
+func reportPushoverResult(session DiscordSessionInterface, config *Config, sendErr error) {
+	if errors.Is(sendErr, context.Canceled) {
+		return
+	}
+	errReporter.mu.Lock()
+	if sendErr == nil {
+		errReporter.consecutivePushoverFails = 0
+		errReporter.pushoverAlertSent = false
+		errReporter.mu.Unlock()
+		return
+	}
+	errReporter.consecutivePushoverFails++
+	fails := errReporter.consecutivePushoverFails
+	threshold := defaultPushoverFailureThreshold
+	if config != nil && config.ErrorNotification != nil && config.ErrorNotification.PushoverFailureThreshold > 0 {
+		threshold = config.ErrorNotification.PushoverFailureThreshold
+	}
+	shouldAlert := fails >= threshold && !errReporter.pushoverAlertSent
+	if shouldAlert {
+		errReporter.pushoverAlertSent = true
+	}
+	errReporter.mu.Unlock()
+
+	if shouldAlert {
+		sendErrorNotification(session, config, errorBackendPushover,
+			fmt.Sprintf("Pushover sends have failed %d times in a row. Last error: %v", fails, sendErr))
+	}
+}
+
+// onDiscordDisconnect arms a timer that emits a meta-alert if the gateway stays disconnected beyond the threshold.
+func onDiscordDisconnect(s *discordgo.Session, d *discordgo.Disconnect) {
+	defer recoverPanic("onDiscordDisconnect")
+	log.Warn("Disconnected from Discord gateway.")
+	recordGatewayDisconnect(time.Now())
+	config := globalConfig
+	threshold := time.Duration(defaultDisconnectThresholdSeconds) * time.Second
+	if config != nil && config.ErrorNotification != nil && config.ErrorNotification.DisconnectThresholdSeconds > 0 {
+		threshold = time.Duration(config.ErrorNotification.DisconnectThresholdSeconds) * time.Second
+	}
+
+	errReporter.mu.Lock()
+	defer errReporter.mu.Unlock()
+	if errReporter.disconnectTimer != nil {
+		return // Already counting from the first disconnect
+	}
+	errReporter.disconnectTimer = time.AfterFunc(threshold, func() {
+		defer recoverPanic("disconnect alert timer")
+		errReporter.mu.Lock()
+		errReporter.disconnectAlertSent = true
+		errReporter.mu.Unlock()
+		sendErrorNotification(nil, config, errorBackendDiscord,
+			fmt.Sprintf("Discord gateway has been disconnected for more than %s.", threshold))
+	})
+}
+
+// onDiscordConnect cancels a pending disconnect alert once the gateway is back.
+func onDiscordConnect(s *discordgo.Session, c *discordgo.Connect) {
+	clearDisconnectAlert(s)
+}
+
+// onDiscordResumed cancels a pending disconnect alert once the gateway session is resumed.
+func onDiscordResumed(s *discordgo.Session, r *discordgo.Resumed) {
+	clearDisconnectAlert(s)
+}
+
+func clearDisconnectAlert(s *discordgo.Session) {
+	defer recoverPanic("clearDisconnectAlert")
+	if outage := recordGatewayReconnect(time.Now()); outage > 0 {
+		log.Infof("Reconnected to Discord gateway after %s.", outage.Round(time.Millisecond))
+	}
+	errReporter.mu.Lock()
+	if errReporter.disconnectTimer != nil {
+		errReporter.disconnectTimer.Stop()
+		errReporter.disconnectTimer = nil
+	}
+	wasAlerted := errReporter.disconnectAlertSent
+	errReporter.disconnectAlertSent = false
+	errReporter.mu.Unlock()
+
+	if wasAlerted {
+		log.Info("Discord gateway connection restored after disconnect alert.")
+		sendErrorNotification(&DiscordGoSessionWrapper{RealSession: s}, globalConfig, "", "Discord gateway connection restored.")
+	}
+}
@@ -0,0 +1,61 @@
+package discord2pushover
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReportPushoverResult_ThresholdRoutesToDiscord(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	originalTestHookDisablePushoverSend := testHookDisablePushoverSend
+	testHookDisablePushoverSend = true
+	defer func() {
+		testHookDisablePushoverSend = originalTestHookDisablePushoverSend
+		testHookPushoverSendCalled = false
+		errReporter = errorReporter{}
+	}()
+	errReporter = errorReporter{}
+
+	mockSess := &MockDiscordSession{}
+	cfg := &Config{
+		PushoverAppKey: "fakeAppKey",
+		ErrorNotification: &ErrorNotification{
+			DiscordChannelID:         "chErrors",
+			PushoverDestination:      "userkey",
+			PushoverFailureThreshold: 2,
+		},
+	}
+	alertLog := "ChannelMessageSend called with: chID=chErrors"
+	sendErr := errors.New("simulated pushover failure")
+
+	reportPushoverResult(mockSess, cfg, sendErr)
+	if strings.Contains(testLogBufferForTest.String(), alertLog) {
+		t.Fatalf("Meta-alert sent before threshold was reached. Log: %s", testLogBufferForTest.String())
+	}
+
+	testHookPushoverSendCalled = false
+	reportPushoverResult(mockSess, cfg, sendErr)
+	if !strings.Contains(testLogBufferForTest.String(), alertLog) {
+		t.Errorf("Expected meta-alert to Discord after threshold. Log: %s", testLogBufferForTest.String())
+	}
+	if testHookPushoverSendCalled {
+		t.Errorf("Meta-alert about Pushover failures should not be routed through Pushover when Discord is configured.")
+	}
+
+	// A third failure must not repeat the alert until a success re-arms it.
+	testLogBufferForTest.Reset()
+	reportPushoverResult(mockSess, cfg, sendErr)
+	if strings.Contains(testLogBufferForTest.String(), alertLog) {
+		t.Errorf("Meta-alert repeated without an intervening success. Log: %s", testLogBufferForTest.String())
+	}
+
+	reportPushoverResult(mockSess, cfg, nil)
+	reportPushoverResult(mockSess, cfg, sendErr)
+	reportPushoverResult(mockSess, cfg, sendErr)
+	if !strings.Contains(testLogBufferForTest.String(), alertLog) {
+		t.Errorf("Expected meta-alert to be re-armed after a successful send. Log: %s", testLogBufferForTest.String())
+	}
+}
@@ -0,0 +1,49 @@
+package discord2pushover
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// defaultEscalateAfter is how long an emergency may stay unacknowledged before it is escalated
+// when escalateAfterSeconds is unset.
+const defaultEscalateAfter = 300 * time.Second
+
+// emergencyEscalation is the escalation step of a tracked emergency. It is shared by all receipts
+// of the alert (one per device variant), so it fires once per alert.
+type emergencyEscalation struct {
+	At           time.Time
+	Notifiers    []string
+	Notification *Notification
+	fired        atomic.Bool
+}
+
+// newEmergencyEscalation returns the escalation configured for an emergency, or nil if there is none.
+func newEmergencyEscalation(params *EmergencyParams, notification *Notification, sent time.Time) *emergencyEscalation {
+	if params == nil || len(params.EscalateTo) == 0 || notification == nil {
+		return nil
+	}
+	after := defaultEscalateAfter
+	if params.EscalateAfterSeconds > 0 {
+		after = time.Duration(params.EscalateAfterSeconds) * time.Second
+	}
+	escalated := *notification
+	escalated.Title = "Unacknowledged: " + notification.Title
+	return &emergencyEscalation{At: sent.Add(after), Notifiers: params.EscalateTo, Notification: &escalated}
+}
+
+// escalateIfDue sends an unacknowledged emergency to its escalation notifiers once the threshold has
+// passed. Returns true if it escalated now.
+func escalateIfDue(ctx context.Context, config *Config, trackedMsg TrackedEmergencyMessage, now time.Time) bool {
+	escalation := trackedMsg.Escalation
+	if escalation == nil || now.Before(escalation.At) || !escalation.fired.CompareAndSwap(false, true) {
+		return false
+	}
+	log.Warnf("Emergency for rule '%s' (DiscordMsg: %s) is still unacknowledged; escalating to %v.",
+		trackedMsg.RuleName, trackedMsg.DiscordMessageID, escalation.Notifiers)
+	if err := notifyBackends(ctx, config, escalation.Notifiers, escalation.Notification); err != nil {
+		log.Errorf("Error escalating emergency for rule '%s' (DiscordMsg: %s): %v", trackedMsg.RuleName, trackedMsg.DiscordMessageID, err)
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package discord2pushover
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// eventStreamKeepalive is how often an idle event stream gets a comment, so proxies keep it open.
+const eventStreamKeepalive = 30 * time.Second
+
+// eventStreamAuthorized checks the bearer token of an event stream request. Browsers' EventSource
+// cannot set headers, so the token may also be given as the token query parameter.
+func eventStreamAuthorized(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		given = r.URL.Query().Get("token")
+	}
+	return given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// serveEventStream returns the handler of /events for clients with the bearer token.
+func serveEventStream(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !eventStreamAuthorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		streamEvents(w, r)
+	}
+}
+
+// streamEvents streams the bot's events as server-sent events named after the event, e.g.
+// "event: ruleMatched". The events query parameter limits the stream to a comma-separated list of events.
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	var names []string
+	if filter := r.URL.Query().Get("events"); filter != "" {
+		names = strings.Split(filter, ",")
+	}
+
+	events, unsubscribe := subscribeBotEvents()
+	defer unsubscribe()
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keeps nginx from buffering the stream
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	log.Infof("Event stream opened by %s.", r.RemoteAddr)
+	defer log.Infof("Event stream of %s closed.", r.RemoteAddr)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case event := <-events:
+			if len(names) > 0 && !containsFold(names, event.Event) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Errorf("Error encoding %s event: %v", event.Event, err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,69 @@
+package discord2pushover
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	server := httptest.NewServer(newAdminMux(&AdminListener{EventsToken: "s3cret"}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected a stream without the token to be refused, got HTTP %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/events?token=s3cret&events=ruleMatched")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got HTTP %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("Expected the connected comment, got %q", line)
+	}
+	reader.ReadString('\n')
+
+	emitBotEvent(nil, botEvent{Event: botEventNotificationFailed, Rule: "Alerts"})
+	emitBotEvent(nil, botEvent{Event: botEventRuleMatched, Rule: "Alerts", MessageID: "m1", Summary: "disk full"})
+	lines := make(chan string)
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- strings.TrimSuffix(line, "\n")
+		}
+	}()
+	var got []string
+	for len(got) < 2 {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected the ruleMatched event, got %q", got)
+		}
+	}
+	if got[0] != "event: ruleMatched" {
+		t.Fatalf("Expected only the ruleMatched event, got %q", got)
+	}
+	var event botEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(got[1], "data: ")), &event); err != nil || event.MessageID != "m1" || event.Summary != "disk full" {
+		t.Errorf("Expected the match as data, got %q (err %v)", got[1], err)
+	}
+}
@@ -0,0 +1,158 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Events posted to eventWebhooks.
+const (
+	botEventStarted               = "started"               // The bot connected to Discord
+	botEventStopping              = "stopping"              // The bot is shutting down
+	botEventRuleMatched           = "ruleMatched"           // A rule matched a message
+	botEventNotificationSent      = "notificationSent"      // A rule's notification was sent
+	botEventNotificationFailed    = "notificationFailed"    // Sending a rule's notification failed
+	botEventEmergencyAcknowledged = "emergencyAcknowledged" // An emergency notification was acknowledged
+	botEventEmergencyExpired      = "emergencyExpired"      // An emergency notification expired unacknowledged
+)
+
+// botEventBuffer is how many events an in-process subscriber may fall behind before events are dropped.
+const botEventBuffer = 64
+
+// botEventSummaryRunes bounds the message text carried in events.
+const botEventSummaryRunes = 200
+
+// eventWebhookSignatureHeader carries the HMAC-SHA256 of the body with the webhook's secret.
+const eventWebhookSignatureHeader = "X-Discord2pushover-Signature"
+
+// botEvent is the JSON body posted to eventWebhooks.
+type botEvent struct {
+	Event          string            `json:"event"`
+	Time           time.Time         `json:"time"`
+	Rule           string            `json:"rule,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	GuildID        string            `json:"guildId,omitempty"`
+	ChannelID      string            `json:"channelId,omitempty"`
+	MessageID      string            `json:"messageId,omitempty"`
+	Receipt        string            `json:"receipt,omitempty"`        // Pushover receipts of emergency notifications, comma-separated
+	AcknowledgedBy string            `json:"acknowledgedBy,omitempty"` // Pushover user key
+	Error          string            `json:"error,omitempty"`
+	Summary        string            `json:"summary,omitempty"` // Start of the matched message's text
+	Text           string            `json:"text,omitempty"`    // Description of started and stopping events
+}
+
+// eventWebhookSignature returns the signature header value of body: "sha256=" and the hex HMAC.
+func eventWebhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postEventWebhook posts an event to one webhook.
+func postEventWebhook(ctx context.Context, httpClient *http.Client, webhook *EventWebhook, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if webhook.Secret != "" {
+		request.Header.Set(eventWebhookSignatureHeader, eventWebhookSignature(webhook.Secret, body))
+	}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// botEventSubscribers receives every event in process, for the control plane's event streams.
+var botEventSubscribers = struct {
+	sync.Mutex
+	channels map[chan botEvent]bool
+}{channels: make(map[chan botEvent]bool)}
+
+// subscribeBotEvents returns a channel receiving the bot's events and a function ending the
+// subscription. Events are dropped while the channel is full rather than holding up the bot.
+func subscribeBotEvents() (<-chan botEvent, func()) {
+	events := make(chan botEvent, botEventBuffer)
+	botEventSubscribers.Lock()
+	botEventSubscribers.channels[events] = true
+	botEventSubscribers.Unlock()
+	return events, func() {
+		botEventSubscribers.Lock()
+		delete(botEventSubscribers.channels, events)
+		botEventSubscribers.Unlock()
+	}
+}
+
+// publishBotEvent hands an event to the in-process subscribers.
+func publishBotEvent(event botEvent) {
+	botEventSubscribers.Lock()
+	defer botEventSubscribers.Unlock()
+	for events := range botEventSubscribers.channels {
+		select {
+		case events <- event:
+		default:
+			log.Warnf("Event subscriber is not keeping up, dropped a %s event.", event.Event)
+		}
+	}
+}
+
+// sendBotEvent posts an event to the eventWebhooks subscribed to it. Failures are logged, never returned,
+// since the events are informational.
+func sendBotEvent(ctx context.Context, config *Config, event botEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+		publishBotEvent(event)
+	}
+	if config == nil || len(config.EventWebhooks) == 0 {
+		return
+	}
+	var body []byte
+	for i := range config.EventWebhooks {
+		webhook := &config.EventWebhooks[i]
+		if len(webhook.Events) > 0 && !containsFold(webhook.Events, event.Event) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(event); err != nil {
+				log.Errorf("Error encoding %s event: %v", event.Event, err)
+				return
+			}
+		}
+		if err := postEventWebhook(ctx, notifierHTTPClient(config), webhook, body); err != nil {
+			log.Errorf("Error posting %s event to webhook %s: %v", event.Event, webhook.URL, err)
+			continue
+		}
+		log.Debugf("Posted %s event to webhook %s.", event.Event, webhook.URL)
+	}
+}
+
+// emitBotEvent publishes an event and posts it to the eventWebhooks in the background, so slow endpoints don't hold up
+// message processing.
+func emitBotEvent(config *Config, event botEvent) {
+	event.Time = time.Now().UTC()
+	publishBotEvent(event)
+	if config == nil || len(config.EventWebhooks) == 0 {
+		return
+	}
+	go func() {
+		defer recoverPanic("emitBotEvent")
+		ctx, cancel := backgroundContext()
+		defer cancel()
+		sendBotEvent(ctx, config, event)
+	}()
+}
@@ -0,0 +1,75 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSendBotEvent(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	type received struct {
+		path      string
+		signature string
+		event     botEvent
+	}
+	requests := make(chan received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event botEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("Invalid event body %s: %v", body, err)
+		}
+		if signature := r.Header.Get(eventWebhookSignatureHeader); signature != "" && signature != eventWebhookSignature("s3cret", body) {
+			t.Errorf("Unexpected signature %s", signature)
+		}
+		requests <- received{path: r.URL.Path, signature: r.Header.Get(eventWebhookSignatureHeader), event: event}
+	}))
+	defer server.Close()
+
+	config := &Config{EventWebhooks: []EventWebhook{
+		{URL: server.URL + "/all", Secret: "s3cret"},
+		{URL: server.URL + "/matches", Events: []string{botEventRuleMatched}},
+	}}
+	sendBotEvent(context.Background(), config, botEvent{Event: botEventStarted, Text: "started"})
+	first := <-requests
+	if first.path != "/all" || first.signature == "" || first.event.Event != botEventStarted || first.event.Time.IsZero() {
+		t.Errorf("Expected a signed started event to /all only, got %+v", first)
+	}
+	select {
+	case r := <-requests:
+		t.Errorf("Expected /matches not to get a started event, got %+v", r)
+	default:
+	}
+
+	config.Rules = []Rule{{Name: "Alerts", Labels: map[string]string{"team": "infra"}, Conditions: RuleConditions{ChannelID: "alerts"},
+		Actions: RuleActions{PushoverDestination: "uKey"}}} // No app key, so the send fails
+	message := &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "alerts", Content: "disk full", Author: &discordgo.User{ID: "dave"}}
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+
+	events := make(map[string]botEvent)
+	for len(events) < 3 {
+		select {
+		case r := <-requests:
+			events[r.path+" "+r.event.Event] = r.event
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected three events, got %v", events)
+		}
+	}
+	matched, ok := events["/matches ruleMatched"]
+	if !ok || matched.Rule != "Alerts" || matched.MessageID != "m1" || matched.Labels["team"] != "infra" {
+		t.Errorf("Expected the match at /matches, got %+v", events)
+	}
+	if failed, ok := events["/all notificationFailed"]; !ok || failed.Error == "" {
+		t.Errorf("Expected the failed send with its error at /all, got %+v", events)
+	}
+}
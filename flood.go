@@ -0,0 +1,167 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultFloodWindow is the burst window when a rule's flood block sets no windowSeconds.
+const defaultFloodWindow = 60 * time.Second
+
+// floodState tracks a rule's recent matches in one channel.
+type floodState struct {
+	recent     []time.Time // Matches within the window while not flooding
+	flooding   bool
+	burstStart time.Time // Start of the period the next summary covers
+	matches    int       // Matches in that period, including those notified individually
+	collapsed  int       // Matches in that period that were not notified
+}
+
+// floodTracker detects bursts of matches, keyed by rule name and channel.
+type floodTracker struct {
+	mu     sync.Mutex
+	states map[string]*floodState
+}
+
+var floods = &floodTracker{states: make(map[string]*floodState)}
+
+func floodKey(ruleName string, channelID string) string {
+	return ruleName + "\x00" + channelID
+}
+
+// floodWindow returns the rule's burst window.
+func floodWindow(flood *FloodControl) time.Duration {
+	if flood.WindowSeconds > 0 {
+		return time.Duration(flood.WindowSeconds) * time.Second
+	}
+	return defaultFloodWindow
+}
+
+// observe records a match and reports whether it is collapsed into a summary instead of being
+// notified. started is true for the match that begins a flood; the caller then schedules a summary.
+func (t *floodTracker) observe(key string, flood *FloodControl, now time.Time) (collapse bool, started bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[key]
+	if !ok {
+		state = &floodState{}
+		t.states[key] = state
+	}
+	if state.flooding {
+		state.matches++
+		state.collapsed++
+		return true, false
+	}
+
+	window := floodWindow(flood)
+	i := 0
+	for i < len(state.recent) && now.Sub(state.recent[i]) > window {
+		i++
+	}
+	state.recent = append(state.recent[i:], now)
+	if flood.Threshold <= 0 || len(state.recent) < flood.Threshold {
+		return false, false
+	}
+	state.flooding = true
+	state.burstStart = state.recent[0]
+	state.matches = len(state.recent)
+	state.collapsed = 1
+	state.recent = nil
+	return true, true
+}
+
+// flush ends a summary period. It returns the number of matches and the period length to report, or
+// zero matches if nothing was collapsed, in which case the flood is over. Otherwise the flood goes on
+// and another summary is due after the next window.
+func (t *floodTracker) flush(key string, now time.Time) (matches int, period time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[key]
+	if !ok || !state.flooding {
+		return 0, 0
+	}
+	if state.collapsed == 0 {
+		delete(t.states, key)
+		return 0, 0
+	}
+	matches, period = state.matches, now.Sub(state.burstStart)
+	state.burstStart = now
+	state.matches = 0
+	state.collapsed = 0
+	return matches, period
+}
+
+// channelLabel returns "#name" for a channel in the session state, or its ID.
+func channelLabel(session DiscordSessionInterface, channelID string) string {
+	if state := session.State(); state != nil {
+		if channel, err := state.Channel(channelID); err == nil && channel.Name != "" {
+			return "#" + channel.Name
+		}
+	}
+	return "channel " + channelID
+}
+
+// collapseFlood checks a match against the rule's flood settings. A collapsed message is only logged;
+// the first collapsed message of a flood schedules the summary.
+func collapseFlood(session DiscordSessionInterface, config *Config, rule *Rule, actions RuleActions, ruleNameLog string, message *discordgo.Message) bool {
+	if rule.Flood == nil {
+		return false
+	}
+	key := floodKey(ruleNameLog, message.ChannelID)
+	collapse, started := floods.observe(key, rule.Flood, time.Now())
+	if !collapse {
+		return false
+	}
+	author := ""
+	if message.Author != nil {
+		author = message.Author.Username
+	}
+	log.Infof("Flood: collapsed match of rule '%s' (message ID %s, channel %s, author %s): %.200s",
+		ruleNameLog, message.ID, message.ChannelID, author, message.Content)
+	if started {
+		log.Warnf("Flood detected for rule '%s' in channel %s; collapsing further matches into summaries.", ruleNameLog, message.ChannelID)
+		scheduleFloodSummary(session, config, actions, ruleNameLog, message.ChannelID, floodWindow(rule.Flood))
+	}
+	return true
+}
+
+// scheduleFloodSummary sends a summary of the flood after each window until a window passes without
+// collapsed matches.
+func scheduleFloodSummary(session DiscordSessionInterface, config *Config, actions RuleActions, ruleNameLog string, channelID string, window time.Duration) {
+	time.AfterFunc(window, func() {
+		defer recoverPanic("scheduleFloodSummary")
+		matches, period := floods.flush(floodKey(ruleNameLog, channelID), time.Now())
+		if matches == 0 {
+			log.Infof("Flood for rule '%s' in channel %s is over.", ruleNameLog, channelID)
+			return
+		}
+		text := fmt.Sprintf("%d messages matched rule '%s' in %s in the last %s",
+			matches, ruleNameLog, channelLabel(session, channelID), period.Round(time.Second))
+		ctx, cancel := backgroundContext()
+		defer cancel()
+		sendFloodSummary(ctx, config, actions, ruleNameLog, text)
+		scheduleFloodSummary(session, config, actions, ruleNameLog, channelID, window)
+	})
+}
+
+// sendFloodSummary notifies the rule's destinations about a flood. Emergencies are sent as high
+// priority, since a summary can't be acknowledged per message.
+func sendFloodSummary(ctx context.Context, config *Config, actions RuleActions, ruleNameLog string, text string) {
+	priority := min(actions.Priority, 1)
+	log.Infof("Sending flood summary for rule '%s': %s", ruleNameLog, text)
+	if actions.PushoverDestination != "" {
+		if err := SendPushoverText(ctx, config, actions.PushoverDestination, "Flood: "+ruleNameLog, text, priority); err != nil {
+			log.Errorf("Error sending flood summary for rule '%s': %v", ruleNameLog, err)
+		}
+	}
+	if len(actions.Notify) > 0 {
+		notification := &Notification{RuleName: ruleNameLog, Title: "Flood: " + ruleNameLog, Body: text, Priority: priority}
+		if err := notifyBackends(ctx, config, actions.Notify, notification); err != nil {
+			log.Errorf("Error sending flood summary for rule '%s': %v", ruleNameLog, err)
+		}
+	}
+}
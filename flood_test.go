@@ -0,0 +1,92 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestFloodTracker(t *testing.T) {
+	tracker := &floodTracker{states: make(map[string]*floodState)}
+	flood := &FloodControl{Threshold: 3, WindowSeconds: 60}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := floodKey("Alerts", "c1")
+
+	// Matches spread wider than the window never flood
+	for i := 0; i < 5; i++ {
+		if collapse, _ := tracker.observe(key, flood, start.Add(time.Duration(i)*31*time.Second)); collapse {
+			t.Fatalf("Match %d collapsed without a burst", i+1)
+		}
+	}
+
+	burst := start.Add(time.Hour)
+	tracker.observe(key, flood, burst)
+	tracker.observe(key, flood, burst.Add(time.Second))
+	collapse, started := tracker.observe(key, flood, burst.Add(2*time.Second))
+	if !collapse || !started {
+		t.Fatalf("Expected the third match within the window to start a flood, got %v %v", collapse, started)
+	}
+	for i := 3; i < 14; i++ {
+		if collapse, started := tracker.observe(key, flood, burst.Add(time.Duration(i)*time.Second)); !collapse || started {
+			t.Fatalf("Expected match %d to be collapsed into the running flood", i+1)
+		}
+	}
+	if other, _ := tracker.observe(floodKey("Alerts", "c2"), flood, burst); other {
+		t.Error("A flood in one channel must not affect another")
+	}
+
+	matches, period := tracker.flush(key, burst.Add(60*time.Second))
+	if matches != 14 || period != 60*time.Second {
+		t.Errorf("Expected 14 matches in 60s, got %d in %s", matches, period)
+	}
+	tracker.observe(key, flood, burst.Add(90*time.Second))
+	if matches, _ := tracker.flush(key, burst.Add(120*time.Second)); matches != 1 {
+		t.Errorf("Expected the flood to continue with 1 match, got %d", matches)
+	}
+	if matches, _ := tracker.flush(key, burst.Add(180*time.Second)); matches != 0 {
+		t.Errorf("Expected the flood to end after a quiet window, got %d", matches)
+	}
+	if collapse, _ := tracker.observe(key, flood, burst.Add(181*time.Second)); collapse {
+		t.Error("Expected matches to notify again after the flood ended")
+	}
+}
+
+func TestProcessRules_FloodCollapsed(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() {
+		testHookDisablePushoverSend = false
+		testHookPushoverSendCalled = false
+		floods = &floodTracker{states: make(map[string]*floodState)}
+	}()
+
+	config := &Config{Rules: []Rule{{
+		Name:       "Alerts",
+		Conditions: RuleConditions{ChannelID: "alerts"},
+		Actions:    RuleActions{PushoverDestination: "user", Priority: 1},
+		Flood:      &FloodControl{Threshold: 2},
+	}}}
+	session := mockSessionForRulesTest("bot")
+	for _, id := range []string{"m1", "m2", "m3"} {
+		testHookPushoverSendCalled = false
+		ProcessRules(context.Background(), &discordgo.Message{ID: id, ChannelID: "alerts", Content: "alert " + id, Author: &discordgo.User{Username: "grafana"}}, config, session, math.MaxInt32)
+		if sent := testHookPushoverSendCalled; sent != (id == "m1") {
+			t.Errorf("Message %s: Pushover sent = %v", id, sent)
+		}
+	}
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, "Flood: collapsed match of rule 'Alerts' (message ID m3, channel alerts, author grafana): alert m3") {
+		t.Errorf("Expected collapsed messages to be logged. Logs:\n%s", logs)
+	}
+
+	testHookPushoverSendCalled = false
+	sendFloodSummary(context.Background(), config, config.Rules[0].Actions, "Alerts", "2 messages matched rule 'Alerts' in channel alerts in the last 1m0s")
+	if !testHookPushoverSendCalled {
+		t.Error("Expected the flood summary to be sent via Pushover")
+	}
+}
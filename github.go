@@ -0,0 +1,86 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// "[owner/repo] ..." or, for pushes, "[owner/repo:branch] ..."
+	githubTitlePattern = regexp.MustCompile(`^\[([\w.-]+/[\w.-]+)(?::([^\]]+))?\]\s*(.+)$`)
+
+	githubPullRequestPattern = regexp.MustCompile(`(?i)^pull request (\w[\w ]*?): #(\d+) (.*)$`)
+	githubReviewPattern      = regexp.MustCompile(`(?i)^pull request review (\w[\w ]*?): #(\d+) (.*)$`)
+	githubIssuePattern       = regexp.MustCompile(`(?i)^issue (\w[\w ]*?): #(\d+) (.*)$`)
+	githubCommentPattern     = regexp.MustCompile(`(?i)^new comment on (pull request|issue|commit) #?(\w+): ?(.*)$`)
+	githubPushPattern        = regexp.MustCompile(`(?i)^(\d+) new commits?$`)
+	githubReleasePattern     = regexp.MustCompile(`(?i)^new release published: (.*)$`)
+)
+
+// parseGitHubPayload parses a notification of GitHub's Discord-compatible webhook: repo, branch
+// (pushes), event ("pull_request", "pull_request_review", "issue", "comment", "push", "release" or
+// "other"), action (e.g. "opened"), number, title, actor and url.
+func parseGitHubPayload(message *discordgo.Message) map[string]string {
+	for _, embed := range message.Embeds {
+		if embed == nil {
+			continue
+		}
+		m := githubTitlePattern.FindStringSubmatch(embed.Title)
+		if m == nil {
+			continue
+		}
+		payload := map[string]string{"repo": m[1], "branch": m[2], "event": "other", "title": m[3], "url": embed.URL}
+		if embed.Author != nil {
+			payload["actor"] = embed.Author.Name
+		}
+		rest := m[3]
+		if m := githubReviewPattern.FindStringSubmatch(rest); m != nil {
+			payload["event"], payload["action"], payload["number"], payload["title"] = "pull_request_review", strings.ToLower(m[1]), m[2], m[3]
+		} else if m := githubPullRequestPattern.FindStringSubmatch(rest); m != nil {
+			payload["event"], payload["action"], payload["number"], payload["title"] = "pull_request", strings.ToLower(m[1]), m[2], m[3]
+		} else if m := githubIssuePattern.FindStringSubmatch(rest); m != nil {
+			payload["event"], payload["action"], payload["number"], payload["title"] = "issue", strings.ToLower(m[1]), m[2], m[3]
+		} else if m := githubCommentPattern.FindStringSubmatch(rest); m != nil {
+			payload["event"], payload["action"], payload["number"], payload["title"] = "comment", strings.ToLower(m[1]), m[2], m[3]
+		} else if m := githubPushPattern.FindStringSubmatch(rest); m != nil {
+			payload["event"], payload["number"], payload["title"] = "push", m[1], ""
+		} else if m := githubReleasePattern.FindStringSubmatch(rest); m != nil {
+			payload["event"], payload["action"], payload["title"] = "release", "published", m[1]
+		}
+		payload["text"] = githubText(payload)
+		return payload
+	}
+	return nil
+}
+
+// githubText renders a GitHub payload as one line, e.g. "[owner/repo] PR #123 opened by alice: Fix login".
+func githubText(p map[string]string) string {
+	by := ""
+	if p["actor"] != "" {
+		by = " by " + p["actor"]
+	}
+	var text string
+	switch p["event"] {
+	case "pull_request":
+		text = fmt.Sprintf("PR #%s %s%s: %s", p["number"], p["action"], by, p["title"])
+	case "pull_request_review":
+		text = fmt.Sprintf("PR #%s review %s%s: %s", p["number"], p["action"], by, p["title"])
+	case "issue":
+		text = fmt.Sprintf("Issue #%s %s%s: %s", p["number"], p["action"], by, p["title"])
+	case "comment":
+		text = fmt.Sprintf("New comment%s on %s %s", by, p["action"], p["number"])
+		if p["title"] != "" {
+			text += ": " + p["title"]
+		}
+	case "push":
+		text = fmt.Sprintf("%s new commit(s) pushed%s to %s", p["number"], by, p["branch"])
+	case "release":
+		text = fmt.Sprintf("Release %s published%s", p["title"], by)
+	default:
+		text = p["title"] + by
+	}
+	return fmt.Sprintf("[%s] %s", p["repo"], text)
+}
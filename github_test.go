@@ -0,0 +1,71 @@
+package discord2pushover
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseGitHubPayload(t *testing.T) {
+	tests := []struct {
+		title  string
+		fields map[string]string
+		text   string
+	}{
+		{
+			title:  "[octo/app] Pull request opened: #123 Fix login",
+			fields: map[string]string{"repo": "octo/app", "event": "pull_request", "action": "opened", "number": "123", "title": "Fix login"},
+			text:   "[octo/app] PR #123 opened by alice: Fix login",
+		},
+		{
+			title:  "[octo/app] Pull request review submitted: #123 Fix login",
+			fields: map[string]string{"event": "pull_request_review", "action": "submitted", "number": "123"},
+			text:   "[octo/app] PR #123 review submitted by alice: Fix login",
+		},
+		{
+			title:  "[octo/app] Issue closed: #45 Crash on start",
+			fields: map[string]string{"event": "issue", "action": "closed", "number": "45", "title": "Crash on start"},
+			text:   "[octo/app] Issue #45 closed by alice: Crash on start",
+		},
+		{
+			title:  "[octo/app] New comment on pull request #123: Fix login",
+			fields: map[string]string{"event": "comment", "action": "pull request", "number": "123"},
+			text:   "[octo/app] New comment by alice on pull request 123: Fix login",
+		},
+		{
+			title:  "[octo/app:main] 2 new commits",
+			fields: map[string]string{"event": "push", "branch": "main", "number": "2"},
+			text:   "[octo/app] 2 new commit(s) pushed by alice to main",
+		},
+		{
+			title:  "[octo/app] New release published: v1.2.0",
+			fields: map[string]string{"event": "release", "title": "v1.2.0"},
+			text:   "[octo/app] Release v1.2.0 published by alice",
+		},
+		{
+			title:  "[octo/app] New star added",
+			fields: map[string]string{"event": "other", "title": "New star added"},
+			text:   "[octo/app] New star added by alice",
+		},
+	}
+	for _, tt := range tests {
+		embed := &discordgo.MessageEmbed{Title: tt.title, URL: "https://github.com/octo/app", Author: &discordgo.MessageEmbedAuthor{Name: "alice"}}
+		payload := parseGitHubPayload(&discordgo.Message{Embeds: []*discordgo.MessageEmbed{embed}})
+		if payload == nil {
+			t.Errorf("%q: not parsed", tt.title)
+			continue
+		}
+		for key, want := range tt.fields {
+			if payload[key] != want {
+				t.Errorf("%q: expected %s %q, got %q", tt.title, key, want, payload[key])
+			}
+		}
+		if payload["actor"] != "alice" || payload["url"] != "https://github.com/octo/app" || payload["text"] != tt.text {
+			t.Errorf("%q: unexpected payload %v", tt.title, payload)
+		}
+	}
+
+	if payload := parseGitHubPayload(&discordgo.Message{Embeds: []*discordgo.MessageEmbed{{Title: "[FIRING:1] HighCPU"}}}); payload != nil {
+		t.Errorf("Expected no payload for other embeds, got %v", payload)
+	}
+}
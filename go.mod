@@ -3,14 +3,27 @@ module github.com/user/discord2pushover
 go 1.22.2
 
 require (
+	filippo.io/age v1.2.1
 	github.com/bwmarrin/discordgo v0.29.0
+	github.com/eclipse/paho.mqtt.golang v1.5.0
+	github.com/expr-lang/expr v1.17.8
+	github.com/getsentry/sentry-go v0.27.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/gregdel/pushover v1.3.1
+	github.com/kardianos/service v1.2.2
 	github.com/sirupsen/logrus v1.9.3
+	github.com/yuin/gopher-lua v1.1.1
+	golang.org/x/text v0.16.0
+	google.golang.org/grpc v1.65.0
+	google.golang.org/protobuf v1.34.2
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/gorilla/websocket v1.4.2 // indirect
-	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/crypto v0.25.0 // indirect
+	golang.org/x/net v0.27.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
 )
@@ -0,0 +1,81 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// Grafana alerting: "[FIRING:2] HighCPU (web-1 Infra)"
+	grafanaTitlePattern = regexp.MustCompile(`(?i)^\[(firing|resolved)(?::(\d+))?\]\s*(.*?)\s*(?:\((.*)\))?$`)
+	// Legacy dashboard alerts: "[Alerting] High CPU alert"
+	grafanaLegacyTitlePattern = regexp.MustCompile(`(?i)^\[(alerting|ok|no data|pending)\]\s*(.+)$`)
+	// "Value: A=82.5, C=1" or "Value: [ var='A' labels={...} value=82.5 ]"
+	grafanaValuePattern = regexp.MustCompile(`(?m)^\s*\**Values?\**:\s*(.+?)\s*$`)
+	// " - alertname = HighCPU" in the Labels and Annotations lists
+	grafanaKeyValuePattern = regexp.MustCompile(`(?m)^\s*-\s*([A-Za-z_][\w.]*)\s*=\s*(.*?)\s*$`)
+)
+
+// grafanaState normalizes the states of both alerting systems to "firing" and "resolved".
+func grafanaState(state string) string {
+	switch state = strings.ToLower(state); state {
+	case "alerting":
+		return "firing"
+	case "ok":
+		return "resolved"
+	default:
+		return state
+	}
+}
+
+// parseGrafanaPayload parses a Grafana alert notification: alertName, state ("firing", "resolved",
+// "no data" or "pending"), count (of alerts in the group), values, summary and url.
+func parseGrafanaPayload(message *discordgo.Message) map[string]string {
+	for _, embed := range message.Embeds {
+		if embed == nil {
+			continue
+		}
+		payload := map[string]string{"url": embed.URL}
+		if m := grafanaTitlePattern.FindStringSubmatch(embed.Title); m != nil {
+			payload["state"], payload["count"], payload["alertName"] = grafanaState(m[1]), m[2], m[3]
+			if payload["count"] == "" {
+				payload["count"] = "1"
+			}
+			if m := grafanaValuePattern.FindStringSubmatch(embed.Description); m != nil {
+				payload["values"] = m[1]
+			}
+			for _, kv := range grafanaKeyValuePattern.FindAllStringSubmatch(embed.Description, -1) {
+				switch key := kv[1]; {
+				case key == "alertname" && payload["alertName"] == "":
+					payload["alertName"] = kv[2]
+				case (key == "summary" || key == "description") && payload["summary"] == "":
+					payload["summary"] = kv[2]
+				}
+			}
+		} else if m := grafanaLegacyTitlePattern.FindStringSubmatch(embed.Title); m != nil {
+			payload["state"], payload["count"], payload["alertName"] = grafanaState(m[1]), "1", m[2]
+			payload["summary"] = strings.TrimSpace(embed.Description)
+			var values []string
+			for _, field := range embed.Fields {
+				if field != nil && field.Name != "" && !strings.EqualFold(field.Name, "Error message") {
+					values = append(values, field.Name+"="+field.Value)
+				}
+			}
+			payload["values"] = strings.Join(values, ", ")
+		} else {
+			continue
+		}
+		payload["text"] = fmt.Sprintf("%s is %s", payload["alertName"], payload["state"])
+		if payload["values"] != "" {
+			payload["text"] += ": " + payload["values"]
+		}
+		if payload["summary"] != "" {
+			payload["text"] += " — " + payload["summary"]
+		}
+		return payload
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package discord2pushover
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseGrafanaPayload(t *testing.T) {
+	tests := []struct {
+		name  string
+		embed *discordgo.MessageEmbed
+		want  map[string]string
+	}{
+		{
+			name: "alerting",
+			embed: &discordgo.MessageEmbed{
+				Title: "[FIRING:2] HighCPU (web-1 Infra)",
+				URL:   "https://grafana.example.com/alerting/list",
+				Description: "**Firing**\n\nValue: A=82.5, C=1\nLabels:\n - alertname = HighCPU\n - instance = web-1\n" +
+					"Annotations:\n - summary = CPU above 80%\nSource: https://grafana.example.com/alerting/grafana/abc/view\n",
+			},
+			want: map[string]string{"alertName": "HighCPU", "state": "firing", "count": "2", "values": "A=82.5, C=1",
+				"summary": "CPU above 80%", "url": "https://grafana.example.com/alerting/list", "text": "HighCPU is firing: A=82.5, C=1 — CPU above 80%"},
+		},
+		{
+			name:  "alerting resolved",
+			embed: &discordgo.MessageEmbed{Title: "[RESOLVED] DiskFull", Description: "**Resolved**\n\nValue: A=40\n"},
+			want:  map[string]string{"alertName": "DiskFull", "state": "resolved", "count": "1", "values": "A=40", "url": "", "text": "DiskFull is resolved: A=40"},
+		},
+		{
+			name: "legacy",
+			embed: &discordgo.MessageEmbed{Title: "[Alerting] High CPU alert", Description: "CPU is high",
+				Fields: []*discordgo.MessageEmbedField{{Name: "web-1", Value: "95.2"}, {Name: "web-2", Value: "91"}}},
+			want: map[string]string{"alertName": "High CPU alert", "state": "firing", "count": "1", "values": "web-1=95.2, web-2=91",
+				"summary": "CPU is high", "url": "", "text": "High CPU alert is firing: web-1=95.2, web-2=91 — CPU is high"},
+		},
+		{
+			name:  "not grafana",
+			embed: &discordgo.MessageEmbed{Title: "Deploy finished"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGrafanaPayload(&discordgo.Message{Embeds: []*discordgo.MessageEmbed{tt.embed}})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// checkConditionGroups evaluates the allOf, anyOf and not groups of conditions, each of which is a
+// full set of conditions that may nest further groups. Returns whether they are met and, if not, why.
+func checkConditionGroups(ctx context.Context, config *Config, message *discordgo.Message, conditions *RuleConditions, session DiscordSessionInterface, ruleNameLog string, logPrefix string) (bool, string) {
+	for i := range conditions.AllOf {
+		if met, reason := evaluateRuleConditions(ctx, config, message, &conditions.AllOf[i], session, fmt.Sprintf("%s allOf[%d]", ruleNameLog, i)); !met {
+			return conditionFailed(logPrefix, "AllOf", "group %d: %s", i+1, reason)
+		}
+	}
+	if len(conditions.AnyOf) > 0 {
+		reasons := make([]string, 0, len(conditions.AnyOf))
+		matched := false
+		for i := range conditions.AnyOf {
+			met, reason := evaluateRuleConditions(ctx, config, message, &conditions.AnyOf[i], session, fmt.Sprintf("%s anyOf[%d]", ruleNameLog, i))
+			if met {
+				matched = true
+				break
+			}
+			reasons = append(reasons, fmt.Sprintf("group %d: %s", i+1, reason))
+		}
+		if !matched {
+			return conditionFailed(logPrefix, "AnyOf", "no group matched (%s)", strings.Join(reasons, "; "))
+		}
+	}
+	if conditions.Not != nil {
+		if met, _ := evaluateRuleConditions(ctx, config, message, conditions.Not, session, ruleNameLog+" not"); met {
+			return conditionFailed(logPrefix, "Not", "the excluded conditions matched")
+		}
+	}
+	return true, ""
+}
+
+// conditionGroups returns conditions and every group nested in it, depth first.
+func conditionGroups(conditions *RuleConditions) []*RuleConditions {
+	groups := []*RuleConditions{conditions}
+	for i := range conditions.AllOf {
+		groups = append(groups, conditionGroups(&conditions.AllOf[i])...)
+	}
+	for i := range conditions.AnyOf {
+		groups = append(groups, conditionGroups(&conditions.AnyOf[i])...)
+	}
+	if conditions.Not != nil {
+		groups = append(groups, conditionGroups(conditions.Not)...)
+	}
+	return groups
+}
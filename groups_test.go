@@ -0,0 +1,72 @@
+package discord2pushover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckRuleConditions_Groups(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	// (channel A AND keyword "deploy") OR (channel B AND mentions the bot), but never from the CI bot
+	var conditions RuleConditions
+	err := yaml.Unmarshal([]byte(`
+anyOf:
+  - channelId: "A"
+    contentIncludes: ["deploy"]
+  - channelId: "B"
+    reactToAtMention: true
+not:
+  specificMentions: ["ci"]
+`), &conditions)
+	if err != nil {
+		t.Fatalf("Failed to parse conditions: %v", err)
+	}
+
+	mockSess := mockSessionForRulesTest("bot")
+	bot := &discordgo.User{ID: "bot"}
+	tests := []struct {
+		name           string
+		message        *discordgo.Message
+		expectedResult bool
+	}{
+		{"FirstGroup", &discordgo.Message{ID: "m1", ChannelID: "A", Content: "deploy done"}, true},
+		{"FirstGroupWrongKeyword", &discordgo.Message{ID: "m2", ChannelID: "A", Content: "lunch"}, false},
+		{"SecondGroup", &discordgo.Message{ID: "m3", ChannelID: "B", Mentions: []*discordgo.User{bot}}, true},
+		{"KeywordInSecondChannel", &discordgo.Message{ID: "m4", ChannelID: "B", Content: "deploy done"}, false},
+		{"Excluded", &discordgo.Message{ID: "m5", ChannelID: "A", Content: "deploy done", Mentions: []*discordgo.User{{ID: "ci"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := checkRuleConditions(context.Background(), nil, tt.message, &conditions, mockSess, tt.name); result != tt.expectedResult {
+				t.Errorf("Expected %v, got %v. Log: %s", tt.expectedResult, result, testLogBufferForTest.String())
+			}
+		})
+	}
+
+	allOf := &RuleConditions{AllOf: []RuleConditions{{ContentIncludes: []string{"disk"}}, {ContentIncludes: []string{"full"}}}}
+	met, reason := evaluateRuleConditions(context.Background(), nil, &discordgo.Message{ID: "m6", Content: "disk ok"}, allOf, mockSess, "AllOf")
+	if met || !strings.HasPrefix(reason, "AllOf: group 2: ContentIncludes") {
+		t.Errorf("Expected allOf to fail on its second group, got %v %q", met, reason)
+	}
+}
+
+func TestConditionGroups(t *testing.T) {
+	conditions := &RuleConditions{
+		ChannelID: "root",
+		AnyOf:     []RuleConditions{{ChannelID: "a", Not: &RuleConditions{ChannelID: "a-not"}}, {ChannelID: "b"}},
+		Not:       &RuleConditions{ChannelID: "not"},
+	}
+	var channels []string
+	for _, group := range conditionGroups(conditions) {
+		channels = append(channels, group.ChannelID)
+	}
+	if got := strings.Join(channels, ","); got != "root,a,a-not,b,not" {
+		t.Errorf("Unexpected groups %s", got)
+	}
+}
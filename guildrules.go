@@ -0,0 +1,364 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultGuildRuleCommand is the slash command guild admins manage their rules with when guildRules sets no command.
+const defaultGuildRuleCommand = "pushover"
+
+// defaultMaxGuildRules bounds the rules per guild when guildRules sets no maxRulesPerGuild.
+const defaultMaxGuildRules = 25
+
+// maxGuildRuleKeywords bounds the keywords of one guild rule.
+const maxGuildRuleKeywords = 20
+
+// guildRule is a keyword rule a guild admin added with the slash command. It is persisted in the state file.
+type guildRule struct {
+	Name      string   `json:"name"`
+	Keywords  []string `json:"keywords"`            // A message containing any of them matches
+	ChannelID string   `json:"channelId,omitempty"` // Default: any channel of the guild
+	Priority  int      `json:"priority,omitempty"`
+	CreatedBy string   `json:"createdBy,omitempty"` // Discord user ID
+}
+
+// guildRuleSet is what a guild's admins configured: their Pushover destination and their rules.
+type guildRuleSet struct {
+	Destination string      `json:"destination,omitempty"`
+	Rules       []guildRule `json:"rules,omitempty"`
+}
+
+// guildRuleSet returns a copy of the rules of a guild.
+func (c *checkpointStore) guildRuleSet(guildID string) guildRuleSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	set := c.guildRules[guildID]
+	set.Rules = append([]guildRule(nil), set.Rules...)
+	return set
+}
+
+// updateGuildRuleSet changes the rules of a guild, persisted in the state file.
+func (c *checkpointStore) updateGuildRuleSet(guildID string, update func(set *guildRuleSet)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.guildRules == nil {
+		c.guildRules = make(map[string]guildRuleSet)
+	}
+	set := c.guildRules[guildID]
+	update(&set)
+	if set.Destination == "" && len(set.Rules) == 0 {
+		delete(c.guildRules, guildID)
+	} else {
+		c.guildRules[guildID] = set
+	}
+	c.dirty = true
+}
+
+// guildRuleName is the name a guild rule has in logs and statistics, e.g. "123456/Deploys".
+func guildRuleName(guildID string, name string) string {
+	return guildID + "/" + name
+}
+
+// guildRulesConfig returns the config a guild's rules are evaluated with: the static config with only
+// the guild's rules, all notifying the guild's destination. It is nil if the guild has no rules to evaluate.
+func guildRulesConfig(config *Config, guildID string) *Config {
+	set := checkpoints.guildRuleSet(guildID)
+	if set.Destination == "" || len(set.Rules) == 0 {
+		return nil
+	}
+	unlock := config.readLockCredentials()
+	guildConfig := *config
+	unlock()
+	guildConfig.ruleIndex = nil
+	guildConfig.RuleEvaluation = ""
+	guildConfig.Rules = make([]Rule, 0, len(set.Rules))
+	for _, rule := range set.Rules {
+		guildConfig.Rules = append(guildConfig.Rules, Rule{
+			Name:       guildRuleName(guildID, rule.Name),
+			Conditions: RuleConditions{ChannelID: rule.ChannelID, ContentIncludes: rule.Keywords, ContentMatch: contentMatchAnyOf},
+			Actions:    RuleActions{PushoverDestination: set.Destination, Priority: rule.Priority},
+		})
+	}
+	return &guildConfig
+}
+
+// processGuildRules evaluates the rules the message's guild manages itself, after and independently of
+// the static rules.
+func processGuildRules(ctx context.Context, message *discordgo.Message, config *Config, session DiscordSessionInterface) {
+	if config.GuildRules == nil || message.GuildID == "" {
+		return
+	}
+	if guildConfig := guildRulesConfig(config, message.GuildID); guildConfig != nil {
+		ProcessRules(ctx, message, guildConfig, session, math.MaxInt32)
+	}
+}
+
+// guildRuleCommandName returns the name of the slash command.
+func guildRuleCommandName(config *Config) string {
+	if config.GuildRules.Command != "" {
+		return config.GuildRules.Command
+	}
+	return defaultGuildRuleCommand
+}
+
+// guildRuleApplicationCommand returns the definition of the slash command.
+func guildRuleApplicationCommand(config *Config) *discordgo.ApplicationCommand {
+	dmPermission := false
+	command := &discordgo.ApplicationCommand{
+		Name:         guildRuleCommandName(config),
+		Description:  "Manage this server's Pushover notification rules",
+		DMPermission: &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add a keyword rule, or change the rule with this name",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Name of the rule", Required: true, MaxLength: 50},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "keywords", Description: "Comma-separated; a message containing any of them matches", Required: true},
+					{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "Only match messages in this channel"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "priority", Description: "Pushover priority", Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "lowest", Value: -2}, {Name: "low", Value: -1}, {Name: "normal", Value: 0}, {Name: "high", Value: 1},
+					}},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a rule",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Name of the rule", Required: true},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "List this server's rules"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "stats", Description: "Show how often this server's rules matched and notified"},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "destination",
+				Description: "Set the Pushover user or group key this server's rules notify",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "key", Description: "Pushover user or group key", Required: true},
+				},
+			},
+		},
+	}
+	if len(config.GuildRules.AdminRoleIDs) == 0 {
+		// Hidden from other members; with adminRoleIds, whether they see it is left to the server's settings
+		manageGuild := int64(discordgo.PermissionManageGuild)
+		command.DefaultMemberPermissions = &manageGuild
+	}
+	return command
+}
+
+// registerGuildRuleCommand registers the slash command in the configured guilds, or globally.
+func registerGuildRuleCommand(s *discordgo.Session, config *Config) {
+	if s.State == nil || s.State.User == nil {
+		log.Error("Guild rules: the bot's user is unknown, cannot register the slash command.")
+		return
+	}
+	command := guildRuleApplicationCommand(config)
+	guildIDs := config.GuildRules.GuildIDs
+	if len(guildIDs) == 0 {
+		guildIDs = []string{""} // A global command
+	}
+	for _, guildID := range guildIDs {
+		if _, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, command); err != nil {
+			log.Errorf("Guild rules: error registering /%s in guild %q: %v", command.Name, guildID, err)
+			continue
+		}
+		log.Infof("Guild rules: registered /%s in guild %q.", command.Name, guildID)
+	}
+}
+
+// isGuildRuleAdmin reports whether a member may manage their guild's rules: members with the Manage
+// Server permission and, if configured, the admin roles.
+func isGuildRuleAdmin(config *Config, member *discordgo.Member) bool {
+	if member.Permissions&(discordgo.PermissionManageGuild|discordgo.PermissionAdministrator) != 0 {
+		return true
+	}
+	for _, roleID := range member.Roles {
+		if containsFold(config.GuildRules.AdminRoleIDs, roleID) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGuildRuleKeywords splits the comma-separated keywords of the add command.
+func parseGuildRuleKeywords(value string) []string {
+	var keywords []string
+	for _, keyword := range strings.Split(value, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// guildRuleCommandReply runs the slash command of an interaction and returns the reply to the member.
+func guildRuleCommandReply(ctx context.Context, config *Config, interaction *discordgo.Interaction) string {
+	if interaction.GuildID == "" || interaction.Member == nil || interaction.Member.User == nil {
+		return "Rules are managed per server; use this command in a server."
+	}
+	if !isGuildRuleAdmin(config, interaction.Member) {
+		return "Only members with the Manage Server permission can manage this server's rules."
+	}
+	data := interaction.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return "Use one of the subcommands: add, remove, list, stats or destination."
+	}
+	subcommand := data.Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range subcommand.Options {
+		options[option.Name] = option
+	}
+	guildID, userID := interaction.GuildID, interaction.Member.User.ID
+	persisted := ""
+	if config.StateFile == "" {
+		persisted = " It is kept until the bot restarts."
+	}
+
+	switch subcommand.Name {
+	case "destination":
+		key := strings.TrimSpace(options["key"].StringValue())
+		if !tenantAllowsDestination(tenantFor(config, guildID), key) {
+			log.Warnf("Guild rules: user %s set a destination not allowed for guild %s.", userID, guildID)
+			return "That Pushover key is not allowed for this server."
+		}
+		if err := ValidatePushoverDestination(ctx, config, key); err != nil {
+			log.Warnf("Guild rules: user %s set an invalid destination for guild %s: %v", userID, guildID, err)
+			return "That is not a valid Pushover user or group key."
+		}
+		checkpoints.updateGuildRuleSet(guildID, func(set *guildRuleSet) { set.Destination = key })
+		log.Infof("Guild rules: user %s set the destination of guild %s.", userID, guildID)
+		return "This server's rules now notify that Pushover key." + persisted
+
+	case "add":
+		rule := guildRule{Name: strings.TrimSpace(options["name"].StringValue()), Keywords: parseGuildRuleKeywords(options["keywords"].StringValue()), CreatedBy: userID}
+		if option, ok := options["channel"]; ok {
+			rule.ChannelID = fmt.Sprint(option.Value)
+		}
+		if option, ok := options["priority"]; ok {
+			rule.Priority = int(option.IntValue())
+		}
+		if rule.Name == "" || len(rule.Keywords) == 0 {
+			return "A rule needs a name and at least one keyword."
+		}
+		if len(rule.Keywords) > maxGuildRuleKeywords {
+			return fmt.Sprintf("A rule can have at most %d keywords.", maxGuildRuleKeywords)
+		}
+		max := config.GuildRules.MaxRulesPerGuild
+		if max <= 0 {
+			max = defaultMaxGuildRules
+		}
+		reply := ""
+		checkpoints.updateGuildRuleSet(guildID, func(set *guildRuleSet) {
+			for i := range set.Rules {
+				if strings.EqualFold(set.Rules[i].Name, rule.Name) {
+					set.Rules[i] = rule
+					reply = fmt.Sprintf("Rule '%s' changed.", rule.Name)
+					return
+				}
+			}
+			if len(set.Rules) >= max {
+				reply = fmt.Sprintf("This server already has %d rules, the most allowed. Remove one first.", max)
+				return
+			}
+			set.Rules = append(set.Rules, rule)
+			reply = fmt.Sprintf("Rule '%s' added.", rule.Name)
+		})
+		log.Infof("Guild rules: user %s in guild %s: %s", userID, guildID, reply)
+		if checkpoints.guildRuleSet(guildID).Destination == "" {
+			reply += fmt.Sprintf(" It notifies nobody until you set a destination with `/%s destination`.", guildRuleCommandName(config))
+		}
+		return reply + persisted
+
+	case "remove":
+		name := strings.TrimSpace(options["name"].StringValue())
+		removed := false
+		checkpoints.updateGuildRuleSet(guildID, func(set *guildRuleSet) {
+			for i := range set.Rules {
+				if strings.EqualFold(set.Rules[i].Name, name) {
+					set.Rules = append(set.Rules[:i], set.Rules[i+1:]...)
+					removed = true
+					return
+				}
+			}
+		})
+		if !removed {
+			return fmt.Sprintf("This server has no rule '%s'.", name)
+		}
+		log.Infof("Guild rules: user %s removed rule '%s' of guild %s.", userID, name, guildID)
+		return fmt.Sprintf("Rule '%s' removed.", name)
+
+	case "list":
+		set := checkpoints.guildRuleSet(guildID)
+		if len(set.Rules) == 0 {
+			return "This server has no rules."
+		}
+		sort.Slice(set.Rules, func(i, j int) bool { return set.Rules[i].Name < set.Rules[j].Name })
+		var lines []string
+		for _, rule := range set.Rules {
+			line := fmt.Sprintf("**%s**: %s", rule.Name, strings.Join(rule.Keywords, ", "))
+			if rule.ChannelID != "" {
+				line += fmt.Sprintf(" in <#%s>", rule.ChannelID)
+			}
+			if rule.Priority != 0 {
+				line += fmt.Sprintf(", priority %d", rule.Priority)
+			}
+			lines = append(lines, line)
+		}
+		if set.Destination == "" {
+			lines = append(lines, "No destination is set, so the rules notify nobody.")
+		}
+		return strings.Join(lines, "\n")
+
+	case "stats":
+		stats := tenantRuleStats(snapshotRuleStats(nil), guildID)
+		var lines []string
+		for _, s := range stats {
+			lines = append(lines, fmt.Sprintf("**%s**: matched %d, notified %d, suppressed %d, errored %d",
+				strings.TrimPrefix(s.Rule, guildRuleName(guildID, "")), s.Matched, s.Notified, s.Suppressed, s.Errored))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "No rule of this server has been evaluated since the bot started.")
+		}
+		if usage := describeTenantUsage(config, guildID, time.Now()); usage != "" {
+			lines = append(lines, "Quota: "+usage+".")
+		}
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("Unknown subcommand %s.", subcommand.Name)
+}
+
+// dgInteractionCreate handles the guild rules slash command.
+func dgInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	defer recoverPanic("dgInteractionCreate")
+	config := globalConfig
+	if config == nil || config.GuildRules == nil || i.Type != discordgo.InteractionApplicationCommand ||
+		i.ApplicationCommandData().Name != guildRuleCommandName(config) {
+		return
+	}
+	// Validating a destination may take longer than the three seconds Discord waits for a response
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		log.Errorf("Guild rules: error acknowledging /%s: %v", guildRuleCommandName(config), err)
+		return
+	}
+	ctx, cancel := eventContext(config)
+	defer cancel()
+	reply := guildRuleCommandReply(ctx, config, i.Interaction)
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &reply}); err != nil {
+		log.Errorf("Guild rules: error replying to /%s: %v", guildRuleCommandName(config), err)
+	}
+}
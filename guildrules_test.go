@@ -0,0 +1,89 @@
+package discord2pushover
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// guildRuleInteraction builds a slash command interaction of a member running a subcommand.
+func guildRuleInteraction(guildID string, member *discordgo.Member, subcommand string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.Interaction {
+	return &discordgo.Interaction{
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: guildID,
+		Member:  member,
+		Data: discordgo.ApplicationCommandInteractionData{Name: defaultGuildRuleCommand, Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: subcommand, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options},
+		}},
+	}
+}
+
+func stringOption(name string, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+}
+
+func TestGuildRules(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	saved := checkpoints
+	checkpoints = &checkpointStore{}
+	defer func() { checkpoints = saved }()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := checkpoints.load(statePath); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	fake := &fakePushoverClient{}
+	config := &Config{PushoverAppKey: "app", StateFile: statePath, GuildRules: &GuildRules{AdminRoleIDs: []string{"mods"}, MaxRulesPerGuild: 2}}
+	config.SetPushoverClient(fake)
+	admin := &discordgo.Member{User: &discordgo.User{ID: "alice"}, Permissions: discordgo.PermissionManageGuild}
+	moderator := &discordgo.Member{User: &discordgo.User{ID: "bob"}, Roles: []string{"mods"}}
+	member := &discordgo.Member{User: &discordgo.User{ID: "carol"}}
+	ctx := context.Background()
+
+	if reply := guildRuleCommandReply(ctx, config, guildRuleInteraction("g1", member, "list")); !strings.Contains(reply, "Manage Server") {
+		t.Errorf("Expected a member without permission to be refused, got %q", reply)
+	}
+	add := guildRuleInteraction("g1", admin, "add", stringOption("name", "Deploys"), stringOption("keywords", "deploy failed, rollback"),
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "priority", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(1)})
+	if reply := guildRuleCommandReply(ctx, config, add); !strings.Contains(reply, "added") || !strings.Contains(reply, "destination") {
+		t.Errorf("Expected the rule added with a hint to set a destination, got %q", reply)
+	}
+	if reply := guildRuleCommandReply(ctx, config, guildRuleInteraction("g1", moderator, "destination", stringOption("key", "uGuild1"))); !strings.Contains(reply, "now notify") {
+		t.Errorf("Expected an admin role to set the destination, got %q", reply)
+	}
+	guildRuleCommandReply(ctx, config, guildRuleInteraction("g1", admin, "add", stringOption("name", "Outages"), stringOption("keywords", "down")))
+	if reply := guildRuleCommandReply(ctx, config, guildRuleInteraction("g1", admin, "add", stringOption("name", "More"), stringOption("keywords", "x"))); !strings.Contains(reply, "most allowed") {
+		t.Errorf("Expected maxRulesPerGuild to be enforced, got %q", reply)
+	}
+	if reply := guildRuleCommandReply(ctx, config, guildRuleInteraction("g1", admin, "remove", stringOption("name", "outages"))); reply != "Rule 'outages' removed." {
+		t.Errorf("Expected the rule removed, got %q", reply)
+	}
+	if reply := guildRuleCommandReply(ctx, config, guildRuleInteraction("g1", admin, "list")); !strings.Contains(reply, "**Deploys**: deploy failed, rollback, priority 1") {
+		t.Errorf("Expected the remaining rule listed, got %q", reply)
+	}
+
+	session := mockSessionForRulesTest("bot")
+	processGuildRules(ctx, &discordgo.Message{ID: "m1", GuildID: "g2", ChannelID: "c1", Content: "rollback now", Author: &discordgo.User{ID: "dave"}}, config, session)
+	if len(fake.sent) != 0 {
+		t.Fatalf("Expected another guild's message not to match, got %v", fake.recipients)
+	}
+	processGuildRules(ctx, &discordgo.Message{ID: "m2", GuildID: "g1", ChannelID: "c1", Content: "Rollback now", Author: &discordgo.User{ID: "dave"}}, config, session)
+	if len(fake.recipients) != 1 || fake.recipients[0] != "uGuild1" || fake.sent[0].Priority != 1 {
+		t.Errorf("Expected the guild's rule to notify its destination, got %v", fake.recipients)
+	}
+
+	if err := checkpoints.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	restarted := &checkpointStore{}
+	if err := restarted.load(statePath); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if set := restarted.guildRuleSet("g1"); set.Destination != "uGuild1" || len(set.Rules) != 1 || set.Rules[0].CreatedBy != "alice" {
+		t.Errorf("Expected the guild's rules to survive a restart, got %+v", set)
+	}
+}
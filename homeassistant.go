@@ -0,0 +1,86 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type homeAssistantNotifier struct {
+	config     *HomeAssistantNotifier
+	httpClient *http.Client
+}
+
+// homeAssistantRequest returns the API path and JSON body for a notification. Without configured data,
+// notify.* services get the title and message, events get the notification payload and other services
+// are called without data.
+func homeAssistantRequest(cfg *HomeAssistantNotifier, n *Notification) (path string, body interface{}, err error) {
+	switch {
+	case cfg.Service != "" && cfg.Event != "":
+		return "", nil, fmt.Errorf("home assistant notifier takes either service or event, not both")
+	case cfg.Service != "":
+		domain, service, ok := strings.Cut(cfg.Service, ".")
+		if !ok || domain == "" || service == "" {
+			return "", nil, fmt.Errorf("invalid home assistant service '%s' (expected domain.service)", cfg.Service)
+		}
+		path = "/api/services/" + url.PathEscape(domain) + "/" + url.PathEscape(service)
+		body = map[string]string{}
+		if domain == "notify" {
+			body = map[string]string{"title": n.Title, "message": n.Body}
+		}
+	case cfg.Event != "":
+		path = "/api/events/" + url.PathEscape(cfg.Event)
+		body = newNotificationPayload(n)
+	default:
+		return "", nil, fmt.Errorf("home assistant notifier requires a service or an event")
+	}
+
+	if len(cfg.Data) > 0 {
+		data := make(map[string]string, len(cfg.Data))
+		for key, source := range cfg.Data {
+			value, err := renderTemplate(source, nil, templateData(n))
+			if err != nil {
+				return "", nil, fmt.Errorf("data '%s': %w", key, err)
+			}
+			data[key] = value
+		}
+		body = data
+	}
+	return path, body, nil
+}
+
+// Notify calls the configured service or fires the configured event via the REST API.
+func (h *homeAssistantNotifier) Notify(ctx context.Context, n *Notification) error {
+	if h.config.URL == "" || h.config.Token == "" {
+		return fmt.Errorf("home assistant notifier requires url and token")
+	}
+	path, body, err := homeAssistantRequest(h.config, n)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(h.config.URL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+h.config.Token)
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := h.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to call Home Assistant: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("home assistant API error: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
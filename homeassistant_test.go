@@ -0,0 +1,56 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHomeAssistantNotifier(t *testing.T) {
+	var path, auth string
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		received = nil
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	n := &Notification{
+		RuleName: "Doorbell", Title: "Ring", Body: "someone is at the door", Priority: 1,
+		Data: &NotificationData{RuleName: "Doorbell", ChannelID: "c1", AuthorName: "frontdoor"},
+	}
+
+	notify := &homeAssistantNotifier{httpClient: server.Client(), config: &HomeAssistantNotifier{URL: server.URL + "/", Token: "tok", Service: "notify.mobile_app_phone"}}
+	if err := notify.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if path != "/api/services/notify/mobile_app_phone" || auth != "Bearer tok" || received["title"] != "Ring" || received["message"] != "someone is at the door" {
+		t.Errorf("Unexpected service call %s %q %v", path, auth, received)
+	}
+
+	tts := &homeAssistantNotifier{httpClient: server.Client(), config: &HomeAssistantNotifier{URL: server.URL, Token: "tok", Service: "tts.speak",
+		Data: map[string]string{"message": "Alert from {{.AuthorName}}", "entity_id": "tts.piper"}}}
+	if err := tts.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if path != "/api/services/tts/speak" || received["message"] != "Alert from frontdoor" || received["entity_id"] != "tts.piper" {
+		t.Errorf("Unexpected templated service call %s %v", path, received)
+	}
+
+	event := &homeAssistantNotifier{httpClient: server.Client(), config: &HomeAssistantNotifier{URL: server.URL, Token: "tok", Event: "discord_alert"}}
+	if err := event.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if path != "/api/events/discord_alert" || received["rule"] != "Doorbell" || received["channelId"] != "c1" || received["priority"] != float64(1) {
+		t.Errorf("Unexpected event %s %v", path, received)
+	}
+
+	invalid := &homeAssistantNotifier{httpClient: server.Client(), config: &HomeAssistantNotifier{URL: server.URL, Token: "tok", Service: "siren"}}
+	if err := invalid.Notify(context.Background(), n); err == nil {
+		t.Error("Expected an error for a service without domain")
+	}
+}
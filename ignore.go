@@ -0,0 +1,47 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ignoreReason returns why a message is dropped by the global ignore list, or "" if it isn't.
+func ignoreReason(ignore *IgnoreList, message *discordgo.Message) string {
+	if ignore == nil {
+		return ""
+	}
+	if message.Author != nil && slices.Contains(ignore.UserIDs, message.Author.ID) {
+		return fmt.Sprintf("author %s is ignored", message.Author.ID)
+	}
+	if message.ChannelID != "" && slices.Contains(ignore.ChannelIDs, message.ChannelID) {
+		return fmt.Sprintf("channel %s is ignored", message.ChannelID)
+	}
+	if message.GuildID != "" && slices.Contains(ignore.GuildIDs, message.GuildID) {
+		return fmt.Sprintf("guild %s is ignored", message.GuildID)
+	}
+	if len(ignore.ContentPatterns) > 0 {
+		text := messageSearchText(message)
+		for _, pattern := range ignore.ContentPatterns {
+			re, err := compiledPattern(pattern)
+			if err != nil {
+				log.Errorf("Invalid ignore contentPattern '%s': %v", pattern, err)
+				continue
+			}
+			if re.MatchString(text) {
+				return fmt.Sprintf("content matches ignored pattern '%s'", pattern)
+			}
+		}
+	}
+	return ""
+}
+
+// isIgnored reports whether a message is dropped by the global ignore list before any rule sees it.
+func isIgnored(config *Config, message *discordgo.Message) bool {
+	reason := ignoreReason(config.Ignore, message)
+	if reason != "" {
+		log.Debugf("Ignoring message ID %s: %s.", message.ID, reason)
+	}
+	return reason != ""
+}
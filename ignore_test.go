@@ -0,0 +1,64 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestIgnoreReason(t *testing.T) {
+	ignore := &IgnoreList{
+		UserIDs:         []string{"spambot"},
+		ChannelIDs:      []string{"noisy"},
+		GuildIDs:        []string{"otherguild"},
+		ContentPatterns: []string{`(?i)\btest alert\b`},
+	}
+	tests := []struct {
+		name    string
+		message *discordgo.Message
+		ignored bool
+	}{
+		{"user", &discordgo.Message{Author: &discordgo.User{ID: "spambot"}, ChannelID: "c1"}, true},
+		{"channel", &discordgo.Message{Author: &discordgo.User{ID: "u1"}, ChannelID: "noisy"}, true},
+		{"guild", &discordgo.Message{Author: &discordgo.User{ID: "u1"}, ChannelID: "c1", GuildID: "otherguild"}, true},
+		{"content", &discordgo.Message{Author: &discordgo.User{ID: "u1"}, ChannelID: "c1", Content: "This is a TEST ALERT, please ignore"}, true},
+		{"embed", &discordgo.Message{Author: &discordgo.User{ID: "u1"}, ChannelID: "c1", Embeds: []*discordgo.MessageEmbed{{Title: "test alert"}}}, true},
+		{"other", &discordgo.Message{Author: &discordgo.User{ID: "u1"}, ChannelID: "c1", GuildID: "g1", Content: "real alert"}, false},
+	}
+	for _, tt := range tests {
+		if reason := ignoreReason(ignore, tt.message); (reason != "") != tt.ignored {
+			t.Errorf("%s: expected ignored=%v, got reason %q", tt.name, tt.ignored, reason)
+		}
+	}
+	if reason := ignoreReason(nil, tests[0].message); reason != "" {
+		t.Errorf("Expected nothing to be ignored without an ignore list, got %q", reason)
+	}
+}
+
+func TestProcessRules_IgnoredBeforeRules(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	config := &Config{
+		Ignore: &IgnoreList{UserIDs: []string{"spambot"}},
+		Rules: []Rule{{
+			Name:       "Everything",
+			Conditions: RuleConditions{ChannelID: "c1"},
+			Actions:    RuleActions{ReactionEmoji: EmojiList{"👀"}},
+		}},
+	}
+	session := mockSessionForRulesTest("bot")
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m1", ChannelID: "c1", Author: &discordgo.User{ID: "spambot"}}, config, session, math.MaxInt32)
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m2", ChannelID: "c1", Author: &discordgo.User{ID: "u1"}}, config, session, math.MaxInt32)
+
+	logs := testLogBufferForTest.String()
+	if strings.Contains(logs, "msgID=m1") || !strings.Contains(logs, "Ignoring message ID m1: author spambot is ignored.") {
+		t.Errorf("Expected m1 to be ignored. Logs:\n%s", logs)
+	}
+	if !strings.Contains(logs, "msgID=m2") {
+		t.Errorf("Expected m2 to be processed. Logs:\n%s", logs)
+	}
+}
@@ -0,0 +1,135 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSignatureHeader      = "X-Signature"
+	defaultSignatureSkewSeconds = 300
+)
+
+// parseAllowedSources parses the IPs and CIDRs of allowedSources.
+func parseAllowedSources(sources []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, source := range sources {
+		source = strings.TrimSpace(source)
+		if !strings.Contains(source, "/") {
+			ip := net.ParseIP(source)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid allowedSources entry %q: expected an IP or CIDR", source)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowedSources entry %q: %w", source, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// sourceAllowed reports whether the remote address of a request is in one of the networks.
+func sourceAllowed(networks []*net.IPNet, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validInboundSignature checks the HMAC-SHA256 signature of a request's body, hex-encoded in the
+// signature header with an optional "sha256=" prefix. With a timestamp header, the signed payload is
+// "<timestamp>:<body>" and the timestamp, in Unix seconds, must be recent, so captured requests cannot
+// be replayed later. That is the scheme of Grafana's webhook contact point.
+func validInboundSignature(security *InboundSecurity, r *http.Request, body []byte, now time.Time) error {
+	header := security.SignatureHeader
+	if header == "" {
+		header = defaultSignatureHeader
+	}
+	signature := strings.TrimPrefix(strings.TrimSpace(r.Header.Get(header)), "sha256=")
+	if signature == "" {
+		return fmt.Errorf("missing %s header", header)
+	}
+	mac := hmac.New(sha256.New, []byte(security.SignatureSecret))
+	if security.TimestampHeader != "" {
+		timestamp := r.Header.Get(security.TimestampHeader)
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("missing or invalid %s header", security.TimestampHeader)
+		}
+		skew := security.MaxSkewSeconds
+		if skew <= 0 {
+			skew = defaultSignatureSkewSeconds
+		}
+		if math.Abs(now.Sub(time.Unix(seconds, 0)).Seconds()) > float64(skew) {
+			return fmt.Errorf("timestamp %s is more than %d seconds off", timestamp, skew)
+		}
+		mac.Write([]byte(timestamp + ":"))
+	}
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return fmt.Errorf("invalid signature in %s header", header)
+	}
+	return nil
+}
+
+// guardInbound wraps the handler of an inbound endpoint so only requests from allowedSources and, with
+// a signatureSecret, with a valid signature reach it. Without security the handler is returned as it is.
+func guardInbound(security *InboundSecurity, name string, handler http.Handler) (http.Handler, error) {
+	if security == nil {
+		return handler, nil
+	}
+	networks, err := parseAllowedSources(security.AllowedSources)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(networks) > 0 && !sourceAllowed(networks, r.RemoteAddr) {
+			log.Warnf("%s: rejected %s %s from %s, not in allowedSources.", name, r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if security.SignatureSecret != "" {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIncidentWebhookBody))
+			if err != nil {
+				http.Error(w, "failed to read body", http.StatusBadRequest)
+				return
+			}
+			if err := validInboundSignature(security, r, body, time.Now()); err != nil {
+				log.Warnf("%s: rejected %s %s from %s: %v.", name, r.Method, r.URL.Path, r.RemoteAddr, err)
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		handler.ServeHTTP(w, r)
+	}), nil
+}
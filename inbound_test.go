@@ -0,0 +1,71 @@
+package discord2pushover
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuardInbound(t *testing.T) {
+	var received string
+	handler, err := guardInbound(&InboundSecurity{
+		AllowedSources:  []string{"192.0.2.0/24", "2001:db8::1"},
+		SignatureSecret: "s3cret",
+		SignatureHeader: "X-Grafana-Alerting-Signature",
+		TimestampHeader: "X-Grafana-Alerting-Timestamp",
+	}, "Test listener", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+	if err != nil {
+		t.Fatalf("guardInbound: %v", err)
+	}
+	sign := func(timestamp string, body string) string {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(timestamp + ":" + body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	for _, c := range []struct {
+		name      string
+		remote    string
+		timestamp string
+		signature string
+		want      int
+	}{
+		{"signed", "192.0.2.10:4000", now, sign(now, "alert"), http.StatusOK},
+		{"IPv6 source", "[2001:db8::1]:4000", now, "sha256=" + sign(now, "alert"), http.StatusOK},
+		{"other source", "198.51.100.1:4000", now, sign(now, "alert"), http.StatusForbidden},
+		{"unsigned", "192.0.2.10:4000", now, "", http.StatusUnauthorized},
+		{"other body", "192.0.2.10:4000", now, sign(now, "other"), http.StatusUnauthorized},
+		{"replayed", "192.0.2.10:4000", old, sign(old, "alert"), http.StatusUnauthorized},
+	} {
+		received = ""
+		request := httptest.NewRequest(http.MethodPost, "/webhooks/opsgenie", strings.NewReader("alert"))
+		request.RemoteAddr = c.remote
+		request.Header.Set("X-Grafana-Alerting-Timestamp", c.timestamp)
+		if c.signature != "" {
+			request.Header.Set("X-Grafana-Alerting-Signature", c.signature)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, recorder.Code)
+		}
+		if c.want == http.StatusOK && received != "alert" {
+			t.Errorf("%s: expected the body passed on, got %q", c.name, received)
+		}
+	}
+
+	if _, err := guardInbound(&InboundSecurity{AllowedSources: []string{"pagerduty.com"}}, "Test listener", nil); err == nil {
+		t.Error("Expected an invalid allowedSources entry to be refused")
+	}
+}
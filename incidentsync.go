@@ -0,0 +1,236 @@
+package discord2pushover
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	incidentSyncAcknowledge = "acknowledge"
+	incidentSyncResolve     = "resolve"
+)
+
+// maxIncidentWebhookBody bounds the webhook payloads read from PagerDuty and Opsgenie.
+const maxIncidentWebhookBody = 1 << 20
+
+// incidentToolNotifiers returns the names among a rule's notifiers that open incidents, i.e. the
+// configured PagerDuty and Opsgenie notifiers.
+func incidentToolNotifiers(config *Config, names []string) []string {
+	var tools []string
+	for _, name := range names {
+		if cfg, ok := config.Notifiers[name]; ok && (cfg.PagerDuty != nil || cfg.Opsgenie != nil) {
+			tools = append(tools, name)
+		}
+	}
+	return tools
+}
+
+// syncAcknowledgementToIncidents acknowledges or, with onAcknowledge "resolve", resolves the incidents
+// the rule of an acknowledged emergency opened in PagerDuty and Opsgenie.
+func syncAcknowledgementToIncidents(ctx context.Context, config *Config, trackedMsg TrackedEmergencyMessage) {
+	if config.IncidentSync == nil || trackedMsg.IncidentKey == "" {
+		return
+	}
+	resolve := config.IncidentSync.OnAcknowledge == incidentSyncResolve
+	for _, name := range trackedMsg.IncidentNotifiers {
+		cfg := config.Notifiers[name]
+		var err error
+		switch {
+		case cfg.PagerDuty != nil:
+			action := incidentSyncAcknowledge
+			if resolve {
+				action = incidentSyncResolve
+			}
+			err = sendPagerDutyEvent(ctx, notifierHTTPClient(config), cfg.PagerDuty, pagerDutyEvent{RoutingKey: cfg.PagerDuty.RoutingKey, EventAction: action, DedupKey: trackedMsg.IncidentKey})
+		case cfg.Opsgenie != nil:
+			action := "acknowledge"
+			if resolve {
+				action = "close"
+			}
+			path := "/v2/alerts/" + url.PathEscape(trackedMsg.IncidentKey) + "/" + action + "?identifierType=alias"
+			err = opsgenieRequest(ctx, notifierHTTPClient(config), cfg.Opsgenie, path, map[string]string{"source": "discord2pushover", "note": "Acknowledged in Pushover"})
+		default:
+			continue
+		}
+		if err != nil {
+			log.Errorf("Error syncing acknowledgement of rule '%s' (DiscordMsg: %s) to notifier '%s': %v", trackedMsg.RuleName, trackedMsg.DiscordMessageID, name, err)
+			continue
+		}
+		log.Infof("Synced acknowledgement of rule '%s' (DiscordMsg: %s) to notifier '%s'.", trackedMsg.RuleName, trackedMsg.DiscordMessageID, name)
+	}
+}
+
+// applyIncidentUpdate ends the emergencies whose incident was acknowledged or resolved in an incident
+// tool: their Pushover retries are cancelled and their Discord messages marked like an acknowledgement
+// in Pushover, plus the resolvedEmoji if the incident was resolved. Returns the number of receipts ended.
+func applyIncidentUpdate(ctx context.Context, config *Config, session DiscordSessionInterface, incidentKey string, resolved bool, tool string) int {
+	if incidentKey == "" {
+		return 0
+	}
+	state := "acknowledged"
+	if resolved {
+		state = "resolved"
+	}
+	ended := 0
+	marked := make(map[string]bool) // Discord messages already marked
+	config.trackedEmergencies().Range(func(key, value interface{}) bool {
+		receiptID := key.(string)
+		trackedMsg, ok := value.(TrackedEmergencyMessage)
+		if !ok || trackedMsg.IncidentKey != incidentKey {
+			return true
+		}
+		if _, loaded := config.trackedEmergencies().LoadAndDelete(receiptID); !loaded {
+			return true // Acknowledged or resolved concurrently
+		}
+		errCancel := CancelPushoverEmergency(ctx, config, receiptID)
+		reportPushoverResult(session, config, errCancel)
+		if errCancel != nil {
+			log.Errorf("Error cancelling emergency (Receipt: %s, DiscordMsg: %s) %s in %s: %v", receiptID, trackedMsg.DiscordMessageID, state, tool, errCancel)
+		} else {
+			log.Infof("Emergency (Receipt: %s, DiscordMsg: %s) was %s in %s; cancelled.", receiptID, trackedMsg.DiscordMessageID, state, tool)
+		}
+		if !marked[trackedMsg.DiscordMessageID] {
+			marked[trackedMsg.DiscordMessageID] = true
+			markAcknowledged(session, trackedMsg)
+			finishEmergencyStatus(session, trackedMsg, "✅ "+strings.ToUpper(state[:1])+state[1:]+" in "+tool)
+			clearEmergencyCountdown(session, trackedMsg)
+			if resolved {
+				addResolvedEmoji(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, trackedMsg.ResolvedEmoji)
+			}
+		}
+		ended++
+		return true
+	})
+	return ended
+}
+
+// validPagerDutySignature reports whether one of the signatures in an X-PagerDuty-Signature header,
+// e.g. "v1=abc,v1=def" while the secret is rotated, is the HMAC-SHA256 of the body.
+func validPagerDutySignature(secret string, header string, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	for _, signature := range strings.Split(header, ",") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(signature), "v1="); ok && hmac.Equal([]byte(value), []byte(expected)) {
+			return true
+		}
+	}
+	return false
+}
+
+// pagerDutyWebhook is the part of a PagerDuty V3 webhook event needed to find the alert.
+type pagerDutyWebhook struct {
+	Event struct {
+		EventType string `json:"event_type"`
+		Data      struct {
+			IncidentKey string `json:"incident_key"`
+		} `json:"data"`
+	} `json:"event"`
+}
+
+// opsgenieWebhook is the part of an Opsgenie webhook needed to find the alert.
+type opsgenieWebhook struct {
+	Action string `json:"action"`
+	Alert  struct {
+		Alias string `json:"alias"`
+	} `json:"alert"`
+}
+
+// newIncidentWebhookMux returns the handler of the webhook listener. Each tool's endpoint is only
+// served when its secret is configured.
+func newIncidentWebhookMux(ctx context.Context, config *Config, session DiscordSessionInterface) *http.ServeMux {
+	incidentSync := config.IncidentSync
+	mux := http.NewServeMux()
+	if incidentSync.PagerDutySecret != "" {
+		mux.HandleFunc("/webhooks/pagerduty", func(w http.ResponseWriter, r *http.Request) {
+			body, ok := readIncidentWebhook(w, r)
+			if !ok {
+				return
+			}
+			if !validPagerDutySignature(incidentSync.PagerDutySecret, r.Header.Get("X-PagerDuty-Signature"), body) {
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+			var webhook pagerDutyWebhook
+			if err := json.Unmarshal(body, &webhook); err != nil {
+				http.Error(w, "invalid payload", http.StatusBadRequest)
+				return
+			}
+			switch webhook.Event.EventType {
+			case "incident.acknowledged", "incident.resolved":
+				applyIncidentUpdate(ctx, config, session, webhook.Event.Data.IncidentKey, webhook.Event.EventType == "incident.resolved", "PagerDuty")
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	if incidentSync.OpsgenieToken != "" {
+		mux.HandleFunc("/webhooks/opsgenie", func(w http.ResponseWriter, r *http.Request) {
+			body, ok := readIncidentWebhook(w, r)
+			if !ok {
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(incidentSync.OpsgenieToken)) != 1 {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			var webhook opsgenieWebhook
+			if err := json.Unmarshal(body, &webhook); err != nil {
+				http.Error(w, "invalid payload", http.StatusBadRequest)
+				return
+			}
+			switch webhook.Action {
+			case "Acknowledge", "Close":
+				applyIncidentUpdate(ctx, config, session, webhook.Alert.Alias, webhook.Action == "Close", "Opsgenie")
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	return mux
+}
+
+// readIncidentWebhook reads the body of a webhook POST, answering other requests with an error.
+func readIncidentWebhook(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxIncidentWebhookBody))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// startIncidentWebhookListener serves the PagerDuty and Opsgenie webhooks in the background.
+func startIncidentWebhookListener(ctx context.Context, config *Config, session DiscordSessionInterface) *http.Server {
+	address := config.IncidentSync.Listen
+	if config.IncidentSync.PagerDutySecret == "" && config.IncidentSync.OpsgenieToken == "" {
+		log.Warnf("Incident webhook listener on %s has neither pagerDutySecret nor opsgenieToken, so it accepts no webhooks.", address)
+	}
+	server := &http.Server{Addr: address, ReadHeaderTimeout: 10 * time.Second}
+	handler, err := guardInbound(config.IncidentSync.Security, "Incident webhook listener", newIncidentWebhookMux(ctx, config, session))
+	if err != nil {
+		log.Errorf("Incident webhook listener on %s not started: %v", address, err)
+		return server
+	}
+	server.Handler = handler
+	go func() {
+		defer recoverPanic("startIncidentWebhookListener")
+		log.Infof("Incident webhook listener on %s.", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Incident webhook listener on %s failed: %v", address, err)
+		}
+	}()
+	return server
+}
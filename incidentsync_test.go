@@ -0,0 +1,112 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSyncAcknowledgementToIncidents(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	var pdEvents []pagerDutyEvent
+	var ogPaths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v2/alerts") {
+			ogPaths = append(ogPaths, r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		} else {
+			var event pagerDutyEvent
+			json.NewDecoder(r.Body).Decode(&event)
+			pdEvents = append(pdEvents, event)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	originalPD, originalOG := pagerDutyEventsURLs["us"], opsgenieAPIBases["us"]
+	pagerDutyEventsURLs["us"], opsgenieAPIBases["us"] = server.URL+"/enqueue", server.URL
+	defer func() { pagerDutyEventsURLs["us"], opsgenieAPIBases["us"] = originalPD, originalOG }()
+
+	config := &Config{
+		Notifiers: map[string]NotifierConfig{
+			"pd":     {PagerDuty: &PagerDutyNotifier{RoutingKey: "R0UT1NG"}},
+			"og":     {Opsgenie: &OpsgenieNotifier{APIKey: "k3y"}},
+			"matrix": {Matrix: &MatrixNotifier{}},
+		},
+		IncidentSync: &IncidentSync{OnAcknowledge: incidentSyncResolve},
+	}
+	notifiers := incidentToolNotifiers(config, []string{"matrix", "pd", "og", "missing"})
+	if strings.Join(notifiers, ",") != "pd,og" {
+		t.Fatalf("Expected the incident tools among the notifiers, got %v", notifiers)
+	}
+	syncAcknowledgementToIncidents(context.Background(), config, TrackedEmergencyMessage{RuleName: "DB", IncidentKey: "DB/m1", IncidentNotifiers: notifiers})
+
+	if len(pdEvents) != 1 || pdEvents[0].EventAction != "resolve" || pdEvents[0].DedupKey != "DB/m1" || pdEvents[0].Payload != nil {
+		t.Errorf("Expected a resolve event for the incident, got %+v", pdEvents)
+	}
+	if len(ogPaths) != 1 || ogPaths[0] != "/v2/alerts/DB%2Fm1/close?identifierType=alias" {
+		t.Errorf("Expected the alert to be closed by alias, got %v", ogPaths)
+	}
+}
+
+func TestIncidentWebhooks(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+
+	config := &Config{IncidentSync: &IncidentSync{PagerDutySecret: "s3cret", OpsgenieToken: "t0ken"}}
+	mux := newIncidentWebhookMux(context.Background(), config, &MockDiscordSession{Session: &discordgo.Session{}})
+	config.trackedEmergencies().Store("r1", TrackedEmergencyMessage{PushoverReceiptID: "r1", DiscordMessageID: "m1", DiscordChannelID: "c1", IncidentKey: "DB/m1"})
+	config.trackedEmergencies().Store("r2", TrackedEmergencyMessage{PushoverReceiptID: "r2", DiscordMessageID: "m2", DiscordChannelID: "c1", IncidentKey: "DB/m2"})
+
+	post := func(path string, body string, header http.Header) int {
+		request := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		for key, values := range header {
+			request.Header[key] = values
+		}
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	pdBody := `{"event":{"event_type":"incident.acknowledged","data":{"id":"Q1","incident_key":"DB/m1"}}}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(pdBody))
+	if code := post("/webhooks/pagerduty", pdBody, http.Header{"X-Pagerduty-Signature": {"v1=bad"}}); code != http.StatusUnauthorized {
+		t.Errorf("Expected a bad signature to be rejected, got %d", code)
+	}
+	if _, ok := config.trackedEmergencies().Load("r1"); !ok {
+		t.Fatal("Expected the emergency to stay tracked after a rejected webhook")
+	}
+	signature := "v1=old,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if code := post("/webhooks/pagerduty", pdBody, http.Header{"X-Pagerduty-Signature": {signature}}); code != http.StatusNoContent {
+		t.Errorf("Expected the webhook to be accepted, got %d", code)
+	}
+	if _, ok := config.trackedEmergencies().Load("r1"); ok {
+		t.Error("Expected the emergency acknowledged in PagerDuty to be untracked")
+	}
+
+	ogBody := `{"action":"Close","alert":{"alertId":"a1","alias":"DB/m2"}}`
+	if code := post("/webhooks/opsgenie?token=wrong", ogBody, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong token to be rejected, got %d", code)
+	}
+	if code := post("/webhooks/opsgenie?token=t0ken", ogBody, nil); code != http.StatusNoContent {
+		t.Errorf("Expected the webhook to be accepted, got %d", code)
+	}
+	if _, ok := config.trackedEmergencies().Load("r2"); ok {
+		t.Error("Expected the emergency closed in Opsgenie to be untracked")
+	}
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "resolved in Opsgenie; cancelled") {
+		t.Errorf("Expected the cancellation to be logged. Logs:\n%s", logs)
+	}
+}
@@ -0,0 +1,189 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// alertmanagerSeverities orders the severity label values of Alertmanager alerts, most severe first.
+var alertmanagerSeverities = []string{"critical", "error", "warning", "info"}
+
+// alertmanagerWebhook is the payload of Alertmanager's webhook receiver (version 4): the alerts of one group.
+type alertmanagerWebhook struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	Status            string              `json:"status"` // "firing" while any alert of the group fires, else "resolved"
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []alertmanagerAlert `json:"alerts"`
+}
+
+// alertmanagerAlert is an alert of an Alertmanager webhook.
+type alertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// alertmanagerSeverity returns the most severe severity label of the firing alerts of a group, or of
+// all its alerts once it is resolved; empty if none has a known severity.
+func alertmanagerSeverity(webhook *alertmanagerWebhook) string {
+	best := len(alertmanagerSeverities)
+	for _, alert := range webhook.Alerts {
+		if webhook.Status == "firing" && alert.Status != "firing" {
+			continue
+		}
+		for i, severity := range alertmanagerSeverities[:best] {
+			if strings.EqualFold(alert.Labels["severity"], severity) {
+				best = i
+				break
+			}
+		}
+	}
+	if best == len(alertmanagerSeverities) {
+		return ""
+	}
+	return alertmanagerSeverities[best]
+}
+
+// alertGroupLabels formats the labels a group is grouped by, other than its alertname, as "k=v" pairs
+// sorted by name.
+func alertGroupLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if name != "alertname" {
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// formatAlertmanagerSummary formats a group of alerts in the style of Grafana's notifications, so
+// rules match them like those: "[FIRING:2] HighCPU (job=node)", the severity, and a line per alert.
+func formatAlertmanagerSummary(webhook *alertmanagerWebhook) string {
+	firing := 0
+	for _, alert := range webhook.Alerts {
+		if alert.Status == "firing" {
+			firing++
+		}
+	}
+	icon, count := "🔥", firing
+	if webhook.Status != "firing" {
+		icon, count = "✅", len(webhook.Alerts)
+	}
+	name := webhook.GroupLabels["alertname"]
+	if name == "" {
+		name = webhook.CommonLabels["alertname"]
+	}
+	title := fmt.Sprintf("%s [%s:%d] %s", icon, strings.ToUpper(webhook.Status), count, name)
+	if labels := alertGroupLabels(webhook.GroupLabels); labels != "" {
+		title += " (" + labels + ")"
+	}
+	lines := []string{strings.TrimSpace(title)}
+	if severity := alertmanagerSeverity(webhook); severity != "" {
+		lines = append(lines, "Severity: "+severity)
+	}
+	for _, alert := range webhook.Alerts {
+		line := fmt.Sprintf("- [%s] %s", alert.Status, alert.Labels["alertname"])
+		if instance := alert.Labels["instance"]; instance != "" {
+			line += " on " + instance
+		}
+		text := alert.Annotations["summary"]
+		if text == "" {
+			text = alert.Annotations["description"]
+		}
+		if text != "" {
+			line += ": " + text
+		}
+		lines = append(lines, line)
+	}
+	if webhook.ExternalURL != "" {
+		lines = append(lines, webhook.ExternalURL)
+	}
+	return truncateRunes(strings.Join(lines, "\n"), 2000) // Discord message limit
+}
+
+// ingestAlertmanager evaluates the onAlertmanager rules for a group of alerts. With ingest.channelId the
+// summary is posted there first and the rules see the posted message, so notifications link to it and
+// reactions work; otherwise they see the summary as message of their own.
+func ingestAlertmanager(ctx context.Context, config *Config, session DiscordSessionInterface, webhook *alertmanagerWebhook) {
+	summary := formatAlertmanagerSummary(webhook)
+	var message *discordgo.Message
+	if channelID := config.Ingest.ChannelID; channelID != "" && session != nil {
+		posted, err := session.ChannelMessageSend(channelID, summary)
+		if err != nil {
+			log.Errorf("Error posting Alertmanager alerts to channel %s: %v", channelID, err)
+		} else {
+			message = posted
+			// Messages created over REST do not carry their guild, which the link to them needs
+			if state := session.State(); message.GuildID == "" && state != nil {
+				if channel, err := state.Channel(channelID); err == nil {
+					message.GuildID = channel.GuildID
+				}
+			}
+		}
+	}
+	if message == nil {
+		message = &discordgo.Message{ID: fmt.Sprintf("alertmanager-%d", time.Now().UnixNano()), ChannelID: config.Ingest.ChannelID, Content: summary}
+	}
+	message.Author = &discordgo.User{ID: "alertmanager", Username: "Alertmanager", Bot: true}
+	log.Infof("Alertmanager: %s group %s with %d alerts.", webhook.Status, webhook.GroupKey, len(webhook.Alerts))
+	processRulesForEvent(ctx, ruleEventAlertmanager, message, nil, config, session, math.MaxInt32)
+}
+
+// newIngestMux returns the handler of the ingest listener: /alertmanager for Alertmanager's webhook receiver.
+func newIngestMux(config *Config, session DiscordSessionInterface) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/alertmanager", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readIncidentWebhook(w, r)
+		if !ok {
+			return
+		}
+		var webhook alertmanagerWebhook
+		if err := json.Unmarshal(body, &webhook); err != nil || webhook.Status == "" {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		eventCtx, cancel := eventContext(config)
+		defer cancel()
+		ingestAlertmanager(eventCtx, config, session, &webhook)
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// startIngestListener serves the ingest endpoints in the background.
+func startIngestListener(config *Config, session DiscordSessionInterface) *http.Server {
+	address := config.Ingest.Listen
+	server := &http.Server{Addr: address, ReadHeaderTimeout: 10 * time.Second}
+	handler, err := guardInbound(config.Ingest.Security, "Ingest listener", newIngestMux(config, session))
+	if err != nil {
+		log.Errorf("Ingest listener on %s not started: %v", address, err)
+		return server
+	}
+	server.Handler = handler
+	go func() {
+		defer recoverPanic("startIngestListener")
+		log.Infof("Ingest listener on %s.", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Ingest listener on %s failed: %v", address, err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,77 @@
+package discord2pushover
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAlertmanagerWebhook = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"HighCPU\"}",
+  "status": "firing",
+  "receiver": "discord2pushover",
+  "groupLabels": {"alertname": "HighCPU", "job": "node"},
+  "commonLabels": {"alertname": "HighCPU", "job": "node"},
+  "externalURL": "http://alertmanager:9093",
+  "alerts": [
+    {"status": "firing", "labels": {"alertname": "HighCPU", "instance": "web-1", "severity": "warning"}, "annotations": {"summary": "CPU above 90%"}},
+    {"status": "firing", "labels": {"alertname": "HighCPU", "instance": "web-2", "severity": "critical"}, "annotations": {"description": "CPU above 99%"}},
+    {"status": "resolved", "labels": {"alertname": "HighCPU", "instance": "web-3", "severity": "critical"}}
+  ]
+}`
+
+func TestFormatAlertmanagerSummary(t *testing.T) {
+	webhook := &alertmanagerWebhook{
+		Status:      "resolved",
+		GroupLabels: map[string]string{"alertname": "DiskFull"},
+		Alerts:      []alertmanagerAlert{{Status: "resolved", Labels: map[string]string{"alertname": "DiskFull", "severity": "info"}}},
+	}
+	want := "✅ [RESOLVED:1] DiskFull\nSeverity: info\n- [resolved] DiskFull"
+	if got := formatAlertmanagerSummary(webhook); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestIngestAlertmanager(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		Ingest:         &Ingest{ChannelID: "alerts"},
+		Rules: []Rule{
+			{Name: "Chat", Conditions: RuleConditions{ContentIncludes: []string{"CPU"}}, Actions: RuleActions{PushoverDestination: "uChat"}},
+			{Name: "Prometheus", Event: ruleEventAlertmanager, Conditions: RuleConditions{ContentIncludes: []string{"[FIRING"}}, Actions: RuleActions{
+				PushoverDestination: "uOncall",
+				SeverityMap:         []SeverityMapping{{Pattern: "Severity: critical", Priority: 1}},
+			}},
+		},
+	}
+	config.SetPushoverClient(fake)
+	mux := newIngestMux(config, mockSessionForRulesTest("bot"))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alertmanager", strings.NewReader(testAlertmanagerWebhook)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if len(fake.sent) != 1 || fake.recipients[0] != "uOncall" || fake.sent[0].Priority != 1 {
+		t.Fatalf("Expected the critical group notified to uOncall with priority 1, got %d notifications to %v", len(fake.sent), fake.recipients)
+	}
+	for _, want := range []string{"[FIRING:2] HighCPU (job=node)", "Severity: critical", "- [firing] HighCPU on web-1: CPU above 90%", "- [firing] HighCPU on web-2: CPU above 99%", "- [resolved] HighCPU on web-3"} {
+		if !strings.Contains(fake.sent[0].Message, want) {
+			t.Errorf("Expected %q in the notification, got %q", want, fake.sent[0].Message)
+		}
+	}
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "ChannelMessageSend called with: chID=alerts, content=🔥 [FIRING:2] HighCPU") {
+		t.Errorf("Expected the summary posted to the channel. Logs:\n%s", logs)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alertmanager", strings.NewReader(`{"alerts":[]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a payload without status, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,96 @@
+package discord2pushover
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Values of RuleConditions.ContentMatch.
+const (
+	contentMatchAllOf = "allOf" // Every keyword must be present (default)
+	contentMatchAnyOf = "anyOf" // At least one keyword must be present
+)
+
+// turkishI maps the Turkish dotted capital and dotless small i to i, so case-insensitive matching
+// works whatever locale the text was cased in.
+var turkishI = runes.Map(func(r rune) rune {
+	if r == 'İ' || r == 'ı' {
+		return 'i'
+	}
+	return r
+})
+
+// foldKeywordText prepares content or a keyword for comparison. Unless matchCase, it applies full
+// Unicode case folding, which unlike strings.ToLower also equates "ß" with "ss". With foldDiacritics
+// accents are removed, so "café" and "cafe" compare equal.
+func foldKeywordText(s string, matchCase bool, foldDiacritics bool) string {
+	if !matchCase {
+		s, _, _ = transform.String(transform.Chain(turkishI, cases.Fold()), s)
+	}
+	if foldDiacritics {
+		s, _, _ = transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	}
+	return s
+}
+
+// keywordPattern builds the regular expression for a folded contentIncludes keyword with wholeWord:
+// the keyword must not be surrounded by letters, digits or underscores, so "db" doesn't match "feedback".
+func keywordPattern(keyword string) string {
+	return `(?:^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(keyword) + `(?:[^\p{L}\p{N}_]|$)`
+}
+
+// containsKeyword reports whether content contains keyword under the rule's matching options.
+func containsKeyword(content string, keyword string, matchCase bool, wholeWord bool, foldDiacritics bool) bool {
+	return containsFoldedKeyword(foldKeywordText(content, matchCase, foldDiacritics), foldKeywordText(keyword, matchCase, foldDiacritics), wholeWord)
+}
+
+// containsFoldedKeyword is containsKeyword for content and keyword already passed through foldKeywordText.
+func containsFoldedKeyword(content string, keyword string, wholeWord bool) bool {
+	if !wholeWord {
+		return strings.Contains(content, keyword)
+	}
+	re, err := compiledPattern(keywordPattern(keyword))
+	if err != nil { // Not expected, the keyword is quoted
+		log.Errorf("Invalid keyword pattern for %q: %v", keyword, err)
+		return false
+	}
+	return re.MatchString(content)
+}
+
+// checkContentIncludes evaluates the contentIncludes keywords with the rule's matchCase, wholeWord,
+// foldDiacritics and contentMatch options.
+func checkContentIncludes(content string, conditions *RuleConditions, logPrefix string) bool {
+	anyOf := false
+	switch conditions.ContentMatch {
+	case "", contentMatchAllOf:
+	case contentMatchAnyOf:
+		anyOf = true
+	default:
+		log.Warnf(logPrefix+"Unknown contentMatch '%s', using '%s'.", conditions.ContentMatch, contentMatchAllOf)
+	}
+
+	content = foldKeywordText(content, conditions.MatchCase, conditions.FoldDiacritics)
+	for _, keyword := range conditions.ContentIncludes {
+		found := containsFoldedKeyword(content, foldKeywordText(keyword, conditions.MatchCase, conditions.FoldDiacritics), conditions.WholeWord)
+		if anyOf && found {
+			log.Debugf(logPrefix+"Condition passed (ContentIncludes): keyword '%s' found (any of %v).", keyword, conditions.ContentIncludes)
+			return true
+		}
+		if !anyOf && !found {
+			log.Debugf(logPrefix+"Condition failed (ContentIncludes): keyword '%s' not found in message.", keyword)
+			return false
+		}
+	}
+	if anyOf {
+		log.Debugf(logPrefix+"Condition failed (ContentIncludes): none of the keywords %v found in message.", conditions.ContentIncludes)
+		return false
+	}
+	log.Debugf(logPrefix+"Condition passed (ContentIncludes): All keywords %v found.", conditions.ContentIncludes)
+	return true
+}
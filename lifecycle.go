@@ -0,0 +1,26 @@
+package discord2pushover
+
+import "context"
+
+// announceLifecycle posts a lifecycle message (startup/shutdown) to the configured Discord channel
+// and/or Pushover destination. Failures are logged but never fatal, since announcements are best-effort.
+func announceLifecycle(ctx context.Context, session DiscordSessionInterface, config *Config, text string) {
+	if config == nil || config.LifecycleNotifications == nil {
+		return
+	}
+	ln := config.LifecycleNotifications
+
+	if ln.DiscordChannelID != "" {
+		if _, err := session.ChannelMessageSend(ln.DiscordChannelID, text); err != nil {
+			log.Errorf("Error posting lifecycle announcement to Discord channel %s: %v", ln.DiscordChannelID, err)
+		} else {
+			log.Infof("Posted lifecycle announcement to Discord channel %s.", ln.DiscordChannelID)
+		}
+	}
+
+	if ln.PushoverDestination != "" {
+		if err := SendPushoverText(ctx, config, ln.PushoverDestination, "discord2pushover", text, ln.Priority); err != nil {
+			log.Errorf("Error sending lifecycle announcement to Pushover destination %s: %v", ln.PushoverDestination, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package discord2pushover
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAnnounceLifecycle(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	originalTestHookDisablePushoverSend := testHookDisablePushoverSend
+	testHookDisablePushoverSend = true
+	defer func() {
+		testHookDisablePushoverSend = originalTestHookDisablePushoverSend
+		testHookPushoverSendCalled = false
+	}()
+
+	mockSess := &MockDiscordSession{}
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		testLogBufferForTest.Reset()
+		testHookPushoverSendCalled = false
+		announceLifecycle(context.Background(), mockSess, &Config{}, "started")
+		if strings.Contains(testLogBufferForTest.String(), "ChannelMessageSend called") {
+			t.Errorf("Unexpected Discord post without lifecycleNotifications. Log: %s", testLogBufferForTest.String())
+		}
+		if testHookPushoverSendCalled {
+			t.Errorf("Unexpected Pushover send without lifecycleNotifications.")
+		}
+	})
+
+	t.Run("DiscordAndPushover", func(t *testing.T) {
+		testLogBufferForTest.Reset()
+		testHookPushoverSendCalled = false
+		cfg := &Config{
+			PushoverAppKey: "fakeAppKey",
+			LifecycleNotifications: &LifecycleNotifications{
+				DiscordChannelID:    "chLifecycle",
+				PushoverDestination: "userkey",
+			},
+		}
+		announceLifecycle(context.Background(), mockSess, cfg, "discord2pushover started")
+		expectedLog := "ChannelMessageSend called with: chID=chLifecycle, content=discord2pushover started"
+		if !strings.Contains(testLogBufferForTest.String(), expectedLog) {
+			t.Errorf("Expected log '%s' not found. Log: %s", expectedLog, testLogBufferForTest.String())
+		}
+		if !testHookPushoverSendCalled {
+			t.Errorf("Expected Pushover send for lifecycle announcement.")
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package discord2pushover
+
+import (
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	defaultLogMaxSizeMB  = 100
+	defaultLogMaxBackups = 5
+)
+
+// newLogFile returns a writer appending to config.LogFile that rotates it once it reaches
+// logMaxSizeMB, keeping logMaxBackups rotated files next to it.
+func newLogFile(config *Config) *lumberjack.Logger {
+	maxSize := config.LogMaxSizeMB
+	if maxSize <= 0 {
+		maxSize = defaultLogMaxSizeMB
+	}
+	maxBackups := config.LogMaxBackups
+	if maxBackups <= 0 {
+		maxBackups = defaultLogMaxBackups
+	}
+	return &lumberjack.Logger{
+		Filename:   config.LogFile,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+		LocalTime:  true,
+	}
+}
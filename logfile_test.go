@@ -0,0 +1,29 @@
+package discord2pushover
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLogFile(t *testing.T) {
+	dir := t.TempDir()
+	logFile := newLogFile(&Config{LogFile: filepath.Join(dir, "bot.log")})
+	if logFile.MaxSize != defaultLogMaxSizeMB || logFile.MaxBackups != defaultLogMaxBackups {
+		t.Errorf("Expected the defaults, got %d MB and %d backups", logFile.MaxSize, logFile.MaxBackups)
+	}
+
+	logFile = newLogFile(&Config{LogFile: filepath.Join(dir, "bot.log"), LogMaxSizeMB: 1, LogMaxBackups: 2})
+	defer logFile.Close()
+	line := []byte(strings.Repeat("x", 600*1024) + "\n")
+	for i := 0; i < 2; i++ {
+		if _, err := logFile.Write(line); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected the log file to be rotated once it exceeded 1 MB, got %d files", len(entries))
+	}
+}
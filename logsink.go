@@ -0,0 +1,220 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	logSinkSyslog   = "syslog"
+	logSinkJournald = "journald"
+
+	syslogFacilityDaemon = 3
+	logSinkIdentifier    = "discord2pushover"
+)
+
+// journaldSocket is the socket of journald's native protocol. A var for tests.
+var journaldSocket = "/run/systemd/journal/socket"
+
+// localSyslogSockets are tried in order when syslogAddress is not set.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogSeverity maps a logrus level to a syslog severity, which journald uses as PRIORITY too.
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 2 // crit
+	case logrus.ErrorLevel:
+		return 3 // err
+	case logrus.WarnLevel:
+		return 4 // warning
+	case logrus.InfoLevel:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+// logSinkHook sends every log entry to syslog or journald, keeping its severity.
+type logSinkHook struct {
+	mu     sync.Mutex
+	dial   func() (net.Conn, error)
+	conn   net.Conn
+	encode func(entry *logrus.Entry) []byte
+}
+
+func (h *logSinkHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire sends an entry, reconnecting once if the sink went away (e.g. syslog was restarted).
+func (h *logSinkHook) Fire(entry *logrus.Entry) error {
+	data := h.encode(entry)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if h.conn == nil {
+			if h.conn, err = h.dial(); err != nil {
+				h.conn = nil
+				continue
+			}
+		}
+		if _, err = h.conn.Write(data); err == nil {
+			return nil
+		}
+		h.conn.Close()
+		h.conn = nil
+	}
+	return err
+}
+
+func (h *logSinkHook) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+// entryFields returns an entry's fields as sorted key=value pairs.
+func entryFields(entry *logrus.Entry) []string {
+	fields := make([]string, 0, len(entry.Data))
+	for key, value := range entry.Data {
+		fields = append(fields, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// newSyslogHook returns a hook sending to the local syslog daemon, or to address given as
+// "udp://host:514" or "tcp://host:514".
+func newSyslogHook(address string) (*logSinkHook, error) {
+	network, host := "", ""
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return nil, fmt.Errorf("invalid syslogAddress '%s', expected udp://host:port or tcp://host:port", address)
+		}
+		network, host = u.Scheme, u.Host
+	}
+	hostname, _ := os.Hostname()
+	hook := &logSinkHook{
+		dial: func() (net.Conn, error) {
+			if network != "" {
+				return net.DialTimeout(network, host, 5*time.Second)
+			}
+			var err error
+			for _, socket := range localSyslogSockets {
+				for _, socketNetwork := range []string{"unixgram", "unix"} {
+					var conn net.Conn
+					if conn, err = net.Dial(socketNetwork, socket); err == nil {
+						return conn, nil
+					}
+				}
+			}
+			return nil, fmt.Errorf("no local syslog socket found: %w", err)
+		},
+		encode: func(entry *logrus.Entry) []byte {
+			message := strings.Join(append([]string{entry.Message}, entryFields(entry)...), " ")
+			priority := syslogFacilityDaemon*8 + syslogSeverity(entry.Level)
+			var line string
+			if network == "" {
+				line = fmt.Sprintf("<%d>%s %s[%d]: %s", priority, entry.Time.Format(time.Stamp), logSinkIdentifier, os.Getpid(), message)
+			} else {
+				line = fmt.Sprintf("<%d>%s %s %s[%d]: %s", priority, entry.Time.Format(time.RFC3339), hostname, logSinkIdentifier, os.Getpid(), message)
+			}
+			if network == "tcp" {
+				line += "\n" // Messages on a stream are newline-delimited
+			}
+			return []byte(line)
+		},
+	}
+	conn, err := hook.dial()
+	if err != nil {
+		return nil, err
+	}
+	hook.conn = conn
+	return hook, nil
+}
+
+// journaldField appends a field in journald's native protocol, using the binary form for values
+// with newlines.
+func journaldField(buf *bytes.Buffer, key string, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", key, value)
+		return
+	}
+	buf.WriteString(key + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// journaldFieldName turns a logrus field name into a valid journal field name: uppercase letters,
+// digits and underscores, not starting with an underscore.
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	return strings.TrimLeft(name, "_0123456789")
+}
+
+// newJournaldHook returns a hook sending structured entries to journald.
+func newJournaldHook() (*logSinkHook, error) {
+	hook := &logSinkHook{
+		dial: func() (net.Conn, error) {
+			return net.Dial("unixgram", journaldSocket)
+		},
+		encode: func(entry *logrus.Entry) []byte {
+			var buf bytes.Buffer
+			journaldField(&buf, "MESSAGE", entry.Message)
+			journaldField(&buf, "PRIORITY", fmt.Sprint(syslogSeverity(entry.Level)))
+			journaldField(&buf, "SYSLOG_IDENTIFIER", logSinkIdentifier)
+			journaldField(&buf, "SYSLOG_PID", fmt.Sprint(os.Getpid()))
+			for key, value := range entry.Data {
+				if name := journaldFieldName(key); name != "" {
+					journaldField(&buf, name, fmt.Sprint(value))
+				}
+			}
+			return buf.Bytes()
+		},
+	}
+	conn, err := hook.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journald at %s: %w", journaldSocket, err)
+	}
+	hook.conn = conn
+	return hook, nil
+}
+
+// newLogSinkHook returns the hook for config.LogSink.
+func newLogSinkHook(config *Config) (*logSinkHook, error) {
+	switch config.LogSink {
+	case logSinkSyslog:
+		return newSyslogHook(config.SyslogAddress)
+	case logSinkJournald:
+		return newJournaldHook()
+	default:
+		return nil, fmt.Errorf("unknown logSink '%s', expected '%s' or '%s'", config.LogSink, logSinkSyslog, logSinkJournald)
+	}
+}
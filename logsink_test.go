@@ -0,0 +1,88 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyslogHook(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	hook, err := newLogSinkHook(&Config{LogSink: logSinkSyslog, SyslogAddress: "udp://" + listener.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("newLogSinkHook failed: %v", err)
+	}
+	defer hook.Close()
+	entry := &logrus.Entry{Level: logrus.WarnLevel, Message: "Budget exhausted", Time: time.Now(), Data: logrus.Fields{"rule": "Alerts"}}
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Fire failed: %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	got := string(buf[:n])
+	// Facility daemon (3) * 8 + severity warning (4)
+	if !strings.HasPrefix(got, "<28>") || !strings.Contains(got, " discord2pushover[") || !strings.HasSuffix(got, ": Budget exhausted rule=Alerts") {
+		t.Errorf("Unexpected syslog message %q", got)
+	}
+
+	if _, err := newLogSinkHook(&Config{LogSink: logSinkSyslog, SyslogAddress: "host:514"}); err == nil {
+		t.Error("Expected an error for an address without scheme")
+	}
+	if _, err := newLogSinkHook(&Config{LogSink: "eventlog"}); err == nil {
+		t.Error("Expected an error for an unknown sink")
+	}
+}
+
+func TestJournaldHook(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.socket")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+	oldSocket := journaldSocket
+	journaldSocket = socket
+	defer func() { journaldSocket = oldSocket }()
+
+	hook, err := newLogSinkHook(&Config{LogSink: logSinkJournald})
+	if err != nil {
+		t.Fatalf("newLogSinkHook failed: %v", err)
+	}
+	defer hook.Close()
+	if err := hook.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Message: "line one\nline two", Data: logrus.Fields{"message-id": "123"}}); err != nil {
+		t.Fatalf("Fire failed: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	got := buf[:n]
+	var message bytes.Buffer
+	message.WriteString("MESSAGE\n")
+	binary.Write(&message, binary.LittleEndian, uint64(len("line one\nline two")))
+	message.WriteString("line one\nline two\n")
+	for _, want := range [][]byte{message.Bytes(), []byte("PRIORITY=3\n"), []byte("SYSLOG_IDENTIFIER=discord2pushover\n"), []byte("MESSAGE_ID=123\n")} {
+		if !bytes.Contains(got, want) {
+			t.Errorf("Expected %q in journal entry %q", want, got)
+		}
+	}
+}
@@ -1,11 +1,14 @@
-package main
+package discord2pushover
 
 import (
+	"context"
 	"flag"
 	"fmt" // Added for version printing
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -13,7 +16,6 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"math" // Added for MaxInt32
 
-	"github.com/gregdel/pushover"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,16 +27,37 @@ var log = logrus.New()
 // TrackedEmergencyMessage holds information about an emergency Pushover notification
 // that requires acknowledgment tracking.
 type TrackedEmergencyMessage struct {
-	DiscordMessageID  string
-	DiscordChannelID  string
-	PushoverReceiptID string
-	AckEmoji          string
-	ExpiryTime        time.Time
+	DiscordMessageID    string
+	DiscordChannelID    string
+	PushoverReceiptID   string
+	AckEmoji            string
+	ExpiryTime          time.Time
+	RuleName            string // Rule that sent the notification
+	Fingerprint         string // Rendered resolveOn fingerprint of the alert
+	ResolvedEmoji       string
+	ReactionEmojis      []string             // The rule's reaction emoji on the message, removed again if the alert is retracted
+	PendingEmoji        string               // Bot reaction shown until the notification is acknowledged
+	RemoveReactionOnAck bool                 // Remove ReactionEmojis once acknowledged
+	Escalation          *emergencyEscalation // Sent if still unacknowledged after a while; shared by the alert's receipts
+	Status              *emergencyStatus     // Reply showing the paging progress; shared by the alert's receipts
+	Countdown           *emergencyCountdown  // Reaction counting down to the expiry; shared by the alert's receipts
+	IncidentKey         string               // Dedup key of the incidents opened by the rule's IncidentNotifiers
+	IncidentNotifiers   []string             // The rule's PagerDuty and Opsgenie notifiers
 }
 
-// trackedMessages stores emergency messages that are pending acknowledgment.
-// Keyed by PushoverReceiptID.
-var trackedMessages sync.Map
+// trackedEmergenciesInit guards the creation of a config's emergency tracking.
+var trackedEmergenciesInit sync.Mutex
+
+// trackedEmergencies returns the emergency messages of the config that are pending acknowledgment,
+// keyed by PushoverReceiptID, so every config loaded by an embedding program tracks its own.
+func (c *Config) trackedEmergencies() *sync.Map {
+	trackedEmergenciesInit.Lock()
+	defer trackedEmergenciesInit.Unlock()
+	if c.emergencies == nil {
+		c.emergencies = &sync.Map{}
+	}
+	return c.emergencies
+}
 
 var (
 	// Populated by go build
@@ -49,6 +72,11 @@ type DiscordSessionInterface interface {
 	ChannelMessage(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error)
 	State() *discordgo.State // Provided by wrapper for *discordgo.Session
 	MessageReactionAdd(channelID, messageID, emojiID string, opts ...discordgo.RequestOption) error
+	MessageReactionRemove(channelID, messageID, emojiID, userID string, opts ...discordgo.RequestOption) error
+	ChannelMessageSend(channelID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendReply(channelID, content string, reference *discordgo.MessageReference, opts ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEdit(channelID, messageID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string, opts ...discordgo.RequestOption) ([]*discordgo.Message, error)
 }
 
 // DiscordGoSessionWrapper wraps a *discordgo.Session to satisfy DiscordSessionInterface.
@@ -74,11 +102,39 @@ func (w *DiscordGoSessionWrapper) MessageReactionAdd(channelID, messageID, emoji
 	return w.RealSession.MessageReactionAdd(channelID, messageID, emojiID, opts...)
 }
 
+// MessageReactionRemove calls the RealSession's MessageReactionRemove.
+func (w *DiscordGoSessionWrapper) MessageReactionRemove(channelID, messageID, emojiID, userID string, opts ...discordgo.RequestOption) error {
+	return w.RealSession.MessageReactionRemove(channelID, messageID, emojiID, userID, opts...)
+}
+
+// ChannelMessageSend calls the RealSession's ChannelMessageSend.
+func (w *DiscordGoSessionWrapper) ChannelMessageSend(channelID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return w.RealSession.ChannelMessageSend(channelID, content, opts...)
+}
+
+// ChannelMessageSendReply calls the RealSession's ChannelMessageSendReply.
+func (w *DiscordGoSessionWrapper) ChannelMessageSendReply(channelID, content string, reference *discordgo.MessageReference, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return w.RealSession.ChannelMessageSendReply(channelID, content, reference, opts...)
+}
+
+// ChannelMessageEdit calls the RealSession's ChannelMessageEdit.
+func (w *DiscordGoSessionWrapper) ChannelMessageEdit(channelID, messageID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return w.RealSession.ChannelMessageEdit(channelID, messageID, content, opts...)
+}
+
+// ChannelMessages calls the RealSession's ChannelMessages.
+func (w *DiscordGoSessionWrapper) ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string, opts ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	return w.RealSession.ChannelMessages(channelID, limit, beforeID, afterID, aroundID, opts...)
+}
+
 // Ensure DiscordGoSessionWrapper satisfies DiscordSessionInterface at compile time.
 var _ DiscordSessionInterface = &DiscordGoSessionWrapper{}
 
 
-func main() {
+// Main runs the discord2pushover command: it parses the command line flags, loads the config and
+// runs the bot or the given subcommand, and returns the exit code. cmd/discord2pushover calls it;
+// programs embedding the rule engine use LoadConfig and ProcessMessage instead.
+func Main() int {
 	// Setup logging - initial minimal setup. Level will be set after config load.
 	log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
 	// Default to InfoLevel, will be overridden by config if specified.
@@ -92,13 +148,42 @@ func main() {
 
 	configPath := flag.String("c", "", "Path to the configuration file (e.g., discord2pushover.yaml)")
 	versionFlag := flag.Bool("version", false, "Print version information and exit")
-	flag.Parse()
+	runTestsFlag := flag.Bool("run-tests", false, "With validate, also run the rule tests of the configuration")
+	flag.StringVar(&configEnv, "env", "", "Environment whose overlay is merged over the configuration, e.g. staging for staging.yaml next to it")
+	flag.StringVar(&ageIdentityFile, "age-identity", "", "age identity file decrypting encrypted configuration values (default $"+ageKeyFileEnv+")")
+	flag.StringVar(&recordFile, "record", "", "Append the message and reaction events the bot receives to this file, with their text redacted, for --replay")
+	flag.StringVar(&replayFile, "replay", "", "Feed the events recorded in this file through the rules instead of connecting to Discord")
+	flag.Float64Var(&replaySpeed, "replay-speed", 1, "With --replay, speed relative to the recording; 0 replays as fast as possible")
+	flag.BoolVar(&mockNotifiers, "mock-notifiers", false, "With --replay, log notifications instead of sending them")
+	flag.Usage = printUsage
+	publishMetrics()
+
+	// An optional subcommand may precede the flags, e.g. `discord2pushover channels -c config.yaml`.
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
+	if command == "" && flag.NArg() > 0 {
+		command = flag.Arg(0)
+	}
+	if command != "" && !isKnownSubcommand(command) {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
+		printUsage()
+		return 2
+	}
 
 	// If version flag is set, print version and exit BEFORE config loading & full log setup.
 	// Use fmt.Printf for this as log level isn't fully configured yet.
 	if *versionFlag {
 		fmt.Printf("discord2pushover version %s, commit %s, built at %s\n", Version, Commit, Date)
-		os.Exit(0)
+		return 0
+	}
+
+	if command == "schema" {
+		return printSchema(os.Stdout)
 	}
 
 	actualConfigPath := ""
@@ -107,13 +192,13 @@ func main() {
 			actualConfigPath = *configPath
 		} else {
 			log.Errorf("Config file specified by -c flag not found: %s", *configPath)
-			os.Exit(1)
+			return 1
 		}
 	} else {
 		cwd, err := os.Getwd()
 		if err != nil {
 			log.Errorf("Error getting current working directory: %v", err)
-			os.Exit(1)
+			return 1
 		}
 		defaultPathYaml := filepath.Join(cwd, "discord2pushover.yaml")
 		if _, err := os.Stat(defaultPathYaml); err == nil {
@@ -130,15 +215,32 @@ func main() {
 		log.Error("Configuration file not found.")
 		log.Error("Please specify a config file using the -c flag,")
 		log.Error("or place 'discord2pushover.yaml' or 'discord2pushover.yml' in the current directory.")
-		os.Exit(1)
+		return 1
+	}
+
+	// Rule bundles carry the rules as written, without environment variables substituted
+	if command == "export-rules" {
+		return runExportRules(actualConfigPath, os.Stdout)
+	}
+	if command == "import-rules" {
+		bundlePath := flag.Arg(0)
+		if bundlePath == command {
+			bundlePath = flag.Arg(1)
+		}
+		return runImportRules(actualConfigPath, bundlePath, os.Stdout)
+	}
+
+	// Older configs may not load correctly, so they are migrated before loading
+	if command == "migrate-config" {
+		return runMigrateConfig(actualConfigPath, os.Stdout)
 	}
 
 	log.Infof("Loading configuration from: %s", actualConfigPath)
-	loadedConfig, err := LoadConfig(actualConfigPath) // Use a temporary variable
+	loadedConfig, err := LoadConfigEnv(actualConfigPath, configEnv) // Use a temporary variable
 	if err != nil {
 		// Use current log level (default Info) for this error, as config hasn't been processed for log level yet.
 		log.Errorf("Error loading configuration: %v", err)
-		os.Exit(1)
+		return 1
 	}
 	globalConfig = loadedConfig // Assign to the global variable
 
@@ -157,54 +259,203 @@ func main() {
 		// log.SetLevel(logrus.InfoLevel) // Already default, but explicit if needed
 	}
 
+	if globalConfig.LogFile != "" {
+		logFile := newLogFile(globalConfig)
+		defer logFile.Close()
+		log.Infof("Logging to %s (rotated at %d MB, %d backups kept).", logFile.Filename, logFile.MaxSize, logFile.MaxBackups)
+		log.SetOutput(logFile)
+	}
+
+	if globalConfig.LogSink != "" {
+		if hook, err := newLogSinkHook(globalConfig); err != nil {
+			log.Errorf("Error setting up logSink, logging to stderr: %v", err)
+		} else {
+			defer hook.Close()
+			log.AddHook(hook)
+			if globalConfig.LogFile == "" {
+				log.SetOutput(io.Discard) // The sink replaces stderr
+			}
+			log.Infof("Logging to %s.", globalConfig.LogSink)
+		}
+	}
+
 	// Now log version info, as log level is configured.
 	log.Infof("discord2pushover version %s, commit %s, built at %s", Version, Commit, Date)
 	log.Info("Configuration loaded successfully.")
+	initSentry(globalConfig)
 
 
+	if command == "validate" {
+		return runValidate(globalConfig, actualConfigPath, *runTestsFlag, os.Stdout)
+	}
+	if replayFile != "" {
+		return runReplay(globalConfig, replayFile, replaySpeed, mockNotifiers)
+	}
 	if globalConfig.DiscordToken == "" {
 		log.Error("DiscordToken is missing from the configuration.")
-		os.Exit(1)
+		return 1
+	}
+	if serviceCommands[command] {
+		return runServiceCommand(command, actualConfigPath)
+	}
+	if command != "" {
+		return runSubcommand(command, globalConfig)
 	}
+
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	return runBot(sc, reload)
+}
+
+// runBot connects to Discord and processes events until a signal is received on stop, and returns
+// the exit code. A signal on reload rotates the tokens (see monitorTokens).
+func runBot(stop <-chan os.Signal, reload <-chan os.Signal) int {
 	if globalConfig.PushoverAppKey == "" {
 		log.Error("PushoverAppKey is missing from the configuration.")
-		os.Exit(1)
+		return 1
 	}
 	// Note: PushoverUserKey (the destination) is per-rule, so not checked globally here.
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runContext = ctx
+
 	log.Info("Connecting to Discord...")
 	dg, err := discordgo.New("Bot " + globalConfig.DiscordToken)
 	if err != nil {
 		log.Errorf("Error creating Discord session: %v", err)
-		os.Exit(1)
+		return 1
 	}
+	applyDiscordTransport(dg, globalConfig)
 
 	// Register handlers
 	dg.AddHandler(messageCreate)
 	dg.AddHandler(messageUpdate)
 	dg.AddHandler(dgMessageReactionAdd) // Register new handler
+	dg.AddHandler(dgMessageReactionRemove)
+	dg.AddHandler(dgMessageDelete)
+	dg.AddHandler(dgMessageDeleteBulk)
+	dg.AddHandler(dgChannelPinsUpdate)
+	dg.AddHandler(dgThreadCreate)
+	dg.AddHandler(dgAutoModerationActionExecution)
+	dg.AddHandler(dgGuildAuditLogEntryCreate)
+	dg.AddHandler(dgInteractionCreate)
+	dg.AddHandler(onDiscordDisconnect)
+	dg.AddHandler(onDiscordConnect)
+	dg.AddHandler(onDiscordResumed)
+	if recordFile != "" {
+		recorder, err := newEventRecorder(recordFile)
+		if err != nil {
+			log.Errorf("Error setting up --record: %v", err)
+			return 1
+		}
+		defer recorder.Close()
+		dg.AddHandler(recorder.onEvent)
+		log.Infof("Recording events to %s.", recordFile)
+	}
 
-	// We need intents for messages and message reactions to get message update events with reaction data.
-	// Also add DirectMessageReactions for DM support.
-	dg.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsGuildMessageReactions | discordgo.IntentsDirectMessageReactions
+	dg.Identify.Intents = gatewayIntents(globalConfig)
+	discordPoster = dg
 
 	// Open a websocket connection to Discord and begin listening.
 	err = dg.Open()
 	if err != nil {
 		log.Errorf("Error opening connection to Discord: %v", err)
-		os.Exit(1)
+		return 1
 	}
 	log.Info("Discord session opened successfully.")
 
+	if dg.State != nil && dg.State.User != nil {
+		preflightChannelPermissions(dg, dg.State.User.ID, globalConfig)
+	}
+	if err := loadCustomEmojis(dg); err != nil {
+		log.Warnf("Could not load custom emoji, custom emoji given by name cannot be checked or reacted with: %v", err)
+	} else if unknown := validateConfiguredEmojis(globalConfig); unknown > 0 {
+		log.Warnf("%d configured custom emoji are unknown; reactions with them will fail.", unknown)
+	}
+	if globalConfig.GuildRules != nil {
+		registerGuildRuleCommand(dg, globalConfig)
+	}
+
 	// Start polling for emergency acknowledgements
-	go PollEmergencyAcknowledgements(dg, globalConfig) // Logging for poller start is inside the function
+	go pollEmergencyAcknowledgements(ctx, dg, globalConfig) // Logging for poller start is inside the function
+	go monitorTokens(ctx, dg, globalConfig, reload)
+
+	sessionWrapper := &DiscordGoSessionWrapper{RealSession: dg}
+	if globalConfig.Admin != nil && globalConfig.Admin.Listen != "" {
+		adminServer := startAdminListener(globalConfig.Admin)
+		defer adminServer.Close()
+	}
+	if globalConfig.ControlPlane != nil && globalConfig.ControlPlane.Listen != "" {
+		if controlServer := startControlPlane(globalConfig, globalConfig.filePath); controlServer != nil {
+			defer controlServer.Stop()
+		}
+	}
+	if globalConfig.StatsLogIntervalMinutes >= 0 {
+		go pollRuleStatsLog(globalConfig)
+	}
+	if globalConfig.StateFile != "" {
+		if err := checkpoints.load(globalConfig.StateFile); err != nil {
+			log.Errorf("Error loading state, starting without checkpoints: %v", err)
+		}
+		go flushCheckpoints()
+	}
+	if globalConfig.Backfill != nil {
+		go runBackfill(sessionWrapper, globalConfig)
+	}
+	if globalConfig.ReplyBridge != nil {
+		go pollReplyBridge(ctx, sessionWrapper, globalConfig)
+	}
+	if globalConfig.IncidentSync != nil && globalConfig.IncidentSync.Listen != "" {
+		incidentServer := startIncidentWebhookListener(ctx, globalConfig, sessionWrapper)
+		defer incidentServer.Close()
+	}
+	if globalConfig.Ingest != nil && globalConfig.Ingest.Listen != "" {
+		ingestServer := startIngestListener(globalConfig, sessionWrapper)
+		defer ingestServer.Close()
+	}
+	if globalConfig.OnCall != nil && globalConfig.OnCall.CalendarURL != "" {
+		go pollOnCallCalendar(ctx, globalConfig)
+	}
+	if globalConfig.BusinessHours != nil && globalConfig.BusinessHours.HolidayCalendarURL != "" {
+		go pollHolidayCalendar(ctx, globalConfig)
+	}
+	if usesReminders(globalConfig) {
+		go pollReminders(ctx, sessionWrapper, globalConfig)
+	}
+	announceLifecycle(ctx, sessionWrapper, globalConfig, fmt.Sprintf("discord2pushover %s (commit %s) started.", Version, Commit))
+	emitBotEvent(globalConfig, botEvent{Event: botEventStarted, Text: fmt.Sprintf("discord2pushover %s (commit %s) started.", Version, Commit)})
+
+	// With systemd Type=notify the unit only becomes active once the gateway connection is open
+	if _, err := sdNotify("READY=1\nSTATUS=Connected to Discord"); err != nil {
+		log.Errorf("Error notifying systemd of readiness: %v", err)
+	}
+	go runWatchdog(dg)
+	adminHealth = func(now time.Time) error {
+		if !gatewayHealthy(dg, now) {
+			return fmt.Errorf("Discord gateway has not acknowledged a heartbeat for %s", maxHeartbeatAge)
+		}
+		return nil
+	}
 
 	log.Info("Bot is now running. Press CTRL-C to exit.")
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 
-	receivedSignal := <-sc
+	receivedSignal := <-stop
 	log.Infof("Received signal: %v. Shutting down...", receivedSignal)
+	sdNotify("STOPPING=1")
+
+	// Abort in-flight rule processing and notifications; the announcement gets a deadline of its own
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), backgroundSendTimeout)
+	defer cancelShutdown()
+	announceLifecycle(shutdownCtx, sessionWrapper, globalConfig, fmt.Sprintf("discord2pushover %s shutting down (signal: %v).", Version, receivedSignal))
+	sendBotEvent(shutdownCtx, globalConfig, botEvent{Event: botEventStopping, Text: fmt.Sprintf("discord2pushover %s shutting down (signal: %v).", Version, receivedSignal)})
+
+	if err := checkpoints.flush(); err != nil {
+		log.Errorf("Error saving checkpoints: %v", err)
+	}
 
 	// Cleanly close down the Discord session.
 	log.Info("Closing Discord session...")
@@ -214,23 +465,25 @@ func main() {
 	} else {
 		log.Info("Discord session closed.")
 	}
+	flushSentry()
 	log.Info("Exiting.")
+	return 0
 }
 
-// PollEmergencyAcknowledgements periodically checks Pushover for acknowledged emergency messages
-// and reacts on Discord if they are acknowledged.
-func PollEmergencyAcknowledgements(session *discordgo.Session, config *Config) {
-	// Create a new Pushover app instance
-	app := pushover.New(config.PushoverAppKey)
-
+// pollEmergencyAcknowledgements periodically checks Pushover for acknowledged emergency messages
+// and reacts on Discord if they are acknowledged. It returns once ctx is done.
+func pollEmergencyAcknowledgements(ctx context.Context, session *discordgo.Session, config *Config) {
+	defer recoverPanic("pollEmergencyAcknowledgements")
 	if config == nil {
-		log.Error("PollEmergencyAcknowledgements: globalConfig is nil, cannot poll.")
+		log.Error("pollEmergencyAcknowledgements: globalConfig is nil, cannot poll.")
 		return
 	}
 	if session == nil {
-		log.Error("PollEmergencyAcknowledgements: Discord session is nil, cannot poll.")
+		log.Error("pollEmergencyAcknowledgements: Discord session is nil, cannot poll.")
 		return
 	}
+	client := config.pushoverClient()
+	wrapper := &DiscordGoSessionWrapper{RealSession: session}
 
 	// How often to poll Pushover for receipt status
 	// Requirement: "every 5 seconds"
@@ -239,13 +492,24 @@ func PollEmergencyAcknowledgements(session *discordgo.Session, config *Config) {
 
 	log.Info("Starting emergency acknowledgement polling (interval: 5s)...")
 
-	for range ticker.C {
-		trackedMessages.Range(func(key, value interface{}) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopped emergency acknowledgement polling.")
+			return
+		case <-ticker.C:
+		}
+		config.trackedEmergencies().Range(func(key, value interface{}) bool {
+			if ctx.Err() != nil {
+				return false // Shutting down
+			}
+			// A panic here ends this tick's iteration only; polling resumes on the next tick.
+			defer recoverPanic("pollEmergencyAcknowledgements receipt check")
 			receiptID := key.(string)
 			trackedMsg, ok := value.(TrackedEmergencyMessage)
 			if !ok {
 				log.Errorf("Error: Could not cast value for receipt %s to TrackedEmergencyMessage", receiptID)
-				trackedMessages.Delete(receiptID)
+				config.trackedEmergencies().Delete(receiptID)
 				return true // continue iteration
 			}
 
@@ -253,47 +517,80 @@ func PollEmergencyAcknowledgements(session *discordgo.Session, config *Config) {
 			if time.Now().After(trackedMsg.ExpiryTime) {
 				log.Infof("Emergency message (Receipt: %s, DiscordMsg: %s) expired without acknowledgement.",
 					receiptID, trackedMsg.DiscordMessageID)
-				trackedMessages.Delete(receiptID)
+				config.trackedEmergencies().Delete(receiptID)
+				finishEmergencyStatus(wrapper, trackedMsg, "⌛ Expired unacknowledged")
+				clearEmergencyCountdown(wrapper, trackedMsg)
+				emitBotEvent(config, botEvent{Event: botEventEmergencyExpired, Rule: trackedMsg.RuleName,
+					ChannelID: trackedMsg.DiscordChannelID, MessageID: trackedMsg.DiscordMessageID, Receipt: receiptID})
 				return true // continue iteration
 			}
 
 			// Check Pushover for acknowledgment
 			log.Debugf("Polling Pushover for receipt: %s (DiscordMsg: %s)", receiptID, trackedMsg.DiscordMessageID)
 
-			receiptDetails, err := app.GetReceiptDetails(receiptID) // This is a blocking call, so it will wait for the response
+			receiptDetails, err := client.GetReceiptDetails(ctx, receiptID)
 			if err != nil {
 				log.Errorf("Error checking Pushover receipt %s: %v", receiptID, err)
 				// Don't remove from map, try again next time unless it's a permanent error (not handled yet)
+				escalateIfDue(ctx, config, trackedMsg, time.Now())
+				updateEmergencyStatus(wrapper, trackedMsg, time.Now())
+				updateEmergencyCountdown(wrapper, trackedMsg, time.Now())
 			} else if receiptDetails.Status != 1 {
 				log.Warnf("Pushover receipt %s returned non-success status (%d).", receiptID, receiptDetails.Status)
 				// Remove from map
-				trackedMessages.Delete(receiptID)
+				config.trackedEmergencies().Delete(receiptID)
+				finishEmergencyStatus(wrapper, trackedMsg, "⚠️ No longer tracked")
+				clearEmergencyCountdown(wrapper, trackedMsg)
 			} else if receiptDetails.Acknowledged {
 				log.Infof("Pushover emergency message (Receipt: %s, DiscordMsg: %s) was acknowledged!",
 					receiptID, trackedMsg.DiscordMessageID)
 
-				if trackedMsg.AckEmoji != "" {
-					errReact := session.MessageReactionAdd(trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, trackedMsg.AckEmoji)
-					if errReact != nil {
-						log.Errorf("Error adding AckEmoji '%s' to Discord message %s (channel %s): %v",
-							trackedMsg.AckEmoji, trackedMsg.DiscordMessageID, trackedMsg.DiscordChannelID, errReact)
-					} else {
-						log.Infof("Added AckEmoji '%s' to Discord message %s (channel %s).",
-							trackedMsg.AckEmoji, trackedMsg.DiscordMessageID, trackedMsg.DiscordChannelID)
-					}
-				}
-				trackedMessages.Delete(receiptID) // Remove from tracking
+				markAcknowledged(wrapper, trackedMsg)
+				finishEmergencyStatus(wrapper, trackedMsg, "✅ Acknowledged")
+				clearEmergencyCountdown(wrapper, trackedMsg)
+				config.trackedEmergencies().Delete(receiptID) // Remove from tracking
+				cancelSiblingReceipts(ctx, config, trackedMsg)
+				syncAcknowledgementToIncidents(ctx, config, trackedMsg)
+				emitBotEvent(config, botEvent{Event: botEventEmergencyAcknowledged, Rule: trackedMsg.RuleName,
+					ChannelID: trackedMsg.DiscordChannelID, MessageID: trackedMsg.DiscordMessageID, Receipt: receiptID,
+					AcknowledgedBy: receiptDetails.AcknowledgedBy})
 			} else {
 				log.Debugf("Pushover receipt %s (DiscordMsg: %s) not yet acknowledged.", receiptID, trackedMsg.DiscordMessageID)
+				escalateIfDue(ctx, config, trackedMsg, time.Now())
+				updateEmergencyStatus(wrapper, trackedMsg, time.Now())
+				updateEmergencyCountdown(wrapper, trackedMsg, time.Now())
 			}
 			return true // continue iteration
 		})
 	}
 }
 
+// markAcknowledged updates the reactions on an acknowledged emergency's Discord message: the AckEmoji
+// is added and replaces the pending emoji and, with removeReactionOnAck, the rule's reaction emoji.
+func markAcknowledged(session DiscordSessionInterface, trackedMsg TrackedEmergencyMessage) {
+	if trackedMsg.AckEmoji != "" {
+		errReact := session.MessageReactionAdd(trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, reactionEmojiAPIName(trackedMsg.AckEmoji))
+		if errReact != nil {
+			log.Errorf("Error adding AckEmoji '%s' to Discord message %s (channel %s): %v",
+				trackedMsg.AckEmoji, trackedMsg.DiscordMessageID, trackedMsg.DiscordChannelID, errReact)
+		} else {
+			log.Infof("Added AckEmoji '%s' to Discord message %s (channel %s).",
+				trackedMsg.AckEmoji, trackedMsg.DiscordMessageID, trackedMsg.DiscordChannelID)
+		}
+	}
+	// The ack emoji takes the place of the pending markers rather than stacking next to them
+	removeBotReaction(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, trackedMsg.PendingEmoji)
+	if trackedMsg.RemoveReactionOnAck {
+		for _, emoji := range trackedMsg.ReactionEmojis {
+			removeBotReaction(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, emoji)
+		}
+	}
+}
+
 // messageCreate will be called (by the discordgo library) every time a new
 // message is created on any channel that the authenticated bot has access to.
 func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	defer recoverPanic("messageCreate")
 	// Guard against nil State or User, which can happen in tests or edge cases.
 	if s.State == nil || s.State.User == nil {
 		log.Error("messageCreate: session state or user is nil. Cannot reliably determine bot ID. Skipping message.")
@@ -304,16 +601,30 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	messageCreateLogic(ctx, &DiscordGoSessionWrapper{RealSession: s}, m)
+}
+
+// messageCreateLogic handles a new message from another user, e.g. one replayed by --replay.
+func messageCreateLogic(ctx context.Context, wrapper DiscordSessionInterface, m *discordgo.MessageCreate) {
 	// Log the basic message info (can be removed or made more verbose later)
 	log.Debugf("Received message: ID=%s, AuthorID=%s, ChannelID=%s, Content='%s'", m.Message.ID, m.Message.Author.ID, m.Message.ChannelID, m.Message.Content) // Use m.Message for consistency
 
 	// Process rules against the message
 	if globalConfig != nil {
-		wrapper := &DiscordGoSessionWrapper{RealSession: s}
+		defer checkpointMessage(globalConfig, m.ChannelID, m.ID)
+		if handleSubscriptionCommand(ctx, m.Message, globalConfig, wrapper) {
+			return
+		}
+		if handleCommandMessage(ctx, m.Message, globalConfig, wrapper) {
+			return // Commands are not also matched against the passive message rules
+		}
 		// For new messages, there's no prior notification context from bot reactions on this message event
-		ProcessRules(m.Message, globalConfig, wrapper, math.MaxInt32) // Pass m.Message
+		ProcessMessage(ctx, m.Message, globalConfig, wrapper)
+		processGuildRules(ctx, m.Message, globalConfig, wrapper)
 	} else {
-		// This should ideally not happen if main() ensures globalConfig is initialized.
+		// This should ideally not happen if Main() ensures globalConfig is initialized.
 		log.Error("globalConfig is nil in messageCreate. Rules cannot be processed.")
 	}
 }
@@ -323,13 +634,16 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 // This includes changes to content, embeds, and reactions.
 // This is the actual handler registered with DiscordGo.
 func messageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	defer recoverPanic("messageUpdate")
 	wrapper := &DiscordGoSessionWrapper{RealSession: s}
-	messageUpdateLogic(wrapper, m)
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	messageUpdateLogic(ctx, wrapper, m)
 }
 
 // messageUpdateLogic contains the actual logic for handling message updates.
 // It accepts an interface to allow mocking for tests.
-func messageUpdateLogic(s DiscordSessionInterface, m *discordgo.MessageUpdate) {
+func messageUpdateLogic(ctx context.Context, s DiscordSessionInterface, m *discordgo.MessageUpdate) {
 	currentSessionState := s.State()
 	if currentSessionState == nil || currentSessionState.User == nil {
 		log.Error("messageUpdateLogic: session state or user is nil. Cannot reliably determine bot ID. Skipping update.")
@@ -375,46 +689,58 @@ func messageUpdateLogic(s DiscordSessionInterface, m *discordgo.MessageUpdate) {
 	if globalConfig != nil {
 		// Determine if a notification was likely sent by checking bot's reactions
 		// against configured rule action emojis.
-		previouslyNotifiedRulePriority := math.MaxInt32 // Higher value means lower Pushover priority
-
-		if len(fullMessage.Reactions) > 0 && len(globalConfig.Rules) > 0 {
-			for _, reaction := range fullMessage.Reactions {
-				if reaction.Me { // Bot added this reaction
-					for _, rule := range globalConfig.Rules {
-						if rule.Actions.ReactionEmoji == reaction.Emoji.Name {
-							// This reaction corresponds to a rule's action emoji.
-							// Store the highest priority (lowest numerical value for Pushover).
-							if rule.Actions.Priority < previouslyNotifiedRulePriority {
-								previouslyNotifiedRulePriority = rule.Actions.Priority
-							}
-							// Log this finding for debugging
-							log.Debugf("messageUpdateLogic: Bot reaction '%s' matches rule '%s' (Priority: %d). Current highest notified priority: %d",
-								reaction.Emoji.Name, rule.Name, rule.Actions.Priority, previouslyNotifiedRulePriority)
-						}
-					}
-				}
-			}
-		}
+		previouslyNotifiedRulePriority := notifiedPriorityFromReactions(globalConfig, fullMessage)
 		if previouslyNotifiedRulePriority == math.MaxInt32 {
 			log.Debugf("messageUpdateLogic: No prior bot reactions found matching rule actions.")
 		} else {
 			log.Debugf("messageUpdateLogic: Determined highest previously notified rule priority (from bot reactions) as: %d", previouslyNotifiedRulePriority)
 		}
 
-		ProcessRules(fullMessage, globalConfig, s, previouslyNotifiedRulePriority) // Pass fullMessage directly
+		ProcessRules(ctx, fullMessage, globalConfig, s, previouslyNotifiedRulePriority) // Pass fullMessage directly
 	} else {
 		log.Error("globalConfig is nil in messageUpdate. Rules cannot be processed.")
 	}
 }
 
+// notifiedPriorityFromReactions returns the highest priority (lowest number) of the rules whose
+// reaction emoji the bot has already added to the message, or math.MaxInt32 if there are none.
+func notifiedPriorityFromReactions(config *Config, message *discordgo.Message) int {
+	previouslyNotifiedRulePriority := math.MaxInt32 // Higher value means lower Pushover priority
+
+	if len(message.Reactions) > 0 && len(config.Rules) > 0 {
+		for _, reaction := range message.Reactions {
+			if reaction.Me { // Bot added this reaction
+				for _, rule := range config.Rules {
+					if ruleReactsWith(&rule, reaction.Emoji) {
+						// This reaction corresponds to a rule's action emoji.
+						// Store the highest priority (lowest numerical value for Pushover).
+						// The severityMap may have changed the priority the rule sent with.
+						notifiedPriority := effectiveActions(&rule, message, rule.Name).Priority
+						if notifiedPriority < previouslyNotifiedRulePriority {
+							previouslyNotifiedRulePriority = notifiedPriority
+						}
+						// Log this finding for debugging
+						log.Debugf("notifiedPriorityFromReactions: Bot reaction '%s' matches rule '%s' (Priority: %d). Current highest notified priority: %d",
+							reaction.Emoji.Name, rule.Name, notifiedPriority, previouslyNotifiedRulePriority)
+					}
+				}
+			}
+		}
+	}
+	return previouslyNotifiedRulePriority
+}
+
 // dgMessageReactionAdd is the raw handler for discordgo's MessageReactionAdd events
 func dgMessageReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	defer recoverPanic("dgMessageReactionAdd")
 	wrapper := &DiscordGoSessionWrapper{RealSession: s}
-	messageReactionAddLogic(wrapper, r)
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	messageReactionAddLogic(ctx, wrapper, r)
 }
 
 // messageReactionAddLogic contains the testable logic for handling reaction additions.
-func messageReactionAddLogic(s DiscordSessionInterface, r *discordgo.MessageReactionAdd) {
+func messageReactionAddLogic(ctx context.Context, s DiscordSessionInterface, r *discordgo.MessageReactionAdd) {
 	log.Infof("Received MessageReactionAdd event: UserID: %s, MessageID: %s, Emoji: %s (ID: %s)",
 		r.UserID, r.MessageID, r.Emoji.Name, r.Emoji.ID)
 
@@ -445,20 +771,8 @@ func messageReactionAddLogic(s DiscordSessionInterface, r *discordgo.MessageReac
 
 	// Determine previouslyNotifiedRulePriority based on existing bot reactions on the message
 	previouslyNotifiedRulePriority := math.MaxInt32
-	if globalConfig != nil && len(fullMessage.Reactions) > 0 && len(globalConfig.Rules) > 0 {
-		for _, reaction := range fullMessage.Reactions {
-			if reaction.Me { // Bot added this reaction
-				for _, rule := range globalConfig.Rules {
-					if rule.Actions.ReactionEmoji == reaction.Emoji.Name {
-						if rule.Actions.Priority < previouslyNotifiedRulePriority {
-							previouslyNotifiedRulePriority = rule.Actions.Priority
-						}
-						log.Debugf("messageReactionAddLogic: Bot reaction '%s' matches rule '%s' (Priority: %d). Current highest notified: %d",
-							reaction.Emoji.Name, rule.Name, rule.Actions.Priority, previouslyNotifiedRulePriority)
-					}
-				}
-			}
-		}
+	if globalConfig != nil {
+		previouslyNotifiedRulePriority = notifiedPriorityFromReactions(globalConfig, fullMessage)
 	}
 	if previouslyNotifiedRulePriority == math.MaxInt32 {
 		log.Debugf("messageReactionAddLogic: No prior bot reactions found matching rule actions for msg %s.", r.MessageID)
@@ -468,7 +782,7 @@ func messageReactionAddLogic(s DiscordSessionInterface, r *discordgo.MessageReac
 
 	// Process rules against the message state
 	if globalConfig != nil {
-		ProcessRules(fullMessage, globalConfig, s, previouslyNotifiedRulePriority)
+		ProcessRules(ctx, fullMessage, globalConfig, s, previouslyNotifiedRulePriority)
 	} else {
 		log.Error("globalConfig is nil in messageReactionAddLogic. Rules cannot be processed.")
 	}
@@ -1,7 +1,8 @@
-package main
+package discord2pushover
 
 import (
 	"bytes"
+	"context"
 	// "flag" // No longer used directly in these tests for log level
 	"fmt"
 	"math" // For math.MaxInt32 in new tests
@@ -154,8 +155,9 @@ func TestLogLevelFromConfig(t *testing.T) {
 // --- MockDiscordSession and helpers (existing) ---
 type MockDiscordSession struct {
 	*discordgo.Session
-	CustomChannelMessageFunc func(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error)
-	TestStateOverride        *discordgo.State
+	CustomChannelMessageFunc  func(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error)
+	CustomChannelMessagesFunc func(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error)
+	TestStateOverride         *discordgo.State
 }
 
 func (m *MockDiscordSession) ChannelMessage(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
@@ -185,6 +187,33 @@ func (m *MockDiscordSession) MessageReactionAdd(channelID, messageID, emojiID st
 	return nil
 }
 
+func (m *MockDiscordSession) MessageReactionRemove(channelID, messageID, emojiID, userID string, opts ...discordgo.RequestOption) error {
+	log.Debugf("MockDiscordSession: MessageReactionRemove called with: chID=%s, msgID=%s, emoji=%s, user=%s", channelID, messageID, emojiID, userID)
+	return nil
+}
+
+func (m *MockDiscordSession) ChannelMessageSend(channelID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	log.Debugf("MockDiscordSession: ChannelMessageSend called with: chID=%s, content=%s", channelID, content)
+	return &discordgo.Message{ChannelID: channelID, Content: content}, nil
+}
+
+func (m *MockDiscordSession) ChannelMessageSendReply(channelID, content string, reference *discordgo.MessageReference, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	log.Debugf("MockDiscordSession: ChannelMessageSendReply called with: chID=%s, replyTo=%s, content=%s", channelID, reference.MessageID, content)
+	return &discordgo.Message{ChannelID: channelID, Content: content}, nil
+}
+
+func (m *MockDiscordSession) ChannelMessageEdit(channelID, messageID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	log.Debugf("MockDiscordSession: ChannelMessageEdit called with: chID=%s, msgID=%s, content=%s", channelID, messageID, content)
+	return &discordgo.Message{ID: messageID, ChannelID: channelID, Content: content}, nil
+}
+
+func (m *MockDiscordSession) ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string, opts ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	if m.CustomChannelMessagesFunc != nil {
+		return m.CustomChannelMessagesFunc(channelID, limit, beforeID, afterID, aroundID)
+	}
+	return nil, fmt.Errorf("ChannelMessagesFunc not implemented")
+}
+
 var (
 	originalGlobalConfigForTest *Config
 	testLogBufferForTest        *bytes.Buffer
@@ -224,7 +253,7 @@ func TestMessageUpdateHandler(t *testing.T) {
 				Author:    &discordgo.User{ID: mockSess.State().User.ID},
 			},
 		}
-		messageUpdateLogic(mockSess, update)
+		messageUpdateLogic(context.Background(), mockSess, update)
 		output := testLogBufferForTest.String()
 		expectedLog := "Ignoring message update: original message author is bot (m.Author.ID)"
 		if !strings.Contains(output, expectedLog) {
@@ -250,7 +279,7 @@ func TestMessageUpdateHandler(t *testing.T) {
 				Author:    &discordgo.User{ID: "userTestID"},
 			},
 		}
-		messageUpdateLogic(mockSess, updateEvent)
+		messageUpdateLogic(context.Background(), mockSess, updateEvent)
 		output := testLogBufferForTest.String()
 		expectedLog := "Ignoring message update: full message author is bot (fullMessage.Author.ID)"
 		if !strings.Contains(output, expectedLog) {
@@ -268,7 +297,7 @@ func TestMessageUpdateHandler(t *testing.T) {
 	ruleMatchingReaction_Update := func(emojiName string, priority int) Rule { // Changed from ruleMatchingReaction
 		return Rule{
 			Name: fmt.Sprintf("RuleFor%s_Update", emojiName),
-			Actions: RuleActions{ReactionEmoji: emojiName, Priority: priority, PushoverDestination: "testdest"},
+			Actions: RuleActions{ReactionEmoji: EmojiList{emojiName}, Priority: priority, PushoverDestination: "testdest"},
 			Conditions: RuleConditions{ChannelID: "chPrioUpdate"},
 		}
 	}
@@ -306,7 +335,7 @@ func TestMessageUpdateHandler(t *testing.T) {
 			}
 			updateEvent := &discordgo.MessageUpdate{Message: &currentMsg}
 			globalConfig = &Config{Rules: tt.rules}
-			messageUpdateLogic(mockSess, updateEvent)
+			messageUpdateLogic(context.Background(), mockSess, updateEvent)
 			logOutput := testLogBufferForTest.String()
 			processRulesLogStart := fmt.Sprintf("Processing rules for message ID %s", currentMsg.ID)
 			if !strings.Contains(logOutput, processRulesLogStart) {
@@ -333,7 +362,7 @@ func TestMessageUpdateHandler(t *testing.T) {
 			Message: &discordgo.Message{ID: "msg3", ChannelID: "ch1", Author: &discordgo.User{ID: "userTestID"}},
 		}
 		globalConfig = &Config{}
-		messageUpdateLogic(mockSess, updateEvent)
+		messageUpdateLogic(context.Background(), mockSess, updateEvent)
 		logOutput := testLogBufferForTest.String()
 		expectedProcessRulesLog := fmt.Sprintf("Processing rules for message ID %s", fetchedMessage.ID)
 		if !strings.Contains(logOutput, fmt.Sprintf("Received message update: ID=%s", fetchedMessage.ID)) {
@@ -359,7 +388,7 @@ func TestMessageUpdateHandler(t *testing.T) {
 		updateEvent := &discordgo.MessageUpdate{
 			Message: &discordgo.Message{ID: "msg4", ChannelID: "ch1", Author: &discordgo.User{ID: "userTestID"}},
 		}
-		messageUpdateLogic(mockSess, updateEvent)
+		messageUpdateLogic(context.Background(), mockSess, updateEvent)
 		output := testLogBufferForTest.String()
 		if !strings.Contains(output, "Error fetching full message for update") {
 			t.Errorf("Expected log message about fetch error, got: %s", output)
@@ -390,7 +419,7 @@ func TestMessageReactionAddHandler(t *testing.T) {
 	ruleForReactionTest := func(emojiName string, priority int) Rule {
 		return Rule{
 			Name: fmt.Sprintf("RuleForReact%s", emojiName),
-			Actions: RuleActions{ReactionEmoji: emojiName, Priority: priority, PushoverDestination: "testdest"},
+			Actions: RuleActions{ReactionEmoji: EmojiList{emojiName}, Priority: priority, PushoverDestination: "testdest"},
 			Conditions: RuleConditions{ChannelID: "chReact"}, // Simple condition
 		}
 	}
@@ -409,7 +438,7 @@ func TestMessageReactionAddHandler(t *testing.T) {
 			},
 		}
 
-		messageReactionAddLogic(mockSess, botReaction)
+		messageReactionAddLogic(context.Background(), mockSess, botReaction)
 		output := testLogBufferForTest.String()
 		if !strings.Contains(output, "Ignoring reaction added by the bot itself") {
 			t.Errorf("Expected log indicating bot's own reaction ignored, got: %s", output)
@@ -422,7 +451,7 @@ func TestMessageReactionAddHandler(t *testing.T) {
 		mockSess.CustomChannelMessageFunc = func(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
 			return nil, fmt.Errorf("simulated fetch error for reaction")
 		}
-		messageReactionAddLogic(mockSess, baseReaction)
+		messageReactionAddLogic(context.Background(), mockSess, baseReaction)
 		output := testLogBufferForTest.String()
 		if !strings.Contains(output, "Error fetching full message for reaction add") {
 			t.Errorf("Expected log for fetch error, got: %s", output)
@@ -474,7 +503,7 @@ func TestMessageReactionAddHandler(t *testing.T) {
 			// The previouslyNotifiedRulePriority is based on what's *already on the message*.
 			globalConfig = &Config{Rules: tt.rules}
 
-			messageReactionAddLogic(mockSess, baseReaction) // baseReaction has 👍 by a user
+			messageReactionAddLogic(context.Background(), mockSess, baseReaction) // baseReaction has 👍 by a user
 			logOutput := testLogBufferForTest.String()
 
 			processRulesLogStart := fmt.Sprintf("Processing rules for message ID %s", currentMsg.ID)
@@ -487,3 +516,38 @@ func TestMessageReactionAddHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestMarkAcknowledged(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+
+	tracked := TrackedEmergencyMessage{
+		DiscordMessageID: "m1",
+		DiscordChannelID: "ops",
+		AckEmoji:         "✅",
+		PendingEmoji:     "⏳",
+		ReactionEmojis:   []string{"📟"},
+	}
+	markAcknowledged(session, tracked)
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, "MessageReactionAdd called with: chID=ops, msgID=m1, emoji=✅") ||
+		!strings.Contains(logs, "MessageReactionRemove called with: chID=ops, msgID=m1, emoji=⏳, user=@me") {
+		t.Errorf("Expected ✅ to replace ⏳. Logs:\n%s", logs)
+	}
+	if strings.Contains(logs, "emoji=📟") {
+		t.Errorf("Expected the reaction emoji to stay without removeReactionOnAck. Logs:\n%s", logs)
+	}
+
+	testLogBufferForTest.Reset()
+	tracked.RemoveReactionOnAck = true
+	markAcknowledged(session, tracked)
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "MessageReactionRemove called with: chID=ops, msgID=m1, emoji=📟, user=@me") {
+		t.Errorf("Expected the reaction emoji to be removed. Logs:\n%s", logs)
+	}
+
+	rule := &Rule{Actions: RuleActions{ReactionEmoji: EmojiList{"📟"}, Emergency: &EmergencyParams{AckEmoji: "✅", RemoveReactionOnAck: true}}}
+	if !ruleReactsWith(rule, &discordgo.Emoji{Name: "✅"}) {
+		t.Error("Expected the ack emoji to mark the message as handled with removeReactionOnAck")
+	}
+}
@@ -0,0 +1,107 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// matrixTxnCounter makes Matrix transaction IDs unique within the process.
+var matrixTxnCounter atomic.Uint64
+
+type matrixNotifier struct {
+	config     *MatrixNotifier
+	httpClient *http.Client
+}
+
+// matrixMessage is the m.room.message event content sent to the room.
+type matrixMessage struct {
+	MsgType       string          `json:"msgtype"`
+	Body          string          `json:"body"`
+	Format        string          `json:"format,omitempty"`
+	FormattedBody string          `json:"formatted_body,omitempty"`
+	Mentions      *matrixMentions `json:"m.mentions,omitempty"`
+}
+
+type matrixMentions struct {
+	UserIDs []string `json:"user_ids,omitempty"`
+	Room    bool     `json:"room,omitempty"`
+}
+
+// matrixContent maps a notification to a room message. Low priorities are sent as notices, which
+// clients don't highlight; priority 1 mentions the configured users and 2 also pings the whole room.
+func matrixContent(n *Notification, mentionUserIDs []string) matrixMessage {
+	plain := n.Body
+	formatted := strings.ReplaceAll(html.EscapeString(n.Body), "\n", "<br>")
+	if n.Title != "" {
+		plain = n.Title + "\n" + plain
+		formatted = "<strong>" + html.EscapeString(n.Title) + "</strong><br>" + formatted
+	}
+	if n.Link != "" {
+		plain += "\n" + n.Link
+		formatted += fmt.Sprintf(`<br><a href="%s">Open in Discord</a>`, html.EscapeString(n.Link))
+	}
+
+	msg := matrixMessage{MsgType: "m.text", Format: "org.matrix.custom.html"}
+	switch {
+	case n.Priority < 0:
+		msg.MsgType = "m.notice"
+	case n.Priority >= 1:
+		var names []string
+		for _, id := range mentionUserIDs {
+			names = append(names, fmt.Sprintf(`<a href="https://matrix.to/#/%s">%s</a>`, url.PathEscape(id), html.EscapeString(id)))
+		}
+		msg.Mentions = &matrixMentions{UserIDs: mentionUserIDs, Room: n.Priority >= 2}
+		prefix, htmlPrefix := "", ""
+		if n.Priority >= 2 {
+			prefix, htmlPrefix = "🚨 @room ", "🚨 @room "
+		}
+		if len(mentionUserIDs) > 0 {
+			prefix += strings.Join(mentionUserIDs, " ") + " "
+			htmlPrefix += strings.Join(names, " ") + " "
+		}
+		plain = prefix + plain
+		formatted = htmlPrefix + formatted
+	}
+	msg.Body = plain
+	msg.FormattedBody = formatted
+	return msg
+}
+
+// Notify sends the notification to the room via the client-server API.
+func (m *matrixNotifier) Notify(ctx context.Context, n *Notification) error {
+	if m.config.Homeserver == "" || m.config.RoomID == "" || m.config.AccessToken == "" {
+		return fmt.Errorf("matrix notifier requires homeserver, roomId and accessToken")
+	}
+	payload, err := json.Marshal(matrixContent(n, m.config.MentionUserIDs))
+	if err != nil {
+		return err
+	}
+	txnID := fmt.Sprintf("d2p-%d-%d", time.Now().UnixNano(), matrixTxnCounter.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(m.config.Homeserver, "/"), url.PathEscape(m.config.RoomID), txnID)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+m.config.AccessToken)
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := m.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send Matrix message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("matrix API error: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
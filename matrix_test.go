@@ -0,0 +1,78 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestMatrixContent(t *testing.T) {
+	n := &Notification{Title: "Disk <full>", Body: "db1\n97%", Link: "https://discord.com/channels/1/2/3"}
+	mentions := []string{"@alice:example.org"}
+
+	n.Priority = -1
+	if msg := matrixContent(n, mentions); msg.MsgType != "m.notice" || msg.Mentions != nil {
+		t.Errorf("Expected an unmentioned notice for low priority, got %+v", msg)
+	}
+
+	n.Priority = 0
+	msg := matrixContent(n, mentions)
+	if msg.MsgType != "m.text" || msg.Mentions != nil || msg.Body != "Disk <full>\ndb1\n97%\nhttps://discord.com/channels/1/2/3" {
+		t.Errorf("Unexpected normal message %+v", msg)
+	}
+	if !strings.HasPrefix(msg.FormattedBody, "<strong>Disk &lt;full&gt;</strong><br>db1<br>97%") {
+		t.Errorf("Unexpected formatted body %q", msg.FormattedBody)
+	}
+
+	n.Priority = 1
+	if msg := matrixContent(n, mentions); msg.Mentions == nil || msg.Mentions.Room || !strings.HasPrefix(msg.Body, "@alice:example.org ") {
+		t.Errorf("Expected the users to be mentioned for high priority, got %+v", msg)
+	}
+	n.Priority = 2
+	if msg := matrixContent(n, mentions); msg.Mentions == nil || !msg.Mentions.Room || !strings.HasPrefix(msg.Body, "🚨 @room @alice:example.org ") {
+		t.Errorf("Expected a room ping for emergencies, got %+v", msg)
+	}
+}
+
+func TestProcessRules_MatrixNotifier(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	var path, auth string
+	var received matrixMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.EscapedPath(), r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Notifiers: map[string]NotifierConfig{
+			"ops-matrix": {Matrix: &MatrixNotifier{Homeserver: server.URL + "/", RoomID: "!room:example.org", AccessToken: "tok"}},
+		},
+		Rules: []Rule{{
+			Name:       "Matrix",
+			Conditions: RuleConditions{ChannelID: "ops"},
+			Actions:    RuleActions{Notify: []string{"ops-matrix", "missing"}},
+		}},
+	}
+	message := &discordgo.Message{ID: "m1", ChannelID: "ops", GuildID: "g1", Content: "disk full", Author: &discordgo.User{ID: "u1"}}
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+
+	if !strings.HasPrefix(path, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/d2p-") || auth != "Bearer tok" {
+		t.Errorf("Unexpected request to %s with %q", path, auth)
+	}
+	if received.Body != "Discord Notification\ndisk full\nhttps://discord.com/channels/g1/ops/m1" {
+		t.Errorf("Unexpected message body %q", received.Body)
+	}
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "unknown notifier 'missing'") {
+		t.Errorf("Expected an error for the unknown notifier. Logs:\n%s", logs)
+	}
+}
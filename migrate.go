@@ -0,0 +1,203 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configMigration upgrades one aspect of an older config schema in place. It is given a mapping
+// node holding a value of struct type t and returns a description of every change it made.
+type configMigration func(path string, node *yaml.Node, t reflect.Type) []string
+
+// configMigrations are applied to every mapping of the config, in order.
+var configMigrations = []configMigration{migrateKeyNames, migrateEmojiLists}
+
+var emojiListType = reflect.TypeOf(EmojiList{})
+
+// yamlFieldTypes maps the YAML keys of a struct type to their field types.
+func yamlFieldTypes(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// canonicalKey reduces a key to compare it regardless of case, "_" and "-", e.g. "Reaction_Emoji".
+func canonicalKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+// migrateKeyNames renames keys written in another case or style (e.g. "ReactionEmoji" or
+// "reaction_emoji"), which the config parser silently ignores.
+func migrateKeyNames(path string, node *yaml.Node, t reflect.Type) []string {
+	fields := yamlFieldTypes(t)
+	byCanonical := make(map[string]string, len(fields))
+	for name := range fields {
+		byCanonical[canonicalKey(name)] = name
+	}
+	var changes []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if _, ok := fields[key.Value]; ok {
+			continue
+		}
+		if name, ok := byCanonical[canonicalKey(key.Value)]; ok {
+			changes = append(changes, fmt.Sprintf("%s%s: renamed to %s", path, key.Value, name))
+			key.Value = name
+		}
+	}
+	return changes
+}
+
+// migrateEmojiLists turns emoji lists written as a single string into lists.
+func migrateEmojiLists(path string, node *yaml.Node, t reflect.Type) []string {
+	fields := yamlFieldTypes(t)
+	var changes []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if fields[key.Value] != emojiListType || value.Kind != yaml.ScalarNode || value.Tag == "!!null" {
+			continue
+		}
+		item := *value
+		item.HeadComment, item.LineComment, item.FootComment = "", "", ""
+		*value = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle, Content: []*yaml.Node{&item},
+			LineComment: value.LineComment}
+		if item.Value == "" {
+			value.Content = nil
+		}
+		changes = append(changes, fmt.Sprintf("%s%s: converted to a list", path, key.Value))
+	}
+	return changes
+}
+
+// migrateNode applies the migrations to node, which holds a value of type t, and to its children.
+// Keys that still don't belong to the type are reported as unknown.
+func migrateNode(path string, node *yaml.Node, t reflect.Type) (changes []string, unknown []string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		return migrateNode(path, node.Content[0], t)
+	}
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		for _, migration := range configMigrations {
+			changes = append(changes, migration(path, node, t)...)
+		}
+		fields := yamlFieldTypes(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			fieldType, ok := fields[key]
+			if !ok {
+				unknown = append(unknown, path+key)
+				continue
+			}
+			c, u := migrateNode(path+key+".", node.Content[i+1], fieldType)
+			changes, unknown = append(changes, c...), append(unknown, u...)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			c, u := migrateNode(path+node.Content[i].Value+".", node.Content[i+1], t.Elem())
+			changes, unknown = append(changes, c...), append(unknown, u...)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			c, u := migrateNode(fmt.Sprintf("%s[%d].", strings.TrimSuffix(path, "."), i), item, t.Elem())
+			changes, unknown = append(changes, c...), append(unknown, u...)
+		}
+	}
+	return changes, unknown
+}
+
+// migrateConfig upgrades a config file's YAML to the current schema, keeping comments, and returns
+// the result with the changes made and the keys it doesn't know.
+func migrateConfig(data []byte) ([]byte, []string, []string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	changes, unknown := migrateNode("", &root, reflect.TypeOf(Config{}))
+	if len(changes) == 0 {
+		return data, nil, unknown, nil
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to write config: %w", err)
+	}
+	encoder.Close()
+	return unescapeAstral(out.Bytes()), changes, unknown, nil
+}
+
+var astralEscape = regexp.MustCompile(`\\(\\|U[0-9A-F]{8})`)
+
+// unescapeAstral undoes the encoder's escaping of characters outside the Basic Multilingual Plane,
+// which includes most emoji, in double-quoted strings: "\U0001F4DF" becomes "📟" again.
+func unescapeAstral(data []byte) []byte {
+	return astralEscape.ReplaceAllFunc(data, func(escape []byte) []byte {
+		if escape[1] == '\\' {
+			return escape
+		}
+		r, err := strconv.ParseUint(string(escape[2:]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return escape
+		}
+		return utf8.AppendRune(nil, rune(r))
+	})
+}
+
+// runMigrateConfig migrates the config file in place, keeping the original as <path>.bak, and
+// returns the process exit code.
+func runMigrateConfig(path string, w io.Writer) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Errorf("Error reading config: %v", err)
+		return 1
+	}
+	migrated, changes, unknown, err := migrateConfig(data)
+	if err != nil {
+		log.Errorf("Error migrating %s: %v", path, err)
+		return 1
+	}
+	for _, key := range unknown {
+		fmt.Fprintf(w, "Unknown key, left as is: %s\n", key)
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "%s is up to date.\n", path)
+		return 0
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Errorf("Error reading config: %v", err)
+		return 1
+	}
+	if err := os.WriteFile(path+".bak", data, info.Mode().Perm()); err != nil {
+		log.Errorf("Error backing up config: %v", err)
+		return 1
+	}
+	if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+		log.Errorf("Error writing migrated config: %v", err)
+		return 1
+	}
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s\n", change)
+	}
+	fmt.Fprintf(w, "Migrated %s (%d changes); the original is saved as %s.bak.\n", path, len(changes), path)
+	return 0
+}
@@ -0,0 +1,84 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const legacyConfig = `# Bot settings
+DiscordToken: "$DISCORD_TOKEN"
+pushoverAppKey: "app"
+rules:
+  - name: Alerts # Grafana alerts
+    conditions:
+      channel_id: "123"
+    actions:
+      Priority: 1
+      reactionEmoji: "📟" # Marks notified messages
+      PushoverDestination: "user"
+    typo: true
+`
+
+func TestMigrateConfig(t *testing.T) {
+	migrated, changes, unknown, err := migrateConfig([]byte(legacyConfig))
+	if err != nil {
+		t.Fatalf("migrateConfig failed: %v", err)
+	}
+	wantChanges := []string{
+		"DiscordToken: renamed to discordToken",
+		"rules[0].conditions.channel_id: renamed to channelId",
+		"rules[0].actions.Priority: renamed to priority",
+		"rules[0].actions.PushoverDestination: renamed to pushoverDestination",
+		"rules[0].actions.reactionEmoji: converted to a list",
+	}
+	if strings.Join(changes, "\n") != strings.Join(wantChanges, "\n") {
+		t.Errorf("Unexpected changes:\n%s", strings.Join(changes, "\n"))
+	}
+	if strings.Join(unknown, ",") != "rules[0].typo" {
+		t.Errorf("Unexpected unknown keys %v", unknown)
+	}
+	for _, want := range []string{"# Bot settings", `discordToken: "$DISCORD_TOKEN"`, "name: Alerts # Grafana alerts", `reactionEmoji: ["📟"] # Marks notified messages`, "channelId: \"123\""} {
+		if !strings.Contains(string(migrated), want) {
+			t.Errorf("Expected %q in the migrated config:\n%s", want, migrated)
+		}
+	}
+
+	// Without the unknown key the migrated config loads as intended and needs no further changes
+	path := filepath.Join(t.TempDir(), "discord2pushover.yaml")
+	os.WriteFile(path, []byte(strings.Replace(string(migrated), "    typo: true\n", "", 1)), 0o600) // Unknown keys are rejected
+	t.Setenv("DISCORD_TOKEN", "token")
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	rule := config.Rules[0]
+	if config.DiscordToken != "token" || rule.Conditions.ChannelID != "123" || rule.Actions.Priority != 1 || len(rule.Actions.ReactionEmoji) != 1 {
+		t.Errorf("Unexpected migrated config %+v", config)
+	}
+	if _, changes, _, _ := migrateConfig(migrated); len(changes) != 0 {
+		t.Errorf("Expected no changes on a second run, got %v", changes)
+	}
+}
+
+func TestRunMigrateConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discord2pushover.yaml")
+	os.WriteFile(path, []byte(legacyConfig), 0o600)
+	var out bytes.Buffer
+	if code := runMigrateConfig(path, &out); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if backup, _ := os.ReadFile(path + ".bak"); string(backup) != legacyConfig {
+		t.Error("Expected the original to be kept as .bak")
+	}
+	if !strings.Contains(out.String(), "Migrated "+path+" (5 changes)") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+	out.Reset()
+	runMigrateConfig(path, &out)
+	if !strings.Contains(out.String(), "is up to date") {
+		t.Errorf("Expected the migrated config to be up to date:\n%s", out.String())
+	}
+}
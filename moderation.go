@@ -0,0 +1,152 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// moderationSession is the subset of session calls needed to handle moderation events.
+type moderationSession interface {
+	DiscordSessionInterface
+	AutoModerationRule(guildID, ruleID string, opts ...discordgo.RequestOption) (*discordgo.AutoModerationRule, error)
+}
+
+// AutoModerationRule calls the RealSession's AutoModerationRule.
+func (w *DiscordGoSessionWrapper) AutoModerationRule(guildID, ruleID string, opts ...discordgo.RequestOption) (*discordgo.AutoModerationRule, error) {
+	return w.RealSession.AutoModerationRule(guildID, ruleID, opts...)
+}
+
+var _ moderationSession = &DiscordGoSessionWrapper{}
+
+// automodRuleNames caches AutoMod rule names by rule ID, since execution events only carry the ID.
+var automodRuleNames sync.Map
+
+// automodActionTypes maps AutoMod action types to the names used in the actionTypes condition.
+var automodActionTypes = map[discordgo.AutoModerationActionType]string{
+	discordgo.AutoModerationRuleActionBlockMessage:     "block_message",
+	discordgo.AutoModerationRuleActionSendAlertMessage: "send_alert_message",
+	discordgo.AutoModerationRuleActionTimeout:          "timeout",
+}
+
+// auditLogActionTypes maps audit log actions to the names used in the actionTypes condition.
+var auditLogActionTypes = map[discordgo.AuditLogAction]string{
+	discordgo.AuditLogActionMemberKick:       "kick",
+	discordgo.AuditLogActionMemberPrune:      "prune",
+	discordgo.AuditLogActionMemberBanAdd:     "ban",
+	discordgo.AuditLogActionMemberBanRemove:  "unban",
+	discordgo.AuditLogActionMemberUpdate:     "member_update",
+	discordgo.AuditLogActionMemberRoleUpdate: "member_role_update",
+}
+
+// dgAutoModerationActionExecution is the raw handler for discordgo's AutoModerationActionExecution events.
+func dgAutoModerationActionExecution(s *discordgo.Session, e *discordgo.AutoModerationActionExecution) {
+	defer recoverPanic("dgAutoModerationActionExecution")
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	autoModerationActionLogic(ctx, &DiscordGoSessionWrapper{RealSession: s}, e)
+}
+
+// autoModerationActionLogic runs onAutomod rules for an AutoMod action execution.
+func autoModerationActionLogic(ctx context.Context, s moderationSession, e *discordgo.AutoModerationActionExecution) {
+	if globalConfig == nil {
+		log.Error("globalConfig is nil in autoModerationActionLogic. Rules cannot be processed.")
+		return
+	}
+	details := &EventDetails{
+		ModerationRuleName: automodRuleName(s, e.GuildID, e.RuleID),
+		ActionType:         automodActionTypes[e.Action.Type],
+	}
+	if details.ActionType == "" {
+		details.ActionType = fmt.Sprintf("action_%d", e.Action.Type)
+	}
+	log.Infof("Received AutoModerationActionExecution event: Guild: %s, Rule: '%s', Action: %s, User: %s",
+		e.GuildID, details.ModerationRuleName, details.ActionType, e.UserID)
+
+	// Blocked messages are never created; point at the alert message instead when there is one.
+	message := &discordgo.Message{
+		ID:        e.MessageID,
+		ChannelID: e.ChannelID,
+		GuildID:   e.GuildID,
+		Author:    &discordgo.User{ID: e.UserID},
+		Content: fmt.Sprintf("🛡️ AutoMod rule '%s' (%s) triggered by <@%s>: %s",
+			details.ModerationRuleName, details.ActionType, e.UserID, e.Content),
+	}
+	if message.ID == "" && e.AlertSystemMessageID != "" && e.Action.Metadata != nil {
+		message.ID = e.AlertSystemMessageID
+		message.ChannelID = e.Action.Metadata.ChannelID
+	}
+	processRulesForEvent(ctx, ruleEventAutomod, message, details, globalConfig, s, math.MaxInt32)
+}
+
+// automodRuleName resolves an AutoMod rule ID to its name, caching the result. Falls back to the ID.
+func automodRuleName(s moderationSession, guildID, ruleID string) string {
+	if name, ok := automodRuleNames.Load(ruleID); ok {
+		return name.(string)
+	}
+	rule, err := s.AutoModerationRule(guildID, ruleID)
+	if err != nil || rule == nil {
+		log.Warnf("Cannot resolve AutoMod rule %s in guild %s (bot needs Manage Server): %v", ruleID, guildID, err)
+		return ruleID
+	}
+	automodRuleNames.Store(ruleID, rule.Name)
+	return rule.Name
+}
+
+// dgGuildAuditLogEntryCreate is the raw handler for discordgo's GuildAuditLogEntryCreate events.
+func dgGuildAuditLogEntryCreate(s *discordgo.Session, e *discordgo.GuildAuditLogEntryCreate) {
+	defer recoverPanic("dgGuildAuditLogEntryCreate")
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	auditLogEntryLogic(ctx, &DiscordGoSessionWrapper{RealSession: s}, e)
+}
+
+// auditLogEntryLogic runs onAuditLog rules for a new audit log entry.
+func auditLogEntryLogic(ctx context.Context, s DiscordSessionInterface, e *discordgo.GuildAuditLogEntryCreate) {
+	if globalConfig == nil {
+		log.Error("globalConfig is nil in auditLogEntryLogic. Rules cannot be processed.")
+		return
+	}
+	if e.AuditLogEntry == nil || e.ActionType == nil {
+		return
+	}
+	actionType, ok := auditLogActionTypes[*e.ActionType]
+	if !ok {
+		actionType = fmt.Sprintf("action_%d", *e.ActionType)
+	}
+	log.Infof("Received GuildAuditLogEntryCreate event: Guild: %s, Action: %s, Target: %s, By: %s", e.GuildID, actionType, e.TargetID, e.UserID)
+
+	content := fmt.Sprintf("🔨 Audit log: <@%s> performed %s on %s", e.UserID, actionType, e.TargetID)
+	if e.Reason != "" {
+		content += fmt.Sprintf(" (reason: %s)", e.Reason)
+	}
+	message := &discordgo.Message{
+		GuildID: e.GuildID,
+		Author:  &discordgo.User{ID: e.UserID},
+		Content: content,
+	}
+	processRulesForEvent(ctx, ruleEventAuditLog, message, &EventDetails{ActionType: actionType}, globalConfig, s, math.MaxInt32)
+}
+
+// gatewayIntents returns the gateway intents the bot needs. Moderation intents are only requested
+// when rules use the corresponding events, since they require extra permissions.
+func gatewayIntents(config *Config) discordgo.Intent {
+	// We need intents for messages and message reactions to get message update events with reaction data.
+	// Also add DirectMessageReactions for DM support, and Guilds for channel pin and thread events.
+	intents := discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsGuildMessageReactions | discordgo.IntentsDirectMessageReactions
+	if config.Subscriptions != nil && config.Subscriptions.SelfRegistration {
+		intents |= discordgo.IntentsDirectMessages // Registration commands are sent as DMs
+	}
+	for i := range config.Rules {
+		switch ruleEvent(&config.Rules[i]) {
+		case ruleEventAutomod:
+			intents |= discordgo.IntentAutoModerationExecution
+		case ruleEventAuditLog:
+			intents |= discordgo.IntentGuildModeration
+		}
+	}
+	return intents
+}
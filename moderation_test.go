@@ -0,0 +1,96 @@
+package discord2pushover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type mockModerationSession struct {
+	*MockDiscordSession
+	ruleNames map[string]string
+}
+
+func (m *mockModerationSession) AutoModerationRule(guildID, ruleID string, opts ...discordgo.RequestOption) (*discordgo.AutoModerationRule, error) {
+	return &discordgo.AutoModerationRule{ID: ruleID, Name: m.ruleNames[ruleID]}, nil
+}
+
+func TestModerationEventRules(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	originalTestHookDisablePushoverSend := testHookDisablePushoverSend
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = originalTestHookDisablePushoverSend }()
+
+	globalConfig = &Config{Rules: []Rule{
+		{Name: "SlurBlocked", Event: ruleEventAutomod,
+			Conditions: RuleConditions{AutomodRuleNames: []string{"Slur filter"}, ActionTypes: []string{"block_message"}},
+			Actions:    RuleActions{PushoverDestination: "mods", ReactionEmoji: EmojiList{"👀"}}},
+		{Name: "Bans", Event: ruleEventAuditLog,
+			Conditions: RuleConditions{ActionTypes: []string{"ban", "kick"}},
+			Actions:    RuleActions{PushoverDestination: "mods", ReactionEmoji: EmojiList{"👀"}}},
+		{Name: "MessageRuleWithActionTypes",
+			Conditions: RuleConditions{ActionTypes: []string{"ban"}},
+			Actions:    RuleActions{PushoverDestination: "mods"}},
+	}}
+	sess := &mockModerationSession{MockDiscordSession: &MockDiscordSession{}, ruleNames: map[string]string{"r1": "Slur filter", "r2": "Spam links"}}
+
+	t.Run("AutomodMatch", func(t *testing.T) {
+		testLogBufferForTest.Reset()
+		autoModerationActionLogic(context.Background(), sess, &discordgo.AutoModerationActionExecution{
+			GuildID: "g1", RuleID: "r1", UserID: "u1", ChannelID: "c1", Content: "bad words",
+			Action: discordgo.AutoModerationAction{Type: discordgo.AutoModerationRuleActionBlockMessage},
+		})
+		output := testLogBufferForTest.String()
+		if !strings.Contains(output, "('SlurBlocked') MATCHED") {
+			t.Errorf("Expected SlurBlocked to match. Log: %s", output)
+		}
+		if strings.Contains(output, "MessageReactionAdd called") {
+			t.Errorf("Blocked messages do not exist, so no reaction should be attempted. Log: %s", output)
+		}
+	})
+
+	t.Run("AutomodOtherRule", func(t *testing.T) {
+		testLogBufferForTest.Reset()
+		autoModerationActionLogic(context.Background(), sess, &discordgo.AutoModerationActionExecution{
+			GuildID: "g1", RuleID: "r2", UserID: "u1", ChannelID: "c1",
+			Action: discordgo.AutoModerationAction{Type: discordgo.AutoModerationRuleActionBlockMessage},
+		})
+		if strings.Contains(testLogBufferForTest.String(), "MATCHED") {
+			t.Errorf("Expected no match for a different AutoMod rule. Log: %s", testLogBufferForTest.String())
+		}
+	})
+
+	t.Run("AuditLogBan", func(t *testing.T) {
+		testLogBufferForTest.Reset()
+		ban := discordgo.AuditLogActionMemberBanAdd
+		auditLogEntryLogic(context.Background(), sess, &discordgo.GuildAuditLogEntryCreate{GuildID: "g1",
+			AuditLogEntry: &discordgo.AuditLogEntry{ActionType: &ban, UserID: "mod1", TargetID: "u2", Reason: "spam"}})
+		output := testLogBufferForTest.String()
+		if !strings.Contains(output, "('Bans') MATCHED") {
+			t.Errorf("Expected Bans rule to match. Log: %s", output)
+		}
+	})
+
+	t.Run("MessageEventIgnoresModerationConditions", func(t *testing.T) {
+		testLogBufferForTest.Reset()
+		ProcessRules(context.Background(), &discordgo.Message{ID: "m1", ChannelID: "c1"}, globalConfig, sess, 0)
+		if strings.Contains(testLogBufferForTest.String(), "MATCHED") {
+			t.Errorf("actionTypes must not match plain messages. Log: %s", testLogBufferForTest.String())
+		}
+	})
+}
+
+func TestGatewayIntents(t *testing.T) {
+	base := gatewayIntents(&Config{})
+	if base&discordgo.IntentAutoModerationExecution != 0 || base&discordgo.IntentGuildModeration != 0 {
+		t.Errorf("Moderation intents must not be requested without moderation rules")
+	}
+	withMod := gatewayIntents(&Config{Rules: []Rule{{Event: ruleEventAutomod}, {Event: ruleEventAuditLog}}})
+	if withMod&discordgo.IntentAutoModerationExecution == 0 || withMod&discordgo.IntentGuildModeration == 0 {
+		t.Errorf("Expected moderation intents when rules use onAutomod/onAuditLog")
+	}
+}
@@ -0,0 +1,92 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// defaultMQTTClientID is used when a notifier sets no clientId.
+const defaultMQTTClientID = "discord2pushover"
+
+type mqttNotifier struct {
+	config *MQTTNotifier
+}
+
+// mqttTopic renders the notifier's topic template for a notification.
+func mqttTopic(topicTemplate string, n *Notification) (string, error) {
+	topic, err := renderTemplate(topicTemplate, nil, templateData(n))
+	if err != nil {
+		return "", fmt.Errorf("topic %w", err)
+	}
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return "", fmt.Errorf("invalid topic %q: must be non-empty and contain no wildcards", topic)
+	}
+	return topic, nil
+}
+
+// Notify connects to the broker, publishes the notification and disconnects again.
+func (m *mqttNotifier) Notify(ctx context.Context, n *Notification) error {
+	if m.config.Broker == "" || m.config.Topic == "" {
+		return fmt.Errorf("mqtt notifier requires broker and topic")
+	}
+	if m.config.QoS > 2 {
+		return fmt.Errorf("invalid mqtt qos %d (expected 0, 1 or 2)", m.config.QoS)
+	}
+	topic, err := mqttTopic(m.config.Topic, n)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(newNotificationPayload(n))
+	if err != nil {
+		return err
+	}
+
+	clientID := m.config.ClientID
+	if clientID == "" {
+		clientID = defaultMQTTClientID
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(m.config.Broker).
+		SetClientID(clientID).
+		SetUsername(m.config.Username).
+		SetPassword(m.config.Password).
+		SetConnectTimeout(notifierHTTPTimeout).
+		SetAutoReconnect(false)
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if err := waitMQTT(ctx, token); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s: %w", m.config.Broker, err)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s: %w", m.config.Broker, err)
+	}
+	defer client.Disconnect(250)
+
+	token = client.Publish(topic, m.config.QoS, m.config.Retain, payload)
+	if err := waitMQTT(ctx, token); err != nil {
+		return fmt.Errorf("failed to publish to MQTT topic %s: %w", topic, err)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to publish to MQTT topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// waitMQTT waits for an MQTT operation to complete, for at most notifierHTTPTimeout.
+func waitMQTT(ctx context.Context, token mqtt.Token) error {
+	timer := time.NewTimer(notifierHTTPTimeout)
+	defer timer.Stop()
+	select {
+	case <-token.Done():
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("timed out after %s", notifierHTTPTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
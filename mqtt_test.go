@@ -0,0 +1,115 @@
+package discord2pushover
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+)
+
+// mqttPublish is a PUBLISH packet received by fakeMQTTBroker.
+type mqttPublish struct {
+	Topic   string
+	QoS     byte
+	Retain  bool
+	Payload []byte
+}
+
+// fakeMQTTBroker accepts one MQTT 3.1.1 connection and reports the first message published on it.
+func fakeMQTTBroker(t *testing.T) (addr string, published <-chan mqttPublish) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	ch := make(chan mqttPublish, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			header, err := reader.ReadByte()
+			if err != nil {
+				return
+			}
+			length, multiplier := 0, 1
+			for {
+				b, err := reader.ReadByte()
+				if err != nil {
+					return
+				}
+				length += int(b&127) * multiplier
+				multiplier *= 128
+				if b&128 == 0 {
+					break
+				}
+			}
+			packet := make([]byte, length)
+			if _, err := io.ReadFull(reader, packet); err != nil {
+				return
+			}
+			switch header >> 4 {
+			case 1: // CONNECT
+				conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+			case 3: // PUBLISH
+				msg := mqttPublish{QoS: header >> 1 & 3, Retain: header&1 == 1}
+				topicLen := int(binary.BigEndian.Uint16(packet))
+				msg.Topic = string(packet[2 : 2+topicLen])
+				rest := packet[2+topicLen:]
+				if msg.QoS > 0 {
+					conn.Write([]byte{0x40, 0x02, rest[0], rest[1]})
+					rest = rest[2:]
+				}
+				msg.Payload = rest
+				ch <- msg
+			case 14: // DISCONNECT
+				return
+			}
+		}
+	}()
+	return listener.Addr().String(), ch
+}
+
+func TestMQTTNotifier(t *testing.T) {
+	addr, published := fakeMQTTBroker(t)
+	notifier := &mqttNotifier{config: &MQTTNotifier{Broker: "tcp://" + addr, Topic: "d2p/{{.RuleName}}/{{.ChannelID}}", QoS: 1, Retain: true}}
+	n := &Notification{
+		RuleName: "Doorbell", Title: "Ring", Body: "someone is at the door", Priority: 1,
+		Data: &NotificationData{ChannelID: "c1", MessageID: "m1", AuthorName: "frontdoor"},
+	}
+	n.Data.RuleName = n.RuleName
+	if err := notifier.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	msg := <-published
+	if msg.Topic != "d2p/Doorbell/c1" || msg.QoS != 1 || !msg.Retain {
+		t.Errorf("Unexpected publish %+v", msg)
+	}
+	var payload notificationPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		t.Fatalf("Payload is not JSON: %v", err)
+	}
+	if payload.Rule != "Doorbell" || payload.Body != "someone is at the door" || payload.Priority != 1 || payload.MessageID != "m1" || payload.AuthorName != "frontdoor" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+func TestMQTTTopic(t *testing.T) {
+	n := &Notification{RuleName: "Alerts"}
+	if topic, err := mqttTopic("alerts/{{.RuleName}}", n); err != nil || topic != "alerts/Alerts" {
+		t.Errorf("Expected alerts/Alerts, got %q, %v", topic, err)
+	}
+	if _, err := mqttTopic("alerts/#", n); err == nil {
+		t.Error("Expected wildcard topics to be rejected")
+	}
+	if _, err := mqttTopic("{{.ChannelID}}", n); err == nil {
+		t.Error("Expected an empty topic to be rejected")
+	}
+}
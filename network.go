@@ -0,0 +1,221 @@
+package discord2pushover
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// networkDialTimeout bounds each connection attempt, as in net/http's default transport.
+	networkDialTimeout = 30 * time.Second
+	// happyEyeballsDelay is how long an attempt may take before the next address is tried in parallel (RFC 8305).
+	happyEyeballsDelay = 300 * time.Millisecond
+)
+
+// Gateway connection counters, served in /debug/state and /debug/vars to judge network settings by.
+var (
+	gatewayDisconnects      = new(expvar.Int)
+	gatewayReconnects       = new(expvar.Int)
+	gatewayLastOutageMillis = new(expvar.Int) // From the last disconnect until the gateway was back
+)
+
+// gatewayDisconnectedAt is when the gateway connection was lost, in Unix nanoseconds, or 0 while connected.
+var gatewayDisconnectedAt atomic.Int64
+
+// recordGatewayDisconnect counts a lost gateway connection. Repeated disconnects while reconnecting
+// count once and keep the time of the first.
+func recordGatewayDisconnect(now time.Time) {
+	if gatewayDisconnectedAt.CompareAndSwap(0, now.UnixNano()) {
+		gatewayDisconnects.Add(1)
+	}
+}
+
+// recordGatewayReconnect counts a gateway connection restored after a disconnect and returns the
+// outage's length. The initial connect is not a reconnect and returns 0.
+func recordGatewayReconnect(now time.Time) time.Duration {
+	disconnectedAt := gatewayDisconnectedAt.Swap(0)
+	if disconnectedAt == 0 {
+		return 0
+	}
+	outage := now.Sub(time.Unix(0, disconnectedAt))
+	gatewayReconnects.Add(1)
+	gatewayLastOutageMillis.Set(outage.Milliseconds())
+	return outage
+}
+
+// networkDialer connects to the hosts of outbound requests with the network settings applied.
+type networkDialer struct {
+	dialer        *net.Dialer
+	resolver      *net.Resolver
+	dnsTimeout    time.Duration
+	preferIPv4    bool
+	fallbackDelay time.Duration
+}
+
+// newNetworkDialer returns a dialer for the network settings.
+func newNetworkDialer(network *Network) (*networkDialer, error) {
+	d := &networkDialer{
+		dialer:        &net.Dialer{Timeout: networkDialTimeout, KeepAlive: 30 * time.Second},
+		resolver:      net.DefaultResolver,
+		dnsTimeout:    time.Duration(network.DNSTimeoutSeconds) * time.Second,
+		preferIPv4:    network.PreferIPv4,
+		fallbackDelay: happyEyeballsDelay,
+	}
+	if network.PreferIPv4 {
+		d.fallbackDelay = networkDialTimeout // IPv6 only once IPv4 has failed
+	}
+	if len(network.Resolvers) > 0 {
+		servers := make([]string, 0, len(network.Resolvers))
+		for _, resolver := range network.Resolvers {
+			server, err := resolverAddress(resolver)
+			if err != nil {
+				return nil, err
+			}
+			servers = append(servers, server)
+		}
+		// The resolver retries a timed out query by dialing again, so rotating the servers falls back
+		// from an unresponsive one to the next
+		var next atomic.Uint64
+		dnsDialer := &net.Dialer{Timeout: 5 * time.Second}
+		d.resolver = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := servers[(next.Add(1)-1)%uint64(len(servers))]
+			return dnsDialer.DialContext(ctx, network, server)
+		}}
+	}
+	return d, nil
+}
+
+// resolverAddress validates a configured DNS server, given as an IP address with an optional port.
+func resolverAddress(resolver string) (string, error) {
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil {
+		host, port = resolver, "53"
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid resolver %q: expected an IP address, optionally with a port", resolver)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// DialContext resolves the address's host within the DNS timeout and connects to its IP addresses.
+func (d *networkDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+	lookupCtx := ctx
+	if d.dnsTimeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, d.dnsTimeout)
+		defer cancel()
+	}
+	ips, err := d.resolver.LookupIPAddr(lookupCtx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	addresses := orderAddresses(ips, network, d.preferIPv4)
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no %s address found for %s", network, host)
+	}
+	for i, ip := range addresses {
+		addresses[i] = net.JoinHostPort(ip, port)
+	}
+	return d.dialAddresses(ctx, network, addresses)
+}
+
+// orderAddresses returns the IPs usable for network in the order they are tried: all IPv4 addresses
+// first with preferIPv4, otherwise alternating between the families, starting with the resolver's first.
+func orderAddresses(ips []net.IPAddr, network string, preferIPv4 bool) []string {
+	var v4, v6 []string
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			if network != "tcp6" {
+				v4 = append(v4, ip.IP.String())
+			}
+		} else if network != "tcp4" {
+			v6 = append(v6, ip.String())
+		}
+	}
+	if preferIPv4 {
+		return append(v4, v6...)
+	}
+	first, second := v4, v6
+	if len(ips) > 0 && ips[0].IP.To4() == nil {
+		first, second = v6, v4
+	}
+	ordered := make([]string, 0, len(v4)+len(v6))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ordered = append(ordered, first[i])
+		}
+		if i < len(second) {
+			ordered = append(ordered, second[i])
+		}
+	}
+	return ordered
+}
+
+// dialAddresses connects to the first of addresses that answers. The next address is tried as soon
+// as an attempt fails or has not connected within the fallback delay; the first connection wins.
+func (d *networkDialer) dialAddresses(ctx context.Context, network string, addresses []string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(addresses))
+	started, pending := 0, 0
+	start := func() {
+		address := addresses[started]
+		started++
+		pending++
+		go func() {
+			conn, err := d.dialer.DialContext(ctx, network, address)
+			results <- result{conn, err}
+		}()
+	}
+	start()
+	fallback := time.NewTimer(d.fallbackDelay)
+	defer fallback.Stop()
+	var firstErr error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go func(pending int) { // Close connections of attempts that completed anyway
+					for ; pending > 0; pending-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if started < len(addresses) {
+				start()
+				if !fallback.Stop() {
+					select {
+					case <-fallback.C:
+					default:
+					}
+				}
+				fallback.Reset(d.fallbackDelay)
+			}
+		case <-fallback.C:
+			if started < len(addresses) {
+				start()
+				fallback.Reset(d.fallbackDelay)
+			}
+		}
+	}
+	return nil, firstErr
+}
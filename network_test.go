@@ -0,0 +1,111 @@
+package discord2pushover
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOrderAddresses(t *testing.T) {
+	ips := []net.IPAddr{
+		{IP: net.ParseIP("2001:db8::1")},
+		{IP: net.ParseIP("2001:db8::2")},
+		{IP: net.ParseIP("192.0.2.1")},
+		{IP: net.ParseIP("192.0.2.2")},
+	}
+	tests := []struct {
+		name       string
+		network    string
+		preferIPv4 bool
+		want       []string
+	}{
+		{"interleaved", "tcp", false, []string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2"}},
+		{"prefer IPv4", "tcp", true, []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "2001:db8::2"}},
+		{"tcp4", "tcp4", false, []string{"192.0.2.1", "192.0.2.2"}},
+		{"tcp6", "tcp6", true, []string{"2001:db8::1", "2001:db8::2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderAddresses(ips, tt.network, tt.preferIPv4); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNetworkDialer_FallsBack(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	// A closed port refuses the first attempt, so the second is started without waiting
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddress := closed.Addr().String()
+	closed.Close()
+
+	d, err := newNetworkDialer(&Network{PreferIPv4: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	conn, err := d.dialAddresses(context.Background(), "tcp", []string{closedAddress, listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("Expected the second address to connect, got %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the fallback right after the refusal, took %s", elapsed)
+	}
+
+	if _, err := d.dialAddresses(context.Background(), "tcp", []string{closedAddress}); err == nil {
+		t.Error("Expected an error when no address connects")
+	}
+}
+
+func TestNewTransport_Network(t *testing.T) {
+	if _, err := newTransport(&Config{Network: &Network{Resolvers: []string{"1.1.1.1", "[2606:4700::1111]:53", "10.0.0.2:5353"}}}); err != nil {
+		t.Errorf("Expected valid resolvers to be accepted, got %v", err)
+	}
+	if _, err := newTransport(&Config{Network: &Network{Resolvers: []string{"dns.example.com"}}}); err == nil {
+		t.Error("Expected a resolver host name to be rejected")
+	}
+}
+
+func TestGatewayReconnectMetrics(t *testing.T) {
+	gatewayDisconnectedAt.Store(0)
+	disconnects, reconnects := gatewayDisconnects.Value(), gatewayReconnects.Value()
+
+	now := time.Now()
+	if outage := recordGatewayReconnect(now); outage != 0 {
+		t.Errorf("Expected the initial connect not to count as a reconnect, got %s", outage)
+	}
+	recordGatewayDisconnect(now)
+	recordGatewayDisconnect(now.Add(time.Second))
+	if outage := recordGatewayReconnect(now.Add(3 * time.Second)); outage != 3*time.Second {
+		t.Errorf("Expected an outage of 3s since the first disconnect, got %s", outage)
+	}
+	if got := gatewayDisconnects.Value() - disconnects; got != 1 {
+		t.Errorf("Expected 1 disconnect, got %d", got)
+	}
+	if got := gatewayReconnects.Value() - reconnects; got != 1 {
+		t.Errorf("Expected 1 reconnect, got %d", got)
+	}
+	if got := gatewayLastOutageMillis.Value(); got != 3000 {
+		t.Errorf("Expected the last outage to be 3000ms, got %d", got)
+	}
+}
@@ -0,0 +1,138 @@
+package discord2pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// notifierHTTPTimeout bounds requests of HTTP-based notifiers.
+const notifierHTTPTimeout = 10 * time.Second
+
+// notifierHTTPClient returns the client for requests of HTTP-based notifiers.
+func notifierHTTPClient(config *Config) *http.Client {
+	return newHTTPClientTimeout(config, notifierHTTPTimeout)
+}
+
+// Notification is a rendered notification handed to a backend.
+type Notification struct {
+	RuleName string
+	Title    string
+	Body     string
+	Link     string // Discord jump link
+	Priority int    // Pushover scale, -2 (lowest) to 2 (emergency)
+	DedupKey string // The rule's correlation key for the message, if any, identifying its incident
+
+	Data *NotificationData // Template data of the triggering message, for backends with templated fields
+}
+
+// notificationPayload is the JSON document describing a notification for machine consumers.
+type notificationPayload struct {
+	Rule       string            `json:"rule"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Link       string            `json:"link,omitempty"`
+	Priority   int               `json:"priority"`
+	GuildID    string            `json:"guildId,omitempty"`
+	ChannelID  string            `json:"channelId,omitempty"`
+	MessageID  string            `json:"messageId,omitempty"`
+	AuthorName string            `json:"authorName,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Payload    map[string]string `json:"payload,omitempty"`
+}
+
+func newNotificationPayload(n *Notification) notificationPayload {
+	payload := notificationPayload{Rule: n.RuleName, Title: n.Title, Body: n.Body, Link: n.Link, Priority: n.Priority}
+	if n.Data != nil {
+		payload.GuildID = n.Data.GuildID
+		payload.ChannelID = n.Data.ChannelID
+		payload.MessageID = n.Data.MessageID
+		payload.AuthorName = n.Data.AuthorName
+		payload.Labels = n.Data.Labels
+		payload.Payload = n.Data.Payload
+	}
+	return payload
+}
+
+// templateData returns the data for rendering a backend's templated fields.
+func templateData(n *Notification) *NotificationData {
+	if n.Data == nil {
+		return &NotificationData{RuleName: n.RuleName, Body: n.Body, Link: n.Link}
+	}
+	return n.Data
+}
+
+// Notifier is a notification backend besides Pushover.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+var (
+	registeredNotifiersMu sync.RWMutex
+	registeredNotifiers   = map[string]Notifier{}
+)
+
+// RegisterNotifier makes a backend of a program embedding the rule engine available to rules'
+// notifiers under name. Notifiers in the config take precedence over registered ones.
+func RegisterNotifier(name string, notifier Notifier) {
+	registeredNotifiersMu.Lock()
+	defer registeredNotifiersMu.Unlock()
+	registeredNotifiers[name] = notifier
+}
+
+// newNotifier creates the backend selected in a notifier's config, connecting through the
+// transport of config.
+func newNotifier(name string, cfg NotifierConfig, config *Config) (Notifier, error) {
+	switch {
+	case cfg.Matrix != nil:
+		return &matrixNotifier{config: cfg.Matrix, httpClient: notifierHTTPClient(config)}, nil
+	case cfg.Twilio != nil:
+		return &twilioNotifier{config: cfg.Twilio, httpClient: notifierHTTPClient(config)}, nil
+	case cfg.MQTT != nil:
+		return &mqttNotifier{config: cfg.MQTT}, nil
+	case cfg.HomeAssistant != nil:
+		return &homeAssistantNotifier{config: cfg.HomeAssistant, httpClient: notifierHTTPClient(config)}, nil
+	case cfg.Desktop != nil:
+		return &desktopNotifier{config: cfg.Desktop}, nil
+	case cfg.PagerDuty != nil:
+		return &pagerDutyNotifier{config: cfg.PagerDuty, httpClient: notifierHTTPClient(config)}, nil
+	case cfg.Opsgenie != nil:
+		return &opsgenieNotifier{config: cfg.Opsgenie, httpClient: notifierHTTPClient(config)}, nil
+	case cfg.Discord != nil:
+		return &discordNotifier{config: cfg.Discord}, nil
+	default:
+		return nil, fmt.Errorf("notifier '%s' has no backend configured", name)
+	}
+}
+
+// notifyBackends sends a notification to each of the rule's named notifiers and returns the combined error.
+func notifyBackends(ctx context.Context, config *Config, names []string, n *Notification) error {
+	var errs []error
+	for _, name := range names {
+		var notifier Notifier
+		var err error
+		if cfg, ok := config.Notifiers[name]; ok {
+			notifier, err = newNotifier(name, cfg, config)
+		} else {
+			registeredNotifiersMu.RLock()
+			notifier, ok = registeredNotifiers[name]
+			registeredNotifiersMu.RUnlock()
+			if !ok {
+				errs = append(errs, fmt.Errorf("unknown notifier '%s'", name))
+				continue
+			}
+		}
+		if err == nil {
+			err = notifier.Notify(ctx, n)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notifier '%s': %w", name, err))
+			continue
+		}
+		log.Infof("Notification for rule '%s' sent via notifier '%s'.", n.RuleName, name)
+	}
+	return errors.Join(errs...)
+}
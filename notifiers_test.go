@@ -0,0 +1,42 @@
+package discord2pushover
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type recordingNotifier struct {
+	notifications []*Notification
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, n *Notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func TestNotifyBackends_RegisteredNotifier(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	recorder := &recordingNotifier{}
+	RegisterNotifier("embedded", recorder)
+	defer func() {
+		registeredNotifiersMu.Lock()
+		delete(registeredNotifiers, "embedded")
+		registeredNotifiersMu.Unlock()
+	}()
+
+	config := &Config{}
+	if err := notifyBackends(context.Background(), config, []string{"embedded"}, &Notification{RuleName: "R", Body: "hello"}); err != nil {
+		t.Fatalf("Expected the registered notifier to be used, got %v", err)
+	}
+	if len(recorder.notifications) != 1 || recorder.notifications[0].Body != "hello" {
+		t.Errorf("Expected one notification, got %+v", recorder.notifications)
+	}
+
+	err := notifyBackends(context.Background(), config, []string{"missing"}, &Notification{RuleName: "R"})
+	if err == nil || !strings.Contains(err.Error(), "unknown notifier 'missing'") {
+		t.Errorf("Expected an unknown notifier error, got %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package discord2pushover
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// onCallToken in a pushoverDestination is replaced by the key of whoever is on call.
+const onCallToken = "{{oncall}}"
+
+// defaultOnCallShift is the length of a rota shift when the rota sets no shiftHours.
+const defaultOnCallShift = 7 * 24 * time.Hour
+
+// defaultCalendarPollInterval is how often the on-call calendar is fetched when pollIntervalMinutes is not set.
+const defaultCalendarPollInterval = 15 * time.Minute
+
+// calendarEvent is one on-call shift of a calendar.
+type calendarEvent struct {
+	Start   time.Time
+	End     time.Time
+	Summary string
+}
+
+// onCallCalendar holds the shifts of the last fetched calendar.
+var onCallCalendar = struct {
+	sync.Mutex
+	events  []calendarEvent
+	fetched time.Time
+}{}
+
+// rotaOnCall returns who is on call at now according to the rota, or "" if the rota has not started.
+func rotaOnCall(rota *OnCallRota, now time.Time) (string, error) {
+	if len(rota.People) == 0 {
+		return "", nil
+	}
+	start, err := time.Parse(time.RFC3339, rota.Start)
+	if err != nil {
+		return "", fmt.Errorf("invalid rota start %q: expected a time like 2024-01-01T09:00:00Z", rota.Start)
+	}
+	if now.Before(start) {
+		return "", nil
+	}
+	shift := defaultOnCallShift
+	if rota.ShiftHours > 0 {
+		shift = time.Duration(rota.ShiftHours) * time.Hour
+	}
+	return rota.People[int(now.Sub(start)/shift)%len(rota.People)], nil
+}
+
+// calendarOnCall returns the person of the first configured people named in a calendar shift running
+// at now, or "".
+func calendarOnCall(events []calendarEvent, people map[string]string, now time.Time) string {
+	names := make([]string, 0, len(people))
+	for name := range people {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, event := range events {
+		if now.Before(event.Start) || !now.Before(event.End) {
+			continue
+		}
+		summary := strings.ToLower(event.Summary)
+		for _, name := range names {
+			if strings.Contains(summary, strings.ToLower(name)) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// currentOnCall returns the Pushover key of whoever is on call at now: the calendar's current shift,
+// else the rota's, else the fallback. Names are looked up in people; a name that is not listed is
+// used as the key itself.
+func currentOnCall(onCall *OnCall, now time.Time) (string, error) {
+	name := ""
+	if onCall.CalendarURL != "" {
+		onCallCalendar.Lock()
+		name = calendarOnCall(onCallCalendar.events, onCall.People, now)
+		onCallCalendar.Unlock()
+	}
+	if name == "" && onCall.Rota != nil {
+		var err error
+		if name, err = rotaOnCall(onCall.Rota, now); err != nil {
+			return onCall.Fallback, err
+		}
+	}
+	if name == "" {
+		return onCall.Fallback, nil
+	}
+	if key, ok := onCall.People[name]; ok {
+		return key, nil
+	}
+	return name, nil
+}
+
+// resolveOnCallDestination replaces the {{oncall}} token in a destination with the key of whoever is
+// on call. Without anyone on call the token is dropped, leaving the rest of a comma-separated list.
+func resolveOnCallDestination(config *Config, destination string, ruleNameLog string) string {
+	if !strings.Contains(destination, onCallToken) {
+		return destination
+	}
+	key := ""
+	if config.OnCall == nil {
+		log.Errorf("Rule '%s' notifies %s but the config has no onCall schedule.", ruleNameLog, onCallToken)
+	} else {
+		var err error
+		if key, err = currentOnCall(config.OnCall, time.Now()); err != nil {
+			log.Errorf("Rule '%s': on-call schedule: %v", ruleNameLog, err)
+		}
+		if key == "" {
+			log.Warnf("Rule '%s': nobody is on call and onCall has no fallback.", ruleNameLog)
+		}
+	}
+	var keys []string
+	for _, part := range strings.Split(strings.ReplaceAll(destination, onCallToken, key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			keys = append(keys, part)
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// parseCalendar reads the events of an iCalendar (RFC 5545) feed. Recurring events are not expanded;
+// schedule exports such as PagerDuty's and Opsgenie's list every shift as its own event.
+func parseCalendar(r io.Reader) ([]calendarEvent, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:] // Folded continuation line
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var events []calendarEvent
+	var event *calendarEvent
+	for _, line := range lines {
+		nameAndParams, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(nameAndParams, ";")
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				event = &calendarEvent{}
+			}
+		case "END":
+			if strings.EqualFold(value, "VEVENT") && event != nil {
+				if !event.Start.IsZero() && !event.End.IsZero() {
+					events = append(events, *event)
+				}
+				event = nil
+			}
+		case "DTSTART", "DTEND":
+			if event == nil {
+				continue
+			}
+			t, err := parseCalendarTime(params, value)
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				event.Start = t
+			} else {
+				event.End = t
+			}
+		case "SUMMARY":
+			if event != nil {
+				event.Summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(value)
+			}
+		}
+	}
+	return events, nil
+}
+
+// parseCalendarTime parses a DTSTART or DTEND value: UTC ("20240101T090000Z"), local to a TZID
+// parameter, floating (read as UTC), or a date.
+func parseCalendarTime(params string, value string) (time.Time, error) {
+	loc := time.UTC
+	for _, param := range strings.Split(params, ";") {
+		if key, zone, ok := strings.Cut(param, "="); ok && strings.EqualFold(key, "TZID") {
+			if l, err := time.LoadLocation(strings.Trim(zone, `"`)); err == nil {
+				loc = l
+			}
+		}
+	}
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid calendar time %q", value)
+}
+
+// fetchCalendar downloads and parses an iCalendar feed, such as the on-call or holiday calendar.
+func fetchCalendar(ctx context.Context, config *Config, url string) ([]calendarEvent, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar URL: %w", err)
+	}
+	resp, err := newHTTPClient(config).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar returned %s", resp.Status)
+	}
+	return parseCalendar(resp.Body)
+}
+
+// pollOnCallCalendar keeps the on-call calendar current until ctx is done. A failed fetch keeps the
+// previous calendar.
+func pollOnCallCalendar(ctx context.Context, config *Config) {
+	defer recoverPanic("pollOnCallCalendar")
+	interval := defaultCalendarPollInterval
+	if config.OnCall.PollIntervalMinutes > 0 {
+		interval = time.Duration(config.OnCall.PollIntervalMinutes) * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Infof("Polling on-call calendar every %s...", interval)
+	for {
+		if events, err := fetchCalendar(ctx, config, config.OnCall.CalendarURL); err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("On-call calendar: %v", err)
+			}
+		} else {
+			onCallCalendar.Lock()
+			onCallCalendar.events = events
+			onCallCalendar.fetched = time.Now()
+			onCallCalendar.Unlock()
+			log.Debugf("On-call calendar: %d shifts loaded.", len(events))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
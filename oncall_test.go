@@ -0,0 +1,106 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCurrentOnCall(t *testing.T) {
+	const calendar = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20240103T090000Z\r\n" +
+		"DTEND:20240103T170000Z\r\n" +
+		"SUMMARY:On call: Carol\r\n" +
+		"  (cover)\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20240110T090000\r\n" +
+		"DTEND;TZID=Europe/Berlin:20240110T170000\r\n" +
+		"SUMMARY:Dave\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	events, err := parseCalendar(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("parseCalendar: %v", err)
+	}
+	if len(events) != 2 || events[0].Summary != "On call: Carol (cover)" || !events[1].Start.Equal(time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+	onCallCalendar.Lock()
+	onCallCalendar.events = events
+	onCallCalendar.Unlock()
+	defer func() {
+		onCallCalendar.Lock()
+		onCallCalendar.events = nil
+		onCallCalendar.Unlock()
+	}()
+
+	onCall := &OnCall{
+		Rota:        &OnCallRota{Start: "2024-01-01T09:00:00Z", People: []string{"alice", "uBobKey"}},
+		CalendarURL: "https://example.com/schedule.ics",
+		People:      map[string]string{"alice": "uAlice", "carol": "uCarol"},
+		Fallback:    "uTeam",
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"Before the rota starts", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), "uTeam"},
+		{"First shift", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "uAlice"},
+		{"Calendar shift overrides the rota", time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC), "uCarol"},
+		{"Second shift uses the name as key", time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC), "uBobKey"},
+		{"Calendar shift of someone not in people", time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC), "uBobKey"},
+		{"Rotation wraps around", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), "uAlice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := currentOnCall(onCall, tt.now)
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %q, got %q (err %v)", tt.want, got, err)
+			}
+		})
+	}
+
+	if _, err := currentOnCall(&OnCall{Rota: &OnCallRota{Start: "monday", People: []string{"alice"}}}, time.Now()); err == nil {
+		t.Error("Expected an error for an invalid rota start")
+	}
+}
+
+func TestProcessRules_OnCallDestination(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		OnCall: &OnCall{
+			Rota:   &OnCallRota{Start: time.Now().Add(-time.Hour).Format(time.RFC3339), ShiftHours: 24, People: []string{"alice"}},
+			People: map[string]string{"alice": "uAlice"},
+		},
+		Rules: []Rule{{
+			Name:       "Alerts",
+			Conditions: RuleConditions{ChannelID: "alerts"},
+			Actions:    RuleActions{PushoverDestination: "{{oncall}}"},
+		}},
+	}
+	config.SetPushoverClient(fake)
+
+	message := &discordgo.Message{ID: "m1", ChannelID: "alerts", Content: "disk full", Author: &discordgo.User{ID: "dave"}}
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	if len(fake.recipients) != 1 || fake.recipients[0] != "uAlice" {
+		t.Errorf("Expected the on-call person to be notified, got %v", fake.recipients)
+	}
+
+	if got := resolveOnCallDestination(&Config{}, "uTeam, {{oncall}}", "test"); got != "uTeam" {
+		t.Errorf("Expected the token to be dropped without a schedule, got %q", got)
+	}
+	if got := configuredPushoverDestinations(config); len(got) != 1 || got[0] != "uAlice" {
+		t.Errorf("Expected the schedule's keys instead of the token, got %v", got)
+	}
+}
@@ -0,0 +1,140 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// opsgenieAPIBases are the Alert API endpoints per region; tests point them at a local server.
+var opsgenieAPIBases = map[string]string{
+	"us": "https://api.opsgenie.com",
+	"eu": "https://api.eu.opsgenie.com",
+}
+
+const (
+	opsgenieMaxMessage     = 130   // Opsgenie's limit for an alert message
+	opsgenieMaxDescription = 15000 // and for its description
+	opsgenieMaxAlias       = 512
+)
+
+type opsgenieNotifier struct {
+	config     *OpsgenieNotifier
+	httpClient *http.Client
+}
+
+// opsgenieAlert is a create alert request.
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Responders  []opsgenieTeam    `json:"responders,omitempty"`
+}
+
+type opsgenieTeam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// opsgeniePriority maps a Pushover priority to an alert priority, P1 being the most urgent.
+func opsgeniePriority(priority int) string {
+	switch {
+	case priority >= 2:
+		return "P1"
+	case priority == 1:
+		return "P2"
+	case priority == 0:
+		return "P3"
+	case priority == -1:
+		return "P4"
+	default:
+		return "P5"
+	}
+}
+
+// opsgenieAlertRequest returns the alert for a notification. Its alias is the incident dedup key, so
+// Opsgenie folds repeated alerts of one incident into the open alert.
+func opsgenieAlertRequest(cfg *OpsgenieNotifier, n *Notification) opsgenieAlert {
+	message := n.Title
+	if message == "" {
+		message = n.Body
+	}
+	description := n.Body
+	if n.Link != "" {
+		description += "\n\n" + n.Link
+	}
+	alert := opsgenieAlert{
+		Message:     truncateRunes(message, opsgenieMaxMessage),
+		Alias:       truncateRunes(incidentDedupKey(n), opsgenieMaxAlias),
+		Description: truncateRunes(description, opsgenieMaxDescription),
+		Priority:    opsgeniePriority(n.Priority),
+		Source:      "discord2pushover",
+		Entity:      n.RuleName,
+		Tags:        cfg.Tags,
+		Details:     map[string]string{},
+	}
+	if n.Data != nil {
+		for key, value := range n.Data.Labels {
+			alert.Details[key] = value
+		}
+		alert.Details["channelId"] = n.Data.ChannelID
+		alert.Details["messageId"] = n.Data.MessageID
+	}
+	alert.Details["rule"] = n.RuleName
+	if n.Link != "" {
+		alert.Details["link"] = n.Link
+	}
+	for _, team := range cfg.Teams {
+		alert.Responders = append(alert.Responders, opsgenieTeam{Name: team, Type: "team"})
+	}
+	return alert
+}
+
+// opsgenieRequest sends a request to the Alert API, which accepts requests for asynchronous processing.
+func opsgenieRequest(ctx context.Context, httpClient *http.Client, cfg *OpsgenieNotifier, path string, body interface{}) error {
+	if cfg.APIKey == "" {
+		return fmt.Errorf("opsgenie notifier requires apiKey")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us"
+	}
+	base, ok := opsgenieAPIBases[region]
+	if !ok {
+		return fmt.Errorf("unknown opsgenie region '%s' (expected us or eu)", cfg.Region)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, base+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "GenieKey "+cfg.APIKey)
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to call Opsgenie: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("opsgenie API error: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Notify creates an alert, or adds to the open one with the same alias.
+func (o *opsgenieNotifier) Notify(ctx context.Context, n *Notification) error {
+	return opsgenieRequest(ctx, o.httpClient, o.config, "/v2/alerts", opsgenieAlertRequest(o.config, n))
+}
@@ -0,0 +1,59 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOpsgenieNotifier(t *testing.T) {
+	var path, auth string
+	var alert opsgenieAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&alert)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"result":"Request will be processed","requestId":"r1"}`))
+	}))
+	defer server.Close()
+	originalBase := opsgenieAPIBases["us"]
+	opsgenieAPIBases["us"] = server.URL
+	defer func() { opsgenieAPIBases["us"] = originalBase }()
+
+	notifier := &opsgenieNotifier{httpClient: server.Client(), config: &OpsgenieNotifier{APIKey: "k3y", Teams: []string{"ops"}, Tags: []string{"discord"}}}
+	n := &Notification{
+		RuleName: "DB", Title: "Disk full", Body: "db1 at 97%", Link: "https://discord.com/channels/g1/ops/m1", Priority: 2,
+		Data: &NotificationData{ChannelID: "ops", MessageID: "m1", Labels: map[string]string{"team": "dba"}},
+	}
+	if err := notifier.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if path != "/v2/alerts" || auth != "GenieKey k3y" {
+		t.Errorf("Unexpected request to %s with %q", path, auth)
+	}
+	want := opsgenieAlert{
+		Message:     "Disk full",
+		Alias:       "DB/m1",
+		Description: "db1 at 97%\n\nhttps://discord.com/channels/g1/ops/m1",
+		Priority:    "P1",
+		Source:      "discord2pushover",
+		Entity:      "DB",
+		Tags:        []string{"discord"},
+		Details:     map[string]string{"rule": "DB", "link": "https://discord.com/channels/g1/ops/m1", "team": "dba", "channelId": "ops", "messageId": "m1"},
+		Responders:  []opsgenieTeam{{Name: "ops", Type: "team"}},
+	}
+	if !reflect.DeepEqual(alert, want) {
+		t.Errorf("Expected alert %+v, got %+v", want, alert)
+	}
+
+	n.DedupKey = "db1"
+	if got := opsgenieAlertRequest(notifier.config, n).Alias; got != "DB/db1" {
+		t.Errorf("Expected the correlation key as alias, got %q", got)
+	}
+	if err := (&opsgenieNotifier{httpClient: server.Client(), config: &OpsgenieNotifier{APIKey: "k3y", Region: "mars"}}).Notify(context.Background(), n); err == nil {
+		t.Error("Expected an error for an unknown region")
+	}
+}
@@ -0,0 +1,144 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configEnv is the environment overlay selected with --env, passed on to the installed service.
+var configEnv string
+
+// overlayPath returns the overlay file of an environment: the file named after it next to the base
+// config, with the base's extension, e.g. staging.yaml for base.yaml.
+func overlayPath(configPath string, env string) string {
+	ext := filepath.Ext(configPath)
+	if ext == "" {
+		ext = ".yaml"
+	}
+	return filepath.Join(filepath.Dir(configPath), env+ext)
+}
+
+// mergeOverlayNode merges an overlay value into a base value. Mappings are merged key by key, with a
+// null value removing the key; the rules list is merged by rule name; any other overlay value
+// replaces the base value.
+func mergeOverlayNode(base *yaml.Node, overlay *yaml.Node, key string) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			name, value := overlay.Content[i], overlay.Content[i+1]
+			j := 0
+			for ; j+1 < len(base.Content); j += 2 {
+				if base.Content[j].Value == name.Value {
+					break
+				}
+			}
+			switch {
+			case value.Tag == "!!null" && j < len(base.Content):
+				base.Content = append(base.Content[:j], base.Content[j+2:]...)
+			case value.Tag == "!!null":
+			case j < len(base.Content):
+				base.Content[j+1] = mergeOverlayNode(base.Content[j+1], value, name.Value)
+			default:
+				base.Content = append(base.Content, name, value)
+			}
+		}
+		return base
+	case key == "rules" && base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode:
+		for _, rule := range overlay.Content {
+			name := mappingValue(rule, "name")
+			merged := false
+			for i, baseRule := range base.Content {
+				if baseName := mappingValue(baseRule, "name"); name != nil && name.Value != "" && baseName != nil && baseName.Value == name.Value {
+					base.Content[i] = mergeOverlayNode(baseRule, rule, "")
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				base.Content = append(base.Content, rule)
+			}
+		}
+		return base
+	default:
+		return overlay
+	}
+}
+
+// mergeOverlay merges an overlay config document into a base config document.
+func mergeOverlay(baseData []byte, overlayData []byte) ([]byte, error) {
+	base, err := configDocument(baseData)
+	if err != nil {
+		return nil, err
+	}
+	var overlay yaml.Node
+	if err := yaml.Unmarshal(overlayData, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse overlay: %w", err)
+	}
+	if len(overlay.Content) == 0 {
+		return baseData, nil // Empty overlay
+	}
+	if overlay.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("overlay is not a YAML mapping")
+	}
+	merged := mergeOverlayNode(base, overlay.Content[0], "")
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(merged); err != nil {
+		return nil, fmt.Errorf("failed to merge overlay: %w", err)
+	}
+	encoder.Close()
+	return out.Bytes(), nil
+}
+
+// checkConfigSchema rejects unknown keys and mistyped values, which would otherwise be silently ignored.
+func checkConfigSchema(filePath string, data []byte) error {
+	if violations, err := validateConfigSchema(data); err == nil && len(violations) > 0 {
+		return fmt.Errorf("invalid config file %s (keys written in another case or style can be fixed with 'discord2pushover migrate-config'):\n  %s",
+			filePath, strings.Join(violations, "\n  "))
+	}
+	return nil
+}
+
+// readConfigFile reads a config file and merges the overlay of env over it, checking both against
+// the schema.
+func readConfigFile(filePath string, env string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+	if data, err = decryptConfig(filePath, data); err != nil {
+		return nil, fmt.Errorf("failed to decrypt config file %s: %w", filePath, err)
+	}
+	if err := checkConfigSchema(filePath, data); err != nil {
+		return nil, err
+	}
+	if env == "" {
+		return data, nil
+	}
+	if strings.ContainsAny(env, `/\`) {
+		return nil, fmt.Errorf("invalid environment %q: expected a name such as staging", env)
+	}
+	path := overlayPath(filePath, env)
+	overlayData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay of environment %s: %w", env, err)
+	}
+	if overlayData, err = decryptConfig(path, overlayData); err != nil {
+		return nil, fmt.Errorf("failed to decrypt overlay %s: %w", path, err)
+	}
+	if err := checkConfigSchema(path, overlayData); err != nil {
+		return nil, err
+	}
+	merged, err := mergeOverlay(data, overlayData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply overlay %s: %w", path, err)
+	}
+	log.Infof("Applied overlay %s for environment %s.", path, env)
+	return merged, nil
+}
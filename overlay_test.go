@@ -0,0 +1,83 @@
+package discord2pushover
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigEnv(t *testing.T) {
+	dir := t.TempDir()
+	base := `pushoverAppKey: "prod-app"
+logLevel: "info"
+admin:
+  listen: ":9090"
+rules:
+  - name: Alerts
+    conditions:
+      channelId: "123"
+      contentIncludes: ["FIRING"]
+    actions:
+      pushoverDestination: "uProdOps"
+      priority: 1
+      reactionEmoji: ["📟"]
+  - name: Deploys
+    conditions:
+      channelId: "456"
+    actions:
+      pushoverDestination: "uProdOps"
+`
+	staging := `pushoverAppKey: "staging-app"
+admin: null
+rules:
+  - name: Alerts
+    actions:
+      pushoverDestination: "uStaging"
+      priority: -1
+  - name: Staging only
+    conditions:
+      channelId: "789"
+    actions:
+      pushoverDestination: "uStaging"
+`
+	basePath := filepath.Join(dir, "base.yaml")
+	if err := os.WriteFile(basePath, []byte(base), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "staging.yaml"), []byte(staging), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigEnv(basePath, "staging")
+	if err != nil {
+		t.Fatalf("LoadConfigEnv failed: %v", err)
+	}
+	if cfg.PushoverAppKey != "staging-app" || cfg.LogLevel != "info" || cfg.Admin != nil {
+		t.Errorf("Unexpected global settings: app %q, log level %q, admin %+v", cfg.PushoverAppKey, cfg.LogLevel, cfg.Admin)
+	}
+	if len(cfg.Rules) != 3 {
+		t.Fatalf("Expected the overlay's new rule to be appended, got %d rules", len(cfg.Rules))
+	}
+	alerts := cfg.Rules[0]
+	if alerts.Actions.PushoverDestination != "uStaging" || alerts.Actions.Priority != -1 || alerts.Conditions.ChannelID != "123" ||
+		len(alerts.Conditions.ContentIncludes) != 1 || len(alerts.Actions.ReactionEmoji) != 1 || alerts.Actions.ReactionEmoji[0] != "📟" {
+		t.Errorf("Expected the overlay to be merged into the base rule, got %+v", alerts)
+	}
+	if cfg.Rules[1].Actions.PushoverDestination != "uProdOps" || cfg.Rules[2].Name != "Staging only" {
+		t.Errorf("Unexpected rules %+v", cfg.Rules[1:])
+	}
+
+	if cfg, err := LoadConfigEnv(basePath, ""); err != nil || cfg.PushoverAppKey != "prod-app" {
+		t.Errorf("Expected the base config without an environment, got %v", err)
+	}
+	if _, err := LoadConfigEnv(basePath, "prod"); err == nil || !strings.Contains(err.Error(), "prod.yaml") {
+		t.Errorf("Expected an error for the missing overlay, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "typo.yaml"), []byte("pushoverAppkey: x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigEnv(basePath, "typo"); err == nil || !strings.Contains(err.Error(), "typo.yaml") {
+		t.Errorf("Expected the overlay to be checked against the schema, got %v", err)
+	}
+}
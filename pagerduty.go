@@ -0,0 +1,148 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// pagerDutyEventsURLs are the Events API v2 endpoints per region; tests point them at a local server.
+var pagerDutyEventsURLs = map[string]string{
+	"us": "https://events.pagerduty.com/v2/enqueue",
+	"eu": "https://events.eu.pagerduty.com/v2/enqueue",
+}
+
+// pagerDutyMaxSummary is PagerDuty's limit for an event summary.
+const pagerDutyMaxSummary = 1024
+
+type pagerDutyNotifier struct {
+	config     *PagerDutyNotifier
+	httpClient *http.Client
+}
+
+// pagerDutyEvent is an Events API v2 event.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // "trigger", "acknowledge" or "resolve"
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"` // Only for triggers
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+	Client      string            `json:"client,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string              `json:"summary"`
+	Source        string              `json:"source"`
+	Severity      string              `json:"severity"`
+	Component     string              `json:"component,omitempty"`
+	Group         string              `json:"group,omitempty"`
+	CustomDetails notificationPayload `json:"custom_details"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// incidentDedupKey returns the key identifying a notification's incident in incident tools: the rule
+// and its correlation key, so all messages of one correlated alert update the same incident, else the
+// rule and the Discord message. It returns "" if neither is known.
+func incidentDedupKey(n *Notification) string {
+	switch {
+	case n.DedupKey != "":
+		return n.RuleName + "/" + n.DedupKey
+	case n.Data != nil && n.Data.MessageID != "":
+		return n.RuleName + "/" + n.Data.MessageID
+	default:
+		return ""
+	}
+}
+
+// pagerDutySeverity maps a Pushover priority to an event severity.
+func pagerDutySeverity(priority int) string {
+	switch {
+	case priority >= 2:
+		return "critical"
+	case priority == 1:
+		return "error"
+	case priority == 0:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// pagerDutyTrigger returns the event opening or updating the incident of a notification.
+func pagerDutyTrigger(cfg *PagerDutyNotifier, n *Notification) pagerDutyEvent {
+	summary := n.Body
+	if n.Title != "" {
+		summary = n.Title + ": " + summary
+	}
+	source := cfg.Source
+	if source == "" {
+		source = "discord2pushover"
+	}
+	event := pagerDutyEvent{
+		RoutingKey:  cfg.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    incidentDedupKey(n),
+		Payload: &pagerDutyPayload{
+			Summary:       truncateRunes(summary, pagerDutyMaxSummary),
+			Source:        source,
+			Severity:      pagerDutySeverity(n.Priority),
+			Group:         n.RuleName,
+			CustomDetails: newNotificationPayload(n),
+		},
+		Client: "discord2pushover",
+	}
+	if n.Data != nil {
+		event.Payload.Component = n.Data.ChannelID
+	}
+	if n.Link != "" {
+		event.Links = []pagerDutyLink{{Href: n.Link, Text: "Open in Discord"}}
+	}
+	return event
+}
+
+// sendPagerDutyEvent posts an event to the Events API.
+func sendPagerDutyEvent(ctx context.Context, httpClient *http.Client, cfg *PagerDutyNotifier, event pagerDutyEvent) error {
+	if cfg.RoutingKey == "" {
+		return fmt.Errorf("pagerduty notifier requires routingKey")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us"
+	}
+	endpoint, ok := pagerDutyEventsURLs[region]
+	if !ok {
+		return fmt.Errorf("unknown pagerduty region '%s' (expected us or eu)", cfg.Region)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send PagerDuty event: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pagerduty API error: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Notify triggers an incident, or updates the open one with the same dedup key.
+func (p *pagerDutyNotifier) Notify(ctx context.Context, n *Notification) error {
+	return sendPagerDutyEvent(ctx, p.httpClient, p.config, pagerDutyTrigger(p.config, n))
+}
@@ -0,0 +1,62 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestProcessRules_PagerDutyNotifier(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	originalIncidents := incidents
+	incidents = &incidentTracker{open: make(map[string]*incident)}
+	defer func() { incidents = originalIncidents }()
+
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"status":"success","dedup_key":"x"}`))
+	}))
+	defer server.Close()
+	originalURL := pagerDutyEventsURLs["eu"]
+	pagerDutyEventsURLs["eu"] = server.URL
+	defer func() { pagerDutyEventsURLs["eu"] = originalURL }()
+
+	config := &Config{
+		Notifiers: map[string]NotifierConfig{
+			"pd": {PagerDuty: &PagerDutyNotifier{RoutingKey: "R0UT1NG", Region: "eu"}},
+		},
+		Rules: []Rule{{
+			Name:           "DB",
+			Conditions:     RuleConditions{ChannelID: "ops"},
+			CorrelationKey: "{{.ChannelID}}",
+			Actions:        RuleActions{Notify: []string{"pd"}, Priority: 1},
+		}},
+	}
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m1", ChannelID: "ops", GuildID: "g1", Content: "host=db1 disk full", Author: &discordgo.User{ID: "u1"}}, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	config.Rules[0].Actions.Priority = 2
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m2", ChannelID: "ops", GuildID: "g1", Content: "host=db1 disk gone", Author: &discordgo.User{ID: "u1"}}, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+
+	if len(events) != 2 {
+		t.Fatalf("Expected a trigger and an escalation, got %+v", events)
+	}
+	first, second := events[0], events[1]
+	if first.RoutingKey != "R0UT1NG" || first.EventAction != "trigger" || first.DedupKey != "DB/ops" || first.Payload.Severity != "error" {
+		t.Errorf("Unexpected trigger %+v", first)
+	}
+	if first.Payload.Summary != "Discord Notification: host=db1 disk full" || len(first.Links) != 1 || first.Links[0].Href != "https://discord.com/channels/g1/ops/m1" {
+		t.Errorf("Unexpected trigger payload %+v, links %+v", first.Payload, first.Links)
+	}
+	if second.DedupKey != first.DedupKey || second.Payload.Severity != "critical" {
+		t.Errorf("Expected the escalation to update the same incident, got %+v", second)
+	}
+}
@@ -0,0 +1,148 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Payload formats for RuleActions.PayloadFormat.
+const (
+	payloadFormatGrafana      = "grafana"
+	payloadFormatGitHub       = "github"
+	payloadFormatUptimeRobot  = "uptimerobot"
+	payloadFormatUptimeKuma   = "uptimekuma"
+	payloadFormatGenericEmbed = "generic-embed"
+	payloadFormatAuto         = "auto" // The first parser detecting the message
+)
+
+// PayloadParser extracts structured fields from the webhook messages of one sender.
+type PayloadParser interface {
+	// Detect reports whether the message was posted by the parser's sender.
+	Detect(message *discordgo.Message) bool
+	// Parse returns the fields of a detected message, including "text", a one-line rendering used
+	// as the body of messages without content.
+	Parse(message *discordgo.Message) map[string]string
+}
+
+// payloadParserFunc adapts a parse function that returns nil for other senders' messages.
+type payloadParserFunc func(message *discordgo.Message) map[string]string
+
+func (f payloadParserFunc) Detect(message *discordgo.Message) bool {
+	return f(message) != nil
+}
+
+func (f payloadParserFunc) Parse(message *discordgo.Message) map[string]string {
+	return f(message)
+}
+
+// payloadParsers are the parsers available as payloadFormat. A parser for another webhook sender is
+// added here.
+var payloadParsers = map[string]PayloadParser{
+	payloadFormatGrafana:      payloadParserFunc(parseGrafanaPayload),
+	payloadFormatGitHub:       payloadParserFunc(parseGitHubPayload),
+	payloadFormatUptimeRobot:  payloadParserFunc(parseUptimeRobotPayload),
+	payloadFormatUptimeKuma:   payloadParserFunc(parseUptimeKumaPayload),
+	payloadFormatGenericEmbed: payloadParserFunc(parseGenericEmbedPayload),
+}
+
+// payloadFormats lists the valid payloadFormat values, for the schema and error messages.
+func payloadFormats() []string {
+	formats := []string{payloadFormatAuto}
+	for name := range payloadParsers {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats[1:])
+	return formats
+}
+
+// parsePayload extracts the structured fields of a webhook message in the rule's payloadFormat,
+// falling back to the generic-embed parser for messages not in that format. It returns nil when
+// the rule has no payloadFormat or no parser applies.
+func parsePayload(rule *Rule, message *discordgo.Message, ruleNameLog string) map[string]string {
+	format := rule.Actions.PayloadFormat
+	switch format {
+	case "":
+		return nil
+	case payloadFormatAuto:
+		for _, name := range payloadFormats()[1:] {
+			if name != payloadFormatGenericEmbed && payloadParsers[name].Detect(message) {
+				log.Debugf("Rule '%s': message ID %s detected as a %s payload.", ruleNameLog, message.ID, name)
+				return payloadParsers[name].Parse(message)
+			}
+		}
+	default:
+		parser, ok := payloadParsers[format]
+		if !ok {
+			log.Errorf("Rule '%s' has unknown payloadFormat '%s', expected one of %s.", ruleNameLog, format, strings.Join(payloadFormats(), ", "))
+			return nil
+		}
+		if parser.Detect(message) {
+			return parser.Parse(message)
+		}
+		log.Debugf("Rule '%s': message ID %s is not a %s payload.", ruleNameLog, message.ID, format)
+	}
+	if fallback := payloadParsers[payloadFormatGenericEmbed]; format != payloadFormatGenericEmbed && fallback.Detect(message) {
+		return fallback.Parse(message)
+	}
+	return nil
+}
+
+// payloadKey turns an embed field name into a template-friendly key, e.g. "Service Name" into "service_name".
+func payloadKey(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+	return strings.Join(fields, "_")
+}
+
+// parseGenericEmbedPayload flattens the embeds of any message: title, description, url, author and
+// footer, plus one key per field named after it (see payloadKey). The first embed wins when several
+// have the same key.
+func parseGenericEmbedPayload(message *discordgo.Message) map[string]string {
+	payload := map[string]string{}
+	set := func(key, value string) {
+		if _, ok := payload[key]; !ok && key != "" && value != "" {
+			payload[key] = value
+		}
+	}
+	var title string
+	var fields []string
+	for _, embed := range message.Embeds {
+		if embed == nil {
+			continue
+		}
+		set("title", embed.Title)
+		set("description", embed.Description)
+		set("url", embed.URL)
+		if embed.Author != nil {
+			set("author", embed.Author.Name)
+		}
+		if embed.Footer != nil {
+			set("footer", embed.Footer.Text)
+		}
+		for _, field := range embed.Fields {
+			if field != nil {
+				set(payloadKey(field.Name), field.Value)
+				fields = append(fields, fmt.Sprintf("%s: %s", field.Name, field.Value))
+			}
+		}
+		if title == "" {
+			title = embed.Title
+		}
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+	text := title
+	if len(fields) > 0 {
+		text = strings.TrimSpace(text + " (" + strings.Join(fields, ", ") + ")")
+	}
+	if text == "" {
+		text, _, _ = strings.Cut(payload["description"], "\n")
+	}
+	payload["text"] = text
+	return payload
+}
@@ -0,0 +1,83 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestProcessRules_PayloadFormat(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	var received matrixMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Notifiers: map[string]NotifierConfig{"ops": {Matrix: &MatrixNotifier{Homeserver: server.URL, RoomID: "!r:example.org", AccessToken: "tok"}}},
+		Rules: []Rule{
+			{Name: "Default body", Conditions: RuleConditions{ChannelID: "grafana"}, Actions: RuleActions{Notify: []string{"ops"}, PayloadFormat: payloadFormatGrafana}},
+			{Name: "Templated", Conditions: RuleConditions{ChannelID: "templated"}, Actions: RuleActions{Notify: []string{"ops"}, PayloadFormat: payloadFormatGrafana,
+				Template: "{{.Payload.alertName}} {{.Payload.state}}"}},
+		},
+	}
+	embeds := []*discordgo.MessageEmbed{{Title: "[FIRING:1] HighCPU", Description: "Value: A=82.5"}}
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m1", ChannelID: "grafana", Embeds: embeds}, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	if !strings.Contains(received.Body, "\nHighCPU is firing: A=82.5\n") {
+		t.Errorf("Expected the parsed payload as the body, got %q", received.Body)
+	}
+
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m2", ChannelID: "templated", Embeds: embeds}, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	if !strings.Contains(received.Body, "\nHighCPU firing\n") {
+		t.Errorf("Expected the payload fields in the template, got %q", received.Body)
+	}
+}
+
+func TestParsePayload_Registry(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	grafana := &discordgo.Message{ID: "m1", Embeds: []*discordgo.MessageEmbed{{Title: "[FIRING:1] HighCPU", Description: "Value: A=82.5"}}}
+	deploy := &discordgo.Message{ID: "m2", Embeds: []*discordgo.MessageEmbed{{
+		Title:  "Deploy finished",
+		Footer: &discordgo.MessageEmbedFooter{Text: "CI"},
+		Fields: []*discordgo.MessageEmbedField{{Name: "Service Name", Value: "api"}, {Name: "Took", Value: "2m"}},
+	}}}
+
+	rule := &Rule{Actions: RuleActions{PayloadFormat: payloadFormatAuto}}
+	if payload := parsePayload(rule, grafana, "test"); payload["alertName"] != "HighCPU" {
+		t.Errorf("Expected auto to detect the Grafana alert, got %v", payload)
+	}
+	want := map[string]string{"title": "Deploy finished", "footer": "CI", "service_name": "api", "took": "2m",
+		"text": "Deploy finished (Service Name: api, Took: 2m)"}
+	if payload := parsePayload(rule, deploy, "test"); !reflect.DeepEqual(payload, want) {
+		t.Errorf("Expected auto to fall back to generic-embed %v, got %v", want, payload)
+	}
+
+	rule.Actions.PayloadFormat = payloadFormatGitHub
+	if payload := parsePayload(rule, deploy, "test"); !reflect.DeepEqual(payload, want) {
+		t.Errorf("Expected a fallback to generic-embed for other messages, got %v", payload)
+	}
+	if payload := parsePayload(rule, &discordgo.Message{Content: "no embeds"}, "test"); payload != nil {
+		t.Errorf("Expected no payload without embeds, got %v", payload)
+	}
+
+	rule.Actions.PayloadFormat = "nagios"
+	if payload := parsePayload(rule, grafana, "test"); payload != nil {
+		t.Errorf("Expected no payload for an unknown format, got %v", payload)
+	}
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "unknown payloadFormat 'nagios', expected one of auto, generic-embed, github, grafana, uptimekuma, uptimerobot") {
+		t.Errorf("Expected an error for the unknown format. Logs:\n%s", logs)
+	}
+}
@@ -0,0 +1,165 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// namedPermission pairs a Discord permission bit with a human-readable name for reporting.
+type namedPermission struct {
+	Bit  int64
+	Name string
+}
+
+var (
+	permViewChannel        = namedPermission{discordgo.PermissionViewChannel, "View Channel"}
+	permReadMessageHistory = namedPermission{discordgo.PermissionReadMessageHistory, "Read Message History"}
+	permAddReactions       = namedPermission{discordgo.PermissionAddReactions, "Add Reactions"}
+	permSendMessages       = namedPermission{discordgo.PermissionSendMessages, "Send Messages"}
+)
+
+// rulePermissions are needed in channels watched by rules: messages are fetched and reacted to.
+var rulePermissions = []namedPermission{permViewChannel, permReadMessageHistory, permAddReactions}
+
+// postingPermissions are needed in channels the bot posts announcements or meta-alerts to.
+var postingPermissions = []namedPermission{permViewChannel, permSendMessages}
+
+// channelPermissionResolver computes the bot's effective permissions in a channel.
+// *discordgo.Session satisfies it, falling back to REST when the state cache is empty.
+type channelPermissionResolver interface {
+	UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error)
+}
+
+var _ channelPermissionResolver = &discordgo.Session{}
+
+// missingChannelPermissions returns the names of required permissions the user lacks in the channel.
+func missingChannelPermissions(resolver channelPermissionResolver, userID, channelID string, required []namedPermission) ([]string, error) {
+	perms, err := resolver.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve permissions in channel %s: %w", channelID, err)
+	}
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return nil, nil
+	}
+	var missing []string
+	for _, p := range required {
+		if perms&p.Bit == 0 {
+			missing = append(missing, p.Name)
+		}
+	}
+	return missing, nil
+}
+
+// configuredChannels returns every channel ID referenced by the config, mapped to the permissions the bot needs there.
+func configuredChannels(config *Config) map[string][]namedPermission {
+	channels := make(map[string][]namedPermission)
+	add := func(channelID string, required []namedPermission) {
+		if channelID == "" {
+			return
+		}
+		existing := channels[channelID]
+		for _, p := range required {
+			found := false
+			for _, e := range existing {
+				if e.Bit == p.Bit {
+					found = true
+					break
+				}
+			}
+			if !found {
+				existing = append(existing, p)
+			}
+		}
+		channels[channelID] = existing
+	}
+
+	for _, rule := range config.Rules {
+		add(rule.Conditions.ChannelID, rulePermissions)
+	}
+	if config.LifecycleNotifications != nil {
+		add(config.LifecycleNotifications.DiscordChannelID, postingPermissions)
+	}
+	if config.ErrorNotification != nil {
+		add(config.ErrorNotification.DiscordChannelID, postingPermissions)
+	}
+	return channels
+}
+
+// configuredPushoverDestinations returns every distinct Pushover destination referenced by the config.
+// Destinations with the {{oncall}} token are covered by the keys of the on-call schedule.
+func configuredPushoverDestinations(config *Config) []string {
+	seen := make(map[string]bool)
+	var destinations []string
+	add := func(dest string) {
+		if dest != "" && !seen[dest] && !strings.Contains(dest, onCallToken) {
+			seen[dest] = true
+			destinations = append(destinations, dest)
+		}
+	}
+	for _, rule := range config.Rules {
+		add(rule.Actions.PushoverDestination)
+	}
+	if config.LifecycleNotifications != nil {
+		add(config.LifecycleNotifications.PushoverDestination)
+	}
+	if config.ErrorNotification != nil {
+		add(config.ErrorNotification.PushoverDestination)
+	}
+	keys := make([]string, 0, len(config.UserDestinations)+len(config.RoleDestinations))
+	for _, key := range config.UserDestinations {
+		keys = append(keys, key)
+	}
+	for _, key := range config.RoleDestinations {
+		keys = append(keys, key)
+	}
+	if onCall := config.OnCall; onCall != nil {
+		for _, key := range onCall.People {
+			keys = append(keys, key)
+		}
+		if onCall.Rota != nil {
+			for _, name := range onCall.Rota.People {
+				if _, ok := onCall.People[name]; !ok {
+					keys = append(keys, name)
+				}
+			}
+		}
+		keys = append(keys, onCall.Fallback)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(key)
+	}
+	return destinations
+}
+
+// preflightChannelPermissions verifies the bot's permissions in every configured channel and logs an
+// explicit warning per missing permission, so misconfiguration shows up at startup rather than as
+// opaque 403s during an incident. It returns the number of channels with problems.
+func preflightChannelPermissions(resolver channelPermissionResolver, botID string, config *Config) int {
+	problems := 0
+	for channelID, required := range configuredChannels(config) {
+		missing, err := missingChannelPermissions(resolver, botID, channelID, required)
+		if err != nil {
+			log.Warnf("Permission preflight: cannot check channel %s: %v", channelID, err)
+			problems++
+			continue
+		}
+		for _, name := range missing {
+			log.Warnf("Permission preflight: bot is missing '%s' in channel %s.", name, channelID)
+		}
+		if len(missing) > 0 {
+			problems++
+		} else {
+			log.Debugf("Permission preflight: channel %s OK.", channelID)
+		}
+	}
+	if problems == 0 {
+		log.Info("Permission preflight passed for all configured channels.")
+	} else {
+		log.Warnf("Permission preflight found problems in %d channel(s). Run 'discord2pushover diagnose' for details.", problems)
+	}
+	return problems
+}
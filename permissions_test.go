@@ -0,0 +1,44 @@
+package discord2pushover
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPreflightChannelPermissions(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	resolver := &fakeDiagnoseSession{permissions: map[string]int64{
+		"chOK":       discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory | discordgo.PermissionAddReactions,
+		"chAdmin":    discordgo.PermissionAdministrator,
+		"chNoReact":  discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory,
+		"chAnnounce": discordgo.PermissionViewChannel,
+	}}
+	cfg := &Config{
+		Rules: []Rule{
+			{Conditions: RuleConditions{ChannelID: "chOK"}},
+			{Conditions: RuleConditions{ChannelID: "chAdmin"}},
+			{Conditions: RuleConditions{ChannelID: "chNoReact"}},
+		},
+		LifecycleNotifications: &LifecycleNotifications{DiscordChannelID: "chAnnounce"},
+	}
+
+	if problems := preflightChannelPermissions(resolver, "bot1", cfg); problems != 2 {
+		t.Errorf("Expected 2 channels with problems, got %d", problems)
+	}
+	output := testLogBufferForTest.String()
+	for _, expected := range []string{
+		"bot is missing 'Add Reactions' in channel chNoReact",
+		"bot is missing 'Send Messages' in channel chAnnounce",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected log '%s' not found. Log: %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "in channel chAdmin") {
+		t.Errorf("Administrator channel should not report missing permissions. Log: %s", output)
+	}
+}
@@ -0,0 +1,103 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// pinDetectionWindow bounds how old the channel's last pin may be for the first pin event seen in a
+// channel, since there is no earlier snapshot of its pins to compare with.
+const pinDetectionWindow = time.Minute
+
+// pinFetcher is the subset of session calls needed to handle pin events.
+type pinFetcher interface {
+	DiscordSessionInterface
+	ChannelMessagesPinned(channelID string, opts ...discordgo.RequestOption) ([]*discordgo.Message, error)
+}
+
+// ChannelMessagesPinned calls the RealSession's ChannelMessagesPinned.
+func (w *DiscordGoSessionWrapper) ChannelMessagesPinned(channelID string, opts ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	return w.RealSession.ChannelMessagesPinned(channelID, opts...)
+}
+
+var _ pinFetcher = &DiscordGoSessionWrapper{}
+
+// knownPins remembers pinned message IDs per channel (channelID -> map[messageID]bool) so that
+// pin events can be told apart from unpin events.
+var (
+	knownPinsMu sync.Mutex
+	knownPins   = make(map[string]map[string]bool)
+)
+
+// dgChannelPinsUpdate is the raw handler for discordgo's ChannelPinsUpdate events.
+func dgChannelPinsUpdate(s *discordgo.Session, p *discordgo.ChannelPinsUpdate) {
+	defer recoverPanic("dgChannelPinsUpdate")
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	channelPinsUpdateLogic(ctx, &DiscordGoSessionWrapper{RealSession: s}, p)
+}
+
+// channelPinsUpdateLogic determines which messages were newly pinned and runs onPin rules for each.
+func channelPinsUpdateLogic(ctx context.Context, s pinFetcher, p *discordgo.ChannelPinsUpdate) {
+	log.Infof("Received ChannelPinsUpdate event: ChannelID: %s, LastPinTimestamp: %s", p.ChannelID, p.LastPinTimestamp)
+	if globalConfig == nil {
+		log.Error("globalConfig is nil in channelPinsUpdateLogic. Rules cannot be processed.")
+		return
+	}
+
+	pinned, err := s.ChannelMessagesPinned(p.ChannelID)
+	if err != nil {
+		log.Errorf("Error fetching pinned messages for channel %s: %v", p.ChannelID, err)
+		return
+	}
+
+	for _, message := range newlyPinnedMessages(p, pinned) {
+		if message.GuildID == "" {
+			message.GuildID = p.GuildID // REST message objects omit the guild ID
+		}
+		log.Infof("Message %s was pinned in channel %s.", message.ID, p.ChannelID)
+		processRulesForEvent(ctx, ruleEventPin, message, nil, globalConfig, s, math.MaxInt32)
+	}
+}
+
+// newlyPinnedMessages compares the channel's current pins with the last known snapshot and returns new ones.
+// For the first event in a channel, only the most recent pin is reported and only if it happened just now.
+func newlyPinnedMessages(p *discordgo.ChannelPinsUpdate, pinned []*discordgo.Message) []*discordgo.Message {
+	knownPinsMu.Lock()
+	defer knownPinsMu.Unlock()
+
+	previous, seen := knownPins[p.ChannelID]
+	current := make(map[string]bool, len(pinned))
+	for _, m := range pinned {
+		current[m.ID] = true
+	}
+	knownPins[p.ChannelID] = current
+
+	var fresh []*discordgo.Message
+	if seen {
+		for _, m := range pinned {
+			if !previous[m.ID] {
+				fresh = append(fresh, m)
+			}
+		}
+		return fresh
+	}
+
+	if len(pinned) == 0 || p.LastPinTimestamp == "" {
+		return nil
+	}
+	lastPin, err := time.Parse(time.RFC3339, p.LastPinTimestamp)
+	if err != nil {
+		log.Warnf("Cannot parse LastPinTimestamp '%s' for channel %s: %v", p.LastPinTimestamp, p.ChannelID, err)
+		return nil
+	}
+	if time.Since(lastPin) > pinDetectionWindow {
+		log.Debugf("First pin event for channel %s refers to an old pin (%s); treating it as an unpin.", p.ChannelID, p.LastPinTimestamp)
+		return nil
+	}
+	return pinned[:1] // Pins are returned most recent first
+}
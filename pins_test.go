@@ -0,0 +1,69 @@
+package discord2pushover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type mockPinSession struct {
+	*MockDiscordSession
+	pinned []*discordgo.Message
+}
+
+func (m *mockPinSession) ChannelMessagesPinned(channelID string, opts ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	return m.pinned, nil
+}
+
+func TestChannelPinsUpdateLogic(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	defer func() {
+		knownPinsMu.Lock()
+		delete(knownPins, "chPins")
+		knownPinsMu.Unlock()
+	}()
+
+	originalTestHookDisablePushoverSend := testHookDisablePushoverSend
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = originalTestHookDisablePushoverSend }()
+
+	globalConfig = &Config{Rules: []Rule{
+		{Name: "MessageRule", Conditions: RuleConditions{ChannelID: "chPins"}, Actions: RuleActions{PushoverDestination: "userkey"}},
+		{Name: "PinRule", Event: ruleEventPin, Conditions: RuleConditions{ChannelID: "chPins"}, Actions: RuleActions{PushoverDestination: "userkey"}},
+	}}
+
+	msgA := &discordgo.Message{ID: "pinA", ChannelID: "chPins", Content: "runbook"}
+	msgB := &discordgo.Message{ID: "pinB", ChannelID: "chPins", Content: "incident"}
+	sess := &mockPinSession{MockDiscordSession: &MockDiscordSession{}, pinned: []*discordgo.Message{msgA}}
+
+	// First event: recent pin is reported.
+	channelPinsUpdateLogic(context.Background(), sess, &discordgo.ChannelPinsUpdate{ChannelID: "chPins", LastPinTimestamp: time.Now().Format(time.RFC3339)})
+	output := testLogBufferForTest.String()
+	if !strings.Contains(output, "Rule #2 ('PinRule') MATCHED for message ID pinA") {
+		t.Errorf("Expected PinRule to match pinA. Log: %s", output)
+	}
+	if strings.Contains(output, "'MessageRule'") {
+		t.Errorf("Message rules must not be evaluated for pin events. Log: %s", output)
+	}
+
+	// Second event: a new pin is added on top of the known one.
+	testLogBufferForTest.Reset()
+	sess.pinned = []*discordgo.Message{msgB, msgA}
+	channelPinsUpdateLogic(context.Background(), sess, &discordgo.ChannelPinsUpdate{ChannelID: "chPins", LastPinTimestamp: time.Now().Format(time.RFC3339)})
+	output = testLogBufferForTest.String()
+	if !strings.Contains(output, "MATCHED for message ID pinB") || strings.Contains(output, "message ID pinA") {
+		t.Errorf("Expected only pinB to be processed. Log: %s", output)
+	}
+
+	// Third event: an unpin must not trigger anything.
+	testLogBufferForTest.Reset()
+	sess.pinned = []*discordgo.Message{msgA}
+	channelPinsUpdateLogic(context.Background(), sess, &discordgo.ChannelPinsUpdate{ChannelID: "chPins"})
+	if strings.Contains(testLogBufferForTest.String(), "Processing rules") {
+		t.Errorf("Unpin must not trigger rule processing. Log: %s", testLogBufferForTest.String())
+	}
+}
@@ -0,0 +1,66 @@
+package discord2pushover
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultTagEmergency is used when a tag escalates a rule without emergency parameters to priority 2.
+var defaultTagEmergency = EmergencyParams{Retry: 60, Expire: 3600}
+
+// inlineTagPriority returns the priority requested by a tag in the message content, such as "!p2".
+// Tags only count as whole words and only from authors allowed by the config; if several tags are
+// present the highest priority wins. ok is false when no usable tag is found.
+func inlineTagPriority(message *discordgo.Message, tags *PriorityTags, ruleNameLog string) (priority int, ok bool) {
+	if tags == nil || len(tags.Tags) == 0 {
+		return 0, false
+	}
+	for tag, tagPriority := range tags.Tags {
+		if !containsKeyword(message.Content, tag, false, true, false) {
+			continue
+		}
+		if tagPriority < -2 || tagPriority > 2 {
+			log.Warnf("Rule '%s': priority tag '%s' has invalid priority %d; ignoring it.", ruleNameLog, tag, tagPriority)
+			continue
+		}
+		if !ok || tagPriority > priority {
+			priority, ok = tagPriority, true
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	if !mayUsePriorityTags(message, tags) {
+		authorID := "unknown"
+		if message.Author != nil {
+			authorID = message.Author.ID
+		}
+		log.Infof("Rule '%s': ignoring priority tag in message ID %s: author %s is not allowed to change the priority.", ruleNameLog, message.ID, authorID)
+		return 0, false
+	}
+	log.Infof("Rule '%s': priority tag in message ID %s sets priority %d.", ruleNameLog, message.ID, priority)
+	return priority, true
+}
+
+// mayUsePriorityTags reports whether the message's author is in the allowlist, by user ID or by role.
+// Roles are only known for messages that carry the author's member data (e.g. newly created messages).
+func mayUsePriorityTags(message *discordgo.Message, tags *PriorityTags) bool {
+	if message.Author == nil {
+		return false
+	}
+	for _, id := range tags.AuthorIDs {
+		if id == message.Author.ID {
+			return true
+		}
+	}
+	if message.Member == nil {
+		return false
+	}
+	for _, role := range message.Member.Roles {
+		for _, allowed := range tags.RoleIDs {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package discord2pushover
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestInlineTagPriority(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	tags := &PriorityTags{
+		Tags:      map[string]int{"!p2": 2, "#page": 2, "!p1": 1, "!quiet": -1},
+		AuthorIDs: []string{"oncall"},
+		RoleIDs:   []string{"sre"},
+	}
+	oncall := &discordgo.User{ID: "oncall"}
+	tests := []struct {
+		name     string
+		message  *discordgo.Message
+		expected int
+		ok       bool
+	}{
+		{"NoTag", &discordgo.Message{Content: "disk full", Author: oncall}, 0, false},
+		{"Tag", &discordgo.Message{Content: "disk full !P2", Author: oncall}, 2, true},
+		{"HighestTagWins", &discordgo.Message{Content: "!p1 disk full #page", Author: oncall}, 2, true},
+		{"Deescalate", &discordgo.Message{Content: "!quiet test alert", Author: oncall}, -1, true},
+		{"TagInsideWord", &discordgo.Message{Content: "see http://x/!p2x", Author: oncall}, 0, false},
+		{"AuthorNotAllowed", &discordgo.Message{Content: "!p2", Author: &discordgo.User{ID: "intern"}}, 0, false},
+		{"AllowedRole", &discordgo.Message{Content: "!p2", Author: &discordgo.User{ID: "u2"}, Member: &discordgo.Member{Roles: []string{"dev", "sre"}}}, 2, true},
+		{"OtherRole", &discordgo.Message{Content: "!p2", Author: &discordgo.User{ID: "u2"}, Member: &discordgo.Member{Roles: []string{"dev"}}}, 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			priority, ok := inlineTagPriority(tc.message, tags, "test")
+			if ok != tc.ok || priority != tc.expected {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", tc.expected, tc.ok, priority, ok)
+			}
+		})
+	}
+
+	rule := &Rule{Actions: RuleActions{Priority: 0, PriorityTags: tags}}
+	actions := effectiveActions(rule, &discordgo.Message{Content: "!p2 db down", Author: oncall}, "test")
+	if actions.Priority != 2 || actions.Emergency == nil || actions.Emergency.Retry != 60 {
+		t.Errorf("Expected an emergency with default parameters, got priority %d and %+v", actions.Priority, actions.Emergency)
+	}
+	if rule.Actions.Emergency != nil {
+		t.Error("Expected the rule itself to be left alone")
+	}
+}
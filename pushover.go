@@ -1,21 +1,68 @@
-package main
+package discord2pushover
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gregdel/pushover"
 )
 
+// defaultNotificationTitle is used when a rule has no titleTemplate.
+const defaultNotificationTitle = "Discord Notification"
+
+// defaultLinkTitle is the label Pushover shows for the Discord jump link when linkTitle is not configured.
+const defaultLinkTitle = "Open in Discord"
+
+// Link styles for the Discord jump link in notifications.
+const (
+	linkStyleWeb  = "web"  // https://discord.com/... (default), opens the browser or app depending on the device
+	linkStyleApp  = "app"  // discord://... opens the native Discord app directly
+	linkStyleBoth = "both" // app link as the tappable URL, web link in the body as fallback
+)
+
+// discordAppLink converts a https://discord.com/channels/... link into the discord:// protocol form.
+func discordAppLink(webLink string) string {
+	return "discord://" + strings.TrimPrefix(webLink, "https://")
+}
+
+// selectDiscordLinks returns the link to use as the notification URL and an optional secondary link for the body.
+func selectDiscordLinks(linkStyle string, webLink string) (primary string, secondary string) {
+	switch linkStyle {
+	case "", linkStyleWeb:
+		return webLink, ""
+	case linkStyleApp:
+		return discordAppLink(webLink), ""
+	case linkStyleBoth:
+		return discordAppLink(webLink), webLink
+	default:
+		log.Warnf("Unknown linkStyle '%s', using web link.", linkStyle)
+		return webLink, ""
+	}
+}
+
 // testHookDisablePushoverSend is for unit testing. If true, SendPushoverNotification returns success without actual sending.
 var testHookDisablePushoverSend bool
 // testHookPushoverSendCalled is for unit testing, to check if SendPushoverNotification's core logic was invoked.
 var testHookPushoverSendCalled bool
 
 
-// SendPushoverNotification sends a notification via Pushover.
+// withTrailer appends trailer, e.g. the Discord link, to body, truncating body rather than the trailer
+// so the message stays within Pushover's length limit.
+func withTrailer(body string, trailer string) string {
+	room := pushover.MessageMaxLength - utf8.RuneCountInString(trailer)
+	if room < 0 {
+		return truncateRunes(body+trailer, pushover.MessageMaxLength)
+	}
+	return truncateRunes(body, room) + trailer
+}
+
+// SendPushoverNotification sends a notification via Pushover. An empty title uses the default title.
+// device restricts it to a device variant's devices and sound; nil sends to all devices of the destination.
 // It returns the receipt ID if the message was an emergency priority and successfully sent, otherwise an empty string.
-func SendPushoverNotification(config *Config, ruleAction *RuleActions, messageContent string, discordMessageLink string) (string, error) {
+func SendPushoverNotification(ctx context.Context, config *Config, ruleAction *RuleActions, device *DeviceVariant, title string, messageContent string, discordMessageLink string) (string, error) {
 	testHookPushoverSendCalled = true // Mark that we entered the function for test verification
 	if testHookDisablePushoverSend {
 		log.Debug("testHookDisablePushoverSend is true, faking successful Pushover send.")
@@ -26,26 +73,53 @@ func SendPushoverNotification(config *Config, ruleAction *RuleActions, messageCo
 		return "", nil
 	}
 
-	if config.PushoverAppKey == "" {
+	if config.currentPushoverAppKey() == "" {
 		return "", fmt.Errorf("pushover AppKey is missing from global config")
 	}
 	if ruleAction.PushoverDestination == "" {
 		return "", fmt.Errorf("pushoverDestination is missing from rule action")
 	}
 
-	log.Infof("Preparing Pushover notification for destination '%s' with app key '%s'", ruleAction.PushoverDestination, config.PushoverAppKey)
-
-	// Create a new Pushover app instance
-	app := pushover.New(config.PushoverAppKey)
-
-	// Create a new recipient
-	recipient := pushover.NewRecipient(ruleAction.PushoverDestination)
+	log.Infof("Preparing Pushover notification for destination '%s'", ruleAction.PushoverDestination)
 
 	// Create the message
-	title := "Discord Notification" // Or make this configurable later
-	fullMessage := fmt.Sprintf("%s\n\nDiscord Link: %s", messageContent, discordMessageLink)
-	log.Debugf("Pushover message content (first 50 chars): %.50s", fullMessage) // Log snippet of message
-	message := pushover.NewMessageWithTitle(fullMessage, title)
+	if title == "" {
+		title = defaultNotificationTitle
+	}
+	primaryLink, secondaryLink := selectDiscordLinks(ruleAction.LinkStyle, discordMessageLink)
+	var message *pushover.Message
+	if config.LinkInBody {
+		// Compatibility mode: append the jump link to the body as earlier versions did.
+		links := fmt.Sprintf("\n\nDiscord Link: %s", primaryLink)
+		if secondaryLink != "" {
+			links += fmt.Sprintf("\nWeb Link: %s", secondaryLink)
+		}
+		fullMessage := withTrailer(messageContent, links)
+		log.Debugf("Pushover message content (first 50 chars): %.50s", fullMessage) // Log snippet of message
+		message = pushover.NewMessageWithTitle(fullMessage, truncateRunes(title, pushover.MessageTitleMaxLength))
+	} else {
+		body := messageContent
+		if body == "" {
+			body = "(no text content)" // Pushover rejects empty bodies, e.g. for embed-only messages
+		}
+		if secondaryLink != "" {
+			body = withTrailer(body, fmt.Sprintf("\n\nWeb: %s", secondaryLink))
+		} else {
+			body = truncateRunes(body, pushover.MessageMaxLength)
+		}
+		log.Debugf("Pushover message content (first 50 chars): %.50s", body)
+		message = pushover.NewMessageWithTitle(body, truncateRunes(title, pushover.MessageTitleMaxLength))
+		message.URL = primaryLink
+		message.URLTitle = config.LinkTitle
+		if message.URLTitle == "" {
+			message.URLTitle = defaultLinkTitle
+		}
+	}
+
+	if device != nil {
+		message.DeviceName = device.Device
+		message.Sound = device.Sound
+	}
 
 	// Set priority
 	// Pushover library uses these constants:
@@ -81,7 +155,8 @@ func SendPushoverNotification(config *Config, ruleAction *RuleActions, messageCo
 
 	// Send the message
 	log.Infof("Sending Pushover notification to %s...", ruleAction.PushoverDestination)
-	resp, err := app.SendMessage(message, recipient)
+	resp, err := config.pushoverClient().SendMessage(ctx, message, ruleAction.PushoverDestination)
+	recordDelivery(ctx, config, message, ruleAction.PushoverDestination, resp, err)
 	if err != nil {
 		log.Errorf("Error sending Pushover notification to %s: %v", ruleAction.PushoverDestination, err)
 		return "", fmt.Errorf("failed to send Pushover notification: %w", err)
@@ -101,3 +176,80 @@ func SendPushoverNotification(config *Config, ruleAction *RuleActions, messageCo
 
 	return "", nil
 }
+
+// SendPushoverText sends a plain, non-rule notification (e.g. lifecycle announcements) via Pushover.
+// Emergency priority is not supported here since no acknowledgement is tracked; it is downgraded to High.
+func SendPushoverText(ctx context.Context, config *Config, destination string, title string, text string, priority int) error {
+	testHookPushoverSendCalled = true
+	if testHookDisablePushoverSend {
+		log.Debug("testHookDisablePushoverSend is true, faking successful Pushover text send.")
+		return nil
+	}
+
+	if config.currentPushoverAppKey() == "" {
+		return fmt.Errorf("pushover AppKey is missing from global config")
+	}
+	if destination == "" {
+		return fmt.Errorf("pushover destination is empty")
+	}
+
+	message := pushover.NewMessageWithTitle(truncateRunes(text, pushover.MessageMaxLength), truncateRunes(title, pushover.MessageTitleMaxLength))
+
+	switch {
+	case priority >= 2:
+		log.Warnf("Emergency priority is not supported for plain notifications to %s, sending as High Priority.", destination)
+		message.Priority = pushover.PriorityHigh
+	case priority < -2:
+		message.Priority = pushover.PriorityLowest
+	default:
+		message.Priority = priority
+	}
+
+	log.Infof("Sending Pushover text notification '%s' to %s...", title, destination)
+	resp, err := config.pushoverClient().SendMessage(ctx, message, destination)
+	recordDelivery(ctx, config, message, destination, resp, err)
+	if err != nil {
+		return fmt.Errorf("failed to send Pushover notification: %w", err)
+	}
+	if resp.Status != 1 {
+		return fmt.Errorf("pushover API error for destination %s: status %d, errors: %v", destination, resp.Status, resp.Errors)
+	}
+	log.Infof("Pushover text notification sent successfully to %s. Message ID: %s", destination, resp.ID)
+	return nil
+}
+
+// ValidatePushoverDestination checks with the Pushover API that the destination is a valid user or group key
+// for the configured application. An invalid application key is reported the same way.
+func ValidatePushoverDestination(ctx context.Context, config *Config, destination string) error {
+	if config.currentPushoverAppKey() == "" {
+		return fmt.Errorf("pushover AppKey is missing from global config")
+	}
+	details, err := config.pushoverClient().GetRecipientDetails(ctx, destination)
+	if err != nil {
+		return fmt.Errorf("failed to validate Pushover destination %s: %w", destination, err)
+	}
+	if details.Status != 1 {
+		return fmt.Errorf("pushover rejected destination %s: status %d, errors: %v", destination, details.Status, details.Errors)
+	}
+	return nil
+}
+
+// CancelPushoverEmergency stops Pushover from retrying an unacknowledged emergency notification.
+func CancelPushoverEmergency(ctx context.Context, config *Config, receiptID string) error {
+	testHookPushoverSendCalled = true
+	if testHookDisablePushoverSend {
+		log.Debug("testHookDisablePushoverSend is true, faking successful Pushover cancellation.")
+		return nil
+	}
+	if config.currentPushoverAppKey() == "" {
+		return fmt.Errorf("pushover AppKey is missing from global config")
+	}
+	resp, err := config.pushoverClient().CancelEmergencyNotification(ctx, receiptID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel Pushover emergency %s: %w", receiptID, err)
+	}
+	if resp.Status != 1 {
+		return fmt.Errorf("pushover API error cancelling receipt %s: status %d, errors: %v", receiptID, resp.Status, resp.Errors)
+	}
+	return nil
+}
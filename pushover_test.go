@@ -0,0 +1,109 @@
+package discord2pushover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gregdel/pushover"
+)
+
+func TestSelectDiscordLinks(t *testing.T) {
+	webLink := "https://discord.com/channels/g1/c1/m1"
+	appLink := "discord://discord.com/channels/g1/c1/m1"
+
+	tests := []struct {
+		linkStyle         string
+		expectedPrimary   string
+		expectedSecondary string
+	}{
+		{"", webLink, ""},
+		{linkStyleWeb, webLink, ""},
+		{linkStyleApp, appLink, ""},
+		{linkStyleBoth, appLink, webLink},
+		{"bogus", webLink, ""},
+	}
+	for _, tt := range tests {
+		t.Run("style_"+tt.linkStyle, func(t *testing.T) {
+			primary, secondary := selectDiscordLinks(tt.linkStyle, webLink)
+			if primary != tt.expectedPrimary || secondary != tt.expectedSecondary {
+				t.Errorf("linkStyle %q: expected (%q, %q), got (%q, %q)", tt.linkStyle, tt.expectedPrimary, tt.expectedSecondary, primary, secondary)
+			}
+		})
+	}
+}
+
+func TestSendPushoverNotification_LinkPlacement(t *testing.T) {
+	link := "https://discord.com/channels/g1/c1/m1"
+	action := &RuleActions{PushoverDestination: "uUser"}
+	fake := &fakePushoverClient{}
+	config := &Config{PushoverAppKey: "app"}
+	config.SetPushoverClient(fake)
+	if _, err := SendPushoverNotification(context.Background(), config, action, nil, "Alert", "disk full", link); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	message := fake.sent[0]
+	if message.URL != link || message.URLTitle != defaultLinkTitle || message.Message != "disk full" {
+		t.Errorf("Expected the link as supplementary URL and a body without it, got URL %q, URLTitle %q, body %q", message.URL, message.URLTitle, message.Message)
+	}
+
+	config.LinkInBody = true
+	if _, err := SendPushoverNotification(context.Background(), config, action, nil, "Alert", "disk full", link); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	message = fake.sent[1]
+	if message.URL != "" || message.URLTitle != "" || !strings.HasSuffix(message.Message, "\n\nDiscord Link: "+link) {
+		t.Errorf("Expected the link in the body only with linkInBody, got URL %q, URLTitle %q, body %q", message.URL, message.URLTitle, message.Message)
+	}
+}
+
+func TestSendPushoverNotification_LongBodyKeepsLink(t *testing.T) {
+	link := "https://discord.com/channels/g1/c1/m1"
+	transcript := strings.Repeat("> earlier message in the channel\n", 100)
+	fake := &fakePushoverClient{}
+	config := &Config{PushoverAppKey: "app", LinkInBody: true}
+	config.SetPushoverClient(fake)
+	action := &RuleActions{PushoverDestination: "uUser", LinkStyle: linkStyleBoth}
+	if _, err := SendPushoverNotification(context.Background(), config, action, nil, "Alert", transcript, link); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	body := fake.sent[0].Message
+	if n := utf8.RuneCountInString(body); n > pushover.MessageMaxLength {
+		t.Errorf("Expected at most %d characters, got %d", pushover.MessageMaxLength, n)
+	}
+	if !strings.HasSuffix(body, "…\n\nDiscord Link: "+discordAppLink(link)+"\nWeb Link: "+link) {
+		t.Errorf("Expected the transcript truncated before the links, got %q", body)
+	}
+
+	config.LinkInBody = false
+	if _, err := SendPushoverNotification(context.Background(), config, action, nil, "Alert", transcript, link); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if body := fake.sent[1].Message; utf8.RuneCountInString(body) > pushover.MessageMaxLength || !strings.HasSuffix(body, "…\n\nWeb: "+link) {
+		t.Errorf("Expected the transcript truncated before the web link, got %q", body)
+	}
+}
+
+func TestEventContext_Timeout(t *testing.T) {
+	ctx, cancel := eventContext(&Config{EventTimeoutSeconds: 5})
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > 5*time.Second {
+		t.Errorf("Expected a deadline within 5s, got %v (%v)", deadline, ok)
+	}
+}
+
+func TestSendPushoverText_Truncated(t *testing.T) {
+	fake := &fakePushoverClient{}
+	config := &Config{PushoverAppKey: "app"}
+	config.SetPushoverClient(fake)
+	text := "Reloading the configuration failed: " + strings.Repeat("yaml: line 1: mapping values are not allowed here\n", 40)
+	if err := SendPushoverText(context.Background(), config, "uAdmin", "discord2pushover error", text, 1); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if n := utf8.RuneCountInString(fake.sent[0].Message); n != pushover.MessageMaxLength || !strings.HasPrefix(fake.sent[0].Message, "Reloading the configuration failed") {
+		t.Errorf("Expected the text truncated to %d characters, got %d", pushover.MessageMaxLength, n)
+	}
+}
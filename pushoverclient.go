@@ -0,0 +1,196 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gregdel/pushover"
+)
+
+// defaultHTTPTimeout bounds requests to the Pushover API when httpTimeoutSeconds is not set.
+const defaultHTTPTimeout = 10 * time.Second
+
+// pushoverAPIBase is the Pushover API endpoint unless pushoverApiBase is configured; tests point it at
+// a local server.
+var pushoverAPIBase = "https://api.pushover.net/1"
+
+// defaultPushoverHTTPClient serves configs not created by LoadConfig, e.g. in tests.
+var defaultPushoverHTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+
+// PushoverClient is the part of the Pushover API the bot uses. A config holds one client, created by
+// LoadConfig, for all sends and the acknowledgement poller; SetPushoverClient substitutes another.
+type PushoverClient interface {
+	SendMessage(ctx context.Context, message *pushover.Message, recipient string) (*pushover.Response, error)
+	GetReceiptDetails(ctx context.Context, receipt string) (*pushover.ReceiptDetails, error)
+	GetRecipientDetails(ctx context.Context, recipient string) (*pushover.RecipientDetails, error)
+	CancelEmergencyNotification(ctx context.Context, receipt string) (*pushover.Response, error)
+}
+
+// pushoverAPI talks to the Pushover API with a shared http.Client, so connections are reused across sends.
+type pushoverAPI struct {
+	mu         sync.RWMutex
+	token      string // Replaced by setToken when the app key is rotated
+	base       string
+	httpClient *http.Client
+}
+
+// NewPushoverClient returns a client for the application token that sends its requests with httpClient.
+func NewPushoverClient(token string, httpClient *http.Client) PushoverClient {
+	return NewPushoverClientWithBase(pushoverAPIBase, token, httpClient)
+}
+
+// NewPushoverClientWithBase returns a client like NewPushoverClient for the Pushover-compatible API at
+// baseURL, e.g. a proxy, a self-hosted server or a fake in tests.
+func NewPushoverClientWithBase(baseURL string, token string, httpClient *http.Client) PushoverClient {
+	return &pushoverAPI{token: token, base: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// pushoverBase returns the Pushover API endpoint of the config, without trailing slash.
+func (c *Config) pushoverBase() string {
+	if c.PushoverAPIBase != "" {
+		return strings.TrimSuffix(c.PushoverAPIBase, "/")
+	}
+	return pushoverAPIBase
+}
+
+// validatePushoverAPIBase checks that pushoverApiBase is an http(s) URL.
+func validatePushoverAPIBase(base string) error {
+	if base == "" {
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("pushoverApiBase %q is not an http or https URL", base)
+	}
+	return nil
+}
+
+// appToken returns the application token requests are sent with.
+func (p *pushoverAPI) appToken() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.token
+}
+
+// setToken replaces the application token for subsequent requests.
+func (p *pushoverAPI) setToken(token string) {
+	p.mu.Lock()
+	p.token = token
+	p.mu.Unlock()
+}
+
+// SetPushoverClient replaces the client used for the config's Pushover requests, e.g. with a fake in
+// tests or a client sharing the embedding program's transport.
+func (c *Config) SetPushoverClient(client PushoverClient) {
+	c.pushover = client
+}
+
+// pushoverClient returns the config's client, or one using the default HTTP client for configs
+// not created by LoadConfig.
+func (c *Config) pushoverClient() PushoverClient {
+	if c.pushover != nil {
+		return c.pushover
+	}
+	return &pushoverAPI{token: c.currentPushoverAppKey(), base: c.pushoverBase(), httpClient: defaultPushoverHTTPClient}
+}
+
+func (p *pushoverAPI) SendMessage(ctx context.Context, message *pushover.Message, recipient string) (*pushover.Response, error) {
+	form := url.Values{
+		"token":    {p.appToken()},
+		"user":     {recipient},
+		"message":  {message.Message},
+		"priority": {strconv.Itoa(message.Priority)},
+	}
+	optional := map[string]string{
+		"title":     message.Title,
+		"url":       message.URL,
+		"url_title": message.URLTitle,
+		"device":    message.DeviceName,
+		"sound":     message.Sound,
+	}
+	for key, value := range optional {
+		if value != "" {
+			form.Set(key, value)
+		}
+	}
+	if message.HTML {
+		form.Set("html", "1")
+	}
+	if message.Priority == pushover.PriorityEmergency {
+		form.Set("retry", strconv.Itoa(int(message.Retry.Seconds())))
+		form.Set("expire", strconv.Itoa(int(message.Expire.Seconds())))
+	}
+	var response pushover.Response
+	if err := p.do(ctx, http.MethodPost, "/messages.json", form, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (p *pushoverAPI) GetReceiptDetails(ctx context.Context, receipt string) (*pushover.ReceiptDetails, error) {
+	if receipt == "" {
+		return nil, pushover.ErrEmptyReceipt
+	}
+	var details pushover.ReceiptDetails
+	path := "/receipts/" + url.PathEscape(receipt) + ".json?" + url.Values{"token": {p.appToken()}}.Encode()
+	if err := p.do(ctx, http.MethodGet, path, nil, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+func (p *pushoverAPI) GetRecipientDetails(ctx context.Context, recipient string) (*pushover.RecipientDetails, error) {
+	var details pushover.RecipientDetails
+	if err := p.do(ctx, http.MethodPost, "/users/validate.json", url.Values{"token": {p.appToken()}, "user": {recipient}}, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+func (p *pushoverAPI) CancelEmergencyNotification(ctx context.Context, receipt string) (*pushover.Response, error) {
+	if receipt == "" {
+		return nil, pushover.ErrEmptyReceipt
+	}
+	var response pushover.Response
+	if err := p.do(ctx, http.MethodPost, "/receipts/"+url.PathEscape(receipt)+"/cancel.json", url.Values{"token": {p.appToken()}}, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// do sends a request to the API and decodes its JSON response into result. Rejected requests are
+// answered with a status of 0 and the reasons in errors, which the caller checks.
+func (p *pushoverAPI) do(ctx context.Context, method string, path string, form url.Values, result interface{}) error {
+	var request *http.Request
+	var err error
+	if form == nil {
+		request, err = http.NewRequestWithContext(ctx, method, p.base+path, nil)
+	} else {
+		request, err = http.NewRequestWithContext(ctx, method, p.base+path, strings.NewReader(form.Encode()))
+		if err == nil {
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: HTTP %d", pushover.ErrHTTPPushover, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode Pushover response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package discord2pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gregdel/pushover"
+	"github.com/user/discord2pushover/pushovertest"
+)
+
+// fakePushoverClient records the messages sent through it, and signals each on delivered if set.
+type fakePushoverClient struct {
+	mu         sync.Mutex
+	sent       []*pushover.Message
+	recipients []string
+	delivered  chan string
+}
+
+func (f *fakePushoverClient) SendMessage(ctx context.Context, message *pushover.Message, recipient string) (*pushover.Response, error) {
+	f.mu.Lock()
+	f.sent = append(f.sent, message)
+	f.recipients = append(f.recipients, recipient)
+	response := &pushover.Response{Status: 1, ID: fmt.Sprintf("req-%d", len(f.sent))}
+	f.mu.Unlock()
+	if f.delivered != nil {
+		f.delivered <- recipient
+	}
+	if message.Priority == pushover.PriorityEmergency {
+		response.Receipt = "receipt-1"
+	}
+	return response, nil
+}
+
+func (f *fakePushoverClient) GetReceiptDetails(ctx context.Context, receipt string) (*pushover.ReceiptDetails, error) {
+	return &pushover.ReceiptDetails{Status: 1}, nil
+}
+
+func (f *fakePushoverClient) GetRecipientDetails(ctx context.Context, recipient string) (*pushover.RecipientDetails, error) {
+	return &pushover.RecipientDetails{Status: 1}, nil
+}
+
+func (f *fakePushoverClient) CancelEmergencyNotification(ctx context.Context, receipt string) (*pushover.Response, error) {
+	return &pushover.Response{Status: 1}, nil
+}
+
+func TestSendPushoverNotification_Client(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	fake := &fakePushoverClient{}
+	config := &Config{PushoverAppKey: "app", LinkTitle: "Jump"}
+	config.SetPushoverClient(fake)
+	actions := &RuleActions{PushoverDestination: "team", Priority: 2, Emergency: &EmergencyParams{Retry: 60, Expire: 600}}
+	device := &DeviceVariant{Device: "phone", Sound: "siren"}
+
+	receipt, err := SendPushoverNotification(context.Background(), config, actions, device, "", "disk full", "https://discord.com/channels/g/c/m")
+	if err != nil || receipt != "receipt-1" {
+		t.Fatalf("Expected the emergency's receipt, got %q %v", receipt, err)
+	}
+	if len(fake.sent) != 1 || fake.recipients[0] != "team" {
+		t.Fatalf("Expected one message to team, got %v", fake.recipients)
+	}
+	m := fake.sent[0]
+	if m.Title != defaultNotificationTitle || m.Message != "disk full" || m.URLTitle != "Jump" || m.DeviceName != "phone" || m.Sound != "siren" ||
+		m.Priority != pushover.PriorityEmergency || m.Retry != time.Minute || m.Expire != 10*time.Minute {
+		t.Errorf("Unexpected message %+v", m)
+	}
+}
+
+func TestPushoverAPI(t *testing.T) {
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = map[string]string{}
+		for key := range r.Form {
+			form[key] = r.Form.Get(key)
+		}
+		switch r.URL.Path {
+		case "/messages.json":
+			fmt.Fprint(w, `{"status":1,"request":"req-1","receipt":"r1"}`)
+		case "/receipts/r1.json":
+			fmt.Fprint(w, `{"status":1,"acknowledged":1,"acknowledged_at":1700000000,"acknowledged_by":"u1","last_delivered_at":1700000000,`+
+				`"expired":0,"expires_at":1700003600,"called_back":0,"called_back_at":0}`)
+		case "/users/validate.json":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":0,"errors":["user key is invalid"]}`)
+		case "/slow.json":
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(base string) { pushoverAPIBase = base }(pushoverAPIBase)
+	pushoverAPIBase = server.URL
+
+	client := NewPushoverClient("app", server.Client())
+	message := &pushover.Message{Message: "hi", Title: "T", Priority: pushover.PriorityEmergency, Retry: time.Minute, Expire: time.Hour}
+	resp, err := client.SendMessage(context.Background(), message, "user")
+	if err != nil || resp.Receipt != "r1" {
+		t.Fatalf("SendMessage: got %+v %v", resp, err)
+	}
+	for key, want := range map[string]string{"token": "app", "user": "user", "message": "hi", "title": "T", "priority": "2", "retry": "60", "expire": "3600"} {
+		if form[key] != want {
+			t.Errorf("Expected %s=%q, got %q", key, want, form[key])
+		}
+	}
+
+	details, err := client.GetReceiptDetails(context.Background(), "r1")
+	if err != nil || !details.Acknowledged || details.AcknowledgedBy != "u1" || form["token"] != "app" {
+		t.Errorf("GetReceiptDetails: got %+v %v", details, err)
+	}
+
+	recipient, err := client.GetRecipientDetails(context.Background(), "bad")
+	if err != nil || recipient.Status != 0 || len(recipient.Errors) != 1 {
+		t.Errorf("Expected the rejection to be returned, got %+v %v", recipient, err)
+	}
+
+	// Cancellation aborts the request itself
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var response pushover.Response
+	if err := client.(*pushoverAPI).do(ctx, http.MethodGet, "/slow.json", nil, &response); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to abort the request, got %v", err)
+	}
+}
+
+func TestPushoverAPIBase_FakeServer(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	server := pushovertest.NewServer("app", "uOncall")
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("discordToken: d\npushoverAppKey: app\npushoverApiBase: "+server.APIBase()+"/\n"), 0o600)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := &RuleActions{PushoverDestination: "uOncall", Priority: 2, Emergency: &EmergencyParams{Retry: 60, Expire: 600}}
+	receipt, err := SendPushoverNotification(context.Background(), config, actions, nil, "Outage", "site down", "")
+	if err != nil || receipt == "" {
+		t.Fatalf("Expected the fake to accept the emergency, got %q %v", receipt, err)
+	}
+	if messages := server.Messages(); len(messages) != 1 || messages[0].Title != "Outage" || messages[0].Retry != time.Minute {
+		t.Errorf("Unexpected messages %+v", messages)
+	}
+
+	client := config.pushoverClient()
+	if details, err := client.GetReceiptDetails(context.Background(), receipt); err != nil || details.Acknowledged {
+		t.Errorf("Expected the emergency pending, got %+v %v", details, err)
+	}
+	server.Acknowledge(receipt, "uPhone")
+	if details, err := client.GetReceiptDetails(context.Background(), receipt); err != nil || !details.Acknowledged || details.AcknowledgedBy != "uPhone" {
+		t.Errorf("Expected the emergency acknowledged, got %+v %v", details, err)
+	}
+	if err := CancelPushoverEmergency(context.Background(), config, receipt); err != nil || !server.Cancelled(receipt) {
+		t.Errorf("Expected the emergency cancelled, got %v", err)
+	}
+	if err := ValidatePushoverDestination(context.Background(), config, "uStranger"); err == nil {
+		t.Error("Expected an unknown user key to be rejected")
+	}
+	if limits, err := fetchPushoverAppLimits(context.Background(), newHTTPClient(config), config.pushoverBase(), "app"); err != nil || limits.Remaining != pushovertest.MonthlyLimit-1 {
+		t.Errorf("Expected the quota of the fake, got %+v %v", limits, err)
+	}
+	embedded := NewPushoverClientWithBase(server.APIBase()+"/", "app", server.Client())
+	if details, err := embedded.GetRecipientDetails(context.Background(), "uOncall"); err != nil || details.Status != 1 {
+		t.Errorf("Expected the embedded client to use the fake, got %+v %v", details, err)
+	}
+
+	os.WriteFile(path, []byte("pushoverAppKey: app\npushoverApiBase: api.example.com\n"), 0o600)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "pushoverApiBase") {
+		t.Errorf("Expected an invalid pushoverApiBase to be rejected, got %v", err)
+	}
+}
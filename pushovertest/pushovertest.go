@@ -0,0 +1,295 @@
+// Package pushovertest provides an in-memory fake of the Pushover API for integration tests and local
+// development: it accepts messages, issues receipts for emergencies, which tests acknowledge with
+// Acknowledge, and cancels them. Point the bot's pushoverApiBase at the server's APIBase.
+package pushovertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MonthlyLimit is the message quota the fake reports for the app.
+const MonthlyLimit = 10000
+
+// Message is a message the fake accepted.
+type Message struct {
+	Request  string // Request ID returned for the message
+	Receipt  string // Receipt of an emergency
+	User     string
+	Device   string
+	Title    string
+	Message  string
+	Priority int
+	Sound    string
+	URL      string
+	URLTitle string
+	HTML     bool
+	Retry    time.Duration
+	Expire   time.Duration
+	Time     time.Time
+}
+
+// receipt is the state of an emergency.
+type receipt struct {
+	sent           time.Time
+	expiresAt      time.Time
+	acknowledgedAt time.Time
+	acknowledgedBy string
+	cancelled      bool
+}
+
+// Server is a fake Pushover API. Its methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+	appToken string
+	users    map[string]bool
+
+	mu       sync.Mutex
+	messages []Message
+	receipts map[string]*receipt
+	requests int
+}
+
+// NewServer starts a fake accepting requests with appToken, or any token if it is empty. With users,
+// only those user and group keys are valid; otherwise every key is. Close it when done.
+func NewServer(appToken string, users ...string) *Server {
+	s := &Server{appToken: appToken, receipts: make(map[string]*receipt)}
+	if len(users) > 0 {
+		s.users = make(map[string]bool)
+		for _, user := range users {
+			s.users[user] = true
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /1/messages.json", s.handleMessage)
+	mux.HandleFunc("POST /1/users/validate.json", s.handleValidate)
+	mux.HandleFunc("GET /1/apps/limits.json", s.handleLimits)
+	mux.HandleFunc("GET /1/receipts/{receipt}", s.handleReceipt)
+	mux.HandleFunc("POST /1/receipts/{receipt}/cancel.json", s.handleCancel)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// APIBase returns the URL to configure as pushoverApiBase.
+func (s *Server) APIBase() string {
+	return s.URL + "/1"
+}
+
+// Messages returns the messages accepted so far, in the order they were sent.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Acknowledge acknowledges an emergency as user, as if they tapped it on their device. It returns false
+// for unknown, cancelled, expired or already acknowledged receipts.
+func (s *Server) Acknowledge(receiptID string, user string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.receipts[receiptID]
+	if r == nil || r.cancelled || !r.acknowledgedAt.IsZero() || time.Now().After(r.expiresAt) {
+		return false
+	}
+	r.acknowledgedAt, r.acknowledgedBy = time.Now(), user
+	return true
+}
+
+// Cancelled reports whether an emergency was cancelled.
+func (s *Server) Cancelled(receiptID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.receipts[receiptID]
+	return r != nil && r.cancelled
+}
+
+// reply writes a response of the API: status 1 with the fields, or status 0 with errors and HTTP 400.
+func reply(w http.ResponseWriter, request string, fields map[string]any, errors ...string) {
+	body := map[string]any{"status": 1, "request": request}
+	for key, value := range fields {
+		body[key] = value
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(errors) > 0 {
+		body["status"], body["errors"] = 0, errors
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// nextRequest returns a new request ID.
+func (s *Server) nextRequest() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	return fmt.Sprintf("fake-request-%d", s.requests)
+}
+
+// checkToken returns the error for a missing or wrong application token.
+func (s *Server) checkToken(token string) []string {
+	if token == "" || (s.appToken != "" && token != s.appToken) {
+		return []string{"application token is invalid"}
+	}
+	return nil
+}
+
+// validUser reports whether a user or group key is valid.
+func (s *Server) validUser(user string) bool {
+	return user != "" && (s.users == nil || s.users[user])
+}
+
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
+	request := s.nextRequest()
+	r.ParseForm()
+	if errs := s.checkToken(r.PostForm.Get("token")); errs != nil {
+		reply(w, request, nil, errs...)
+		return
+	}
+	priority, _ := strconv.Atoi(r.PostForm.Get("priority"))
+	retry, _ := strconv.Atoi(r.PostForm.Get("retry"))
+	expire, _ := strconv.Atoi(r.PostForm.Get("expire"))
+	message := Message{
+		Request:  request,
+		User:     r.PostForm.Get("user"),
+		Device:   r.PostForm.Get("device"),
+		Title:    r.PostForm.Get("title"),
+		Message:  r.PostForm.Get("message"),
+		Priority: priority,
+		Sound:    r.PostForm.Get("sound"),
+		URL:      r.PostForm.Get("url"),
+		URLTitle: r.PostForm.Get("url_title"),
+		HTML:     r.PostForm.Get("html") == "1",
+		Retry:    time.Duration(retry) * time.Second,
+		Expire:   time.Duration(expire) * time.Second,
+		Time:     time.Now(),
+	}
+	var errs []string
+	if !s.validUser(message.User) {
+		errs = append(errs, "user identifier is not a valid user, group, or subscribed user key")
+	}
+	if strings.TrimSpace(message.Message) == "" {
+		errs = append(errs, "message cannot be blank")
+	}
+	if priority < -2 || priority > 2 {
+		errs = append(errs, "priority is invalid")
+	}
+	if priority == 2 {
+		if retry < 30 {
+			errs = append(errs, "retry must be at least 30 seconds")
+		}
+		if expire <= 0 || expire > 10800 {
+			errs = append(errs, "expire must be at most 10800 seconds")
+		}
+	}
+	if len(errs) > 0 {
+		reply(w, request, nil, errs...)
+		return
+	}
+	fields := map[string]any{}
+	s.mu.Lock()
+	if priority == 2 {
+		message.Receipt = fmt.Sprintf("fake-receipt-%d", len(s.receipts)+1)
+		s.receipts[message.Receipt] = &receipt{sent: message.Time, expiresAt: message.Time.Add(message.Expire)}
+		fields["receipt"] = message.Receipt
+	}
+	s.messages = append(s.messages, message)
+	s.mu.Unlock()
+	reply(w, request, fields)
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	request := s.nextRequest()
+	r.ParseForm()
+	if errs := s.checkToken(r.PostForm.Get("token")); errs != nil {
+		reply(w, request, nil, errs...)
+		return
+	}
+	if !s.validUser(r.PostForm.Get("user")) {
+		reply(w, request, nil, "user key is invalid")
+		return
+	}
+	reply(w, request, map[string]any{"group": 0, "devices": []string{"phone"}, "licenses": []string{"Android"}})
+}
+
+func (s *Server) handleLimits(w http.ResponseWriter, r *http.Request) {
+	request := s.nextRequest()
+	if errs := s.checkToken(r.URL.Query().Get("token")); errs != nil {
+		reply(w, request, nil, errs...)
+		return
+	}
+	s.mu.Lock()
+	remaining := MonthlyLimit - len(s.messages)
+	s.mu.Unlock()
+	now := time.Now()
+	reset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	reply(w, request, map[string]any{"limit": MonthlyLimit, "remaining": remaining, "reset": reset.Unix()})
+}
+
+// unix returns the Unix time the API reports, 0 for times that did not happen.
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// boolInt returns the 0 or 1 the API reports for booleans.
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (s *Server) handleReceipt(w http.ResponseWriter, r *http.Request) {
+	request := s.nextRequest()
+	if errs := s.checkToken(r.URL.Query().Get("token")); errs != nil {
+		reply(w, request, nil, errs...)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := s.receipts[strings.TrimSuffix(r.PathValue("receipt"), ".json")]
+	if rec == nil {
+		reply(w, request, nil, "receipt not found; may be invalid or expired")
+		return
+	}
+	now := time.Now()
+	expired := rec.cancelled || (rec.acknowledgedAt.IsZero() && now.After(rec.expiresAt))
+	reply(w, request, map[string]any{
+		"acknowledged":      boolInt(!rec.acknowledgedAt.IsZero()),
+		"acknowledged_at":   unix(rec.acknowledgedAt),
+		"acknowledged_by":   rec.acknowledgedBy,
+		"last_delivered_at": unix(rec.sent),
+		"expired":           boolInt(expired),
+		"expires_at":        rec.expiresAt.Unix(),
+		"called_back":       0,
+		"called_back_at":    0,
+	})
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	request := s.nextRequest()
+	r.ParseForm()
+	if errs := s.checkToken(r.PostForm.Get("token")); errs != nil {
+		reply(w, request, nil, errs...)
+		return
+	}
+	s.mu.Lock()
+	rec := s.receipts[r.PathValue("receipt")]
+	if rec != nil {
+		rec.cancelled = true
+	}
+	s.mu.Unlock()
+	if rec == nil {
+		reply(w, request, nil, "receipt not found; may be invalid or expired")
+		return
+	}
+	reply(w, request, nil)
+}
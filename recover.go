@@ -0,0 +1,60 @@
+package discord2pushover
+
+import (
+	"expvar"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// panicsRecovered counts panics caught by recoverPanic since startup.
+var panicsRecovered = new(expvar.Int)
+
+// sentryEnabled is set once Sentry has been initialised from the sentryDsn config key.
+var sentryEnabled bool
+
+// initSentry initialises the Sentry client if a DSN is configured. Failure to initialise is logged, not fatal.
+func initSentry(config *Config) {
+	if config == nil || config.SentryDSN == "" {
+		return
+	}
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:     config.SentryDSN,
+		Release: fmt.Sprintf("discord2pushover@%s", Version),
+	})
+	if err != nil {
+		log.Errorf("Error initialising Sentry, panics will only be logged: %v", err)
+		return
+	}
+	sentryEnabled = true
+	log.Info("Sentry panic reporting enabled.")
+}
+
+// flushSentry waits briefly for buffered Sentry events to be delivered before exit.
+func flushSentry() {
+	if sentryEnabled {
+		sentry.Flush(2 * time.Second)
+	}
+}
+
+// recoverPanic recovers from a panic in the calling goroutine, logs it with a stack trace,
+// counts it and reports it to Sentry when enabled. It must be deferred directly:
+//
+//	defer recoverPanic("messageCreate")
+func recoverPanic(where string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	panicsRecovered.Add(1)
+	log.Errorf("Recovered from panic in %s: %v\n%s", where, r, debug.Stack())
+
+	if sentryEnabled {
+		hub := sentry.CurrentHub().Clone()
+		hub.Scope().SetTag("handler", where)
+		hub.Recover(r)
+		hub.Flush(2 * time.Second)
+	}
+}
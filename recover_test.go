@@ -0,0 +1,29 @@
+package discord2pushover
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	before := panicsRecovered.Value()
+	func() {
+		defer recoverPanic("TestRecoverPanic")
+		var m map[string]int
+		m["boom"] = 1 // nil map write panics
+	}()
+
+	if got := panicsRecovered.Value(); got != before+1 {
+		t.Errorf("Expected panics_recovered to be %d, got %d", before+1, got)
+	}
+	output := testLogBufferForTest.String()
+	if !strings.Contains(output, "Recovered from panic in TestRecoverPanic") {
+		t.Errorf("Expected recovery log not found. Log: %s", output)
+	}
+	if !strings.Contains(output, "goroutine") {
+		t.Errorf("Expected stack trace in recovery log. Log: %s", output)
+	}
+}
@@ -0,0 +1,176 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// reminderCheckInterval is how often due reminders are checked.
+const reminderCheckInterval = 30 * time.Second
+
+// pendingReminder is a follow-up notification for a matched message, sent if nobody has reacted to
+// or replied to the message by Due. It is persisted in the state file.
+type pendingReminder struct {
+	RuleName     string    `json:"rule"`
+	GuildID      string    `json:"guildId,omitempty"`
+	ChannelID    string    `json:"channelId"`
+	MessageID    string    `json:"messageId"`
+	Destination  string    `json:"destination,omitempty"` // The rule's Pushover destination when it notified
+	Notify       []string  `json:"notify,omitempty"`
+	Priority     int       `json:"priority"` // Priority of the original notification
+	AfterMinutes int       `json:"afterMinutes"`
+	Due          time.Time `json:"due"`
+}
+
+func reminderKey(ruleName string, messageID string) string {
+	return ruleName + "\x00" + messageID
+}
+
+// addReminder stores a reminder, replacing an earlier one of the rule for the same message.
+func (c *checkpointStore) addReminder(reminder pendingReminder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reminders == nil {
+		c.reminders = make(map[string]pendingReminder)
+	}
+	c.reminders[reminderKey(reminder.RuleName, reminder.MessageID)] = reminder
+	c.dirty = true
+}
+
+// takeDueReminders removes and returns the reminders due at now.
+func (c *checkpointStore) takeDueReminders(now time.Time) []pendingReminder {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var due []pendingReminder
+	for key, reminder := range c.reminders {
+		if !now.Before(reminder.Due) {
+			due = append(due, reminder)
+			delete(c.reminders, key)
+			c.dirty = true
+		}
+	}
+	return due
+}
+
+// scheduleReminder remembers to remind the rule's destinations about message after the actions'
+// remindAfterMinutes, unless someone handles it first.
+func scheduleReminder(actions *RuleActions, ruleNameLog string, message *discordgo.Message, now time.Time) {
+	after := time.Duration(actions.RemindAfterMinutes) * time.Minute
+	checkpoints.addReminder(pendingReminder{
+		RuleName:     ruleNameLog,
+		GuildID:      message.GuildID,
+		ChannelID:    message.ChannelID,
+		MessageID:    message.ID,
+		Destination:  actions.PushoverDestination,
+		Notify:       actions.Notify,
+		Priority:     actions.Priority,
+		AfterMinutes: actions.RemindAfterMinutes,
+		Due:          now.Add(after),
+	})
+	log.Debugf("Rule '%s': reminder for message ID %s scheduled in %s unless someone reacts or replies.", ruleNameLog, message.ID, after)
+}
+
+// usesReminders reports whether any rule has remindAfterMinutes.
+func usesReminders(config *Config) bool {
+	for i := range config.Rules {
+		if config.Rules[i].Actions.RemindAfterMinutes > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOtherReaction reports whether anyone other than the bot reacted to message.
+func hasOtherReaction(message *discordgo.Message) bool {
+	for _, reaction := range message.Reactions {
+		others := reaction.Count
+		if reaction.Me {
+			others--
+		}
+		if others > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReply reports whether a message posted after message in its channel replies to it. Only the 100
+// messages following it are checked.
+func hasReply(session DiscordSessionInterface, message *discordgo.Message) (bool, error) {
+	later, err := session.ChannelMessages(message.ChannelID, 100, "", message.ID, "")
+	if err != nil {
+		return false, err
+	}
+	for _, m := range later {
+		if m.MessageReference != nil && m.MessageReference.MessageID == message.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sendReminder checks whether the reminded message is still unhandled and, if so, notifies the
+// reminder's destinations one priority level higher, up to high priority.
+func sendReminder(ctx context.Context, session DiscordSessionInterface, config *Config, reminder pendingReminder) {
+	message, err := session.ChannelMessage(reminder.ChannelID, reminder.MessageID)
+	if err != nil {
+		log.Warnf("Reminder of rule '%s': cannot fetch message ID %s, dropping the reminder: %v", reminder.RuleName, reminder.MessageID, err)
+		return
+	}
+	if message.GuildID == "" {
+		message.GuildID = reminder.GuildID // Not set on fetched messages, but needed for the link
+	}
+	if hasOtherReaction(message) {
+		log.Infof("Reminder of rule '%s': message ID %s has reactions; not reminding.", reminder.RuleName, reminder.MessageID)
+		return
+	}
+	replied, err := hasReply(session, message)
+	if err != nil {
+		log.Warnf("Reminder of rule '%s': cannot check replies to message ID %s: %v", reminder.RuleName, reminder.MessageID, err)
+	}
+	if replied {
+		log.Infof("Reminder of rule '%s': message ID %s has replies; not reminding.", reminder.RuleName, reminder.MessageID)
+		return
+	}
+
+	priority := min(reminder.Priority+1, 1)
+	title := "Still unhandled: " + reminder.RuleName
+	text := fmt.Sprintf("Still unhandled after %s: nobody reacted to or replied to this message.\n%.200s\n%s",
+		time.Duration(reminder.AfterMinutes)*time.Minute, message.Content, discordMessageLink(message))
+	log.Infof("Reminder of rule '%s': message ID %s is still unhandled after %d minutes; reminding.", reminder.RuleName, reminder.MessageID, reminder.AfterMinutes)
+	if reminder.Destination != "" {
+		err := SendPushoverText(ctx, config, reminder.Destination, title, text, priority)
+		reportPushoverResult(session, config, err)
+		if err != nil {
+			log.Errorf("Error sending reminder of rule '%s' for message ID %s: %v", reminder.RuleName, reminder.MessageID, err)
+		}
+	}
+	if len(reminder.Notify) > 0 {
+		notification := &Notification{RuleName: reminder.RuleName, Title: title, Body: text, Link: discordMessageLink(message), Priority: priority}
+		if err := notifyBackends(ctx, config, reminder.Notify, notification); err != nil {
+			log.Errorf("Error sending reminder of rule '%s' for message ID %s: %v", reminder.RuleName, reminder.MessageID, err)
+		}
+	}
+}
+
+// pollReminders sends due reminders until ctx is done.
+func pollReminders(ctx context.Context, session DiscordSessionInterface, config *Config) {
+	defer recoverPanic("pollReminders")
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	log.Infof("Checking reminders every %s...", reminderCheckInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, reminder := range checkpoints.takeDueReminders(time.Now()) {
+			sendReminder(ctx, session, config, reminder)
+		}
+	}
+}
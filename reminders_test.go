@@ -0,0 +1,102 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestReminders(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	saved := checkpoints
+	checkpoints = &checkpointStore{channels: make(map[string]string), subscribers: make(map[string]string)}
+	defer func() { checkpoints = saved }()
+
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		Rules: []Rule{{
+			Name:       "Support",
+			Conditions: RuleConditions{ChannelID: "support"},
+			Actions:    RuleActions{PushoverDestination: "uKey", RemindAfterMinutes: 30},
+		}},
+	}
+	config.SetPushoverClient(fake)
+	message := &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "support", Content: "login is broken", Author: &discordgo.User{ID: "dave"}}
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	if len(fake.sent) != 1 {
+		t.Fatalf("Expected the match to notify, got %d notifications", len(fake.sent))
+	}
+
+	if due := checkpoints.takeDueReminders(time.Now().Add(29 * time.Minute)); len(due) != 0 {
+		t.Fatalf("Expected no reminder due before remindAfterMinutes, got %d", len(due))
+	}
+	due := checkpoints.takeDueReminders(time.Now().Add(31 * time.Minute))
+	if len(due) != 1 || due[0].MessageID != "m1" || due[0].Destination != "uKey" {
+		t.Fatalf("Expected the reminder of m1 to be due, got %+v", due)
+	}
+
+	tests := []struct {
+		name      string
+		reactions []*discordgo.MessageReactions
+		later     []*discordgo.Message
+		remind    bool
+	}{
+		{"Unhandled", []*discordgo.MessageReactions{{Count: 1, Me: true, Emoji: &discordgo.Emoji{Name: "👀"}}}, []*discordgo.Message{{ID: "m2"}}, true},
+		{"Reacted to", []*discordgo.MessageReactions{{Count: 2, Me: true, Emoji: &discordgo.Emoji{Name: "👀"}}}, nil, false},
+		{"Replied to", nil, []*discordgo.Message{{ID: "m2", MessageReference: &discordgo.MessageReference{MessageID: "m1"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.sent, fake.recipients = nil, nil
+			session := &MockDiscordSession{
+				Session: &discordgo.Session{},
+				CustomChannelMessageFunc: func(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+					return &discordgo.Message{ID: messageID, ChannelID: channelID, Content: "login is broken", Reactions: tt.reactions}, nil
+				},
+				CustomChannelMessagesFunc: func(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error) {
+					return tt.later, nil
+				},
+			}
+			sendReminder(context.Background(), session, config, due[0])
+			if !tt.remind {
+				if len(fake.sent) != 0 {
+					t.Errorf("Expected no reminder, got %d notifications", len(fake.sent))
+				}
+				return
+			}
+			if len(fake.sent) != 1 || fake.sent[0].Priority != 1 || !strings.Contains(fake.sent[0].Message, "after 30m0s") ||
+				!strings.Contains(fake.sent[0].Message, "https://discord.com/channels/g1/support/m1") {
+				t.Errorf("Expected a high-priority reminder linking the message, got %+v", fake.sent)
+			}
+		})
+	}
+}
+
+func TestRemindersPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := &checkpointStore{}
+	if err := store.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	due := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.addReminder(pendingReminder{RuleName: "Support", ChannelID: "support", MessageID: "m1", Destination: "uKey", AfterMinutes: 30, Due: due})
+	if err := store.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	restarted := &checkpointStore{}
+	if err := restarted.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	reminders := restarted.takeDueReminders(due)
+	if len(reminders) != 1 || reminders[0].Destination != "uKey" || !reminders[0].Due.Equal(due) {
+		t.Errorf("Expected the reminder to survive a restart, got %+v", reminders)
+	}
+}
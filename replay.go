@@ -0,0 +1,274 @@
+package discord2pushover
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gregdel/pushover"
+)
+
+// Settings of the --record, --replay, --replay-speed and --mock-notifiers flags.
+var (
+	recordFile    string
+	replayFile    string
+	replaySpeed   float64
+	mockNotifiers bool
+)
+
+// replayRedacted replaces the text of recorded events.
+const replayRedacted = "[redacted]"
+
+// replayEvent is a line of a recording: a gateway event as Discord sent it, with the time it was received.
+type replayEvent struct {
+	Time time.Time       `json:"time"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// replayHandlers feed the data of each recorded event type through the same logic as the live handlers.
+var replayHandlers = map[string]func(ctx context.Context, s DiscordSessionInterface, data []byte) error{
+	"MESSAGE_CREATE": func(ctx context.Context, s DiscordSessionInterface, data []byte) error {
+		var m discordgo.MessageCreate
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		if m.Message == nil || m.Author == nil || m.Author.ID == s.State().User.ID {
+			return nil
+		}
+		messageCreateLogic(ctx, s, &m)
+		return nil
+	},
+	"MESSAGE_UPDATE": func(ctx context.Context, s DiscordSessionInterface, data []byte) error {
+		var m discordgo.MessageUpdate
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		messageUpdateLogic(ctx, s, &m)
+		return nil
+	},
+	"MESSAGE_REACTION_ADD": func(ctx context.Context, s DiscordSessionInterface, data []byte) error {
+		var r discordgo.MessageReactionAdd
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		messageReactionAddLogic(ctx, s, &r)
+		return nil
+	},
+	"MESSAGE_REACTION_REMOVE": func(ctx context.Context, s DiscordSessionInterface, data []byte) error {
+		var r discordgo.MessageReactionRemove
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		messageReactionRemoveLogic(ctx, s, &r)
+		return nil
+	},
+	"MESSAGE_DELETE": func(ctx context.Context, s DiscordSessionInterface, data []byte) error {
+		var m struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		messageDeleteLogic(m.ID)
+		return nil
+	},
+}
+
+// redactedFields are the keys of text users wrote, or that identifies them, removed from recordings.
+// IDs and emoji names are kept, so recordings still exercise rules matching on them.
+var redactedFields = map[string]bool{
+	"content": true, "description": true, "title": true, "value": true, "text": true,
+	"username": true, "global_name": true, "nick": true, "filename": true, "url": true, "proxy_url": true,
+}
+
+// redactEvent replaces the strings of redactedFields anywhere in an event's data.
+func redactEvent(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if s, ok := field.(string); ok && redactedFields[key] && s != "" {
+				v[key] = replayRedacted
+			} else {
+				v[key] = redactEvent(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactEvent(item)
+		}
+	}
+	return value
+}
+
+// eventRecorder appends the events the bot can replay to a recording.
+type eventRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// newEventRecorder opens a recording for appending.
+func newEventRecorder(path string) (*eventRecorder, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	return &eventRecorder{file: file}, nil
+}
+
+// onEvent is the discordgo handler receiving every gateway event.
+func (r *eventRecorder) onEvent(s *discordgo.Session, e *discordgo.Event) {
+	if replayHandlers[e.Type] == nil {
+		return
+	}
+	// The bot's own messages and reactions are ignored by the handlers, and would not be in a replay,
+	// which runs as a different bot user
+	var origin struct {
+		Author *discordgo.User `json:"author"`
+		UserID string          `json:"user_id"`
+	}
+	if err := json.Unmarshal(e.RawData, &origin); err != nil {
+		return
+	}
+	if s.State != nil && s.State.User != nil {
+		botID := s.State.User.ID
+		if origin.UserID == botID || (origin.Author != nil && origin.Author.ID == botID) {
+			return
+		}
+	}
+	var data any
+	if err := json.Unmarshal(e.RawData, &data); err != nil {
+		return
+	}
+	redacted, err := json.Marshal(redactEvent(data))
+	if err != nil {
+		return
+	}
+	line, err := json.Marshal(replayEvent{Time: time.Now(), Type: e.Type, Data: redacted})
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		log.Errorf("Error writing recording %s: %v", r.file.Name(), err)
+	}
+}
+
+// Close closes the recording.
+func (r *eventRecorder) Close() error {
+	return r.file.Close()
+}
+
+// loggingPushoverClient accepts every notification without sending it, for replays against mocked
+// notifiers.
+type loggingPushoverClient struct {
+	mu   sync.Mutex
+	sent int
+}
+
+func (c *loggingPushoverClient) SendMessage(ctx context.Context, message *pushover.Message, recipient string) (*pushover.Response, error) {
+	c.mu.Lock()
+	c.sent++
+	id := c.sent
+	c.mu.Unlock()
+	log.Infof("Mock Pushover: %q to %s (device %q, priority %d): %s", message.Title, recipient, message.DeviceName, message.Priority, message.Message)
+	response := &pushover.Response{Status: 1, ID: fmt.Sprintf("mock-%d", id)}
+	if message.Priority == pushover.PriorityEmergency {
+		response.Receipt = fmt.Sprintf("mock-receipt-%d", id)
+	}
+	return response, nil
+}
+
+func (c *loggingPushoverClient) GetReceiptDetails(ctx context.Context, receipt string) (*pushover.ReceiptDetails, error) {
+	return &pushover.ReceiptDetails{Status: 1}, nil
+}
+
+func (c *loggingPushoverClient) GetRecipientDetails(ctx context.Context, recipient string) (*pushover.RecipientDetails, error) {
+	return &pushover.RecipientDetails{Status: 1}, nil
+}
+
+func (c *loggingPushoverClient) CancelEmergencyNotification(ctx context.Context, receipt string) (*pushover.Response, error) {
+	log.Infof("Mock Pushover: cancelled %s", receipt)
+	return &pushover.Response{Status: 1}, nil
+}
+
+// replayEvents feeds the events of a recording through the rules, waiting between them for the time
+// that passed between them when they were recorded, divided by speed. With speed 0 they are fed as fast
+// as possible. Discord is not connected: the session is the offline one of the rule tests, so reactions
+// and replies of the bot fail and are logged. It returns the number of events replayed.
+func replayEvents(ctx context.Context, config *Config, r io.Reader, speed float64) (int, error) {
+	session := newRuleTestSession()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	var last time.Time
+	replayed := 0
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event replayEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return replayed, fmt.Errorf("line %d: %w", line, err)
+		}
+		handler := replayHandlers[event.Type]
+		if handler == nil {
+			log.Warnf("Replay: skipping line %d, %s events cannot be replayed.", line, event.Type)
+			continue
+		}
+		if speed > 0 && !last.IsZero() && event.Time.After(last) {
+			select {
+			case <-ctx.Done():
+				return replayed, ctx.Err()
+			case <-time.After(time.Duration(float64(event.Time.Sub(last)) / speed)):
+			}
+		}
+		if !event.Time.IsZero() {
+			last = event.Time
+		}
+		log.Debugf("Replay: line %d, %s.", line, event.Type)
+		eventCtx, cancel := eventContext(config)
+		err := handler(eventCtx, session, event.Data)
+		cancel()
+		if err != nil {
+			return replayed, fmt.Errorf("line %d: invalid %s event: %w", line, event.Type, err)
+		}
+		replayed++
+	}
+	return replayed, scanner.Err()
+}
+
+// runReplay replays the recording of --replay and returns the exit code.
+func runReplay(config *Config, path string, speed float64, mock bool) int {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Errorf("Error opening recording: %v", err)
+		return 1
+	}
+	defer file.Close()
+	if mock {
+		config.SetPushoverClient(&loggingPushoverClient{})
+		log.Info("Replay: notifications are logged, not sent.")
+	} else if config.PushoverAppKey == "" {
+		log.Error("PushoverAppKey is missing from the configuration; replay with --mock-notifiers to log notifications instead.")
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	runContext = ctx
+	replayed, err := replayEvents(ctx, config, file, speed)
+	if err != nil {
+		log.Errorf("Replay of %s stopped after %d events: %v", path, replayed, err)
+		return 1
+	}
+	log.Infof("Replayed %d events from %s.", replayed, path)
+	return 0
+}
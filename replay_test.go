@@ -0,0 +1,75 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestReplayEvents(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		Rules: []Rule{
+			{Name: "Outage", Conditions: RuleConditions{ContentIncludes: []string{"down"}}, Actions: RuleActions{PushoverDestination: "uOncall"}},
+		},
+	}
+	config.SetPushoverClient(fake)
+	globalConfig = config
+
+	recording := strings.Join([]string{
+		`{"time":"2026-01-01T10:00:00Z","type":"MESSAGE_CREATE","data":{"id":"m1","channel_id":"c1","content":"site down","author":{"id":"dave"}}}`,
+		`{"time":"2026-01-01T10:00:01Z","type":"TYPING_START","data":{}}`,
+		`{"time":"2026-01-01T10:00:02Z","type":"MESSAGE_CREATE","data":{"id":"m2","channel_id":"c1","content":"all good","author":{"id":"dave"}}}`,
+		`{"time":"2026-01-01T10:00:03Z","type":"MESSAGE_CREATE","data":{"id":"m3","channel_id":"c1","content":"down again","author":{"id":"bot"}}}`,
+		`{"time":"2026-01-01T10:00:04Z","type":"MESSAGE_DELETE","data":{"id":"m1","channel_id":"c1"}}`,
+	}, "\n")
+	replayed, err := replayEvents(context.Background(), config, strings.NewReader(recording), 0)
+	if err != nil || replayed != 4 {
+		t.Fatalf("Expected 4 events replayed, got %d (err %v)", replayed, err)
+	}
+	if len(fake.sent) != 1 || fake.recipients[0] != "uOncall" {
+		t.Errorf("Expected only the outage notified, got %d notifications to %v", len(fake.sent), fake.recipients)
+	}
+
+	if _, err := replayEvents(context.Background(), config, strings.NewReader(`{"type":"MESSAGE_CREATE","data":[]}`), 0); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an error for the invalid event, got %v", err)
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recorder, err := newEventRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot"}
+	recorder.onEvent(session, &discordgo.Event{Type: "MESSAGE_CREATE", RawData: json.RawMessage(`{"id":"m1","content":"the password is hunter2","author":{"id":"dave","username":"dave"},"embeds":[{"title":"secret","color":255}]}`)})
+	recorder.onEvent(session, &discordgo.Event{Type: "MESSAGE_CREATE", RawData: json.RawMessage(`{"id":"m2","content":"bot reply","author":{"id":"bot"}}`)})
+	recorder.onEvent(session, &discordgo.Event{Type: "MESSAGE_REACTION_ADD", RawData: json.RawMessage(`{"message_id":"m1","user_id":"erin","emoji":{"name":"✅"}}`)})
+	recorder.onEvent(session, &discordgo.Event{Type: "GUILD_CREATE", RawData: json.RawMessage(`{"id":"g1"}`)})
+	recorder.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected the other user's message and reaction recorded, got %q", data)
+	}
+	if strings.Contains(lines[0], "hunter2") || strings.Contains(lines[0], "secret") || strings.Contains(lines[0], `"username":"dave"`) {
+		t.Errorf("Expected the text redacted, got %s", lines[0])
+	}
+	if !strings.Contains(lines[0], `"id":"dave"`) || !strings.Contains(lines[0], `"color":255`) || !strings.Contains(lines[1], `"name":"✅"`) {
+		t.Errorf("Expected IDs and emoji kept, got %q", lines)
+	}
+}
@@ -0,0 +1,99 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// regexCache holds compiled patterns from the config, keyed by pattern string.
+var regexCache sync.Map
+
+// compiledPattern returns the compiled regular expression for pattern, compiling it once.
+func compiledPattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+// resolveReferencedMessage returns the parent message if message is a reply, fetching it when the
+// event did not include it. The result is cached on message.ReferencedMessage. Returns nil for non-replies.
+func resolveReferencedMessage(session DiscordSessionInterface, message *discordgo.Message) *discordgo.Message {
+	if message.ReferencedMessage != nil {
+		return message.ReferencedMessage
+	}
+	ref := message.MessageReference
+	if ref == nil || ref.MessageID == "" || message.Type != discordgo.MessageTypeReply {
+		return nil
+	}
+	channelID := ref.ChannelID
+	if channelID == "" {
+		channelID = message.ChannelID
+	}
+	parent, err := session.ChannelMessage(channelID, ref.MessageID)
+	if err != nil {
+		log.Errorf("Error fetching replied-to message %s (channel %s) for message %s: %v", ref.MessageID, channelID, message.ID, err)
+		return nil
+	}
+	message.ReferencedMessage = parent
+	return parent
+}
+
+// checkReplyCondition reports whether message is a reply whose parent matches cond.
+func checkReplyCondition(session DiscordSessionInterface, message *discordgo.Message, cond *ReplyCondition, logPrefix string) bool {
+	parent := resolveReferencedMessage(session, message)
+	if parent == nil {
+		log.Debugf(logPrefix + "Condition failed (IsReplyTo): message is not a reply or parent is unavailable.")
+		return false
+	}
+
+	if len(cond.AuthorIDs) > 0 {
+		authorMatched := false
+		if parent.Author != nil {
+			for _, id := range cond.AuthorIDs {
+				if parent.Author.ID == id {
+					authorMatched = true
+					break
+				}
+			}
+		}
+		if !authorMatched {
+			log.Debugf(logPrefix+"Condition failed (IsReplyTo): parent message %s author is not one of %v.", parent.ID, cond.AuthorIDs)
+			return false
+		}
+	}
+
+	if cond.ContentPattern != "" {
+		re, err := compiledPattern(cond.ContentPattern)
+		if err != nil {
+			log.Errorf(logPrefix+"Invalid IsReplyTo contentPattern '%s': %v. Condition will fail.", cond.ContentPattern, err)
+			return false
+		}
+		if !re.MatchString(parent.Content) {
+			log.Debugf(logPrefix+"Condition failed (IsReplyTo): parent message %s content does not match '%s'.", parent.ID, cond.ContentPattern)
+			return false
+		}
+	}
+
+	log.Debugf(logPrefix+"Condition passed (IsReplyTo): message replies to %s.", parent.ID)
+	return true
+}
+
+// formatReplyParent renders the parent of a reply as a single line for inclusion in notifications.
+func formatReplyParent(parent *discordgo.Message) string {
+	author := "unknown"
+	if parent.Author != nil {
+		author = parent.Author.Username
+	}
+	content := strings.Join(strings.Fields(parent.Content), " ")
+	return truncateRunes(fmt.Sprintf("In reply to %s: %s", author, content), 2*contextLineMaxRunes)
+}
@@ -0,0 +1,109 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// alertFingerprint renders a rule's resolveOn fingerprint for a message, defaulting to its correlation key.
+// Alerts and their resolutions must render to the same string to be paired.
+func alertFingerprint(rule *Rule, ruleNameLog string, message *discordgo.Message) string {
+	if rule.ResolveOn == nil {
+		return ""
+	}
+	if rule.ResolveOn.Fingerprint == "" {
+		return correlationKey(rule, ruleNameLog, ruleEventMessage, message)
+	}
+	data := newNotificationData(rule, ruleNameLog, ruleEventMessage, message, message.Content, discordMessageLink(message))
+	fingerprint, err := renderTemplate(rule.ResolveOn.Fingerprint, rule.Actions.TemplateDefinitions, data)
+	if err != nil {
+		log.Errorf("Rule '%s': resolveOn fingerprint %v", ruleNameLog, err)
+		return ""
+	}
+	return strings.TrimSpace(fingerprint)
+}
+
+// resolveAlerts checks whether message resolves pending emergency notifications of any rule with a
+// resolveOn block. Matching receipts in the same channel with the same fingerprint are cancelled
+// and the original alert messages get the rule's resolvedEmoji; notifications still held by
+// delaySeconds are dropped. For rules with a correlationKey the incident is closed as well. Returns the number of alerts resolved.
+func resolveAlerts(ctx context.Context, message *discordgo.Message, config *Config, session DiscordSessionInterface) int {
+	resolved := 0
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.ResolveOn == nil {
+			continue
+		}
+		ruleNameLog := rule.Name
+		if ruleNameLog == "" {
+			ruleNameLog = fmt.Sprintf("unnamed_rule_%d", i+1)
+		}
+		if rule.ResolveOn.ContentPattern == "" {
+			log.Warnf("Rule '%s' has a resolveOn block without contentPattern; ignoring it.", ruleNameLog)
+			continue
+		}
+		re, err := compiledPattern(rule.ResolveOn.ContentPattern)
+		if err != nil {
+			log.Errorf("Rule '%s': invalid resolveOn contentPattern %q: %v", ruleNameLog, rule.ResolveOn.ContentPattern, err)
+			continue
+		}
+		if !re.MatchString(messageSearchText(message)) {
+			continue
+		}
+		fingerprint := alertFingerprint(rule, ruleNameLog, message)
+		marked := make(map[string]bool) // Alert messages already marked as resolved
+		log.Debugf("Message ID %s matches resolveOn of rule '%s' (fingerprint %q).", message.ID, ruleNameLog, fingerprint)
+
+		config.trackedEmergencies().Range(func(key, value interface{}) bool {
+			receiptID := key.(string)
+			trackedMsg, ok := value.(TrackedEmergencyMessage)
+			if !ok || trackedMsg.RuleName != ruleNameLog || trackedMsg.DiscordChannelID != message.ChannelID ||
+				trackedMsg.Fingerprint != fingerprint || trackedMsg.DiscordMessageID == message.ID {
+				return true
+			}
+			if _, loaded := config.trackedEmergencies().LoadAndDelete(receiptID); !loaded {
+				return true // Acknowledged or resolved concurrently
+			}
+			errCancel := CancelPushoverEmergency(ctx, config, receiptID)
+			reportPushoverResult(session, config, errCancel)
+			if errCancel != nil {
+				log.Errorf("Error cancelling resolved emergency (Receipt: %s, DiscordMsg: %s): %v", receiptID, trackedMsg.DiscordMessageID, errCancel)
+			} else {
+				log.Infof("Emergency (Receipt: %s, DiscordMsg: %s) resolved by message ID %s; cancelled.", receiptID, trackedMsg.DiscordMessageID, message.ID)
+			}
+			removeBotReaction(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, trackedMsg.PendingEmoji)
+			finishEmergencyStatus(session, trackedMsg, "✅ Resolved")
+			clearEmergencyCountdown(session, trackedMsg)
+			addResolvedEmoji(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, trackedMsg.ResolvedEmoji)
+			marked[trackedMsg.DiscordMessageID] = true
+			resolved++
+			return true
+		})
+		resolved += resolveHeld(ruleNameLog, fingerprint, message)
+
+		if rule.CorrelationKey == "" || fingerprint == "" {
+			continue
+		}
+		if inc := incidents.close(ruleNameLog, fingerprint, message.ChannelID); inc != nil {
+			log.Infof("Incident %q of rule '%s' (%d updates) resolved by message ID %s.", inc.Key, ruleNameLog, inc.Updates, message.ID)
+			if !marked[inc.MessageID] {
+				addResolvedEmoji(session, inc.ChannelID, inc.MessageID, rule.ResolveOn.ResolvedEmoji)
+				resolved++
+			}
+		}
+	}
+	return resolved
+}
+
+// addResolvedEmoji marks an alert message as resolved. emoji may be empty.
+func addResolvedEmoji(session DiscordSessionInterface, channelID string, messageID string, emoji string) {
+	if emoji == "" {
+		return
+	}
+	if errReact := session.MessageReactionAdd(channelID, messageID, reactionEmojiAPIName(emoji)); errReact != nil {
+		log.Errorf("Error adding resolvedEmoji '%s' to Discord message %s: %v", emoji, messageID, errReact)
+	}
+}
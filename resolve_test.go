@@ -0,0 +1,63 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestResolveAlerts(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+
+	config := &Config{Rules: []Rule{{
+		Name:       "Alerts",
+		Conditions: RuleConditions{ChannelID: "alerts", ContentIncludes: []string{"FIRING"}},
+		Actions: RuleActions{
+			PushoverDestination: "uKey",
+			Priority:            2,
+			Emergency:           &EmergencyParams{AckEmoji: "👍", Expire: 600, Retry: 60},
+		},
+		ResolveOn: &ResolveOn{
+			ContentPattern: `(?i)\[resolved\]`,
+			Fingerprint:    `{{capture "alertname=(\\S+)" .Content}}`,
+			ResolvedEmoji:  "✅",
+		},
+	}}}
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+	author := &discordgo.User{ID: "grafana"}
+
+	alert := &discordgo.Message{ID: "alertMsg", ChannelID: "alerts", Content: "[FIRING] alertname=DiskFull", Author: author}
+	ProcessRules(context.Background(), alert, config, session, math.MaxInt32)
+	tracked, ok := config.trackedEmergencies().Load("fake-receipt-id-for-test")
+	if !ok {
+		t.Fatal("Expected the alert to be tracked")
+	}
+	if fp := tracked.(TrackedEmergencyMessage).Fingerprint; fp != "DiskFull" {
+		t.Fatalf("Expected fingerprint DiskFull, got %q", fp)
+	}
+
+	other := &discordgo.Message{ID: "otherMsg", ChannelID: "alerts", Content: "[RESOLVED] alertname=CPUHigh", Author: author}
+	if n := resolveAlerts(context.Background(), other, config, session); n != 0 {
+		t.Errorf("Expected a different fingerprint not to resolve, resolved %d", n)
+	}
+	elsewhere := &discordgo.Message{ID: "elsewhereMsg", ChannelID: "other", Content: "[RESOLVED] alertname=DiskFull", Author: author}
+	if n := resolveAlerts(context.Background(), elsewhere, config, session); n != 0 {
+		t.Errorf("Expected another channel not to resolve, resolved %d", n)
+	}
+
+	resolution := &discordgo.Message{ID: "resolvedMsg", ChannelID: "alerts", Content: "[RESOLVED] alertname=DiskFull", Author: author}
+	ProcessRules(context.Background(), resolution, config, session, math.MaxInt32)
+	if _, ok := config.trackedEmergencies().Load("fake-receipt-id-for-test"); ok {
+		t.Fatal("Expected the alert to be resolved and untracked")
+	}
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, "MessageReactionAdd called with: chID=alerts, msgID=alertMsg, emoji=✅") {
+		t.Errorf("Expected resolvedEmoji on the original alert. Logs:\n%s", logs)
+	}
+}
@@ -0,0 +1,138 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// dgMessageReactionRemove is the raw handler for discordgo's MessageReactionRemove events.
+func dgMessageReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	defer recoverPanic("dgMessageReactionRemove")
+	wrapper := &DiscordGoSessionWrapper{RealSession: s}
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	messageReactionRemoveLogic(ctx, wrapper, r)
+}
+
+// messageReactionRemoveLogic retracts pending emergency notifications of rules with retractOnReactionRemove
+// once the reactions that triggered them are gone: the receipt is cancelled and the bot's reaction emoji removed.
+// Notifications still held by delaySeconds are dropped.
+func messageReactionRemoveLogic(ctx context.Context, s DiscordSessionInterface, r *discordgo.MessageReactionRemove) {
+	log.Debugf("Received MessageReactionRemove event: UserID: %s, MessageID: %s, Emoji: %s (ID: %s)",
+		r.UserID, r.MessageID, r.Emoji.Name, r.Emoji.ID)
+	if globalConfig == nil {
+		log.Error("globalConfig is nil in messageReactionRemoveLogic. Cannot retract notifications.")
+		return
+	}
+	sessionState := s.State()
+	if sessionState == nil || sessionState.User == nil {
+		log.Error("messageReactionRemoveLogic: session state or user is nil. Cannot reliably determine bot ID. Skipping.")
+		return
+	}
+	if r.UserID == sessionState.User.ID {
+		return // The bot removing its own reaction, e.g. during a retraction
+	}
+	held := heldRetractions(globalConfig, r.MessageID, &r.Emoji)
+	if len(pendingRetractions(globalConfig, r.MessageID, &r.Emoji)) == 0 && len(held) == 0 {
+		return
+	}
+
+	fullMessage, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+	if err != nil {
+		log.Errorf("Error fetching full message for reaction remove (MsgID: %s, ChanID: %s): %v", r.MessageID, r.ChannelID, err)
+		return
+	}
+	retractAlerts(ctx, fullMessage, &r.Emoji, globalConfig, s)
+	retractHeld(fullMessage, held)
+}
+
+// pendingRetractions returns the receipts of tracked emergencies on messageID whose rule retracts on
+// removal of the given emoji, keyed by receipt ID.
+func pendingRetractions(config *Config, messageID string, removed *discordgo.Emoji) map[string]*Rule {
+	pending := make(map[string]*Rule)
+	config.trackedEmergencies().Range(func(key, value interface{}) bool {
+		trackedMsg, ok := value.(TrackedEmergencyMessage)
+		if !ok || trackedMsg.DiscordMessageID != messageID {
+			return true
+		}
+		rule := ruleByLogName(config, trackedMsg.RuleName)
+		if rule == nil || !rule.RetractOnReactionRemove {
+			return true
+		}
+		for _, spec := range rule.Conditions.MessageHasEmoji {
+			if emojiMatches(spec, removed) {
+				pending[key.(string)] = rule
+				break
+			}
+		}
+		return true
+	})
+	return pending
+}
+
+// ruleByLogName finds a rule by the name used in logs and tracking, including "unnamed_rule_N" names.
+func ruleByLogName(config *Config, name string) *Rule {
+	for i := range config.Rules {
+		ruleNameLog := config.Rules[i].Name
+		if ruleNameLog == "" {
+			ruleNameLog = fmt.Sprintf("unnamed_rule_%d", i+1)
+		}
+		if ruleNameLog == name {
+			return &config.Rules[i]
+		}
+	}
+	return nil
+}
+
+// hasUserReaction reports whether someone other than the bot still reacts on message with any of specs.
+func hasUserReaction(message *discordgo.Message, specs []string) bool {
+	for _, reaction := range message.Reactions {
+		others := reaction.Count
+		if reaction.Me {
+			others--
+		}
+		if others <= 0 {
+			continue
+		}
+		for _, spec := range specs {
+			if emojiMatches(spec, reaction.Emoji) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retractAlerts cancels the pending emergencies on message whose triggering emoji reactions were all
+// removed before acknowledgement, and removes the bot's reaction emoji for them. Returns the number retracted.
+func retractAlerts(ctx context.Context, message *discordgo.Message, removed *discordgo.Emoji, config *Config, session DiscordSessionInterface) int {
+	retracted := 0
+	for receiptID, rule := range pendingRetractions(config, message.ID, removed) {
+		if hasUserReaction(message, rule.Conditions.MessageHasEmoji) {
+			log.Debugf("Message ID %s still has a reaction required by rule '%s'; not retracting receipt %s.", message.ID, rule.Name, receiptID)
+			continue
+		}
+		value, loaded := config.trackedEmergencies().LoadAndDelete(receiptID)
+		if !loaded {
+			continue // Acknowledged or resolved concurrently
+		}
+		trackedMsg := value.(TrackedEmergencyMessage)
+		errCancel := CancelPushoverEmergency(ctx, config, receiptID)
+		reportPushoverResult(session, config, errCancel)
+		if errCancel != nil {
+			log.Errorf("Error cancelling retracted emergency (Receipt: %s, DiscordMsg: %s): %v", receiptID, message.ID, errCancel)
+		} else {
+			log.Infof("Emergency (Receipt: %s, DiscordMsg: %s) of rule '%s' retracted: its reaction was removed before acknowledgement.",
+				receiptID, message.ID, trackedMsg.RuleName)
+		}
+		finishEmergencyStatus(session, trackedMsg, "↩️ Retracted")
+		clearEmergencyCountdown(session, trackedMsg)
+		for _, emoji := range append([]string{trackedMsg.PendingEmoji}, trackedMsg.ReactionEmojis...) {
+			removeBotReaction(session, trackedMsg.DiscordChannelID, trackedMsg.DiscordMessageID, emoji)
+		}
+		retracted++
+	}
+	return retracted
+}
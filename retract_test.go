@@ -0,0 +1,67 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestMessageReactionRemove_Retract(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+
+	globalConfig = &Config{Rules: []Rule{{
+		Name:       "Escalate",
+		Conditions: RuleConditions{ChannelID: "ops", MessageHasEmoji: []string{"🚨"}},
+		Actions: RuleActions{
+			PushoverDestination: "uKey",
+			Priority:            2,
+			ReactionEmoji:       EmojiList{"📟"},
+			Emergency:           &EmergencyParams{AckEmoji: "👍", Expire: 600, Retry: 60},
+		},
+		RetractOnReactionRemove: true,
+	}}}
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+	session.TestStateOverride = &discordgo.State{}
+	session.TestStateOverride.User = &discordgo.User{ID: "bot"}
+
+	message := &discordgo.Message{
+		ID: "m1", ChannelID: "ops", Author: &discordgo.User{ID: "u1"},
+		Reactions: []*discordgo.MessageReactions{
+			{Emoji: &discordgo.Emoji{Name: "🚨"}, Count: 2},
+		},
+	}
+	ProcessRules(context.Background(), message, globalConfig, session, math.MaxInt32)
+	if _, ok := globalConfig.trackedEmergencies().Load("fake-receipt-id-for-test"); !ok {
+		t.Fatal("Expected the emergency to be tracked")
+	}
+	session.CustomChannelMessageFunc = func(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+		return message, nil
+	}
+	removal := &discordgo.MessageReactionRemove{MessageReaction: &discordgo.MessageReaction{
+		UserID: "u1", MessageID: "m1", ChannelID: "ops", Emoji: discordgo.Emoji{Name: "🚨"},
+	}}
+
+	// Another user still reacts with 🚨
+	message.Reactions[0].Count = 1
+	messageReactionRemoveLogic(context.Background(), session, removal)
+	if _, ok := globalConfig.trackedEmergencies().Load("fake-receipt-id-for-test"); !ok {
+		t.Fatal("Expected the emergency to stay tracked while a 🚨 reaction remains")
+	}
+
+	// The bot's own 📟 doesn't keep the alert alive
+	message.Reactions = []*discordgo.MessageReactions{{Emoji: &discordgo.Emoji{Name: "📟"}, Count: 1, Me: true}}
+	messageReactionRemoveLogic(context.Background(), session, removal)
+	if _, ok := globalConfig.trackedEmergencies().Load("fake-receipt-id-for-test"); ok {
+		t.Fatal("Expected the emergency to be retracted")
+	}
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, "MessageReactionRemove called with: chID=ops, msgID=m1, emoji=📟, user=@me") {
+		t.Errorf("Expected the bot's reaction emoji to be removed. Logs:\n%s", logs)
+	}
+}
@@ -0,0 +1,303 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ruleBundleKind identifies rule bundles written by export-rules.
+const ruleBundleKind = "discord2pushover-rules"
+
+// ruleBundle is a portable set of rules. Pushover destinations are replaced by aliases whose keys are
+// filled in per instance on import, and secrets in the rules are stripped.
+type ruleBundle struct {
+	Kind         string            `yaml:"kind"`
+	Version      int               `yaml:"version"`
+	Destinations map[string]string `yaml:"destinations,omitempty"` // Alias to Pushover key; empty on export
+	Notifiers    []string          `yaml:"notifiers,omitempty"`    // Notifiers the rules use, which the importing config must define
+	Rules        yaml.Node         `yaml:"rules"`
+}
+
+var (
+	envPlaceholderRe = regexp.MustCompile(envVarPattern)
+	aliasSeparatorRe = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// configDocument parses a config file's YAML and returns its root mapping.
+func configDocument(data []byte) (*yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) != 1 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config is not a YAML mapping")
+	}
+	return root.Content[0], nil
+}
+
+// destinationAlias names a destination after the first rule using it, e.g. "grafana-alerts".
+func destinationAlias(ruleName string, taken map[string]bool) string {
+	base := strings.Trim(aliasSeparatorRe.ReplaceAllString(strings.ToLower(ruleName), "-"), "-")
+	if base == "" {
+		base = "destination"
+	}
+	alias := base
+	for i := 2; taken[alias]; i++ {
+		alias = fmt.Sprintf("%s-%d", base, i)
+	}
+	taken[alias] = true
+	return alias
+}
+
+// exportRules returns the rules of a config as a bundle. Destinations given literally are replaced
+// by "@alias", while "$VAR" placeholders are kept; classifier headers, which may hold credentials,
+// are emptied. It returns notes on what was stripped.
+func exportRules(data []byte) (*ruleBundle, []string, error) {
+	doc, err := configDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules := mappingValue(doc, "rules")
+	if rules == nil || rules.Kind != yaml.SequenceNode || len(rules.Content) == 0 {
+		return nil, nil, fmt.Errorf("config has no rules")
+	}
+	bundle := &ruleBundle{Kind: ruleBundleKind, Version: 1, Destinations: make(map[string]string)}
+	aliases := make(map[string]string) // Destination to alias
+	taken := make(map[string]bool)
+	notifiers := make(map[string]bool)
+	var notes []string
+	for i, rule := range rules.Content {
+		name := fmt.Sprintf("unnamed_rule_%d", i+1)
+		if n := mappingValue(rule, "name"); n != nil && n.Value != "" {
+			name = n.Value
+		}
+		actions := mappingValue(rule, "actions")
+		if dest := mappingValue(actions, "pushoverDestination"); dest != nil && dest.Kind == yaml.ScalarNode &&
+			dest.Value != "" && !envPlaceholderRe.MatchString(dest.Value) && dest.Value != onCallToken {
+			alias, ok := aliases[dest.Value]
+			if !ok {
+				alias = destinationAlias(name, taken)
+				aliases[dest.Value] = alias
+				bundle.Destinations[alias] = ""
+			}
+			dest.Value, dest.Style = "@"+alias, 0
+			dest.LineComment = ""
+		}
+		if notify := mappingValue(actions, "notify"); notify != nil {
+			for _, item := range notify.Content {
+				notifiers[item.Value] = true
+			}
+		}
+		if headers := mappingValue(mappingValue(mappingValue(rule, "conditions"), "classify"), "headers"); headers != nil {
+			for j := 1; j < len(headers.Content); j += 2 {
+				if value := headers.Content[j]; !envPlaceholderRe.MatchString(value.Value) {
+					value.Value = ""
+					notes = append(notes, fmt.Sprintf("Rule '%s': classify header %s emptied", name, headers.Content[j-1].Value))
+				}
+			}
+		}
+		if script := mappingValue(actions, "script"); script != nil && script.Value != "" {
+			notes = append(notes, fmt.Sprintf("Rule '%s': script %s is referenced but not included", name, script.Value))
+		}
+	}
+	for name := range notifiers {
+		bundle.Notifiers = append(bundle.Notifiers, name)
+	}
+	sort.Strings(bundle.Notifiers)
+	bundle.Rules = *rules
+	return bundle, notes, nil
+}
+
+// destinationEnvVar returns the placeholder an alias without a key is imported as, e.g.
+// "${PUSHOVER_GRAFANA_ALERTS}".
+func destinationEnvVar(alias string) string {
+	return "${PUSHOVER_" + strings.ToUpper(strings.Trim(aliasSeparatorRe.ReplaceAllString(strings.ToLower(alias), "_"), "_")) + "}"
+}
+
+// importRules merges a bundle's rules into a config: rules named like an existing rule replace it,
+// others are appended. Aliases get the key given in the bundle's destinations, else an environment
+// placeholder. It returns the new config with the changes made and notes for the operator.
+func importRules(configData []byte, bundleData []byte) ([]byte, []string, []string, error) {
+	var bundle ruleBundle
+	if err := yaml.Unmarshal(bundleData, &bundle); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	if bundle.Kind != ruleBundleKind {
+		return nil, nil, nil, fmt.Errorf("not a rule bundle (kind %q)", bundle.Kind)
+	}
+	if bundle.Version != 1 {
+		return nil, nil, nil, fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+	if bundle.Rules.Kind != yaml.SequenceNode {
+		return nil, nil, nil, fmt.Errorf("bundle has no rules")
+	}
+	doc, err := configDocument(configData)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var notes []string
+	placeholders := make(map[string]bool)
+	for _, rule := range bundle.Rules.Content {
+		dest := mappingValue(mappingValue(rule, "actions"), "pushoverDestination")
+		if dest == nil || !strings.HasPrefix(dest.Value, "@") {
+			continue
+		}
+		alias := strings.TrimPrefix(dest.Value, "@")
+		key, ok := bundle.Destinations[alias]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("bundle rule uses unknown destination alias %q", alias)
+		}
+		if key == "" {
+			key = destinationEnvVar(alias)
+			if !placeholders[key] {
+				placeholders[key] = true
+				notes = append(notes, fmt.Sprintf("Destination %s is imported as %s; set it in the environment or edit the config", alias, key))
+			}
+		}
+		dest.Value, dest.Style = key, yaml.DoubleQuotedStyle
+	}
+
+	rules := mappingValue(doc, "rules")
+	if rules == nil {
+		rules = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "rules"}, rules)
+	}
+	existing := make(map[string]int)
+	for i, rule := range rules.Content {
+		if name := mappingValue(rule, "name"); name != nil && name.Value != "" {
+			existing[name.Value] = i
+		}
+	}
+	var changes []string
+	for _, rule := range bundle.Rules.Content {
+		name := ""
+		if n := mappingValue(rule, "name"); n != nil {
+			name = n.Value
+		}
+		if i, ok := existing[name]; ok && name != "" {
+			rules.Content[i] = rule
+			changes = append(changes, fmt.Sprintf("Replaced rule '%s'", name))
+			continue
+		}
+		rules.Content = append(rules.Content, rule)
+		changes = append(changes, fmt.Sprintf("Added rule '%s'", name))
+	}
+	for _, name := range bundle.Notifiers {
+		if mappingValue(mappingValue(doc, "notifiers"), name) == nil {
+			notes = append(notes, fmt.Sprintf("Notifier '%s' is used by the rules but not configured", name))
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to write config: %w", err)
+	}
+	encoder.Close()
+	merged := unescapeAstral(out.Bytes())
+	if violations, err := validateConfigSchema(merged); err == nil && len(violations) > 0 {
+		return nil, nil, nil, fmt.Errorf("the merged config would be invalid:\n  %s", strings.Join(violations, "\n  "))
+	}
+	return merged, changes, notes, nil
+}
+
+// runExportRules writes the config's rules as a bundle to w and returns the process exit code.
+func runExportRules(path string, w io.Writer) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Errorf("Error reading config: %v", err)
+		return 1
+	}
+	bundle, notes, err := exportRules(data)
+	if err != nil {
+		log.Errorf("Error exporting rules of %s: %v", path, err)
+		return 1
+	}
+	for _, note := range notes {
+		log.Warn(note)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(bundle); err != nil {
+		log.Errorf("Error writing bundle: %v", err)
+		return 1
+	}
+	encoder.Close()
+	return 0
+}
+
+// importRulesIntoFile merges a bundle into the config file, keeping the original as <path>.bak, and
+// returns the changes made and notes for the operator.
+func importRulesIntoFile(path string, bundleData []byte) ([]string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if isSOPSDocument(data) {
+		return nil, nil, fmt.Errorf("%s is encrypted with SOPS: decrypt it, import and encrypt it again", path)
+	}
+	merged, changes, notes, err := importRules(data, bundleData)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := os.WriteFile(path+".bak", data, info.Mode().Perm()); err != nil {
+		return nil, nil, fmt.Errorf("failed to back up config: %w", err)
+	}
+	if err := os.WriteFile(path, merged, info.Mode().Perm()); err != nil {
+		return nil, nil, fmt.Errorf("failed to write config: %w", err)
+	}
+	return changes, notes, nil
+}
+
+// runImportRules merges a bundle into the config file, keeping the original as <path>.bak, and
+// returns the process exit code.
+func runImportRules(path string, bundlePath string, w io.Writer) int {
+	if bundlePath == "" {
+		log.Error("import-rules needs the bundle file, e.g. 'discord2pushover import-rules -c config.yaml rules.yaml'.")
+		return 2
+	}
+	bundleData, err := os.ReadFile(bundlePath)
+	if err != nil {
+		log.Errorf("Error reading bundle: %v", err)
+		return 1
+	}
+	changes, notes, err := importRulesIntoFile(path, bundleData)
+	if err != nil {
+		log.Errorf("Error importing %s into %s: %v", bundlePath, path, err)
+		return 1
+	}
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s\n", change)
+	}
+	for _, note := range notes {
+		fmt.Fprintf(w, "Note: %s\n", note)
+	}
+	fmt.Fprintf(w, "Imported %d rules into %s; the original is saved as %s.bak.\n", len(changes), path, path)
+	return 0
+}
@@ -0,0 +1,101 @@
+package discord2pushover
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const bundleSourceConfig = `discordToken: "$DISCORD_TOKEN"
+pushoverAppKey: "app"
+notifiers:
+  pd:
+    pagerDuty:
+      routingKey: "secret-routing-key"
+rules:
+  - name: Grafana Alerts
+    conditions:
+      channelId: "123"
+      classify:
+        url: "http://classifier.local/"
+        headers:
+          Authorization: "Bearer s3cret"
+          X-Token: "${CLASSIFIER_TOKEN}"
+    actions:
+      pushoverDestination: "uLiteralKey" # Ops team
+      notify: ["pd"]
+  - name: Deploys
+    conditions:
+      channelId: "456"
+    actions:
+      pushoverDestination: "uLiteralKey"
+  - name: On call
+    conditions:
+      channelId: "789"
+    actions:
+      pushoverDestination: "${ONCALL_KEY}"
+`
+
+func TestExportImportRules(t *testing.T) {
+	bundle, notes, err := exportRules([]byte(bundleSourceConfig))
+	if err != nil {
+		t.Fatalf("exportRules failed: %v", err)
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], "Authorization") {
+		t.Errorf("Expected a note on the emptied header, got %v", notes)
+	}
+	exported, err := yaml.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, secret := range []string{"uLiteralKey", "s3cret", "secret-routing-key"} {
+		if strings.Contains(string(exported), secret) {
+			t.Errorf("Expected %q to be stripped from the bundle:\n%s", secret, exported)
+		}
+	}
+	for _, want := range []string{`pushoverDestination: '@grafana-alerts'`, `grafana-alerts: ""`, "${ONCALL_KEY}", "${CLASSIFIER_TOKEN}", "- pd"} {
+		if !strings.Contains(string(exported), want) {
+			t.Errorf("Expected %q in the bundle:\n%s", want, exported)
+		}
+	}
+
+	target := `pushoverAppKey: "staging-app" # Staging
+rules:
+  - name: Deploys
+    conditions:
+      channelId: "old"
+    actions:
+      pushoverDestination: "uStaging"
+`
+	merged, changes, importNotes, err := importRules([]byte(target), exported)
+	if err != nil {
+		t.Fatalf("importRules failed: %v", err)
+	}
+	if strings.Join(changes, ",") != "Added rule 'Grafana Alerts',Replaced rule 'Deploys',Added rule 'On call'" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+	if strings.Join(importNotes, "\n") != "Destination grafana-alerts is imported as ${PUSHOVER_GRAFANA_ALERTS}; set it in the environment or edit the config\n"+
+		"Notifier 'pd' is used by the rules but not configured" {
+		t.Errorf("Unexpected notes %v", importNotes)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(merged, &cfg); err != nil {
+		t.Fatalf("Merged config does not parse: %v\n%s", err, merged)
+	}
+	if !strings.Contains(string(merged), `pushoverAppKey: "staging-app" # Staging`) || len(cfg.Rules) != 3 {
+		t.Fatalf("Unexpected merged config:\n%s", merged)
+	}
+	if cfg.Rules[0].Conditions.ChannelID != "456" || cfg.Rules[0].Actions.PushoverDestination != "${PUSHOVER_GRAFANA_ALERTS}" {
+		t.Errorf("Expected Deploys to be replaced in place, got %+v", cfg.Rules[0])
+	}
+
+	// Keys given in the bundle are used as they are
+	filled := strings.Replace(string(exported), `grafana-alerts: ""`, `grafana-alerts: "uStagingOps"`, 1)
+	if merged, _, _, err = importRules([]byte(target), []byte(filled)); err != nil || !strings.Contains(string(merged), `pushoverDestination: "uStagingOps"`) {
+		t.Errorf("Expected the bundle's key to be used, got %v:\n%s", err, merged)
+	}
+	if _, _, _, err := importRules([]byte(target), []byte("kind: other\nversion: 1\n")); err == nil {
+		t.Error("Expected an error for a file that is not a bundle")
+	}
+}
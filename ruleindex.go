@@ -0,0 +1,106 @@
+package discord2pushover
+
+// ruleIndex lists the rules that can match an event in a channel, so a message is only evaluated
+// against the rules for its channel plus those not restricted to one.
+type ruleIndex struct {
+	byChannel map[string]map[string][]int // Event, then channel ID, to rule indexes
+	unscoped  map[string][]int            // Event to indexes of rules without channelId
+}
+
+// newRuleIndex indexes the rules by event and channelId, keeping them in config order.
+func newRuleIndex(rules []Rule) *ruleIndex {
+	index := &ruleIndex{byChannel: make(map[string]map[string][]int), unscoped: make(map[string][]int)}
+	for i := range rules {
+		event := ruleEvent(&rules[i])
+		channelID := rules[i].Conditions.ChannelID
+		if channelID == "" {
+			index.unscoped[event] = append(index.unscoped[event], i)
+			continue
+		}
+		if index.byChannel[event] == nil {
+			index.byChannel[event] = make(map[string][]int)
+		}
+		index.byChannel[event][channelID] = append(index.byChannel[event][channelID], i)
+	}
+	return index
+}
+
+// candidates returns the indexes of the rules to evaluate for an event in a channel, in config order.
+func (index *ruleIndex) candidates(event string, channelID string) []int {
+	scoped, unscoped := index.byChannel[event][channelID], index.unscoped[event]
+	if len(scoped) == 0 {
+		return unscoped
+	}
+	if len(unscoped) == 0 {
+		return scoped
+	}
+	merged := make([]int, 0, len(scoped)+len(unscoped))
+	for len(scoped) > 0 && len(unscoped) > 0 {
+		if scoped[0] < unscoped[0] {
+			merged, scoped = append(merged, scoped[0]), scoped[1:]
+		} else {
+			merged, unscoped = append(merged, unscoped[0]), unscoped[1:]
+		}
+	}
+	merged = append(merged, scoped...)
+	return append(merged, unscoped...)
+}
+
+// candidateRules returns the indexes of the rules to evaluate for an event in a channel. Configs not
+// created by LoadConfig (e.g. in tests) have no index and evaluate every rule.
+func candidateRules(config *Config, event string, channelID string) []int {
+	if config.ruleIndex != nil {
+		return config.ruleIndex.candidates(event, channelID)
+	}
+	all := make([]int, len(config.Rules))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// configPatterns returns every regular expression in the config that is matched against messages.
+func configPatterns(config *Config) []string {
+	var patterns []string
+	for _, rule := range config.Rules {
+		for _, c := range conditionGroups(&rule.Conditions) {
+			if c.IsReplyTo != nil && c.IsReplyTo.ContentPattern != "" {
+				patterns = append(patterns, c.IsReplyTo.ContentPattern)
+			}
+			if c.ThreadNamePattern != "" {
+				patterns = append(patterns, c.ThreadNamePattern)
+			}
+			if c.WholeWord {
+				for _, keyword := range c.ContentIncludes {
+					patterns = append(patterns, keywordPattern(foldKeywordText(keyword, c.MatchCase, c.FoldDiacritics)))
+				}
+			}
+		}
+		for _, mapping := range rule.Actions.SeverityMap {
+			if mapping.Pattern != "" {
+				patterns = append(patterns, mapping.Pattern)
+			}
+		}
+		if rule.ResolveOn != nil && rule.ResolveOn.ContentPattern != "" {
+			patterns = append(patterns, rule.ResolveOn.ContentPattern)
+		}
+	}
+	if config.Ignore != nil {
+		patterns = append(patterns, config.Ignore.ContentPatterns...)
+	}
+	return patterns
+}
+
+// precompilePatterns compiles the config's regular expressions into the pattern cache up front, so
+// the first matching message doesn't pay for it and invalid patterns are reported at startup.
+// Returns the number of invalid patterns.
+func precompilePatterns(config *Config) int {
+	invalid := 0
+	for _, pattern := range configPatterns(config) {
+		if _, err := compiledPattern(pattern); err != nil {
+			log.Warnf("Invalid regular expression '%s' in config: %v. Conditions using it never match.", pattern, err)
+			invalid++
+		}
+	}
+	return invalid
+}
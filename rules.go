@@ -1,6 +1,8 @@
-package main
+package discord2pushover
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math" // Added for MaxInt32
 	"strings"
@@ -9,31 +11,150 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-// ProcessRules iterates through the configured rules and processes the first one that matches.
+// Rule events select which Discord event a rule is evaluated for.
+const (
+	ruleEventMessage      = "message"        // New or updated messages and reactions (default)
+	ruleEventPin          = "onPin"          // A message was pinned
+	ruleEventThreadCreate = "onThreadCreate" // A thread or forum post was created
+	ruleEventAutomod      = "onAutomod"      // AutoMod executed an action
+	ruleEventAuditLog     = "onAuditLog"     // A guild audit log entry was created (bans, kicks, ...)
+	ruleEventCommand      = "onCommand"      // A message invoked a command such as "!page"
+	ruleEventAlertmanager = "onAlertmanager" // Alertmanager posted a group of alerts to the ingest listener
+)
+
+// Values of Config.RuleEvaluation.
+const (
+	ruleEvaluationFirstMatch = "firstMatch" // Only the first matching rule fires (default)
+	ruleEvaluationAllMatches = "allMatches" // Every matching rule fires, notifying each destination once
+)
+
+// destinationSet records the destinations notified for a message in allMatches mode.
+type destinationSet map[string]bool
+
+// claim returns the Pushover destination and notifiers not notified yet, marking them as notified.
+func (s destinationSet) claim(pushoverDestination string, notify []string) (string, []string) {
+	if pushoverDestination != "" {
+		if s["pushover:"+pushoverDestination] {
+			pushoverDestination = ""
+		} else {
+			s["pushover:"+pushoverDestination] = true
+		}
+	}
+	var unclaimed []string
+	for _, name := range notify {
+		if !s["notifier:"+name] {
+			s["notifier:"+name] = true
+			unclaimed = append(unclaimed, name)
+		}
+	}
+	return pushoverDestination, unclaimed
+}
+
+// ruleEvent returns the event a rule applies to, defaulting to ruleEventMessage.
+func ruleEvent(rule *Rule) string {
+	if rule.Event == "" {
+		return ruleEventMessage
+	}
+	return rule.Event
+}
+
+// ProcessRules iterates through the configured rules and processes the first one that matches, or
+// every matching one with ruleEvaluation allMatches.
 // previouslyNotifiedRulePriority helps avoid duplicate Pushover notifications if a bot reaction triggered the update.
-func ProcessRules(message *discordgo.Message, config *Config, session DiscordSessionInterface, previouslyNotifiedRulePriority int) {
+// Once ctx is done, no further rules are evaluated and in-flight notifications are abandoned.
+func ProcessRules(ctx context.Context, message *discordgo.Message, config *Config, session DiscordSessionInterface, previouslyNotifiedRulePriority int) {
+	processRulesForEvent(ctx, ruleEventMessage, message, nil, config, session, previouslyNotifiedRulePriority)
+}
+
+// ProcessMessage is ProcessRules for a message nothing has been notified for yet, e.g. a new one.
+func ProcessMessage(ctx context.Context, message *discordgo.Message, config *Config, session DiscordSessionInterface) {
+	ProcessRules(ctx, message, config, session, math.MaxInt32)
+}
+
+// EventDetails carries data of non-message events (e.g. moderation) that some conditions match on.
+type EventDetails struct {
+	ModerationRuleName string // Name of the AutoMod rule that fired
+	ActionType         string // Normalized action, e.g. "block_message", "timeout", "ban", "kick"
+	Late               bool   // The message is processed late, e.g. by the startup backfill
+}
+
+// processRulesForEvent is ProcessRules restricted to rules registered for the given event.
+// details may be nil for events that carry nothing beyond the message.
+func processRulesForEvent(ctx context.Context, event string, message *discordgo.Message, details *EventDetails, config *Config, session DiscordSessionInterface, previouslyNotifiedRulePriority int) {
 	authorUsername := "unknown_author"
 	if message.Author != nil { // Author can be nil for some system messages or if not properly resolved
 		authorUsername = message.Author.Username
 	}
-	log.Infof("Processing rules for message ID %s (user: %s, channel: %s). Previously notified priority: %d", message.ID, authorUsername, message.ChannelID, previouslyNotifiedRulePriority)
-	for i, rule := range config.Rules {
-		ruleNameLog := rule.Name
-		if ruleNameLog == "" {
-			ruleNameLog = fmt.Sprintf("unnamed_rule_%d", i+1)
+	trace := newDecisionTrace(config, event, message)
+	defer trace.emit()
+	if isIgnored(config, message) {
+		trace.setOutcome("ignored")
+		return
+	}
+	log.Infof("Processing rules for message ID %s (user: %s, channel: %s, event: %s). Previously notified priority: %d", message.ID, authorUsername, message.ChannelID, event, previouslyNotifiedRulePriority)
+	if event == ruleEventMessage {
+		resolveAlerts(ctx, message, config, session)
+	}
+	candidates := candidateRules(config, event, message.ChannelID)
+	var matchedRules []string
+	var notifiedDestinations destinationSet // Only in allMatches mode
+	if config.RuleEvaluation == ruleEvaluationAllMatches {
+		notifiedDestinations = make(destinationSet)
+	}
+	for _, i := range candidates {
+		rule := config.Rules[i]
+		ruleNameLog := ruleNameForLog(&rule, i)
+		if ruleEvent(&rule) != event {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			log.Warnf("Stopped processing rules for message ID %s before rule '%s': %v", message.ID, ruleNameLog, err)
+			trace.setOutcome("cancelled: %v", err)
+			return
 		}
 		log.Debugf("Evaluating rule #%d: '%s' for message ID %s", i+1, ruleNameLog, message.ID)
+		counters := countersFor(ruleNameLog)
+		counters.evaluated.Add(1)
 
-		conditionsMet := checkRuleConditions(message, &rule.Conditions, session, ruleNameLog)
+		conditionsMet, reason := evaluateRuleConditions(ctx, config, message, &rule.Conditions, session, ruleNameLog)
 		if conditionsMet {
-			log.Infof("Rule #%d ('%s') MATCHED for message ID %s.", i+1, ruleNameLog, message.ID)
-			// Construct Discord message link
-			var discordMessageURL string
-			if message.GuildID != "" {
-				discordMessageURL = fmt.Sprintf("https://discord.com/channels/%s/%s/%s", message.GuildID, message.ChannelID, message.ID)
+			conditionsMet, reason = checkEventConditions(details, &rule.Conditions, ruleNameLog)
+		}
+		if conditionsMet {
+			conditionsMet, reason = checkScheduleCondition(config, &rule.Conditions, time.Now(), ruleNameLog)
+		}
+		score := 0
+		if conditionsMet && rule.Scoring != nil {
+			var threshold *ScoreThreshold
+			score, threshold, reason = scoreMessage(ctx, config, message, rule.Scoring, session, ruleNameLog)
+			if conditionsMet = threshold != nil; conditionsMet {
+				applyScoreThreshold(&rule, threshold)
+			}
+		}
+		if conditionsMet {
+			trace.record(i, ruleNameLog, "matched", "")
+			matchedRules = append(matchedRules, ruleNameLog)
+			if len(matchedRules) == 1 {
+				trace.setOutcome("matched rule '%s'", ruleNameLog)
 			} else {
-				discordMessageURL = fmt.Sprintf("https://discord.com/channels/@me/%s/%s", message.ChannelID, message.ID)
+				trace.setOutcome("matched rules '%s'", strings.Join(matchedRules, "', '"))
 			}
+			log.Infof("Rule #%d ('%s')%s MATCHED for message ID %s.", i+1, ruleNameLog, formatLabels(rule.Labels), message.ID)
+			counters.matched.Add(1)
+			emitBotEvent(config, botEvent{Event: botEventRuleMatched, Rule: ruleNameLog, Labels: rule.Labels,
+				GuildID: message.GuildID, ChannelID: message.ChannelID, MessageID: message.ID,
+				Summary: truncateRunes(message.Content, botEventSummaryRunes)})
+			discordMessageURL := discordMessageLink(message)
+			actions := effectiveActions(&rule, message, ruleNameLog)
+			actions.PushoverDestination = resolveOnCallDestination(config, actions.PushoverDestination, ruleNameLog)
+			actions.PushoverDestination = allowedTenantDestination(config, message.GuildID, actions.PushoverDestination, ruleNameLog)
+			actions.Notify = allowedTenantNotifiers(config, message.GuildID, actions.Notify, ruleNameLog)
+			if actions.Emergency != nil && len(actions.Emergency.EscalateTo) > 0 {
+				emergency := *actions.Emergency
+				emergency.EscalateTo = allowedTenantNotifiers(config, message.GuildID, emergency.EscalateTo, ruleNameLog)
+				actions.Emergency = &emergency
+			}
+			payload := parsePayload(&rule, message, ruleNameLog)
 
 			// Trigger actions
 			log.Infof("Triggering actions for matched rule '%s' on message ID %s", ruleNameLog, message.ID)
@@ -42,93 +163,380 @@ func ProcessRules(message *discordgo.Message, config *Config, session DiscordSes
 			// Pushover priorities: -2 (lowest) to 2 (emergency). Lower number = higher priority.
 			// If current rule's priority is same or lower (numerically greater or equal) than a previously notified one, skip Pushover.
 			sendNotification := true
-			if rule.Actions.PushoverDestination != "" { // Only consider suppression if a destination is set
-				if previouslyNotifiedRulePriority != math.MaxInt32 && rule.Actions.Priority <= previouslyNotifiedRulePriority {
+			alreadyNotified := previouslyNotifiedRulePriority != math.MaxInt32 && actions.Priority <= previouslyNotifiedRulePriority
+			if actions.PushoverDestination != "" || len(actions.Notify) > 0 || hasUserDestinations(&actions) { // Only consider suppression if a destination is set
+				if alreadyNotified {
 					log.Warnf("Suppressing Pushover notification for rule '%s' (Priority: %d) on message ID %s. A notification with higher or equal priority (%d) was likely already sent due to bot reaction.",
-						ruleNameLog, rule.Actions.Priority, message.ID, previouslyNotifiedRulePriority)
+						ruleNameLog, actions.Priority, message.ID, previouslyNotifiedRulePriority)
 					sendNotification = false
 				}
 			} else {
-				log.Debugf("Rule '%s' has no Pushover destination defined. No Pushover notification to send or suppress.", ruleNameLog)
+				log.Debugf("Rule '%s' has no Pushover destination or notifiers defined. No Pushover notification to send or suppress.", ruleNameLog)
 				sendNotification = false // No destination means no notification to send
 			}
 
-			var receiptID string
-			var errPushover error
+			// In allMatches mode, destinations already notified for the message by an earlier rule are skipped
+			if sendNotification && notifiedDestinations != nil {
+				actions.PushoverDestination, actions.Notify = notifiedDestinations.claim(actions.PushoverDestination, actions.Notify)
+				if actions.PushoverDestination == "" && len(actions.Notify) == 0 {
+					log.Infof("Suppressing notification for rule '%s' on message ID %s: its destinations were already notified by an earlier rule.", ruleNameLog, message.ID)
+					sendNotification = false
+					counters.suppressed.Add(1)
+				}
+			}
 
-			if sendNotification {
-				receiptID, errPushover = SendPushoverNotification(config, &rule.Actions, message.Content, discordMessageURL)
-				if errPushover != nil {
-					log.Errorf("Error sending Pushover notification for rule '%s' (message ID %s): %v", ruleNameLog, message.ID, errPushover)
+			// Chatty rules notify a sample of their matches
+			if sendNotification && rule.SamplePercent > 0 && rule.SamplePercent < 100 {
+				if sampledIn(&rule, ruleNameLog, message.ID) {
+					log.Infof("Sampling: match of rule '%s' on message ID %s sampled in (samplePercent %g).", ruleNameLog, message.ID, rule.SamplePercent)
+					trace.record(i, ruleNameLog, "matched", fmt.Sprintf("sampled in (samplePercent %g)", rule.SamplePercent))
 				} else {
-					log.Infof("Pushover notification sent for rule '%s' (message ID %s). Receipt ID (if emergency): '%s'", ruleNameLog, message.ID, receiptID)
+					log.Infof("Sampling: match of rule '%s' on message ID %s sampled out (samplePercent %g); not notifying.", ruleNameLog, message.ID, rule.SamplePercent)
+					trace.record(i, ruleNameLog, "matched", fmt.Sprintf("sampled out (samplePercent %g)", rule.SamplePercent))
+					sendNotification = false
+				}
+			}
+
+			incidentKey := ""
+			if sendNotification {
+				incidentKey = correlationKey(&rule, ruleNameLog, event, message)
+			}
+			// admit charges the notification to its incident, the author's cooldown, the channel's
+			// flood and the budgets, and reports whether it may be sent. Held notifications are only
+			// charged once their delay passes, so cancelled ones use up nothing.
+			admit := func() bool {
+				// Updates to an open incident are only notified when they escalate its priority
+				if incidentKey != "" && !incidents.observe(ruleNameLog, incidentKey, actions.Priority, correlationWindow(&rule), time.Now()) {
+					log.Infof("Suppressing Pushover notification for rule '%s' on message ID %s: update to open incident %q without escalation.",
+						ruleNameLog, message.ID, incidentKey)
+					return false
+				}
+
+				// Authors splitting one thought across several messages are notified once per cooldown
+				if rule.PerAuthorCooldownSeconds > 0 && message.Author != nil {
+					cooldown := time.Duration(rule.PerAuthorCooldownSeconds) * time.Second
+					if !authorCooldowns.allow(ruleNameLog, message.ChannelID, message.Author.ID, actions.Priority, cooldown, time.Now()) {
+						log.Infof("Suppressing notification for rule '%s' on message ID %s: a match by author %s was notified within the last %s (perAuthorCooldownSeconds).",
+							ruleNameLog, message.ID, message.Author.ID, cooldown)
+						return false
+					}
+				}
+
+				// Bursts of matches in one channel are collapsed into summaries
+				if message.ChannelID != "" && collapseFlood(session, config, &rule, actions, ruleNameLog, message) {
+					return false
+				}
+
+				// Runaway rules are capped by the notification budgets
+				allowed, exceeded := budgets.allow(config, &rule, ruleNameLog, message.GuildID, time.Now())
+				for _, text := range exceeded {
+					sendErrorNotification(session, config, "", text)
+				}
+				if !allowed {
+					log.Warnf("Suppressing notification for rule '%s' on message ID %s: notification budget exhausted.", ruleNameLog, message.ID)
+				}
+				return allowed
+			}
+			if sendNotification && actions.DelaySeconds <= 0 {
+				sendNotification = admit()
+			}
+
+			if !sendNotification && (actions.PushoverDestination != "" || len(actions.Notify) > 0 || hasUserDestinations(&actions)) {
+				counters.suppressed.Add(1)
+			}
+
+			// deliver sends the notification and returns the receipt IDs of emergencies and the
+			// notification rendered for notifiers and emergency escalation
+			deliver := func(ctx context.Context) ([]string, *Notification) {
+				var receiptIDs []string
+				var errPushover error
+				var notification *Notification
+
+				notificationContent := message.Content
+				if notificationContent == "" && payload != nil {
+					notificationContent = payload["text"] // Instead of "(no text content)" for embed-only webhook messages
+				}
+				translated := ""
+				if actions.TranslateTo != "" && notificationContent != "" {
+					translated = translateNotificationContent(ctx, config, notificationContent, actions.TranslateTo, ruleNameLog, message.ID)
+					notificationContent = translated
+				}
+				if event == ruleEventPin {
+					notificationContent = "📌 Pinned: " + notificationContent
+				}
+				if event == ruleEventCommand {
+					notificationContent = commandNotificationBody(message, rule.Conditions.Command)
+				}
+				if parent := resolveReferencedMessage(session, message); parent != nil {
+					notificationContent = fmt.Sprintf("%s\n\n%s", notificationContent, formatReplyParent(parent))
+				}
+				if actions.IncludeContext > 0 {
+					if transcript := fetchMessageContext(session, message, actions.IncludeContext); transcript != "" {
+						notificationContent = fmt.Sprintf("%s\n\nEarlier in channel:\n%s", notificationContent, transcript)
+					}
+				}
+				if details != nil && details.Late {
+					notificationContent = fmt.Sprintf("⏰ Late, posted %s ago: %s", time.Since(messageTime(message)).Round(time.Minute), notificationContent)
+				}
+				notificationData := newNotificationData(&rule, ruleNameLog, event, message, notificationContent, discordMessageURL)
+				notificationData.Late = details != nil && details.Late
+				notificationData.Payload = payload
+				notificationData.Translated = translated
+				notificationData.Score = score
+				if config.ReplyBridge != nil && message.ChannelID != "" {
+					notificationData.ReplyCode = replyTargets.register(message.ChannelID, message.ID, time.Now())
+				}
+				if actions.PushoverDestination != "" {
+					receiptIDs, errPushover = sendRuleNotification(ctx, config, &rule, &actions, notificationData, ruleNameLog, discordMessageURL)
+					reportPushoverResult(session, config, errPushover)
+					if errPushover != nil {
+						log.Errorf("Error sending Pushover notification for rule '%s' (message ID %s): %v", ruleNameLog, message.ID, errPushover)
+					} else {
+						log.Infof("Pushover notification sent for rule '%s' (message ID %s). Receipt ID (if emergency): '%s'", ruleNameLog, message.ID, strings.Join(receiptIDs, ", "))
+					}
+				}
+				var errUsers error
+				if hasUserDestinations(&actions) {
+					var userReceiptIDs []string
+					userReceiptIDs, errUsers = notifyUserDestinations(ctx, config, session, &rule, &actions, message, notificationData, ruleNameLog, discordMessageURL)
+					receiptIDs = append(receiptIDs, userReceiptIDs...)
+				}
+				if len(actions.Notify) > 0 || (actions.Emergency != nil && len(actions.Emergency.EscalateTo) > 0) {
+					title, body := renderNotification(&rule, notificationData, ruleNameLog)
+					if title == "" {
+						title = defaultNotificationTitle
+					}
+					notification = &Notification{RuleName: ruleNameLog, Title: title, Body: body, Link: discordMessageURL, Priority: actions.Priority, DedupKey: incidentKey, Data: notificationData}
+				}
+				var errNotify error
+				if len(actions.Notify) > 0 {
+					if errNotify = notifyBackends(ctx, config, actions.Notify, notification); errNotify != nil {
+						log.Errorf("Error sending notification for rule '%s' (message ID %s): %v", ruleNameLog, message.ID, errNotify)
+					}
+				}
+				if errPushover != nil || errNotify != nil || errUsers != nil {
+					counters.errored.Add(1)
+					emitBotEvent(config, botEvent{Event: botEventNotificationFailed, Rule: ruleNameLog, Labels: rule.Labels,
+						GuildID: message.GuildID, ChannelID: message.ChannelID, MessageID: message.ID,
+						Error: errors.Join(errPushover, errNotify, errUsers).Error()})
+				}
+				if (actions.PushoverDestination != "" && errPushover == nil) || (len(actions.Notify) > 0 && errNotify == nil) ||
+					(hasUserDestinations(&actions) && errUsers == nil) {
+					counters.notified.Add(1)
+					emitBotEvent(config, botEvent{Event: botEventNotificationSent, Rule: ruleNameLog, Labels: rule.Labels,
+						GuildID: message.GuildID, ChannelID: message.ChannelID, MessageID: message.ID, Receipt: strings.Join(receiptIDs, ",")})
+					if actions.RemindAfterMinutes > 0 && message.ID != "" && message.ChannelID != "" {
+						scheduleReminder(&actions, ruleNameLog, message, time.Now())
+					}
 				}
+				if errPushover == nil && incidentKey != "" {
+					incidents.record(ruleNameLog, incidentKey, message, actions.Priority, time.Now())
+				}
+				return receiptIDs, notification
+			}
+			var receiptIDs []string
+			var notification *Notification
+			if sendNotification && actions.DelaySeconds <= 0 {
+				receiptIDs, notification = deliver(ctx)
 			}
 
 			// Handle standard reaction emoji for the rule, regardless of Pushover send status,
 			// unless this reaction emoji itself was the one that triggered this evaluation pass
 			// and we want to avoid re-adding it. For now, always attempt reaction if specified.
 			// The `MessageReactionAdd` function in discordgo is idempotent (won't add if already present by bot).
-			if rule.Actions.ReactionEmoji != "" {
-				log.Debugf("Attempting to add reaction emoji '%s' for rule '%s' to message %s", rule.Actions.ReactionEmoji, ruleNameLog, message.ID)
-				// Pass empty opts for now
-				errReact := session.MessageReactionAdd(message.ChannelID, message.ID, rule.Actions.ReactionEmoji)
-				if errReact != nil {
-					log.Errorf("Error adding reaction emoji '%s' for rule '%s' (message %s): %v",
-						rule.Actions.ReactionEmoji, ruleNameLog, message.ID, errReact)
-				} else {
-					log.Debugf("Successfully added reaction emoji '%s' for rule '%s' to message %s.",
-						rule.Actions.ReactionEmoji, ruleNameLog, message.ID)
+			if len(actions.ReactionEmoji) > 0 && (message.ChannelID == "" || message.ID == "") {
+				log.Debugf("Rule '%s' has a reaction emoji but event has no Discord message to react to.", ruleNameLog)
+			} else {
+				// Added one by one, in order, so the reactions appear in the configured sequence
+				for _, reactionEmoji := range actions.ReactionEmoji {
+					log.Debugf("Attempting to add reaction emoji '%s' for rule '%s' to message %s", reactionEmoji, ruleNameLog, message.ID)
+					errReact := session.MessageReactionAdd(message.ChannelID, message.ID, reactionEmojiAPIName(reactionEmoji))
+					if errReact != nil {
+						log.Errorf("Error adding reaction emoji '%s' for rule '%s' (message %s): %v",
+							reactionEmoji, ruleNameLog, message.ID, errReact)
+					} else {
+						log.Debugf("Successfully added reaction emoji '%s' for rule '%s' to message %s.",
+							reactionEmoji, ruleNameLog, message.ID)
+					}
 				}
 			}
 
-			// Handle emergency notification tracking if a receipt ID was returned (meaning notification was sent)
-			if sendNotification && errPushover == nil && receiptID != "" && rule.Actions.Priority == 2 { // Check sendNotification and no error
-				if rule.Actions.Emergency != nil {
-					expiryDuration := time.Duration(rule.Actions.Emergency.Expire) * time.Second
-					if rule.Actions.Emergency.Expire <= 0 { // Ensure non-negative, non-zero expiry for tracking
-						log.Warnf("Rule '%s' has emergency priority but invalid 'expire' value (%d). Using default 1 hour for internal tracking.", ruleNameLog, rule.Actions.Emergency.Expire)
+			// Run the rule's script, unless this is a re-evaluation of a message it already ran for
+			if actions.Script != "" && !alreadyNotified {
+				if errScript := runRuleScript(ctx, &rule, ruleNameLog, event, message, config, session); errScript != nil {
+					log.Errorf("Error running script for rule '%s' (message ID %s): %v", ruleNameLog, message.ID, errScript)
+					counters.errored.Add(1)
+				}
+			}
+
+			// Handle emergency notification tracking if a receipt ID was returned (meaning notification was sent).
+			// With device variants only some sends may have succeeded; those are tracked regardless.
+			trackEmergency := func(receiptIDs []string, notification *Notification) {
+				if len(receiptIDs) == 0 || actions.Priority != 2 {
+					return
+				}
+				if actions.Emergency != nil {
+					expiryDuration := time.Duration(actions.Emergency.Expire) * time.Second
+					if actions.Emergency.Expire <= 0 { // Ensure non-negative, non-zero expiry for tracking
+						log.Warnf("Rule '%s' has emergency priority but invalid 'expire' value (%d). Using default 1 hour for internal tracking.", ruleNameLog, actions.Emergency.Expire)
 						expiryDuration = 3600 * time.Second
 					}
 
 					trackedMsg := TrackedEmergencyMessage{
-						DiscordMessageID:  message.ID,
-						DiscordChannelID:  message.ChannelID,
-						PushoverReceiptID: receiptID,
-						AckEmoji:          rule.Actions.Emergency.AckEmoji,
-						ExpiryTime:        time.Now().Add(expiryDuration),
+						DiscordMessageID:    message.ID,
+						DiscordChannelID:    message.ChannelID,
+						AckEmoji:            actions.Emergency.AckEmoji,
+						ExpiryTime:          time.Now().Add(expiryDuration),
+						RuleName:            ruleNameLog,
+						ReactionEmojis:      actions.ReactionEmoji,
+						PendingEmoji:        actions.Emergency.PendingEmoji,
+						RemoveReactionOnAck: actions.Emergency.RemoveReactionOnAck,
+						Escalation:          newEmergencyEscalation(actions.Emergency, notification, time.Now()),
+					}
+					trackedMsg.Status = newEmergencyStatus(actions.Emergency, time.Now(), trackedMsg.ExpiryTime)
+					trackedMsg.Countdown = newEmergencyCountdown(actions.Emergency, time.Now(), trackedMsg.ExpiryTime)
+					if notification != nil {
+						trackedMsg.IncidentKey = incidentDedupKey(notification)
+						trackedMsg.IncidentNotifiers = incidentToolNotifiers(config, actions.Notify)
+					}
+					if rule.ResolveOn != nil {
+						trackedMsg.Fingerprint = alertFingerprint(&rule, ruleNameLog, message)
+						trackedMsg.ResolvedEmoji = rule.ResolveOn.ResolvedEmoji
+					}
+					for _, receiptID := range receiptIDs {
+						trackedMsg.PushoverReceiptID = receiptID
+						config.trackedEmergencies().Store(receiptID, trackedMsg)
+					}
+					if trackedMsg.PendingEmoji != "" {
+						if errReact := session.MessageReactionAdd(message.ChannelID, message.ID, reactionEmojiAPIName(trackedMsg.PendingEmoji)); errReact != nil {
+							log.Errorf("Error adding pendingEmoji '%s' for rule '%s' (message %s): %v", trackedMsg.PendingEmoji, ruleNameLog, message.ID, errReact)
+						}
 					}
-					trackedMessages.Store(receiptID, trackedMsg)
 					log.Infof("Tracking emergency message for rule '%s' (Receipt: %s, DiscordMsg: %s, AckEmoji: %s, Expires: %s)",
-						ruleNameLog, receiptID, message.ID, trackedMsg.AckEmoji, trackedMsg.ExpiryTime.Format(time.RFC3339))
+						ruleNameLog, strings.Join(receiptIDs, ", "), message.ID, trackedMsg.AckEmoji, trackedMsg.ExpiryTime.Format(time.RFC3339))
 				} else {
 					log.Warnf("Rule '%s' is emergency priority but 'emergency' parameters are not defined. Cannot track acknowledgement, despite notification being sent.", ruleNameLog)
 				}
 			}
+			switch {
+			case sendNotification && actions.DelaySeconds > 0:
+				// Held back so flapping alerts resolved, retracted or deleted within the delay never notify
+				held := &heldNotification{RuleName: ruleNameLog, ChannelID: message.ChannelID, MessageID: message.ID,
+					Fingerprint: alertFingerprint(&rule, ruleNameLog, message)}
+				holdNotification(config, held, time.Duration(actions.DelaySeconds)*heldDelayUnit, func(ctx context.Context) {
+					if !admit() {
+						counters.suppressed.Add(1)
+						return
+					}
+					trackEmergency(deliver(ctx))
+				})
+			case sendNotification:
+				trackEmergency(receiptIDs, notification)
+			}
+			if notifiedDestinations != nil {
+				log.Infof("Finished processing actions for matched rule '%s' on message ID %s. Evaluating further rules (allMatches).", ruleNameLog, message.ID)
+				continue
+			}
 			// Stop processing further rules for this message
 			log.Infof("Finished processing actions for matched rule '%s' on message ID %s. No further rules will be evaluated for this message.", ruleNameLog, message.ID)
 			return
 		}
 		log.Debugf("Rule #%d ('%s') did not match for message ID %s.", i+1, ruleNameLog, message.ID)
+		trace.record(i, ruleNameLog, "failed", reason)
+	}
+	if len(matchedRules) > 0 {
+		log.Infof("%d rule(s) matched for message ID %s: %s.", len(matchedRules), message.ID, strings.Join(matchedRules, ", "))
+		return
+	}
+	log.Infof("No rules matched for message ID %s after evaluating %d of %d rules.", message.ID, len(candidates), len(config.Rules))
+}
+
+// discordMessageLink constructs the jump link for a message, falling back to the channel or guild
+// for events (e.g. audit log entries) that have no message.
+func discordMessageLink(message *discordgo.Message) string {
+	guild := message.GuildID
+	if guild == "" {
+		guild = "@me"
+	}
+	switch {
+	case message.ChannelID == "":
+		return fmt.Sprintf("https://discord.com/channels/%s", guild)
+	case message.ID == "":
+		return fmt.Sprintf("https://discord.com/channels/%s/%s", guild, message.ChannelID)
+	default:
+		return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guild, message.ChannelID, message.ID)
+	}
+}
+
+// checkEventConditions evaluates conditions that match on event details rather than the message.
+// They fail when set but the event carries no details (i.e. for message events).
+// Also returns the failed condition and why, as recorded in decision traces.
+func checkEventConditions(details *EventDetails, conditions *RuleConditions, ruleNameLog string) (bool, string) {
+	if len(conditions.AutomodRuleNames) == 0 && len(conditions.ActionTypes) == 0 {
+		return true, ""
 	}
-	log.Infof("No rules matched for message ID %s after evaluating all %d rules.", message.ID, len(config.Rules))
+	logPrefix := fmt.Sprintf("Rule '%s': ", ruleNameLog)
+	if details == nil {
+		return conditionFailed(logPrefix, "AutomodRuleNames/ActionTypes", "event has no moderation details")
+	}
+	if len(conditions.AutomodRuleNames) > 0 && !containsFold(conditions.AutomodRuleNames, details.ModerationRuleName) {
+		return conditionFailed(logPrefix, "AutomodRuleNames", "'%s' not in %v", details.ModerationRuleName, conditions.AutomodRuleNames)
+	}
+	if len(conditions.ActionTypes) > 0 && !containsFold(conditions.ActionTypes, details.ActionType) {
+		return conditionFailed(logPrefix, "ActionTypes", "'%s' not in %v", details.ActionType, conditions.ActionTypes)
+	}
+	log.Debugf(logPrefix+"Condition passed (AutomodRuleNames/ActionTypes): rule '%s', action '%s'", details.ModerationRuleName, details.ActionType)
+	return true, ""
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
 }
 
 // checkRuleConditions evaluates all conditions for a single rule using AND logic.
 // A condition is considered "active" if its corresponding field in the config is non-zero.
 // If a condition is active, it must evaluate to true. If not active, it's skipped (effectively true).
-func checkRuleConditions(message *discordgo.Message, conditions *RuleConditions, session DiscordSessionInterface, ruleNameLog string) bool {
+func checkRuleConditions(ctx context.Context, config *Config, message *discordgo.Message, conditions *RuleConditions, session DiscordSessionInterface, ruleNameLog string) bool {
+	met, _ := evaluateRuleConditions(ctx, config, message, conditions, session, ruleNameLog)
+	return met
+}
+
+// conditionFailed logs a failed condition and returns the result of evaluateRuleConditions for it.
+func conditionFailed(logPrefix string, condition string, format string, args ...interface{}) (bool, string) {
+	reason := fmt.Sprintf(format, args...)
+	log.Debugf(logPrefix+"Condition failed (%s): %s", condition, reason)
+	return false, condition + ": " + reason
+}
+
+// evaluateRuleConditions is checkRuleConditions, also returning the first failed condition and why.
+func evaluateRuleConditions(ctx context.Context, config *Config, message *discordgo.Message, conditions *RuleConditions, session DiscordSessionInterface, ruleNameLog string) (bool, string) {
 	logPrefix := fmt.Sprintf("Rule '%s', MessageID '%s': ", ruleNameLog, message.ID) // Keep this prefix for readability in logs
 
 	// ChannelID condition
 	if conditions.ChannelID != "" {
 		if message.ChannelID != conditions.ChannelID {
-			log.Debugf(logPrefix+"Condition failed (ChannelID): message channel %s != rule channel %s", message.ChannelID, conditions.ChannelID)
-			return false
+			return conditionFailed(logPrefix, "ChannelID", "message channel %s != rule channel %s", message.ChannelID, conditions.ChannelID)
 		}
 		log.Debugf(logPrefix+"Condition passed (ChannelID): %s", conditions.ChannelID)
 	}
 
+	// Command condition (onCommand rules): the message must invoke the command, by an allowed author
+	if conditions.Command != nil {
+		if _, ok := parseCommand(message.Content, conditions.Command.Name); !ok {
+			return conditionFailed(logPrefix, "Command", "message does not invoke '%s'", conditions.Command.Name)
+		}
+		if !mayInvokeCommand(message, conditions.Command) {
+			return conditionFailed(logPrefix, "Command", "author may not invoke '%s'", conditions.Command.Name)
+		}
+		log.Debugf(logPrefix+"Condition passed (Command): %s", conditions.Command.Name)
+	}
+
 	// MessageHasEmoji condition (checks reactions on the message) - ANY OF LOGIC
 	if len(conditions.MessageHasEmoji) > 0 {
 		anyEmojiFound := false
@@ -136,7 +544,7 @@ func checkRuleConditions(message *discordgo.Message, conditions *RuleConditions,
 		for _, reactionOnMessage := range message.Reactions {
 			// Check if this reaction matches ANY of the emojis specified in the rule's conditions
 			for _, requiredEmojiName := range conditions.MessageHasEmoji {
-				if reactionOnMessage.Emoji.Name == requiredEmojiName {
+				if emojiMatches(requiredEmojiName, reactionOnMessage.Emoji) {
 					// An emoji specified in the condition is found on the message.
 					// Now, apply the ReactToAtMention & reaction.Me exclusion.
 					if conditions.ReactToAtMention && reactionOnMessage.Me {
@@ -166,28 +574,17 @@ func checkRuleConditions(message *discordgo.Message, conditions *RuleConditions,
 			for _, r := range message.Reactions {
 				presentEmojis = append(presentEmojis, fmt.Sprintf("%s (Me:%t)", r.Emoji.Name, r.Me))
 			}
-			log.Debugf(logPrefix+"Condition failed (MessageHasEmoji): None of the required emojis %v were found (or applicable after exclusions). Present reactions: [%s]", conditions.MessageHasEmoji, strings.Join(presentEmojis, ", "))
-			return false
+			return conditionFailed(logPrefix, "MessageHasEmoji", "None of the required emojis %v were found (or applicable after exclusions). Present reactions: [%s]", conditions.MessageHasEmoji, strings.Join(presentEmojis, ", "))
 		}
 		// If anyEmojiFound is true, this log is implicitly covered by the positive match log inside the loop.
 		// log.Debugf(logPrefix+"Condition passed (MessageHasEmoji): At least one of required emojis %v found and applicable.", conditions.MessageHasEmoji)
 	}
 
-	// ContentIncludes condition (ALL keywords must be present)
+	// ContentIncludes condition (ALL keywords must be present, or ANY with contentMatch: anyOf)
 	if len(conditions.ContentIncludes) > 0 {
-		allKeywordsFound := true
-		lowerMessageContent := strings.ToLower(message.Content) // Optimize: convert message content to lower once
-		for _, keyword := range conditions.ContentIncludes {
-			if !strings.Contains(lowerMessageContent, strings.ToLower(keyword)) {
-				allKeywordsFound = false
-				log.Debugf(logPrefix+"Condition failed (ContentIncludes): keyword '%s' not found in message.", keyword)
-				break
-			}
+		if !checkContentIncludes(message.Content, conditions, logPrefix) {
+			return false, fmt.Sprintf("ContentIncludes: keywords %v not in message", conditions.ContentIncludes)
 		}
-		if !allKeywordsFound {
-			return false
-		}
-		log.Debugf(logPrefix+"Condition passed (ContentIncludes): All keywords %v found.", conditions.ContentIncludes)
 	}
 
 	// Mentions conditions: ReactToAtMention and SpecificMentions
@@ -216,8 +613,7 @@ func checkRuleConditions(message *discordgo.Message, conditions *RuleConditions,
 			if currentSessionState != nil && currentSessionState.User != nil {
 				botIDForLog = currentSessionState.User.ID
 			}
-			log.Debugf(logPrefix+"Condition failed (ReactToAtMention): Bot (ID: %s) was not mentioned in message content.", botIDForLog)
-			return false
+			return conditionFailed(logPrefix, "ReactToAtMention", "Bot (ID: %s) was not mentioned in message content.", botIDForLog)
 		}
 		log.Debugf(logPrefix + "Condition passed (ReactToAtMention): Bot was mentioned in message content.")
 	}
@@ -250,13 +646,109 @@ func checkRuleConditions(message *discordgo.Message, conditions *RuleConditions,
 			}
 		}
 		if !specificMentionFound {
-			log.Debugf(logPrefix+"Condition failed (SpecificMentions): None of the specified users/roles %v were mentioned.", conditions.SpecificMentions)
-			return false
+			return conditionFailed(logPrefix, "SpecificMentions", "None of the specified users/roles %v were mentioned.", conditions.SpecificMentions)
 		}
 		log.Debugf(logPrefix+"Condition passed (SpecificMentions): At least one of %v was mentioned.", conditions.SpecificMentions)
 	}
 
+	// ParentChannelID and ThreadNamePattern conditions (the message must be in, or be the creation of, a matching thread)
+	if conditions.ParentChannelID != "" || conditions.ThreadNamePattern != "" {
+		thread := messageThread(session, message)
+		if thread == nil {
+			return conditionFailed(logPrefix, "ParentChannelID/ThreadNamePattern", "message is not in a thread")
+		}
+		if conditions.ParentChannelID != "" && thread.ParentID != conditions.ParentChannelID {
+			return conditionFailed(logPrefix, "ParentChannelID", "thread parent %s != rule parent channel %s", thread.ParentID, conditions.ParentChannelID)
+		}
+		if conditions.ThreadNamePattern != "" {
+			re, err := compiledPattern(conditions.ThreadNamePattern)
+			if err != nil {
+				log.Errorf(logPrefix+"Invalid threadNamePattern '%s': %v. Condition will fail.", conditions.ThreadNamePattern, err)
+				return false, fmt.Sprintf("ThreadNamePattern: %v", err)
+			}
+			if !re.MatchString(thread.Name) {
+				return conditionFailed(logPrefix, "ThreadNamePattern", "thread name '%s' does not match '%s'", thread.Name, conditions.ThreadNamePattern)
+			}
+		}
+		log.Debugf(logPrefix+"Condition passed (ParentChannelID/ThreadNamePattern): thread '%s' under %s", thread.Name, thread.ParentID)
+	}
+
+	// EmbedColorIn condition (any embed's color matches any listed color) - ANY OF LOGIC
+	if len(conditions.EmbedColorIn) > 0 {
+		matched, err := hasEmbedColor(message, conditions.EmbedColorIn)
+		if err != nil {
+			log.Errorf(logPrefix+"%v. Condition will fail.", err)
+			return false, fmt.Sprintf("EmbedColorIn: %v", err)
+		}
+		if !matched {
+			return conditionFailed(logPrefix, "EmbedColorIn", "no embed with a color in %v", conditions.EmbedColorIn)
+		}
+		log.Debugf(logPrefix+"Condition passed (EmbedColorIn): %v", conditions.EmbedColorIn)
+	}
+
+	// WebhookIDs and WebhookNameIncludes conditions (the message must be posted by a matching webhook) - ANY OF LOGIC
+	if len(conditions.WebhookIDs) > 0 || len(conditions.WebhookNameIncludes) > 0 {
+		if message.WebhookID == "" {
+			return conditionFailed(logPrefix, "WebhookIDs/WebhookNameIncludes", "message was not posted by a webhook")
+		}
+		if len(conditions.WebhookIDs) > 0 && !containsFold(conditions.WebhookIDs, message.WebhookID) {
+			return conditionFailed(logPrefix, "WebhookIDs", "webhook %s not in %v", message.WebhookID, conditions.WebhookIDs)
+		}
+		if len(conditions.WebhookNameIncludes) > 0 {
+			webhookName := ""
+			if message.Author != nil {
+				webhookName = strings.ToLower(message.Author.Username)
+			}
+			nameFound := false
+			for _, part := range conditions.WebhookNameIncludes {
+				if strings.Contains(webhookName, strings.ToLower(part)) {
+					nameFound = true
+					break
+				}
+			}
+			if !nameFound {
+				return conditionFailed(logPrefix, "WebhookNameIncludes", "webhook name '%s' contains none of %v", webhookName, conditions.WebhookNameIncludes)
+			}
+		}
+		log.Debugf(logPrefix+"Condition passed (WebhookIDs/WebhookNameIncludes): webhook %s", message.WebhookID)
+	}
+
+	// IsReplyTo condition (the message must reply to a matching parent message)
+	if conditions.IsReplyTo != nil {
+		if !checkReplyCondition(session, message, conditions.IsReplyTo, logPrefix) {
+			return false, "IsReplyTo: not a reply to a matching message"
+		}
+	}
+
+	// When condition (expression over message fields)
+	if conditions.When != "" {
+		matched, err := evaluateWhen(conditions.When, message)
+		if err != nil {
+			log.Errorf(logPrefix+"%v. Condition will fail.", err)
+			return false, fmt.Sprintf("When: %v", err)
+		}
+		if !matched {
+			return conditionFailed(logPrefix, "When", "%s", conditions.When)
+		}
+		log.Debugf(logPrefix+"Condition passed (When): %s", conditions.When)
+	}
+
+	// AllOf, AnyOf and Not condition groups
+	if len(conditions.AllOf) > 0 || len(conditions.AnyOf) > 0 || conditions.Not != nil {
+		if met, reason := checkConditionGroups(ctx, config, message, conditions, session, ruleNameLog, logPrefix); !met {
+			return false, reason
+		}
+		log.Debugf(logPrefix + "Condition passed (AllOf/AnyOf/Not).")
+	}
+
+	// Classify condition (external classifier; evaluated last since it makes an HTTP request)
+	if conditions.Classify != nil {
+		if !checkClassifyCondition(ctx, config, message, conditions.Classify, logPrefix) {
+			return false, "Classify: no wanted label, or the classifier failed"
+		}
+	}
+
 	// If all active conditions passed (or no conditions were active), the rule conditions are met.
 	log.Debugf(logPrefix + "All active conditions passed for rule.")
-	return true
+	return true, ""
 }
@@ -1,7 +1,8 @@
-package main
+package discord2pushover
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
 	"strings"
@@ -146,7 +147,7 @@ func TestCheckRuleConditions_MessageHasEmoji_Logic(t *testing.T) {
 				tt.conditions.ChannelID = msg.ChannelID
 			}
 
-			result := checkRuleConditions(msg, &tt.conditions, session, tt.name)
+			result := checkRuleConditions(context.Background(), nil, msg, &tt.conditions, session, tt.name)
 			if result != tt.expectedResult {
 				t.Errorf("Test '%s': Expected result %v, got %v", tt.name, tt.expectedResult, result)
 			}
@@ -205,7 +206,7 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 	}{
 		{
 			name:                           "Notify_PrioMaxInt32",
-			rule:                           Rule{Name: "TestRule1", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "userkey", ReactionEmoji: "👍"}},
+			rule:                           Rule{Name: "TestRule1", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "userkey", ReactionEmoji: EmojiList{"👍"}}},
 			previouslyNotifiedRulePriority: math.MaxInt32,
 			configPushoverAppKey:           "fakeAppKey",
 			expectSuppressionLog:           false,
@@ -214,7 +215,7 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 		},
 		{
 			name:                           "Notify_CurrentPrioHigher",
-			rule:                           Rule{Name: "TestRule2", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 1, PushoverDestination: "userkey", ReactionEmoji: "👍"}},
+			rule:                           Rule{Name: "TestRule2", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 1, PushoverDestination: "userkey", ReactionEmoji: EmojiList{"👍"}}},
 			previouslyNotifiedRulePriority: 0,
 			configPushoverAppKey:           "fakeAppKey",
 			expectSuppressionLog:           false,
@@ -223,7 +224,7 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 		},
 		{
 			name:                           "Suppress_CurrentPrioEqual",
-			rule:                           Rule{Name: "TestRule3", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "userkey", ReactionEmoji: "👍"}},
+			rule:                           Rule{Name: "TestRule3", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "userkey", ReactionEmoji: EmojiList{"👍"}}},
 			previouslyNotifiedRulePriority: 0,
 			configPushoverAppKey:           "fakeAppKey",
 			expectSuppressionLog:           true,
@@ -232,7 +233,7 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 		},
 		{
 			name:                           "Suppress_CurrentPrioLower",
-			rule:                           Rule{Name: "TestRule4", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: -1, PushoverDestination: "userkey", ReactionEmoji: "👍"}},
+			rule:                           Rule{Name: "TestRule4", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: -1, PushoverDestination: "userkey", ReactionEmoji: EmojiList{"👍"}}},
 			previouslyNotifiedRulePriority: 0,
 			configPushoverAppKey:           "fakeAppKey",
 			expectSuppressionLog:           true,
@@ -241,7 +242,7 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 		},
 		{
 			name:                           "NoPushover_NoDestination",
-			rule:                           Rule{Name: "TestRule5", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "", ReactionEmoji: "👍"}},
+			rule:                           Rule{Name: "TestRule5", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "", ReactionEmoji: EmojiList{"👍"}}},
 			previouslyNotifiedRulePriority: math.MaxInt32,
 			configPushoverAppKey:           "fakeAppKey",
 			expectSuppressionLog:           false,
@@ -250,7 +251,7 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 		},
 		{
 			name:                           "NoPushover_NoAppKey",
-			rule:                           Rule{Name: "TestRule6", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "userkey", ReactionEmoji: "👍"}},
+			rule:                           Rule{Name: "TestRule6", Conditions: RuleConditions{ChannelID: "chProcRules"}, Actions: RuleActions{Priority: 0, PushoverDestination: "userkey", ReactionEmoji: EmojiList{"👍"}}},
 			previouslyNotifiedRulePriority: math.MaxInt32,
 			configPushoverAppKey:           "",
 			expectSuppressionLog:           false,
@@ -281,7 +282,7 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 				Rules:          []Rule{tt.rule},
 			}
 
-			ProcessRules(baseMsg, globalConfig, mockSession, tt.previouslyNotifiedRulePriority)
+			ProcessRules(context.Background(), baseMsg, globalConfig, mockSession, tt.previouslyNotifiedRulePriority)
 			logOutput := testLogCap.String()
 
 			suppressionLogExpected := fmt.Sprintf("Suppressing Pushover notification for rule '%s'", tt.rule.Name)
@@ -313,18 +314,272 @@ func TestProcessRules_NotificationSuppression(t *testing.T) {
 				}
 			}
 
-			reactionAddLogExpected := fmt.Sprintf("MockDiscordSession: MessageReactionAdd called with: chID=%s, msgID=%s, emoji=%s", baseMsg.ChannelID, baseMsg.ID, tt.rule.Actions.ReactionEmoji)
+			reactionAddLogExpected := fmt.Sprintf("MockDiscordSession: MessageReactionAdd called with: chID=%s, msgID=%s, emoji=%s", baseMsg.ChannelID, baseMsg.ID, strings.Join(tt.rule.Actions.ReactionEmoji, ""))
 			if tt.expectReactionAddLog {
 				if !strings.Contains(logOutput, reactionAddLogExpected) {
 					t.Errorf("Expected MessageReactionAdd log ('%s') not found. Log: %s", reactionAddLogExpected, logOutput)
 				}
 			} else {
-				if tt.rule.Actions.ReactionEmoji != "" && strings.Contains(logOutput, reactionAddLogExpected) {
+				if len(tt.rule.Actions.ReactionEmoji) > 0 && strings.Contains(logOutput, reactionAddLogExpected) {
 					t.Errorf("Unexpected MessageReactionAdd log ('%s') found. Log: %s", reactionAddLogExpected, logOutput)
-				} else if tt.rule.Actions.ReactionEmoji == "" && strings.Contains(logOutput, "MockDiscordSession: MessageReactionAdd called") {
+				} else if len(tt.rule.Actions.ReactionEmoji) == 0 && strings.Contains(logOutput, "MockDiscordSession: MessageReactionAdd called") {
 					t.Errorf("Unexpected MessageReactionAdd log found when no ReactionEmoji was set. Log: %s", logOutput)
 				}
 			}
 		})
 	}
 }
+
+func TestCheckRuleConditions_IsReplyTo(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	incidentBotID := "incidentBot"
+	parent := &discordgo.Message{ID: "parentMsg", ChannelID: "chReply", Author: &discordgo.User{ID: incidentBotID}, Content: "INCIDENT-42 opened: api down"}
+
+	mockSess := &MockDiscordSession{
+		TestStateOverride: mockSessionForRulesTest("bot").State(),
+		CustomChannelMessageFunc: func(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+			if messageID == parent.ID {
+				return parent, nil
+			}
+			return nil, fmt.Errorf("unexpected ChannelMessage call for %s", messageID)
+		},
+	}
+
+	tests := []struct {
+		name           string
+		message        *discordgo.Message
+		condition      ReplyCondition
+		expectedResult bool
+	}{
+		{
+			name:           "NotAReply",
+			message:        &discordgo.Message{ID: "m1", ChannelID: "chReply"},
+			condition:      ReplyCondition{},
+			expectedResult: false,
+		},
+		{
+			name: "ReplyFetchedParent_AuthorMatches",
+			message: &discordgo.Message{ID: "m2", ChannelID: "chReply", Type: discordgo.MessageTypeReply,
+				MessageReference: &discordgo.MessageReference{MessageID: parent.ID}},
+			condition:      ReplyCondition{AuthorIDs: []string{incidentBotID}},
+			expectedResult: true,
+		},
+		{
+			name:           "ReplyEmbeddedParent_AuthorMismatch",
+			message:        &discordgo.Message{ID: "m3", ChannelID: "chReply", ReferencedMessage: parent},
+			condition:      ReplyCondition{AuthorIDs: []string{"someoneElse"}},
+			expectedResult: false,
+		},
+		{
+			name:           "ReplyEmbeddedParent_ContentPatternMatches",
+			message:        &discordgo.Message{ID: "m4", ChannelID: "chReply", ReferencedMessage: parent},
+			condition:      ReplyCondition{ContentPattern: `^INCIDENT-\d+`},
+			expectedResult: true,
+		},
+		{
+			name:           "ReplyEmbeddedParent_ContentPatternMismatch",
+			message:        &discordgo.Message{ID: "m5", ChannelID: "chReply", ReferencedMessage: parent},
+			condition:      ReplyCondition{ContentPattern: `resolved`},
+			expectedResult: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := tt.condition
+			conditions := RuleConditions{IsReplyTo: &cond}
+			if result := checkRuleConditions(context.Background(), nil, tt.message, &conditions, mockSess, tt.name); result != tt.expectedResult {
+				t.Errorf("Expected %v, got %v. Log: %s", tt.expectedResult, result, testLogBufferForTest.String())
+			}
+		})
+	}
+}
+
+func TestCheckRuleConditions_Webhook(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	mockSess := mockSessionForRulesTest("bot")
+	grafana := &discordgo.Message{ID: "m1", ChannelID: "ch", WebhookID: "wh1", Author: &discordgo.User{ID: "wh1", Username: "Grafana Alerts (prod)"}}
+	user := &discordgo.Message{ID: "m2", ChannelID: "ch", Author: &discordgo.User{ID: "u1", Username: "grafana-fan"}}
+
+	tests := []struct {
+		name           string
+		message        *discordgo.Message
+		conditions     RuleConditions
+		expectedResult bool
+	}{
+		{"WebhookIDMatches", grafana, RuleConditions{WebhookIDs: []string{"wh0", "wh1"}}, true},
+		{"WebhookIDMismatch", grafana, RuleConditions{WebhookIDs: []string{"wh2"}}, false},
+		{"WebhookNameMatches", grafana, RuleConditions{WebhookNameIncludes: []string{"uptime", "grafana"}}, true},
+		{"WebhookNameMismatch", grafana, RuleConditions{WebhookNameIncludes: []string{"github"}}, false},
+		{"BothMatch", grafana, RuleConditions{WebhookIDs: []string{"wh1"}, WebhookNameIncludes: []string{"prod"}}, true},
+		{"NotAWebhook", user, RuleConditions{WebhookNameIncludes: []string{"grafana"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := checkRuleConditions(context.Background(), nil, tt.message, &tt.conditions, mockSess, tt.name); result != tt.expectedResult {
+				t.Errorf("Expected %v, got %v. Log: %s", tt.expectedResult, result, testLogBufferForTest.String())
+			}
+		})
+	}
+}
+
+func TestCheckRuleConditions_ContentIncludesOptions(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	mockSess := mockSessionForRulesTest("bot")
+	feedback := &discordgo.Message{ID: "m1", Content: "Thanks for the feedback!"}
+	dbDown := &discordgo.Message{ID: "m2", Content: "Primary DB is down\nfailover (db-2) started"}
+	accented := &discordgo.Message{ID: "m3", Content: "Café İSTANBUL: Straße gesperrt"}
+
+	tests := []struct {
+		name           string
+		message        *discordgo.Message
+		conditions     RuleConditions
+		expectedResult bool
+	}{
+		{"SubstringMatchesInsideWord", feedback, RuleConditions{ContentIncludes: []string{"db"}}, true},
+		{"WholeWordRejectsInsideWord", feedback, RuleConditions{ContentIncludes: []string{"db"}, WholeWord: true}, false},
+		{"WholeWordMatchesWord", dbDown, RuleConditions{ContentIncludes: []string{"db"}, WholeWord: true}, true},
+		{"WholeWordMatchesAcrossLines", dbDown, RuleConditions{ContentIncludes: []string{"down", "failover"}, WholeWord: true}, true},
+		{"WholeWordWithPunctuation", dbDown, RuleConditions{ContentIncludes: []string{"db-2"}, WholeWord: true}, true},
+		{"MatchCaseRejects", dbDown, RuleConditions{ContentIncludes: []string{"primary db"}, MatchCase: true}, false},
+		{"MatchCaseMatches", dbDown, RuleConditions{ContentIncludes: []string{"Primary DB"}, MatchCase: true}, true},
+		{"AllOfFailsOnOneMissing", dbDown, RuleConditions{ContentIncludes: []string{"down", "rollback"}}, false},
+		{"AnyOfMatchesOne", dbDown, RuleConditions{ContentIncludes: []string{"rollback", "down"}, ContentMatch: "anyOf"}, true},
+		{"AnyOfMatchesNone", feedback, RuleConditions{ContentIncludes: []string{"rollback", "down"}, ContentMatch: "anyOf"}, false},
+		{"CaseFoldingSharpS", accented, RuleConditions{ContentIncludes: []string{"STRASSE"}}, true},
+		{"CaseFoldingTurkishI", accented, RuleConditions{ContentIncludes: []string{"ıstanbul"}, WholeWord: true}, true},
+		{"DiacriticsKeptByDefault", accented, RuleConditions{ContentIncludes: []string{"cafe"}}, false},
+		{"FoldDiacritics", accented, RuleConditions{ContentIncludes: []string{"cafe", "istanbul"}, FoldDiacritics: true, WholeWord: true}, true},
+		{"FoldDiacriticsWithMatchCase", accented, RuleConditions{ContentIncludes: []string{"cafe"}, FoldDiacritics: true, MatchCase: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := checkRuleConditions(context.Background(), nil, tt.message, &tt.conditions, mockSess, tt.name); result != tt.expectedResult {
+				t.Errorf("Expected %v, got %v. Log: %s", tt.expectedResult, result, testLogBufferForTest.String())
+			}
+		})
+	}
+}
+
+func TestProcessRules_MultipleReactionsAndPendingEmoji(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+
+	config := &Config{Rules: []Rule{{
+		Name:       "Page",
+		Conditions: RuleConditions{ChannelID: "ops"},
+		Actions: RuleActions{
+			PushoverDestination: "uKey",
+			Priority:            2,
+			ReactionEmoji:       EmojiList{"📟", "🔴"},
+			Emergency:           &EmergencyParams{AckEmoji: "✅", PendingEmoji: "⏳", Expire: 600, Retry: 60},
+		},
+	}}}
+	message := &discordgo.Message{ID: "m1", ChannelID: "ops", Author: &discordgo.User{ID: "u1"}}
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+
+	logs := testLogBufferForTest.String()
+	pager := strings.Index(logs, "msgID=m1, emoji=📟")
+	severity := strings.Index(logs, "msgID=m1, emoji=🔴")
+	pending := strings.Index(logs, "msgID=m1, emoji=⏳")
+	if pager < 0 || severity < pager || pending < 0 {
+		t.Errorf("Expected 📟, then 🔴, and ⏳ to be added. Logs:\n%s", logs)
+	}
+	tracked, ok := config.trackedEmergencies().Load("fake-receipt-id-for-test")
+	if !ok || tracked.(TrackedEmergencyMessage).PendingEmoji != "⏳" {
+		t.Fatalf("Expected the emergency to be tracked with its pending emoji, got %+v", tracked)
+	}
+
+	// The pending emoji marks the message as handled like the reaction emoji do
+	if !ruleReactsWith(&config.Rules[0], &discordgo.Emoji{Name: "⏳"}) || ruleReactsWith(&config.Rules[0], &discordgo.Emoji{Name: "✅"}) {
+		t.Error("Expected ruleReactsWith to recognize the reaction and pending emoji only")
+	}
+}
+
+func TestProcessRules_RuleEvaluation(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() {
+		testHookDisablePushoverSend = false
+		testHookPushoverSendCalled = false
+	}()
+
+	config := &Config{
+		Rules: []Rule{
+			{Name: "EvalTeam", Conditions: RuleConditions{ContentIncludes: []string{"disk"}}, Actions: RuleActions{PushoverDestination: "team"}},
+			{Name: "EvalTeamAgain", Conditions: RuleConditions{ContentIncludes: []string{"full"}}, Actions: RuleActions{PushoverDestination: "team"}},
+			{Name: "EvalOnCall", Conditions: RuleConditions{ContentIncludes: []string{"disk"}}, Actions: RuleActions{PushoverDestination: "oncall"}},
+			{Name: "EvalOther", Conditions: RuleConditions{ContentIncludes: []string{"cpu"}}, Actions: RuleActions{PushoverDestination: "oncall"}},
+		},
+	}
+	message := &discordgo.Message{ID: "m1", ChannelID: "c1", Content: "disk full", Author: &discordgo.User{ID: "u1"}}
+
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, "Pushover notification sent for rule 'EvalTeam'") || strings.Contains(logs, "EvalOnCall") {
+		t.Errorf("Expected only the first matching rule in firstMatch mode. Logs:\n%s", logs)
+	}
+
+	testLogBufferForTest.Reset()
+	config.RuleEvaluation = ruleEvaluationAllMatches
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	logs = testLogBufferForTest.String()
+	for _, want := range []string{
+		"Pushover notification sent for rule 'EvalTeam'",
+		"Suppressing notification for rule 'EvalTeamAgain' on message ID m1: its destinations were already notified by an earlier rule.",
+		"Pushover notification sent for rule 'EvalOnCall'",
+		"3 rule(s) matched for message ID m1: EvalTeam, EvalTeamAgain, EvalOnCall.",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected %q in allMatches mode. Logs:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "Pushover notification sent for rule 'EvalTeamAgain'") {
+		t.Errorf("Expected the duplicate destination to be skipped. Logs:\n%s", logs)
+	}
+}
+
+func TestDestinationSet_Claim(t *testing.T) {
+	s := make(destinationSet)
+	if dest, notify := s.claim("team", []string{"matrix", "sms"}); dest != "team" || len(notify) != 2 {
+		t.Errorf("Expected everything unclaimed at first, got %q %v", dest, notify)
+	}
+	if dest, notify := s.claim("team", []string{"sms", "mqtt"}); dest != "" || len(notify) != 1 || notify[0] != "mqtt" {
+		t.Errorf("Expected only new destinations, got %q %v", dest, notify)
+	}
+}
+
+func TestProcessRules_CancelledContext(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	testHookPushoverSendCalled = false
+	defer func() {
+		testHookDisablePushoverSend = false
+		testHookPushoverSendCalled = false
+	}()
+
+	config := &Config{Rules: []Rule{
+		{Name: "Cancelled", Conditions: RuleConditions{ContentIncludes: []string{"disk"}}, Actions: RuleActions{PushoverDestination: "team"}},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ProcessRules(ctx, &discordgo.Message{ID: "m1", ChannelID: "c1", Content: "disk full"}, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+	if testHookPushoverSendCalled {
+		t.Error("Expected no notification once the context is cancelled")
+	}
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "Stopped processing rules for message ID m1 before rule 'Cancelled': context canceled") {
+		t.Errorf("Expected the cancellation to be logged. Logs:\n%s", logs)
+	}
+}
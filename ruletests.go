@@ -0,0 +1,297 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleTestBotID is the bot's user ID in rule tests; mention it to test reactToAtMention.
+const ruleTestBotID = "bot"
+
+// RuleTest is a synthetic message and the rule expected to match it, run by `validate --run-tests`.
+type RuleTest struct {
+	Name    string      `yaml:"name"`
+	Event   string      `yaml:"event,omitempty"` // Rule event the message is processed as; default "message"
+	Message TestMessage `yaml:"message"`
+	Expect  string      `yaml:"expect"` // Name of the rule expected to match first; empty if no rule should match
+}
+
+// TestMessage is the synthetic message of a RuleTest.
+type TestMessage struct {
+	ID              string      `yaml:"id"`
+	Content         string      `yaml:"content"`
+	ChannelID       string      `yaml:"channelId"`
+	GuildID         string      `yaml:"guildId"`
+	AuthorID        string      `yaml:"authorId"`
+	AuthorName      string      `yaml:"authorName"`
+	AuthorBot       bool        `yaml:"authorBot"`
+	WebhookID       string      `yaml:"webhookId"`
+	Mentions        []string    `yaml:"mentions"`     // Mentioned user IDs; "bot" is the bot itself
+	RoleMentions    []string    `yaml:"roleMentions"` // Mentioned role IDs
+	Reactions       []string    `yaml:"reactions"`    // Emoji others reacted with
+	Embeds          []TestEmbed `yaml:"embeds"`
+	ReplyTo         *TestReply  `yaml:"replyTo,omitempty"`
+	ThreadName      string      `yaml:"threadName"`      // The message is in a thread with this name
+	ParentChannelID string      `yaml:"parentChannelId"` // The thread's parent channel
+	ActionType      string      `yaml:"actionType"`      // onAutomod/onAuditLog events
+	AutomodRuleName string      `yaml:"automodRuleName"` // onAutomod events
+}
+
+// TestEmbed is an embed of a TestMessage.
+type TestEmbed struct {
+	Title       string            `yaml:"title"`
+	Description string            `yaml:"description"`
+	URL         string            `yaml:"url"`
+	Color       string            `yaml:"color"`  // e.g. "#ff0000"
+	Fields      map[string]string `yaml:"fields"` // Field name to value, added in name order
+	Footer      string            `yaml:"footer"`
+}
+
+// TestReply is the parent message a TestMessage replies to.
+type TestReply struct {
+	AuthorID string `yaml:"authorId"`
+	Content  string `yaml:"content"`
+}
+
+// ruleTestFile is the format of the tests file next to the config.
+type ruleTestFile struct {
+	Tests []RuleTest `yaml:"tests"`
+}
+
+// ruleTestSession is the Discord session of rule tests. It knows nothing but the bot's user, so
+// conditions needing Discord data only see what the test message provides.
+type ruleTestSession struct {
+	state *discordgo.State
+}
+
+func newRuleTestSession() *ruleTestSession {
+	state := discordgo.NewState()
+	state.User = &discordgo.User{ID: ruleTestBotID}
+	return &ruleTestSession{state: state}
+}
+
+var errRuleTestOffline = errors.New("not available in rule tests")
+
+func (s *ruleTestSession) ChannelMessage(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return nil, errRuleTestOffline
+}
+
+func (s *ruleTestSession) State() *discordgo.State {
+	return s.state
+}
+
+func (s *ruleTestSession) MessageReactionAdd(channelID, messageID, emojiID string, opts ...discordgo.RequestOption) error {
+	return errRuleTestOffline
+}
+
+func (s *ruleTestSession) MessageReactionRemove(channelID, messageID, emojiID, userID string, opts ...discordgo.RequestOption) error {
+	return errRuleTestOffline
+}
+
+func (s *ruleTestSession) ChannelMessageSend(channelID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return nil, errRuleTestOffline
+}
+
+func (s *ruleTestSession) ChannelMessageSendReply(channelID, content string, reference *discordgo.MessageReference, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return nil, errRuleTestOffline
+}
+
+func (s *ruleTestSession) ChannelMessageEdit(channelID, messageID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return nil, errRuleTestOffline
+}
+
+func (s *ruleTestSession) ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string, opts ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	return nil, errRuleTestOffline
+}
+
+// discordMessage builds the Discord message and event details a test describes.
+func (m *TestMessage) discordMessage() (*discordgo.Message, *EventDetails, error) {
+	message := &discordgo.Message{
+		ID:           m.ID,
+		ChannelID:    m.ChannelID,
+		GuildID:      m.GuildID,
+		Content:      m.Content,
+		WebhookID:    m.WebhookID,
+		Author:       &discordgo.User{ID: m.AuthorID, Username: m.AuthorName, Bot: m.AuthorBot},
+		MentionRoles: m.RoleMentions,
+	}
+	if message.ID == "" {
+		message.ID = "test"
+	}
+	for _, id := range m.Mentions {
+		message.Mentions = append(message.Mentions, &discordgo.User{ID: id})
+	}
+	for _, emoji := range m.Reactions {
+		spec := parseEmojiSpec(emoji)
+		message.Reactions = append(message.Reactions, &discordgo.MessageReactions{Count: 1, Emoji: &discordgo.Emoji{Name: spec.Name, ID: spec.ID}})
+	}
+	for _, e := range m.Embeds {
+		embed := &discordgo.MessageEmbed{Title: e.Title, Description: e.Description, URL: e.URL}
+		if e.Color != "" {
+			color, err := parseEmbedColor(e.Color)
+			if err != nil {
+				return nil, nil, err
+			}
+			embed.Color = color
+		}
+		names := make([]string, 0, len(e.Fields))
+		for name := range e.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: name, Value: e.Fields[name]})
+		}
+		if e.Footer != "" {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: e.Footer}
+		}
+		message.Embeds = append(message.Embeds, embed)
+	}
+	if m.ReplyTo != nil {
+		message.Type = discordgo.MessageTypeReply
+		message.ReferencedMessage = &discordgo.Message{Author: &discordgo.User{ID: m.ReplyTo.AuthorID}, Content: m.ReplyTo.Content}
+	}
+	if m.ThreadName != "" || m.ParentChannelID != "" {
+		message.Thread = &discordgo.Channel{ID: m.ChannelID, Name: m.ThreadName, ParentID: m.ParentChannelID, Type: discordgo.ChannelTypeGuildPublicThread}
+	}
+	var details *EventDetails
+	if m.ActionType != "" || m.AutomodRuleName != "" {
+		details = &EventDetails{ActionType: m.ActionType, ModerationRuleName: m.AutomodRuleName}
+	}
+	return message, details, nil
+}
+
+// matchingRules returns the names of the rules matching a message for an event, without running
+// their actions: only the first in firstMatch mode, all of them in allMatches mode.
+func matchingRules(ctx context.Context, config *Config, event string, message *discordgo.Message, details *EventDetails, session DiscordSessionInterface) []string {
+	if isIgnored(config, message) {
+		return nil
+	}
+	var matched []string
+	for _, i := range candidateRules(config, event, message.ChannelID) {
+		rule := &config.Rules[i]
+		if ruleEvent(rule) != event {
+			continue
+		}
+		ruleNameLog := ruleNameForLog(rule, i)
+		conditionsMet, _ := evaluateRuleConditions(ctx, config, message, &rule.Conditions, session, ruleNameLog)
+		if conditionsMet {
+			conditionsMet, _ = checkEventConditions(details, &rule.Conditions, ruleNameLog)
+		}
+		if conditionsMet {
+			conditionsMet, _ = checkScheduleCondition(config, &rule.Conditions, time.Now(), ruleNameLog)
+		}
+		if conditionsMet && rule.Scoring != nil {
+			_, threshold, _ := scoreMessage(ctx, config, message, rule.Scoring, session, ruleNameLog)
+			conditionsMet = threshold != nil
+		}
+		if !conditionsMet {
+			continue
+		}
+		matched = append(matched, ruleNameLog)
+		if config.RuleEvaluation != ruleEvaluationAllMatches {
+			break
+		}
+	}
+	return matched
+}
+
+// ruleTestsPath returns the tests file next to a config, e.g. "discord2pushover.tests.yaml" for
+// "discord2pushover.yaml".
+func ruleTestsPath(configPath string) string {
+	ext := filepath.Ext(configPath)
+	return strings.TrimSuffix(configPath, ext) + ".tests" + ext
+}
+
+// loadRuleTests returns the config's tests followed by those of the tests file next to it, if any.
+func loadRuleTests(config *Config, configPath string) ([]RuleTest, error) {
+	tests := config.Tests
+	path := ruleTestsPath(configPath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tests, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file ruleTestFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return append(tests, file.Tests...), nil
+}
+
+// runRuleTests runs the tests against the config's rules, printing a line per failure and a
+// summary, and returns the number of failed tests.
+func runRuleTests(config *Config, tests []RuleTest, w io.Writer) int {
+	session := newRuleTestSession()
+	failed := 0
+	for i, test := range tests {
+		name := test.Name
+		if name == "" {
+			name = fmt.Sprintf("test #%d", i+1)
+		}
+		event := test.Event
+		if event == "" {
+			event = ruleEventMessage
+		}
+		message, details, err := test.Message.discordMessage()
+		if err != nil {
+			fmt.Fprintf(w, "FAIL %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		got := "(no rule)"
+		if matched := matchingRules(context.Background(), config, event, message, details, session); len(matched) > 0 {
+			got = strings.Join(matched, ", ")
+			if test.Expect != "" && matched[0] == test.Expect {
+				continue
+			}
+		} else if test.Expect == "" {
+			continue
+		}
+		want := test.Expect
+		if want == "" {
+			want = "(no rule)"
+		}
+		fmt.Fprintf(w, "FAIL %s: expected %s, matched %s\n", name, want, got)
+		failed++
+	}
+	fmt.Fprintf(w, "%d of %d rule tests passed.\n", len(tests)-failed, len(tests))
+	return failed
+}
+
+// runValidate reports that the config is valid, as LoadConfig has already checked it, and runs the
+// rule tests with runTests. Returns the process exit code.
+func runValidate(config *Config, configPath string, runTests bool, w io.Writer) int {
+	fmt.Fprintf(w, "%s is valid (%d rules).\n", configPath, len(config.Rules))
+	if !runTests {
+		return 0
+	}
+	tests, err := loadRuleTests(config, configPath)
+	if err != nil {
+		log.Errorf("Error loading rule tests: %v", err)
+		return 1
+	}
+	if len(tests) == 0 {
+		fmt.Fprintf(w, "No rule tests found in the config's 'tests' or in %s.\n", ruleTestsPath(configPath))
+		return 0
+	}
+	if runRuleTests(config, tests, w) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ruleTestsConfig = `discordToken: "token"
+pushoverAppKey: "app"
+rules:
+  - name: Pages
+    conditions:
+      channelId: "ops"
+      contentIncludes: ["page"]
+    actions:
+      pushoverDestination: "oncall"
+  - name: Mentions
+    conditions:
+      reactToAtMention: true
+    actions:
+      pushoverDestination: "team"
+  - name: Red alerts
+    conditions:
+      embedColorIn: ["red"]
+    actions:
+      pushoverDestination: "team"
+tests:
+  - name: page in ops
+    message: {channelId: "ops", content: "please page someone"}
+    expect: Pages
+  - name: page elsewhere
+    message: {channelId: "random", content: "page me"}
+    expect: ""
+  - name: bot mention
+    message: {channelId: "random", mentions: ["bot"]}
+    expect: Mentions
+`
+
+func TestRunValidate_RuleTests(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "discord2pushover.yaml")
+	if err := os.WriteFile(path, []byte(ruleTestsConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := runValidate(config, path, true, &out); code != 0 {
+		t.Errorf("Expected the config's tests to pass, got exit code %d:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "3 of 3 rule tests passed.") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+
+	// The tests file next to the config adds to the config's tests
+	testsFile := `tests:
+  - name: grafana embed
+    message:
+      embeds: [{title: "[FIRING:1] HighCPU", color: "#e02f44"}]
+    expect: Red alerts
+  - name: wrong expectation
+    message: {channelId: "ops", content: "page"}
+    expect: Mentions
+`
+	if err := os.WriteFile(filepath.Join(dir, "discord2pushover.tests.yaml"), []byte(testsFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := runValidate(config, path, true, &out); code != 1 {
+		t.Errorf("Expected a failing test to fail validation, got exit code %d", code)
+	}
+	if !strings.Contains(out.String(), "FAIL wrong expectation: expected Mentions, matched Pages\n") ||
+		!strings.Contains(out.String(), "4 of 5 rule tests passed.") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := runValidate(config, path, false, &out); code != 0 || strings.Contains(out.String(), "rule tests") {
+		t.Errorf("Expected tests to run only with --run-tests, got exit code %d:\n%s", code, out.String())
+	}
+}
@@ -0,0 +1,33 @@
+package discord2pushover
+
+import (
+	"context"
+	"time"
+)
+
+// defaultEventTimeout bounds the processing of one Discord event, including the notifications it sends.
+const defaultEventTimeout = 60 * time.Second
+
+// backgroundSendTimeout bounds notifications not sent on behalf of an event, e.g. error reports and
+// flood summaries, and those sent while shutting down.
+const backgroundSendTimeout = 30 * time.Second
+
+// runContext is cancelled when the bot shuts down, aborting in-flight rule processing and
+// notifications. Discord event handlers cannot be passed a context, so they derive theirs from it.
+var runContext = context.Background()
+
+// eventContext returns the context for processing one Discord event, bounded by the config's
+// eventTimeoutSeconds.
+func eventContext(config *Config) (context.Context, context.CancelFunc) {
+	timeout := defaultEventTimeout
+	if config != nil && config.EventTimeoutSeconds > 0 {
+		timeout = time.Duration(config.EventTimeoutSeconds) * time.Second
+	}
+	return context.WithTimeout(runContext, timeout)
+}
+
+// backgroundContext returns the context for a notification that must not be cut short by the event
+// that caused it, such as the report of that event's failed send.
+func backgroundContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(runContext, backgroundSendTimeout)
+}
@@ -0,0 +1,27 @@
+package discord2pushover
+
+import (
+	"hash/fnv"
+)
+
+// sampleBuckets is the resolution of samplePercent: 0.01%.
+const sampleBuckets = 10000
+
+// sampleBucket places a message in one of sampleBuckets buckets, the same for every evaluation of
+// the message by a rule, so edits and reactions don't re-roll the decision.
+func sampleBucket(ruleNameLog string, messageID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(ruleNameLog))
+	h.Write([]byte{0})
+	h.Write([]byte(messageID))
+	return int(h.Sum32() % sampleBuckets)
+}
+
+// sampledIn reports whether a match of the rule is notified under its samplePercent. Rules without
+// samplePercent, or with 100 or more, notify every match.
+func sampledIn(rule *Rule, ruleNameLog string, messageID string) bool {
+	if rule.SamplePercent <= 0 || rule.SamplePercent >= 100 {
+		return true
+	}
+	return float64(sampleBucket(ruleNameLog, messageID)) < rule.SamplePercent*sampleBuckets/100
+}
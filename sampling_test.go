@@ -0,0 +1,58 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestProcessRules_SamplePercent(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		TraceDecisions: true,
+		Rules: []Rule{{
+			Name:          "Chatter",
+			Conditions:    RuleConditions{ChannelID: "general"},
+			Actions:       RuleActions{PushoverDestination: "uKey"},
+			SamplePercent: 10,
+		}},
+	}
+	config.SetPushoverClient(fake)
+	session := mockSessionForRulesTest("bot")
+
+	const messages = 1000
+	for i := 0; i < messages; i++ {
+		message := &discordgo.Message{ID: fmt.Sprintf("m%d", i), ChannelID: "general", Content: "hello", Author: &discordgo.User{ID: "u1"}}
+		ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+	}
+	if n := len(fake.sent); n < messages/20 || n > messages/5 {
+		t.Errorf("Expected about 10%% of %d matches to be notified, got %d", messages, n)
+	}
+	stats := countersFor("Chatter").snapshot("Chatter")
+	if stats.Matched != messages || stats.Suppressed != int64(messages-len(fake.sent)) {
+		t.Errorf("Expected sampled-out matches to count as suppressed, got %+v", stats)
+	}
+	logs := testLogBufferForTest.String()
+	if !strings.Contains(logs, `sampled out (samplePercent 10)`) || !strings.Contains(logs, `sampled in (samplePercent 10)`) {
+		t.Errorf("Expected the sampling decisions in the decision trace. Logs:\n%.2000s", logs)
+	}
+
+	// The decision is the same for every evaluation of a message
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("m%d", i)
+		if sampledIn(&config.Rules[0], "Chatter", id) != sampledIn(&config.Rules[0], "Chatter", id) {
+			t.Fatalf("Expected a stable decision for message %s", id)
+		}
+	}
+	if !sampledIn(&Rule{SamplePercent: 100}, "All", "m1") || !sampledIn(&Rule{}, "Unset", "m1") {
+		t.Error("Expected rules without sampling to notify every match")
+	}
+}
@@ -0,0 +1,225 @@
+package discord2pushover
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSchema is the subset of JSON Schema used to describe the config.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"` // false or a *jsonSchema
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+// envVarDef names the schema of "$VAR" placeholders, which may stand in for any scalar.
+const envVarDef = "envVar"
+
+const envVarPattern = `^\$(\{[A-Z_][A-Z0-9_]*\}|[A-Z_][A-Z0-9_]*)$`
+
+// schemaEnums lists the allowed values of fields, by struct type and YAML key.
+var schemaEnums = map[string][]string{
+	"Rule.event":                  {ruleEventMessage, ruleEventPin, ruleEventThreadCreate, ruleEventAutomod, ruleEventAuditLog, ruleEventCommand, ruleEventAlertmanager},
+	"RuleConditions.contentMatch": {contentMatchAllOf, contentMatchAnyOf},
+	"RuleConditions.schedule":     {scheduleBusinessHours, scheduleOffHours},
+	"Config.logSink":              {logSinkSyslog, logSinkJournald},
+	"Config.ruleEvaluation":       {ruleEvaluationFirstMatch, ruleEvaluationAllMatches},
+	"TwilioNotifier.mode":         {"sms", "call"},
+	"PagerDutyNotifier.region":    {"us", "eu"},
+	"OpsgenieNotifier.region":     {"us", "eu"},
+	"IncidentSync.onAcknowledge":  {incidentSyncAcknowledge, incidentSyncResolve},
+	"RuleActions.payloadFormat":   payloadFormats(),
+	"RuleActions.linkStyle":       {linkStyleWeb, linkStyleApp, linkStyleBoth},
+	"Translation.provider":        {translationProviderLibreTranslate, translationProviderDeepL},
+}
+
+// schemaBuilder generates the schema of a Go type, with a definition per struct type so recursive
+// types are possible.
+type schemaBuilder struct {
+	defs map[string]*jsonSchema
+}
+
+func (b *schemaBuilder) scalar(schemaType string) *jsonSchema {
+	return &jsonSchema{AnyOf: []*jsonSchema{{Type: schemaType}, {Ref: "#/$defs/" + envVarDef}}}
+}
+
+func (b *schemaBuilder) build(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == emojiListType {
+		return &jsonSchema{AnyOf: []*jsonSchema{{Type: "string"}, {Type: "array", Items: &jsonSchema{Type: "string"}}}}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return b.scalar("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return b.scalar("integer")
+	case reflect.Float32, reflect.Float64:
+		return b.scalar("number")
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: b.build(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: b.build(t.Elem())}
+	case reflect.Struct:
+		if _, ok := b.defs[t.Name()]; !ok {
+			def := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema), AdditionalProperties: false}
+			b.defs[t.Name()] = def // Before the fields, for recursive types
+			for name, fieldType := range yamlFieldTypes(t) {
+				property := b.build(fieldType)
+				if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+					property = &jsonSchema{AnyOf: []*jsonSchema{{Type: "string", Enum: enum}, {Ref: "#/$defs/" + envVarDef}}}
+				}
+				def.Properties[name] = property
+			}
+		}
+		return &jsonSchema{Ref: "#/$defs/" + t.Name()}
+	default:
+		return &jsonSchema{}
+	}
+}
+
+// configSchema returns the JSON Schema of the config file.
+func configSchema() *jsonSchema {
+	b := &schemaBuilder{defs: map[string]*jsonSchema{envVarDef: {Type: "string", Pattern: envVarPattern}}}
+	root := b.build(reflect.TypeOf(Config{}))
+	root.Schema = "https://json-schema.org/draft/2020-12/schema"
+	root.Title = "discord2pushover configuration"
+	root.Defs = b.defs
+	return root
+}
+
+// printSchema writes the config's JSON Schema and returns the process exit code.
+func printSchema(w io.Writer) int {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(configSchema()); err != nil {
+		log.Errorf("Error writing schema: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// schemaValidator checks a YAML document against the config schema.
+type schemaValidator struct {
+	root *jsonSchema
+}
+
+// resolve follows a schema's $ref.
+func (v *schemaValidator) resolve(schema *jsonSchema) *jsonSchema {
+	for schema.Ref != "" {
+		schema = v.root.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+	}
+	return schema
+}
+
+// validate returns the violations of node against schema, each prefixed with its line and path.
+func (v *schemaValidator) validate(path string, node *yaml.Node, schema *jsonSchema) []string {
+	schema = v.resolve(schema)
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil // Empty values leave the default
+	}
+	if len(schema.AnyOf) > 0 {
+		var first []string
+		for _, option := range schema.AnyOf {
+			errs := v.validate(path, node, option)
+			if len(errs) == 0 {
+				return nil
+			}
+			if first == nil {
+				first = errs
+			}
+		}
+		return first
+	}
+	violation := func(format string, args ...interface{}) []string {
+		return []string{fmt.Sprintf("line %d: %s: %s", node.Line, strings.TrimSuffix(path, "."), fmt.Sprintf(format, args...))}
+	}
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			return violation("expected a mapping")
+		}
+		var errs []string
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" { // Merge key
+				continue
+			}
+			property, ok := schema.Properties[key.Value]
+			if !ok {
+				additional, isSchema := schema.AdditionalProperties.(*jsonSchema)
+				if !isSchema {
+					errs = append(errs, fmt.Sprintf("line %d: %s%s: unknown key", key.Line, path, key.Value))
+					continue
+				}
+				property = additional
+			}
+			errs = append(errs, v.validate(path+key.Value+".", value, property)...)
+		}
+		return errs
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			return violation("expected a list")
+		}
+		var errs []string
+		for i, item := range node.Content {
+			errs = append(errs, v.validate(fmt.Sprintf("%s[%d].", strings.TrimSuffix(path, "."), i), item, schema.Items)...)
+		}
+		return errs
+	case "string", "integer", "number", "boolean":
+		if node.Kind != yaml.ScalarNode {
+			return violation("expected a single %s value", schema.Type)
+		}
+		switch {
+		case schema.Type == "integer" && node.Tag != "!!int",
+			schema.Type == "number" && node.Tag != "!!int" && node.Tag != "!!float",
+			schema.Type == "boolean" && node.Tag != "!!bool":
+			return violation("expected %s, got '%s'", schema.Type, node.Value)
+		}
+		if schema.Pattern != "" {
+			if re, err := compiledPattern(schema.Pattern); err == nil && !re.MatchString(node.Value) {
+				return violation("'%s' does not match %s", node.Value, schema.Pattern)
+			}
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, node.Value) {
+			return violation("'%s' is not one of %s", node.Value, strings.Join(schema.Enum, ", "))
+		}
+	}
+	return nil
+}
+
+// validateConfigSchema checks the raw config YAML against the config schema, before environment
+// variables are substituted, and returns the violations in document order.
+func validateConfigSchema(data []byte) ([]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	schema := configSchema()
+	v := &schemaValidator{root: schema}
+	return v.validate("", root.Content[0], schema), nil
+}
@@ -0,0 +1,63 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	var out bytes.Buffer
+	if code := printSchema(&out); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	defs := schema["$defs"].(map[string]interface{})
+	rule := defs["Rule"].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := rule["conditions"]; !ok || schema["$ref"] != "#/$defs/Config" {
+		t.Errorf("Unexpected schema %s", out.String())
+	}
+}
+
+func TestValidateConfigSchema(t *testing.T) {
+	config := `discordToken: "$DISCORD_TOKEN"
+pushoverAppKey: app
+budget:
+  maxPerHour: ${MAX_PER_HOUR}
+notifiers:
+  phone:
+    twilio: {mode: fax}
+rules:
+  - name: Alerts
+    event: onPin
+    conditions:
+      channelID: "123"
+      reactToAtMention: yes please
+    actions:
+      priority: high
+      linkStyle: ap
+      reactionEmoji: "📟"
+      severityMap:
+        - {pattern: crit, priority: 2}
+  - name: Defaults
+    actions:
+`
+	violations, err := validateConfigSchema([]byte(config))
+	if err != nil {
+		t.Fatalf("validateConfigSchema failed: %v", err)
+	}
+	want := []string{
+		"line 7: notifiers.phone.twilio.mode: 'fax' is not one of sms, call",
+		"line 12: rules[0].conditions.channelID: unknown key",
+		"line 13: rules[0].conditions.reactToAtMention: expected boolean, got 'yes please'",
+		"line 15: rules[0].actions.priority: expected integer, got 'high'",
+		"line 16: rules[0].actions.linkStyle: 'ap' is not one of web, app, both",
+	}
+	if got := strings.Join(violations, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("Unexpected violations:\n%s", got)
+	}
+}
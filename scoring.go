@@ -0,0 +1,63 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// scoreMessage adds up the points of the scoring signals whose conditions the message meets and
+// returns the score with the highest threshold it reaches, or nil and the reason if it reaches none.
+func scoreMessage(ctx context.Context, config *Config, message *discordgo.Message, scoring *Scoring, session DiscordSessionInterface, ruleNameLog string) (int, *ScoreThreshold, string) {
+	if len(scoring.Thresholds) == 0 {
+		log.Errorf("Rule '%s' has scoring without thresholds, so it never matches.", ruleNameLog)
+		return 0, nil, "Score: no thresholds configured"
+	}
+	score := 0
+	var hits []string
+	for i, signal := range scoring.Signals {
+		if met, _ := evaluateRuleConditions(ctx, config, message, &signal.Conditions, session, ruleNameLog); !met {
+			continue
+		}
+		if met, _ := checkScheduleCondition(config, &signal.Conditions, time.Now(), ruleNameLog); !met {
+			continue
+		}
+		score += signal.Points
+		name := signal.Name
+		if name == "" {
+			name = fmt.Sprintf("signal %d", i+1)
+		}
+		hits = append(hits, fmt.Sprintf("%s %+d", name, signal.Points))
+	}
+
+	thresholds := append([]ScoreThreshold(nil), scoring.Thresholds...)
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i].Score > thresholds[j].Score })
+	breakdown := "no signals"
+	if len(hits) > 0 {
+		breakdown = strings.Join(hits, ", ")
+	}
+	for i := range thresholds {
+		if score >= thresholds[i].Score {
+			log.Infof("Rule '%s': message ID %s scored %d (%s), reaching threshold %d (priority %d).",
+				ruleNameLog, message.ID, score, breakdown, thresholds[i].Score, thresholds[i].Priority)
+			return score, &thresholds[i], ""
+		}
+	}
+	lowest := thresholds[len(thresholds)-1].Score
+	log.Debugf("Rule '%s': message ID %s scored %d (%s), below the lowest threshold %d.", ruleNameLog, message.ID, score, breakdown, lowest)
+	return score, nil, fmt.Sprintf("Score: %d (%s) is below the lowest threshold %d", score, breakdown, lowest)
+}
+
+// applyScoreThreshold sets the priority of the threshold a message reached as the rule's priority,
+// which severityMap and priority tags may still override. Emergencies without emergency settings
+// use those of priority tags.
+func applyScoreThreshold(rule *Rule, threshold *ScoreThreshold) {
+	rule.Actions.Priority = threshold.Priority
+	if threshold.Priority == 2 && rule.Actions.Emergency == nil {
+		rule.Actions.Emergency = &defaultTagEmergency
+	}
+}
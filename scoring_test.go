@@ -0,0 +1,68 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestProcessRules_Scoring(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		Rules: []Rule{{
+			Name:       "Outage",
+			Conditions: RuleConditions{ChannelID: "support"},
+			Actions:    RuleActions{PushoverDestination: "uKey", Priority: -1, Template: "{{.Score}}: {{.Content}}"},
+			Scoring: &Scoring{
+				Signals: []ScoreSignal{
+					{Name: "down", Points: 5, Conditions: RuleConditions{ContentIncludes: []string{"down"}}},
+					{Name: "everyone", Points: 4, Conditions: RuleConditions{ContentIncludes: []string{"everyone", "all users"}, ContentMatch: contentMatchAnyOf}},
+					{Name: "payments", Points: 3, Conditions: RuleConditions{ContentIncludes: []string{"payment"}}},
+					{Name: "resolved", Points: -10, Conditions: RuleConditions{ContentIncludes: []string{"resolved"}}},
+				},
+				Thresholds: []ScoreThreshold{{Score: 5, Priority: 0}, {Score: 10, Priority: 2}, {Score: 8, Priority: 1}},
+			},
+		}},
+	}
+	config.SetPushoverClient(fake)
+	session := mockSessionForRulesTest("bot")
+
+	tests := []struct {
+		content      string
+		wantPriority int // -99: not notified
+	}{
+		{"site is slow", -99},
+		{"login is down", 0},
+		{"payment is down", 1},
+		{"payment down for everyone", 2},
+		{"payment down for everyone, resolved now", -99},
+	}
+	for _, tt := range tests {
+		fake.sent = nil
+		message := &discordgo.Message{ID: "m-" + tt.content, ChannelID: "support", Content: tt.content, Author: &discordgo.User{ID: "u1"}}
+		ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+		if tt.wantPriority == -99 {
+			if len(fake.sent) != 0 {
+				t.Errorf("%q: expected no notification, got priority %d", tt.content, fake.sent[0].Priority)
+			}
+			continue
+		}
+		if len(fake.sent) != 1 || fake.sent[0].Priority != tt.wantPriority {
+			t.Errorf("%q: expected a notification with priority %d, got %d notifications", tt.content, tt.wantPriority, len(fake.sent))
+			continue
+		}
+		if tt.wantPriority == 2 && !strings.HasPrefix(fake.sent[0].Message, "12: ") {
+			t.Errorf("Expected the score in the template, got %q", fake.sent[0].Message)
+		}
+	}
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "scored 12 (down +5, everyone +4, payments +3), reaching threshold 10 (priority 2)") {
+		t.Errorf("Expected the score breakdown to be logged. Logs:\n%.3000s", logs)
+	}
+}
@@ -0,0 +1,146 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// defaultScriptTimeout bounds a script run, including its HTTP requests, when the rule sets no scriptTimeoutSeconds.
+const defaultScriptTimeout = 10 * time.Second
+
+// maxScriptResponseBytes limits how much of an httpPost response is handed to the script.
+const maxScriptResponseBytes = 64 * 1024
+
+// scriptLibs are the Lua standard libraries scripts may use. io, os and package are left out so
+// scripts can only affect the outside world through the helpers below.
+var scriptLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// runRuleScript executes a rule's Lua script for a matched message. The script sees the message as
+// the global `message` table and can call sendPushover, addReaction, httpPost and log.
+func runRuleScript(ctx context.Context, rule *Rule, ruleNameLog string, event string, message *discordgo.Message, config *Config, session DiscordSessionInterface) error {
+	timeout := defaultScriptTimeout
+	if rule.Actions.ScriptTimeoutSeconds > 0 {
+		timeout = time.Duration(rule.Actions.ScriptTimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	L.SetContext(ctx)
+	for _, lib := range scriptLibs {
+		if err := L.CallByParam(lua.P{Fn: L.NewFunction(lib.open), NRet: 0, Protect: true}, lua.LString(lib.name)); err != nil {
+			return fmt.Errorf("failed to open Lua library %s: %w", lib.name, err)
+		}
+	}
+	for _, unsafe := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(unsafe, lua.LNil)
+	}
+
+	L.SetGlobal("rule", lua.LString(ruleNameLog))
+	L.SetGlobal("event", lua.LString(event))
+	labels := L.NewTable()
+	for key, value := range rule.Labels {
+		labels.RawSetString(key, lua.LString(value))
+	}
+	L.SetGlobal("labels", labels)
+	L.SetGlobal("message", scriptMessageTable(L, message))
+	L.SetGlobal("log", L.NewFunction(func(L *lua.LState) int {
+		log.Infof("Script for rule '%s': %s", ruleNameLog, L.CheckString(1))
+		return 0
+	}))
+	L.SetGlobal("sendPushover", L.NewFunction(func(L *lua.LState) int {
+		err := SendPushoverText(ctx, config, L.CheckString(1), L.CheckString(2), L.CheckString(3), L.OptInt(4, 0))
+		reportPushoverResult(session, config, err)
+		return scriptResult(L, err)
+	}))
+	L.SetGlobal("addReaction", L.NewFunction(func(L *lua.LState) int {
+		if message.ChannelID == "" || message.ID == "" {
+			return scriptResult(L, fmt.Errorf("event has no Discord message to react to"))
+		}
+		return scriptResult(L, session.MessageReactionAdd(message.ChannelID, message.ID, reactionEmojiAPIName(L.CheckString(1))))
+	}))
+	httpClient := newHTTPClientTimeout(config, timeout)
+	L.SetGlobal("httpPost", L.NewFunction(func(L *lua.LState) int {
+		status, body, err := scriptHTTPPost(ctx, httpClient, L.CheckString(1), L.CheckString(2), L.OptString(3, "application/json"))
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LNumber(status))
+		L.Push(lua.LString(body))
+		return 2
+	}))
+
+	log.Debugf("Running script %s for rule '%s' on message ID %s", rule.Actions.Script, ruleNameLog, message.ID)
+	if err := L.DoFile(rule.Actions.Script); err != nil {
+		return fmt.Errorf("script %s failed: %w", rule.Actions.Script, err)
+	}
+	return nil
+}
+
+// scriptResult pushes the Lua-style result of a helper: true, or nil and an error message.
+func scriptResult(L *lua.LState, err error) int {
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// scriptMessageTable converts a message into the Lua table scripts see as `message`.
+func scriptMessageTable(L *lua.LState, message *discordgo.Message) *lua.LTable {
+	table := L.NewTable()
+	table.RawSetString("id", lua.LString(message.ID))
+	table.RawSetString("channelId", lua.LString(message.ChannelID))
+	table.RawSetString("guildId", lua.LString(message.GuildID))
+	table.RawSetString("webhookId", lua.LString(message.WebhookID))
+	table.RawSetString("content", lua.LString(message.Content))
+	table.RawSetString("text", lua.LString(messageSearchText(message)))
+	table.RawSetString("link", lua.LString(discordMessageLink(message)))
+	author := L.NewTable()
+	if message.Author != nil {
+		author.RawSetString("id", lua.LString(message.Author.ID))
+		author.RawSetString("username", lua.LString(message.Author.Username))
+		author.RawSetString("bot", lua.LBool(message.Author.Bot))
+	}
+	table.RawSetString("author", author)
+	return table
+}
+
+// scriptHTTPPost posts body to url and returns the status code and (truncated) response body.
+func scriptHTTPPost(ctx context.Context, httpClient *http.Client, url string, body string, contentType string) (int, string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	request.Header.Set("Content-Type", contentType)
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxScriptResponseBytes))
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, string(responseBody), nil
+}
@@ -0,0 +1,89 @@
+package discord2pushover
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func writeTestScript(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rule.lua")
+	if err := os.WriteFile(path, []byte(source), 0o600); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestProcessRules_Script(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+
+	var posted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posted = string(body)
+		w.Write([]byte("accepted"))
+	}))
+	defer server.Close()
+
+	script := writeTestScript(t, `URL = "`+server.URL+`"
+if string.find(message.content, "deploy") then
+  local status, body = httpPost(URL, '{"who":"' .. message.author.username .. '"}')
+  assert(status == 200 and body == "accepted", "unexpected response")
+  assert(sendPushover("uKey", "Deploy", message.content, 1))
+  assert(addReaction("🚀"))
+  log("handled " .. message.id .. " for " .. rule)
+end
+`)
+	config := &Config{Rules: []Rule{{
+		Name:       "Deploys",
+		Conditions: RuleConditions{ChannelID: "ci"},
+		Actions:    RuleActions{Script: script},
+	}}}
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+	message := &discordgo.Message{ID: "m1", ChannelID: "ci", Content: "deploy done", Author: &discordgo.User{ID: "u1", Username: "alice"}}
+	ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+
+	logs := testLogBufferForTest.String()
+	if posted != `{"who":"alice"}` {
+		t.Errorf("Unexpected httpPost body %q. Logs:\n%s", posted, logs)
+	}
+	if !testHookPushoverSendCalled {
+		t.Error("Expected sendPushover to send a notification")
+	}
+	for _, expected := range []string{"msgID=m1, emoji=🚀", "handled m1 for Deploys"} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("Expected logs to contain %q. Logs:\n%s", expected, logs)
+		}
+	}
+}
+
+func TestRunRuleScript_Sandbox(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+	message := &discordgo.Message{ID: "m1", ChannelID: "c1"}
+	for _, source := range []string{`os.exit(1)`, `io.open("/etc/passwd")`, `dofile("/etc/passwd")`, `require("os")`} {
+		rule := &Rule{Actions: RuleActions{Script: writeTestScript(t, source)}}
+		if err := runRuleScript(context.Background(), rule, "sandbox", ruleEventMessage, message, &Config{}, session); err == nil {
+			t.Errorf("Expected %q to fail in the sandbox", source)
+		}
+	}
+
+	rule := &Rule{Actions: RuleActions{Script: writeTestScript(t, `while true do end`), ScriptTimeoutSeconds: 1}}
+	if err := runRuleScript(context.Background(), rule, "loop", ruleEventMessage, message, &Config{}, session); err == nil {
+		t.Error("Expected an endless script to time out")
+	}
+}
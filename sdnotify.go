@@ -0,0 +1,83 @@
+package discord2pushover
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxHeartbeatAge is how long the gateway may go without acknowledging a heartbeat before the
+// session is considered wedged and the systemd watchdog is no longer petted. Discord asks for a
+// heartbeat about every 41 seconds.
+const maxHeartbeatAge = 2 * time.Minute
+
+// sdNotify sends a state, e.g. "READY=1", to systemd's notification socket. Without NOTIFY_SOCKET
+// (not started by systemd with Type=notify) it does nothing and returns false.
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // Abstract socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// watchdogInterval returns how often to pet the systemd watchdog (half its timeout), or 0 if
+// WatchdogSec is not set for this process.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// gatewayHealthy reports whether the Discord session still gets its heartbeats acknowledged.
+func gatewayHealthy(dg *discordgo.Session, now time.Time) bool {
+	dg.RLock()
+	lastAck := dg.LastHeartbeatAck
+	dg.RUnlock()
+	return now.Sub(lastAck) < maxHeartbeatAge
+}
+
+// petWatchdog sends WATCHDOG=1 if healthy, so systemd restarts the bot once it stops doing so.
+func petWatchdog(healthy bool) {
+	if !healthy {
+		log.Warnf("Discord gateway has not acknowledged a heartbeat for %s; not petting the systemd watchdog.", maxHeartbeatAge)
+		return
+	}
+	if _, err := sdNotify("WATCHDOG=1"); err != nil {
+		log.Errorf("Error petting the systemd watchdog: %v", err)
+	}
+}
+
+// runWatchdog pets the systemd watchdog while the Discord session is healthy, if WatchdogSec is set.
+func runWatchdog(dg *discordgo.Session) {
+	defer recoverPanic("runWatchdog")
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+	log.Infof("Petting the systemd watchdog every %s.", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		petWatchdog(gatewayHealthy(dg, now))
+	}
+}
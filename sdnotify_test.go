@@ -0,0 +1,69 @@
+package discord2pushover
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := sdNotify("READY=1"); sent || err != nil {
+		t.Errorf("Expected nothing to be sent without NOTIFY_SOCKET, got %v %v", sent, err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "notify.socket")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	if sent, err := sdNotify("READY=1"); !sent || err != nil {
+		t.Fatalf("sdNotify failed: %v %v", sent, err)
+	}
+	buf := make([]byte, 64)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := listener.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("Expected READY=1, got %q (%v)", buf[:n], err)
+	}
+
+	// A wedged session doesn't pet the watchdog
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	dg := &discordgo.Session{LastHeartbeatAck: time.Now().Add(-5 * time.Minute)}
+	petWatchdog(gatewayHealthy(dg, time.Now()))
+	if !strings.Contains(testLogBufferForTest.String(), "not petting the systemd watchdog") {
+		t.Error("Expected a warning for a wedged session")
+	}
+	dg.LastHeartbeatAck = time.Now()
+	petWatchdog(gatewayHealthy(dg, time.Now()))
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err = listener.Read(buf); err != nil || string(buf[:n]) != "WATCHDOG=1" {
+		t.Errorf("Expected WATCHDOG=1, got %q (%v)", buf[:n], err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	if got := watchdogInterval(); got != 0 {
+		t.Errorf("Expected no watchdog, got %s", got)
+	}
+	t.Setenv("WATCHDOG_USEC", "60000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if got := watchdogInterval(); got != 30*time.Second {
+		t.Errorf("Expected half of WatchdogSec, got %s", got)
+	}
+	t.Setenv("WATCHDOG_PID", "1")
+	if got := watchdogInterval(); got != 0 {
+		t.Errorf("Expected no watchdog for another process, got %s", got)
+	}
+}
@@ -0,0 +1,268 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretPattern matches secret references like ${vault:secret/myapp#token}, ${awssm:name} or ${gcpsm:name#key}.
+var secretPattern = regexp.MustCompile(`\$\{(vault|awssm|gcpsm):([^}#]+)(?:#([^}]+))?\}`)
+
+// secretTimeout bounds fetching one secret.
+const secretTimeout = 30 * time.Second
+
+// Commands fetching secrets from the cloud secret managers with their credentials; variables so tests can
+// replace them.
+var (
+	awsCommand    = "aws"
+	gcloudCommand = "gcloud"
+)
+
+// secretResolvers fetch a secret by its name from a secret manager, those with an HTTP API through httpClient.
+var secretResolvers = map[string]func(ctx context.Context, httpClient *http.Client, name string) (string, error){
+	"vault": fetchVaultSecret,
+	"awssm": fetchAWSSecret,
+	"gcpsm": fetchGCPSecret,
+}
+
+// resolveSecrets replaces the secret references of a config with the secrets fetched from their secret
+// manager. A reference with #key selects a field of a secret stored as JSON object, as Vault secrets are.
+// Secrets are fetched each time the config is loaded, and each only once.
+//
+// References are replaced in the parsed YAML values, so those in comments are left alone and a secret
+// is always one value, whatever characters it contains. A value that is just a reference takes the
+// type of the secret, e.g. a number for priority.
+func resolveSecrets(data []byte) ([]byte, error) {
+	if !secretPattern.Match(data) {
+		return data, nil
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return data, nil // Reported when the config is parsed
+	}
+	fetched := make(map[string]string)
+	var httpClient *http.Client
+	var firstErr error
+	replaced := false
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.AliasNode {
+			return // Resolved where the anchor is
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+		if node.Kind != yaml.ScalarNode || !secretPattern.MatchString(node.Value) {
+			return
+		}
+		whole := secretPattern.FindString(node.Value) == node.Value
+		node.Value = secretPattern.ReplaceAllStringFunc(node.Value, func(found string) string {
+			if firstErr != nil {
+				return found
+			}
+			if value, ok := fetched[found]; ok {
+				return value
+			}
+			if httpClient == nil {
+				httpClient = secretHTTPClient(data)
+			}
+			match := secretPattern.FindStringSubmatch(found)
+			value, err := resolveSecret(httpClient, match[1], match[2], match[3])
+			if err != nil {
+				firstErr = fmt.Errorf("failed to resolve %s: %w", found, err)
+				return found
+			}
+			log.Debugf("Substituting secret '%s' with value (length %d).", found, len(value))
+			fetched[found] = value
+			return value
+		})
+		if whole && node.Style == 0 {
+			node.Tag = "" // Resolved anew from the secret
+		}
+		replaced = true
+	}
+	walk(&document)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if !replaced {
+		return data, nil // The references were all in comments
+	}
+	return yaml.Marshal(&document)
+}
+
+// secretHTTPClient returns the client fetching the secrets of a config through the transport its
+// httpProxy, caFile and network configure. As these are read before any secret is resolved, they
+// cannot reference secrets themselves; the default transport is used if they are invalid.
+func secretHTTPClient(data []byte) *http.Client {
+	var settings struct {
+		HTTPProxy string   `yaml:"httpProxy"`
+		CAFile    string   `yaml:"caFile"`
+		Network   *Network `yaml:"network"`
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &settings); err == nil {
+		config = Config{HTTPProxy: settings.HTTPProxy, CAFile: settings.CAFile, Network: settings.Network}
+		if config.transport, err = newTransport(&config); err != nil {
+			log.Warnf("Fetching secrets without the configured transport: %v", err)
+		}
+	}
+	return newHTTPClientTimeout(&config, secretTimeout)
+}
+
+// resolveSecret fetches a secret, or the field key of it if key is set.
+func resolveSecret(httpClient *http.Client, kind string, name string, key string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	secret, err := secretResolvers[kind](ctx, httpClient, strings.TrimSpace(name))
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return secret, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so it has no field %q", key)
+	}
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// vaultToken returns the token authenticating to Vault: VAULT_TOKEN, else the token the vault CLI
+// stored at login.
+func vaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("VAULT_TOKEN is not set")
+	}
+	token, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return "", errors.New("VAULT_TOKEN is not set and there is no ~/.vault-token")
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// vaultGet reads a path of the Vault HTTP API and returns the data of the response.
+func vaultGet(ctx context.Context, httpClient *http.Client, address string, token string, path string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var decoded struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid response from vault: %w", err)
+	}
+	return decoded.Data, nil
+}
+
+// vaultDataPath returns the API path of a secret. Like `vault kv get`, it inserts "data/" after the
+// mount for a secret in a version 2 key/value engine, so secret/myapp reads secret/data/myapp.
+func vaultDataPath(ctx context.Context, httpClient *http.Client, address string, token string, path string) string {
+	mount, err := vaultGet(ctx, httpClient, address, token, "sys/internal/ui/mounts/"+path)
+	if err != nil {
+		return path
+	}
+	mountPath, _ := mount["path"].(string)
+	options, _ := mount["options"].(map[string]any)
+	if options["version"] != "2" || mountPath == "" || !strings.HasPrefix(path, mountPath) || strings.HasPrefix(path, mountPath+"data/") {
+		return path
+	}
+	return mountPath + "data/" + strings.TrimPrefix(path, mountPath)
+}
+
+// fetchVaultSecret reads a secret from the Vault at VAULT_ADDR, returned as JSON object of its fields.
+func fetchVaultSecret(ctx context.Context, httpClient *http.Client, path string) (string, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return "", errors.New("VAULT_ADDR is not set")
+	}
+	token, err := vaultToken()
+	if err != nil {
+		return "", err
+	}
+	data, err := vaultGet(ctx, httpClient, address, token, vaultDataPath(ctx, httpClient, address, token, path))
+	if err != nil {
+		return "", err
+	}
+	// Secrets of a version 2 key/value engine are nested in data along with their metadata
+	if fields, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = fields
+		}
+	}
+	encoded, err := json.Marshal(data)
+	return string(encoded), err
+}
+
+// runSecretCommand runs a secret manager's CLI and returns its output without the trailing newline.
+func runSecretCommand(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("the %s command is not installed", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// fetchAWSSecret reads the current value of a secret from AWS Secrets Manager, by name or ARN, with the
+// credentials and region the aws CLI is configured with.
+func fetchAWSSecret(ctx context.Context, _ *http.Client, name string) (string, error) {
+	return runSecretCommand(ctx, awsCommand, "secretsmanager", "get-secret-value", "--secret-id", name, "--query", "SecretString", "--output", "text")
+}
+
+// fetchGCPSecret reads a secret from Google Cloud Secret Manager with the credentials the gcloud CLI is
+// configured with: the latest version of a secret in the default project, or the version given by a
+// resource name like projects/p/secrets/name/versions/3.
+func fetchGCPSecret(ctx context.Context, _ *http.Client, name string) (string, error) {
+	if !strings.Contains(name, "/") {
+		return runSecretCommand(ctx, gcloudCommand, "secrets", "versions", "access", "latest", "--secret", name)
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	return runSecretCommand(ctx, gcloudCommand, "secrets", "versions", "access", name)
+}
@@ -0,0 +1,82 @@
+package discord2pushover
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/sys/internal/ui/mounts/secret/bot":
+			w.Write([]byte(`{"data":{"path":"secret/","type":"kv","options":{"version":"2"}}}`))
+		case "/v1/secret/data/bot":
+			w.Write([]byte(`{"data":{"data":{"discordToken":"from-vault","priority":1,"tricky":"*p@ss: w#rd"},"metadata":{"version":3}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "vault-token")
+
+	dir := t.TempDir()
+	fakeAWS := filepath.Join(dir, "aws")
+	os.WriteFile(fakeAWS, []byte("#!/bin/sh\necho \"{\\\"appKey\\\":\\\"from-aws-$4\\\"}\"\n"), 0o700)
+	fakeGcloud := filepath.Join(dir, "gcloud")
+	os.WriteFile(fakeGcloud, []byte("#!/bin/sh\nprintf 'from-gcp-%s' \"$4\"\n"), 0o700)
+	savedAWS, savedGcloud := awsCommand, gcloudCommand
+	awsCommand, gcloudCommand = fakeAWS, fakeGcloud
+	defer func() { awsCommand, gcloudCommand = savedAWS, savedGcloud }()
+	t.Setenv("BOT_ENV", "prod")
+
+	data, err := substituteEnvVars([]byte(`discordToken: "${vault:secret/bot#discordToken}"
+pushoverAppKey: "${awssm:bot-${BOT_ENV}#appKey}"
+priority: ${vault:secret/bot#priority}
+deepl: "${gcpsm:projects/p/secrets/deepl}"
+`))
+	if err != nil {
+		t.Fatalf("substituteEnvVars failed: %v", err)
+	}
+	want := `discordToken: "from-vault"
+pushoverAppKey: "from-aws-bot-prod"
+priority: 1
+deepl: "from-gcp-projects/p/secrets/deepl/versions/latest"
+`
+	if string(data) != want {
+		t.Errorf("Unexpected substitution:\n%s", data)
+	}
+
+	data, err = substituteEnvVars([]byte(`# Rotated away: ${vault:secret/bot#missing}
+discordToken: ${vault:secret/bot#tricky}
+`))
+	var parsed map[string]string
+	if err != nil || yaml.Unmarshal(data, &parsed) != nil || parsed["discordToken"] != "*p@ss: w#rd" {
+		t.Errorf("Expected the reference in the comment ignored and the secret kept whole, got %v:\n%s", err, data)
+	}
+
+	if _, err := substituteEnvVars([]byte(`discordToken: "${vault:secret/bot#missing}"`)); err == nil || !strings.Contains(err.Error(), `no field "missing"`) {
+		t.Errorf("Expected an error for a missing field, got %v", err)
+	}
+	t.Setenv("VAULT_TOKEN", "wrong")
+	if _, err := substituteEnvVars([]byte(`discordToken: "${vault:secret/bot#discordToken}"`)); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected Vault's error, got %v", err)
+	}
+
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("VAULT_ADDR", "http://vault.invalid")
+	data, err = substituteEnvVars([]byte("httpProxy: " + vault.URL + "\ndiscordToken: \"${vault:secret/bot#discordToken}\"\n"))
+	if err != nil || !strings.Contains(string(data), `"from-vault"`) {
+		t.Errorf("Expected the secret fetched through httpProxy, got %v:\n%s", err, data)
+	}
+}
@@ -0,0 +1,121 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/kardianos/service"
+)
+
+const serviceName = "discord2pushover"
+
+// serviceCommands are the subcommands managing the bot as an OS service (Windows service, launchd
+// daemon or systemd unit), which run without a Discord session.
+var serviceCommands = map[string]bool{"install": true, "uninstall": true, "run-as-service": true}
+
+// botService runs the bot under the OS service manager.
+type botService struct {
+	stop chan os.Signal
+	done chan struct{}
+}
+
+func (p *botService) Start(s service.Service) error {
+	go func() {
+		defer close(p.done)
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP) // systemctl reload, where the service manager supports it
+		if code := runBot(p.stop, reload); code != 0 {
+			os.Exit(code) // Let the service manager see the failure and restart the bot
+		}
+	}()
+	return nil
+}
+
+func (p *botService) Stop(s service.Service) error {
+	p.stop <- syscall.SIGTERM
+	<-p.done
+	return nil
+}
+
+// newService describes the bot as a service started with `run-as-service -c configPath`, plus the
+// environment selected with --env and the identity selected with --age-identity.
+func newService(configPath string, program service.Interface) (service.Service, error) {
+	absConfigPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	arguments := []string{"run-as-service", "-c", absConfigPath}
+	if configEnv != "" {
+		arguments = append(arguments, "-env", configEnv)
+	}
+	if ageIdentityFile != "" {
+		identity, err := filepath.Abs(ageIdentityFile)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, "-age-identity", identity)
+	}
+	return service.New(program, &service.Config{
+		Name:        serviceName,
+		DisplayName: "discord2pushover",
+		Description: "Forwards Discord messages matching rules to Pushover.",
+		Arguments:   arguments,
+		Option: service.KeyValue{
+			"Restart":          "on-failure", // systemd
+			"KeepAlive":        true,         // launchd
+			"OnFailure":        "restart",    // Windows
+			"DelayedAutoStart": true,
+		},
+	})
+}
+
+// runServiceCommand installs or uninstalls the service, returning the process exit code.
+func runServiceCommand(name string, configPath string) int {
+	s, err := newService(configPath, &botService{})
+	if err != nil {
+		log.Errorf("Error setting up the service: %v", err)
+		return 1
+	}
+	switch name {
+	case "install":
+		if err = s.Install(); err == nil {
+			err = s.Start()
+		}
+		if err == nil {
+			fmt.Printf("Installed and started service %s (%s) with config %s.\n", serviceName, service.ChosenSystem().String(), configPath)
+		}
+	case "uninstall":
+		if errStop := s.Stop(); errStop != nil {
+			log.Warnf("Could not stop service %s: %v", serviceName, errStop)
+		}
+		if err = s.Uninstall(); err == nil {
+			fmt.Printf("Uninstalled service %s.\n", serviceName)
+		}
+	case "run-as-service":
+		return runAsService(configPath)
+	}
+	if err != nil {
+		log.Errorf("Command '%s' failed: %v", name, err)
+		return 1
+	}
+	return 0
+}
+
+// runAsService runs the bot until the service manager stops it. Run interactively, it behaves like
+// running the bot without a command.
+func runAsService(configPath string) int {
+	program := &botService{stop: make(chan os.Signal, 1), done: make(chan struct{})}
+	s, err := newService(configPath, program)
+	if err != nil {
+		log.Errorf("Error setting up the service: %v", err)
+		return 1
+	}
+	if err := s.Run(); err != nil {
+		log.Errorf("Service failed: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,18 @@
+package discord2pushover
+
+import "testing"
+
+func TestServiceCommands(t *testing.T) {
+	for name := range serviceCommands {
+		if !isKnownSubcommand(name) {
+			t.Errorf("Service command %s is missing from the usage", name)
+		}
+	}
+	s, err := newService("discord2pushover.yaml", &botService{})
+	if err != nil {
+		t.Fatalf("newService failed: %v", err)
+	}
+	if s.String() != "discord2pushover" {
+		t.Errorf("Unexpected service name %s", s.String())
+	}
+}
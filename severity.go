@@ -0,0 +1,96 @@
+package discord2pushover
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// messageSearchText returns the message content followed by the text of its embeds (title,
+// description, fields and footer), one part per line. Alerting integrations such as Grafana
+// usually put the interesting bits into embeds rather than the content.
+func messageSearchText(message *discordgo.Message) string {
+	parts := []string{}
+	if message.Content != "" {
+		parts = append(parts, message.Content)
+	}
+	for _, embed := range message.Embeds {
+		if embed == nil {
+			continue
+		}
+		for _, s := range []string{embed.Title, embed.Description} {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+		for _, field := range embed.Fields {
+			if field != nil {
+				parts = append(parts, field.Name, field.Value)
+			}
+		}
+		if embed.Footer != nil && embed.Footer.Text != "" {
+			parts = append(parts, embed.Footer.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// severityPriority returns the priority of the first severityMap entry that matches the message.
+// An entry matches when its pattern matches the content or embed text and its embedColor (if set)
+// matches the color of one of the embeds. ok is false when no entry matches.
+func severityPriority(message *discordgo.Message, mappings []SeverityMapping, ruleNameLog string) (priority int, ok bool) {
+	if len(mappings) == 0 {
+		return 0, false
+	}
+	text := messageSearchText(message)
+	for i, mapping := range mappings {
+		if mapping.Pattern == "" && mapping.EmbedColor == "" {
+			log.Warnf("Rule '%s': severityMap entry #%d has neither 'pattern' nor 'embedColor'; skipping.", ruleNameLog, i+1)
+			continue
+		}
+		if mapping.Pattern != "" {
+			re, err := compiledPattern(mapping.Pattern)
+			if err != nil {
+				log.Errorf("Rule '%s': invalid severityMap pattern %q: %v", ruleNameLog, mapping.Pattern, err)
+				continue
+			}
+			if !re.MatchString(text) {
+				continue
+			}
+		}
+		if mapping.EmbedColor != "" {
+			matched, err := hasEmbedColor(message, []string{mapping.EmbedColor})
+			if err != nil {
+				log.Errorf("Rule '%s': %v", ruleNameLog, err)
+				continue
+			}
+			if !matched {
+				continue
+			}
+		}
+		log.Debugf("Rule '%s': severityMap entry #%d matched message ID %s, using priority %d.", ruleNameLog, i+1, message.ID, mapping.Priority)
+		return mapping.Priority, true
+	}
+	return 0, false
+}
+
+// effectiveActions returns the rule's actions with the priority replaced by the severityMap result, if any,
+// then by the payload's status with payloadPriority, and then by an inline priority tag from an allowed author.
+func effectiveActions(rule *Rule, message *discordgo.Message, ruleNameLog string) RuleActions {
+	actions := rule.Actions
+	if priority, ok := severityPriority(message, rule.Actions.SeverityMap, ruleNameLog); ok {
+		actions.Priority = priority
+	}
+	if rule.Actions.PayloadPriority {
+		if priority, ok := payloadStatusPriorities[parsePayload(rule, message, ruleNameLog)["status"]]; ok {
+			actions.Priority = priority
+		}
+	}
+	if priority, ok := inlineTagPriority(message, rule.Actions.PriorityTags, ruleNameLog); ok {
+		actions.Priority = priority
+		if priority == 2 && actions.Emergency == nil {
+			actions.Emergency = &defaultTagEmergency
+		}
+	}
+	return actions
+}
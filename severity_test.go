@@ -0,0 +1,94 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSeverityPriority(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	mappings := []SeverityMapping{
+		{Pattern: `(?i)\[resolved\]`, Priority: -2},
+		{Pattern: `(?i)critical`, EmbedColor: "#e01e5a", Priority: 2},
+		{EmbedColor: "#e01e5a", Priority: 1},
+		{Pattern: `(?i)firing`, Priority: 0},
+	}
+
+	tests := []struct {
+		name     string
+		message  *discordgo.Message
+		expected int
+		ok       bool
+	}{
+		{
+			name:     "content pattern",
+			message:  &discordgo.Message{Content: "[RESOLVED] disk usage"},
+			expected: -2, ok: true,
+		},
+		{
+			name: "pattern in embed and matching color",
+			message: &discordgo.Message{Embeds: []*discordgo.MessageEmbed{{
+				Title: "[FIRING:1] disk usage", Color: 0xe01e5a,
+				Fields: []*discordgo.MessageEmbedField{{Name: "severity", Value: "critical"}},
+			}}},
+			expected: 2, ok: true,
+		},
+		{
+			name:     "color only",
+			message:  &discordgo.Message{Embeds: []*discordgo.MessageEmbed{{Title: "[FIRING:1] cpu", Color: 0xe01e5a}}},
+			expected: 1, ok: true,
+		},
+		{
+			name:     "embed footer",
+			message:  &discordgo.Message{Embeds: []*discordgo.MessageEmbed{{Footer: &discordgo.MessageEmbedFooter{Text: "Firing since 10:00"}}}},
+			expected: 0, ok: true,
+		},
+		{
+			name:    "no match",
+			message: &discordgo.Message{Content: "hello"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			priority, ok := severityPriority(tc.message, mappings, "test")
+			if ok != tc.ok || priority != tc.expected {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", tc.expected, tc.ok, priority, ok)
+			}
+		})
+	}
+}
+
+func TestProcessRules_SeverityMapEmergency(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+
+	config := &Config{Rules: []Rule{{
+		Name:       "Alerts",
+		Conditions: RuleConditions{ChannelID: "alerts"},
+		Actions: RuleActions{
+			PushoverDestination: "uKey",
+			Priority:            0,
+			SeverityMap:         []SeverityMapping{{Pattern: "(?i)critical", Priority: 2}},
+			Emergency:           &EmergencyParams{AckEmoji: "👍", Expire: 600, Retry: 60},
+		},
+	}}}
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+	message := &discordgo.Message{ID: "sevMsg", ChannelID: "alerts", Content: "CRITICAL: db down", Author: &discordgo.User{ID: "u1"}}
+
+	ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+
+	tracked, ok := config.trackedEmergencies().LoadAndDelete("fake-receipt-id-for-test")
+	if !ok {
+		t.Fatal("Expected the mapped emergency notification to be tracked")
+	}
+	if tracked.(TrackedEmergencyMessage).DiscordMessageID != "sevMsg" {
+		t.Errorf("Unexpected tracked message %+v", tracked)
+	}
+}
@@ -0,0 +1,222 @@
+package discord2pushover
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultStatsLogInterval is how often the rule statistics are logged unless configured otherwise.
+const defaultStatsLogInterval = 60 * time.Minute
+
+// ruleCounters counts what happened to a rule's evaluations since startup.
+type ruleCounters struct {
+	evaluated  atomic.Int64 // Conditions were checked against an event
+	matched    atomic.Int64 // Conditions were met
+	notified   atomic.Int64 // A notification was delivered to at least one destination
+	suppressed atomic.Int64 // A notification was held back: duplicate, incident update, sampling, flood or budget
+	errored    atomic.Int64 // Sending a notification or running the script failed
+}
+
+// RuleStats is a snapshot of a rule's counters.
+type RuleStats struct {
+	Rule       string `json:"rule"`
+	Evaluated  int64  `json:"evaluated"`
+	Matched    int64  `json:"matched"`
+	Notified   int64  `json:"notified"`
+	Suppressed int64  `json:"suppressed"`
+	Errored    int64  `json:"errored"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// statusReport is the document served at /status and printed by the status command.
+type statusReport struct {
+	Version       string      `json:"version"`
+	UptimeSeconds int64       `json:"uptimeSeconds"`
+	Rules         []RuleStats `json:"rules"`
+}
+
+// ruleStats holds the counters per rule name (as logged, e.g. "unnamed_rule_3").
+var ruleStats sync.Map
+
+var publishMetricsOnce sync.Once
+
+// publishMetrics registers the counters with expvar, served at /debug/vars. Main calls it rather
+// than an init function, so importing the package leaves the embedding program's expvars alone.
+func publishMetrics() {
+	publishMetricsOnce.Do(func() {
+		expvar.Publish("rule_stats", expvar.Func(func() interface{} { return snapshotRuleStats(nil) }))
+		expvar.Publish("panics_recovered", panicsRecovered)
+		expvar.Publish("gateway_disconnects", gatewayDisconnects)
+		expvar.Publish("gateway_reconnects", gatewayReconnects)
+		expvar.Publish("gateway_last_outage_ms", gatewayLastOutageMillis)
+	})
+}
+
+// countersFor returns the counters of a rule, creating them on first use.
+func countersFor(ruleNameLog string) *ruleCounters {
+	if counters, ok := ruleStats.Load(ruleNameLog); ok {
+		return counters.(*ruleCounters)
+	}
+	counters, _ := ruleStats.LoadOrStore(ruleNameLog, &ruleCounters{})
+	return counters.(*ruleCounters)
+}
+
+func (c *ruleCounters) snapshot(ruleNameLog string) RuleStats {
+	return RuleStats{
+		Rule:       ruleNameLog,
+		Evaluated:  c.evaluated.Load(),
+		Matched:    c.matched.Load(),
+		Notified:   c.notified.Load(),
+		Suppressed: c.suppressed.Load(),
+		Errored:    c.errored.Load(),
+	}
+}
+
+// ruleNameForLog returns the name a rule is logged and counted under.
+func ruleNameForLog(rule *Rule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("unnamed_rule_%d", index+1)
+}
+
+// snapshotRuleStats returns the counters of the config's rules in config order, including rules
+// that never matched, followed by counted rules no longer in the config (e.g. after a reload).
+// Without a config only counted rules are returned, sorted by name.
+func snapshotRuleStats(config *Config) []RuleStats {
+	stats := []RuleStats{}
+	seen := make(map[string]bool)
+	if config != nil {
+		for i := range config.Rules {
+			name := ruleNameForLog(&config.Rules[i], i)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			snapshot := countersFor(name).snapshot(name)
+			snapshot.Labels = config.Rules[i].Labels
+			stats = append(stats, snapshot)
+		}
+	}
+	var others []RuleStats
+	ruleStats.Range(func(key, value interface{}) bool {
+		if name := key.(string); !seen[name] {
+			others = append(others, value.(*ruleCounters).snapshot(name))
+		}
+		return true
+	})
+	sort.Slice(others, func(i, j int) bool { return others[i].Rule < others[j].Rule })
+	return append(stats, others...)
+}
+
+// formatLabels renders labels for logs as " [key=value, ...]", sorted by key, or "" without labels.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return " [" + strings.Join(pairs, ", ") + "]"
+}
+
+// formatRuleStats renders rule statistics as a table.
+func formatRuleStats(w io.Writer, stats []RuleStats) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "RULE\tEVALUATED\tMATCHED\tNOTIFIED\tSUPPRESSED\tERRORED\t")
+	for _, s := range stats {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%d\t\n", s.Rule, s.Evaluated, s.Matched, s.Notified, s.Suppressed, s.Errored)
+	}
+	table.Flush()
+}
+
+// logRuleStats logs a summary line per rule, pointing out rules that never matched.
+func logRuleStats(config *Config) {
+	stats := snapshotRuleStats(config)
+	log.Infof("Rule statistics after %s:", time.Since(startTime).Round(time.Minute))
+	var unused []string
+	for _, s := range stats {
+		log.Infof("  Rule '%s'%s: evaluated %d, matched %d, notified %d, suppressed %d, errored %d",
+			s.Rule, formatLabels(s.Labels), s.Evaluated, s.Matched, s.Notified, s.Suppressed, s.Errored)
+		if s.Matched == 0 {
+			unused = append(unused, s.Rule)
+		}
+	}
+	if len(unused) > 0 {
+		log.Infof("Rules that have not matched since startup: %s", strings.Join(unused, ", "))
+	}
+}
+
+// pollRuleStatsLog periodically logs the rule statistics.
+func pollRuleStatsLog(config *Config) {
+	defer recoverPanic("pollRuleStatsLog")
+	interval := defaultStatsLogInterval
+	if config.StatsLogIntervalMinutes > 0 {
+		interval = time.Duration(config.StatsLogIntervalMinutes) * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		logRuleStats(config)
+	}
+}
+
+// serveStatus serves the rule statistics; with ?guild=<ID> only those of the rules the guild manages itself.
+func serveStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	stats := snapshotRuleStats(globalConfig)
+	if guildID := r.URL.Query().Get("guild"); guildID != "" {
+		stats = tenantRuleStats(stats, guildID)
+	}
+	encoder.Encode(statusReport{
+		Version:       Version,
+		UptimeSeconds: int64(time.Since(startTime).Seconds()),
+		Rules:         stats,
+	})
+}
+
+// fetchStatus queries the status endpoint of a running bot's admin listener.
+func fetchStatus(admin *AdminListener) (*statusReport, error) {
+	client, base, err := adminClient(admin)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Get(base + "/status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the admin listener: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("admin listener returned HTTP %d", resp.StatusCode)
+	}
+	var report statusReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("invalid status response: %w", err)
+	}
+	return &report, nil
+}
+
+// printStatus prints the status of a running bot.
+func printStatus(admin *AdminListener, w io.Writer) error {
+	report, err := fetchStatus(admin)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "discord2pushover %s, up %s\n\n", report.Version, (time.Duration(report.UptimeSeconds) * time.Second).String())
+	formatRuleStats(w, report.Rules)
+	return nil
+}
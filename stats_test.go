@@ -0,0 +1,73 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestRuleStats(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	oldConfig := globalConfig
+	defer func() {
+		testHookDisablePushoverSend = false
+		testHookPushoverSendCalled = false
+		globalConfig = oldConfig
+	}()
+
+	config := &Config{
+		Rules: []Rule{
+			{Name: "StatsAlerts", Labels: map[string]string{"team": "ops", "service": "grafana"}, Conditions: RuleConditions{ContentIncludes: []string{"FIRING"}}, Actions: RuleActions{PushoverDestination: "user"}},
+			{Name: "StatsUnused", Conditions: RuleConditions{ContentIncludes: []string{"never"}}},
+		},
+	}
+	session := mockSessionForRulesTest("bot")
+	author := &discordgo.User{ID: "grafana"}
+	ProcessRules(context.Background(), &discordgo.Message{ID: "1", ChannelID: "c", Content: "FIRING", Author: author}, config, session, 1<<31-1)
+	ProcessRules(context.Background(), &discordgo.Message{ID: "2", ChannelID: "c", Content: "FIRING again", Author: author}, config, session, 0) // Already notified
+	ProcessRules(context.Background(), &discordgo.Message{ID: "3", ChannelID: "c", Content: "all good", Author: author}, config, session, 1<<31-1)
+
+	stats := snapshotRuleStats(config)
+	if len(stats) < 2 || stats[0].Rule != "StatsAlerts" || stats[1].Rule != "StatsUnused" {
+		t.Fatalf("Expected the config's rules first, in config order, got %+v", stats)
+	}
+	want := RuleStats{Rule: "StatsAlerts", Evaluated: 3, Matched: 2, Notified: 1, Suppressed: 1, Labels: config.Rules[0].Labels}
+	if !reflect.DeepEqual(stats[0], want) {
+		t.Errorf("Expected %+v, got %+v", want, stats[0])
+	}
+	if want := (RuleStats{Rule: "StatsUnused", Evaluated: 1}); !reflect.DeepEqual(stats[1], want) {
+		t.Errorf("Expected %+v, got %+v", want, stats[1])
+	}
+
+	logRuleStats(config)
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "Rule 'StatsAlerts' [service=grafana, team=ops]: evaluated 3, matched 2, notified 1, suppressed 1, errored 0") ||
+		!strings.Contains(logs, "Rules that have not matched since startup: StatsUnused") {
+		t.Errorf("Unexpected summary. Logs:\n%s", logs)
+	}
+
+	// The status command queries the running bot's admin listener
+	globalConfig = config
+	server := httptest.NewServer(newAdminMux(&AdminListener{}))
+	defer server.Close()
+	var out bytes.Buffer
+	if err := printStatus(&AdminListener{Listen: strings.TrimPrefix(server.URL, "http://")}, &out); err != nil {
+		t.Fatalf("printStatus failed: %v", err)
+	}
+	found := false
+	for _, line := range strings.Split(out.String(), "\n") {
+		found = found || strings.Join(strings.Fields(line), " ") == "StatsAlerts 3 2 1 1 0"
+	}
+	if !found {
+		t.Errorf("Unexpected status output:\n%s", out.String())
+	}
+	if err := printStatus(nil, &out); err == nil {
+		t.Error("Expected an error without admin.listen")
+	}
+}
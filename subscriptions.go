@@ -0,0 +1,199 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultSubscriptionCommand is the DM command linking a Pushover user key when subscriptions set no command.
+const defaultSubscriptionCommand = "!pushover"
+
+// subscriberCacheTTL is how long the subscribers of a channel are reused before the pins are fetched again.
+const subscriberCacheTTL = time.Minute
+
+// maxReactionUsersPage is the most users Discord returns per request for a reaction.
+const maxReactionUsersPage = 100
+
+// subscriptionFetcher is the subset of session calls needed to find a channel's subscribers.
+type subscriptionFetcher interface {
+	pinFetcher
+	MessageReactions(channelID, messageID, emojiID string, limit int, beforeID, afterID string, opts ...discordgo.RequestOption) ([]*discordgo.User, error)
+}
+
+// MessageReactions calls the RealSession's MessageReactions.
+func (w *DiscordGoSessionWrapper) MessageReactions(channelID, messageID, emojiID string, limit int, beforeID, afterID string, opts ...discordgo.RequestOption) ([]*discordgo.User, error) {
+	return w.RealSession.MessageReactions(channelID, messageID, emojiID, limit, beforeID, afterID, opts...)
+}
+
+var _ subscriptionFetcher = &DiscordGoSessionWrapper{}
+
+// subscriberCache holds the subscribers per channel and emoji for subscriberCacheTTL, so bursts of
+// matches don't fetch the pins and their reactions for every message.
+var subscriberCache = struct {
+	sync.Mutex
+	entries map[string]cachedSubscribers
+}{entries: make(map[string]cachedSubscribers)}
+
+type cachedSubscribers struct {
+	userIDs []string
+	fetched time.Time
+}
+
+// registerSubscriber links a Discord user to a Pushover user key, persisted in the state file.
+func (c *checkpointStore) registerSubscriber(userID string, userKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[string]string)
+	}
+	c.subscribers[userID] = userKey
+	c.dirty = true
+}
+
+// unregisterSubscriber removes a user's registered key and reports whether there was one.
+func (c *checkpointStore) unregisterSubscriber(userID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subscribers[userID]; !ok {
+		return false
+	}
+	delete(c.subscribers, userID)
+	c.dirty = true
+	return true
+}
+
+// registeredSubscriber returns the key a user registered, or "".
+func (c *checkpointStore) registeredSubscriber(userID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscribers[userID]
+}
+
+// channelSubscribers returns the IDs of the users who reacted with emoji on any pinned message in the channel.
+func channelSubscribers(session subscriptionFetcher, channelID string, emoji string) ([]string, error) {
+	cacheKey := channelID + "\x00" + emoji
+	subscriberCache.Lock()
+	cached, ok := subscriberCache.entries[cacheKey]
+	subscriberCache.Unlock()
+	if ok && time.Since(cached.fetched) < subscriberCacheTTL {
+		return cached.userIDs, nil
+	}
+
+	pinned, err := session.ChannelMessagesPinned(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pinned messages of channel %s: %w", channelID, err)
+	}
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, pin := range pinned {
+		for _, reaction := range pin.Reactions {
+			if reaction == nil || reaction.Emoji == nil || !emojiMatches(emoji, reaction.Emoji) {
+				continue
+			}
+			after := ""
+			for {
+				users, err := session.MessageReactions(channelID, pin.ID, reaction.Emoji.APIName(), maxReactionUsersPage, "", after)
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch reactions of pinned message %s: %w", pin.ID, err)
+				}
+				for _, user := range users {
+					if !user.Bot && !seen[user.ID] {
+						seen[user.ID] = true
+						userIDs = append(userIDs, user.ID)
+					}
+				}
+				if len(users) < maxReactionUsersPage {
+					break
+				}
+				after = users[len(users)-1].ID
+			}
+		}
+	}
+
+	subscriberCache.Lock()
+	subscriberCache.entries[cacheKey] = cachedSubscribers{userIDs: userIDs, fetched: time.Now()}
+	subscriberCache.Unlock()
+	return userIDs, nil
+}
+
+// subscriberDestinations returns the Pushover user keys of the channel's subscribers to emoji. Subscribers
+// without a known key are logged and skipped.
+func subscriberDestinations(config *Config, session DiscordSessionInterface, channelID string, emoji string, ruleNameLog string) []string {
+	fetcher, ok := session.(subscriptionFetcher)
+	if !ok {
+		log.Errorf("Rule '%s': the session cannot fetch pinned messages, so subscribers cannot be notified.", ruleNameLog)
+		return nil
+	}
+	userIDs, err := channelSubscribers(fetcher, channelID, emoji)
+	if err != nil {
+		log.Errorf("Rule '%s': %v", ruleNameLog, err)
+		return nil
+	}
+	var keys []string
+	seen := make(map[string]bool)
+	for _, userID := range userIDs {
+		key := userDestination(config, userID)
+		if key == "" {
+			log.Debugf("Rule '%s': subscriber %s has no Pushover user key.", ruleNameLog, userID)
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// handleSubscriptionCommand handles the registration command sent to the bot in a direct message and
+// reports whether the message was one. "!pushover <user key>" links the key after validating it,
+// "!pushover off" removes it.
+func handleSubscriptionCommand(ctx context.Context, message *discordgo.Message, config *Config, session DiscordSessionInterface) bool {
+	if config.Subscriptions == nil || !config.Subscriptions.SelfRegistration || message.GuildID != "" || message.Author == nil {
+		return false
+	}
+	command := config.Subscriptions.Command
+	if command == "" {
+		command = defaultSubscriptionCommand
+	}
+	args, ok := parseCommand(message.Content, command)
+	if !ok {
+		return false
+	}
+	userID := message.Author.ID
+	var reply string
+	switch {
+	case args == "":
+		reply = fmt.Sprintf("Send `%s <your Pushover user key>` to receive notifications for the channels you subscribed to, or `%s off` to stop.", command, command)
+	case strings.EqualFold(args, "off"):
+		if checkpoints.unregisterSubscriber(userID) {
+			log.Infof("Subscriptions: user %s removed their Pushover user key.", userID)
+			reply = "Your Pushover user key was removed."
+		} else {
+			reply = "You have no registered Pushover user key."
+		}
+	default:
+		if err := ValidatePushoverDestination(ctx, config, args); err != nil {
+			log.Warnf("Subscriptions: user %s sent an invalid Pushover user key: %v", userID, err)
+			reply = "That is not a valid Pushover user key. You find yours on the Pushover dashboard."
+			break
+		}
+		checkpoints.registerSubscriber(userID, args)
+		log.Infof("Subscriptions: user %s registered a Pushover user key.", userID)
+		reply = "Your Pushover user key is registered. React to a channel's subscription pin to receive its notifications."
+		if config.StateFile == "" {
+			reply += " It is kept until the bot restarts."
+		}
+	}
+	if _, err := session.ChannelMessageSend(message.ChannelID, reply); err != nil {
+		log.Errorf("Error replying to subscription command of user %s: %v", userID, err)
+	}
+	return true
+}
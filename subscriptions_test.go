@@ -0,0 +1,91 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type mockSubscriptionSession struct {
+	*mockPinSession
+	reactions map[string][]*discordgo.User // Message ID to the users who reacted
+	sent      []string
+}
+
+func (m *mockSubscriptionSession) MessageReactions(channelID, messageID, emojiID string, limit int, beforeID, afterID string, opts ...discordgo.RequestOption) ([]*discordgo.User, error) {
+	return m.reactions[messageID], nil
+}
+
+func (m *mockSubscriptionSession) ChannelMessageSend(channelID, content string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	m.sent = append(m.sent, content)
+	return &discordgo.Message{}, nil
+}
+
+func TestProcessRules_NotifySubscribers(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	originalCheckpoints := checkpoints
+	checkpoints = &checkpointStore{}
+	defer func() { checkpoints = originalCheckpoints }()
+	subscriberCache.Lock()
+	subscriberCache.entries = make(map[string]cachedSubscribers)
+	subscriberCache.Unlock()
+
+	session := &mockSubscriptionSession{
+		mockPinSession: &mockPinSession{
+			MockDiscordSession: mockSessionForRulesTest("bot").(*MockDiscordSession),
+			pinned: []*discordgo.Message{
+				{ID: "pin1", Reactions: []*discordgo.MessageReactions{{Count: 3, Emoji: &discordgo.Emoji{Name: "🔔"}}, {Count: 1, Emoji: &discordgo.Emoji{Name: "👍"}}}},
+				{ID: "pin2"},
+			},
+		},
+		reactions: map[string][]*discordgo.User{
+			"pin1": {{ID: "alice"}, {ID: "bob"}, {ID: "carol"}, {ID: "bot", Bot: true}},
+		},
+	}
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey:   "app",
+		UserDestinations: map[string]string{"alice": "uAlice"},
+		Subscriptions:    &Subscriptions{SelfRegistration: true},
+		Rules: []Rule{{
+			Name:       "Deploys",
+			Conditions: RuleConditions{ChannelID: "deploys"},
+			Actions:    RuleActions{PushoverDestination: "uTeam", NotifySubscribersOfEmoji: "🔔"},
+		}},
+	}
+	config.SetPushoverClient(fake)
+
+	// bob registers his key by DM; carol has none
+	dm := &discordgo.Message{ID: "dm1", ChannelID: "dmBob", Content: "!pushover uBob", Author: &discordgo.User{ID: "bob"}}
+	if !handleSubscriptionCommand(context.Background(), dm, config, session) {
+		t.Fatal("Expected the DM to be handled as a subscription command")
+	}
+	if len(session.sent) != 1 || !strings.Contains(session.sent[0], "registered") {
+		t.Errorf("Expected a confirmation, got %v", session.sent)
+	}
+	inGuild := &discordgo.Message{ID: "m0", GuildID: "g1", ChannelID: "deploys", Content: "!pushover uEve", Author: &discordgo.User{ID: "eve"}}
+	if handleSubscriptionCommand(context.Background(), inGuild, config, session) {
+		t.Error("Expected the command to be ignored outside DMs")
+	}
+
+	message := &discordgo.Message{ID: "m1", ChannelID: "deploys", Content: "deploying v2", Author: &discordgo.User{ID: "dave"}}
+	ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+	recipients := append([]string(nil), fake.recipients...)
+	sort.Strings(recipients)
+	if want := []string{"uAlice", "uBob", "uTeam"}; !reflect.DeepEqual(recipients, want) {
+		t.Errorf("Expected notifications to %v, got %v", want, recipients)
+	}
+
+	session.sent = nil
+	off := &discordgo.Message{ID: "dm2", ChannelID: "dmBob", Content: "!pushover off", Author: &discordgo.User{ID: "bob"}}
+	handleSubscriptionCommand(context.Background(), off, config, session)
+	if userDestination(config, "bob") != "" || len(session.sent) != 1 {
+		t.Errorf("Expected bob's key to be removed, got %q, replies %v", userDestination(config, "bob"), session.sent)
+	}
+}
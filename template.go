@@ -0,0 +1,154 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	_ "time/tzdata" // Embed zone data; the container image has no zoneinfo
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultTimestampFormat is used for NotificationData.Timestamp when a rule sets no timestampFormat.
+const defaultTimestampFormat = "2006-01-02 15:04 MST"
+
+// NotificationData is the data available to a rule's notification templates.
+type NotificationData struct {
+	RuleName   string
+	Event      string
+	Body       string // The default notification body (message content plus reply/context additions)
+	Content    string // The raw message content
+	Translated string // The content translated into the rule's translateTo language, empty without it
+	Score      int    // The message's score under the rule's scoring, 0 without it
+	AuthorID   string
+	AuthorName string
+	GuildID    string
+	ChannelID  string
+	MessageID  string
+	Link       string
+	Time       time.Time // Message time, converted to the rule's timezone
+	Timestamp  string    // Time formatted with the rule's timestampFormat
+	Embeds     []*discordgo.MessageEmbed
+	Args       []string          // onCommand: the command's arguments, with user mentions as @names
+	ArgText    string            // onCommand: the arguments as one string
+	ReplyCode  string            // Code to start a reply with when the reply bridge is enabled
+	Late       bool              // The message was posted while the bot was offline and found by the backfill
+	Labels     map[string]string // The rule's labels, e.g. {{.Labels.runbook}}
+	Payload    map[string]string // Fields parsed by the rule's payloadFormat, e.g. {{.Payload.alertName}}
+}
+
+// templateCache holds parsed templates keyed by their source text.
+var templateCache sync.Map
+
+// newNotificationData collects template data for a message, converting its time to the rule's timezone.
+func newNotificationData(rule *Rule, ruleNameLog string, event string, message *discordgo.Message, body string, link string) *NotificationData {
+	data := &NotificationData{
+		RuleName:  ruleNameLog,
+		Event:     event,
+		Body:      body,
+		Content:   message.Content,
+		GuildID:   message.GuildID,
+		ChannelID: message.ChannelID,
+		MessageID: message.ID,
+		Link:      link,
+		Time:      messageTime(message),
+		Embeds:    message.Embeds,
+		Labels:    rule.Labels,
+	}
+	if message.Author != nil {
+		data.AuthorID = message.Author.ID
+		data.AuthorName = message.Author.Username
+	}
+	if rule.Conditions.Command != nil {
+		data.ArgText, _ = parseCommand(message.ContentWithMentionsReplaced(), rule.Conditions.Command.Name)
+		data.Args = strings.Fields(data.ArgText)
+	}
+
+	if rule.Actions.Timezone != "" {
+		loc, err := time.LoadLocation(rule.Actions.Timezone)
+		if err != nil {
+			log.Warnf("Rule '%s' has invalid timezone '%s': %v. Using UTC.", ruleNameLog, rule.Actions.Timezone, err)
+			loc = time.UTC
+		}
+		data.Time = data.Time.In(loc)
+	}
+	format := rule.Actions.TimestampFormat
+	if format == "" {
+		format = defaultTimestampFormat
+	}
+	data.Timestamp = data.Time.Format(format)
+	return data
+}
+
+// messageTime returns when a message was posted, derived from its snowflake ID if the timestamp is missing.
+func messageTime(message *discordgo.Message) time.Time {
+	if !message.Timestamp.IsZero() {
+		return message.Timestamp.UTC()
+	}
+	if message.ID != "" {
+		if t, err := discordgo.SnowflakeTimestamp(message.ID); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Now().UTC()
+}
+
+// renderTemplate executes a template against data. definitions are named templates the source can
+// invoke with {{template "name" .}}. Parsed templates are cached.
+func renderTemplate(source string, definitions map[string]string, data *NotificationData) (string, error) {
+	cacheKey := source
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cacheKey += "\x00" + name + "\x00" + definitions[name]
+	}
+
+	var tmpl *template.Template
+	if cached, ok := templateCache.Load(cacheKey); ok {
+		tmpl = cached.(*template.Template)
+	} else {
+		parsed := template.New("notification").Funcs(templateFuncs).Option("missingkey=zero")
+		for _, name := range names {
+			if _, err := parsed.New(name).Parse(definitions[name]); err != nil {
+				return "", fmt.Errorf("failed to parse template definition %q: %w", name, err)
+			}
+		}
+		if _, err := parsed.Parse(source); err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+		templateCache.Store(cacheKey, parsed)
+		tmpl = parsed
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// renderNotification applies a rule's title and body templates, falling back to the defaults on error.
+func renderNotification(rule *Rule, data *NotificationData, ruleNameLog string) (title string, body string) {
+	body = data.Body
+	if rule.Actions.Template != "" {
+		if rendered, err := renderTemplate(rule.Actions.Template, rule.Actions.TemplateDefinitions, data); err != nil {
+			log.Errorf("Rule '%s': %v. Using default notification body.", ruleNameLog, err)
+		} else {
+			body = rendered
+		}
+	}
+	if rule.Actions.TitleTemplate != "" {
+		if rendered, err := renderTemplate(rule.Actions.TitleTemplate, rule.Actions.TemplateDefinitions, data); err != nil {
+			log.Errorf("Rule '%s': title %v. Using default title.", ruleNameLog, err)
+		} else {
+			title = rendered
+		}
+	}
+	return title, body
+}
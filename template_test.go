@@ -0,0 +1,66 @@
+package discord2pushover
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestRenderNotification_TimezoneAndFormat(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	message := &discordgo.Message{
+		ID:        "m1",
+		ChannelID: "c1",
+		Content:   "disk full",
+		Author:    &discordgo.User{ID: "u1", Username: "alice"},
+		Timestamp: time.Date(2024, 3, 1, 22, 30, 0, 0, time.UTC),
+	}
+	rule := &Rule{Actions: RuleActions{
+		Timezone:        "Asia/Tokyo",
+		TimestampFormat: "Jan 2 15:04 MST",
+		TitleTemplate:   "[{{.RuleName}}] {{.AuthorName}}",
+		Template:        "{{.Content}} at {{.Timestamp}}",
+	}}
+
+	data := newNotificationData(rule, "Disk", ruleEventMessage, message, "disk full", "https://discord.com/channels/@me/c1/m1")
+	title, body := renderNotification(rule, data, "Disk")
+	if title != "[Disk] alice" {
+		t.Errorf("Unexpected title %q", title)
+	}
+	if body != "disk full at Mar 2 07:30 JST" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestRenderNotification_Defaults(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	rule := &Rule{Actions: RuleActions{Template: "{{.Broken"}}
+	data := newNotificationData(rule, "r", ruleEventMessage, &discordgo.Message{ID: "175928847299117063"}, "default body", "")
+	title, body := renderNotification(rule, data, "r")
+	if title != "" || body != "default body" {
+		t.Errorf("Expected fallback to defaults on template error, got title=%q body=%q", title, body)
+	}
+	// Snowflake 175928847299117063 was created 2016-04-30 11:18:25.796 UTC
+	if data.Time.Year() != 2016 || data.Time.Month() != time.April {
+		t.Errorf("Expected message time derived from snowflake, got %v", data.Time)
+	}
+}
+
+func TestRenderNotification_Labels(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	rule := &Rule{
+		Labels:  map[string]string{"team": "storage", "runbook": "https://wiki/disk"},
+		Actions: RuleActions{Template: "{{.Content}} ({{.Labels.team}}, see {{.Labels.runbook}}){{.Labels.missing}}"},
+	}
+	data := newNotificationData(rule, "Disk", ruleEventMessage, &discordgo.Message{ID: "m1", Content: "disk full"}, "disk full", "")
+	if _, body := renderNotification(rule, data, "Disk"); body != "disk full (storage, see https://wiki/disk)" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
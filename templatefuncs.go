@@ -0,0 +1,166 @@
+package discord2pushover
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are the functions available in templates besides the text/template builtins.
+// Functions taking the text last can be used in pipelines, e.g. {{.Content | stripMarkdown | truncate 80}}.
+var templateFuncs = template.FuncMap{
+	"capture":       templateCapture,
+	"regexReplace":  templateRegexReplace,
+	"truncate":      templateTruncate,
+	"stripMarkdown": stripMarkdown,
+	"upper":         strings.ToUpper,
+	"lower":         strings.ToLower,
+	"trim":          strings.TrimSpace,
+	"humanizeTime":  humanizeTime,
+	"jsonPath":      jsonPath,
+}
+
+// templateCapture returns the first capture group of pattern in text, the whole match if the
+// pattern has no groups, or "" if it does not match. Used e.g. to pull an alert name out of content.
+func templateCapture(pattern string, text string) (string, error) {
+	re, err := compiledPattern(pattern)
+	if err != nil {
+		return "", err
+	}
+	match := re.FindStringSubmatch(text)
+	switch {
+	case match == nil:
+		return "", nil
+	case len(match) > 1:
+		return match[1], nil
+	default:
+		return match[0], nil
+	}
+}
+
+// templateRegexReplace replaces all matches of pattern in text; replacement may use $1-style references.
+func templateRegexReplace(pattern string, replacement string, text string) (string, error) {
+	re, err := compiledPattern(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(text, replacement), nil
+}
+
+// templateTruncate shortens text to at most n characters, ending with "…" if it was cut.
+func templateTruncate(n int, text string) string {
+	if n < 0 {
+		return text
+	}
+	return truncateRunes(text, n)
+}
+
+var (
+	markdownLink       = regexp.MustCompile(`\[([^\]]*)\]\((<?https?://[^)]*)\)`)
+	markdownCodeBlock  = regexp.MustCompile("(?s)```[a-zA-Z0-9_+-]*\n?(.*?)```")
+	markdownLinePrefix = regexp.MustCompile(`(?m)^(?:>>> |> |#{1,3} |-# )`)
+
+	// Emphasis markers, longest first so "**bold**" isn't taken for two "*italic*" markers.
+	// A single underscore only counts at word boundaries, like in Discord (snake_case_names stay intact).
+	markdownEmphasis = func() []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, marker := range []string{"***", "**", "__", "~~", "||", "*", "`"} {
+			m := regexp.QuoteMeta(marker)
+			res = append(res, regexp.MustCompile(`()`+m+`(\S(?:.*?\S)?)`+m+`()`))
+		}
+		return append(res, regexp.MustCompile(`(^|\W)_(\S(?:.*?\S)?)_(\W|$)`))
+	}()
+)
+
+// stripMarkdown removes Discord markdown (emphasis, spoilers, code, quotes, headers and masked links)
+// so notifications don't show the raw syntax.
+func stripMarkdown(text string) string {
+	text = markdownCodeBlock.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	for _, re := range markdownEmphasis {
+		text = re.ReplaceAllString(text, "$1$2$3")
+	}
+	return markdownLinePrefix.ReplaceAllString(text, "")
+}
+
+// humanizeTime describes t relative to now, e.g. "5 minutes ago" or "in 2 hours".
+func humanizeTime(t time.Time) string {
+	return humanizeDuration(time.Since(t))
+}
+
+func humanizeDuration(d time.Duration) string {
+	future := d < 0
+	if future {
+		d = -d
+	}
+	var amount int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		amount, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		amount, unit = int(d/time.Hour), "hour"
+	default:
+		amount, unit = int(d/(24*time.Hour)), "day"
+	}
+	if amount != 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", amount, unit)
+	}
+	return fmt.Sprintf("%d %s ago", amount, unit)
+}
+
+// jsonPath looks up a dotted path such as "0.fields.1.value" or "[0].title" in the JSON form of
+// value (e.g. .Embeds), so templates can reach into webhook payloads. Missing paths yield "".
+// Scalars are returned as text; objects and arrays as JSON.
+func jsonPath(path string, value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	var current interface{}
+	if err := json.Unmarshal(encoded, &current); err != nil {
+		return "", err
+	}
+
+	path = strings.NewReplacer("[", ".", "]", "").Replace(strings.TrimPrefix(path, "$"))
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", nil
+			}
+			current = node[index]
+		default:
+			return "", nil
+		}
+	}
+
+	switch node := current.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return node, nil
+	case float64:
+		return strconv.FormatFloat(node, 'f', -1, 64), nil
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(node)
+		return string(encoded), err
+	default:
+		return fmt.Sprint(node), nil
+	}
+}
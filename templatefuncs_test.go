@@ -0,0 +1,90 @@
+package discord2pushover
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"**Disk** is *almost* __full__":                 "Disk is almost full",
+		"~~old~~ ||secret|| `code` ***both***":          "old secret code both",
+		"see [runbook](https://example.com/rb) now":     "see runbook now",
+		"> quoted\n# Header\nplain":                     "quoted\nHeader\nplain",
+		"```go\nfmt.Println(1)\n```":                    "fmt.Println(1)\n",
+		"keep snake_case_names but strip _this_ please": "keep snake_case_names but strip this please",
+	}
+	for input, expected := range tests {
+		if result := stripMarkdown(input); result != expected {
+			t.Errorf("stripMarkdown(%q) = %q, expected %q", input, result, expected)
+		}
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		10 * time.Second:  "just now",
+		time.Minute:       "1 minute ago",
+		5 * time.Minute:   "5 minutes ago",
+		-2 * time.Hour:    "in 2 hours",
+		49 * time.Hour:    "2 days ago",
+		-90 * time.Minute: "in 1 hour",
+	}
+	for d, expected := range tests {
+		if result := humanizeDuration(d); result != expected {
+			t.Errorf("humanizeDuration(%v) = %q, expected %q", d, result, expected)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	embeds := []*discordgo.MessageEmbed{{
+		Title: "[FIRING:1] DiskFull",
+		Color: 0xe01e5a,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "severity", Value: "critical"},
+			{Name: "host", Value: "db1"},
+		},
+	}}
+	tests := map[string]string{
+		"[0].title":           "[FIRING:1] DiskFull",
+		"0.fields.1.value":    "db1",
+		"$[0].fields[0].name": "severity",
+		"[0].color":           "14687834",
+		"[1].title":           "",
+		"[0].missing":         "",
+		"[0].fields[0]":       `{"name":"severity","value":"critical"}`,
+	}
+	for path, expected := range tests {
+		result, err := jsonPath(path, embeds)
+		if err != nil || result != expected {
+			t.Errorf("jsonPath(%q) = %q, %v; expected %q", path, result, err, expected)
+		}
+	}
+}
+
+func TestRenderTemplate_FunctionsAndDefinitions(t *testing.T) {
+	data := &NotificationData{
+		RuleName: "Grafana",
+		Content:  "**[FIRING]** disk on `db1` is at 97%",
+		Embeds:   []*discordgo.MessageEmbed{{Fields: []*discordgo.MessageEmbedField{{Name: "severity", Value: "critical"}}}},
+	}
+	definitions := map[string]string{
+		"severity": `{{jsonPath "[0].fields[0].value" .Embeds | upper}}`,
+	}
+	source := `{{template "severity" .}}: {{.Content | stripMarkdown | regexReplace "\\[(\\w+)\\]" "$1:" | truncate 20}}`
+
+	result, err := renderTemplate(source, definitions, data)
+	if err != nil {
+		t.Fatalf("renderTemplate returned error: %v", err)
+	}
+	if expected := "CRITICAL: FIRING: disk on db1…"; result != expected {
+		t.Errorf("Unexpected result %q, expected %q", result, expected)
+	}
+
+	if _, err := renderTemplate(`{{template "missing" .}}`, definitions, data); err == nil {
+		t.Error("Expected error for undefined template")
+	}
+}
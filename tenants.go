@@ -0,0 +1,100 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tenantFor returns the tenant settings of a guild: its own entry, else the default. It is nil without
+// tenants or for messages outside a guild.
+func tenantFor(config *Config, guildID string) *Tenant {
+	if config.Tenants == nil || guildID == "" {
+		return nil
+	}
+	if tenant, ok := config.Tenants.Guilds[guildID]; ok {
+		return &tenant
+	}
+	return config.Tenants.Default
+}
+
+// tenantAllowsDestination reports whether a guild's notifications may go to a Pushover key.
+func tenantAllowsDestination(tenant *Tenant, key string) bool {
+	if tenant == nil || len(tenant.AllowedDestinations) == 0 {
+		return true
+	}
+	for _, allowed := range tenant.AllowedDestinations {
+		if allowed == key {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedTenantDestination removes the keys of a destination that the guild may not notify.
+func allowedTenantDestination(config *Config, guildID string, destination string, ruleNameLog string) string {
+	tenant := tenantFor(config, guildID)
+	if tenant == nil || len(tenant.AllowedDestinations) == 0 || destination == "" {
+		return destination
+	}
+	var keys []string
+	for _, key := range strings.Split(destination, ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		if !tenantAllowsDestination(tenant, key) {
+			log.Warnf("Rule '%s': destination %s is not in the allowedDestinations of guild %s, not notifying it.", ruleNameLog, key, guildID)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ",")
+}
+
+// allowedTenantNotifiers removes the notifiers that the guild may not send to: with allowedDestinations,
+// only those listed there by name.
+func allowedTenantNotifiers(config *Config, guildID string, names []string, ruleNameLog string) []string {
+	tenant := tenantFor(config, guildID)
+	if tenant == nil || len(tenant.AllowedDestinations) == 0 || len(names) == 0 {
+		return names
+	}
+	var allowed []string
+	for _, name := range names {
+		if !tenantAllowsDestination(tenant, name) {
+			log.Warnf("Rule '%s': notifier '%s' is not in the allowedDestinations of guild %s, not notifying it.", ruleNameLog, name, guildID)
+			continue
+		}
+		allowed = append(allowed, name)
+	}
+	return allowed
+}
+
+// tenantRuleStats returns the statistics of the rules a guild manages itself, leaving out every other
+// guild's and the config's rules.
+func tenantRuleStats(stats []RuleStats, guildID string) []RuleStats {
+	filtered := []RuleStats{}
+	prefix := guildRuleName(guildID, "")
+	for _, s := range stats {
+		if strings.HasPrefix(s.Rule, prefix) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// describeTenantUsage describes how much of its budget a guild used, e.g. "12 of 30 notifications in the
+// last hour", or "" if the guild has no budget.
+func describeTenantUsage(config *Config, guildID string, now time.Time) string {
+	tenant := tenantFor(config, guildID)
+	if tenant == nil || tenant.Budget == nil {
+		return ""
+	}
+	var parts []string
+	if tenant.Budget.MaxPerHour > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d notifications in the last hour", budgets.guildSendsSince(guildID, now.Add(-time.Hour)), tenant.Budget.MaxPerHour))
+	}
+	if tenant.Budget.MaxPerDay > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d in the last day", budgets.guildSendsSince(guildID, now.Add(-24*time.Hour)), tenant.Budget.MaxPerDay))
+	}
+	return strings.Join(parts, ", ")
+}
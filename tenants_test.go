@@ -0,0 +1,132 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestTenants(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	savedBudgets := budgets
+	budgets = &budgetTracker{windows: make(map[string]*budgetWindow)}
+	defer func() { budgets = savedBudgets }()
+
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey: "app",
+		Tenants: &Tenants{
+			Default: &Tenant{Budget: &Budget{MaxPerHour: 1}},
+			Guilds:  map[string]Tenant{"big": {Budget: &Budget{MaxPerHour: 5}, AllowedDestinations: []string{"uBig"}}},
+		},
+		Rules: []Rule{{Name: "Alerts", Conditions: RuleConditions{ContentIncludes: []string{"down"}}, Actions: RuleActions{PushoverDestination: "uBig, uOther"}}},
+	}
+	config.SetPushoverClient(fake)
+	session := mockSessionForRulesTest("bot")
+	send := func(id string, guildID string) {
+		ProcessRules(context.Background(), &discordgo.Message{ID: id, GuildID: guildID, ChannelID: "c-" + guildID, Content: "site down", Author: &discordgo.User{ID: "dave"}}, config, session, math.MaxInt32)
+	}
+
+	send("m1", "small")
+	send("m2", "small")
+	if len(fake.sent) != 1 {
+		t.Fatalf("Expected the default tenant budget to allow one notification per hour, got %d", len(fake.sent))
+	}
+	send("m3", "big")
+	send("m4", "big")
+	if len(fake.sent) != 3 {
+		t.Fatalf("Expected the exhausted budget of one guild not to hold up another, got %d", len(fake.sent))
+	}
+	if fake.recipients[1] != "uBig" || fake.recipients[2] != "uBig" {
+		t.Errorf("Expected only the allowed destination for guild big, got %v", fake.recipients)
+	}
+	if usage := describeTenantUsage(config, "big", time.Now()); usage != "2 of 5 notifications in the last hour" {
+		t.Errorf("Unexpected usage %q", usage)
+	}
+
+	countersFor(guildRuleName("big", "Deploys")).matched.Add(3)
+	countersFor(guildRuleName("small", "Secret")).matched.Add(1)
+	recorder := httptest.NewRecorder()
+	serveStatus(recorder, httptest.NewRequest(http.MethodGet, "/status?guild=big", nil))
+	var report statusReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(report.Rules) != 1 || report.Rules[0].Rule != "big/Deploys" {
+		t.Errorf("Expected only the guild's own rules, got %+v", report.Rules)
+	}
+
+	admin := &discordgo.Member{User: &discordgo.User{ID: "alice"}, Permissions: discordgo.PermissionManageGuild}
+	config.GuildRules = &GuildRules{}
+	reply := guildRuleCommandReply(context.Background(), config, guildRuleInteraction("big", admin, "stats"))
+	if !strings.Contains(reply, "**Deploys**: matched 3") || strings.Contains(reply, "Secret") || !strings.Contains(reply, "Quota: 2 of 5") {
+		t.Errorf("Expected the guild's own statistics and quota, got %q", reply)
+	}
+	if reply := guildRuleCommandReply(context.Background(), config, guildRuleInteraction("big", admin, "destination", stringOption("key", "uOther"))); !strings.Contains(reply, "not allowed") {
+		t.Errorf("Expected a destination outside the allowlist to be refused, got %q", reply)
+	}
+}
+
+func TestTenants_AllowedDestinationsEveryRecipient(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	originalCheckpoints := checkpoints
+	checkpoints = &checkpointStore{}
+	defer func() { checkpoints = originalCheckpoints }()
+	subscriberCache.Lock()
+	subscriberCache.entries = make(map[string]cachedSubscribers)
+	subscriberCache.Unlock()
+	allowedBackend, blockedBackend := &recordingNotifier{}, &recordingNotifier{}
+	RegisterNotifier("allowedBackend", allowedBackend)
+	RegisterNotifier("blockedBackend", blockedBackend)
+	defer func() {
+		registeredNotifiersMu.Lock()
+		delete(registeredNotifiers, "allowedBackend")
+		delete(registeredNotifiers, "blockedBackend")
+		registeredNotifiersMu.Unlock()
+	}()
+
+	session := &mockSubscriptionSession{
+		mockPinSession: &mockPinSession{
+			MockDiscordSession: mockSessionForRulesTest("bot").(*MockDiscordSession),
+			pinned:             []*discordgo.Message{{ID: "pin1", Reactions: []*discordgo.MessageReactions{{Count: 2, Emoji: &discordgo.Emoji{Name: "🔔"}}}}},
+		},
+		reactions: map[string][]*discordgo.User{"pin1": {{ID: "alice"}, {ID: "bob"}}},
+	}
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey:   "app",
+		UserDestinations: map[string]string{"alice": "uAlice", "bob": "uBob", "carol": "uCarol"},
+		RoleDestinations: map[string]string{"ops": "gOps", "everyone": "gEveryone"},
+		Tenants:          &Tenants{Guilds: map[string]Tenant{"g1": {AllowedDestinations: []string{"uTeam", "uAlice", "gOps", "allowedBackend"}}}},
+		Rules: []Rule{{
+			Name:       "Deploys",
+			Conditions: RuleConditions{ChannelID: "deploys"},
+			Actions: RuleActions{PushoverDestination: "uTeam", NotifySubscribersOfEmoji: "🔔", NotifyMentionedUsers: true, NotifyMentionedRoles: true,
+				Notify: []string{"allowedBackend", "blockedBackend"}},
+		}},
+	}
+	config.SetPushoverClient(fake)
+
+	message := &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "deploys", Content: "deploying v2", Author: &discordgo.User{ID: "dave"},
+		Mentions: []*discordgo.User{{ID: "carol"}}, MentionRoles: []string{"ops", "everyone"}}
+	ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+	recipients := append([]string(nil), fake.recipients...)
+	sort.Strings(recipients)
+	if !reflect.DeepEqual(recipients, []string{"gOps", "uAlice", "uTeam"}) {
+		t.Errorf("Expected subscriber bob, mentioned carol and role everyone blocked, got %v", recipients)
+	}
+	if len(allowedBackend.notifications) != 1 || len(blockedBackend.notifications) != 0 {
+		t.Errorf("Expected only the allowed notifier used, got %d and %d notifications", len(allowedBackend.notifications), len(blockedBackend.notifications))
+	}
+}
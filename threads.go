@@ -0,0 +1,109 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// threadSession is the subset of session calls needed to handle thread creation.
+type threadSession interface {
+	DiscordSessionInterface
+	ThreadJoin(id string, opts ...discordgo.RequestOption) error
+}
+
+// ThreadJoin calls the RealSession's ThreadJoin.
+func (w *DiscordGoSessionWrapper) ThreadJoin(id string, opts ...discordgo.RequestOption) error {
+	return w.RealSession.ThreadJoin(id, opts...)
+}
+
+var _ threadSession = &DiscordGoSessionWrapper{}
+
+// dgThreadCreate is the raw handler for discordgo's ThreadCreate events.
+func dgThreadCreate(s *discordgo.Session, t *discordgo.ThreadCreate) {
+	defer recoverPanic("dgThreadCreate")
+	ctx, cancel := eventContext(globalConfig)
+	defer cancel()
+	threadCreateLogic(ctx, &DiscordGoSessionWrapper{RealSession: s}, t)
+}
+
+// threadCreateLogic optionally joins a new thread in a monitored channel and runs onThreadCreate rules for it.
+func threadCreateLogic(ctx context.Context, s threadSession, t *discordgo.ThreadCreate) {
+	if t.Channel == nil || !t.NewlyCreated {
+		// ThreadCreate is also sent when the bot is added to an existing thread.
+		return
+	}
+	thread := t.Channel
+	log.Infof("Received ThreadCreate event: ThreadID: %s, ParentID: %s, Name: '%s'", thread.ID, thread.ParentID, thread.Name)
+	if globalConfig == nil {
+		log.Error("globalConfig is nil in threadCreateLogic. Rules cannot be processed.")
+		return
+	}
+
+	if globalConfig.AutoJoinThreads && isMonitoredChannel(globalConfig, thread.ParentID) {
+		if err := s.ThreadJoin(thread.ID); err != nil {
+			log.Errorf("Error joining thread %s in monitored channel %s: %v", thread.ID, thread.ParentID, err)
+		} else {
+			log.Infof("Joined thread '%s' (%s) in monitored channel %s.", thread.Name, thread.ID, thread.ParentID)
+		}
+	}
+
+	processRulesForEvent(ctx, ruleEventThreadCreate, threadEventMessage(s, thread), nil, globalConfig, s, math.MaxInt32)
+}
+
+// threadEventMessage builds the message evaluated by onThreadCreate rules. Forum posts have a starter
+// message with the thread's ID; if it exists its content is used, otherwise only the thread name.
+func threadEventMessage(s DiscordSessionInterface, thread *discordgo.Channel) *discordgo.Message {
+	message := &discordgo.Message{
+		ID:        thread.ID,
+		ChannelID: thread.ID,
+		GuildID:   thread.GuildID,
+		Author:    &discordgo.User{ID: thread.OwnerID},
+		Thread:    thread,
+	}
+	content := fmt.Sprintf("🧵 New thread: %s", thread.Name)
+	if starter, err := s.ChannelMessage(thread.ID, thread.ID); err == nil && starter != nil {
+		if starter.Author != nil {
+			message.Author = starter.Author
+		}
+		message.Mentions = starter.Mentions
+		message.MentionRoles = starter.MentionRoles
+		if starter.Content != "" {
+			content = fmt.Sprintf("%s\n%s", content, starter.Content)
+		}
+	}
+	message.Content = content
+	return message
+}
+
+// isMonitoredChannel reports whether any rule refers to channelID as its channel or parent channel.
+func isMonitoredChannel(config *Config, channelID string) bool {
+	if channelID == "" {
+		return false
+	}
+	for _, rule := range config.Rules {
+		if rule.Conditions.ChannelID == channelID || rule.Conditions.ParentChannelID == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// messageThread returns the thread the message was posted in, or nil if it is not in a thread.
+// Synthetic onThreadCreate messages carry their thread directly; otherwise the state cache is consulted.
+func messageThread(session DiscordSessionInterface, message *discordgo.Message) *discordgo.Channel {
+	if message.Thread != nil && message.Thread.ID == message.ChannelID {
+		return message.Thread
+	}
+	state := session.State()
+	if state == nil {
+		return nil
+	}
+	ch, err := state.Channel(message.ChannelID)
+	if err != nil || ch == nil || !ch.IsThread() {
+		return nil
+	}
+	return ch
+}
@@ -0,0 +1,96 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type mockThreadSession struct {
+	*MockDiscordSession
+	joined []string
+}
+
+func (m *mockThreadSession) ThreadJoin(id string, opts ...discordgo.RequestOption) error {
+	m.joined = append(m.joined, id)
+	return nil
+}
+
+func TestThreadCreateLogic(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	originalTestHookDisablePushoverSend := testHookDisablePushoverSend
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = originalTestHookDisablePushoverSend }()
+
+	globalConfig = &Config{
+		AutoJoinThreads: true,
+		Rules: []Rule{
+			{Name: "IncidentThreads", Event: ruleEventThreadCreate,
+				Conditions: RuleConditions{ParentChannelID: "forumIncidents", ThreadNamePattern: `(?i)^sev[12]`},
+				Actions:    RuleActions{PushoverDestination: "userkey"}},
+		},
+	}
+
+	sess := &mockThreadSession{MockDiscordSession: &MockDiscordSession{
+		CustomChannelMessageFunc: func(channelID, messageID string, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+			if messageID == "threadSev1" {
+				return &discordgo.Message{ID: messageID, Author: &discordgo.User{ID: "poster"}, Content: "db primary unreachable"}, nil
+			}
+			return nil, fmt.Errorf("no starter message")
+		},
+	}}
+
+	tests := []struct {
+		name          string
+		thread        *discordgo.ThreadCreate
+		expectJoin    bool
+		expectMatched bool
+	}{
+		{
+			name:          "MatchingForumPost",
+			thread:        &discordgo.ThreadCreate{NewlyCreated: true, Channel: &discordgo.Channel{ID: "threadSev1", ParentID: "forumIncidents", Name: "SEV1 db outage", Type: discordgo.ChannelTypeGuildPublicThread}},
+			expectJoin:    true,
+			expectMatched: true,
+		},
+		{
+			name:          "NameMismatch",
+			thread:        &discordgo.ThreadCreate{NewlyCreated: true, Channel: &discordgo.Channel{ID: "threadSev3", ParentID: "forumIncidents", Name: "SEV3 typo on website", Type: discordgo.ChannelTypeGuildPublicThread}},
+			expectJoin:    true,
+			expectMatched: false,
+		},
+		{
+			name:          "UnmonitoredParent",
+			thread:        &discordgo.ThreadCreate{NewlyCreated: true, Channel: &discordgo.Channel{ID: "threadOther", ParentID: "general", Name: "SEV1 drill", Type: discordgo.ChannelTypeGuildPublicThread}},
+			expectJoin:    false,
+			expectMatched: false,
+		},
+		{
+			name:          "NotNewlyCreated",
+			thread:        &discordgo.ThreadCreate{NewlyCreated: false, Channel: &discordgo.Channel{ID: "threadOld", ParentID: "forumIncidents", Name: "SEV1 old", Type: discordgo.ChannelTypeGuildPublicThread}},
+			expectJoin:    false,
+			expectMatched: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testLogBufferForTest.Reset()
+			sess.joined = nil
+			threadCreateLogic(context.Background(), sess, tt.thread)
+
+			joined := len(sess.joined) == 1 && sess.joined[0] == tt.thread.ID
+			if joined != tt.expectJoin {
+				t.Errorf("Expected join=%v, got joined=%v", tt.expectJoin, sess.joined)
+			}
+			matched := strings.Contains(testLogBufferForTest.String(), "('IncidentThreads') MATCHED")
+			if matched != tt.expectMatched {
+				t.Errorf("Expected matched=%v. Log: %s", tt.expectMatched, testLogBufferForTest.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	defaultTokenCheckIntervalMinutes = 60
+	defaultQuotaWarningPercent       = 10
+)
+
+// discordAPIBase is the Discord REST API endpoint the token is checked against; tests point it at a
+// local server.
+var discordAPIBase = discordgo.EndpointAPI
+
+// errTokenRejected is returned by the token checks when the API rejected the token, as opposed to
+// being unreachable.
+var errTokenRejected = errors.New("token rejected")
+
+// pushoverAppLimits is the message quota of a Pushover application.
+type pushoverAppLimits struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+// verifyDiscordToken returns the bot user a Discord token belongs to.
+func verifyDiscordToken(ctx context.Context, client *http.Client, token string) (*discordgo.User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discordAPIBase+"users/@me", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bot "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w by Discord: HTTP %d", errTokenRejected, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from Discord: HTTP %d", resp.StatusCode)
+	}
+	var user discordgo.User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("invalid response from Discord: %w", err)
+	}
+	return &user, nil
+}
+
+// fetchPushoverAppLimits returns the message quota of a Pushover app key, which also verifies the key.
+func fetchPushoverAppLimits(ctx context.Context, client *http.Client, base string, appKey string) (*pushoverAppLimits, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/apps/limits.json?"+url.Values{"token": {appKey}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		pushoverAppLimits
+		Status int      `json:"status"`
+		Errors []string `json:"errors"`
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("unexpected response from Pushover: HTTP %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response from Pushover (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if result.Status != 1 {
+		return nil, fmt.Errorf("%w by Pushover: %s", errTokenRejected, strings.Join(result.Errors, ", "))
+	}
+	return &result.pushoverAppLimits, nil
+}
+
+// tokenMonitor holds the tokens in use and which alerts about them were sent, so each problem is
+// alerted once until it is resolved.
+type tokenMonitor struct {
+	discordToken     string
+	pushoverAppKey   string
+	discordAlerted   bool
+	pushoverAlerted  bool
+	quotaAlertedFor  int64 // Reset time of the quota period alerted about
+	httpClient       *http.Client
+	quotaWarnPercent int
+}
+
+// check verifies both tokens and alerts through the error notification when one was rejected or the
+// Pushover quota is running out. Checks that fail for other reasons, e.g. network errors, are logged only.
+func (m *tokenMonitor) check(ctx context.Context, session DiscordSessionInterface, config *Config) {
+	if _, err := verifyDiscordToken(ctx, m.httpClient, m.discordToken); errors.Is(err, errTokenRejected) {
+		if !m.discordAlerted {
+			m.discordAlerted = true
+			sendErrorNotification(session, config, errorBackendDiscord, fmt.Sprintf("The Discord token is no longer valid (%v). The bot cannot reconnect to Discord until the token is replaced and reloaded with SIGHUP.", err))
+		}
+	} else if err != nil {
+		log.Warnf("Could not check the Discord token: %v", err)
+	} else {
+		m.discordAlerted = false
+	}
+
+	limits, err := fetchPushoverAppLimits(ctx, m.httpClient, config.pushoverBase(), m.pushoverAppKey)
+	switch {
+	case errors.Is(err, errTokenRejected):
+		if !m.pushoverAlerted {
+			m.pushoverAlerted = true
+			sendErrorNotification(session, config, errorBackendPushover, fmt.Sprintf("The Pushover app key is no longer valid (%v). Notifications fail until the key is replaced and reloaded with SIGHUP.", err))
+		}
+	case err != nil:
+		log.Warnf("Could not check the Pushover app key: %v", err)
+	default:
+		m.pushoverAlerted = false
+		log.Debugf("Pushover quota: %d of %d messages remaining.", limits.Remaining, limits.Limit)
+		if limits.Limit > 0 && limits.Remaining*100 < limits.Limit*m.quotaWarnPercent && m.quotaAlertedFor != limits.Reset {
+			m.quotaAlertedFor = limits.Reset
+			sendErrorNotification(session, config, errorBackendPushover, fmt.Sprintf("Only %d of %d Pushover messages remain until the quota resets at %s.",
+				limits.Remaining, limits.Limit, time.Unix(limits.Reset, 0).Format(time.RFC1123)))
+		}
+	}
+}
+
+// readLockCredentials read-locks the config's DiscordToken and PushoverAppKey and returns the function
+// unlocking them. Configs not created by LoadConfig are not rotated and have no lock.
+func (c *Config) readLockCredentials() func() {
+	if c.credentials == nil {
+		return func() {}
+	}
+	c.credentials.RLock()
+	return c.credentials.RUnlock
+}
+
+// lockCredentials is readLockCredentials for changing the tokens.
+func (c *Config) lockCredentials() func() {
+	if c.credentials == nil {
+		return func() {}
+	}
+	c.credentials.Lock()
+	return c.credentials.Unlock
+}
+
+// currentDiscordToken returns the config's Discord token, as last rotated.
+func (c *Config) currentDiscordToken() string {
+	defer c.readLockCredentials()()
+	return c.DiscordToken
+}
+
+// currentPushoverAppKey returns the config's Pushover app key, as last rotated.
+func (c *Config) currentPushoverAppKey() string {
+	defer c.readLockCredentials()()
+	return c.PushoverAppKey
+}
+
+// rotate switches to the tokens of the config file as it is now, after verifying them. The new Discord
+// token is used for REST requests right away, and by the gateway the next time it identifies, so the
+// open gateway session is kept. It must belong to the same bot.
+func (m *tokenMonitor) rotate(ctx context.Context, dg *discordgo.Session, config *Config) error {
+	fresh, err := LoadConfigEnv(config.filePath, config.env)
+	if err != nil {
+		return err
+	}
+	discordChanged := fresh.DiscordToken != m.discordToken
+	pushoverChanged := fresh.PushoverAppKey != m.pushoverAppKey
+	if !discordChanged && !pushoverChanged {
+		log.Info("Reload: the Discord token and Pushover app key are unchanged.")
+		return nil
+	}
+	if discordChanged {
+		user, err := verifyDiscordToken(ctx, m.httpClient, fresh.DiscordToken)
+		if err != nil {
+			return fmt.Errorf("new Discord token: %w", err)
+		}
+		if dg.State != nil && dg.State.User != nil && user.ID != dg.State.User.ID {
+			return fmt.Errorf("new Discord token belongs to %s, not to this bot %s", user.String(), dg.State.User.String())
+		}
+	}
+	var rotator interface{ setToken(string) }
+	if pushoverChanged {
+		if _, err := fetchPushoverAppLimits(ctx, m.httpClient, config.pushoverBase(), fresh.PushoverAppKey); err != nil {
+			return fmt.Errorf("new Pushover app key: %w", err)
+		}
+		var ok bool
+		if rotator, ok = config.pushoverClient().(interface{ setToken(string) }); !ok {
+			return errors.New("the Pushover client of this program cannot change its app key")
+		}
+	}
+	if discordChanged {
+		dg.Lock()
+		dg.Token = "Bot " + fresh.DiscordToken
+		dg.Unlock()
+		unlock := config.lockCredentials()
+		config.DiscordToken = fresh.DiscordToken
+		unlock()
+		m.discordToken, m.discordAlerted = fresh.DiscordToken, false
+		log.Info("Reload: switched to the new Discord token.")
+	}
+	if pushoverChanged {
+		rotator.setToken(fresh.PushoverAppKey)
+		unlock := config.lockCredentials()
+		config.PushoverAppKey = fresh.PushoverAppKey
+		unlock()
+		m.pushoverAppKey, m.pushoverAlerted = fresh.PushoverAppKey, false
+		log.Info("Reload: switched to the new Pushover app key.")
+	}
+	return nil
+}
+
+// reload rotates the tokens and alerts through the error notification if the config cannot be
+// reloaded or its tokens are refused.
+func (m *tokenMonitor) reload(ctx context.Context, session DiscordSessionInterface, dg *discordgo.Session, config *Config) {
+	if err := m.rotate(ctx, dg, config); err != nil {
+		log.Errorf("Reload failed, keeping the current tokens: %v", err)
+		sendErrorNotification(session, config, "", fmt.Sprintf("Reloading the configuration failed, the current Discord token and Pushover app key are kept: %v", err))
+	}
+}
+
+// monitorTokens checks the Discord token and Pushover app key every tokenCheck.intervalMinutes, and
+// rotates them to those in the file config was loaded from whenever a signal is received on reload.
+func monitorTokens(ctx context.Context, dg *discordgo.Session, config *Config, reload <-chan os.Signal) {
+	defer recoverPanic("monitorTokens")
+	m := &tokenMonitor{
+		discordToken:     config.DiscordToken,
+		pushoverAppKey:   config.PushoverAppKey,
+		httpClient:       newHTTPClient(config),
+		quotaWarnPercent: defaultQuotaWarningPercent,
+	}
+	interval := defaultTokenCheckIntervalMinutes
+	if tc := config.TokenCheck; tc != nil {
+		if tc.IntervalMinutes != 0 {
+			interval = tc.IntervalMinutes
+		}
+		if tc.QuotaWarningPercent > 0 {
+			m.quotaWarnPercent = tc.QuotaWarningPercent
+		}
+	}
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		tick = ticker.C
+		log.Infof("Checking the Discord token and Pushover app key every %d minutes.", interval)
+	}
+	session := &DiscordGoSessionWrapper{RealSession: dg}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			m.check(ctx, session, config)
+		case sig := <-reload:
+			log.Infof("Received signal: %v. Reloading the Discord token and Pushover app key...", sig)
+			m.reload(ctx, session, dg, config)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package discord2pushover
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestTokenMonitor(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/users/@me":
+			switch r.Header.Get("Authorization") {
+			case "Bot good", "Bot rotated":
+				w.Write([]byte(`{"id":"1","username":"bot"}`))
+			case "Bot other":
+				w.Write([]byte(`{"id":"2","username":"other"}`))
+			default:
+				http.Error(w, `{"message":"401: Unauthorized"}`, http.StatusUnauthorized)
+			}
+		case "/1/apps/limits.json":
+			switch r.URL.Query().Get("token") {
+			case "app", "rotated-app":
+				w.Write([]byte(`{"limit":100,"remaining":5,"reset":1700000000,"status":1}`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"status":0,"errors":["application token is invalid"]}`))
+			}
+		}
+	}))
+	defer server.Close()
+	defer func(discord, pushover string) { discordAPIBase, pushoverAPIBase = discord, pushover }(discordAPIBase, pushoverAPIBase)
+	discordAPIBase, pushoverAPIBase = server.URL+"/api/", server.URL+"/1"
+
+	fake := &fakePushoverClient{}
+	config := &Config{PushoverAppKey: "app", ErrorNotification: &ErrorNotification{PushoverDestination: "uAdmin"}}
+	config.SetPushoverClient(fake)
+	m := &tokenMonitor{discordToken: "revoked", pushoverAppKey: "app", httpClient: server.Client(), quotaWarnPercent: 10}
+	m.check(context.Background(), nil, config)
+	m.check(context.Background(), nil, config)
+	if len(fake.sent) != 2 || !strings.Contains(fake.sent[0].Message, "Discord token is no longer valid") || !strings.Contains(fake.sent[1].Message, "Only 5 of 100") {
+		t.Fatalf("Expected one alert about the token and one about the quota, got %+v", fake.sent)
+	}
+	m.discordToken, m.pushoverAppKey = "good", "deleted"
+	m.check(context.Background(), nil, config)
+	if len(fake.sent) != 3 || !strings.Contains(fake.sent[2].Message, "application token is invalid") || m.discordAlerted {
+		t.Errorf("Expected an alert about the app key and the Discord alert re-armed, got %+v", fake.sent)
+	}
+
+	path := filepath.Join(t.TempDir(), "discord2pushover.yaml")
+	dg, _ := discordgo.New("Bot good")
+	dg.State.User = &discordgo.User{ID: "1", Username: "bot"}
+	client := NewPushoverClient("app", server.Client())
+	config = &Config{PushoverAppKey: "app", DiscordToken: "good", filePath: path, credentials: new(sync.RWMutex)}
+	config.SetPushoverClient(client)
+	m = &tokenMonitor{discordToken: "good", pushoverAppKey: "app", httpClient: server.Client()}
+
+	os.WriteFile(path, []byte("discordToken: other\npushoverAppKey: rotated-app\n"), 0o600)
+	if err := m.rotate(context.Background(), dg, config); err == nil || !strings.Contains(err.Error(), "not to this bot") || dg.Token != "Bot good" {
+		t.Errorf("Expected the token of another bot refused, got %v", err)
+	}
+	os.WriteFile(path, []byte("discordToken: rotated\npushoverAppKey: rotated-app\n"), 0o600)
+	if err := m.rotate(context.Background(), dg, config); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+	if dg.Token != "Bot rotated" || client.(*pushoverAPI).appToken() != "rotated-app" {
+		t.Errorf("Expected both tokens rotated, got %q and %q", dg.Token, client.(*pushoverAPI).appToken())
+	}
+	if config.currentDiscordToken() != "rotated" || config.currentPushoverAppKey() != "rotated-app" {
+		t.Errorf("Expected the config to hold the rotated tokens, got %q and %q", config.currentDiscordToken(), config.currentPushoverAppKey())
+	}
+
+	os.WriteFile(path, []byte("discordToken: [unterminated\n"), 0o600)
+	fake = &fakePushoverClient{}
+	config = &Config{PushoverAppKey: "app", filePath: path, ErrorNotification: &ErrorNotification{PushoverDestination: "uAdmin"}}
+	config.SetPushoverClient(fake)
+	m.reload(context.Background(), nil, dg, config)
+	if len(fake.sent) != 1 || !strings.Contains(fake.sent[0].Message, "Reloading the configuration failed") || dg.Token != "Bot rotated" {
+		t.Errorf("Expected an alert about the failed reload and the tokens kept, got %+v", fake.sent)
+	}
+}
@@ -0,0 +1,89 @@
+package discord2pushover
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// decisionTrace records how the rules decided on a message. With traceDecisions it is logged as a
+// single JSON object once the message is processed.
+type decisionTrace struct {
+	MessageID string         `json:"messageId"`
+	ChannelID string         `json:"channelId"`
+	Event     string         `json:"event"`
+	Outcome   string         `json:"outcome"`
+	Rules     []ruleDecision `json:"rules"`
+
+	config    *Config
+	decisions map[int]ruleDecision // Rule index to its recorded decision
+}
+
+// ruleDecision is the result of one rule for a message.
+type ruleDecision struct {
+	Rule   string `json:"rule"`
+	Result string `json:"result"`           // "matched", "failed" or "skipped"
+	Reason string `json:"reason,omitempty"` // Failed condition and why, or why the rule was skipped
+}
+
+// newDecisionTrace starts a trace for a message, or returns nil if traceDecisions is off. The
+// methods of a nil trace do nothing.
+func newDecisionTrace(config *Config, event string, message *discordgo.Message) *decisionTrace {
+	if !config.TraceDecisions {
+		return nil
+	}
+	return &decisionTrace{MessageID: message.ID, ChannelID: message.ChannelID, Event: event, Outcome: "no rule matched",
+		config: config, decisions: make(map[int]ruleDecision)}
+}
+
+// record sets the decision of the rule at index.
+func (t *decisionTrace) record(index int, ruleNameLog string, result string, reason string) {
+	if t == nil {
+		return
+	}
+	t.decisions[index] = ruleDecision{Rule: ruleNameLog, Result: result, Reason: reason}
+}
+
+// setOutcome sets what happened to the message as a whole.
+func (t *decisionTrace) setOutcome(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.Outcome = fmt.Sprintf(format, args...)
+}
+
+// skipReason explains why a rule without a recorded decision wasn't evaluated.
+func (t *decisionTrace) skipReason(rule *Rule) string {
+	switch {
+	case t.Outcome == "ignored":
+		return "message is ignored"
+	case ruleEvent(rule) != t.Event:
+		return fmt.Sprintf("Event: rule handles '%s' events", ruleEvent(rule))
+	case rule.Conditions.ChannelID != "" && rule.Conditions.ChannelID != t.ChannelID:
+		return fmt.Sprintf("ChannelID: rule is for channel %s", rule.Conditions.ChannelID)
+	default:
+		return "an earlier rule matched"
+	}
+}
+
+// emit logs the trace, listing every rule of the config in order.
+func (t *decisionTrace) emit() {
+	if t == nil {
+		return
+	}
+	t.Rules = make([]ruleDecision, len(t.config.Rules))
+	for i := range t.config.Rules {
+		decision, ok := t.decisions[i]
+		if !ok {
+			decision = ruleDecision{Rule: ruleNameForLog(&t.config.Rules[i], i), Result: "skipped", Reason: t.skipReason(&t.config.Rules[i])}
+		}
+		t.Rules[i] = decision
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		log.Errorf("Failed to encode decision trace for message ID %s: %v", t.MessageID, err)
+		return
+	}
+	log.Infof("Decision trace: %s", data)
+}
@@ -0,0 +1,73 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDecisionTrace(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	config := &Config{
+		TraceDecisions: true,
+		Ignore:         &IgnoreList{UserIDs: []string{"noisy"}},
+		Rules: []Rule{
+			{Name: "Pins", Event: ruleEventPin},
+			{Name: "Elsewhere", Conditions: RuleConditions{ChannelID: "other"}},
+			{Name: "Keywords", Conditions: RuleConditions{ContentIncludes: []string{"FIRING"}}},
+			{Name: "Catch-all"},
+			{Name: "Unreached"},
+		},
+	}
+	config.ruleIndex = newRuleIndex(config.Rules)
+	session := mockSessionForRulesTest("bot")
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m1", ChannelID: "alerts", Content: "resolved", Author: &discordgo.User{ID: "grafana"}}, config, session, 1<<31-1)
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m2", ChannelID: "alerts", Content: "FIRING", Author: &discordgo.User{ID: "noisy"}}, config, session, 1<<31-1)
+
+	var traces []decisionTrace
+	for _, line := range strings.Split(testLogBufferForTest.String(), "\n") {
+		_, quoted, ok := strings.Cut(line, "msg=")
+		if !ok {
+			continue
+		}
+		quoted, _ = strconv.QuotedPrefix(quoted) // The text formatter quotes messages with special characters
+		msg, _ := strconv.Unquote(quoted)
+		if data, ok := strings.CutPrefix(msg, "Decision trace: "); ok {
+			var trace decisionTrace
+			if err := json.Unmarshal([]byte(data), &trace); err != nil {
+				t.Fatalf("Invalid trace %s: %v", data, err)
+			}
+			traces = append(traces, trace)
+		}
+	}
+	if len(traces) != 2 {
+		t.Fatalf("Expected one trace per message, got %d", len(traces))
+	}
+
+	got := traces[0]
+	if got.MessageID != "m1" || got.Outcome != "matched rule 'Catch-all'" || len(got.Rules) != 5 {
+		t.Fatalf("Unexpected trace %+v", got)
+	}
+	want := []ruleDecision{
+		{Rule: "Pins", Result: "skipped", Reason: "Event: rule handles '" + ruleEventPin + "' events"},
+		{Rule: "Elsewhere", Result: "skipped", Reason: "ChannelID: rule is for channel other"},
+		{Rule: "Keywords", Result: "failed", Reason: "ContentIncludes: keywords [FIRING] not in message"},
+		{Rule: "Catch-all", Result: "matched"},
+		{Rule: "Unreached", Result: "skipped", Reason: "an earlier rule matched"},
+	}
+	for i := range want {
+		if got.Rules[i] != want[i] {
+			t.Errorf("Rule %d: expected %+v, got %+v", i, want[i], got.Rules[i])
+		}
+	}
+
+	if traces[1].Outcome != "ignored" || traces[1].Rules[2].Reason != "message is ignored" {
+		t.Errorf("Unexpected trace for an ignored message %+v", traces[1])
+	}
+}
@@ -0,0 +1,166 @@
+package discord2pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Translation providers for Translation.Provider.
+const (
+	translationProviderLibreTranslate = "libretranslate"
+	translationProviderDeepL          = "deepl"
+)
+
+// defaultTranslationTimeout bounds a translation request when the config sets no timeoutSeconds.
+const defaultTranslationTimeout = 5 * time.Second
+
+// maxTranslationCacheEntries bounds the translation cache; it is cleared when full.
+const maxTranslationCacheEntries = 1000
+
+// DeepL serves keys of its free plan, which end in ":fx", from a separate host.
+const (
+	deepLURL     = "https://api.deepl.com/v2/translate"
+	deepLFreeURL = "https://api-free.deepl.com/v2/translate"
+)
+
+// translationCache holds translations per target language and text, so re-evaluations triggered by
+// reactions and edits that don't change the content don't call the provider again.
+var translationCache = struct {
+	sync.Mutex
+	texts map[string]string
+}{texts: make(map[string]string)}
+
+// translateText returns text translated into the target language by the configured provider.
+func translateText(ctx context.Context, client *http.Client, translation *Translation, text string, target string) (string, error) {
+	cacheKey := target + "\x00" + text
+	translationCache.Lock()
+	translated, ok := translationCache.texts[cacheKey]
+	translationCache.Unlock()
+	if ok {
+		return translated, nil
+	}
+
+	timeout := defaultTranslationTimeout
+	if translation.TimeoutSeconds > 0 {
+		timeout = time.Duration(translation.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var err error
+	switch translation.Provider {
+	case translationProviderLibreTranslate:
+		translated, err = translateLibreTranslate(ctx, client, translation, text, target)
+	case translationProviderDeepL:
+		translated, err = translateDeepL(ctx, client, translation, text, target)
+	default:
+		return "", fmt.Errorf("unknown translation provider '%s', expected %s or %s", translation.Provider, translationProviderLibreTranslate, translationProviderDeepL)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	translationCache.Lock()
+	if len(translationCache.texts) >= maxTranslationCacheEntries {
+		translationCache.texts = make(map[string]string)
+	}
+	translationCache.texts[cacheKey] = translated
+	translationCache.Unlock()
+	return translated, nil
+}
+
+// translateLibreTranslate translates text with a LibreTranslate server, which detects the source language.
+func translateLibreTranslate(ctx context.Context, client *http.Client, translation *Translation, text string, target string) (string, error) {
+	if translation.URL == "" {
+		return "", fmt.Errorf("translation provider %s needs a url", translationProviderLibreTranslate)
+	}
+	request := map[string]string{"q": text, "source": "auto", "target": target, "format": "text"}
+	if translation.APIKey != "" {
+		request["api_key"] = translation.APIKey
+	}
+	var response struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := postTranslation(ctx, client, translation.URL, nil, request, &response); err != nil {
+		return "", err
+	}
+	return response.TranslatedText, nil
+}
+
+// translateDeepL translates text with the DeepL API, which detects the source language.
+func translateDeepL(ctx context.Context, client *http.Client, translation *Translation, text string, target string) (string, error) {
+	if translation.APIKey == "" {
+		return "", fmt.Errorf("translation provider %s needs an apiKey", translationProviderDeepL)
+	}
+	url := translation.URL
+	if url == "" {
+		url = deepLURL
+		if strings.HasSuffix(translation.APIKey, ":fx") {
+			url = deepLFreeURL
+		}
+	}
+	request := map[string]interface{}{"text": []string{text}, "target_lang": strings.ToUpper(target)}
+	var response struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + translation.APIKey}
+	if err := postTranslation(ctx, client, url, headers, request, &response); err != nil {
+		return "", err
+	}
+	if len(response.Translations) == 0 {
+		return "", fmt.Errorf("translation response contains no translation")
+	}
+	return response.Translations[0].Text, nil
+}
+
+// postTranslation POSTs a JSON request to a translation endpoint and decodes its JSON response.
+func postTranslation(ctx context.Context, client *http.Client, url string, headers map[string]string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid translation url %q: %w", url, err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		httpRequest.Header.Set(name, value)
+	}
+	resp, err := client.Do(httpRequest)
+	if err != nil {
+		return fmt.Errorf("translation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("translation endpoint returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("invalid translation response: %w", err)
+	}
+	return nil
+}
+
+// translateNotificationContent translates the content of a matched message into the rule's
+// translateTo language. If translation is not configured or fails, the content is returned
+// unchanged, so the notification is still sent.
+func translateNotificationContent(ctx context.Context, config *Config, content string, target string, ruleNameLog string, messageID string) string {
+	if config.Translation == nil {
+		log.Errorf("Rule '%s' sets translateTo but the config has no translation provider; sending message ID %s untranslated.", ruleNameLog, messageID)
+		return content
+	}
+	translated, err := translateText(ctx, newHTTPClient(config), config.Translation, content, target)
+	if err != nil {
+		log.Errorf("Rule '%s': translating message ID %s into '%s' failed, sending it untranslated: %v", ruleNameLog, messageID, target, err)
+		return content
+	}
+	log.Debugf("Rule '%s': translated message ID %s into '%s'.", ruleNameLog, messageID, target)
+	return translated
+}
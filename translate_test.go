@@ -0,0 +1,69 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTranslateNotificationContent(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var request map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/translate": // LibreTranslate
+			if request["target"] != "en" || request["api_key"] != "libre-key" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"translatedText": "The database is down"})
+		case "/v2/translate": // DeepL
+			if r.Header.Get("Authorization") != "DeepL-Auth-Key deepl-key" || request["target_lang"] != "EN" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"translations": []map[string]string{{"detected_source_language": "DE", "text": "The server is down"}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := &Config{Translation: &Translation{Provider: "libretranslate", URL: server.URL + "/translate", APIKey: "libre-key"}}
+	if got := translateNotificationContent(context.Background(), config, "Die Datenbank ist ausgefallen", "en", "test", "m1"); got != "The database is down" {
+		t.Errorf("Expected the LibreTranslate translation, got %q", got)
+	}
+	translateNotificationContent(context.Background(), config, "Die Datenbank ist ausgefallen", "en", "test", "m1")
+	if calls != 1 {
+		t.Errorf("Expected the cached translation to be reused, endpoint called %d times", calls)
+	}
+
+	config.Translation = &Translation{Provider: "deepl", URL: server.URL + "/v2/translate", APIKey: "deepl-key"}
+	if got := translateNotificationContent(context.Background(), config, "Der Server ist ausgefallen", "en", "test", "m2"); got != "The server is down" {
+		t.Errorf("Expected the DeepL translation, got %q", got)
+	}
+
+	// Failures send the original content
+	config.Translation.APIKey = "wrong"
+	if got := translateNotificationContent(context.Background(), config, "Alles ausgefallen", "en", "test", "m3"); got != "Alles ausgefallen" {
+		t.Errorf("Expected the original content on failure, got %q", got)
+	}
+	if !strings.Contains(testLogBufferForTest.String(), "sending it untranslated") {
+		t.Errorf("Expected the failure to be logged. Log: %s", testLogBufferForTest.String())
+	}
+	config.Translation = nil
+	if got := translateNotificationContent(context.Background(), config, "Alles ausgefallen", "en", "test", "m3"); got != "Alles ausgefallen" {
+		t.Errorf("Expected the original content without a provider, got %q", got)
+	}
+}
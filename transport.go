@@ -0,0 +1,88 @@
+package discord2pushover
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// newTransport returns the transport for outbound connections with the config's httpProxy, caFile
+// and network applied. Without httpProxy, the HTTPS_PROXY and HTTP_PROXY environment variables are used.
+func newTransport(config *Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.Network != nil {
+		dialer, err := newNetworkDialer(config.Network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %w", err)
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	if config.HTTPProxy != "" {
+		proxy, err := url.Parse(config.HTTPProxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid httpProxy %q: expected a URL like http://proxy:3128", config.HTTPProxy)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid httpProxy %q: unsupported scheme %q (expected http, https or socks5)", config.HTTPProxy, proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read caFile: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Warnf("Could not load the system's CA certificates, trusting only those of caFile: %v", err)
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("caFile %s contains no PEM certificates", config.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return transport, nil
+}
+
+// newHTTPClient returns the client for the config's outbound API requests.
+func newHTTPClient(config *Config) *http.Client {
+	timeout := defaultHTTPTimeout
+	if config.HTTPTimeoutSeconds > 0 {
+		timeout = time.Duration(config.HTTPTimeoutSeconds) * time.Second
+	}
+	return newHTTPClientTimeout(config, timeout)
+}
+
+// newHTTPClientTimeout returns a client with its own timeout for outbound requests through the
+// config's transport. config may be nil, e.g. while it is being loaded, to use the default transport.
+func newHTTPClientTimeout(config *Config, timeout time.Duration) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if config != nil && config.transport != nil {
+		transport = config.transport
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// applyDiscordTransport routes the session's REST requests and gateway connection through the
+// config's transport, keeping discordgo's timeouts.
+func applyDiscordTransport(dg *discordgo.Session, config *Config) {
+	if config.transport == nil {
+		return
+	}
+	dg.Client = &http.Client{Transport: config.transport, Timeout: dg.Client.Timeout}
+	dialer := *websocket.DefaultDialer
+	dialer.Proxy = config.transport.Proxy
+	dialer.TLSClientConfig = config.transport.TLSClientConfig
+	dialer.NetDialContext = config.transport.DialContext
+	dg.Dialer = &dialer
+}
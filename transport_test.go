@@ -0,0 +1,69 @@
+package discord2pushover
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestNewTransport_Proxy(t *testing.T) {
+	transport, err := newTransport(&Config{HTTPProxy: "http://proxy.example:3128"})
+	if err != nil {
+		t.Fatalf("newTransport failed: %v", err)
+	}
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pushover.net/1/messages.json", nil)
+	if proxy, err := transport.Proxy(request); err != nil || proxy == nil || proxy.Host != "proxy.example:3128" {
+		t.Errorf("Expected requests to go through the proxy, got %v %v", proxy, err)
+	}
+
+	for _, invalid := range []string{"proxy:3128", "ftp://proxy:21"} {
+		if _, err := newTransport(&Config{HTTPProxy: invalid}); err == nil || !strings.Contains(err.Error(), "invalid httpProxy") {
+			t.Errorf("%s: expected an invalid httpProxy error, got %v", invalid, err)
+		}
+	}
+}
+
+func TestNewTransport_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	config := &Config{}
+	if _, err := newHTTPClient(config).Get(server.URL); err == nil {
+		t.Fatal("Expected the test server's certificate to be untrusted without caFile")
+	}
+
+	dir := t.TempDir()
+	config.CAFile = filepath.Join(dir, "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(config.CAFile, certificate, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if config.transport, err = newTransport(config); err != nil {
+		t.Fatalf("newTransport failed: %v", err)
+	}
+	resp, err := newHTTPClient(config).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the certificate from caFile to be trusted, got %v", err)
+	}
+	resp.Body.Close()
+
+	dg, _ := discordgo.New("Bot token")
+	applyDiscordTransport(dg, config)
+	if dg.Client.Transport != config.transport || dg.Dialer.TLSClientConfig != config.transport.TLSClientConfig {
+		t.Error("Expected the Discord session to use the config's transport")
+	}
+
+	if err := os.WriteFile(config.CAFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTransport(config); err == nil || !strings.Contains(err.Error(), "contains no PEM certificates") {
+		t.Errorf("Expected an error for a caFile without certificates, got %v", err)
+	}
+}
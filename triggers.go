@@ -0,0 +1,87 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// parseCommand checks whether content invokes the command name (case-insensitively, as its first word)
+// and returns the text after it.
+func parseCommand(content string, name string) (args string, ok bool) {
+	content = strings.TrimSpace(content)
+	word, rest, _ := strings.Cut(content, " ")
+	if name == "" || !strings.EqualFold(word, name) {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// mayInvokeCommand reports whether the message's author may use the command. Without an allowlist anyone may.
+func mayInvokeCommand(message *discordgo.Message, command *CommandTrigger) bool {
+	if len(command.AuthorIDs) == 0 && len(command.RoleIDs) == 0 {
+		return true
+	}
+	return mayUsePriorityTags(message, &PriorityTags{AuthorIDs: command.AuthorIDs, RoleIDs: command.RoleIDs})
+}
+
+// handleCommandMessage runs the onCommand rules if the message invokes one of their commands and reports
+// whether it did. Authors not allowed to use the command get a reply saying so.
+func handleCommandMessage(ctx context.Context, message *discordgo.Message, config *Config, session DiscordSessionInterface) bool {
+	if isIgnored(config, message) {
+		return false
+	}
+	invoked := ""
+	allowed := false
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		command := rule.Conditions.Command
+		if ruleEvent(rule) != ruleEventCommand || command == nil {
+			continue
+		}
+		if _, ok := parseCommand(message.Content, command.Name); !ok {
+			continue
+		}
+		invoked = command.Name
+		if mayInvokeCommand(message, command) {
+			allowed = true
+			break
+		}
+	}
+	if invoked == "" {
+		return false
+	}
+	if !allowed {
+		authorID := "unknown"
+		if message.Author != nil {
+			authorID = message.Author.ID
+		}
+		log.Warnf("User %s may not use command '%s' (message ID %s).", authorID, invoked, message.ID)
+		reply := fmt.Sprintf("<@%s> you are not allowed to use %s.", authorID, invoked)
+		if _, err := session.ChannelMessageSend(message.ChannelID, reply); err != nil {
+			log.Errorf("Error replying to denied command in channel %s: %v", message.ChannelID, err)
+		}
+		return true
+	}
+	log.Infof("Message ID %s invokes command '%s'.", message.ID, invoked)
+	processRulesForEvent(ctx, ruleEventCommand, message, nil, config, session, math.MaxInt32)
+	return true
+}
+
+// commandNotificationBody is the default notification body for a command: its arguments and who invoked it.
+func commandNotificationBody(message *discordgo.Message, command *CommandTrigger) string {
+	if command == nil {
+		return message.Content
+	}
+	args, _ := parseCommand(message.ContentWithMentionsReplaced(), command.Name)
+	if args == "" {
+		args = command.Name
+	}
+	if message.Author == nil {
+		return args
+	}
+	return fmt.Sprintf("%s\n\n(paged by %s)", args, message.Author.Username)
+}
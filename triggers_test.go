@@ -0,0 +1,74 @@
+package discord2pushover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		content string
+		args    string
+		ok      bool
+	}{
+		{"!page @oncall disk full", "@oncall disk full", true},
+		{"  !PAGE   now ", "now", true},
+		{"!page", "", true},
+		{"!pager test", "", false},
+		{"please !page me", "", false},
+	}
+	for _, tc := range tests {
+		args, ok := parseCommand(tc.content, "!page")
+		if args != tc.args || ok != tc.ok {
+			t.Errorf("parseCommand(%q) = %q, %v; expected %q, %v", tc.content, args, ok, tc.args, tc.ok)
+		}
+	}
+}
+
+func TestHandleCommandMessage(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	testHookPushoverSendCalled = false
+	defer func() { testHookDisablePushoverSend = false }()
+
+	config := &Config{Rules: []Rule{{
+		Name:       "Page",
+		Event:      ruleEventCommand,
+		Conditions: RuleConditions{Command: &CommandTrigger{Name: "!page", RoleIDs: []string{"sre"}}},
+		Actions:    RuleActions{PushoverDestination: "uKey", Priority: 1, Template: "{{index .Args 0}}: {{.ArgText}}"},
+	}}}
+	session := &MockDiscordSession{Session: &discordgo.Session{}}
+	oncall := &discordgo.User{ID: "42", Username: "oncall"}
+
+	if handleCommandMessage(context.Background(), &discordgo.Message{ID: "m0", Content: "disk full"}, config, session) {
+		t.Error("Expected a plain message not to be handled as a command")
+	}
+
+	denied := &discordgo.Message{ID: "m1", ChannelID: "ops", Content: "!page <@42> disk full", Author: &discordgo.User{ID: "intern"}, Mentions: []*discordgo.User{oncall}}
+	if !handleCommandMessage(context.Background(), denied, config, session) || testHookPushoverSendCalled {
+		t.Fatal("Expected a denied command to be handled without notifying")
+	}
+	if logs := testLogBufferForTest.String(); !strings.Contains(logs, "content=<@intern> you are not allowed to use !page.") {
+		t.Errorf("Expected a denial reply. Logs:\n%s", logs)
+	}
+
+	allowed := &discordgo.Message{
+		ID: "m2", ChannelID: "ops", Content: "!page <@42> disk full", Mentions: []*discordgo.User{oncall},
+		Author: &discordgo.User{ID: "u1", Username: "alice"}, Member: &discordgo.Member{Roles: []string{"sre"}},
+	}
+	if !handleCommandMessage(context.Background(), allowed, config, session) || !testHookPushoverSendCalled {
+		t.Fatal("Expected an allowed command to notify")
+	}
+
+	data := newNotificationData(&config.Rules[0], "Page", ruleEventCommand, allowed, commandNotificationBody(allowed, config.Rules[0].Conditions.Command), "")
+	if body, err := renderTemplate(config.Rules[0].Actions.Template, nil, data); err != nil || body != "@oncall: @oncall disk full" {
+		t.Errorf("Unexpected templated body %q (%v)", body, err)
+	}
+	if data.Body != "@oncall disk full\n\n(paged by alice)" {
+		t.Errorf("Unexpected default body %q", data.Body)
+	}
+}
@@ -0,0 +1,92 @@
+package discord2pushover
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// twilioAPIBase is the Twilio REST API endpoint; tests point it at a local server.
+var twilioAPIBase = "https://api.twilio.com/2010-04-01"
+
+const (
+	twilioMaxSMSLength  = 1600 // Twilio's limit for a message body
+	twilioMaxCallLength = 500  // Keeps the spoken text to about half a minute
+)
+
+type twilioNotifier struct {
+	config     *TwilioNotifier
+	httpClient *http.Client
+}
+
+// twilioText returns the plain text sent or read aloud for a notification.
+func twilioText(n *Notification, limit int) string {
+	text := n.Body
+	if n.Title != "" {
+		text = n.Title + ": " + text
+	}
+	return truncateRunes(text, limit)
+}
+
+// twilioCallTwiML returns the TwiML instructions of a call reading the text aloud twice.
+func twilioCallTwiML(text string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(text))
+	return fmt.Sprintf(`<Response><Say loop="2">%s</Say></Response>`, escaped.String())
+}
+
+// Notify texts or calls each configured number. A failure for one number doesn't stop the others.
+func (t *twilioNotifier) Notify(ctx context.Context, n *Notification) error {
+	if t.config.AccountSID == "" || t.config.AuthToken == "" || t.config.From == "" || len(t.config.To) == 0 {
+		return fmt.Errorf("twilio notifier requires accountSid, authToken, from and to")
+	}
+	var resource string
+	form := url.Values{"From": {t.config.From}}
+	switch strings.ToLower(t.config.Mode) {
+	case "", "sms":
+		resource = "Messages.json"
+		form.Set("Body", twilioText(n, twilioMaxSMSLength))
+	case "call":
+		resource = "Calls.json"
+		form.Set("Twiml", twilioCallTwiML(twilioText(n, twilioMaxCallLength)))
+	default:
+		return fmt.Errorf("unknown twilio mode '%s' (expected sms or call)", t.config.Mode)
+	}
+	endpoint := fmt.Sprintf("%s/Accounts/%s/%s", twilioAPIBase, url.PathEscape(t.config.AccountSID), resource)
+
+	var failed []string
+	for _, to := range t.config.To {
+		form.Set("To", to)
+		if err := t.post(ctx, endpoint, form); err != nil {
+			log.Errorf("Twilio %s to %s failed: %v", resource, to, err)
+			failed = append(failed, to)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("twilio delivery failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (t *twilioNotifier) post(ctx context.Context, endpoint string, form url.Values) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(t.config.AccountSID, t.config.AuthToken)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := t.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
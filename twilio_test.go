@@ -0,0 +1,122 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeTwilio records the forms posted to the Twilio API.
+type fakeTwilio struct {
+	mu    sync.Mutex
+	paths []string
+	forms []url.Values
+}
+
+func startFakeTwilio(t *testing.T) *fakeTwilio {
+	fake := &fakeTwilio{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "AC1" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		fake.mu.Lock()
+		fake.paths = append(fake.paths, r.URL.Path)
+		fake.forms = append(fake.forms, r.PostForm)
+		fake.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	oldBase := twilioAPIBase
+	twilioAPIBase = server.URL
+	t.Cleanup(func() {
+		twilioAPIBase = oldBase
+		server.Close()
+	})
+	return fake
+}
+
+func TestTwilioNotifier(t *testing.T) {
+	fake := startFakeTwilio(t)
+	n := &Notification{Title: "DB down", Body: "primary <unreachable>"}
+
+	sms := &twilioNotifier{httpClient: notifierHTTPClient(nil), config: &TwilioNotifier{AccountSID: "AC1", AuthToken: "secret", From: "+1000", To: []string{"+1001", "+1002"}}}
+	if err := sms.Notify(context.Background(), n); err != nil {
+		t.Fatalf("SMS Notify failed: %v", err)
+	}
+	if len(fake.forms) != 2 || fake.paths[0] != "/Accounts/AC1/Messages.json" {
+		t.Fatalf("Expected two messages, got %v", fake.paths)
+	}
+	if f := fake.forms[1]; f.Get("To") != "+1002" || f.Get("From") != "+1000" || f.Get("Body") != "DB down: primary <unreachable>" {
+		t.Errorf("Unexpected SMS form %v", f)
+	}
+
+	call := &twilioNotifier{httpClient: notifierHTTPClient(nil), config: &TwilioNotifier{AccountSID: "AC1", AuthToken: "secret", From: "+1000", To: []string{"+1001"}, Mode: "call"}}
+	if err := call.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Call Notify failed: %v", err)
+	}
+	if fake.paths[2] != "/Accounts/AC1/Calls.json" || fake.forms[2].Get("Twiml") != `<Response><Say loop="2">DB down: primary &lt;unreachable&gt;</Say></Response>` {
+		t.Errorf("Unexpected call request %s %v", fake.paths[2], fake.forms[2])
+	}
+
+	wrongToken := &twilioNotifier{httpClient: notifierHTTPClient(nil), config: &TwilioNotifier{AccountSID: "AC1", AuthToken: "wrong", From: "+1000", To: []string{"+1001"}}}
+	if err := wrongToken.Notify(context.Background(), n); err == nil || !strings.Contains(err.Error(), "+1001") {
+		t.Errorf("Expected a delivery error naming the number, got %v", err)
+	}
+}
+
+func TestProcessRules_EmergencyEscalation(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+	testHookDisablePushoverSend = true
+	defer func() { testHookDisablePushoverSend = false }()
+	fake := startFakeTwilio(t)
+
+	config := &Config{
+		Notifiers: map[string]NotifierConfig{
+			"oncall-phone": {Twilio: &TwilioNotifier{AccountSID: "AC1", AuthToken: "secret", From: "+1000", To: []string{"+1001"}, Mode: "call"}},
+		},
+		Rules: []Rule{{
+			Name:       "Escalating",
+			Conditions: RuleConditions{ChannelID: "ops"},
+			Actions: RuleActions{
+				PushoverDestination: "user",
+				Priority:            2,
+				Emergency:           &EmergencyParams{Retry: 60, Expire: 3600, EscalateTo: []string{"oncall-phone"}, EscalateAfterSeconds: 120},
+			},
+		}},
+	}
+	message := &discordgo.Message{ID: "m1", ChannelID: "ops", GuildID: "g1", Content: "db down", Author: &discordgo.User{ID: "u1"}}
+	ProcessRules(context.Background(), message, config, mockSessionForRulesTest("bot"), math.MaxInt32)
+
+	value, ok := config.trackedEmergencies().Load("fake-receipt-id-for-test")
+	if !ok {
+		t.Fatal("Expected the emergency to be tracked")
+	}
+	trackedMsg := value.(TrackedEmergencyMessage)
+	if trackedMsg.Escalation == nil {
+		t.Fatal("Expected the tracked emergency to carry an escalation")
+	}
+
+	if escalateIfDue(context.Background(), config, trackedMsg, time.Now()) {
+		t.Error("Escalated before escalateAfterSeconds passed")
+	}
+	later := time.Now().Add(121 * time.Second)
+	if !escalateIfDue(context.Background(), config, trackedMsg, later) {
+		t.Fatal("Expected the emergency to escalate after escalateAfterSeconds")
+	}
+	if escalateIfDue(context.Background(), config, trackedMsg, later.Add(time.Minute)) {
+		t.Error("Escalated the same alert twice")
+	}
+	if len(fake.forms) != 1 || !strings.Contains(fake.forms[0].Get("Twiml"), "Unacknowledged: Discord Notification: db down") {
+		t.Errorf("Expected one escalation call, got %v", fake.forms)
+	}
+}
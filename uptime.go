@@ -0,0 +1,122 @@
+package discord2pushover
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	uptimeStatusUp   = "up"
+	uptimeStatusDown = "down"
+)
+
+// payloadStatusPriorities are the priorities of the payload statuses for rules with payloadPriority.
+var payloadStatusPriorities = map[string]int{uptimeStatusDown: 1, uptimeStatusUp: -2}
+
+var (
+	// UptimeRobot: "Monitor is DOWN: Example ( https://example.com )", optionally after a status emoji
+	uptimeRobotPattern = regexp.MustCompile(`(?i)^\W*monitor is (up|down)\s*:\s*(.+?)\s*(?:\(\s*(\S+)\s*\))?\s*\.?$`)
+	// "It was down for 5 minutes and 3 seconds."
+	uptimeRobotDurationPattern = regexp.MustCompile(`(?im)\bdown for (.+?)\.?\s*$`)
+	// "Reason: Connection Timeout" or "Alert Details: Connection Timeout"
+	uptimeRobotReasonPattern = regexp.MustCompile(`(?im)^\s*(?:reason|alert details)\s*:\s*(.+?)\s*$`)
+
+	// Uptime Kuma embeds: "❌ Your service Example went down. ❌" and "✅ Your service Example is up! ✅"
+	uptimeKumaTitlePattern = regexp.MustCompile(`(?i)^\W*your service (.+?) (went down|is up)\b`)
+	// Uptime Kuma plain text: "[Example] [🔴 Down] Connection timeout"
+	uptimeKumaTextPattern = regexp.MustCompile(`(?i)^\[(.+?)\]\s*\[\W*(down|up)\]\s*(.*)$`)
+)
+
+// uptimeText renders an uptime payload as one line, e.g. "Example is down: Connection timeout".
+func uptimeText(p map[string]string) string {
+	text := p["monitor"] + " is " + p["status"]
+	switch {
+	case p["reason"] != "":
+		text += ": " + p["reason"]
+	case p["duration"] != "":
+		text += " (down for " + p["duration"] + ")"
+	case p["ping"] != "":
+		text += " (ping " + p["ping"] + ")"
+	}
+	return text
+}
+
+// parseUptimeRobotPayload parses an UptimeRobot alert: monitor, status ("up" or "down"), url,
+// duration (of the outage, on recovery) and reason.
+func parseUptimeRobotPayload(message *discordgo.Message) map[string]string {
+	texts := []string{message.Content}
+	for _, embed := range message.Embeds {
+		if embed != nil {
+			texts = append(texts, embed.Title+"\n"+embed.Description)
+		}
+	}
+	for _, text := range texts {
+		headline, details, _ := strings.Cut(strings.TrimSpace(text), "\n")
+		// The duration may follow on the same line: "Monitor is UP: Example. It was down for 2 minutes."
+		if before, after, found := strings.Cut(headline, ". "); found {
+			headline, details = before, after+"\n"+details
+		}
+		m := uptimeRobotPattern.FindStringSubmatch(headline)
+		if m == nil {
+			continue
+		}
+		payload := map[string]string{"status": strings.ToLower(m[1]), "monitor": m[2], "url": m[3]}
+		if d := uptimeRobotDurationPattern.FindStringSubmatch(details); d != nil {
+			payload["duration"] = d[1]
+		}
+		if r := uptimeRobotReasonPattern.FindStringSubmatch(details); r != nil {
+			payload["reason"] = r[1]
+		}
+		payload["text"] = uptimeText(payload)
+		return payload
+	}
+	return nil
+}
+
+// parseUptimeKumaPayload parses an Uptime Kuma notification: monitor, status ("up" or "down"), url,
+// reason (the error, when down), ping (when up) and time.
+func parseUptimeKumaPayload(message *discordgo.Message) map[string]string {
+	for _, embed := range message.Embeds {
+		if embed == nil {
+			continue
+		}
+		m := uptimeKumaTitlePattern.FindStringSubmatch(embed.Title)
+		if m == nil {
+			continue
+		}
+		payload := map[string]string{"monitor": m[1], "status": uptimeStatusUp}
+		if strings.EqualFold(m[2], "went down") {
+			payload["status"] = uptimeStatusDown
+		}
+		for _, field := range embed.Fields {
+			if field == nil {
+				continue
+			}
+			switch name := strings.ToLower(field.Name); {
+			case name == "service name":
+				payload["monitor"] = field.Value
+			case strings.HasPrefix(name, "service url"), strings.HasPrefix(name, "service host"):
+				payload["url"] = field.Value
+			case name == "error":
+				payload["reason"] = field.Value
+			case name == "ping":
+				payload["ping"] = field.Value
+			case strings.HasPrefix(name, "time"):
+				payload["time"] = field.Value
+			}
+		}
+		payload["text"] = uptimeText(payload)
+		return payload
+	}
+	if m := uptimeKumaTextPattern.FindStringSubmatch(strings.TrimSpace(message.Content)); m != nil {
+		payload := map[string]string{"monitor": m[1], "status": strings.ToLower(m[2])}
+		if payload["status"] == uptimeStatusDown {
+			payload["reason"] = m[3]
+		}
+		payload["text"] = uptimeText(payload)
+		return payload
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package discord2pushover
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseUptimeRobotPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *discordgo.Message
+		want    map[string]string
+	}{
+		{
+			name: "down embed",
+			message: &discordgo.Message{Embeds: []*discordgo.MessageEmbed{
+				{Title: "🔴 Monitor is DOWN: Example ( https://example.com )", Description: "Reason: Connection Timeout"},
+			}},
+			want: map[string]string{"monitor": "Example", "status": "down", "url": "https://example.com", "reason": "Connection Timeout",
+				"text": "Example is down: Connection Timeout"},
+		},
+		{
+			name:    "up content",
+			message: &discordgo.Message{Content: "Monitor is UP: Example. It was down for 5 minutes and 3 seconds."},
+			want: map[string]string{"monitor": "Example", "status": "up", "url": "", "duration": "5 minutes and 3 seconds",
+				"text": "Example is up (down for 5 minutes and 3 seconds)"},
+		},
+		{
+			name:    "other",
+			message: &discordgo.Message{Content: "The monitor is fine"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUptimeRobotPayload(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseUptimeKumaPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *discordgo.Message
+		want    map[string]string
+	}{
+		{
+			name: "down",
+			message: &discordgo.Message{Embeds: []*discordgo.MessageEmbed{{
+				Title: "❌ Your service Example went down. ❌",
+				Fields: []*discordgo.MessageEmbedField{
+					{Name: "Service Name", Value: "Example"},
+					{Name: "Service URL", Value: "https://example.com"},
+					{Name: "Time (UTC)", Value: "2024-03-01 22:30:00"},
+					{Name: "Error", Value: "timeout of 48000ms exceeded"},
+				},
+			}}},
+			want: map[string]string{"monitor": "Example", "status": "down", "url": "https://example.com", "time": "2024-03-01 22:30:00",
+				"reason": "timeout of 48000ms exceeded", "text": "Example is down: timeout of 48000ms exceeded"},
+		},
+		{
+			name: "up",
+			message: &discordgo.Message{Embeds: []*discordgo.MessageEmbed{{
+				Title:  "✅ Your service Example is up! ✅",
+				Fields: []*discordgo.MessageEmbedField{{Name: "Ping", Value: "42 ms"}},
+			}}},
+			want: map[string]string{"monitor": "Example", "status": "up", "ping": "42 ms", "text": "Example is up (ping 42 ms)"},
+		},
+		{
+			name:    "plain text",
+			message: &discordgo.Message{Content: "[Example] [🔴 Down] Connection refused"},
+			want:    map[string]string{"monitor": "Example", "status": "down", "reason": "Connection refused", "text": "Example is down: Connection refused"},
+		},
+		{
+			name:    "other",
+			message: &discordgo.Message{Content: "[Example] deployed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUptimeKumaPayload(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEffectiveActions_PayloadPriority(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	rule := &Rule{Actions: RuleActions{Priority: 0, PayloadFormat: payloadFormatUptimeKuma, PayloadPriority: true}}
+	for content, want := range map[string]int{
+		"[Example] [🔴 Down] Connection refused": 1,
+		"[Example] [✅ Up] 200 - OK":             -2,
+		"Something else":                        0,
+	} {
+		if got := effectiveActions(rule, &discordgo.Message{Content: content}, "test").Priority; got != want {
+			t.Errorf("%q: expected priority %d, got %d", content, want, got)
+		}
+	}
+
+	rule.Actions.PayloadPriority = false
+	if got := effectiveActions(rule, &discordgo.Message{Content: "[Example] [🔴 Down] Connection refused"}, "test").Priority; got != 0 {
+		t.Errorf("Expected the rule's priority without payloadPriority, got %d", got)
+	}
+}
@@ -0,0 +1,109 @@
+package discord2pushover
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// userDestination returns the Pushover user key of a Discord user: the one in userDestinations, else
+// the one they registered, else "".
+func userDestination(config *Config, userID string) string {
+	if key, ok := config.UserDestinations[userID]; ok {
+		return key
+	}
+	return checkpoints.registeredSubscriber(userID)
+}
+
+// hasUserDestinations reports whether the actions notify people or their roles' groups in addition to
+// the rule's destination.
+func hasUserDestinations(actions *RuleActions) bool {
+	return actions.NotifySubscribersOfEmoji != "" || actions.NotifyMentionedUsers || actions.NotifyMentionedRoles
+}
+
+// mentionedUserDestinations returns the Pushover user keys of the users mentioned in the message.
+// Mentioned users without a key are skipped.
+func mentionedUserDestinations(config *Config, message *discordgo.Message, ruleNameLog string) []string {
+	var keys []string
+	for _, user := range message.Mentions {
+		if user == nil || user.Bot {
+			continue
+		}
+		key := userDestination(config, user.ID)
+		if key == "" {
+			log.Debugf("Rule '%s': mentioned user %s has no Pushover user key.", ruleNameLog, user.ID)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// mentionedRoleDestinations returns the Pushover group keys of the roles mentioned in the message.
+// Mentioned roles without a key are skipped.
+func mentionedRoleDestinations(config *Config, message *discordgo.Message, ruleNameLog string) []string {
+	var keys []string
+	for _, roleID := range message.MentionRoles {
+		key, ok := config.RoleDestinations[roleID]
+		if !ok {
+			log.Debugf("Rule '%s': mentioned role %s has no Pushover group key.", ruleNameLog, roleID)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// notifyUserDestinations sends the rule's notification to the Pushover keys of the people its actions
+// address: subscribers of the channel, mentioned users and mentioned roles. Each key is notified
+// once, and not at all if it is the rule's destination or outside the guild's allowedDestinations.
+// It returns the receipt IDs of emergency notifications.
+func notifyUserDestinations(ctx context.Context, config *Config, session DiscordSessionInterface, rule *Rule, actions *RuleActions, message *discordgo.Message, data *NotificationData, ruleNameLog string, link string) ([]string, error) {
+	var candidates []string
+	if actions.NotifySubscribersOfEmoji != "" && message.ChannelID != "" {
+		candidates = append(candidates, subscriberDestinations(config, session, message.ChannelID, actions.NotifySubscribersOfEmoji, ruleNameLog)...)
+	}
+	if actions.NotifyMentionedUsers {
+		candidates = append(candidates, mentionedUserDestinations(config, message, ruleNameLog)...)
+	}
+	if actions.NotifyMentionedRoles {
+		candidates = append(candidates, mentionedRoleDestinations(config, message, ruleNameLog)...)
+	}
+	tenant := tenantFor(config, message.GuildID)
+	seen := map[string]bool{actions.PushoverDestination: true}
+	var keys []string
+	for _, key := range candidates {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !tenantAllowsDestination(tenant, key) {
+			log.Warnf("Rule '%s': key %s is not in the allowedDestinations of guild %s, not notifying it.", ruleNameLog, key, message.GuildID)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		log.Infof("Rule '%s': no further users or roles with a Pushover key to notify for message ID %s.", ruleNameLog, message.ID)
+		return nil, nil
+	}
+
+	var receiptIDs []string
+	failed := 0
+	for _, key := range keys {
+		userActions := *actions
+		userActions.PushoverDestination = key
+		ids, err := sendRuleNotification(ctx, config, rule, &userActions, data, ruleNameLog, link)
+		receiptIDs = append(receiptIDs, ids...)
+		if err != nil {
+			log.Errorf("Rule '%s': error notifying key %s: %v", ruleNameLog, key, err)
+			failed++
+		}
+	}
+	log.Infof("Rule '%s': notified %d of %d user and group keys for message ID %s.", ruleNameLog, len(keys)-failed, len(keys), message.ID)
+	if failed > 0 {
+		return receiptIDs, fmt.Errorf("failed to notify %d of %d user and group keys", failed, len(keys))
+	}
+	return receiptIDs, nil
+}
@@ -0,0 +1,53 @@
+package discord2pushover
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestProcessRules_NotifyMentionedUsers(t *testing.T) {
+	setupTestEnvironment()
+	defer teardownTestEnvironment()
+
+	fake := &fakePushoverClient{}
+	config := &Config{
+		PushoverAppKey:   "app",
+		UserDestinations: map[string]string{"alice": "uAlice", "bob": "uBob"},
+		Rules: []Rule{{
+			Name:       "Mentions",
+			Conditions: RuleConditions{ChannelID: "ops"},
+			Actions:    RuleActions{NotifyMentionedUsers: true},
+		}},
+	}
+	config.SetPushoverClient(fake)
+	session := mockSessionForRulesTest("bot")
+
+	message := &discordgo.Message{ID: "m1", ChannelID: "ops", Content: "<@alice> <@carol> please look at this, <@alice>",
+		Author:   &discordgo.User{ID: "dave"},
+		Mentions: []*discordgo.User{{ID: "alice"}, {ID: "carol"}, {ID: "alice"}, {ID: "bot", Bot: true}}}
+	ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+	if want := []string{"uAlice"}; !reflect.DeepEqual(fake.recipients, want) {
+		t.Errorf("Expected notifications to %v, got %v", want, fake.recipients)
+	}
+
+	fake.recipients = nil
+	ProcessRules(context.Background(), &discordgo.Message{ID: "m2", ChannelID: "ops", Content: "nobody", Author: &discordgo.User{ID: "dave"}}, config, session, math.MaxInt32)
+	if len(fake.recipients) != 0 {
+		t.Errorf("Expected no notification without mentions, got %v", fake.recipients)
+	}
+
+	// Mentioned roles page their group, once even if it is also the rule's destination
+	config.RoleDestinations = map[string]string{"oncall": "gOncall", "dba": "gDBA"}
+	config.Rules[0].Actions = RuleActions{PushoverDestination: "gDBA", NotifyMentionedRoles: true}
+	fake.recipients = nil
+	message = &discordgo.Message{ID: "m3", ChannelID: "ops", Content: "<@&oncall> <@&dba> <@&design> db is down", Author: &discordgo.User{ID: "dave"},
+		MentionRoles: []string{"oncall", "dba", "design"}}
+	ProcessRules(context.Background(), message, config, session, math.MaxInt32)
+	if want := []string{"gDBA", "gOncall"}; !reflect.DeepEqual(fake.recipients, want) {
+		t.Errorf("Expected notifications to %v, got %v", want, fake.recipients)
+	}
+}
@@ -0,0 +1,135 @@
+package discord2pushover
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// whenEnv is the environment `when` expressions are evaluated against.
+type whenEnv struct {
+	Content      string           `expr:"content"`
+	Text         string           `expr:"text"` // Content plus embed text
+	Author       whenAuthor       `expr:"author"`
+	MessageID    string           `expr:"messageId"`
+	ChannelID    string           `expr:"channelId"`
+	GuildID      string           `expr:"guildId"`
+	WebhookID    string           `expr:"webhookId"`
+	IsReply      bool             `expr:"isReply"`
+	Mentions     []string         `expr:"mentions"`     // Mentioned user IDs
+	RoleMentions []string         `expr:"roleMentions"` // Mentioned role IDs
+	Attachments  []whenAttachment `expr:"attachments"`
+	Embeds       []whenEmbed      `expr:"embeds"`
+	Reactions    []whenReaction   `expr:"reactions"`
+}
+
+type whenAuthor struct {
+	ID       string `expr:"id"`
+	Username string `expr:"username"`
+	Bot      bool   `expr:"bot"`
+}
+
+type whenAttachment struct {
+	Filename    string `expr:"filename"`
+	ContentType string `expr:"contentType"`
+	Size        int    `expr:"size"`
+	URL         string `expr:"url"`
+}
+
+type whenEmbed struct {
+	Title       string `expr:"title"`
+	Description string `expr:"description"`
+	Color       int    `expr:"color"`
+	URL         string `expr:"url"`
+}
+
+type whenReaction struct {
+	Emoji string `expr:"emoji"`
+	Count int    `expr:"count"`
+	Me    bool   `expr:"me"`
+}
+
+// whenPrograms holds compiled expressions keyed by their source.
+var whenPrograms sync.Map
+
+// whenFunctions are available in expressions besides the expr builtins. `contains` is a
+// (case-sensitive) operator in expr, so the case-insensitive variant is named icontains.
+var whenFunctions = []expr.Option{
+	expr.Function("icontains", func(params ...any) (any, error) {
+		return strings.Contains(strings.ToLower(params[0].(string)), strings.ToLower(params[1].(string))), nil
+	}, new(func(string, string) bool)),
+}
+
+// compiledWhen returns the compiled program for a `when` expression, compiling it once.
+func compiledWhen(source string) (*vm.Program, error) {
+	if cached, ok := whenPrograms.Load(source); ok {
+		return cached.(*vm.Program), nil
+	}
+	options := append([]expr.Option{expr.Env(whenEnv{}), expr.AsBool()}, whenFunctions...)
+	program, err := expr.Compile(source, options...)
+	if err != nil {
+		return nil, err
+	}
+	whenPrograms.Store(source, program)
+	return program, nil
+}
+
+// newWhenEnv collects the message fields available to `when` expressions.
+func newWhenEnv(message *discordgo.Message) whenEnv {
+	env := whenEnv{
+		Content:      message.Content,
+		Text:         messageSearchText(message),
+		MessageID:    message.ID,
+		ChannelID:    message.ChannelID,
+		GuildID:      message.GuildID,
+		WebhookID:    message.WebhookID,
+		IsReply:      message.ReferencedMessage != nil || (message.MessageReference != nil && message.Type == discordgo.MessageTypeReply),
+		Mentions:     []string{},
+		RoleMentions: message.MentionRoles,
+		Attachments:  []whenAttachment{},
+		Embeds:       []whenEmbed{},
+		Reactions:    []whenReaction{},
+	}
+	if env.RoleMentions == nil {
+		env.RoleMentions = []string{}
+	}
+	if message.Author != nil {
+		env.Author = whenAuthor{ID: message.Author.ID, Username: message.Author.Username, Bot: message.Author.Bot}
+	}
+	for _, user := range message.Mentions {
+		env.Mentions = append(env.Mentions, user.ID)
+	}
+	for _, attachment := range message.Attachments {
+		env.Attachments = append(env.Attachments, whenAttachment{
+			Filename: attachment.Filename, ContentType: attachment.ContentType, Size: attachment.Size, URL: attachment.URL,
+		})
+	}
+	for _, embed := range message.Embeds {
+		if embed != nil {
+			env.Embeds = append(env.Embeds, whenEmbed{Title: embed.Title, Description: embed.Description, Color: embed.Color, URL: embed.URL})
+		}
+	}
+	for _, reaction := range message.Reactions {
+		if reaction.Emoji != nil {
+			env.Reactions = append(env.Reactions, whenReaction{Emoji: reaction.Emoji.Name, Count: reaction.Count, Me: reaction.Me})
+		}
+	}
+	return env
+}
+
+// evaluateWhen evaluates a `when` expression against message.
+func evaluateWhen(source string, message *discordgo.Message) (bool, error) {
+	program, err := compiledWhen(source)
+	if err != nil {
+		return false, fmt.Errorf("invalid when expression: %w", err)
+	}
+	result, err := expr.Run(program, newWhenEnv(message))
+	if err != nil {
+		return false, fmt.Errorf("when expression failed: %w", err)
+	}
+	return result.(bool), nil
+}
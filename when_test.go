@@ -0,0 +1,50 @@
+package discord2pushover
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestEvaluateWhen(t *testing.T) {
+	message := &discordgo.Message{
+		ID:          "m1",
+		ChannelID:   "c1",
+		Content:     "Deploy of api finished",
+		Author:      &discordgo.User{ID: "ci", Username: "ci-bot", Bot: true},
+		Attachments: []*discordgo.MessageAttachment{{Filename: "log.txt", Size: 2048}},
+		Embeds:      []*discordgo.MessageEmbed{{Title: "Build #42", Color: 0xe01e5a}},
+		Reactions:   []*discordgo.MessageReactions{{Emoji: &discordgo.Emoji{Name: "🔥"}, Count: 3}},
+		Mentions:    []*discordgo.User{{ID: "u1"}},
+	}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`author.id == "ci" && icontains(content, "deploy") && len(attachments) > 0`, true},
+		{`content contains "Deploy" && !(content contains "deploy")`, true},
+		{`author.bot && !isReply`, true},
+		{`any(embeds, .color == 0xe01e5a) and text matches "Build #\\d+"`, true},
+		{`any(reactions, .emoji == "🔥" && .count >= 3)`, true},
+		{`"u1" in mentions && len(roleMentions) == 0`, true},
+		{`attachments[0].size > 4096`, false},
+		{`icontains(content, "rollback")`, false},
+	}
+	for _, tc := range tests {
+		result, err := evaluateWhen(tc.expression, message)
+		if err != nil {
+			t.Errorf("evaluateWhen(%q) returned error: %v", tc.expression, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("evaluateWhen(%q) = %v, expected %v", tc.expression, result, tc.expected)
+		}
+	}
+
+	for _, invalid := range []string{`content ==`, `unknownField == 1`, `len(content)`} {
+		if _, err := evaluateWhen(invalid, message); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}